@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+)
+
+// globalValueFlags are the persistent flags whose value may follow as a separate argument
+var globalValueFlags = map[string]bool{"-C": true, "--chdir": true, "--config": true}
+
+// expandArgs applies the aliases and default command of the global registry to the arguments
+// of go-backup. Without a command, the default command runs in directories with a .backup.yaml.
+// Built-in commands always win over aliases of the same name, and aliases are not expanded again.
+// Returns an error for a malformed alias or default command. A nil registry has no aliases.
+func expandArgs(registry *configService.GlobalBackupRegistry, args []string) ([]string, error) {
+	i, dir := commandIndex(args)
+	if i == len(args) {
+		if hasHelpFlag(args) {
+			return args, nil
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".backup.yaml")); err != nil {
+			return args, nil
+		}
+		defaultArgs, err := registry.DefaultArgs()
+		if err != nil || defaultArgs == nil {
+			return args, err
+		}
+		args = append(append([]string{}, args...), defaultArgs...)
+	}
+
+	if isBuiltinCommand(args[i]) {
+		return args, nil
+	}
+	alias, ok, err := registry.Alias(args[i])
+	if err != nil || !ok {
+		return args, err
+	}
+	expanded := append(append([]string{}, args[:i]...), alias...)
+	return append(expanded, args[i+1:]...), nil
+}
+
+// commandIndex returns the index of the command in the arguments, after the global flags, or
+// len(args) if there is none. It also returns the directory the command will run in, from -C.
+func commandIndex(args []string) (int, string) {
+	dir := "."
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return len(args), dir
+		case !strings.HasPrefix(arg, "-"):
+			return i, dir
+		case globalValueFlags[arg] && i+1 < len(args):
+			if arg != "--config" {
+				dir = args[i+1]
+			}
+			i++
+		case strings.HasPrefix(arg, "--chdir="):
+			dir = strings.TrimPrefix(arg, "--chdir=")
+		case strings.HasPrefix(arg, "-C") && !strings.HasPrefix(arg, "--"):
+			dir = strings.TrimPrefix(strings.TrimPrefix(arg, "-C"), "=")
+		}
+	}
+	return len(args), dir
+}
+
+// hasHelpFlag reports whether the arguments ask for help or the version
+func hasHelpFlag(args []string) bool {
+	for _, arg := range args {
+		switch arg {
+		case "-h", "--help", "-v", "--version":
+			return true
+		}
+	}
+	return false
+}
+
+// isBuiltinCommand reports whether name is a command of go-backup, including those cobra adds itself
+func isBuiltinCommand(name string) bool {
+	switch name {
+	case "help", "completion", "__complete", "__completeNoDesc":
+		return true
+	}
+	for _, command := range rootCmd.Commands() {
+		if command.Name() == name || command.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+	extractService "github.com/kennycyb/go-backup/internal/service/extract"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	catRefresh    bool
+	catPassphrase string
+)
+
+// catCmd represents the cat command
+var catCmd = &cobra.Command{
+	Use:   "cat <backup> <path>",
+	Short: "Print a file from a backup",
+	Long: `Write the contents of one file in a backup to standard output, without restoring it.
+
+The backup may be a local file or a remote location (s3://, sftp://, rclone:, exec:),
+and the path is relative to the backup's root, as listed by inspect. Progress and
+errors go to standard error, so the output can be piped or redirected.
+
+Of a remote backup stored in chunks (a target's chunks setting), only the chunk
+holding the file is downloaded from s3:// and rclone: locations. Other remote
+backups are downloaded whole and kept in the local cache for later commands.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		location, name := args[0], args[1]
+
+		out, restore := nullOutput()
+		defer restore()
+
+		tmpDir, err := os.MkdirTemp("", "go-backup-cat-")
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		defer os.RemoveAll(tmpDir)
+
+		archivePath, err := catArchive(location, name, tmpDir)
+		if err == nil {
+			err = compressionService.CatTarGzFile(archivePath, name, out)
+		}
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.RemoveAll(tmpDir)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	catCmd.Flags().BoolVar(&catRefresh, "refresh", false, "Download remote files again even if they are in the local cache")
+	catCmd.Flags().StringVar(&catPassphrase, "passphrase", "", "Passphrase for GPG decryption (if needed)")
+	rootCmd.AddCommand(catCmd)
+}
+
+// catArchive returns a local, decrypted archive holding the named file of the backup: the
+// file's chunk of a chunked remote backup, or else the whole backup
+func catArchive(location, name, tmpDir string) (string, error) {
+	if storageService.IsRemote(location) {
+		archivePath, ok, err := fetchListedChunks(location, []string{name}, nil, tmpDir, catPassphrase, catRefresh)
+		if err != nil || ok {
+			return archivePath, err
+		}
+	}
+
+	archivePath := location
+	if storageService.IsRemote(location) {
+		remote, err := storageService.ParseRemote(location)
+		if err != nil {
+			return "", err
+		}
+		fmt.Printf("Fetching %s...\n", remote)
+		if archivePath, _, err = storageService.Fetch(remote, catRefresh); err != nil {
+			return "", err
+		}
+	} else if _, err := os.Stat(archivePath); err != nil {
+		return "", err
+	}
+
+	if !strings.HasSuffix(archivePath, ".gpg") {
+		return archivePath, nil
+	}
+	manifest, err := findBackupManifest(location, tmpDir, catPassphrase, catRefresh)
+	if err != nil {
+		return "", err
+	}
+	var chunks []compressionService.ArchiveChunk
+	if manifest != nil {
+		chunks = manifest.Chunks
+	}
+	plainPath := filepath.Join(tmpDir, strings.TrimSuffix(filepath.Base(archivePath), ".gpg"))
+	return extractService.Decrypt(archivePath, plainPath, catPassphrase, chunks)
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+	encryptionService "github.com/kennycyb/go-backup/internal/service/encrypt"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
+)
+
+// fetchListedChunks downloads only the chunks of a remote backup that hold the listed paths or
+// entries matching the patterns, into one archive in tmpDir, and returns its path. The archive is decrypted and holds only
+// those chunks' entries. ok is false when the whole backup has to be fetched instead: the
+// backup has no chunks, or its location cannot read part of a file.
+func fetchListedChunks(location string, paths, patterns []string, tmpDir, passphrase string, refresh bool) (string, bool, error) {
+	remote, err := storageService.ParseRemote(location)
+	if err != nil {
+		return "", false, err
+	}
+	manifest, err := findBackupManifest(location, tmpDir, passphrase, refresh)
+	if err != nil || manifest == nil || len(manifest.Chunks) <= 1 {
+		return "", false, nil
+	}
+
+	selected, err := compressionService.SelectChunks(manifest.Chunks, manifest.Entries, paths, patterns)
+	if err != nil {
+		return "", false, err
+	}
+	if len(selected) == 0 {
+		return "", false, fmt.Errorf("none of the listed paths or patterns are in the backup")
+	}
+	var size, total int64
+	for _, chunk := range selected {
+		size += chunk.Size
+	}
+	for _, chunk := range manifest.Chunks {
+		total += chunk.Size
+	}
+
+	fmt.Printf("Fetching %d of %d chunks (%s of %s) from %s...\n", len(selected), len(manifest.Chunks),
+		compressionService.FormatFileSize(size), compressionService.FormatFileSize(total), remote)
+	archivePath, err := fetchChunks(remote, selected, tmpDir, passphrase)
+	if errors.Is(err, storageService.ErrRangeUnsupported) {
+		fmt.Printf("%s%s cannot read part of a file, fetching the whole backup%s\n", ColorDim, remote.Scheme, ColorReset)
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return archivePath, true, nil
+}
+
+// fetchChunks downloads the chunks of a remote backup, decrypting each one of an encrypted
+// backup, and joins them into one archive in tmpDir. Returns its path.
+// Returns storageService.ErrRangeUnsupported if the location cannot read part of a file.
+func fetchChunks(remote *storageService.Remote, chunks []compressionService.ArchiveChunk, tmpDir, passphrase string) (string, error) {
+	archivePath := filepath.Join(tmpDir, backupService.BackupBaseName(remote.Base())+".tar.gz")
+	archive, err := os.Create(archivePath)
+	if err != nil {
+		return "", fmt.Errorf("error creating %s: %w", archivePath, err)
+	}
+	defer archive.Close()
+
+	encrypted := strings.HasSuffix(remote.Base(), ".gpg")
+	for i, chunk := range chunks {
+		partPath := filepath.Join(tmpDir, fmt.Sprintf("chunk%d.tar.gz", i))
+		if encrypted {
+			partPath += ".gpg"
+		}
+		if err := storageService.DownloadRange(remote, chunk.Offset, chunk.Size, partPath); err != nil {
+			return "", err
+		}
+		if encrypted {
+			plainPath, err := encryptionService.GPGDecrypt(partPath, strings.TrimSuffix(partPath, ".gpg"), passphrase)
+			if err != nil {
+				return "", err
+			}
+			os.Remove(partPath)
+			partPath = plainPath
+		}
+		if err := appendChunk(archive, partPath); err != nil {
+			return "", err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return "", fmt.Errorf("error writing %s: %w", archivePath, err)
+	}
+	return archivePath, nil
+}
+
+// appendChunk copies a downloaded chunk to the end of the archive and removes it
+func appendChunk(archive io.Writer, partPath string) error {
+	part, err := os.Open(partPath)
+	if err != nil {
+		return fmt.Errorf("error reading chunk: %w", err)
+	}
+	defer os.Remove(partPath)
+	defer part.Close()
+	if _, err := io.Copy(archive, part); err != nil {
+		return fmt.Errorf("error writing chunk: %w", err)
+	}
+	return nil
+}
+
+// archiveChunks returns the chunks recorded in the manifest next to a local archive, or nil if
+// the archive is stored in one piece or its manifest cannot be read
+func archiveChunks(archiveFile, passphrase string) []compressionService.ArchiveChunk {
+	tmpDir, err := os.MkdirTemp("", "go-backup-manifest-")
+	if err != nil {
+		return nil
+	}
+	defer os.RemoveAll(tmpDir)
+
+	manifest, err := findBackupManifest(archiveFile, tmpDir, passphrase, false)
+	if err != nil || manifest == nil {
+		return nil
+	}
+	return manifest.Chunks
+}
@@ -3,8 +3,10 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	configService "github.com/kennycyb/go-backup/internal/service/config"
+	encryptionService "github.com/kennycyb/go-backup/internal/service/encrypt"
 	"github.com/spf13/cobra"
 )
 
@@ -15,6 +17,8 @@ var (
 	gpgReceiver       string // GPG recipient email address for encryption
 	deleteTarget      string // Target path to remove from backup configuration
 	addTarget         string // Target path to add to backup configuration
+	cloneOverwrite    bool   // Flag to overwrite an existing config in the clone destination
+	testEncryption    bool   // Flag to run an encrypt/decrypt roundtrip with the configured recipient
 )
 
 // configCmd represents the config command for managing backup settings
@@ -29,7 +33,8 @@ Examples:
   go-backup config --add-target /path/to/directory
   go-backup config --delete-target /path/to/directory
   go-backup config --enable-encryption --gpg-receiver user@example.com
-  go-backup config --disable-encryption`,
+  go-backup config --disable-encryption
+  go-backup config --test-encryption`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Determine configuration file path - use custom path if provided, otherwise default
 		configFile := ".backup.yaml"
@@ -51,6 +56,12 @@ Examples:
 			return
 		}
 
+		// Check the configured encryption without changing anything
+		if testEncryption {
+			runEncryptionTest(config)
+			return
+		}
+
 		// Initialize variable to track if any configuration changes are made
 		configChanged := false
 
@@ -117,17 +128,193 @@ Examples:
 	},
 }
 
+// configCloneCmd copies the current configuration into another project directory
+var configCloneCmd = &cobra.Command{
+	Use:   "clone <directory>",
+	Short: "Copy this configuration to another project",
+	Long: `Copy the current .backup.yaml into another project directory.
+Backup history and last run status are not copied, and target paths that refer
+to the current project (e.g. /mnt/nas/foo) are rewritten for the new project
+(/mnt/nas/bar). The new location is registered in the global registry
+if it exists.
+
+Example:
+  go-backup config clone ~/projects/other`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		configFile := ".backup.yaml"
+		if cfgFile != "" {
+			configFile = cfgFile
+		}
+
+		config, err := configService.ReadBackupConfig(configFile)
+		if err != nil {
+			fmt.Printf("Error reading configuration file: %v\n", err)
+			os.Exit(1)
+		}
+
+		fromDir, err := filepath.Abs(filepath.Dir(configFile))
+		if err != nil {
+			fmt.Printf("Error resolving configuration directory: %v\n", err)
+			os.Exit(1)
+		}
+		toDir, err := filepath.Abs(args[0])
+		if err != nil {
+			fmt.Printf("Error resolving destination directory: %v\n", err)
+			os.Exit(1)
+		}
+
+		if info, err := os.Stat(toDir); err != nil || !info.IsDir() {
+			fmt.Printf("Error: Destination '%s' is not a directory.\n", toDir)
+			os.Exit(1)
+		}
+
+		destConfigFile := filepath.Join(toDir, ".backup.yaml")
+		if _, err := os.Stat(destConfigFile); err == nil && !cloneOverwrite {
+			fmt.Printf("Error: Configuration file '%s' already exists.\n", destConfigFile)
+			fmt.Println("Use --overwrite to replace it.")
+			os.Exit(1)
+		}
+
+		// Do not spread a config that cannot back up anything
+		if _, err := configService.ResolveTargets(config, configService.TargetFlags{}); err != nil {
+			fmt.Printf("Error: %s: %v\n", configFile, err)
+			os.Exit(1)
+		}
+
+		clone := configService.CloneConfig(config, fromDir, toDir)
+		if err := configService.WriteBackupConfig(destConfigFile, clone); err != nil {
+			fmt.Printf("Error writing configuration file: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Configuration cloned to '%s'.\n", destConfigFile)
+		for _, target := range clone.Targets {
+			fmt.Printf("  Target: %s\n", target.GetDestination())
+		}
+
+		if err := configService.RegisterGlobalLocation(toDir); err != nil {
+			fmt.Printf("Warning: Failed to register '%s' in global backup registry: %v\n", toDir, err)
+		}
+	},
+}
+
+// configValidateCmd checks the configuration strictly, including for unknown keys
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration for unknown keys and invalid settings",
+	Long: `Check .backup.yaml (or --config) without running a backup. Unlike other
+commands, keys go-backup does not know are reported, with the key that was
+probably meant, e.g. maxBackup instead of maxBackups, so that typos are not
+silently ignored. Use --strict-config to reject them in every command.
+
+Exits with status 1 if the configuration has problems.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		configFile := ".backup.yaml"
+		if cfgFile != "" {
+			configFile = cfgFile
+		}
+
+		config, err := configService.ValidateConfigFile(configFile)
+		if err != nil {
+			fmt.Printf("%s: %v\n", configFile, err)
+			os.Exit(1)
+		}
+
+		var problems []string
+		if _, err := configService.ResolveTargets(config, configService.TargetFlags{}); err != nil {
+			problems = append(problems, err.Error())
+		}
+		for _, check := range []func() error{
+			func() error { _, err := config.Options.RunTimeout(); return err },
+			func() error { _, err := config.Options.SkipUnreadable(); return err },
+			func() error { _, err := config.Options.PowerPolicy(); return err },
+			func() error { _, err := config.Options.CompressionLevel(); return err },
+			func() error { _, err := config.Options.ArchiveFormat(); return err },
+			func() error { _, err := config.Options.Naming(); return err },
+			func() error { _, err := configService.EncryptionRecipients(config); return err },
+			func() error { return configService.CheckEncryptionReceivers(config) },
+		} {
+			if err := check(); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+		if len(problems) > 0 {
+			fmt.Printf("%s: invalid config:\n", configFile)
+			for _, problem := range problems {
+				fmt.Printf("  %s\n", problem)
+			}
+			os.Exit(1)
+		}
+		fmt.Printf("%s is valid.\n", configFile)
+	},
+}
+
 // init initializes the config command with its flags and adds it to the root command
 func init() {
 	// Add config command to root command tree
 	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configCloneCmd, configValidateCmd)
+	configCloneCmd.Flags().BoolVar(&cloneOverwrite, "overwrite", false, "Overwrite an existing configuration file in the destination")
 
 	// Define encryption-related flags
 	configCmd.Flags().BoolVar(&enableEncryption, "enable-encryption", false, "Enable encryption for backups")
 	configCmd.Flags().BoolVar(&disableEncryption, "disable-encryption", false, "Disable encryption for backups")
 	configCmd.Flags().StringVar(&gpgReceiver, "gpg-receiver", "", "GPG recipient email for encryption")
+	configCmd.Flags().BoolVar(&testEncryption, "test-encryption", false, "Encrypt and decrypt test data with the configured GPG recipient")
 
 	// Define target management flags
 	configCmd.Flags().StringVar(&deleteTarget, "delete-target", "", "Delete a target from the configuration")
 	configCmd.Flags().StringVar(&addTarget, "add-target", "", "Add a new backup target to the configuration")
 }
+
+// runEncryptionTest encrypts and decrypts test data for every GPG recipient in the config,
+// including per-target overrides (or just --gpg-receiver), and reports where each secret key lives.
+// It exits with a non-zero status if any recipient fails, so it can gate scheduled jobs.
+func runEncryptionTest(config *configService.BackupConfig) {
+	recipients := []string{gpgReceiver}
+	if gpgReceiver == "" {
+		var err error
+		recipients, err = configService.EncryptionRecipients(config)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if len(recipients) == 0 {
+		fmt.Println("Error: No GPG recipient configured. Use --gpg-receiver or 'go-backup config --enable-encryption'.")
+		os.Exit(1)
+	}
+
+	failed := 0
+	for _, recipient := range recipients {
+		fmt.Printf("Testing GPG encryption for recipient: %s\n", recipient)
+		result, err := encryptionService.GPGRoundtrip(recipient)
+		if err != nil {
+			fmt.Printf("  Encryption test failed: %v\n", err)
+			failed++
+			continue
+		}
+
+		fmt.Println("  Encryption: OK")
+		if result.Key.OnCard {
+			fmt.Printf("  Secret key: on smartcard %s\n", result.Key.CardSerial)
+			fmt.Println("  Backups are encrypted with the public key only, so scheduled runs need no PIN.")
+			fmt.Println("  Restoring needs the card; without a terminal the PIN must already be cached in gpg-agent.")
+		} else if result.Key.Available {
+			fmt.Println("  Secret key: in keyring")
+		}
+
+		if result.Decrypted {
+			fmt.Println("  Decryption: OK")
+		} else {
+			fmt.Printf("  Decryption: skipped (%s)\n", result.SkipReason)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("Encryption test failed for %d of %d recipient(s).\n", failed, len(recipients))
+		os.Exit(1)
+	}
+	fmt.Println("Encryption test passed.")
+}
@@ -0,0 +1,408 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	encryptionService "github.com/kennycyb/go-backup/internal/service/encrypt"
+	gitService "github.com/kennycyb/go-backup/internal/service/git"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
+	"github.com/spf13/cobra"
+)
+
+// doctorRemoteTimeout limits how long the reachability check of a single remote target may take
+const doctorRemoteTimeout = 30 * time.Second
+
+// doctorKeyExpiryWarning is how long before a GPG key expires the doctor starts warning about it
+const doctorKeyExpiryWarning = 30 * 24 * time.Hour
+
+// doctorReport prints the result of each check and counts the problems found
+type doctorReport struct {
+	failures int
+	warnings int
+}
+
+func (r *doctorReport) ok(name, detail string) {
+	fmt.Printf("  %s%s %s:%s %s\n", ColorGreen, plainMark("✅", "OK"), name, ColorReset, detail)
+}
+
+func (r *doctorReport) warn(name, detail, fix string) {
+	r.warnings++
+	fmt.Printf("  %s%s %s:%s %s\n", ColorYellow, plainMark("⚠️ ", "WARNING"), name, ColorReset, detail)
+	if fix != "" {
+		fmt.Printf("     %sFix:%s %s\n", ColorDim, ColorReset, fix)
+	}
+}
+
+func (r *doctorReport) fail(name, detail, fix string) {
+	r.failures++
+	fmt.Printf("  %s%s %s:%s %s\n", ColorRed, plainMark("❌", "FAILED"), name, ColorReset, detail)
+	if fix != "" {
+		fmt.Printf("     %sFix:%s %s\n", ColorDim, ColorReset, fix)
+	}
+}
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that the environment is ready for backups",
+	Long: `Check everything a backup run depends on and print a fix for each problem:
+
+  • the configuration file and the global registry are valid
+  • gpg is installed and every encryption key can be used
+  • git is installed and the source is a repository when options.git is enabled
+  • the temp directory is writable
+  • every backup target exists and is writable, or is reachable for remote targets
+
+Exits with a non-zero status if any check fails, so it can gate scheduled jobs.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		doctorSource := source
+		if doctorSource == "" {
+			var err error
+			doctorSource, err = os.Getwd()
+			if err != nil {
+				fmt.Printf("%s%s❌ Error getting current directory:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+				os.Exit(1)
+			}
+		}
+
+		configPath := filepath.Join(doctorSource, ".backup.yaml")
+		if cfgFile != "" {
+			configPath = cfgFile
+		}
+
+		fmt.Printf("%s%s🩺 Checking backup environment for %s%s\n", ColorCyan, ColorBold, doctorSource, ColorReset)
+		report := &doctorReport{}
+
+		fmt.Printf("\n%sConfiguration%s\n", ColorBold, ColorReset)
+		config := checkDoctorConfig(report, configPath)
+		checkDoctorRegistry(report)
+
+		fmt.Printf("\n%sTools%s\n", ColorBold, ColorReset)
+		checkDoctorTempDir(report)
+		if config != nil {
+			checkDoctorEncryption(report, config)
+			checkDoctorGit(report, config, doctorSource)
+		}
+
+		if config != nil {
+			fmt.Printf("\n%sTargets%s\n", ColorBold, ColorReset)
+			checkDoctorTargets(report, config)
+		}
+
+		fmt.Println()
+		switch {
+		case report.failures > 0:
+			fmt.Printf("%s%s❌ %d check(s) failed, %d warning(s)%s\n", ColorRed, ColorBold, report.failures, report.warnings, ColorReset)
+			os.Exit(1)
+		case report.warnings > 0:
+			fmt.Printf("%s%s⚠️  All checks passed with %d warning(s)%s\n", ColorYellow, ColorBold, report.warnings, ColorReset)
+		default:
+			fmt.Printf("%s%s✅ All checks passed%s\n", ColorGreen, ColorBold, ColorReset)
+		}
+	},
+}
+
+// checkDoctorConfig reads and validates the config file the way the run command would.
+// Returns nil if the config cannot be read, since the remaining checks depend on it.
+func checkDoctorConfig(report *doctorReport, configPath string) *configService.BackupConfig {
+	config, err := configService.ReadBackupConfig(configPath)
+	if os.IsNotExist(err) {
+		report.fail("Config", fmt.Sprintf("%s does not exist", configPath), "run 'go-backup init' to create it")
+		return nil
+	}
+	if err != nil {
+		report.fail("Config", err.Error(), fmt.Sprintf("fix the YAML syntax in %s", configPath))
+		return nil
+	}
+
+	problems := 0
+	invalid := func(detail, fix string) {
+		problems++
+		report.fail("Config", detail, fix)
+	}
+
+	if err := compressionService.ValidateExcludes(config.Excludes); err != nil {
+		invalid(fmt.Sprintf("excludes: %v", err), "correct or remove the pattern")
+	}
+	if err := compressionService.ValidateExcludes(config.Includes); err != nil {
+		invalid(fmt.Sprintf("includes: %v", err), "correct or remove the pattern")
+	}
+	if err := compressionService.ValidateExcludes(config.NoCompress); err != nil {
+		invalid(fmt.Sprintf("noCompress: %v", err), "correct or remove the pattern")
+	}
+	if config.Options != nil {
+		if err := compressionService.ValidateExcludes(config.Options.HiddenAllow); err != nil {
+			invalid(fmt.Sprintf("options.hiddenAllow: %v", err), "correct or remove the pattern")
+		}
+	}
+	if _, err := config.Options.RunTimeout(); err != nil {
+		invalid(err.Error(), "set options.timeout to a duration like 2h, or remove it")
+	}
+	if _, err := config.Options.PowerPolicy(); err != nil {
+		invalid(err.Error(), "set options.minBattery to a percentage from 0 to 100, or remove it")
+	}
+	if _, err := config.Options.CompressionLevel(); err != nil {
+		invalid(err.Error(), "set options.compression.level to a gzip level from 1 to 9, or remove it")
+	}
+	if _, err := config.Options.ArchiveFormat(); err != nil {
+		invalid(err.Error(), "set options.compression.format to tar.gz, tar or zip, or remove it")
+	}
+	if _, err := config.Options.SkipUnreadable(); err != nil {
+		invalid(err.Error(), fmt.Sprintf("set options.onError to %q or %q", configService.OnErrorSkip, configService.OnErrorFail))
+	}
+	if _, err := configService.EncryptionRecipients(config); err != nil {
+		invalid(err.Error(), "set encryption.method to \"gpg\" or \"none\"")
+	}
+	if _, err := configService.ResolveTargets(config, configService.TargetFlags{}); err != nil {
+		invalid(err.Error(), "add a target with 'go-backup config --add-target <dir>'")
+	}
+	// Unknown keys are ignored unless --strict-config is given, so they only warn here
+	var unknownKeys *configService.UnknownKeysError
+	if _, err := configService.ValidateConfigFile(configPath); errors.As(err, &unknownKeys) {
+		for _, problem := range unknownKeys.Problems {
+			problems++
+			report.warn("Config", problem, "correct or remove the key, then check with 'go-backup config validate'")
+		}
+	}
+
+	if problems == 0 {
+		report.ok("Config", fmt.Sprintf("%s is valid", configPath))
+	}
+	return config
+}
+
+// checkDoctorRegistry validates the schedules and locations in the global registry used by run-all
+func checkDoctorRegistry(report *doctorReport) {
+	registryPath, err := configService.GlobalRegistryPath()
+	if err != nil {
+		report.fail("Registry", err.Error(), "set HOME to your home directory")
+		return
+	}
+	if _, err := os.Stat(registryPath); os.IsNotExist(err) {
+		report.ok("Registry", "no locations registered for run-all")
+		return
+	}
+
+	registry, err := configService.ReadGlobalRegistry()
+	if err != nil {
+		report.fail("Registry", err.Error(), "fix the YAML syntax in "+registryPath)
+		return
+	}
+	if legacyPath, err := configService.LegacyGlobalRegistryPath(); err == nil && registryPath == legacyPath {
+		report.warn("Registry", fmt.Sprintf("%s is the old location of the registry", registryPath), "move it with 'go-backup registry migrate'")
+	}
+
+	problems := 0
+	if _, err := registry.JitterDuration(); err != nil {
+		problems++
+		report.fail("Registry", err.Error(), "correct the jitter in "+registryPath)
+	}
+	if _, _, err := configService.InBlackout(registry.Blackout, time.Now()); err != nil {
+		problems++
+		report.fail("Registry", err.Error(), "correct the blackout windows in "+registryPath)
+	}
+	for _, entry := range registry.Backups {
+		if _, err := entry.ScheduleInterval(); err != nil {
+			problems++
+			report.fail("Registry", fmt.Sprintf("%s: %v", entry.Location, err), "correct the schedule in "+registryPath)
+		}
+		if _, _, err := configService.InBlackout(entry.Blackout, time.Now()); err != nil {
+			problems++
+			report.fail("Registry", fmt.Sprintf("%s: %v", entry.Location, err), "correct the blackout windows in "+registryPath)
+		}
+		if _, err := os.Stat(entry.ConfigPath()); err != nil && entry.IsEnabled() {
+			problems++
+			report.warn("Registry", fmt.Sprintf("%s: %s is missing, run-all will fail for it", entry.Location, filepath.Base(entry.ConfigPath())),
+				"remove the location from "+registryPath+" or set enabled: false")
+		}
+	}
+
+	if problems == 0 {
+		report.ok("Registry", fmt.Sprintf("%d location(s) registered", len(registry.Backups)))
+	}
+}
+
+// checkDoctorTempDir checks that archives can be staged in the temp directory
+func checkDoctorTempDir(report *doctorReport) {
+	tempDir := os.TempDir()
+	file, err := os.CreateTemp(tempDir, "go-backup-doctor-*")
+	if err != nil {
+		report.fail("Temp dir", fmt.Sprintf("%s is not writable: %v", tempDir, err), "set TMPDIR to a writable directory with enough free space")
+		return
+	}
+	file.Close()
+	os.Remove(file.Name())
+	report.ok("Temp dir", fmt.Sprintf("%s is writable", tempDir))
+}
+
+// checkDoctorEncryption checks that gpg is installed and that every recipient's key can encrypt.
+// A missing secret key is only reported, since backup machines often hold just the public key.
+func checkDoctorEncryption(report *doctorReport, config *configService.BackupConfig) {
+	recipients, err := configService.EncryptionRecipients(config)
+	if err != nil || len(recipients) == 0 {
+		report.ok("GPG", "encryption is not enabled")
+		return
+	}
+
+	if _, err := exec.LookPath("gpg"); err != nil {
+		report.fail("GPG", "gpg not found in PATH", "install GnuPG, e.g. 'brew install gnupg' or 'apt install gnupg'")
+		return
+	}
+
+	for _, recipient := range recipients {
+		name := fmt.Sprintf("GPG key %s", recipient)
+		key, err := encryptionService.GPGPublicKeyStatus(recipient)
+		if err != nil {
+			report.fail(name, err.Error(), "check that gpg runs, e.g. 'gpg --list-keys'")
+			continue
+		}
+
+		switch {
+		case !key.Found:
+			report.fail(name, "no public key in the keyring", fmt.Sprintf("import it with 'gpg --import <key file>' or 'gpg --locate-keys %s'", recipient))
+			continue
+		case key.Revoked:
+			report.fail(name, "the key has been revoked", "switch to a new key with 'go-backup config --enable-encryption --gpg-receiver <key>'")
+			continue
+		case key.Expired:
+			report.fail(name, fmt.Sprintf("the key expired on %s", key.Expires.Format("2006-01-02")),
+				"extend it with 'gpg --quick-set-expire <fingerprint> 1y' and re-export it, or switch to a new key")
+			continue
+		case !key.CanEncrypt:
+			report.fail(name, "the key has no usable encryption subkey", "add one with 'gpg --quick-add-key <fingerprint> default encr' and re-export it")
+			continue
+		}
+
+		detail := "can encrypt"
+		if secret, err := encryptionService.GPGSecretKeyStatus(recipient); err == nil {
+			switch {
+			case secret.OnCard:
+				detail += fmt.Sprintf(", secret key on smartcard %s", secret.CardSerial)
+			case secret.Available:
+				detail += ", secret key in keyring"
+			default:
+				detail += ", secret key not on this machine (needed to restore)"
+			}
+		}
+
+		if !key.Expires.IsZero() && time.Until(key.Expires) < doctorKeyExpiryWarning {
+			report.warn(name, fmt.Sprintf("%s, but expires on %s", detail, key.Expires.Format("2006-01-02")),
+				"extend it with 'gpg --quick-set-expire <fingerprint> 1y' and re-export it")
+			continue
+		}
+		report.ok(name, detail)
+	}
+}
+
+// checkDoctorGit checks the requirements of smart backups when options.git is enabled
+func checkDoctorGit(report *doctorReport, config *configService.BackupConfig, source string) {
+	if config.Options == nil || !config.Options.Git.Enable {
+		report.ok("Git", "smart backups are not enabled")
+		return
+	}
+
+	if err := gitService.SetImplementation(config.Options.Git.Implementation); err != nil {
+		report.fail("Git", err.Error(), "set options.git.implementation to git or go-git, or remove it")
+		return
+	}
+	// Without git, the go-git built into go-backup is used unless the git binary was asked for
+	if _, err := exec.LookPath("git"); err != nil && config.Options.Git.Implementation == gitService.ImplementationGit {
+		report.fail("Git", "git not found in PATH, but options.git.implementation is git", "install git, or remove options.git.implementation to use go-git")
+		return
+	}
+
+	if _, err := gitService.HasUncommittedChanges(source); err != nil {
+		report.fail("Git", fmt.Sprintf("cannot check %s for changes: %v", source, err), "run 'git init', or set options.git.enable to false")
+		return
+	}
+
+	if config.Options.Git.Pull == "auto" && config.Options.Git.Branch != "" {
+		branch, err := gitService.GetCurrentBranch(source)
+		if err != nil {
+			report.warn("Git", fmt.Sprintf("auto-pull is configured, but %v", err), "commit once so the branch exists, or remove options.git.pull")
+			return
+		}
+		if branch != config.Options.Git.Branch {
+			report.warn("Git", fmt.Sprintf("on branch '%s', but auto-pull is configured for '%s'", branch, config.Options.Git.Branch),
+				fmt.Sprintf("check out '%s', or update options.git.branch", config.Options.Git.Branch))
+			return
+		}
+		report.ok("Git", fmt.Sprintf("repository on branch '%s', auto-pull enabled", branch))
+		return
+	}
+	report.ok("Git", "source is a git repository")
+}
+
+// checkDoctorTargets checks that each local target is writable and each remote target is reachable
+func checkDoctorTargets(report *doctorReport, config *configService.BackupConfig) {
+	targets, err := configService.ResolveTargets(config, configService.TargetFlags{})
+	if err != nil {
+		// Already reported by the config check
+		return
+	}
+
+	for _, target := range targets {
+		dest := target.GetDestination()
+
+		if storageService.IsRemote(dest) {
+			remote, err := storageService.ParseRemote(dest)
+			if err != nil {
+				report.fail(dest, err.Error(), "correct the target URL")
+				continue
+			}
+			if target.IsFileTarget() {
+				remote = remote.Dir()
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), doctorRemoteTimeout)
+			_, err = storageService.ListContext(ctx, remote)
+			cancel()
+			if err != nil {
+				report.fail(dest, fmt.Sprintf("not reachable: %v", err), "install the tool for this scheme and check its credentials and network access")
+				continue
+			}
+			report.ok(dest, "reachable")
+			continue
+		}
+
+		dir := dest
+		if target.IsFileTarget() {
+			dir = filepath.Dir(dest)
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			if target.IsFileTarget() && os.IsNotExist(err) {
+				report.ok(dest, fmt.Sprintf("%s will be created on the first run", dir))
+				continue
+			}
+			report.fail(dest, fmt.Sprintf("%s does not exist, so the target is skipped", dir), fmt.Sprintf("mkdir -p %s, or mount the drive", dir))
+			continue
+		}
+		if !info.IsDir() {
+			report.fail(dest, fmt.Sprintf("%s is not a directory", dir), "point the target at a directory, or use file: for a single file")
+			continue
+		}
+
+		file, err := os.CreateTemp(dir, ".go-backup-doctor-*")
+		if err != nil {
+			report.fail(dest, fmt.Sprintf("not writable: %v", err), fmt.Sprintf("check the permissions of %s", dir))
+			continue
+		}
+		file.Close()
+		os.Remove(file.Name())
+		report.ok(dest, "writable")
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().StringVarP(&source, "source", "s", "", "Source directory to check (defaults to current directory)")
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	driftAll        bool
+	driftExitCode   bool
+	driftPassphrase string
+)
+
+// driftMaxEntries is how many files at risk drift lists without --all
+const driftMaxEntries = 20
+
+// driftCmd compares the source with its latest backup
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Show which files in the source the latest backup is missing",
+	Long: `Compare the newest backup in the history of the configured targets with the
+source, and list the files that are new or changed since: if the disk failed
+now, only the backed up versions would survive. Files deleted since the backup
+are counted as well.
+
+Files are compared by checksum. The checksum cache of metadata snapshots is
+used, so only files changed since the last run are read.
+
+With --exit-code, drift exits with status 1 when files are at risk, e.g. to
+start a backup right away:
+
+  go-backup drift --exit-code >/dev/null || go-backup run`,
+	Run: func(cmd *cobra.Command, args []string) {
+		driftSource := source
+		if driftSource == "" {
+			var err error
+			driftSource, err = os.Getwd()
+			if err != nil {
+				fmt.Printf("%s%s❌ Error getting current directory:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+				os.Exit(1)
+			}
+		}
+		driftSource, err := filepath.Abs(driftSource)
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		configPath := filepath.Join(driftSource, ".backup.yaml")
+		if cfgFile != "" {
+			configPath = cfgFile
+		}
+
+		config, err := configService.ReadBackupConfig(configPath)
+		if err != nil {
+			fmt.Printf("%s%s❌ Error reading config file %s:%s %v\n", ColorRed, ColorBold, configPath, ColorReset, err)
+			os.Exit(1)
+		}
+		targets, err := configService.ResolveTargets(config, configService.TargetFlags{})
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+
+		location, record, ok := latestBackup(targets)
+		if !ok {
+			fmt.Printf("%s⚠️  No backups recorded yet: everything in %s is at risk%s\n", ColorYellow, driftSource, ColorReset)
+			if driftExitCode {
+				os.Exit(1)
+			}
+			return
+		}
+
+		tmpDir, err := os.MkdirTemp("", "go-backup-drift-")
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		defer os.RemoveAll(tmpDir)
+
+		passphrase := driftPassphrase
+		if passphrase == "" && config.Encryption != nil {
+			passphrase = config.Encryption.Passphrase
+		}
+		var manifest *backupService.Manifest
+		if record.SnapshotOf != "" {
+			manifest, err = backupService.ReadManifest(location)
+		} else {
+			manifest, err = loadBackupManifest(location, tmpDir, passphrase)
+		}
+		if err != nil {
+			fmt.Printf("%s%s❌ Error reading the manifest of %s:%s %v\n", ColorRed, ColorBold, location, ColorReset, err)
+			os.RemoveAll(tmpDir)
+			os.Exit(1)
+		}
+
+		excludes, err := driftExcludes(config, driftSource, targets)
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.RemoveAll(tmpDir)
+			os.Exit(1)
+		}
+		// The config records every run, so it never matches the copy in the backup
+		if relPath, err := filepath.Rel(driftSource, configPath); err == nil && !strings.HasPrefix(relPath, "..") {
+			excludes = append(excludes, filepath.ToSlash(relPath))
+			manifest.Entries = withoutEntry(manifest.Entries, filepath.ToSlash(relPath))
+		}
+
+		// Unreadable files are left out, as a run skips them by default
+		options := compressionService.ArchiveOptions{Excludes: excludes, SkipUnreadable: true}
+		if cachePath, err := checksumCachePath(driftSource); err == nil {
+			options.Checksums = compressionService.LoadHashCache(cachePath)
+		}
+		scan, err := compressionService.ScanSourceContext(context.Background(), driftSource, options)
+		if err != nil {
+			fmt.Printf("%s%s❌ Error scanning %s:%s %v\n", ColorRed, ColorBold, driftSource, ColorReset, err)
+			os.RemoveAll(tmpDir)
+			os.Exit(1)
+		}
+		if options.Checksums != nil {
+			if err := options.Checksums.Save(); err != nil {
+				fmt.Printf("%s⚠️  Warning: Failed to update the checksum cache:%s %v\n", ColorYellow, ColorReset, err)
+			}
+		}
+
+		drift := backupService.CompareWithSource(manifest, scan.Entries)
+		fmt.Printf("\n%s%s🔍 Drift of %s%s\n", ColorCyan, ColorBold, driftSource, ColorReset)
+		fmt.Printf("  Backup:  %s (%s ago)\n", location, formatTimeSince(time.Since(record.CreatedAt)))
+		if record.SnapshotOf != "" {
+			fmt.Printf("  %sMetadata snapshot of %s%s\n", ColorDim, record.SnapshotOf, ColorReset)
+		}
+		fmt.Println()
+
+		if !drift.AtRisk() {
+			fmt.Printf("%s✅ Every file in the source is in the backup%s\n", ColorGreen, ColorReset)
+		} else {
+			fmt.Printf("%s%s⚠️  %d new and %d modified path(s) are not in the backup:%s\n",
+				ColorYellow, ColorBold, len(drift.Added), len(drift.Modified), ColorReset)
+			shown := 0
+			for _, change := range []struct {
+				marker  string
+				entries []compressionService.ArchiveEntry
+			}{{"+", drift.Added}, {"~", drift.Modified}} {
+				for _, entry := range change.entries {
+					if !driftAll && shown == driftMaxEntries {
+						break
+					}
+					printDriftEntry(change.marker, entry)
+					shown++
+				}
+			}
+			if total := len(drift.Added) + len(drift.Modified); shown < total {
+				fmt.Printf("  %s... and %d more (use --all to see all)%s\n", ColorDim, total-shown, ColorReset)
+			}
+		}
+		if len(drift.Removed) > 0 {
+			fmt.Printf("%s%d path(s) deleted since the backup are still in it%s\n", ColorDim, len(drift.Removed), ColorReset)
+		}
+		printWarnings(scan.Warnings)
+
+		if drift.AtRisk() {
+			fmt.Printf("\n%sRun 'go-backup run' to back them up%s\n", ColorDim, ColorReset)
+			if driftExitCode {
+				os.RemoveAll(tmpDir)
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	driftCmd.Flags().StringVarP(&source, "source", "s", "", "Source directory to compare (defaults to current directory)")
+	driftCmd.Flags().BoolVarP(&driftAll, "all", "a", false, "List every file at risk")
+	driftCmd.Flags().BoolVar(&driftExitCode, "exit-code", false, "Exit with status 1 when files are not in the backup")
+	driftCmd.Flags().StringVar(&driftPassphrase, "passphrase", "", "Passphrase for GPG decryption of the manifest (if needed)")
+	rootCmd.AddCommand(driftCmd)
+}
+
+// latestBackup returns the location of the newest backup in the targets' histories with its record.
+// For a metadata snapshot the location is its manifest. Local backups that are gone are left out.
+func latestBackup(targets []configService.ResolvedTarget) (string, configService.BackupRecord, bool) {
+	var latest configService.BackupRecord
+	var latestLocation string
+	for _, target := range targets {
+		dest := target.GetDestination()
+		for _, record := range target.Backups {
+			if latestLocation != "" && !record.CreatedAt.After(latest.CreatedAt) {
+				continue
+			}
+
+			location := dest
+			if storageService.IsRemote(dest) {
+				remote, err := storageService.ParseRemote(dest)
+				if err != nil {
+					break
+				}
+				if !target.IsFileTarget() {
+					location = remote.Join(record.Filename).String()
+				}
+			} else {
+				if !target.IsFileTarget() {
+					location = filepath.Join(dest, record.Filename)
+				}
+				if _, err := os.Stat(location); err != nil {
+					continue
+				}
+			}
+			latest, latestLocation = record, location
+		}
+	}
+	return latestLocation, latest, latestLocation != ""
+}
+
+// driftExcludes returns the exclude patterns a run of the config would archive the source with
+func driftExcludes(config *configService.BackupConfig, driftSource string, targets []configService.ResolvedTarget) ([]string, error) {
+	excludes := excludeDirs
+	if len(config.Excludes) > 0 {
+		excludes = config.Excludes
+	}
+	if config.Options != nil && config.Options.IncludeVCS {
+		excludes = compressionService.WithoutVCS(excludes)
+	}
+	excludes = withoutHidden(config.Options, excludes, false)
+	if err := compressionService.ValidateExcludes(excludes); err != nil {
+		return nil, err
+	}
+
+	destinations := []string{}
+	for _, target := range targets {
+		destinations = append(destinations, target.GetDestination())
+	}
+	selfExcludes, err := backupService.DestinationsInSource(driftSource, destinations)
+	if err != nil {
+		return nil, err
+	}
+	excludes = append(append([]string{}, excludes...), selfExcludes...)
+	return compressionService.IncludeOnly(config.Includes, excludes), nil
+}
+
+// withoutEntry returns the entries without the one at path
+func withoutEntry(entries []compressionService.ArchiveEntry, path string) []compressionService.ArchiveEntry {
+	kept := make([]compressionService.ArchiveEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Path != path {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// printDriftEntry prints a file at risk with its marker: + for new and ~ for modified
+func printDriftEntry(marker string, entry compressionService.ArchiveEntry) {
+	name := compressionService.QuotePath(entry.Path)
+	if entry.IsDir {
+		fmt.Printf("  %s %s/\n", marker, name)
+		return
+	}
+	fmt.Printf("  %s %s %s(%s)%s\n", marker, name, ColorDim, compressionService.FormatFileSize(entry.Size), ColorReset)
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	duLast int
+	duWide bool
+)
+
+// duCmd reports how much space the backups take on each target
+var duCmd = &cobra.Command{
+	Use:   "du",
+	Short: "Report the space backups take on each target",
+	Long: `Report for every configured target how many archives each source has on it,
+their total and average size, and how the size changed over the last
+--last archives.
+
+Targets are scanned, remote ones too, so archives of other sources sharing
+the target are counted as well. Archives in the backup history that are not
+found are reported as missing; a target that cannot be scanned, e.g. an
+unmounted drive, is reported from the history alone.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if duLast < 2 {
+			fmt.Printf("%s%s❌ Error:%s --last must be at least 2 to measure growth\n", ColorRed, ColorBold, ColorReset)
+			os.Exit(1)
+		}
+
+		configPath := ".backup.yaml"
+		if cfgFile != "" {
+			configPath = cfgFile
+		}
+		config, err := configService.ReadBackupConfig(configPath)
+		if err != nil {
+			fmt.Printf("%s%s❌ Error reading config file %s:%s %v\n", ColorRed, ColorBold, configPath, ColorReset, err)
+			os.Exit(1)
+		}
+		targets, err := configService.ResolveTargets(config, configService.TargetFlags{})
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		naming := listNaming(configPath)
+
+		fmt.Printf("%s%s\n==============================\n   📦  Backup Usage Report    \n==============================%s\n", ColorCyan, ColorBold, ColorReset)
+
+		var totalArchives int
+		var totalBytes int64
+		for _, target := range targets {
+			dest := target.GetDestination()
+			fmt.Printf("\n%s→ %s%s\n", ColorBlue, dest, ColorReset)
+
+			files, err := targetArchives(target)
+			if err != nil {
+				fmt.Printf("  %s⚠️  Could not scan the target, reporting the backup history:%s %v\n", ColorYellow, ColorReset, err)
+			}
+			usages := targetUsage(target, files, err == nil, naming)
+			if len(usages) == 0 {
+				fmt.Printf("  %sNo backups%s\n", ColorDim, ColorReset)
+				continue
+			}
+
+			withMissing := false
+			var archives int
+			var bytes int64
+			for _, usage := range usages {
+				withMissing = withMissing || usage.Missing > 0
+				archives += usage.Archives
+				bytes += usage.Bytes
+			}
+			columns := []tableColumn{
+				{title: "SOURCE", maxWidth: 32},
+				{title: "ARCHIVES", right: true},
+				{title: "TOTAL", right: true},
+				{title: "AVERAGE", right: true},
+				{title: fmt.Sprintf("GROWTH (LAST %d)", duLast), right: true},
+			}
+			if withMissing {
+				columns = append(columns, tableColumn{title: "MISSING", right: true})
+			}
+			usageTable := newTable(duWide, columns...)
+			for _, usage := range usages {
+				usageTable.addRow(
+					tableCell{text: usage.Source, color: ColorGreen},
+					tableCell{text: fmt.Sprint(usage.Archives)},
+					tableCell{text: formatFileSize(usage.Bytes)},
+					tableCell{text: formatFileSize(usage.Average())},
+					growthCell(usage),
+					missingCell(usage.Missing),
+				)
+			}
+			usageTable.print("  ")
+
+			fmt.Printf("  %sTotal:%s %d archive(s), %s", ColorDim, ColorReset, archives, formatFileSize(bytes))
+			if quota, _ := target.QuotaBytes(); quota > 0 {
+				fmt.Printf(" of the %s quota (%d%%)", target.Quota, bytes*100/quota)
+			}
+			fmt.Println()
+			totalArchives += archives
+			totalBytes += bytes
+		}
+
+		fmt.Printf("\n%s%sTotal:%s %d archive(s), %s on %d target(s)\n", ColorCyan, ColorBold, ColorReset, totalArchives, formatFileSize(totalBytes), len(targets))
+	},
+}
+
+// targetArchives lists the backup archives on a target, with their size. A file target holds
+// its one backup file.
+func targetArchives(target configService.ResolvedTarget) ([]backupService.ArchiveFile, error) {
+	dest := target.GetDestination()
+	var files []backupService.ArchiveFile
+
+	switch {
+	case storageService.IsRemote(dest):
+		remote, err := storageService.ParseRemote(dest)
+		if err != nil {
+			return nil, err
+		}
+		if target.IsFileTarget() {
+			remote = remote.Dir()
+		}
+		remoteFiles, err := storageService.List(remote)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range remoteFiles {
+			if target.IsFileTarget() && file.Name != filepath.Base(dest) {
+				continue
+			}
+			files = append(files, backupService.ArchiveFile{Name: file.Name, Size: file.Size})
+		}
+	case target.IsFileTarget():
+		info, err := os.Stat(dest)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, backupService.ArchiveFile{Name: filepath.Base(dest), Size: info.Size()})
+	default:
+		entries, err := os.ReadDir(dest)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !backupService.IsArchiveName(entry.Name()) {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, backupService.ArchiveFile{Name: entry.Name(), Size: info.Size()})
+		}
+	}
+	return files, nil
+}
+
+// targetUsage combines the archives found on a target with its backup history: recorded archives
+// that were not found count as missing. Without a scan the history stands in for the target.
+// A file target is reported as a single archive named after the file.
+func targetUsage(target configService.ResolvedTarget, scanned []backupService.ArchiveFile, scannedOK bool, naming backupService.Naming) []backupService.SourceUsage {
+	files := scanned
+	found := make(map[string]bool, len(scanned))
+	for _, file := range scanned {
+		found[file.Name] = true
+	}
+	for _, record := range target.Backups {
+		// Metadata snapshots are manifests, not archives
+		if record.SnapshotOf != "" || found[record.Filename] {
+			continue
+		}
+		files = append(files, backupService.ArchiveFile{Name: record.Filename, Size: record.Size, Missing: scannedOK})
+	}
+
+	if target.IsFileTarget() {
+		usage := backupService.SourceUsage{Source: filepath.Base(target.File)}
+		for _, file := range files {
+			if file.Missing {
+				usage.Missing++
+			} else {
+				usage.Archives++
+				usage.Bytes += file.Size
+			}
+		}
+		if usage.Archives == 0 && usage.Missing == 0 {
+			return nil
+		}
+		return []backupService.SourceUsage{usage}
+	}
+	return backupService.SummarizeUsage(files, naming, duLast)
+}
+
+// growthCell shows how the size of a source's archives changed, e.g. "+1.20 MB (+4.5%)"
+func growthCell(usage backupService.SourceUsage) tableCell {
+	if usage.GrowthOver < 2 {
+		return tableCell{text: "-", color: ColorDim}
+	}
+	text := "+" + formatFileSize(usage.Growth)
+	if usage.Growth < 0 {
+		text = "-" + formatFileSize(-usage.Growth)
+	}
+	text += fmt.Sprintf(" (%+.1f%%)", usage.GrowthPercent())
+	if usage.GrowthOver < duLast {
+		text += fmt.Sprintf(" over %d", usage.GrowthOver)
+	}
+	return tableCell{text: text}
+}
+
+// missingCell shows the number of recorded archives not found on the target
+func missingCell(missing int) tableCell {
+	if missing == 0 {
+		return tableCell{}
+	}
+	return tableCell{text: fmt.Sprint(missing), color: ColorRed}
+}
+
+func init() {
+	duCmd.Flags().IntVarP(&duLast, "last", "n", 10, "Measure growth over this many of the newest archives of each source")
+	duCmd.Flags().BoolVarP(&duWide, "wide", "w", false, "Show source names in full instead of shortening long ones")
+	rootCmd.AddCommand(duCmd)
+}
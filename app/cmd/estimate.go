@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	estimateLevels   []int
+	estimateSampleMB int64
+	estimateFull     bool
+	estimateExcludes []string
+)
+
+// estimateCmd represents the estimate command
+var estimateCmd = &cobra.Command{
+	Use:   "estimate",
+	Short: "Estimate backup size and duration without creating a backup",
+	Long: `Estimate the compressed archive size and backup duration for each gzip
+compression level, without writing an archive.
+
+By default evenly spaced blocks of the source are sampled (--sample MB), which
+is quick even for very large sources. Use --full to compress all content for
+an exact size at the cost of a full read.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		estimateSource := source
+		if estimateSource == "" {
+			var err error
+			estimateSource, err = os.Getwd()
+			if err != nil {
+				fmt.Printf("%s%s❌ Error getting current directory:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+				os.Exit(1)
+			}
+		}
+		if info, err := os.Stat(estimateSource); err != nil || !info.IsDir() {
+			fmt.Printf("%s%s❌ Error:%s %s is not a directory\n", ColorRed, ColorBold, ColorReset, estimateSource)
+			os.Exit(1)
+		}
+
+		configPath := filepath.Join(estimateSource, ".backup.yaml")
+		if cfgFile != "" {
+			configPath = cfgFile
+		}
+
+		// Use the same excludes as the run command would
+		excludes := estimateExcludes
+		levels := append([]int{}, estimateLevels...)
+		if config, err := configService.ReadBackupConfig(configPath); err == nil {
+			if len(config.Excludes) > 0 {
+				excludes = config.Excludes
+				fmt.Printf("%sUsing excludes from config:%s %v\n", ColorDim, ColorReset, excludes)
+			}
+			if config.Options != nil && config.Options.IncludeVCS {
+				excludes = compressionService.WithoutVCS(excludes)
+			}
+			excludes = withoutHidden(config.Options, excludes, false)
+			excludes = compressionService.IncludeOnly(config.Includes, excludes)
+
+			// Also estimate the levels the configured targets use
+			if !cmd.Flags().Changed("levels") {
+				defaultLevel, _ := config.Options.CompressionLevel()
+				for _, target := range config.Targets {
+					level := target.GetCompressionLevel()
+					if level == 0 {
+						level = defaultLevel
+					}
+					if level != 0 && !containsLevel(levels, level) {
+						levels = append(levels, level)
+					}
+				}
+			}
+		}
+		if err := compressionService.ValidateExcludes(excludes); err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		sort.Ints(levels)
+
+		if estimateFull {
+			fmt.Printf("%sCompressing all files in %s...%s\n", ColorDim, estimateSource, ColorReset)
+		} else {
+			fmt.Printf("%sSampling up to %d MB of %s...%s\n", ColorDim, estimateSampleMB, estimateSource, ColorReset)
+		}
+
+		estimate, err := compressionService.EstimateArchive(estimateSource, excludes, compressionService.EstimateOptions{
+			Levels:     levels,
+			SampleSize: estimateSampleMB * compressionService.MB,
+			Full:       estimateFull,
+		})
+		if err != nil {
+			fmt.Printf("%s%s❌ Error estimating backup:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+
+		sampledPercent := 100.0
+		if estimate.TotalSize > 0 {
+			sampledPercent = float64(estimate.SampledSize) / float64(estimate.TotalSize) * 100
+		}
+
+		fmt.Printf("\n%s%sSource:%s %s\n", ColorCyan, ColorBold, ColorReset, estimateSource)
+		fmt.Printf("  Files:      %d\n", estimate.Files)
+		fmt.Printf("  Total size: %s\n", compressionService.FormatFileSize(estimate.TotalSize))
+		fmt.Printf("  Sampled:    %s (%.1f%%)\n", compressionService.FormatFileSize(estimate.SampledSize), sampledPercent)
+		fmt.Printf("  Read time:  %s\n\n", formatEstimateDuration(estimate.ReadTime))
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "%sLevel\tRatio\tEst. Size\tEst. Time%s\n", ColorBold, ColorReset)
+		fmt.Fprintf(w, "%s-----\t-----\t---------\t---------%s\n", ColorDim, ColorReset)
+		for _, level := range estimate.Levels {
+			fmt.Fprintf(w, "%s\t%.1f%%\t%s\t%s\n",
+				formatLevel(level.Level),
+				level.Ratio*100,
+				compressionService.FormatFileSize(level.EstimatedSize),
+				formatEstimateDuration(level.EstimatedTime))
+		}
+		w.Flush()
+
+		if !estimateFull {
+			fmt.Printf("\n%sEstimates are extrapolated from a sample; use --full for exact sizes.%s\n", ColorDim, ColorReset)
+		}
+		fmt.Printf("%sSet a level per target with compression.level in .backup.yaml.%s\n", ColorDim, ColorReset)
+	},
+}
+
+func init() {
+	estimateCmd.Flags().StringVarP(&source, "source", "s", "", "Source directory to estimate (defaults to current directory)")
+	estimateCmd.Flags().IntSliceVar(&estimateLevels, "levels", compressionService.DefaultEstimateLevels, "gzip compression levels to estimate (1-9)")
+	estimateCmd.Flags().Int64Var(&estimateSampleMB, "sample", compressionService.DefaultEstimateSampleSize/compressionService.MB, "Amount of content to sample in MB")
+	estimateCmd.Flags().BoolVar(&estimateFull, "full", false, "Compress all content instead of sampling")
+	estimateCmd.Flags().StringSliceVar(&estimateExcludes, "exclude", []string{".git", "node_modules", "bin"}, "Directories to exclude when no config file is found")
+	rootCmd.AddCommand(estimateCmd)
+}
+
+// containsLevel reports whether the level is in the list
+func containsLevel(levels []int, level int) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// formatLevel returns a display name for a gzip level
+func formatLevel(level int) string {
+	switch level {
+	case 1:
+		return "1 (fastest)"
+	case 6:
+		return "6 (default)"
+	case 9:
+		return "9 (smallest)"
+	}
+	return fmt.Sprintf("%d", level)
+}
+
+// formatEstimateDuration rounds a duration for display
+func formatEstimateDuration(d time.Duration) string {
+	if d < time.Second {
+		return "<1s"
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fetchTargetDir string
+	fetchOverwrite bool
+)
+
+// fetchCandidate is a copy of the requested backup found in one of the targets
+type fetchCandidate struct {
+	location string                 // Local path or remote location of the backup
+	fileName string                 // Backup file name
+	remote   *storageService.Remote // Nil for local backups
+}
+
+// fetchCmd represents the fetch command
+var fetchCmd = &cobra.Command{
+	Use:   "fetch <backup-name>",
+	Short: "Download a backup from the configured targets",
+	Long: `Find a backup by name in the configured targets and copy it to a local directory.
+
+The name may be the backup file name or its base name without extensions,
+e.g. project-20250520-123045. Local targets are tried before remote ones.
+The copy is verified against the target's SHA256SUMS file when it has an
+entry for the backup; on a mismatch the next target holding the backup is tried.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		configPath := ".backup.yaml"
+		if cfgFile != "" {
+			configPath = cfgFile
+		}
+		config, err := configService.ReadBackupConfig(configPath)
+		if err != nil {
+			fmt.Printf("%s%s❌ Error reading config file %s:%s %v\n", ColorRed, ColorBold, configPath, ColorReset, err)
+			os.Exit(1)
+		}
+		targets, err := configService.ResolveTargets(config, configService.TargetFlags{})
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+
+		candidates := findFetchCandidates(targets, name)
+		if len(candidates) == 0 {
+			fmt.Printf("%s%s❌ Error:%s backup %s not found in any target\n", ColorRed, ColorBold, ColorReset, name)
+			os.Exit(1)
+		}
+
+		if err := os.MkdirAll(fetchTargetDir, 0755); err != nil {
+			fmt.Printf("%s%s❌ Error:%s failed to create target directory: %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+
+		for _, candidate := range candidates {
+			destPath := filepath.Join(fetchTargetDir, candidate.fileName)
+			if candidate.remote == nil && sameFile(candidate.location, destPath) {
+				fmt.Printf("%sSkipping %s: it is already in the target directory%s\n", ColorDim, candidate.location, ColorReset)
+				continue
+			}
+			if _, err := os.Stat(destPath); err == nil && !fetchOverwrite {
+				fmt.Printf("%s%s❌ Error:%s %s already exists, use --overwrite to replace it\n", ColorRed, ColorBold, ColorReset, destPath)
+				os.Exit(1)
+			}
+
+			fmt.Printf("%s→ Fetching%s %s\n", ColorBlue, ColorReset, candidate.location)
+			verified, err := fetchBackup(candidate, destPath)
+			if err != nil {
+				fmt.Printf("  %s⚠️  Failed:%s %v\n", ColorYellow, ColorReset, err)
+				continue
+			}
+
+			if verified {
+				fmt.Printf("  %s✅ Checksum verified%s\n", ColorGreen, ColorReset)
+			} else {
+				fmt.Printf("  %s⚠️  No checksum recorded for this backup, the copy was not verified%s\n", ColorYellow, ColorReset)
+			}
+			fmt.Printf("\n%s%s🎉 Fetched backup to %s%s\n", ColorGreen, ColorBold, destPath, ColorReset)
+			return
+		}
+
+		fmt.Printf("\n%s%s❌ Error:%s could not fetch %s from any target\n", ColorRed, ColorBold, ColorReset, name)
+		os.Exit(1)
+	},
+}
+
+func init() {
+	fetchCmd.Flags().StringVarP(&fetchTargetDir, "target-dir", "t", ".", "Directory to copy the backup into")
+	fetchCmd.Flags().BoolVarP(&fetchOverwrite, "overwrite", "o", false, "Replace an existing file in the target directory")
+	rootCmd.AddCommand(fetchCmd)
+}
+
+// findFetchCandidates returns the copies of the named backup in the targets,
+// local targets first and in config order otherwise
+func findFetchCandidates(targets []configService.ResolvedTarget, name string) []fetchCandidate {
+	var local, remote []fetchCandidate
+	for _, target := range targets {
+		dest := target.GetDestination()
+
+		if storageService.IsRemote(dest) {
+			remoteDir, err := storageService.ParseRemote(dest)
+			if err != nil {
+				fmt.Printf("%s⚠️  Skipping %s:%s %v\n", ColorYellow, dest, ColorReset, err)
+				continue
+			}
+			if target.IsFileTarget() {
+				if backupService.MatchesBackupName(remoteDir.Base(), name) {
+					remote = append(remote, fetchCandidate{location: dest, fileName: remoteDir.Base(), remote: remoteDir})
+				}
+				continue
+			}
+
+			files, err := storageService.List(remoteDir)
+			if err != nil {
+				fmt.Printf("%s⚠️  Skipping %s:%s %v\n", ColorYellow, dest, ColorReset, err)
+				continue
+			}
+			for _, file := range files {
+				if backupService.MatchesBackupName(file.Name, name) {
+					fileRemote := remoteDir.Join(file.Name)
+					remote = append(remote, fetchCandidate{location: fileRemote.String(), fileName: file.Name, remote: fileRemote})
+				}
+			}
+			continue
+		}
+
+		if target.IsFileTarget() {
+			if backupService.MatchesBackupName(filepath.Base(dest), name) {
+				if _, err := os.Stat(dest); err == nil {
+					local = append(local, fetchCandidate{location: dest, fileName: filepath.Base(dest)})
+				}
+			}
+			continue
+		}
+
+		entries, err := os.ReadDir(dest)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && backupService.MatchesBackupName(entry.Name(), name) {
+				local = append(local, fetchCandidate{location: filepath.Join(dest, entry.Name()), fileName: entry.Name()})
+			}
+		}
+	}
+	return append(local, remote...)
+}
+
+// fetchBackup copies the candidate to destPath and verifies it against the SHA256SUMS file
+// next to the backup, or the .sha256 file of a local backup. It reports whether a checksum was found and matched.
+// The copy is removed if it cannot be verified.
+func fetchBackup(candidate fetchCandidate, destPath string) (bool, error) {
+	var sums map[string]string
+	var err error
+	if candidate.remote != nil {
+		// Always download the checksum list again, it changes with every backup
+		sumsPath, _, fetchErr := storageService.Fetch(candidate.remote.Dir().Join(backupService.ChecksumsFileName), true)
+		if fetchErr == nil {
+			sums, err = backupService.ReadChecksums(filepath.Dir(sumsPath))
+		}
+		if err == nil {
+			err = storageService.Download(candidate.remote, destPath)
+		}
+	} else {
+		sums, err = backupService.ReadChecksums(filepath.Dir(candidate.location))
+		if err == nil {
+			err = backupService.CopyFile(candidate.location, destPath)
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+
+	expected, ok := sums[candidate.fileName]
+	if !ok && candidate.remote == nil {
+		// A backup copied elsewhere by hand may have only its .sha256 file
+		expected, err = backupService.ReadChecksumFile(candidate.location)
+		ok = err == nil
+	}
+	if !ok {
+		return false, nil
+	}
+	if err := backupService.VerifyChecksum(destPath, expected); err != nil {
+		os.Remove(destPath)
+		return false, err
+	}
+	return true, nil
+}
+
+// sameFile reports whether both paths refer to the same existing file
+func sameFile(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+)
+
+// readFileList reads the paths of a --files-from list from a file, or from standard input for "-"
+func readFileList(name string, nullDelimited bool) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("error opening file list: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+	return compressionService.ReadFileList(r, nullDelimited)
+}
+
+// nullOutput starts the -0 output mode of a command: everything it prints goes to standard error,
+// and only the paths written with writeNullPaths to the returned file remain on standard output.
+// Call restore when the command is done.
+func nullOutput() (out *os.File, restore func()) {
+	out = os.Stdout
+	os.Stdout = os.Stderr
+	return out, func() { os.Stdout = out }
+}
+
+// writeNullPaths writes each path exactly, followed by a NUL character, for 'xargs -0' and --files-from -0
+func writeNullPaths(w io.Writer, paths ...string) {
+	for _, path := range paths {
+		fmt.Fprint(w, path+"\x00")
+	}
+}
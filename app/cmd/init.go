@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/user"
 	"path/filepath"
 	"strings"
 
@@ -36,13 +35,11 @@ in the current directory. This file will define backup targets and settings.`,
 			return
 		}
 
-		// Try to load encryption and target defaults from ~/.backup.yaml
+		// Try to load encryption and target defaults from the global registry
 		// This allows users to define global defaults for new configurations
 		var encryptionDefault *configService.EncryptionConfig
 		var autoTargets []configService.BackupTarget
-		usr, err := user.Current()
-		if err == nil {
-			homeConfig := filepath.Join(usr.HomeDir, ".backup.yaml")
+		if homeConfig, err := configService.GlobalRegistryPath(); err == nil {
 			if f, err := os.Open(homeConfig); err == nil {
 				defer f.Close()
 				var raw map[string]interface{}
@@ -127,7 +124,7 @@ in the current directory. This file will define backup targets and settings.`,
 		}
 
 		// Write the config to file
-		err = configService.WriteBackupConfig(configFile, &config)
+		err := configService.WriteBackupConfig(configFile, &config)
 		if err != nil {
 			fmt.Printf("Error writing configuration file: %v\n", err)
 			return
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+	encryptionService "github.com/kennycyb/go-backup/internal/service/encrypt"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	inspectAll        bool
+	inspectRefresh    bool
+	inspectPassphrase string
+	inspectNull       bool
+)
+
+// inspectMaxEntries is how many entries inspect shows without --all
+const inspectMaxEntries = 50
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <backup>",
+	Short: "Show the contents of a backup without restoring it",
+	Long: `Show the files in a backup without restoring it.
+
+The backup may be a local file or a remote location (s3://, sftp://, rclone:, exec:).
+The manifest written next to the backup is used when there is one, so only
+the small manifest has to be downloaded; otherwise the archive itself is read.
+Remote files are kept in a local cache for later commands.
+
+With -0, only the paths of all entries are printed, each followed by a NUL
+character, e.g. to pick files for 'restore --files-from - -0'.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		location := args[0]
+
+		var nullOut *os.File
+		if inspectNull {
+			var restore func()
+			nullOut, restore = nullOutput()
+			defer restore()
+		}
+
+		tmpDir, err := os.MkdirTemp("", "go-backup-inspect-")
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		defer os.RemoveAll(tmpDir)
+
+		manifest, err := loadBackupManifest(location, tmpDir, inspectPassphrase)
+		if err != nil {
+			fmt.Printf("%s%s❌ Error inspecting backup:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.RemoveAll(tmpDir)
+			os.Exit(1)
+		}
+
+		if nullOut != nil {
+			for _, entry := range manifest.Entries {
+				writeNullPaths(nullOut, entry.Path)
+			}
+			return
+		}
+
+		fmt.Printf("\n%s%sBackup:%s %s\n", ColorCyan, ColorBold, ColorReset, location)
+		if manifest.Source != "" {
+			fmt.Printf("  Source:     %s\n", manifest.Source)
+		}
+		if !manifest.CreatedAt.IsZero() {
+			fmt.Printf("  Created:    %s\n", manifest.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+		}
+		if len(manifest.Tags) > 0 {
+			fmt.Printf("  Tags:       %s\n", strings.Join(manifest.Tags, ", "))
+		}
+		if manifest.Message != "" {
+			fmt.Printf("  Message:    %s\n", manifest.Message)
+		}
+		if manifest.Provenance != nil {
+			fmt.Printf("  Made with:  %s\n", manifest.Provenance.Describe())
+		}
+		if manifest.RunID != "" {
+			fmt.Printf("  Run ID:     %s\n", manifest.RunID)
+		}
+		fmt.Printf("  Files:      %d\n", manifest.Files)
+		if len(manifest.Chunks) > 0 {
+			fmt.Printf("  Chunks:     %d, read separately by restore --files-from and cat\n", len(manifest.Chunks))
+		}
+		fmt.Printf("  Total size: %s\n\n", compressionService.FormatFileSize(manifest.TotalSize))
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Mode\tSize\tModified\tPath")
+		for i, entry := range manifest.Entries {
+			if !inspectAll && i >= inspectMaxEntries {
+				break
+			}
+			size := compressionService.FormatFileSize(entry.Size)
+			name := compressionService.QuotePath(entry.Path)
+			if entry.IsDir {
+				size = "-"
+				name += "/"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Mode, size, entry.ModTime.Local().Format("2006-01-02 15:04"), name)
+		}
+		w.Flush()
+
+		if !inspectAll && len(manifest.Entries) > inspectMaxEntries {
+			fmt.Printf("%s... and %d more (use --all to see all)%s\n", ColorDim, len(manifest.Entries)-inspectMaxEntries, ColorReset)
+		}
+	},
+}
+
+func init() {
+	inspectCmd.Flags().BoolVarP(&inspectAll, "all", "a", false, "Show all entries")
+	inspectCmd.Flags().BoolVar(&inspectRefresh, "refresh", false, "Download remote files again even if they are in the local cache")
+	inspectCmd.Flags().BoolVarP(&inspectNull, "null", "0", false, "Only print the paths of the entries, each followed by a NUL character")
+	inspectCmd.Flags().StringVar(&inspectPassphrase, "passphrase", "", "Passphrase for GPG decryption (if needed)")
+	rootCmd.AddCommand(inspectCmd)
+}
+
+// loadBackupManifest returns the manifest of a local or remote backup.
+// It prefers the manifest file next to the backup and falls back to listing the archive.
+// Encrypted files are decrypted into tmpDir, with the passphrase if one is given.
+func loadBackupManifest(location, tmpDir, passphrase string) (*backupService.Manifest, error) {
+	manifest, err := findBackupManifest(location, tmpDir, passphrase, inspectRefresh)
+	if manifest != nil || err != nil {
+		return manifest, err
+	}
+
+	// No manifest, e.g. for backups made before manifests were written: read the archive
+	fileName := filepath.Base(location)
+	archivePath := location
+	if storageService.IsRemote(location) {
+		remote, err := storageService.ParseRemote(location)
+		if err != nil {
+			return nil, err
+		}
+		fileName = remote.Base()
+		fmt.Printf("%sNo manifest found, fetching %s...%s\n", ColorDim, remote, ColorReset)
+		localPath, _, err := storageService.Fetch(remote, inspectRefresh)
+		if err != nil {
+			return nil, err
+		}
+		archivePath = localPath
+	} else if _, err := os.Stat(archivePath); err != nil {
+		return nil, err
+	}
+
+	plainPath, err := decryptForInspect(archivePath, tmpDir, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := compressionService.ListTarGzArchive(plainPath)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest = backupService.NewManifest(fileName, "", entries)
+	manifest.CreatedAt = time.Time{} // Only the manifest records when the backup was created
+	return manifest, nil
+}
+
+// findBackupManifest returns the manifest file next to a local or remote backup, decrypted
+// into tmpDir when encrypted, or nil if the backup has none
+func findBackupManifest(location, tmpDir, passphrase string, refresh bool) (*backupService.Manifest, error) {
+	var remote *storageService.Remote
+	fileName := filepath.Base(location)
+	if storageService.IsRemote(location) {
+		var err error
+		if remote, err = storageService.ParseRemote(location); err != nil {
+			return nil, err
+		}
+		fileName = remote.Base()
+	}
+
+	// Encrypted backups normally have an encrypted manifest, so look for that first
+	baseName := backupService.BackupBaseName(fileName)
+	manifestNames := []string{baseName + backupService.ManifestSuffix, baseName + backupService.ManifestSuffix + ".gpg"}
+	if strings.HasSuffix(fileName, ".gpg") {
+		manifestNames[0], manifestNames[1] = manifestNames[1], manifestNames[0]
+	}
+
+	// Look for a manifest next to the backup
+	for _, name := range manifestNames {
+		var manifestPath string
+		if remote != nil {
+			localPath, _, err := storageService.Fetch(remote.Dir().Join(name), refresh)
+			if err != nil {
+				continue
+			}
+			manifestPath = localPath
+		} else {
+			manifestPath = filepath.Join(filepath.Dir(location), name)
+			if _, err := os.Stat(manifestPath); err != nil {
+				continue
+			}
+		}
+
+		fmt.Printf("%sUsing manifest:%s %s\n", ColorDim, ColorReset, name)
+		plainPath, err := decryptForInspect(manifestPath, tmpDir, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return backupService.ReadManifest(plainPath)
+	}
+	return nil, nil
+}
+
+// decryptForInspect decrypts a .gpg file into tmpDir and returns the decrypted path.
+// Other files are returned unchanged.
+func decryptForInspect(path, tmpDir, passphrase string) (string, error) {
+	if !strings.HasSuffix(path, ".gpg") {
+		return path, nil
+	}
+	outputFile := filepath.Join(tmpDir, strings.TrimSuffix(filepath.Base(path), ".gpg"))
+	return encryptionService.GPGDecrypt(path, outputFile, passphrase)
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	encryptionService "github.com/kennycyb/go-backup/internal/service/encrypt"
+	"github.com/spf13/cobra"
+)
+
+var (
+	keysAll          bool
+	keysExportFile   string
+	keysExportSecret bool
+)
+
+// unsafeFileNameChars matches characters replaced when a key file is named after its recipient
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9@._-]+`)
+
+// keysCmd groups the commands that manage encryption keys
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "List, export and import GPG keys for backup encryption",
+	Long: `Find a GPG key to encrypt backups to, and move keys between machines,
+without having to remember the gpg command line.
+
+Examples:
+  go-backup keys list
+  go-backup config --enable-encryption --gpg-receiver you@example.com
+  go-backup keys export you@example.com --secret -o backup-key.asc
+  go-backup keys import backup-key.asc`,
+}
+
+// keysListCmd lists the keys that backups can be encrypted to
+var keysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List GPG keys that backups can be encrypted to",
+	Long: `List the GPG keys in the keyring that can encrypt, with their fingerprints
+and email addresses, and whether their secret key is available to restore
+backups on this machine. Use --all to include expired and revoked keys.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		keys, err := encryptionService.ListGPGKeys()
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			fmt.Printf("%sHint:%s Install GnuPG, e.g. 'brew install gnupg' or 'apt install gnupg'.\n", ColorDim, ColorReset)
+			os.Exit(1)
+		}
+
+		shown := 0
+		example := ""
+		for _, key := range keys {
+			if !key.Usable() && !keysAll {
+				continue
+			}
+			shown++
+			if key.Usable() && example == "" {
+				example = key.Fingerprint
+				if len(key.Emails) > 0 {
+					example = key.Emails[0]
+				}
+			}
+
+			state := fmt.Sprintf("%s✅ can encrypt%s", ColorGreen, ColorReset)
+			switch {
+			case key.Revoked:
+				state = fmt.Sprintf("%s❌ revoked%s", ColorRed, ColorReset)
+			case key.Expired:
+				state = fmt.Sprintf("%s❌ expired %s%s", ColorRed, key.Expires.Format("2006-01-02"), ColorReset)
+			case !key.CanEncrypt:
+				state = fmt.Sprintf("%s❌ cannot encrypt%s", ColorRed, ColorReset)
+			case !key.Expires.IsZero():
+				state += fmt.Sprintf(", expires %s", key.Expires.Format("2006-01-02"))
+			}
+			if key.HasSecret {
+				state += ", secret key available"
+			}
+
+			fmt.Printf("%s%s%s  %s\n", ColorBold, key.Fingerprint, ColorReset, state)
+			for _, userID := range key.UserIDs {
+				fmt.Printf("  %s\n", userID)
+			}
+		}
+
+		if shown == 0 {
+			fmt.Printf("%sNo GPG keys that can encrypt were found.%s\n", ColorYellow, ColorReset)
+			fmt.Printf("%sHint:%s Create one with 'gpg --quick-generate-key \"Your Name <you@example.com>\"',\n", ColorDim, ColorReset)
+			fmt.Printf("      or import one with 'go-backup keys import <file>'.\n")
+			return
+		}
+		if example == "" {
+			example = "<email or fingerprint>"
+		}
+		fmt.Printf("\n%sUse a key with:%s go-backup config --enable-encryption --gpg-receiver %s\n", ColorDim, ColorReset, example)
+	},
+}
+
+// keysExportCmd writes a key to a file
+var keysExportCmd = &cobra.Command{
+	Use:   "export <email or fingerprint>",
+	Short: "Export a GPG key to a file",
+	Long: `Export the public key of a recipient to an ASCII-armored file, e.g. to
+encrypt backups on another machine. With --secret the secret key is exported
+too, which is needed to restore backups elsewhere; keep that file safe.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		recipient := args[0]
+		outputFile := keysExportFile
+		if outputFile == "" {
+			kind := "public"
+			if keysExportSecret {
+				kind = "secret"
+			}
+			outputFile = fmt.Sprintf("%s-%s.asc", unsafeFileNameChars.ReplaceAllString(recipient, "_"), kind)
+		}
+
+		if _, err := os.Stat(outputFile); err == nil {
+			fmt.Printf("%s%s❌ Error:%s %s already exists\n", ColorRed, ColorBold, ColorReset, outputFile)
+			os.Exit(1)
+		}
+
+		if err := encryptionService.ExportGPGKey(recipient, outputFile, keysExportSecret); err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+
+		if keysExportSecret {
+			fmt.Printf("%s✅ Exported the secret key of %s to %s%s\n", ColorGreen, recipient, outputFile, ColorReset)
+			fmt.Printf("%sAnyone with this file and its passphrase can decrypt your backups; store it offline.%s\n", ColorYellow, ColorReset)
+			return
+		}
+		fmt.Printf("%s✅ Exported the public key of %s to %s%s\n", ColorGreen, recipient, outputFile, ColorReset)
+	},
+}
+
+// keysImportCmd imports keys from files
+var keysImportCmd = &cobra.Command{
+	Use:   "import <file>...",
+	Short: "Import GPG keys from files",
+	Long:  `Import public or secret GPG keys from ASCII-armored or binary key files into the keyring.`,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		failed := 0
+		for _, file := range args {
+			summary, err := encryptionService.ImportGPGKeys(file)
+			if err != nil {
+				fmt.Printf("%s%s❌ Error importing %s:%s %v\n", ColorRed, ColorBold, file, ColorReset, err)
+				failed++
+				continue
+			}
+			fmt.Printf("%s✅ Imported %s%s\n", ColorGreen, file, ColorReset)
+			fmt.Printf("%s%s%s\n", ColorDim, summary, ColorReset)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+		fmt.Printf("\n%sRun 'go-backup keys list' to see the imported keys.%s\n", ColorDim, ColorReset)
+	},
+}
+
+func init() {
+	keysListCmd.Flags().BoolVar(&keysAll, "all", false, "Include expired, revoked and sign-only keys")
+	keysExportCmd.Flags().StringVarP(&keysExportFile, "output", "o", "", "File to write the key to (defaults to <recipient>-public.asc or <recipient>-secret.asc)")
+	keysExportCmd.Flags().BoolVar(&keysExportSecret, "secret", false, "Export the secret key as well, to restore backups on another machine")
+	keysCmd.AddCommand(keysListCmd, keysExportCmd, keysImportCmd)
+	rootCmd.AddCommand(keysCmd)
+}
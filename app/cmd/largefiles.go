@@ -15,6 +15,7 @@ var (
 	largeMinSize int64
 	largeSort    string
 	largeLimit   int
+	largeNull    bool
 )
 
 // largeFilesCmd represents the large-files command
@@ -37,6 +38,14 @@ This command helps identify files that could cause issues when creating tar arch
 			ColorDim    = "\033[2m"
 		)
 
+		// With -0, only the paths of the large files are written to standard output
+		var nullOut *os.File
+		if largeNull {
+			var restore func()
+			nullOut, restore = nullOutput()
+			defer restore()
+		}
+
 		// Check if source is specified
 		if source == "" {
 			fmt.Printf("%s%sError: Source directory not specified%s\n", ColorRed, ColorBold, ColorReset)
@@ -75,6 +84,14 @@ This command helps identify files that could cause issues when creating tar arch
 			configExcludes = excludeDirs
 			fmt.Printf("%sUsing default excludes:%s %v\n", ColorDim, ColorReset, excludeDirs)
 		}
+		if err := compressionService.ValidateExcludes(configExcludes); err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		if configErr == nil {
+			configExcludes = withoutHidden(config.Options, configExcludes, false)
+			configExcludes = compressionService.IncludeOnly(config.Includes, configExcludes)
+		}
 
 		// Create absolute source path
 		absSource, err := filepath.Abs(source)
@@ -102,6 +119,13 @@ This command helps identify files that could cause issues when creating tar arch
 			largeFiles = largeFiles[:largeLimit]
 		}
 
+		if nullOut != nil {
+			for _, file := range largeFiles {
+				writeNullPaths(nullOut, file.RelativePath)
+			}
+			return
+		}
+
 		// Print results
 		fmt.Printf("%s%sFound %d files larger than %d MB%s\n", ColorYellow, ColorBold, len(largeFiles), largeMinSize, ColorReset)
 
@@ -123,7 +147,7 @@ This command helps identify files that could cause issues when creating tar arch
 				sizeColor,
 				file.SizeHuman,
 				ColorReset,
-				file.RelativePath,
+				compressionService.QuotePath(file.RelativePath),
 				file.ModTime.Format("Jan 02, 2006 15:04"))
 		}
 		w.Flush()
@@ -154,6 +178,7 @@ func init() {
 	largeFilesCmd.Flags().Int64Var(&largeMinSize, "min-size", 100, "Minimum size in MB to include in the list")
 	largeFilesCmd.Flags().StringVar(&largeSort, "sort", "size", "Sort results by: size, name, or date")
 	largeFilesCmd.Flags().IntVar(&largeLimit, "limit", 50, "Limit the number of files to display (0 for no limit)")
+	largeFilesCmd.Flags().BoolVarP(&largeNull, "null", "0", false, "Only print the paths of the files, relative to the source, each followed by a NUL character")
 
 	// Add common flags
 	largeFilesCmd.Flags().StringVarP(&source, "source", "s", "", "Source directory to analyze")
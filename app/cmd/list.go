@@ -8,7 +8,10 @@ import (
 	"strings"
 	"time"
 
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
 	configService "github.com/kennycyb/go-backup/internal/service/config"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
 	"github.com/spf13/cobra"
 )
 
@@ -17,16 +20,24 @@ var (
 	listPath    string
 	listAll     bool
 	showHistory bool
+	listTag     string
+	listOrphans bool
+	listClean   bool
+	listWide    bool
+	listNull    bool
 )
 
 // Backup represents a backup file with metadata
 type Backup struct {
-	Name      string
-	Path      string
-	Size      int64
-	CreatedAt time.Time
-	Source    string
-	Timestamp string
+	Name       string
+	Path       string
+	Size       int64
+	CreatedAt  time.Time
+	Source     string
+	Timestamp  string
+	Tags       []string // From the backup history in the config file
+	Message    string   // From the backup history in the config file
+	SnapshotOf string   // Archive holding the contents of a metadata snapshot, from the backup history
 }
 
 // listCmd represents the list command
@@ -49,6 +60,18 @@ This command will display information about existing backups.`,
 			ColorDim    = "\033[2m"
 		)
 
+		// With -0, only the paths of the backups are written to standard output
+		var nullOut *os.File
+		if listNull {
+			if showHistory {
+				fmt.Printf("%s%s❌ Error:%s -0 lists the backup files found in the targets and cannot be combined with --history\n", ColorRed, ColorBold, ColorReset)
+				os.Exit(1)
+			}
+			var restore func()
+			nullOut, restore = nullOutput()
+			defer restore()
+		}
+
 		fmt.Printf("%s%s\n==============================\n   📦  Backup List           \n==============================%s\n", ColorCyan, ColorBold, ColorReset)
 
 		// Handle history mode separately
@@ -59,6 +82,7 @@ This command will display information about existing backups.`,
 
 		// Get current directory name for filtering
 		currentDir := ""
+		listSource := ""
 		if !listAll {
 			// Get the current directory
 			workDir, err := os.Getwd()
@@ -68,6 +92,7 @@ This command will display information about existing backups.`,
 				currentDir = "go-backup"
 			} else {
 				// Extract the base name
+				listSource = workDir
 				currentDir = filepath.Base(workDir)
 				if currentDir == "." || currentDir == "/" {
 					currentDir = "go-backup"
@@ -91,44 +116,43 @@ This command will display information about existing backups.`,
 				fmt.Println("Using default backup location: .backups/")
 				backupLocations = append(backupLocations, ".backups/")
 			} else {
+				// Resolve typed targets; an empty target list falls back to the default location below
+				var targets []configService.ResolvedTarget
+				if len(config.Targets) > 0 {
+					targets, err = configService.ResolveTargets(config, configService.TargetFlags{})
+					if err != nil {
+						fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+						os.Exit(1)
+					}
+				}
+
 				// Add all target paths from config
-				for _, target := range config.Targets {
+				for _, target := range targets {
 					backupLocations = append(backupLocations, target.GetDestination())
 				}
 
 				// Display Target Status if listing all
 				if listAll {
 					fmt.Printf("\n%s%sTarget Status:%s\n", ColorCyan, ColorBold, ColorReset)
-					fmt.Printf("%-30s %-20s %-10s %s\n", "Target", "Last Run", "Status", "Message")
-					fmt.Println(strings.Repeat("-", 90))
-					for _, target := range config.Targets {
-						dest := target.GetDestination()
-						if len(dest) > 30 {
-							dest = "..." + dest[len(dest)-27:]
-						}
-
-						lastRun := "Never"
-						status := "N/A"
-						msg := ""
-						statusColor := ColorDim
-
-						if target.LastRun != nil {
-							lastRun = target.LastRun.Timestamp.Format("2006-01-02 15:04:05")
-							status = target.LastRun.Status
-							msg = target.LastRun.Message
-							if status == "Success" {
-								statusColor = ColorGreen
-							} else if status == "Failure" {
-								statusColor = ColorRed
-							}
+					statusTable := newTable(listWide,
+						tableColumn{title: "TARGET", maxWidth: 32},
+						tableColumn{title: "LAST RUN"},
+						tableColumn{title: "STATUS"},
+						tableColumn{title: "MESSAGE", maxWidth: 60},
+					)
+					for _, target := range targets {
+						if target.LastRun == nil {
+							statusTable.addRow(tableCell{text: target.GetDestination(), color: ColorBlue}, tableCell{text: "never", color: ColorDim}, tableCell{text: "N/A", color: ColorDim}, tableCell{})
+							continue
 						}
-
-						fmt.Printf("%-30s %-20s %s%-10s%s %s\n",
-							dest,
-							lastRun,
-							statusColor, status, ColorReset,
-							msg)
+						statusTable.addRow(
+							tableCell{text: target.GetDestination(), color: ColorBlue},
+							tableCell{text: target.LastRun.Timestamp.Format("2006-01-02 15:04:05")},
+							outcomeCell(target.LastRun.Status),
+							tableCell{text: target.LastRun.Message},
+						)
 					}
+					statusTable.print("")
 					fmt.Println()
 				}
 
@@ -140,28 +164,83 @@ This command will display information about existing backups.`,
 			}
 		}
 
+		// Tags and messages are recorded in the backup history, not in the backup files
+		records := backupRecordsByFile(".backup.yaml")
+		naming := listNaming(".backup.yaml")
+
 		// List backups in all locations
 		locationGroups := make(map[string][]Backup)
 
 		fmt.Printf("\n%s%sScanning backup locations:%s\n", ColorCyan, ColorBold, ColorReset)
 		for _, location := range backupLocations {
 			fmt.Printf("%s→ %s%s\n", ColorBlue, location, ColorReset)
+
+			// Remote locations are listed with the tool for their scheme (aws, sftp or rclone)
+			if storageService.IsRemote(location) {
+				backups, err := findBackupsInRemote(location, currentDir, naming)
+				if err != nil {
+					fmt.Printf("  Error reading backups: %v\n", err)
+					continue
+				}
+				locationGroups[location] = backups
+				fmt.Printf("  %sFound %d backups (remote)%s\n", ColorDim, len(backups), ColorReset)
+				continue
+			}
+
 			// Check if location exists
-			if _, err := os.Stat(location); os.IsNotExist(err) {
+			locationInfo, err := os.Stat(location)
+			if os.IsNotExist(err) {
 				fmt.Printf("  %s⚠️  Directory does not exist, skipping%s\n", ColorYellow, ColorReset)
 				continue
 			}
 
+			// File targets hold a single backup file rather than a directory of backups
+			if err == nil && !locationInfo.IsDir() {
+				locationGroups[location] = []Backup{{
+					Name:      filepath.Base(location),
+					Path:      location,
+					Size:      locationInfo.Size(),
+					CreatedAt: locationInfo.ModTime(),
+					Source:    filepath.Base(location),
+				}}
+				fmt.Printf("  %sFound 1 backup (file target)%s\n", ColorDim, ColorReset)
+				continue
+			}
+
 			// Get backups in this location
-			backups, err := findBackupsInLocation(location, currentDir)
+			backups, err := findBackupsInLocation(location, currentDir, naming)
 			if err != nil {
 				fmt.Printf("  Error reading backups: %v\n", err)
 				continue
 			}
 
 			// Store backups by location
+			backups, others := ownBackups(location, backups, listSource)
 			locationGroups[location] = backups
 			fmt.Printf("  %sFound %d backups%s\n", ColorDim, len(backups), ColorReset)
+			if others > 0 {
+				fmt.Printf("  %sLeft out %d backups of other machines or similarly named sources (use --all to show them)%s\n", ColorDim, others, ColorReset)
+			}
+
+			if listOrphans || listClean {
+				checkOrphans(location, records)
+			}
+		}
+
+		// Attach the recorded tags and keep only the backups with the requested tag
+		for location, backups := range locationGroups {
+			filtered := []Backup{}
+			for _, backup := range backups {
+				backup.Tags = records[backup.Name].Tags
+				backup.Message = records[backup.Name].Message
+				if listTag == "" || containsTag(backup.Tags, listTag) {
+					filtered = append(filtered, backup)
+				}
+			}
+			locationGroups[location] = filtered
+		}
+		if listTag != "" {
+			fmt.Printf("%sFiltering backups with tag:%s %s\n", ColorDim, ColorReset, listTag)
 		}
 
 		// Check if we found any backups
@@ -186,53 +265,118 @@ This command will display information about existing backups.`,
 			fmt.Printf("\n%sFound %d backups for source '%s' across %d locations:%s\n", ColorGreen, totalBackups, currentDir, len(locationGroups), ColorReset)
 		}
 
-		// Display backups by location
+		// One table per source, newest backups first, each with the target it is in
+		sourceGroups := make(map[string][]Backup)
+		targetOf := make(map[string]string)
 		for location, backups := range locationGroups {
-			fmt.Printf("\n%s📁 Location:%s %s\n", ColorBlue, ColorReset, location)
-
-			// Sort backups by creation time (newest first)
-			sort.Slice(backups, func(i, j int) bool {
-				return backups[i].CreatedAt.After(backups[j].CreatedAt)
-			})
-
-			// Group by source within this location
-			sourceGroups := make(map[string][]Backup)
 			for _, backup := range backups {
 				sourceGroups[backup.Source] = append(sourceGroups[backup.Source], backup)
+				targetOf[backup.Path] = location
 			}
+		}
+		sources := make([]string, 0, len(sourceGroups))
+		for source := range sourceGroups {
+			sources = append(sources, source)
+		}
+		sort.Strings(sources)
 
-			// Display each source group
-			for source, sourceBackups := range sourceGroups {
-				fmt.Printf("  %s📦 Source:%s %s (%d backups)\n", ColorCyan, ColorReset, source, len(sourceBackups))
-				for i, backup := range sourceBackups {
-					// Only show top 5 backups per source unless detailed is enabled
-					if !detailed && i >= 5 {
-						fmt.Printf("    %s... and %d more (use --detailed to see all)%s\n", ColorDim, len(sourceBackups)-5, ColorReset)
-						break
-					}
-
-					// Format file size for human readability
-					sizeStr := formatSize(backup.Size)
-
-					if detailed {
-						// Detailed view
-						fmt.Printf("    %s•%s %s\n", ColorDim, ColorReset, backup.Name)
-						fmt.Printf("      %sSize:%s %s\n", ColorDim, ColorReset, sizeStr)
-						fmt.Printf("      %sCreated:%s %s\n", ColorDim, ColorReset, backup.CreatedAt.Format("2006-01-02 15:04:05"))
-						fmt.Println()
-					} else {
-						// Simple view
-						timeAgo := formatTimeAgo(backup.CreatedAt)
-						fmt.Printf("    %s•%s %s %s(%s, %s ago)%s\n", ColorGreen, ColorReset, backup.Name, ColorDim, sizeStr, timeAgo, ColorReset)
-					}
+		if nullOut != nil {
+			for _, source := range sources {
+				sortBackups(sourceGroups[source])
+				for _, backup := range sourceGroups[source] {
+					writeNullPaths(nullOut, backup.Path)
 				}
 			}
+			return
+		}
+
+		checksums := make(map[string]map[string]string)
+		for _, source := range sources {
+			printBackupTable(source, sourceGroups[source], func(backup Backup) string { return targetOf[backup.Path] }, checksums)
 		}
 	},
 }
 
+// orphanDescriptions explains each kind of unexpected file found by checkOrphans
+var orphanDescriptions = map[string]string{
+	backupService.OrphanUnknown:   "matches no backup naming scheme",
+	backupService.OrphanSidecar:   "config or manifest without its archive",
+	backupService.OrphanUntracked: "archive missing from the backup history",
+}
+
+// checkOrphans lists the unexpected files in a backup directory and, with --clean, removes
+// the orphaned configs and manifests. Archives are tracked when they are in the history records.
+func checkOrphans(location string, records map[string]configService.BackupRecord) {
+	prefix := "go-backup-"
+	if workDir, err := os.Getwd(); err == nil && filepath.Base(workDir) != "/" {
+		prefix = filepath.Base(workDir) + "-"
+	}
+	history := make(map[string]bool, len(records))
+	for name := range records {
+		history[name] = true
+	}
+
+	orphans, err := backupService.FindOrphans(location, prefix, history)
+	if err != nil {
+		fmt.Printf("  %s⚠️  Warning: Failed to check for orphaned files -%s %v\n", ColorYellow, ColorReset, err)
+		return
+	}
+	if len(orphans) == 0 {
+		fmt.Printf("  %s✓ No orphaned or unknown files%s\n", ColorGreen, ColorReset)
+		return
+	}
+
+	fmt.Printf("  %s🔍 %d unexpected file(s):%s\n", ColorYellow, len(orphans), ColorReset)
+	sidecars := 0
+	for _, orphan := range orphans {
+		fmt.Printf("    - %s %s(%s)%s\n", compressionService.QuotePath(orphan.Name), ColorDim, orphanDescriptions[orphan.Kind], ColorReset)
+		if orphan.Kind == backupService.OrphanSidecar {
+			sidecars++
+		}
+	}
+
+	if !listClean || sidecars == 0 {
+		if sidecars > 0 {
+			fmt.Printf("  %sUse --clean to remove the %d orphaned config(s) and manifest(s)%s\n", ColorDim, sidecars, ColorReset)
+		}
+		return
+	}
+	config, _ := configService.ReadBackupConfig(".backup.yaml")
+	if err := backupService.RemoveOrphanedSidecars(location, orphans, rotationOptions(config, nil, false)); err != nil {
+		fmt.Printf("  %s⚠️  Warning: Failed to remove orphaned files -%s %v\n", ColorYellow, ColorReset, err)
+		return
+	}
+	fmt.Printf("  %s🧹 Removed %d orphaned config(s) and manifest(s)%s\n", ColorGreen, sidecars, ColorReset)
+}
+
+// backupRecordsByFile returns the config's history records by backup file name.
+// A missing or unreadable config simply has no records.
+func backupRecordsByFile(configPath string) map[string]configService.BackupRecord {
+	records := make(map[string]configService.BackupRecord)
+	config, err := configService.ReadBackupConfig(configPath)
+	if err != nil {
+		return records
+	}
+	for _, target := range config.Targets {
+		for _, record := range target.Backups {
+			records[record.Filename] = record
+		}
+	}
+	return records
+}
+
+// containsTag reports whether the tag is in the list
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
 // findBackupsInLocation scans a directory for backup files
-func findBackupsInLocation(dir string, filterPrefix string) ([]Backup, error) {
+func findBackupsInLocation(dir string, filterPrefix string, naming backupService.Naming) ([]Backup, error) {
 	backups := []Backup{}
 
 	files, err := os.ReadDir(dir)
@@ -246,12 +390,7 @@ func findBackupsInLocation(dir string, filterPrefix string) ([]Backup, error) {
 		}
 
 		fileName := file.Name()
-		if !strings.HasSuffix(fileName, ".tar.gz") {
-			continue // Skip non-backup files
-		}
-
-		// If filtering is enabled, skip files that don't match the current directory prefix
-		if filterPrefix != "" && !listAll && !strings.HasPrefix(fileName, filterPrefix+"-") {
+		if !isBackupFileName(fileName, filterPrefix) {
 			continue
 		}
 
@@ -262,43 +401,104 @@ func findBackupsInLocation(dir string, filterPrefix string) ([]Backup, error) {
 			continue
 		}
 
-		// Parse file name to extract source and timestamp
-		parts := strings.Split(strings.TrimSuffix(fileName, ".tar.gz"), "-")
-		if len(parts) < 3 {
-			// Not a valid backup file name format, skip
-			continue
+		if backup, ok := parseBackup(fileName, filepath.Join(dir, fileName), info.Size(), naming); ok {
+			backups = append(backups, backup)
 		}
+	}
 
-		// The format is source-date-time.tar.gz
-		// Last two parts make up the timestamp
-		sourceNameParts := parts[:len(parts)-2]
-		sourceName := strings.Join(sourceNameParts, "-")
-		timestampStr := fmt.Sprintf("%s-%s", parts[len(parts)-2], parts[len(parts)-1])
-
-		// Parse timestamp
-		timestamp, _ := time.Parse("20060102-150405", timestampStr)
+	return backups, nil
+}
 
-		// Create backup info
-		backup := Backup{
-			Name:      fileName,
-			Path:      filepath.Join(dir, fileName),
-			Size:      info.Size(),
-			CreatedAt: info.ModTime(), // Use file modification time for sorting
-			Source:    sourceName,
-			Timestamp: timestampStr,
+// ownBackups leaves out the backups that their manifest or sidecar config records as made on
+// another machine or from another source than the listed one, and returns how many were left out
+func ownBackups(dir string, backups []Backup, source string) ([]Backup, int) {
+	if source == "" {
+		return backups, 0
+	}
+	host := backupService.CurrentHost()
+	own := backups[:0]
+	for _, backup := range backups {
+		if backupService.OwnedBy(dir, backup.Name, host, source) {
+			own = append(own, backup)
 		}
+	}
+	return own, len(backups) - len(own)
+}
 
-		// If we successfully parsed the timestamp, use it instead of file mod time
-		if !timestamp.IsZero() {
-			backup.CreatedAt = timestamp
-		}
+// findBackupsInRemote lists the backup files in a remote location
+func findBackupsInRemote(location string, filterPrefix string, naming backupService.Naming) ([]Backup, error) {
+	remote, err := storageService.ParseRemote(location)
+	if err != nil {
+		return nil, err
+	}
 
-		backups = append(backups, backup)
+	files, err := storageService.List(remote)
+	if err != nil {
+		return nil, err
 	}
 
+	backups := []Backup{}
+	for _, file := range files {
+		if !isBackupFileName(file.Name, filterPrefix) {
+			continue
+		}
+		if backup, ok := parseBackup(file.Name, remote.Join(file.Name).String(), file.Size, naming); ok {
+			backups = append(backups, backup)
+		}
+	}
 	return backups, nil
 }
 
+// isBackupFileName reports whether the file is a backup, optionally of the given source
+func isBackupFileName(fileName string, filterPrefix string) bool {
+	if !backupService.IsArchiveName(fileName) || strings.HasSuffix(fileName, ".gpg") {
+		return false // Skip non-backup files
+	}
+
+	// If filtering is enabled, skip files that don't match the current directory prefix
+	if filterPrefix != "" && !listAll && !strings.HasPrefix(fileName, filterPrefix+"-") {
+		return false
+	}
+	return true
+}
+
+// parseBackup builds the backup info from a backup file name of the form source-timestamp.tar.gz,
+// with the timestamp in the config's layout and time zone. Names from before the layout was
+// changed are read with the default one. Returns false if the name does not follow either.
+func parseBackup(fileName, path string, size int64, naming backupService.Naming) (Backup, bool) {
+	sourceName, createdAt, ok := naming.ParseName(fileName)
+	if !ok {
+		sourceName, createdAt, ok = backupService.DefaultNaming().ParseName(fileName)
+	}
+	if !ok {
+		return Backup{}, false
+	}
+
+	return Backup{
+		Name:      fileName,
+		Path:      path,
+		Size:      size,
+		CreatedAt: createdAt,
+		Source:    sourceName,
+		Timestamp: naming.Timestamp(createdAt),
+	}, true
+}
+
+// listNaming returns how the backups listed are timestamped, from the naming options of the
+// config. A missing config or invalid options fall back to the default naming.
+func listNaming(configPath string) backupService.Naming {
+	config, err := configService.ReadBackupConfig(configPath)
+	if err != nil {
+		return backupService.DefaultNaming()
+	}
+	naming, err := config.Options.Naming()
+	if err != nil {
+		fmt.Printf("%s⚠️  Warning:%s %v\n", ColorYellow, ColorReset, err)
+		return backupService.DefaultNaming()
+	}
+	return naming
+}
+
 // formatSize converts bytes to human-readable format
 func formatSize(bytes int64) string {
 	const (
@@ -319,30 +519,115 @@ func formatSize(bytes int64) string {
 	}
 }
 
-// formatTimeAgo returns a human-readable string representing time since the given time
-func formatTimeAgo(t time.Time) string {
-	duration := time.Since(t)
+// printBackupTable prints the backups of a source as a table, newest first: all of them with
+// --detailed, otherwise the latest 5. Tags and snapshot columns are only added when used.
+func printBackupTable(source string, backups []Backup, targetOf func(Backup) string, checksums map[string]map[string]string) {
+	sortBackups(backups)
 
-	hours := int(duration.Hours())
-	minutes := int(duration.Minutes()) % 60
-	days := hours / 24
-	years := days / 365
-	months := days / 30
+	shown := backups
+	if !detailed && len(shown) > 5 {
+		shown = shown[:5]
+	}
+	tagged, snapshots := false, false
+	for _, backup := range shown {
+		tagged = tagged || len(backup.Tags) > 0
+		snapshots = snapshots || backup.SnapshotOf != ""
+	}
+
+	columns := []tableColumn{
+		{title: "NAME", maxWidth: 44},
+		{title: "SIZE", right: true},
+		{title: "AGE", right: true},
+		{title: "TARGET", maxWidth: 32},
+		{title: "VERIFIED"},
+	}
+	if snapshots {
+		columns = append(columns, tableColumn{title: "SNAPSHOT OF", maxWidth: 44})
+	}
+	if detailed {
+		columns = append(columns, tableColumn{title: "CREATED"})
+	}
+	if tagged {
+		columns = append(columns, tableColumn{title: "TAGS", maxWidth: 24})
+	}
+	if detailed {
+		columns = append(columns, tableColumn{title: "MESSAGE", maxWidth: 40})
+	}
 
+	backupTable := newTable(listWide, columns...)
+	for _, backup := range shown {
+		target := targetOf(backup)
+		row := []tableCell{
+			{text: backup.Name, color: ColorGreen},
+			{text: formatSize(backup.Size)},
+			{text: formatTimeSince(time.Since(backup.CreatedAt))},
+			{text: target, color: ColorBlue},
+			verificationCell(target, backup, checksums),
+		}
+		if snapshots {
+			row = append(row, tableCell{text: backup.SnapshotOf, color: ColorDim})
+		}
+		if detailed {
+			row = append(row, tableCell{text: backup.CreatedAt.Format("2006-01-02 15:04:05"), color: ColorDim})
+		}
+		if tagged {
+			row = append(row, tableCell{text: strings.Join(backup.Tags, ", "), color: ColorYellow})
+		}
+		if detailed {
+			row = append(row, tableCell{text: backup.Message})
+		}
+		backupTable.addRow(row...)
+	}
+
+	fmt.Printf("\n  %s📦 Source:%s %s (%d backups)\n", ColorCyan, ColorReset, source, len(backups))
+	backupTable.print("    ")
+	if len(shown) < len(backups) {
+		fmt.Printf("    %s... and %d more (use --detailed to see all)%s\n", ColorDim, len(backups)-len(shown), ColorReset)
+	}
+}
+
+// sortBackups sorts backups newest first
+func sortBackups(backups []Backup) {
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].CreatedAt.Equal(backups[j].CreatedAt) {
+			// Same second: the "_N" sequence suffix sorts after the plain name
+			return backups[i].Name > backups[j].Name
+		}
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+}
+
+// verificationCell describes how a backup can be verified: against the SHA256SUMS of its
+// directory, and with parity files, which can also repair it. Checksums are read once per
+// directory into the cache. Remote backups are not checked, to keep listing fast and offline.
+func verificationCell(target string, backup Backup, checksums map[string]map[string]string) tableCell {
 	switch {
-	case years > 0:
-		return fmt.Sprintf("%dy", years)
-	case months > 0:
-		return fmt.Sprintf("%dm", months)
-	case days > 0:
-		return fmt.Sprintf("%dd", days)
-	case hours > 0:
-		return fmt.Sprintf("%dh", hours)
-	case minutes > 0:
-		return fmt.Sprintf("%dm", minutes)
-	default:
-		return "just now"
+	case backup.SnapshotOf != "":
+		return tableCell{text: "snapshot", color: ColorDim}
+	case storageService.IsRemote(target):
+		return tableCell{text: "remote", color: ColorDim}
+	}
+	if _, err := os.Stat(backup.Path); err != nil {
+		return tableCell{text: "missing", color: ColorRed}
+	}
+
+	dir := filepath.Dir(backup.Path)
+	sums, ok := checksums[dir]
+	if !ok {
+		sums, _ = backupService.ReadChecksums(dir)
+		checksums[dir] = sums
+	}
+	var methods []string
+	if _, ok := sums[backup.Name]; ok {
+		methods = append(methods, "sha256")
+	}
+	if _, err := backupService.ParityFiles(backup.Path); err == nil {
+		methods = append(methods, "par2")
 	}
+	if len(methods) == 0 {
+		return tableCell{text: "no", color: ColorYellow}
+	}
+	return tableCell{text: strings.Join(methods, "+"), color: ColorGreen}
 }
 
 // listBackupHistory displays the backup history from the config file
@@ -376,51 +661,51 @@ func listBackupHistory() {
 
 	fmt.Println("\nBackup History from Config File:")
 
+	targets, err := configService.ResolveTargets(config, configService.TargetFlags{})
+	if err != nil {
+		fmt.Printf("Error reading config file: %v\n", err)
+		return
+	}
+
 	// Display backups by target
-	for _, target := range config.Targets {
+	checksums := make(map[string]map[string]string)
+	for _, target := range targets {
 		if len(target.Backups) == 0 {
 			continue
 		}
 
-		fmt.Printf("\n📁 Location: %s\n", target.Path)
+		fmt.Printf("\n📁 Location: %s\n", target.GetDestination())
 
 		// Group backups by source
-		sourceGroups := make(map[string][]configService.BackupRecord)
-		for _, backup := range target.Backups {
-			sourceGroups[backup.Source] = append(sourceGroups[backup.Source], backup)
-		}
-
-		// Display each source group
-		for source, sourceBackups := range sourceGroups {
-			fmt.Printf("  📦 Source: %s (%d backups)\n", source, len(sourceBackups))
-
-			// Sort backups by creation time (newest first)
-			sort.Slice(sourceBackups, func(i, j int) bool {
-				return sourceBackups[i].CreatedAt.After(sourceBackups[j].CreatedAt)
+		sourceGroups := make(map[string][]Backup)
+		for _, record := range target.Backups {
+			if listTag != "" && !record.HasTag(listTag) {
+				continue
+			}
+			path := target.File
+			if !target.IsFileTarget() {
+				path = filepath.Join(target.GetDestination(), record.Filename)
+			}
+			sourceGroups[record.Source] = append(sourceGroups[record.Source], Backup{
+				Name:       record.Filename,
+				Path:       path,
+				Size:       record.Size,
+				CreatedAt:  record.CreatedAt,
+				Source:     record.Source,
+				Tags:       record.Tags,
+				Message:    record.Message,
+				SnapshotOf: record.SnapshotOf,
 			})
+		}
+		sources := make([]string, 0, len(sourceGroups))
+		for source := range sourceGroups {
+			sources = append(sources, source)
+		}
+		sort.Strings(sources)
 
-			for i, backup := range sourceBackups {
-				// Only show top 5 backups per source unless detailed is enabled
-				if !detailed && i >= 5 {
-					fmt.Printf("    ... and %d more (use --detailed to see all)\n", len(sourceBackups)-5)
-					break
-				}
-
-				// Format file size for human readability
-				sizeStr := formatSize(backup.Size)
-
-				if detailed {
-					// Detailed view
-					fmt.Printf("    • %s\n", backup.Filename)
-					fmt.Printf("      Size: %s\n", sizeStr)
-					fmt.Printf("      Created: %s\n", backup.CreatedAt.Format("2006-01-02 15:04:05"))
-					fmt.Println()
-				} else {
-					// Simple view
-					timeAgo := formatTimeAgo(backup.CreatedAt)
-					fmt.Printf("    • %s (%s, %s ago)\n", backup.Filename, sizeStr, timeAgo)
-				}
-			}
+		dest := target.GetDestination()
+		for _, source := range sources {
+			printBackupTable(source, sourceGroups[source], func(Backup) string { return dest }, checksums)
 		}
 	}
 }
@@ -431,6 +716,11 @@ func init() {
 	listCmd.Flags().StringVarP(&listPath, "path", "p", "", "Custom path to search for backups")
 	listCmd.Flags().BoolVarP(&listAll, "all", "a", false, "List all backups, not just those from current directory")
 	listCmd.Flags().BoolVar(&showHistory, "history", false, "Show backup history from config file instead of scanning directories")
+	listCmd.Flags().BoolVar(&listOrphans, "orphans", false, "Flag unknown files, sidecars without archives and archives missing from the history")
+	listCmd.Flags().BoolVar(&listClean, "clean", false, "Remove configs and manifests whose archive is gone (implies --orphans; uses the trash if rotation.trash is set)")
+	listCmd.Flags().StringVar(&listTag, "tag", "", "Only list backups created with this tag (run --tag)")
+	listCmd.Flags().BoolVarP(&listNull, "null", "0", false, "Only print the paths of the backups, each followed by a NUL character, e.g. for xargs -0")
+	listCmd.Flags().BoolVarP(&listWide, "wide", "w", false, "Show names and targets in full instead of shortening long ones")
 
 	// Add command to root
 	rootCmd.AddCommand(listCmd)
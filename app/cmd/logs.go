@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	logsService "github.com/kennycyb/go-backup/internal/service/logs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsList bool
+	logsTail int
+)
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:   "logs [location]",
+	Short: "Show the saved output of run-all backups",
+	Long: `Show the log of the most recent run-all backup of a location.
+
+The location is a directory path, or the name of a directory tracked in
+the global registry, and defaults to the current directory. Use --list to see
+all saved logs.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		location, err := resolveLogLocation(args)
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+
+		logs, err := logsService.ListLogs(location)
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		if len(logs) == 0 {
+			fmt.Printf("%sNo logs found for %s. Logs are saved by 'go-backup run-all'.%s\n", ColorYellow, location, ColorReset)
+			return
+		}
+
+		if logsList {
+			fmt.Printf("%s%sLogs for %s:%s\n", ColorCyan, ColorBold, location, ColorReset)
+			for _, log := range logs {
+				fmt.Printf("  %s  %s\n", log.Time.Format("2006-01-02 15:04:05"), log.Path)
+			}
+			return
+		}
+
+		latest := logs[0]
+		lines, err := logsService.TailLines(latest.Path, logsTail)
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s%sLog from %s:%s %s\n\n", ColorCyan, ColorBold, latest.Time.Format("2006-01-02 15:04:05"), ColorReset, latest.Path)
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+	},
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsList, "list", "l", false, "List all saved logs instead of showing the latest")
+	logsCmd.Flags().IntVarP(&logsTail, "tail", "n", 0, "Only show the last N lines")
+	rootCmd.AddCommand(logsCmd)
+}
+
+// resolveLogLocation returns the absolute backup location for the logs command.
+// A name that is not an existing directory is looked up among the registry locations.
+func resolveLogLocation(args []string) (string, error) {
+	if len(args) == 0 {
+		return os.Getwd()
+	}
+
+	if info, err := os.Stat(args[0]); err == nil && info.IsDir() {
+		return filepath.Abs(args[0])
+	}
+
+	registry, err := configService.ReadGlobalRegistry()
+	if err != nil {
+		return "", fmt.Errorf("%s is not a directory and the registry cannot be read: %w", args[0], err)
+	}
+
+	var matches []string
+	for _, entry := range registry.Backups {
+		if entry.Location == args[0] || filepath.Base(entry.Location) == args[0] {
+			matches = append(matches, entry.Location)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no tracked location matches %s", args[0])
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("%s matches several tracked locations, use the full path: %v", args[0], matches)
+}
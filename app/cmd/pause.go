@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pauseAll    bool
+	pauseReason string
+	pauseSource string
+)
+
+// pauseCmd represents the pause command
+var pauseCmd = &cobra.Command{
+	Use:   "pause [duration]",
+	Short: "Pause backups of this directory or of the whole machine",
+	Long: `Pause the backups of the current directory (or --source), or with --all of
+every location on this machine, e.g. during a large refactor or while a disk
+is nearly full.
+
+The duration is like 2h, 90m or 3d; without one, backups stay paused until
+'go-backup resume'. While paused, run and run-all skip the backup and record
+it as skipped with the reason. Use 'go-backup run --ignore-pause' to back up
+anyway.
+
+Pauses are kept in ~/.local/state/go-backup/pause.yaml.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var duration time.Duration
+		if len(args) == 1 {
+			var err error
+			if duration, err = configService.ParsePauseDuration(args[0]); err != nil {
+				fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+				os.Exit(1)
+			}
+		}
+		location, err := pauseLocation()
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+
+		pause, err := configService.PauseBackups(location, duration, pauseReason, time.Now())
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		what := "Backups of " + location
+		if location == "" {
+			what = "All backups on this machine"
+		}
+		fmt.Printf("%s⏸️  %s are %s%s\n", ColorYellow, what, pause.Describe(), ColorReset)
+		if pause.Until.IsZero() {
+			fmt.Printf("%sRun 'go-backup resume%s' to back up again%s\n", ColorDim, resumeHint(), ColorReset)
+		}
+	},
+}
+
+// resumeCmd represents the resume command
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume backups paused with 'go-backup pause'",
+	Long: `End the pause of the current directory (or --source), or with --all the
+machine-wide pause.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		location, err := pauseLocation()
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+
+		resumed, err := configService.ResumeBackups(location, time.Now())
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		what := "Backups of " + location
+		if location == "" {
+			what = "Backups on this machine"
+		}
+		if !resumed {
+			fmt.Printf("%s%s were not paused%s\n", ColorDim, what, ColorReset)
+			return
+		}
+		fmt.Printf("%s▶️  %s are resumed%s\n", ColorGreen, what, ColorReset)
+
+		// A machine-wide pause still holds the location, and the other way around
+		if pause, paused, err := configService.PausedAt(pauseLocationOrCwd(location), time.Now()); err == nil && paused {
+			fmt.Printf("%s⚠️  Warning:%s backups here are still %s\n", ColorYellow, ColorReset, pause.Describe())
+		}
+	},
+}
+
+func init() {
+	for _, command := range []*cobra.Command{pauseCmd, resumeCmd} {
+		command.Flags().BoolVar(&pauseAll, "all", false, "Pause or resume all backups on this machine")
+		command.Flags().StringVarP(&pauseSource, "source", "s", "", "Directory to pause or resume (default is the current directory)")
+		command.MarkFlagsMutuallyExclusive("all", "source")
+	}
+	pauseCmd.Flags().StringVarP(&pauseReason, "reason", "m", "", "Why backups are paused, shown when they are skipped")
+	rootCmd.AddCommand(pauseCmd, resumeCmd)
+}
+
+// pauseLocation returns the directory pause and resume apply to, or "" for the whole machine
+func pauseLocation() (string, error) {
+	if pauseAll {
+		return "", nil
+	}
+	if pauseSource != "" {
+		if info, err := os.Stat(pauseSource); err != nil || !info.IsDir() {
+			return "", fmt.Errorf("%s is not a directory", pauseSource)
+		}
+		return filepath.Abs(pauseSource)
+	}
+	return os.Getwd()
+}
+
+// pauseLocationOrCwd returns the location, or the current directory for the whole machine
+func pauseLocationOrCwd(location string) string {
+	if location == "" {
+		if cwd, err := os.Getwd(); err == nil {
+			return cwd
+		}
+	}
+	return location
+}
+
+// resumeHint returns the flags that resume what pause paused
+func resumeHint() string {
+	switch {
+	case pauseAll:
+		return " --all"
+	case pauseSource != "":
+		return " -s " + pauseSource
+	}
+	return ""
+}
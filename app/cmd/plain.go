@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	plainService "github.com/kennycyb/go-backup/internal/service/plain"
+)
+
+// plainOutputEnv turns on plain output like --plain, e.g. once for a whole screen reader session
+const plainOutputEnv = "GO_BACKUP_PLAIN"
+
+// plainChildEnv marks the go-backup process whose output a --plain parent filters
+const plainChildEnv = "GO_BACKUP_PLAIN_FILTERED"
+
+// plainOutput is set by --plain; the filtering itself is done by the parent (see runPlain)
+var plainOutput bool
+
+// wantsPlain reports whether plain output is asked for, by --plain or GO_BACKUP_PLAIN, and
+// is not already being produced by a parent process
+func wantsPlain(args []string) bool {
+	if os.Getenv(plainChildEnv) != "" {
+		return false
+	}
+	if enabled, err := strconv.ParseBool(os.Getenv(plainOutputEnv)); err == nil && enabled {
+		return true
+	}
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--plain" {
+			return true
+		}
+		if value, ok := strings.CutPrefix(arg, "--plain="); ok {
+			enabled, err := strconv.ParseBool(value)
+			return err == nil && enabled
+		}
+	}
+	return false
+}
+
+// isPlainOutput reports whether this process runs with its output made plain
+func isPlainOutput() bool {
+	return os.Getenv(plainChildEnv) != ""
+}
+
+// plainMark returns word in plain output and the emoji otherwise, for the lines where the emoji
+// alone tells the outcome, e.g. the checks of the doctor
+func plainMark(emoji, word string) string {
+	if isPlainOutput() {
+		return word
+	}
+	return emoji
+}
+
+// runPlain runs go-backup with the arguments again and writes its output without colors, emoji
+// and box drawing. Commands exit from anywhere, so filtering in a parent is the one way not to
+// lose output. Returns the exit code of the command.
+func runPlain(args []string) int {
+	execPath, err := os.Executable()
+	if err != nil {
+		execPath = os.Args[0]
+	}
+
+	child := exec.Command(execPath, args...)
+	child.Stdin = os.Stdin
+	child.Stdout = plainService.NewWriter(os.Stdout)
+	child.Stderr = plainService.NewWriter(os.Stderr)
+	child.Env = append(os.Environ(), plainChildEnv+"=1")
+
+	// Interrupts from the terminal reach the command too, which cleans up and reports; a
+	// termination of this process is passed on
+	signal.Ignore(os.Interrupt)
+	terminate := make(chan os.Signal, 1)
+	signal.Notify(terminate, syscall.SIGTERM)
+
+	if err := child.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	go func() {
+		for sig := range terminate {
+			child.Process.Signal(sig)
+		}
+	}()
+
+	if err := child.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+			return exitErr.ExitCode()
+		}
+		if exitErr == nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
+	"github.com/spf13/cobra"
+)
+
+var pruneTrash bool
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Apply backup rotation without running a backup",
+	Long: `Apply the retention policy (maxBackups) to all directory targets in the
+configuration without creating a new backup.
+
+Expired backups are deleted, or moved into a .trash/ subfolder of the target
+when rotation.trash is enabled in the config or --trash is given. Trashed
+backups are purged once they are older than rotation.trashDays (default 30).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configPath := ".backup.yaml"
+		if cfgFile != "" {
+			configPath = cfgFile
+		}
+
+		config, err := configService.ReadBackupConfig(configPath)
+		if err != nil {
+			fmt.Printf("%s%sError reading config file %s:%s %v\n", ColorRed, ColorBold, configPath, ColorReset, err)
+			os.Exit(1)
+		}
+
+		targets, err := configService.ResolveTargets(config, configService.TargetFlags{})
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+
+		// Backups are prefixed with the source folder name, as in the run command
+		pruneSource := source
+		if pruneSource == "" {
+			pruneSource, err = os.Getwd()
+			if err != nil {
+				fmt.Printf("%s%s❌ Error getting current directory:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+				os.Exit(1)
+			}
+		}
+		prefixName := filepath.Base(pruneSource)
+		if prefixName == "." || prefixName == "/" {
+			prefixName = "go-backup"
+		}
+
+		for _, target := range targets {
+			dest := target.GetDestination()
+			fmt.Printf("\n%s→ Target:%s %s\n", ColorBlue, ColorReset, dest)
+
+			if target.IsFileTarget() {
+				fmt.Printf("  %s📄 File target:%s No rotation applied (single file backup)\n", ColorCyan, ColorReset)
+				continue
+			}
+			if target.IsAppendOnly() {
+				fmt.Printf("  %s🔒 Append-only target:%s go-backup never deletes backups here\n", ColorCyan, ColorReset)
+				if target.MaxBackups > 0 {
+					fmt.Printf("  %smaxBackups (%d) is not applied; expire old backups with a lifecycle rule on the bucket or server%s\n", ColorDim, target.MaxBackups, ColorReset)
+				}
+				continue
+			}
+			if target.RotatesRemotely() {
+				// The backups of s3 and sftp sections are rotated by listing the remote directory
+				if err := pruneRemoteTarget(dest, prefixName+"-", target.MaxBackups, rotationOptions(config, target.Backups, pruneTrash).Keep, uploadOptions(target)); err != nil {
+					fmt.Printf("  %s⚠️  Warning: Failed to prune backups -%s %v\n", ColorYellow, ColorReset, err)
+				} else {
+					fmt.Printf("  %s🔄 Rotation:%s Keeping latest %d backups\n", ColorCyan, ColorReset, target.MaxBackups)
+				}
+				continue
+			}
+			if storageService.IsRemote(dest) {
+				fmt.Printf("  %s⚠️  Skipping: rotation is not supported for remote targets%s\n", ColorYellow, ColorReset)
+				continue
+			}
+			if _, err := os.Stat(dest); os.IsNotExist(err) {
+				fmt.Printf("  %s⚠️  Skipping: directory does not exist%s\n", ColorYellow, ColorReset)
+				continue
+			}
+
+			opts := rotationOptions(config, target.Backups, pruneTrash)
+			opts.Host = backupService.CurrentHost()
+			opts.Source, _ = filepath.Abs(pruneSource)
+			if err := backupService.RotateBackups(dest, prefixName+"-", target.MaxBackups, opts); err != nil {
+				fmt.Printf("  %s⚠️  Warning: Failed to prune backups -%s %v\n", ColorYellow, ColorReset, err)
+			} else {
+				fmt.Printf("  %s🔄 Rotation:%s Keeping latest %d backups\n", ColorCyan, ColorReset, target.MaxBackups)
+			}
+		}
+	},
+}
+
+func init() {
+	pruneCmd.Flags().BoolVar(&pruneTrash, "trash", false, "Move expired backups into the .trash/ subfolder instead of deleting them")
+	pruneCmd.Flags().StringVarP(&source, "source", "s", "", "Source directory whose backups are pruned (defaults to current directory)")
+	rootCmd.AddCommand(pruneCmd)
+}
+
+// pruneRemoteTarget applies the retention policy to a remote directory target
+func pruneRemoteTarget(dest, prefix string, maxBackups int, keep map[string]bool, opts storageService.UploadOptions) error {
+	remoteDir, err := storageService.ParseRemote(dest)
+	if err != nil {
+		return err
+	}
+	files, err := storageService.List(remoteDir)
+	if err != nil {
+		return err
+	}
+	return rotateRemoteTarget(remoteDir, files, "", prefix, maxBackups, keep, opts)
+}
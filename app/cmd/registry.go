@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	"github.com/spf13/cobra"
+)
+
+var discoverDryRun bool
+
+// registryCmd groups the commands that manage the global registry
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Manage the global backup registry",
+	Long: `Manage the global backup registry at ~/.config/go-backup/registry.yaml
+(or $XDG_CONFIG_HOME/go-backup/registry.yaml), which tracks the locations
+backed up by run-all. A registry at the older location ~/.backup.yaml is
+still used until it is moved with 'go-backup registry migrate'.
+See docs/global-registry.md for details.`,
+}
+
+// registryPathCmd prints where the global registry is read from
+var registryPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the path of the global registry",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := configService.GlobalRegistryPath()
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		fmt.Println(path)
+	},
+}
+
+// registryMigrateCmd moves the registry from ~/.backup.yaml to the config directory
+var registryMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move the registry from ~/.backup.yaml to ~/.config/go-backup/registry.yaml",
+	Long: `Move the global registry from its old location ~/.backup.yaml to
+~/.config/go-backup/registry.yaml (or $XDG_CONFIG_HOME/go-backup/registry.yaml).
+The contents are kept exactly, comments included.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		from, to, err := configService.MigrateGlobalRegistry()
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s✅ Moved the global registry:%s %s → %s\n", ColorGreen, ColorReset, from, to)
+	},
+}
+
+// registryDiscoverCmd registers every project with a .backup.yaml below a directory
+var registryDiscoverCmd = &cobra.Command{
+	Use:   "discover <directory>",
+	Short: "Register every project with a .backup.yaml below a directory",
+	Long: `Search a directory tree for directories containing .backup.yaml and add
+them to the global registry. Locations that are already registered are left
+untouched, so the command can be re-run whenever projects are added.
+Hidden directories, node_modules and vendor are not searched.
+
+Example:
+  go-backup registry discover ~/projects`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dirs, err := configService.DiscoverConfigDirs(args[0])
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		if len(dirs) == 0 {
+			fmt.Printf("%sNo directories with .backup.yaml found in %s.%s\n", ColorYellow, args[0], ColorReset)
+			return
+		}
+
+		if discoverDryRun {
+			fmt.Printf("%s%sFound %d location(s):%s\n", ColorCyan, ColorBold, len(dirs), ColorReset)
+			for _, dir := range dirs {
+				fmt.Printf("  %s\n", dir)
+			}
+			return
+		}
+
+		added, err := configService.RegisterGlobalLocations(dirs)
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			fmt.Printf("%sHint:%s Create ~/.config/go-backup/registry.yaml to track backup locations.\n", ColorDim, ColorReset)
+			os.Exit(1)
+		}
+
+		for _, dir := range added {
+			fmt.Printf("  %s+%s %s\n", ColorGreen, ColorReset, dir)
+		}
+		fmt.Printf("%s✅ Registered %d new location(s)%s, %d already tracked\n", ColorGreen, len(added), ColorReset, len(dirs)-len(added))
+	},
+}
+
+func init() {
+	registryDiscoverCmd.Flags().BoolVar(&discoverDryRun, "dry-run", false, "Only list the locations that were found")
+	registryCmd.AddCommand(registryDiscoverCmd)
+	registryCmd.AddCommand(registryPathCmd)
+	registryCmd.AddCommand(registryMigrateCmd)
+	rootCmd.AddCommand(registryCmd)
+}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	extractService "github.com/kennycyb/go-backup/internal/service/extract"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rehearseIfDue  bool
+	rehearseSample int
+)
+
+// rehearsalProblemsShown is how many differences from the manifest a failed rehearsal lists
+const rehearsalProblemsShown = 5
+
+// rehearseCmd restores random backups to a temporary directory to prove they can be restored
+var rehearseCmd = &cobra.Command{
+	Use:   "rehearse",
+	Short: "Test that backups can be restored",
+	Long: `Pick random backups from the history of the configured targets and restore each
+to a temporary directory: the archive is fetched and checked against SHA256SUMS,
+decrypted, extracted, and every file is compared with the backup's manifest.
+The temporary files are removed afterwards and the outcome is recorded in the
+config, where 'go-backup status' shows it.
+
+Schedule rehearsals in .backup.yaml; run-all then rehearses a location after
+its backup whenever the last rehearsal is older than the interval:
+
+  rehearsal:
+    every: 30d   # daily, weekly, monthly or a duration like 30d or 72h
+    sample: 1    # backups restored per rehearsal
+
+Exits with status 1 if a backup could not be restored.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		rehearseSource := source
+		if rehearseSource == "" {
+			var err error
+			rehearseSource, err = os.Getwd()
+			if err != nil {
+				fmt.Printf("%s%s❌ Error getting current directory:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+				os.Exit(1)
+			}
+		}
+		configPath := filepath.Join(rehearseSource, ".backup.yaml")
+		if cfgFile != "" {
+			configPath = cfgFile
+		}
+
+		config, err := configService.ReadBackupConfig(configPath)
+		if err != nil {
+			fmt.Printf("%s%s❌ Error reading config file %s:%s %v\n", ColorRed, ColorBold, configPath, ColorReset, err)
+			os.Exit(1)
+		}
+
+		if rehearseIfDue {
+			due, err := config.RehearsalDue(time.Now())
+			if err != nil {
+				fmt.Printf("%s%s❌ Error in configuration file:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+				os.Exit(1)
+			}
+			if !due {
+				fmt.Printf("%sNo restore rehearsal due%s\n", ColorDim, ColorReset)
+				return
+			}
+		}
+
+		sample := 1
+		if config.Rehearsal != nil {
+			sample = config.Rehearsal.SampleSize()
+		}
+		if cmd.Flags().Changed("sample") {
+			if rehearseSample < 1 {
+				fmt.Printf("%s%s❌ Error:%s --sample must be at least 1\n", ColorRed, ColorBold, ColorReset)
+				os.Exit(1)
+			}
+			sample = rehearseSample
+		}
+
+		targets, err := configService.ResolveTargets(config, configService.TargetFlags{})
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s%s🧪 Restore rehearsal for %s%s\n", ColorCyan, ColorBold, rehearseSource, ColorReset)
+		candidates := rehearsalCandidates(targets)
+		if len(candidates) == 0 {
+			fmt.Printf("%s⚠️  No backups to rehearse yet%s\n", ColorYellow, ColorReset)
+			recordRehearsal(config, configPath, configService.StatusSkipped, "no backups to rehearse")
+			return
+		}
+		rand.Shuffle(len(candidates), func(i, j int) {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		})
+		candidates = candidates[:min(sample, len(candidates))]
+
+		passphrase := ""
+		if config.Encryption != nil {
+			passphrase = config.Encryption.Passphrase
+		}
+
+		var passed, failed []string
+		for _, candidate := range candidates {
+			fmt.Printf("\n%s→ Restoring%s %s\n", ColorBlue, ColorReset, candidate.location)
+			restored, verified, err := rehearseBackup(candidate, passphrase)
+			if err != nil {
+				fmt.Printf("  %s❌ Failed:%s %v\n", ColorRed, ColorReset, err)
+				failed = append(failed, fmt.Sprintf("%s: %v", candidate.fileName, err))
+				continue
+			}
+			if verified {
+				fmt.Printf("  %s🔑 Checksum verified%s\n", ColorDim, ColorReset)
+			} else {
+				fmt.Printf("  %s⚠️  No checksum recorded for this backup%s\n", ColorYellow, ColorReset)
+			}
+			fmt.Printf("  %s✅ Restored %d entries matching the manifest%s\n", ColorGreen, restored, ColorReset)
+			passed = append(passed, candidate.fileName)
+		}
+
+		fmt.Println()
+		if len(failed) > 0 {
+			recordRehearsal(config, configPath, configService.StatusFailure, strings.Join(failed, "; "))
+			fmt.Printf("%s%s❌ %d of %d backup(s) could not be restored%s\n", ColorRed, ColorBold, len(failed), len(candidates), ColorReset)
+			os.Exit(1)
+		}
+		recordRehearsal(config, configPath, configService.StatusSuccess, "Restored "+strings.Join(passed, ", "))
+		fmt.Printf("%s%s🎉 %d backup(s) restored successfully%s\n", ColorGreen, ColorBold, len(passed), ColorReset)
+	},
+}
+
+func init() {
+	rehearseCmd.Flags().StringVarP(&source, "source", "s", "", "Source directory whose backups are rehearsed (defaults to current directory)")
+	rehearseCmd.Flags().BoolVar(&rehearseIfDue, "if-due", false, "Only rehearse if the config's rehearsal interval has passed")
+	rehearseCmd.Flags().IntVar(&rehearseSample, "sample", 1, "Number of backups to restore (overrides rehearsal.sample)")
+	rootCmd.AddCommand(rehearseCmd)
+}
+
+// rehearsalCandidates returns the archives in the targets' backup histories.
+// Metadata snapshots have no archive of their own and local backups that are gone are left out.
+func rehearsalCandidates(targets []configService.ResolvedTarget) []fetchCandidate {
+	var candidates []fetchCandidate
+	for _, target := range targets {
+		dest := target.GetDestination()
+		for _, record := range target.Backups {
+			if record.SnapshotOf != "" {
+				continue
+			}
+
+			if storageService.IsRemote(dest) {
+				remote, err := storageService.ParseRemote(dest)
+				if err != nil {
+					continue
+				}
+				if !target.IsFileTarget() {
+					remote = remote.Join(record.Filename)
+				}
+				candidates = append(candidates, fetchCandidate{location: remote.String(), fileName: remote.Base(), remote: remote})
+				continue
+			}
+
+			location := dest
+			if !target.IsFileTarget() {
+				location = filepath.Join(dest, record.Filename)
+			}
+			if _, err := os.Stat(location); err == nil {
+				candidates = append(candidates, fetchCandidate{location: location, fileName: filepath.Base(location)})
+			}
+			// A file target is replaced on every run, so only its latest backup exists
+			if target.IsFileTarget() {
+				break
+			}
+		}
+	}
+	return candidates
+}
+
+// rehearseBackup restores a backup into a temporary directory and compares it with its manifest.
+// It returns the number of restored entries and whether the archive's checksum was verified.
+func rehearseBackup(candidate fetchCandidate, passphrase string) (int, bool, error) {
+	tmpDir, err := os.MkdirTemp("", "go-backup-rehearsal-")
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	archivePath := filepath.Join(tmpDir, candidate.fileName)
+	verified, err := fetchBackup(candidate, archivePath)
+	if err != nil {
+		return 0, false, err
+	}
+
+	manifest, err := loadBackupManifest(candidate.location, tmpDir, passphrase)
+	if err != nil {
+		return 0, verified, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	restoreDir := filepath.Join(tmpDir, "restore")
+	// Immutable files would keep the rehearsal from being cleaned up
+	result, err := extractService.Archive(archivePath, restoreDir, extractService.Options{
+		ExtractOptions: compressionService.ExtractOptions{SkipAttributes: true},
+		Passphrase:     passphrase,
+		Chunks:         manifest.Chunks,
+	})
+	if err != nil {
+		return 0, verified, err
+	}
+
+	if problems := backupService.VerifyRestore(manifest, restoreDir); len(problems) > 0 {
+		shown := problems[:min(rehearsalProblemsShown, len(problems))]
+		for _, problem := range shown {
+			fmt.Printf("  %s│%s %s\n", ColorDim, ColorReset, problem)
+		}
+		return 0, verified, fmt.Errorf("%d entries differ from the manifest", len(problems))
+	}
+	return len(result.Restored), verified, nil
+}
+
+// recordRehearsal stores the outcome of the rehearsal in the config
+func recordRehearsal(config *configService.BackupConfig, configPath, status, message string) {
+	config.LastRehearsal = &configService.BackupStatus{Timestamp: config.Now(), Status: status, Message: message}
+	if err := configService.WriteBackupConfig(configPath, config); err != nil {
+		fmt.Printf("%s⚠️  Warning: Failed to record the rehearsal in %s:%s %v\n", ColorYellow, configPath, ColorReset, err)
+	}
+}
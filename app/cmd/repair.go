@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	"github.com/spf13/cobra"
+)
+
+var repairCheckOnly bool
+
+// repairCmd fixes damaged backups from their parity files
+var repairCmd = &cobra.Command{
+	Use:   "repair <archive>...",
+	Short: "Repair damaged backups from their parity files",
+	Long: `Check each backup against the PAR2 parity files written next to it and repair
+it in place if it is damaged, e.g. by bit errors on optical or archive media.
+Repaired backups are checked against the directory's SHA256SUMS as well.
+
+Parity files are written by targets with a parity setting in .backup.yaml:
+
+  target:
+    - path: /mnt/archive-disc
+      parity: 10%   # repairs damage to up to 10% of each backup
+
+Needs par2 (par2cmdline). Backups on remote targets must be fetched first,
+together with their .par2 files. With --check nothing is changed; the command
+exits with status 1 if a backup is damaged or cannot be repaired.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		failed := 0
+		for _, archivePath := range args {
+			fmt.Printf("%s→ Checking%s %s\n", ColorBlue, ColorReset, archivePath)
+			status, err := backupService.VerifyParity(archivePath)
+			if err != nil {
+				fmt.Printf("  %s❌ Error:%s %v\n", ColorRed, ColorReset, err)
+				failed++
+				continue
+			}
+
+			switch {
+			case status == backupService.ParityIntact:
+				fmt.Printf("  %s✅ Intact%s\n", ColorGreen, ColorReset)
+				continue
+			case status == backupService.ParityUnrepairable:
+				fmt.Printf("  %s❌ Damaged beyond repair:%s the parity files cannot recover this much damage\n", ColorRed, ColorReset)
+				failed++
+				continue
+			case repairCheckOnly:
+				fmt.Printf("  %s⚠️  Damaged, can be repaired:%s run without --check to repair it\n", ColorYellow, ColorReset)
+				failed++
+				continue
+			}
+
+			if err := repairArchive(archivePath); err != nil {
+				fmt.Printf("  %s❌ Repair failed:%s %v\n", ColorRed, ColorReset, err)
+				failed++
+				continue
+			}
+			fmt.Printf("  %s🛠️  Repaired%s\n", ColorGreen, ColorReset)
+
+			sums, err := backupService.ReadChecksums(filepath.Dir(archivePath))
+			if expected, ok := sums[filepath.Base(archivePath)]; err == nil && ok {
+				if sum, err := backupService.FileSHA256(archivePath); err != nil || sum != expected {
+					fmt.Printf("  %s❌ Error:%s the repaired backup does not match %s\n", ColorRed, ColorReset, backupService.ChecksumsFileName)
+					failed++
+					continue
+				}
+				fmt.Printf("  %s🔑 Checksum verified%s\n", ColorDim, ColorReset)
+			}
+		}
+
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	repairCmd.Flags().BoolVar(&repairCheckOnly, "check", false, "Only check the backups against their parity files, without repairing them")
+	rootCmd.AddCommand(repairCmd)
+}
+
+// repairArchive repairs a backup from its parity files. A read-only backup, as kept with
+// rotation.immutable, is made writable for the repair and protected again afterwards.
+func repairArchive(archivePath string) error {
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return err
+	}
+	protected := info.Mode().Perm()&0200 == 0
+	if protected {
+		if err := backupService.MakeMutable(archivePath); err != nil {
+			return err
+		}
+	}
+
+	repairErr := backupService.RepairWithParity(archivePath)
+	if protected {
+		if _, err := backupService.MakeImmutable(archivePath); err != nil && repairErr == nil {
+			return err
+		}
+	}
+	return repairErr
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	reconcileService "github.com/kennycyb/go-backup/internal/service/reconcile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	repairStateDryRun bool
+	repairStateForce  bool
+)
+
+// repairStateCmd reconciles the backup history with the backups the targets hold
+var repairStateCmd = &cobra.Command{
+	Use:   "repair-state",
+	Short: "Reconcile the backup history with the backups on the targets",
+	Long: `Bring the backup history in .backup.yaml back in line with the targets after
+backups were deleted, moved or copied by hand.
+
+For each target, the records of backups that are no longer there are removed,
+and the backups of the source that are missing from the history are added. Their
+records are rebuilt from the sidecar config or manifest kept next to them, or
+else from the timestamp in their name. Backups made on another host or from
+another directory are left out, as are those older than the backups the target
+keeps (maxBackups).
+
+When none of a target's recorded backups is found, e.g. because a drive is not
+mounted, its history is kept unless --force is given.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		configPath := ".backup.yaml"
+		if cfgFile != "" {
+			configPath = cfgFile
+		}
+		config, err := configService.ReadBackupConfig(configPath)
+		if err != nil {
+			fmt.Printf("%s%s❌ Error reading config file %s:%s %v\n", ColorRed, ColorBold, configPath, ColorReset, err)
+			os.Exit(1)
+		}
+		targets, err := configService.ResolveTargets(config, configService.TargetFlags{})
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		naming, err := config.Options.Naming()
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+
+		// Backups are prefixed with the source folder name, as in the run command
+		repairSource := source
+		if repairSource == "" {
+			repairSource, err = os.Getwd()
+			if err != nil {
+				fmt.Printf("%s%s❌ Error getting current directory:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+				os.Exit(1)
+			}
+		}
+		repairSource, _ = filepath.Abs(repairSource)
+		prefixName := filepath.Base(repairSource)
+		if prefixName == "." || prefixName == "/" {
+			prefixName = "go-backup"
+		}
+
+		opts := reconcileService.Options{Prefix: prefixName + "-", Source: repairSource, Naming: naming,
+			Force: repairStateForce, DryRun: repairStateDryRun}
+		removed, added, failed := 0, 0, 0
+		for _, target := range targets {
+			fmt.Printf("\n%s→ Target:%s %s\n", ColorBlue, ColorReset, target.GetDestination())
+			result, err := reconcileService.Target(config, target, opts)
+			if err != nil {
+				fmt.Printf("  %s⚠️  Not reachable:%s %v\n", ColorYellow, ColorReset, err)
+				failed++
+				continue
+			}
+			printReconciliation(result, target)
+			if len(result.Removed) == 0 && len(result.Added) == 0 {
+				fmt.Printf("  %s✓ History matches the target%s\n", ColorGreen, ColorReset)
+			}
+			removed += len(result.Removed)
+			added += len(result.Added)
+		}
+
+		if repairStateDryRun {
+			fmt.Printf("\n%sDry run: would remove %d and add %d record(s); the config was not changed%s\n", ColorDim, removed, added, ColorReset)
+			return
+		}
+		if removed+added > 0 {
+			if err := configService.WriteBackupConfig(configPath, config); err != nil {
+				fmt.Printf("\n%s%s❌ Error writing config file %s:%s %v\n", ColorRed, ColorBold, configPath, ColorReset, err)
+				os.Exit(1)
+			}
+		}
+		if failed > 0 {
+			fmt.Printf("\n%s%s❌ Removed %d and added %d record(s); %d target(s) could not be checked%s\n", ColorRed, ColorBold, removed, added, failed, ColorReset)
+			os.Exit(1)
+		}
+		fmt.Printf("\n%s%s🎉 Removed %d and added %d record(s)%s\n", ColorGreen, ColorBold, removed, added, ColorReset)
+	},
+}
+
+func init() {
+	repairStateCmd.Flags().BoolVarP(&repairStateDryRun, "dry-run", "n", false, "Show what would change without changing the config")
+	repairStateCmd.Flags().BoolVar(&repairStateForce, "force", false, "Drop the history of a target even when none of its recorded backups is found")
+	repairStateCmd.Flags().StringVarP(&source, "source", "s", "", "Source directory whose backups are added to the history (defaults to current directory)")
+	rootCmd.AddCommand(repairStateCmd)
+}
+
+// printReconciliation lists how the history of a target was changed and the backups left out
+func printReconciliation(result *reconcileService.Result, target configService.ResolvedTarget) {
+	if result.KeptMissing > 0 {
+		fmt.Printf("  %s⚠️  None of the %d recorded backup(s) is there; is the target mounted?%s Use --force to drop them\n", ColorYellow, result.KeptMissing, ColorReset)
+	}
+	for _, record := range result.Removed {
+		fmt.Printf("  %s- %s%s %s(no longer on the target)%s\n", ColorRed, record.Filename, ColorReset, ColorDim, ColorReset)
+	}
+	for _, name := range result.Unreadable {
+		fmt.Printf("  %s⚠️  Skipping %s:%s no readable sidecar and no timestamp in its name\n", ColorYellow, name, ColorReset)
+	}
+	for _, foreign := range result.Foreign {
+		fmt.Printf("  %sSkipping %s: made from %s%s\n", ColorDim, foreign.Name, foreign.MadeFrom, ColorReset)
+	}
+	for _, name := range result.TooOld {
+		fmt.Printf("  %sSkipping %s: older than the %d backups the target keeps%s\n", ColorDim, name, target.MaxBackups, ColorReset)
+	}
+	for _, addition := range result.Added {
+		if addition.FromSidecar {
+			fmt.Printf("  %s+ %s%s\n", ColorGreen, addition.Record.Filename, ColorReset)
+		} else {
+			fmt.Printf("  %s+ %s%s %s(from its name; no readable sidecar)%s\n", ColorGreen, addition.Record.Filename, ColorReset, ColorDim, ColorReset)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	replicateService "github.com/kennycyb/go-backup/internal/service/replicate"
+	"github.com/spf13/cobra"
+)
+
+var replicateDryRun bool
+
+// replicateCmd represents the replicate command
+var replicateCmd = &cobra.Command{
+	Use:   "replicate",
+	Short: "Copy backups that went to a fallback target to the other targets",
+	Long: `Catch targets up with the backups that went to a fallback target while they
+were unreachable, e.g. a NAS that was offline.
+
+For each directory target that is not a fallback, the backups in the history of
+the fallback targets that are missing from its own history are copied over with
+their manifest and config copy, added to its SHA256SUMS and recorded in its
+history. Backups older than those the target keeps (maxBackups) are left out,
+as are metadata snapshots. The backups stay on the fallback target, where the
+fallback's own rotation removes them.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		configPath := ".backup.yaml"
+		if cfgFile != "" {
+			configPath = cfgFile
+		}
+		config, err := configService.ReadBackupConfig(configPath)
+		if err != nil {
+			fmt.Printf("%s%s❌ Error reading config file %s:%s %v\n", ColorRed, ColorBold, configPath, ColorReset, err)
+			os.Exit(1)
+		}
+		targets, err := configService.ResolveTargets(config, configService.TargetFlags{})
+		if err != nil {
+			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+
+		plans := replicateService.Plans(config, targets)
+		if plans == nil {
+			fmt.Printf("%sNothing to replicate: %s needs a directory target with fallback: true and one without%s\n", ColorYellow, configPath, ColorReset)
+			return
+		}
+
+		copied, failed := 0, 0
+		for _, plan := range plans {
+			dest := plan.Target.GetDestination()
+			fmt.Printf("\n%s→ Target:%s %s\n", ColorBlue, ColorReset, dest)
+			for _, skipped := range plan.Skipped {
+				fmt.Printf("  %s⚠️  Skipping %s:%s its backups are encrypted or chunked differently\n", ColorYellow, skipped, ColorReset)
+			}
+
+			if len(plan.Missing) == 0 {
+				fmt.Printf("  %s✓ Up to date%s\n", ColorGreen, ColorReset)
+				continue
+			}
+			existing, err := replicateService.ListFiles(dest)
+			if err != nil {
+				fmt.Printf("  %s⚠️  Not reachable:%s %v\n", ColorYellow, ColorReset, err)
+				failed += len(plan.Missing)
+				continue
+			}
+
+			for _, replica := range plan.Missing {
+				from := replica.From.GetDestination()
+				if replicateDryRun {
+					fmt.Printf("  %sWould copy%s %s from %s\n", ColorDim, ColorReset, replica.Record.Filename, from)
+					continue
+				}
+				fmt.Printf("  %sCopying%s %s from %s\n", ColorDim, ColorReset, replica.Record.Filename, from)
+				result, err := replicateService.Copy(config, replica, plan.Target, existing, uploadOptions(plan.Target))
+				if err != nil {
+					fmt.Printf("  %s❌ Error:%s %v\n", ColorRed, ColorReset, err)
+					failed++
+					continue
+				}
+				for _, warning := range result.Warnings {
+					fmt.Printf("  %s⚠️  Warning:%s %s\n", ColorYellow, ColorReset, warning)
+				}
+				fmt.Printf("  %s✅ Copied%s %s\n", ColorGreen, ColorReset, replica.Record.Filename)
+				copied++
+
+				configService.InsertBackupRecord(config, dest, replica.Record)
+				configService.UpdateTargetStatus(config, dest, configService.StatusSuccess, "Replicated "+replica.Record.Filename+" from "+from)
+				if err := configService.WriteBackupConfig(configPath, config); err != nil {
+					fmt.Printf("  %s⚠️  Warning: Failed to update backup history in config -%s %v\n", ColorYellow, ColorReset, err)
+				}
+			}
+		}
+
+		switch {
+		case replicateDryRun:
+			fmt.Printf("\n%sDry run: nothing was copied%s\n", ColorDim, ColorReset)
+		case failed > 0:
+			fmt.Printf("\n%s%s❌ Replicated %d backup(s), %d could not be copied%s\n", ColorRed, ColorBold, copied, failed, ColorReset)
+			os.Exit(1)
+		default:
+			fmt.Printf("\n%s%s🎉 Replicated %d backup(s)%s\n", ColorGreen, ColorBold, copied, ColorReset)
+		}
+	},
+}
+
+func init() {
+	replicateCmd.Flags().BoolVarP(&replicateDryRun, "dry-run", "n", false, "Show what would be copied without copying it")
+	rootCmd.AddCommand(replicateCmd)
+}
@@ -1,13 +1,20 @@
 package cmd
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
 	configService "github.com/kennycyb/go-backup/internal/service/config"
 	encryptionService "github.com/kennycyb/go-backup/internal/service/encrypt"
+	extractService "github.com/kennycyb/go-backup/internal/service/extract"
+	gitService "github.com/kennycyb/go-backup/internal/service/git"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
 	"github.com/spf13/cobra"
 )
 
@@ -19,28 +26,130 @@ var (
 	useConfigFile bool
 	passphrase    string
 	askPassphrase bool
+	refreshCache  bool
+	salvage       bool
+
+	restoreFilesFrom string
+	restoreFilesNull bool
+	restoreYes       bool
+	restoreIncludes  []string
 )
 
+// salvageMaxLost is how many lost files a salvaged restore lists
+const salvageMaxLost = 20
+
+// overwriteMaxShown is how many files to be overwritten restore lists before asking
+const overwriteMaxShown = 20
+
 // restoreCmd represents the restore command
 var restoreCmd = &cobra.Command{
-	Use:   "restore",
+	Use:   "restore [paths...]",
 	Short: "Restore from a backup",
 	Long: `Restore files from a previously created backup.
-This command will extract and restore files from a backup archive.`,
+This command will extract and restore files from a backup archive.
+
+Files are restored into --target, or into a directory named after the backup
+in the current directory. Existing files are kept unless --overwrite is set.
+A truncated or corrupted archive fails the restore; with --salvage the files
+before the damage are restored, and the files that were lost are listed from
+the backup's manifest.
+
+With --files-from, only the listed paths are restored; a listed directory is
+restored with its contents. Paths are relative to the backup's root, one per
+line or, with -0, separated by NUL characters. Of a remote backup stored in
+chunks (a target's chunks setting), only the chunks holding the listed paths
+are downloaded from s3:// and rclone: locations.
+
+Paths given as arguments restore just those files or directories, and may be
+globs relative to the backup's root, e.g. 'src/**' or 'docs/*.md'. --include
+takes patterns with the syntax of excludes, so '*.go' matches at any depth and
+'!src/vendor/**' leaves out what an earlier pattern picked. The archive is read
+once and only the matching entries are written.
+
+With --overwrite, the existing files that would be replaced are listed first.
+In a git working tree each is marked committed, modified, untracked or ignored,
+since only committed files can be brought back with git. When run in a terminal,
+restore asks before replacing anything; --yes skips the question.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if targetDir == "" {
+			fileName := filepath.Base(backupFile)
+			if storageService.IsRemote(backupFile) {
+				if remote, err := storageService.ParseRemote(backupFile); err == nil {
+					fileName = remote.Base() // Leaves out the parameters of s3:// locations
+				}
+			}
+			targetDir = backupService.BackupBaseName(fileName)
+		}
+
 		fmt.Println("Restoring from backup...")
 		fmt.Printf("Backup file: %s\n", backupFile)
 		fmt.Printf("Target directory: %s\n", targetDir)
 		fmt.Printf("Overwrite existing: %v\n", overwrite)
 
+		// Read the list first, so a bad list fails before anything is downloaded or decrypted
+		var listedFiles []string
+		if restoreFilesFrom != "" {
+			paths, err := readFileList(restoreFilesFrom, restoreFilesNull)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if len(paths) == 0 {
+				fmt.Println("Nothing to restore: the file list is empty")
+				os.Exit(0)
+			}
+			listedFiles = paths
+			fmt.Printf("Files: %d listed in %s\n", len(listedFiles), restoreFilesFrom)
+		}
+
+		// Paths given as arguments are anchored at the backup's root; --include patterns match like excludes
+		var patterns []string
+		if len(args) > 0 || len(restoreIncludes) > 0 {
+			patterns = append(rootPatterns(args), restoreIncludes...)
+			if err := compressionService.ValidatePatterns(patterns); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Selecting: %s\n", strings.Join(append(append([]string(nil), args...), restoreIncludes...), ", "))
+		}
+
+		// Of a remote backup stored in chunks, only the chunks holding the selected files are downloaded
+		var chunksDir string
+		partial := false
+		if storageService.IsRemote(backupFile) && (listedFiles != nil || patterns != nil) {
+			var err error
+			if chunksDir, err = os.MkdirTemp("", "go-backup-chunks-"); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer os.RemoveAll(chunksDir)
+			localPath, ok, err := fetchListedChunks(backupFile, listedFiles, patterns, chunksDir, passphrase, refreshCache)
+			if err != nil {
+				fmt.Printf("Error fetching remote backup: %v\n", err)
+				os.RemoveAll(chunksDir)
+				os.Exit(1)
+			}
+			if ok {
+				backupFile, partial = localPath, true
+			}
+		}
+
+		// Download remote backups into the local cache, along with their associated config file
+		if storageService.IsRemote(backupFile) {
+			localPath, err := fetchRemoteBackup(backupFile, refreshCache)
+			if err != nil {
+				fmt.Printf("Error fetching remote backup: %v\n", err)
+				os.Exit(1)
+			}
+			backupFile = localPath
+		}
+		archiveFile := backupFile
+
 		// Process the backup file name
 		backupFileBaseName := filepath.Base(backupFile)
 
-		// Remove extension (could be .tar.gz or .tar.gz.gpg)
-		nameWithoutExt := strings.TrimSuffix(backupFileBaseName, filepath.Ext(backupFileBaseName))
-		if strings.HasSuffix(nameWithoutExt, ".tar") {
-			nameWithoutExt = strings.TrimSuffix(nameWithoutExt, ".tar")
-		}
+		// Remove extension (could be .tar.gz, .tar or .zip, possibly with .gpg)
+		nameWithoutExt := backupService.BackupBaseName(backupFileBaseName)
 
 		// Check for associated config file
 		associatedConfigFile := nameWithoutExt + ".backup.yaml"
@@ -59,8 +168,9 @@ This command will extract and restore files from a backup archive.`,
 			}
 		}
 
-		// Handle GPG encrypted backups
-		if decrypt || strings.HasSuffix(backupFile, ".gpg") {
+		// Handle GPG encrypted backups; the passphrase also decrypts the manifest when salvaging
+		usedPassphrase := ""
+		if (decrypt || strings.HasSuffix(backupFile, ".gpg")) && !partial {
 			fmt.Println("Detected GPG encrypted backup, decrypting...")
 
 			// Create temporary file path for the decrypted archive
@@ -101,7 +211,8 @@ This command will extract and restore files from a backup archive.`,
 			}
 
 			// Decrypt the backup file
-			decryptedPath, err := encryptionService.GPGDecrypt(backupFile, tempOutputFile, finalPassphrase)
+			usedPassphrase = finalPassphrase
+			decryptedPath, err := extractService.Decrypt(backupFile, tempOutputFile, finalPassphrase, archiveChunks(backupFile, finalPassphrase))
 			if err != nil {
 				// If decryption failed and we didn't explicitly ask for the passphrase, try prompting
 				if finalPassphrase == "" && !askPassphrase {
@@ -110,7 +221,8 @@ This command will extract and restore files from a backup archive.`,
 					fmt.Scanln(&promptedPassphrase)
 
 					// Retry decryption with the entered passphrase
-					decryptedPath, err = encryptionService.GPGDecrypt(backupFile, tempOutputFile, promptedPassphrase)
+					usedPassphrase = promptedPassphrase
+					decryptedPath, err = extractService.Decrypt(backupFile, tempOutputFile, promptedPassphrase, archiveChunks(backupFile, promptedPassphrase))
 					if err != nil {
 						fmt.Printf("Error decrypting backup: %v\n", err)
 						os.Exit(1)
@@ -130,20 +242,240 @@ This command will extract and restore files from a backup archive.`,
 			defer os.Remove(decryptedPath)
 		}
 
-		// TODO: Implement restore functionality using the (decrypted) backup file
+		// os.Exit skips the deferred removal of the decrypted archive
+		fail := func() {
+			if backupFile != archiveFile {
+				os.Remove(backupFile)
+			}
+			if chunksDir != "" {
+				os.RemoveAll(chunksDir)
+			}
+			os.Exit(1)
+		}
+
+		if overwrite && !confirmOverwrites(backupFile, targetDir, listedFiles, patterns) {
+			fmt.Println("Restore aborted.")
+			if backupFile != archiveFile {
+				os.Remove(backupFile)
+			}
+			if chunksDir != "" {
+				os.RemoveAll(chunksDir)
+			}
+			os.Exit(0)
+		}
+
+		// The archive is decrypted by now, for the overwrite check above
+		result, err := extractService.Archive(backupFile, targetDir, extractService.Options{ExtractOptions: compressionService.ExtractOptions{
+			Overwrite: overwrite, Salvage: salvage, Files: listedFiles, Patterns: patterns}})
+		if err != nil {
+			fmt.Printf("Error restoring backup: %v\n", err)
+			if !salvage && !errors.Is(err, compressionService.ErrUnsupportedArchive) {
+				fmt.Println("Use --salvage to restore the files before the damage if the archive is truncated or corrupted.")
+			}
+			fail()
+		}
+
+		fmt.Printf("Restored %d entries to %s\n", len(result.Restored), targetDir)
+		if len(result.Skipped) > 0 {
+			fmt.Printf("Kept %d existing file(s) (use --overwrite to replace them)\n", len(result.Skipped))
+		}
+		if len(result.Warnings) > 0 {
+			fmt.Printf("%d path(s) were restored without their capabilities or chattr flags (run as root to restore them):\n", len(result.Warnings))
+			for _, warning := range result.Warnings {
+				fmt.Printf("  - %s: %s\n", compressionService.QuotePath(warning.Path), warning.Message)
+			}
+		}
+
+		if result.Damage != nil {
+			reportSalvage(result, archiveFile, usedPassphrase, listedFiles, patterns)
+			fail()
+		}
+
+		if len(result.Missing) > 0 {
+			fmt.Printf("%d listed path(s) or pattern(s) matched nothing in the backup:\n", len(result.Missing))
+			for _, path := range result.Missing {
+				fmt.Printf("  - %s\n", compressionService.QuotePath(path))
+			}
+			fail()
+		}
+
 		fmt.Println("Restoration completed!")
 	},
 }
 
+// reportSalvage describes what a salvaged restore could not recover.
+// The lost files are listed from the manifest next to the archive, if there is one; with a
+// --files-from list or patterns, only the selected files count as lost.
+func reportSalvage(result *compressionService.ExtractResult, archiveFile, passphrase string, files, patterns []string) {
+	fmt.Printf("\nThe archive is damaged: %v\n", result.Damage)
+	if result.Damaged != "" {
+		fmt.Printf("Removed the incomplete file: %s\n", compressionService.QuotePath(result.Damaged))
+	}
+
+	tmpDir, err := os.MkdirTemp("", "go-backup-salvage-")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	baseName := backupService.BackupBaseName(filepath.Base(archiveFile))
+	var manifest *backupService.Manifest
+	for _, name := range []string{baseName + backupService.ManifestSuffix, baseName + backupService.ManifestSuffix + ".gpg"} {
+		manifestPath := filepath.Join(filepath.Dir(archiveFile), name)
+		if _, err := os.Stat(manifestPath); err != nil {
+			continue
+		}
+		if strings.HasSuffix(manifestPath, ".gpg") {
+			manifestPath, err = encryptionService.GPGDecrypt(manifestPath, filepath.Join(tmpDir, strings.TrimSuffix(name, ".gpg")), passphrase)
+			if err != nil {
+				fmt.Printf("Error decrypting manifest: %v\n", err)
+				return
+			}
+		}
+		if manifest, err = backupService.ReadManifest(manifestPath); err != nil {
+			fmt.Printf("Error reading manifest: %v\n", err)
+			return
+		}
+		break
+	}
+	if manifest == nil {
+		fmt.Println("No manifest found next to the backup, so the lost files cannot be listed.")
+		return
+	}
+
+	lost := backupService.MissingEntries(manifest, append(result.Restored, result.Skipped...))
+	if files != nil || patterns != nil {
+		// Only the selected files were to be restored
+		listed := lost[:0]
+		for _, entry := range lost {
+			if compressionService.InSelection(entry.Path, files, patterns) {
+				listed = append(listed, entry)
+			}
+		}
+		lost = listed
+	}
+	var lostSize int64
+	for _, entry := range lost {
+		lostSize += entry.Size
+	}
+	fmt.Printf("Lost %d of %d file(s), %s:\n", len(lost), manifest.Files, compressionService.FormatFileSize(lostSize))
+	for i, entry := range lost {
+		if i >= salvageMaxLost {
+			fmt.Printf("  ... and %d more\n", len(lost)-salvageMaxLost)
+			break
+		}
+		fmt.Printf("  %s\n", compressionService.QuotePath(entry.Path))
+	}
+}
+
+// confirmOverwrites lists the existing files in targetDir the archive would replace, with their
+// git state when targetDir is in a git working tree, and asks before replacing them when stdin
+// is a terminal. Returns false if the restore should stop.
+func confirmOverwrites(archiveFile, targetDir string, files, patterns []string) bool {
+	entries, err := compressionService.ListTarGzArchive(archiveFile)
+	if err != nil {
+		// The extraction reports a damaged archive
+		return true
+	}
+
+	var existing []string
+	for _, entry := range entries {
+		if entry.IsDir || !compressionService.InSelection(entry.Path, files, patterns) {
+			continue
+		}
+		if info, err := os.Lstat(filepath.Join(targetDir, filepath.FromSlash(entry.Path))); err == nil && !info.IsDir() {
+			existing = append(existing, entry.Path)
+		}
+	}
+	if len(existing) == 0 {
+		return true
+	}
+
+	tree, _ := gitService.ReadWorkTree(targetDir)
+	counts := make(map[gitService.FileState]int)
+	fmt.Printf("%d existing file(s) will be overwritten:\n", len(existing))
+	for i, path := range existing {
+		state := gitService.FileState("")
+		if tree != nil {
+			state = tree.State(path)
+			counts[state]++
+		}
+		if i == overwriteMaxShown {
+			fmt.Printf("  ... and %d more\n", len(existing)-overwriteMaxShown)
+		}
+		if i >= overwriteMaxShown {
+			continue
+		}
+		if state != "" {
+			fmt.Printf("  %-9s  %s\n", state, compressionService.QuotePath(path))
+		} else {
+			fmt.Printf("  %s\n", compressionService.QuotePath(path))
+		}
+	}
+	if tree != nil {
+		fmt.Printf("In git: %d committed, %d modified, %d untracked, %d ignored\n", counts[gitService.FileCommitted],
+			counts[gitService.FileModified], counts[gitService.FileUntracked], counts[gitService.FileIgnored])
+		if lost := len(existing) - counts[gitService.FileCommitted]; lost > 0 {
+			fmt.Printf("Committed files can be brought back with git; the changes in the other %d cannot.\n", lost)
+		}
+	}
+
+	if restoreYes || !stdinIsTerminal() {
+		return true
+	}
+	fmt.Printf("Overwrite %d file(s)? [y/N]: ", len(existing))
+	response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes"
+}
+
+// rootPatterns turns paths and globs relative to the backup's root into patterns anchored there,
+// so "README.md" is only the file at the root, unlike the pattern "README.md"
+func rootPatterns(paths []string) []string {
+	patterns := make([]string, 0, len(paths))
+	for _, path := range paths {
+		negate := strings.HasPrefix(path, "!")
+		path = "/" + strings.TrimLeft(strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(path, "!")), "./"), "/")
+		if negate {
+			path = "!" + path
+		}
+		patterns = append(patterns, path)
+	}
+	return patterns
+}
+
+// stdinIsTerminal reports whether standard input is a terminal, so questions can be answered
+func stdinIsTerminal() bool {
+	return isTerminal(os.Stdin)
+}
+
+// isTerminal reports whether the file is a terminal. The null device is a character device too,
+// but it is what cron and </dev/null give a command.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
+
 func init() {
 	// Local flags for the restore command
-	restoreCmd.Flags().StringVarP(&backupFile, "file", "f", "", "Backup file to restore from (required)")
-	restoreCmd.Flags().StringVarP(&targetDir, "target", "t", "", "Target directory to restore to")
+	restoreCmd.Flags().StringVarP(&backupFile, "file", "f", "", "Backup file to restore from, local or remote (s3://, sftp://, rclone:, exec:) (required)")
+	restoreCmd.Flags().StringVarP(&targetDir, "target", "t", "", "Target directory to restore to (defaults to a directory named after the backup)")
 	restoreCmd.Flags().BoolVarP(&overwrite, "overwrite", "o", false, "Overwrite existing files")
 	restoreCmd.Flags().BoolVarP(&decrypt, "decrypt", "d", false, "Force decrypt the backup file (auto-detected for .gpg files)")
 	restoreCmd.Flags().BoolVar(&useConfigFile, "use-config", true, "Use the associated backup configuration file if found")
 	restoreCmd.Flags().StringVar(&passphrase, "passphrase", "", "Passphrase for GPG decryption (if needed)")
 	restoreCmd.Flags().BoolVar(&askPassphrase, "ask-passphrase", false, "Prompt for a passphrase")
+	restoreCmd.Flags().BoolVar(&refreshCache, "refresh", false, "Download a remote backup again even if it is in the local cache")
+	restoreCmd.Flags().StringVar(&restoreFilesFrom, "files-from", "", "Restore only the paths listed in this file; - reads them from stdin")
+	restoreCmd.Flags().BoolVarP(&restoreFilesNull, "null", "0", false, "Paths in the --files-from list are separated by NUL characters")
+	restoreCmd.Flags().BoolVar(&salvage, "salvage", false, "Restore the files before a damaged part of the archive and list what was lost")
+	restoreCmd.Flags().StringArrayVar(&restoreIncludes, "include", nil, "Restore only entries matching this pattern, e.g. --include 'src/**' or --include '*.go'; repeat for several")
+	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "Overwrite existing files without asking")
 
 	// Mark required flags
 	restoreCmd.MarkFlagRequired("file")
@@ -151,3 +483,40 @@ func init() {
 	// Add command to root
 	rootCmd.AddCommand(restoreCmd)
 }
+
+// fetchRemoteBackup downloads a remote backup into the local cache and returns the local path.
+// The associated config file is fetched next to it if there is one.
+func fetchRemoteBackup(location string, refresh bool) (string, error) {
+	remote, err := storageService.ParseRemote(location)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Fetching %s...\n", remote)
+	localPath, cached, err := storageService.Fetch(remote, refresh)
+	if err != nil {
+		return "", err
+	}
+	if cached {
+		fmt.Printf("Using cached copy: %s (use --refresh to download again)\n", localPath)
+	} else {
+		fmt.Printf("Downloaded to: %s\n", localPath)
+	}
+
+	// Not every backup has a config file or manifest next to it, so a failure here is not an error
+	baseName := backupService.BackupBaseName(remote.Base())
+	configName := baseName + ".backup.yaml"
+	if _, _, err := storageService.Fetch(remote.Dir().Join(configName), refresh); err == nil {
+		fmt.Printf("Fetched associated config file: %s\n", configName)
+	}
+	// The manifest of an encrypted backup records its chunks, which are decrypted one by one
+	if salvage || strings.HasSuffix(remote.Base(), ".gpg") {
+		for _, name := range []string{baseName + backupService.ManifestSuffix, baseName + backupService.ManifestSuffix + ".gpg"} {
+			if _, _, err := storageService.Fetch(remote.Dir().Join(name), refresh); err == nil {
+				fmt.Printf("Fetched manifest: %s\n", name)
+			}
+		}
+	}
+
+	return localPath, nil
+}
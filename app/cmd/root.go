@@ -3,13 +3,22 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	i18nService "github.com/kennycyb/go-backup/internal/service/i18n"
+	updateService "github.com/kennycyb/go-backup/internal/service/update"
 	"github.com/spf13/cobra"
 )
 
+// noUpdateCheckEnv disables the release check when set, e.g. for the runs started by run-all
+const noUpdateCheckEnv = "GO_BACKUP_NO_UPDATE_CHECK"
+
 var (
 	// Used for flags
-	cfgFile string
+	cfgFile      string
+	workDir      string
+	strictConfig bool
 
 	// Version is set during build
 	Version string
@@ -24,12 +33,62 @@ var rootCmd = &cobra.Command{
 A simple backup utility written in Go that helps you manage
 your backup needs easily and efficiently.`,
 	Version: Version,
-	// If no subcommands or arguments are provided, show help
+	// Change into the --chdir directory before any command runs, like git -C or make -C
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if !skipUpdateCheck(cmd) {
+			notifyUpdate()
+		}
+		configService.SetStrict(strictConfig)
+		if workDir == "" {
+			return
+		}
+		if err := os.Chdir(workDir); err != nil {
+			fmt.Printf("Error: failed to change directory to %s: %v\n", workDir, err)
+			os.Exit(1)
+		}
+	},
+	// If no subcommands or arguments are provided, show help, unless the global registry's
+	// defaultCommand applies (see expandArgs)
 	Run: func(cmd *cobra.Command, args []string) {
 		cmd.Help()
 	},
 }
 
+// skipUpdateCheck reports whether the release check is left out for a command: for shell
+// completions, whose output the shell reads, and unless both stdout and stderr are terminals.
+// That covers runs under cron or a systemd timer, whose output goes to mail or the journal, as
+// well as pipes and CI.
+func skipUpdateCheck(cmd *cobra.Command) bool {
+	top := cmd
+	for top.HasParent() && top.Parent().HasParent() {
+		top = top.Parent()
+	}
+	switch top.Name() {
+	case "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	return !isTerminal(os.Stdout) || !isTerminal(os.Stderr)
+}
+
+// notifyUpdate prints a single dim notice when a newer release is available, so long-lived
+// scheduled installs do not keep running old versions unnoticed. The check is rate-limited by
+// the update service and every error is ignored, since it must never get in the way of a backup.
+func notifyUpdate() {
+	if os.Getenv(noUpdateCheckEnv) != "" {
+		return
+	}
+	if registry, err := configService.ReadGlobalRegistry(); err == nil && !registry.UpdateCheckEnabled() {
+		return
+	}
+
+	latest, err := updateService.Check(Version, time.Now(), updateService.CheckOptions{})
+	if err != nil || latest == "" {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%sgo-backup %s is available (you have %s): https://github.com/kennycyb/go-backup/releases"+
+		" (set updateCheck: false in the global registry to hide this)%s\n", ColorDim, latest, Version, ColorReset)
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute(version string) {
@@ -37,6 +96,28 @@ func Execute(version string) {
 	rootCmd.Version = version
 	rootCmd.SetVersionTemplate("go-backup version {{.Version}}\n")
 
+	// The language, aliases and default command come from the global registry. Without a readable
+	// registry there are none; commands report a broken registry themselves.
+	registry, err := configService.ReadGlobalRegistry()
+	if err != nil {
+		registry = nil
+	}
+	language := ""
+	if registry != nil {
+		language = registry.Language
+	}
+	i18nService.SetLanguage(i18nService.Detect(language))
+
+	args, err := expandArgs(registry, os.Args[1:])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if wantsPlain(args) {
+		os.Exit(runPlain(args))
+	}
+	rootCmd.SetArgs(args)
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -48,6 +129,9 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.go-backup.yaml)")
+	rootCmd.PersistentFlags().StringVarP(&workDir, "chdir", "C", "", "Run as if go-backup was started in this directory")
+	rootCmd.PersistentFlags().BoolVar(&strictConfig, "strict-config", false, "Reject unknown keys in .backup.yaml, e.g. a misspelled maxBackups, instead of ignoring them")
+	rootCmd.PersistentFlags().BoolVar(&plainOutput, "plain", false, "Plain output without colors, emoji and box drawing, e.g. for screen readers (or set "+plainOutputEnv+"=1)")
 
 	// Commands are added in their respective files' init() functions
 }
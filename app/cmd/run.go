@@ -3,9 +3,13 @@ package cmd
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,7 +18,15 @@ import (
 	configService "github.com/kennycyb/go-backup/internal/service/config"
 	encryptionService "github.com/kennycyb/go-backup/internal/service/encrypt"
 	gitService "github.com/kennycyb/go-backup/internal/service/git"
+	i18nService "github.com/kennycyb/go-backup/internal/service/i18n"
+	logsService "github.com/kennycyb/go-backup/internal/service/logs"
+	powerService "github.com/kennycyb/go-backup/internal/service/power"
+	priorityService "github.com/kennycyb/go-backup/internal/service/priority"
+	secretsService "github.com/kennycyb/go-backup/internal/service/secrets"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
+	systemlogService "github.com/kennycyb/go-backup/internal/service/systemlog"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
@@ -27,8 +39,48 @@ var (
 	encryptTo   string
 	copyConfig  bool
 	force       bool
+	saveConfig  bool
+
+	overwriteExisting  bool
+	runNice            bool
+	runCPULimit        int
+	compressionLevel   int
+	archiveFormat      string
+	metadataSnapshot   bool
+	runHome            bool
+	runTimeout         time.Duration
+	runOnError         string
+	runIncludeVCS      bool
+	runTags            []string
+	runMessage         string
+	runLogSyslog       bool
+	runSplitByDir      bool
+	runSplitPart       string
+	runFilesFrom       string
+	runFilesNull       bool
+	runNoChecksumCache bool
+	runStrict          bool
+	runReflink         bool
+	runScanSecrets     bool
+	runIgnorePower     bool
+	runIgnorePause     bool
 )
 
+// deferredExitCode is the exit status of a run deferred by options.requireAC or options.skipOnMetered,
+// EX_TEMPFAIL from sysexits.h, so run-all and schedulers can tell it from a failure
+const deferredExitCode = 75
+
+// runID identifies this run in its output, system log entries, manifests and history records.
+// run-all passes the ID it logs and notifies with; otherwise a new one is made.
+var runID string
+
+// runSyslog receives the results of the run when --log-syslog or options.syslog is set; nil otherwise
+var runSyslog *systemlogService.Logger
+
+// runReceivedTargets holds the destinations that received the backup in this run, as reported to
+// logTargetResult, so fallback targets can tell whether they are needed
+var runReceivedTargets = map[string]bool{}
+
 // runCmd represents the run command (previously backup command)
 var runCmd = &cobra.Command{
 	Use:   "run",
@@ -49,33 +101,52 @@ This command will package and compress the specified sources.`,
 			ColorDim    = "\033[2m"
 		)
 
-		fmt.Printf("%s%s\n==============================\n   📦  Starting Backup Job    \n==============================%s\n", ColorCyan, ColorBold, ColorReset)
+		fmt.Printf(i18nService.T("%s%s\n==============================\n   📦  Starting Backup Job    \n==============================%s\n"), ColorCyan, ColorBold, ColorReset)
+
+		// Home directory mode backs up the dotfiles in the home directory with its own config
+		if runHome {
+			if source != "" {
+				fmt.Printf(i18nService.T("%s%s❌ Error:%s --home cannot be combined with --source\n"), ColorRed, ColorBold, ColorReset)
+				os.Exit(1)
+			}
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				fmt.Printf(i18nService.T("%s%s❌ Error getting home directory:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+				os.Exit(1)
+			}
+			source = homeDir
+		}
 
 		// If source is empty, use current directory
 		if source == "" {
 			sourceDir, err := os.Getwd()
 			if err != nil {
-				fmt.Printf("%s%s❌ Error getting current directory:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+				fmt.Printf(i18nService.T("%s%s❌ Error getting current directory:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
 				os.Exit(1)
 			}
 			source = sourceDir
 		}
 
-		// Create a timestamp for the backup file
-		timestamp := time.Now().Format("20060102-150405")
-
 		// Get the current folder name for the backup file prefix
 		currentDir := filepath.Base(source)
 		if currentDir == "." || currentDir == "/" {
 			currentDir = "go-backup"
 		}
+		// Each part of a split backup is rotated on its own, e.g. project.clientA-20250520-123045.tar.gz
+		if runSplitPart != "" && runSplitPart != splitRootPart {
+			currentDir += "." + runSplitPart
+		}
 
-		backupFileName := fmt.Sprintf("%s-%s.tar.gz", currentDir, timestamp)
-		tempBackupPath := filepath.Join(os.TempDir(), backupFileName)
+		fmt.Printf(i18nService.T("%sSource:%s %s\n"), ColorDim, ColorReset, source)
 
-		fmt.Printf("%sSource:%s %s\n", ColorDim, ColorReset, source)
-		fmt.Printf("%sBackup name:%s %s\n", ColorDim, ColorReset, backupFileName)
-		fmt.Printf("%sTemporary backup file:%s %s\n", ColorDim, ColorReset, tempBackupPath)
+		// Every line of the output, the system log and the records of the run can be traced back to its ID
+		if runID == "" {
+			runID = backupService.NewRunID()
+		} else if err := backupService.ValidRunID(runID); err != nil {
+			fmt.Printf(i18nService.T("%s%s❌ Error:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		fmt.Printf(i18nService.T("%sRun ID:%s %s\n"), ColorDim, ColorReset, runID)
 
 		// Get excludes from config file
 		configExcludes := []string{} // Default empty list
@@ -86,17 +157,142 @@ This command will package and compress the specified sources.`,
 		if configFile != "" {
 			configPath = configFile
 		}
+		if runHome && !cmd.Flags().Changed("config") {
+			configPath = filepath.Join(source, configService.HomeConfigFileName)
+		}
 
 		var configErr error
 		config, configErr = configService.ReadBackupConfig(configPath)
+		configLoaded := configErr == nil
 		if configErr != nil {
-			fmt.Printf("Error reading config file %s: %v\n", configPath, configErr)
+			// A missing config is fine for ad-hoc backups where the destination is given explicitly
+			if !os.IsNotExist(configErr) || destination == "" {
+				fmt.Printf(i18nService.T("Error reading config file %s: %v\n"), configPath, configErr)
+				if os.IsNotExist(configErr) {
+					fmt.Println(i18nService.T("Run 'go-backup init' to create a config file, or use --dest for an ad-hoc backup."))
+				}
+				os.Exit(1)
+			}
+			fmt.Printf(i18nService.T("%sNo config file found at %s, running ad-hoc backup from flags%s\n"), ColorDim, configPath, ColorReset)
+			config = &configService.BackupConfig{}
+			if runHome {
+				fmt.Printf(i18nService.T("%sUsing the default home directory includes and excludes%s\n"), ColorDim, ColorReset)
+				config = configService.NewHomeConfig()
+			}
+		}
+
+		// Create a timestamp for the backup file, in the configured layout and time zone
+		naming, err := config.Options.Naming()
+		if err != nil {
+			fmt.Printf(i18nService.T("%s%s❌ Error in configuration file:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		timestamp := naming.Timestamp(time.Now())
+
+		defaultCompressionLevel, err := config.Options.CompressionLevel()
+		if err != nil {
+			fmt.Printf(i18nService.T("%s%s❌ Error in configuration file:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
 			os.Exit(1)
 		}
+		defaultFormat, err := config.Options.ArchiveFormat()
+		if err != nil {
+			fmt.Printf(i18nService.T("%s%s❌ Error in configuration file:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+
+		// Stop archiving and uploads once the run takes longer than the timeout; the flag overrides options.timeout
+		timeout, err := config.Options.RunTimeout()
+		if err != nil {
+			fmt.Printf(i18nService.T("%s%s❌ Error in configuration file:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		if cmd.Flags().Changed("timeout") {
+			timeout = runTimeout
+		}
+		ctx := context.Background()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+			fmt.Printf(i18nService.T("%sTimeout:%s %s\n"), ColorDim, ColorReset, timeout)
+		}
+
+		if runLogSyslog || (config.Options != nil && config.Options.Syslog) {
+			if runSyslog, err = systemlogService.Open(); err != nil {
+				fmt.Printf(i18nService.T("%s⚠️  Warning:%s %v\n"), ColorYellow, ColorReset, err)
+			}
+		}
+
+		// 'go-backup pause' holds backups of the source or of the whole machine; the parts of a split backup were checked once
+		if !runIgnorePause && runSplitPart == "" {
+			pause, paused, err := configService.PausedAt(source, time.Now())
+			if err != nil {
+				fmt.Printf(i18nService.T("%s⚠️  Warning:%s %v\n"), ColorYellow, ColorReset, err)
+			} else if paused {
+				fmt.Printf(i18nService.T("%s⏸️  Backup skipped:%s %s\n"), ColorYellow, ColorReset, pause.Describe())
+				fmt.Printf(i18nService.T("%sUse 'go-backup resume' to end the pause, or --ignore-pause to back up now%s\n"), ColorDim, ColorReset)
+				logRunResult(systemlogService.PriorityNotice, "backup skipped: "+pause.Describe())
+				if configLoaded {
+					configService.MarkTargetsSkipped(config, pause.Describe())
+					if err := configService.WriteBackupConfig(configPath, config); err != nil {
+						fmt.Printf(i18nService.T("%s⚠️  Warning: Failed to record skipped run in config -%s %v\n"), ColorYellow, ColorReset, err)
+					}
+				}
+				os.Exit(0)
+			}
+		}
+
+		// Laptops can put the backup off while on battery or a metered connection; the parts of a split backup were checked once
+		powerPolicy, err := config.Options.PowerPolicy()
+		if err != nil {
+			fmt.Printf(i18nService.T("%s%s❌ Error in configuration file:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		if powerPolicy.Enabled() && !runIgnorePower && runSplitPart == "" {
+			state := powerService.Current()
+			if powerPolicy.SkipOnMetered && !state.MeteredKnown {
+				fmt.Printf(i18nService.T("%s⚠️  Warning:%s cannot tell whether the connection is metered, backing up anyway\n"), ColorYellow, ColorReset)
+			}
+			if reason := powerPolicy.DeferReason(state); reason != "" {
+				fmt.Printf(i18nService.T("%s🔌 Backup deferred:%s %s\n"), ColorYellow, ColorReset, reason)
+				fmt.Printf(i18nService.T("%sIt runs again on AC power or an unmetered connection; use --ignore-power to back up now%s\n"), ColorDim, ColorReset)
+				logRunResult(systemlogService.PriorityNotice, "backup deferred: "+reason)
+				if configLoaded {
+					configService.MarkTargetsDeferred(config, reason)
+					if err := configService.WriteBackupConfig(configPath, config); err != nil {
+						fmt.Printf(i18nService.T("%s⚠️  Warning: Failed to record deferred run in config -%s %v\n"), ColorYellow, ColorReset, err)
+					}
+				}
+				os.Exit(deferredExitCode)
+			}
+		}
+
+		// Lower the priority before any work starts, so git and gpg inherit it
+		nice, cpuLimit := runNice, runCPULimit
+		if config.Options != nil {
+			nice = nice || config.Options.Nice
+			if !cmd.Flags().Changed("cpu-limit") {
+				cpuLimit = config.Options.CPULimit
+			}
+		}
+		if nice {
+			if err := priorityService.Lower(); err != nil {
+				fmt.Printf(i18nService.T("%s⚠️  Warning:%s %v\n"), ColorYellow, ColorReset, err)
+			} else {
+				fmt.Printf(i18nService.T("%sRunning at low CPU and IO priority%s\n"), ColorDim, ColorReset)
+			}
+		}
+		if cpuLimit > 0 {
+			priorityService.LimitCPU(cpuLimit)
+			fmt.Printf(i18nService.T("%sUsing at most %d CPU(s)%s\n"), ColorDim, cpuLimit, ColorReset)
+		}
 
-		// Check git status if git option is enabled
-		if config.Options != nil && config.Options.Git.Enable {
-			fmt.Printf("%s🔍 Checking git status...%s\n", ColorCyan, ColorReset)
+		// Check git status if git option is enabled; the parts of a split backup were checked once
+		if config.Options != nil && config.Options.Git.Enable && runSplitPart == "" {
+			fmt.Printf(i18nService.T("%s🔍 Checking git status...%s\n"), ColorCyan, ColorReset)
+			if err := gitService.SetImplementation(config.Options.Git.Implementation); err != nil {
+				fmt.Printf(i18nService.T("%s⚠️  Warning:%s %v\n"), ColorYellow, ColorReset, err)
+			}
 
 			// Check if auto-pull is enabled
 			shouldPull := config.Options.Git.Pull == "auto" && config.Options.Git.Branch != ""
@@ -106,25 +302,25 @@ This command will package and compress the specified sources.`,
 				// Check if we're on the configured branch
 				currentBranch, err := gitService.GetCurrentBranch(source)
 				if err != nil {
-					fmt.Printf("%s⚠️  Warning: Failed to get current branch:%s %v\n", ColorYellow, ColorReset, err)
-					fmt.Printf("%sContinuing with backup anyway...%s\n", ColorDim, ColorReset)
+					fmt.Printf(i18nService.T("%s⚠️  Warning: Failed to get current branch:%s %v\n"), ColorYellow, ColorReset, err)
+					fmt.Printf(i18nService.T("%sContinuing with backup anyway...%s\n"), ColorDim, ColorReset)
 				} else if currentBranch != config.Options.Git.Branch {
-					fmt.Printf("%s⚠️  Warning: Current branch '%s' does not match configured branch '%s'%s\n",
+					fmt.Printf(i18nService.T("%s⚠️  Warning: Current branch '%s' does not match configured branch '%s'%s\n"),
 						ColorYellow, currentBranch, config.Options.Git.Branch, ColorReset)
-					fmt.Printf("%sSkipping auto-pull. Continuing with backup...%s\n", ColorDim, ColorReset)
+					fmt.Printf(i18nService.T("%sSkipping auto-pull. Continuing with backup...%s\n"), ColorDim, ColorReset)
 				} else {
 					// We're on the right branch, pull latest changes
-					fmt.Printf("%s🔄 Auto-pull enabled on branch '%s'. Pulling latest changes...%s\n",
+					fmt.Printf(i18nService.T("%s🔄 Auto-pull enabled on branch '%s'. Pulling latest changes...%s\n"),
 						ColorCyan, config.Options.Git.Branch, ColorReset)
 					pulledUpdates, err := gitService.PullLatest(source)
 					if err != nil {
-						fmt.Printf("%s⚠️  Warning: Failed to pull latest changes:%s %v\n", ColorYellow, ColorReset, err)
-						fmt.Printf("%sContinuing with backup anyway...%s\n", ColorDim, ColorReset)
+						fmt.Printf(i18nService.T("%s⚠️  Warning: Failed to pull latest changes:%s %v\n"), ColorYellow, ColorReset, err)
+						fmt.Printf(i18nService.T("%sContinuing with backup anyway...%s\n"), ColorDim, ColorReset)
 					} else if pulledUpdates {
 						hasUpdatesFromPull = true
-						fmt.Printf("%s✓ Pulled latest changes successfully.%s\n", ColorGreen, ColorReset)
+						fmt.Printf(i18nService.T("%s✓ Pulled latest changes successfully.%s\n"), ColorGreen, ColorReset)
 					} else {
-						fmt.Printf("%s✓ Already up-to-date.%s\n", ColorGreen, ColorReset)
+						fmt.Printf(i18nService.T("%s✓ Already up-to-date.%s\n"), ColorGreen, ColorReset)
 					}
 				}
 			}
@@ -133,172 +329,528 @@ This command will package and compress the specified sources.`,
 			hasChanges, err := gitService.HasUncommittedChanges(source)
 			if err != nil {
 				// If it's not a git repository or git fails, just log a warning and continue
-				fmt.Printf("%s⚠️  Warning: Git check failed:%s %v\n", ColorYellow, ColorReset, err)
-				fmt.Printf("%sContinuing with backup anyway...%s\n", ColorDim, ColorReset)
+				fmt.Printf(i18nService.T("%s⚠️  Warning: Git check failed:%s %v\n"), ColorYellow, ColorReset, err)
+				fmt.Printf(i18nService.T("%sContinuing with backup anyway...%s\n"), ColorDim, ColorReset)
 			} else if !hasChanges && !hasUpdatesFromPull {
 				// No uncommitted changes and no updates from pull, skip the backup
-				fmt.Printf("%s✨ No uncommitted changes or updates detected. Backup skipped.%s\n", ColorGreen, ColorReset)
-				fmt.Printf("%sTo run backup anyway, disable git check in .backup.yaml (options.git.enable: false)%s\n", ColorDim, ColorReset)
+				fmt.Printf(i18nService.T("%s✨ No uncommitted changes or updates detected. Backup skipped.%s\n"), ColorGreen, ColorReset)
+				fmt.Printf(i18nService.T("%sTo run backup anyway, disable git check in .backup.yaml (options.git.enable: false)%s\n"), ColorDim, ColorReset)
+				logRunResult(systemlogService.PriorityNotice, "backup skipped: no uncommitted changes or updates from pull")
+				if configLoaded {
+					configService.MarkTargetsSkipped(config, "no uncommitted changes or updates from pull")
+					if err := configService.WriteBackupConfig(configPath, config); err != nil {
+						fmt.Printf(i18nService.T("%s⚠️  Warning: Failed to record skipped run in config -%s %v\n"), ColorYellow, ColorReset, err)
+					}
+				}
 				os.Exit(0)
 			} else {
 				if hasChanges {
-					fmt.Printf("%s✓ Uncommitted changes detected. Proceeding with backup...%s\n", ColorGreen, ColorReset)
+					fmt.Printf(i18nService.T("%s✓ Uncommitted changes detected. Proceeding with backup...%s\n"), ColorGreen, ColorReset)
 				}
 				if hasUpdatesFromPull {
-					fmt.Printf("%s✓ Updates pulled from remote. Proceeding with backup...%s\n", ColorGreen, ColorReset)
+					fmt.Printf(i18nService.T("%s✓ Updates pulled from remote. Proceeding with backup...%s\n"), ColorGreen, ColorReset)
 				}
 			}
 		}
 
 		if len(config.Excludes) > 0 {
 			configExcludes = config.Excludes
-			fmt.Printf("%sUsing excludes from config:%s %v\n", ColorDim, ColorReset, configExcludes)
+			fmt.Printf(i18nService.T("%sUsing excludes from config:%s %v\n"), ColorDim, ColorReset, configExcludes)
 		} else {
 			configExcludes = excludeDirs
-			fmt.Printf("%sUsing default excludes:%s %v\n", ColorDim, ColorReset, configExcludes)
+			fmt.Printf(i18nService.T("%sUsing default excludes:%s %v\n"), ColorDim, ColorReset, configExcludes)
+		}
+		// Repositories backed up for their full history keep their version control directories
+		if runIncludeVCS || (config.Options != nil && config.Options.IncludeVCS) {
+			configExcludes = compressionService.WithoutVCS(configExcludes)
+			fmt.Printf(i18nService.T("%sIncluding version control directories (%s)%s\n"), ColorDim, strings.Join(compressionService.VCSDirs, ", "), ColorReset)
+		}
+		// Bundles shared outside leave out dotfiles such as .env and .ssh, except the allowed ones
+		if config.Options.ExcludesHidden() {
+			configExcludes = withoutHidden(config.Options, configExcludes, runIncludeVCS)
+			if len(config.Options.HiddenAllow) > 0 {
+				fmt.Printf(i18nService.T("%sLeaving out hidden files except:%s %v\n"), ColorDim, ColorReset, config.Options.HiddenAllow)
+			} else {
+				fmt.Printf(i18nService.T("%sLeaving out hidden files%s\n"), ColorDim, ColorReset)
+			}
+		}
+		if err := compressionService.ValidateExcludes(configExcludes); err != nil {
+			fmt.Printf(i18nService.T("%s%s❌ Error:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		if len(config.Includes) > 0 {
+			fmt.Printf(i18nService.T("%sUsing includes from config:%s %v\n"), ColorDim, ColorReset, config.Includes)
+			if err := compressionService.ValidateExcludes(config.Includes); err != nil {
+				fmt.Printf(i18nService.T("%s%s❌ Error in includes:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+				os.Exit(1)
+			}
+		}
+		if err := compressionService.ValidateExcludes(config.NoCompress); err != nil {
+			fmt.Printf(i18nService.T("%s%s❌ Error in noCompress:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		// Unreadable files are skipped with a warning unless options.onError or --on-error says fail
+		onError := config.Options
+		if cmd.Flags().Changed("on-error") {
+			onError = &configService.Options{OnError: runOnError}
+		}
+		skipUnreadable, err := onError.SkipUnreadable()
+		if err != nil {
+			fmt.Printf(i18nService.T("%s%s❌ Error:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		if err := configService.ValidateTags(runTags); err != nil {
+			fmt.Printf(i18nService.T("%s%s❌ Error:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		if len(runTags) > 0 {
+			fmt.Printf(i18nService.T("%sTags:%s %s\n"), ColorDim, ColorReset, strings.Join(runTags, ", "))
+		}
+		if runMessage != "" {
+			fmt.Printf(i18nService.T("%sMessage:%s %s\n"), ColorDim, ColorReset, runMessage)
+		}
+		if cmd.Flags().Changed("compression-level") && (compressionLevel < 1 || compressionLevel > 9) {
+			fmt.Printf(i18nService.T("%s%s❌ Error:%s --compression-level must be between 1 (fastest) and 9 (smallest)\n"), ColorRed, ColorBold, ColorReset)
+			os.Exit(1)
+		}
+		if err := compressionService.ValidateFormat(archiveFormat); err != nil {
+			fmt.Printf(i18nService.T("%s%s❌ Error:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+
+		// Determine destinations from config or command line argument
+		targets, err := configService.ResolveTargets(config, configService.TargetFlags{Destination: destination})
+		if err != nil {
+			fmt.Printf(i18nService.T("%s%s❌ Error:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		// A misspelt method such as "GPG" would otherwise store the backups unencrypted without a word
+		if _, err := configService.EncryptionRecipients(config); err != nil {
+			fmt.Printf(i18nService.T("%s%s❌ Error:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+			logRunResult(systemlogService.PriorityErr, "backup failed: "+err.Error())
+			os.Exit(1)
+		}
+		// Fallback targets come last, once it is known whether another target received the backup
+		sort.SliceStable(targets, func(i, j int) bool { return !targets[i].Fallback && targets[j].Fallback })
+
+		// Never archive destinations that live inside the source, or each backup would contain the previous ones
+		targetDestinations := []string{}
+		var localDestinations []string
+		for _, target := range targets {
+			targetDestinations = append(targetDestinations, target.GetDestination())
+			if !storageService.IsRemote(target.GetDestination()) {
+				localDestinations = append(localDestinations, target.GetDestination())
+			}
+		}
+		selfExcludes, err := backupService.DestinationsInSource(source, localDestinations)
+		if err != nil {
+			fmt.Printf(i18nService.T("%s%s❌ Error:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+		for _, relPath := range selfExcludes {
+			fmt.Printf(i18nService.T("%s⚠️  Warning: Destination '%s' is inside the source, excluding it from the backup%s\n"), ColorYellow, relPath, ColorReset)
+			configExcludes = append(configExcludes, relPath)
+		}
+		archiveExcludes := compressionService.IncludeOnly(config.Includes, configExcludes)
+
+		// A split backup runs once for every top-level directory, each archiving only its part
+		if runSplitByDir {
+			if runFilesFrom != "" {
+				fmt.Printf(i18nService.T("%s%s❌ Error:%s --split-by-dir cannot be combined with --files-from\n"), ColorRed, ColorBold, ColorReset)
+				os.Exit(1)
+			}
+			for _, target := range targets {
+				if target.IsFileTarget() {
+					fmt.Printf(i18nService.T("%s%s❌ Error:%s --split-by-dir needs directory targets, but %s is a file target\n"), ColorRed, ColorBold, ColorReset, target.GetDestination())
+					os.Exit(1)
+				}
+			}
+			os.Exit(runSplitParts(source, archiveExcludes))
+		}
+		if runSplitPart != "" {
+			partExcludes, err := splitPartExcludes(source, runSplitPart)
+			if err != nil {
+				fmt.Printf(i18nService.T("%s%s❌ Error:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+				os.Exit(1)
+			}
+			archiveExcludes = append(archiveExcludes, partExcludes...)
+			fmt.Printf(i18nService.T("%sPart:%s %s\n"), ColorDim, ColorReset, splitPartLabel(runSplitPart))
+		}
+
+		// With --files-from, only the listed paths are archived instead of the whole source
+		var listedFiles []string
+		if runFilesFrom != "" {
+			paths, err := readFileList(runFilesFrom, runFilesNull)
+			if err == nil {
+				listedFiles, err = compressionService.CleanFileList(source, paths)
+			}
+			if err != nil {
+				fmt.Printf(i18nService.T("%s%s❌ Error:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+				os.Exit(1)
+			}
+			if len(listedFiles) == 0 {
+				fmt.Printf(i18nService.T("%s⚠️  Nothing to back up: the file list is empty%s\n"), ColorYellow, ColorReset)
+				os.Exit(0)
+			}
+			fmt.Printf(i18nService.T("%sFiles:%s %d listed in %s\n"), ColorDim, ColorReset, len(listedFiles), runFilesFrom)
+		}
+
+		// Pick a name that does not collide with existing backups, e.g. from two runs within the same second
+		directoryTargets := []string{}
+		for _, target := range targets {
+			if !target.IsFileTarget() {
+				directoryTargets = append(directoryTargets, target.GetDestination())
+			}
+		}
+		nameSuffixes := []string{backupService.ManifestSuffix}
+		for _, ext := range compressionService.ArchiveExtensions {
+			nameSuffixes = append(nameSuffixes, ext, ext+".gpg")
+		}
+		backupBaseName := backupService.UniqueBackupName(directoryTargets, fmt.Sprintf("%s-%s", currentDir, timestamp), nameSuffixes)
+
+		// Custom pipeline steps see the source before anything is archived and may add excludes
+		pipeline := &backupService.PipelineState{Source: source, BaseName: backupBaseName, Excludes: archiveExcludes, Targets: targetDestinations}
+		runPipelineStage(ctx, backupService.StageScan, pipeline, nil)
+		runPipelineStage(ctx, backupService.StageFilter, pipeline, nil)
+		archiveExcludes = pipeline.Excludes
+
+		// Encryption flags override the config's top-level encryption; targets may override both
+		var defaultEncryption *configService.EncryptionConfig
+		if encrypt {
+			defaultEncryption = &configService.EncryptionConfig{Method: "gpg", Receiver: encryptTo}
+			if encryptTo == "" && config.Encryption != nil {
+				defaultEncryption.Receiver = config.Encryption.Receiver
+			}
+		} else if config.Encryption != nil && config.Encryption.Method == "gpg" {
+			defaultEncryption = &configService.EncryptionConfig{Method: "gpg", Receiver: config.Encryption.Receiver}
+			if encryptTo != "" {
+				defaultEncryption.Receiver = encryptTo
+			}
+		}
+
+		// --compression-level and --format apply to every target for this run only; the config is not changed.
+		// Otherwise options.compression applies to the targets without a level or format of their own.
+		for i := range targets {
+			compression := configService.CompressionConfig{Level: defaultCompressionLevel, Format: defaultFormat}
+			if own := targets[i].Compression; own != nil {
+				if own.Level != 0 {
+					compression.Level = own.Level
+				}
+				if own.Format != "" {
+					compression.Format = own.Format
+				}
+			}
+			if cmd.Flags().Changed("compression-level") {
+				compression.Level = compressionLevel
+			}
+			if archiveFormat != "" {
+				compression.Format = archiveFormat
+			}
+			if compression != (configService.CompressionConfig{}) {
+				targets[i].Compression = &compression
+			}
+		}
+
+		// Work out which archive variants the targets need, e.g. a fast local copy and a small encrypted cloud copy
+		artifacts, targetArtifacts, err := planBackupArtifacts(targets, defaultEncryption, backupBaseName)
+		if err != nil {
+			fmt.Printf(i18nService.T("%s%s❌ Error:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+			fmt.Println(i18nService.T("Please specify a recipient using --encrypt-to flag or in the config file"))
+			os.Exit(1)
+		}
+
+		fmt.Printf(i18nService.T("%sBackup name:%s %s\n"), ColorDim, ColorReset, backupBaseName+compressionService.FormatExtension(artifacts[0].format))
+		for _, artifact := range artifacts {
+			fmt.Printf(i18nService.T("%sTemporary backup file:%s %s\n"), ColorDim, ColorReset, artifact.path)
 		}
 
 		// Check for potentially problematic file sizes before creating archive
-		fmt.Printf("%sAnalyzing files for potential size issues...%s\n", ColorDim, ColorReset)
-		fileSummary, sizeErr := compressionService.CheckFileSizes(source, configExcludes, 8) // 8GB is the standard tar size limit
+		fmt.Printf(i18nService.T("%sAnalyzing files for potential size issues...%s\n"), ColorDim, ColorReset)
+		fileSummary, sizeErr := compressionService.CheckFileSizes(source, archiveExcludes, 8) // 8GB is the standard tar size limit
+		if sizeErr == nil && listedFiles != nil {
+			fileSummary.FilesOverSize = onlyListedFiles(fileSummary.FilesOverSize, listedFiles)
+		}
+		var warnings []compressionService.Warning
+		if sizeErr == nil {
+			for _, file := range fileSummary.FilesOverSize {
+				warnings = append(warnings, compressionService.Warning{Kind: compressionService.WarningOversize, Path: filepath.ToSlash(file), Message: "larger than the recommended 8 GB for tar archives"})
+			}
+		}
 		if sizeErr != nil {
-			fmt.Printf("%s%s⚠️ Warning: Unable to analyze file sizes:%s %v\n", ColorYellow, ColorBold, ColorReset, sizeErr)
+			fmt.Printf(i18nService.T("%s%s⚠️ Warning: Unable to analyze file sizes:%s %v\n"), ColorYellow, ColorBold, ColorReset, sizeErr)
 		} else if len(fileSummary.FilesOverSize) > 0 {
-			fmt.Printf("%s%s⚠️ Warning: %d files exceed the recommended size limit for tar archives:%s\n",
+			fmt.Printf(i18nService.T("%s%s⚠️ Warning: %d files exceed the recommended size limit for tar archives:%s\n"),
 				ColorYellow, ColorBold, len(fileSummary.FilesOverSize), ColorReset)
 			for i, file := range fileSummary.FilesOverSize {
 				if i < 5 { // Only show the first 5 files
-					fmt.Printf("  - %s (%.2f GB)\n", file, float64(fileSummary.LargestFileSize)/(1024*1024*1024))
+					fmt.Printf("  - %s (%.2f GB)\n", compressionService.QuotePath(file), float64(fileSummary.LargestFileSize)/(1024*1024*1024))
 				} else {
-					fmt.Printf("  - ... and %d more\n", len(fileSummary.FilesOverSize)-5)
+					fmt.Printf(i18nService.T("  - ... and %d more\n"), len(fileSummary.FilesOverSize)-5)
 					break
 				}
 			}
-			fmt.Printf("%sConsider excluding these files or using the --split option for large files%s\n",
+			fmt.Printf(i18nService.T("%sConsider excluding these files or using the --split option for large files%s\n"),
 				ColorDim, ColorReset)
 
 			// If force flag is not set, ask for confirmation
 			if !force {
 				reader := bufio.NewReader(os.Stdin)
-				fmt.Printf("%sContinue with backup anyway? [y/N]:%s ", ColorYellow, ColorReset)
+				fmt.Printf(i18nService.T("%sContinue with backup anyway? [y/N]:%s "), ColorYellow, ColorReset)
 				response, _ := reader.ReadString('\n')
 				response = strings.TrimSpace(strings.ToLower(response))
 				if response != "y" && response != "yes" {
-					fmt.Println("Backup aborted.")
+					fmt.Println(i18nService.T("Backup aborted."))
 					os.Exit(0)
 				}
 			}
 		}
 
-		// Create the tar.gz archive using the compression service
-		err := compressionService.CreateTarGzArchive(source, tempBackupPath, configExcludes)
+		// Warn before files that look like secrets leave the machine unencrypted
+		if runScanSecrets || (config.Options != nil && config.Options.SecretScan) {
+			if remotes := unencryptedRemoteTargets(targets, artifacts, targetArtifacts); len(remotes) > 0 {
+				warnings = append(warnings, scanSecrets(source, archiveExcludes, listedFiles, remotes)...)
+			}
+		}
+
+		// For ad-hoc backups with --save-config, build the equivalent config so history
+		// and status are recorded and the file is written alongside the first backup
+		persistConfig := configLoaded
+		if !configLoaded && saveConfig {
+			config.Excludes = configExcludes
+			for _, target := range targets {
+				config.Targets = append(config.Targets, target.BackupTarget)
+			}
+			config.Encryption = defaultEncryption
+			persistConfig = true
+		} else if configLoaded && saveConfig {
+			fmt.Printf(i18nService.T("%sConfig file %s already exists, --save-config has no effect%s\n"), ColorDim, configPath, ColorReset)
+		}
+
+		// Metadata snapshots need the history to find the previous manifest
+		useSnapshots := persistConfig && (metadataSnapshot || (config.Options != nil && config.Options.MetadataSnapshots))
+
+		// The checksum cache lets later runs tell that the source is unchanged without reading its files
+		archiveOptions := compressionService.ArchiveOptions{Excludes: archiveExcludes, SkipUnreadable: skipUnreadable, Files: listedFiles}
+		if useSnapshots && listedFiles == nil && !runNoChecksumCache {
+			if cachePath, err := checksumCachePath(source); err == nil {
+				archiveOptions.Checksums = compressionService.LoadHashCache(cachePath)
+			}
+		}
+
+		// When every target would only record a metadata snapshot, no archive is needed at all
+		var archive *compressionService.ArchiveResult
+		snapshotsOnly := false
+		if archiveOptions.Checksums != nil && snapshotTargets(targets, artifacts, targetArtifacts) {
+			fmt.Printf(i18nService.T("%sScanning for changes since the last backup...%s\n"), ColorDim, ColorReset)
+			scan, err := compressionService.ScanSourceContext(ctx, source, archiveOptions)
+			if err == nil {
+				cached, hashed := archiveOptions.Checksums.Stats()
+				fmt.Printf(i18nService.T("%sChecksums:%s %d cached, %d read\n"), ColorDim, ColorReset, cached, hashed)
+			}
+			if err == nil && unchangedForTargets(targets, scan.Entries) {
+				fmt.Printf(i18nService.T("%sNo file contents changed: recording metadata snapshots without an archive%s\n"), ColorDim, ColorReset)
+				archive = scan
+				snapshotsOnly = true
+			}
+		}
+
+		// Create all archive variants in a single pass over the source
+		compressionStats := &compressionService.ArchiveStats{}
+		if archive == nil {
+			if len(artifacts) > 1 {
+				fmt.Printf(i18nService.T("%sCreating %d archive variants in one pass...%s\n"), ColorDim, len(artifacts), ColorReset)
+			}
+			outputs := make([]compressionService.ArchiveOutput, 0, len(artifacts))
+			for _, artifact := range artifacts {
+				outputs = append(outputs, compressionService.ArchiveOutput{Path: artifact.path, Format: artifact.format, Level: artifact.level,
+					NoCompress: config.NoCompress, ChunkSize: artifact.chunkSize, Chunks: &artifact.chunks, SHA256: &artifact.checksum})
+			}
+			// Collect stats from the first variant only; they are reported at the end of the run
+			outputs[0].Stats = compressionStats
+			archive, err = compressionService.CreateTarGzArchivesContext(ctx, source, outputs, archiveOptions)
+		}
 		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				recordTimeout(config, configPath, configLoaded, timeout, targets)
+				removeBackupArtifacts(artifacts)
+				os.Exit(1)
+			}
 			if strings.Contains(err.Error(), "too large for tar format") {
-				fmt.Printf("%s%s❌ Error creating backup archive:%s %v\n", ColorRed, ColorBold, ColorReset, err)
-				fmt.Printf("%sSuggestion: Use --exclude to skip large files or consider using a different backup strategy for very large files%s\n",
+				fmt.Printf(i18nService.T("%s%s❌ Error creating backup archive:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+				fmt.Printf(i18nService.T("%sSuggestion: Use --exclude to skip large files or consider using a different backup strategy for very large files%s\n"),
 					ColorYellow, ColorReset)
 			} else {
-				fmt.Printf("%s%s❌ Error creating backup archive:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+				fmt.Printf(i18nService.T("%s%s❌ Error creating backup archive:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
 			}
+			logRunResult(systemlogService.PriorityErr, "backup failed: error creating backup archive", systemlogService.Field{Key: "ERROR", Value: err.Error()})
+			removeBackupArtifacts(artifacts)
 			os.Exit(1)
 		}
+		entries := archive.Entries
+		warnings = append(warnings, archive.Warnings...)
 
-		// Handle encryption if requested or configured
-		useEncryption := encrypt
-		encryptionReceiver := encryptTo
-		if !useEncryption && config != nil && config.Encryption != nil {
-			if config.Encryption.Method == "gpg" {
-				useEncryption = true
-				if encryptionReceiver == "" {
-					encryptionReceiver = config.Encryption.Receiver
-				}
+		if checksums := archiveOptions.Checksums; checksums != nil {
+			if err := checksums.Save(); err != nil {
+				fmt.Printf(i18nService.T("%s%s⚠️  Warning: Failed to update the checksum cache:%s %v\n"), ColorYellow, ColorBold, ColorReset, err)
 			}
 		}
 
-		// Apply encryption if enabled
-		if useEncryption {
-			if encryptionReceiver == "" {
-				fmt.Printf("%s%s❌ Error:%s GPG encryption enabled but no recipient specified\n", ColorRed, ColorBold, ColorReset)
-				fmt.Println("Please specify a recipient using --encrypt-to flag or in the config file")
+		// Record how each variant is produced in its manifest and history records
+		for _, artifact := range artifacts {
+			artifact.provenance = backupService.NewProvenance(Version, artifact.level, artifact.receiver, artifact.chunkSize, archiveExcludes)
+		}
+
+		// Only variants that were archived get a manifest, pipeline entry and encryption
+		archived := artifacts
+		if snapshotsOnly {
+			archived = nil
+		}
+
+		// Write a manifest of the archived files next to each variant, with the warnings of the run
+		for _, artifact := range archived {
+			artifact.manifestPath = backupService.BackupBaseName(artifact.path) + backupService.ManifestSuffix
+			manifest := backupService.NewManifest(artifact.fileName, source, entries)
+			manifest.Warnings = warnings
+			manifest.RunID = runID
+			manifest.Tags = runTags
+			manifest.Message = runMessage
+			manifest.Chunks = artifact.chunks
+			manifest.Provenance = artifact.provenance
+			if err := backupService.WriteManifest(artifact.manifestPath, manifest); err != nil {
+				fmt.Printf(i18nService.T("%s%s❌ Error writing backup manifest:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+				logRunResult(systemlogService.PriorityErr, "backup failed: error writing backup manifest", systemlogService.Field{Key: "ERROR", Value: err.Error()})
+				removeBackupArtifacts(artifacts)
 				os.Exit(1)
 			}
+		}
+
+		for _, artifact := range archived {
+			pipeline.Archives = append(pipeline.Archives, backupService.PipelineArchive{Path: artifact.path, FileName: artifact.fileName})
+		}
+		runPipelineStage(ctx, backupService.StageArchive, pipeline, artifacts)
+		forgetChecksumsIfRewritten(backupService.StageArchive, artifacts)
+
+		// Encrypt the variants that need it, along with their manifests which list every file and path
+		for _, artifact := range archived {
+			if artifact.receiver == "" {
+				continue
+			}
 
-			fmt.Printf("%s🔒 Encrypting backup with GPG for recipient:%s %s\n", ColorYellow, ColorReset, encryptionReceiver)
-			// Encrypt the temporary backup file
-			encryptedPath, err := encryptionService.GPGEncrypt(tempBackupPath, encryptionReceiver)
+			fmt.Printf(i18nService.T("%s🔒 Encrypting backup with GPG for recipient:%s %s\n"), ColorYellow, ColorReset, artifact.receiver)
+			if key, err := encryptionService.GPGSecretKeyStatus(artifact.receiver); err == nil && key.OnCard {
+				fmt.Printf(i18nService.T("%sKey is on smartcard %s: encrypting needs no PIN, restoring will need the card%s\n"), ColorDim, key.CardSerial, ColorReset)
+			}
+			encryptedPath, err := encryptArtifact(artifact)
 			if err != nil {
-				fmt.Printf("%s%s❌ Error encrypting backup:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+				fmt.Printf(i18nService.T("%s%s❌ Error encrypting backup:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+				logRunResult(systemlogService.PriorityErr, "backup failed: error encrypting backup", systemlogService.Field{Key: "ERROR", Value: err.Error()})
+				removeBackupArtifacts(artifacts)
 				os.Exit(1)
 			}
 
-			os.Remove(tempBackupPath)
-			tempBackupPath = encryptedPath
-			backupFileName = backupFileName + ".gpg"
-		}
+			os.Remove(artifact.path)
+			artifact.path = encryptedPath
+			artifact.checksum = ""
 
-		// Determine destinations from config or command line argument
-		destinations := []string{}
-		if destination != "" {
-			destinations = append(destinations, destination)
-		} else {
-			for _, target := range config.Targets {
-				destinations = append(destinations, target.GetDestination())
-			}
-			if len(destinations) == 0 {
-				fmt.Printf("%s%s❌ Error:%s No backup destinations found in config file and no destination specified\n", ColorRed, ColorBold, ColorReset)
+			encryptedManifest, err := encryptionService.GPGEncrypt(artifact.manifestPath, artifact.receiver)
+			if err != nil {
+				fmt.Printf(i18nService.T("%s%s❌ Error encrypting backup manifest:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+				logRunResult(systemlogService.PriorityErr, "backup failed: error encrypting backup manifest", systemlogService.Field{Key: "ERROR", Value: err.Error()})
+				removeBackupArtifacts(artifacts)
 				os.Exit(1)
 			}
+			os.Remove(artifact.manifestPath)
+			artifact.manifestPath = encryptedManifest
+		}
+		for i, artifact := range archived {
+			pipeline.Archives[i].Path = artifact.path
+			pipeline.Archives[i].Encrypted = artifact.receiver != ""
 		}
+		runPipelineStage(ctx, backupService.StageEncrypt, pipeline, artifacts)
+		forgetChecksumsIfRewritten(backupService.StageEncrypt, artifacts)
 
-		fmt.Printf("\n%s%sProcessing backup destinations:%s\n", ColorCyan, ColorBold, ColorReset)
-		for _, dest := range destinations {
-			isFileTarget := false
+		// The last target receiving an artifact may have it moved there instead of copied, unless
+		// custom steps of the later stages still expect the temporary file
+		lastUse := make([]int, len(artifacts))
+		for i := range targets {
+			lastUse[targetArtifacts[i]] = i
+		}
+		canMove := true
+		for _, stage := range []backupService.Stage{backupService.StageTransfer, backupService.StageRecord, backupService.StageRotate} {
+			canMove = canMove && len(backupService.RegisteredSteps(stage)) == 0
+		}
+		reflink := runReflink || (config.Options != nil && config.Options.Reflink)
 
-			// If destination comes from config, try to find the matching target for file/dir info
-			var backupFileNameForTarget string = backupFileName
+		fmt.Printf(i18nService.T("\n%s%sProcessing backup destinations:%s\n"), ColorCyan, ColorBold, ColorReset)
+		overQuota := 0
+		for i, target := range targets {
+			if ctx.Err() != nil {
+				recordTimeout(config, configPath, persistConfig, timeout, targets[i:])
+				removeBackupArtifacts(artifacts)
+				os.Exit(1)
+			}
+
+			dest := target.GetDestination()
+			isFileTarget := target.IsFileTarget()
+			artifact := artifacts[targetArtifacts[i]]
+
+			var backupFileNameForTarget string = artifact.fileName
 			var destFilePath string
+			var destDir string // Directory receiving the backup and its manifest and config
 
-			// Try to match config target for this destination
-			var matchedTarget *configService.BackupTarget
-			for _, t := range config.Targets {
-				if t.Path == dest {
-					matchedTarget = &t
-					break
-				}
+			fmt.Printf(i18nService.T("\n%s→ Destination:%s %s"), ColorBlue, ColorReset, dest)
+			if isFileTarget {
+				fmt.Printf(i18nService.T(" %s(file)%s"), ColorDim, ColorReset)
 			}
+			fmt.Println()
 
-			if matchedTarget != nil {
-				isFileTarget = matchedTarget.IsFileTarget()
-			} else {
-				// If not found in config, infer: if path exists and is dir, or ends with separator, treat as dir
-				info, err := os.Stat(dest)
-				if err == nil && info.IsDir() {
-					isFileTarget = false
-				} else if strings.HasSuffix(dest, string(os.PathSeparator)) {
-					isFileTarget = false
-				} else {
-					isFileTarget = true
+			if target.Fallback {
+				if received := primaryTargetsReceived(targets); len(received) > 0 {
+					fmt.Printf(i18nService.T("  %s⏭️  Skipping: fallback target, the backup went to %s%s\n"), ColorDim, strings.Join(received, ", "), ColorReset)
+					logTargetResult(dest, configService.StatusSkipped, "fallback not needed", 0)
+					if persistConfig {
+						configService.UpdateTargetStatus(config, dest, configService.StatusSkipped, "fallback not needed")
+						configService.WriteBackupConfig(configPath, config)
+					}
+					continue
 				}
+				fmt.Printf(i18nService.T("  %s↪️  Fallback:%s no other target received the backup; use 'go-backup replicate' once they are back%s\n"), ColorYellow, ColorDim, ColorReset)
 			}
 
-			fmt.Printf("\n%s→ Destination:%s %s", ColorBlue, ColorReset, dest)
-			if isFileTarget {
-				fmt.Printf(" %s(file)%s", ColorDim, ColorReset)
+			if !checkTargetQuota(config, configPath, persistConfig, target, artifact) {
+				overQuota++
+				continue
 			}
-			fmt.Println()
+
+			if storageService.IsRemote(dest) {
+				if err := uploadRemoteTarget(ctx, config, configPath, persistConfig, source, currentDir+"-", target, artifact); errors.Is(err, context.DeadlineExceeded) {
+					recordTimeout(config, configPath, persistConfig, timeout, targets[i+1:])
+					removeBackupArtifacts(artifacts)
+					os.Exit(1)
+				}
+				continue
+			}
+
 			if !isFileTarget {
 				// For directory targets, check if directory exists
 				if _, err := os.Stat(dest); os.IsNotExist(err) {
-					fmt.Printf("  %s⚠️  Skipping: directory does not exist%s\n", ColorYellow, ColorReset)
+					fmt.Printf(i18nService.T("  %s⚠️  Skipping: directory does not exist%s\n"), ColorYellow, ColorReset)
+					logTargetResult(dest, configService.StatusSkipped, "destination directory does not exist", 0)
+					if persistConfig {
+						// Usually an unmounted drive, so the target is skipped rather than failed
+						configService.UpdateTargetStatus(config, dest, configService.StatusSkipped, "destination directory does not exist")
+						configService.WriteBackupConfig(configPath, config)
+					}
 					continue
 				}
-				destFilePath = filepath.Join(dest, backupFileName)
+				destFilePath = filepath.Join(dest, artifact.fileName)
+				destDir = dest
 			} else {
 				// For file targets, use the file path directly
 				// Create directory if it doesn't exist
-				destDir := filepath.Dir(dest)
+				destDir = filepath.Dir(dest)
 				if err := os.MkdirAll(destDir, 0755); err != nil {
-					fmt.Printf("  %s❌ Error: failed to create destination directory -%s %v\n", ColorRed, ColorReset, err)
+					fmt.Printf(i18nService.T("  %s❌ Error: failed to create destination directory -%s %v\n"), ColorRed, ColorReset, err)
 					continue
 				}
 				destFilePath = dest
@@ -306,67 +858,175 @@ This command will package and compress the specified sources.`,
 				backupFileNameForTarget = filepath.Base(dest)
 			}
 
-			fmt.Printf("  %sCopying file:%s %s\n", ColorDim, ColorReset, filepath.Base(destFilePath))
+			// Unencrypted manifests can be compared, so an unchanged source only needs a new manifest
+			if useSnapshots && !isFileTarget && artifact.receiver == "" {
+				if baseArchive, ok := metadataSnapshotBase(dest, splitPartBackups(target.Backups), entries); ok {
+					recordMetadataSnapshot(config, configPath, source, dest, baseArchive, artifact, entries)
+					continue
+				}
+			}
 
-			if err := backupService.CopyFile(tempBackupPath, destFilePath); err != nil {
-				fmt.Printf("  %s❌ Error: failed to copy backup -%s %v\n", ColorRed, ColorReset, err)
-				if configFile != "" {
-					configService.UpdateTargetStatus(config, dest, "Failure", err.Error())
+			// Never silently replace an existing backup in a directory target
+			if !isFileTarget && !overwriteExisting {
+				if _, err := os.Stat(destFilePath); err == nil {
+					fmt.Printf(i18nService.T("  %s❌ Error: backup file already exists -%s %s\n"), ColorRed, ColorReset, destFilePath)
+					fmt.Printf(i18nService.T("  %sUse --overwrite-existing to replace it%s\n"), ColorDim, ColorReset)
+					logTargetResult(dest, configService.StatusFailure, "backup file already exists: "+destFilePath, 0)
+					if persistConfig {
+						configService.UpdateTargetStatus(config, dest, configService.StatusFailure, "backup file already exists: "+destFilePath)
+						configService.WriteBackupConfig(configPath, config)
+					}
+					continue
+				}
+			}
+
+			// A protected backup being replaced, by a file target or --overwrite-existing, is unprotected first
+			immutable := config != nil && config.Rotation != nil && config.Rotation.Immutable
+			if _, err := os.Stat(destFilePath); err == nil && immutable {
+				if err := backupService.MakeMutable(destFilePath); err != nil {
+					fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to lift protection of existing backup -%s %v\n"), ColorYellow, ColorReset, err)
+				}
+			}
+
+			fmt.Printf(i18nService.T("  %sCopying file:%s %s\n"), ColorDim, ColorReset, filepath.Base(destFilePath))
+
+			// The stored backup is read back and checked against the archive's checksum, which
+			// SHA256SUMS needs anyway; without it, a move or clone cannot be checked and is not tried
+			transfer := backupService.TransferOptions{Move: canMove && i == lastUse[targetArtifacts[i]], Clone: reflink}
+			if sum, err := artifact.sha256(); err == nil {
+				transfer.SHA256 = sum
+			} else {
+				transfer = backupService.TransferOptions{}
+			}
+			method, err := backupService.TransferFile(artifact.path, destFilePath, transfer)
+			if method == backupService.TransferMove {
+				artifact.moved = true
+			}
+			if err != nil {
+				fmt.Printf(i18nService.T("  %s❌ Error: failed to copy backup -%s %v\n"), ColorRed, ColorReset, err)
+				logTargetResult(dest, configService.StatusFailure, err.Error(), 0)
+				if persistConfig {
+					configService.UpdateTargetStatus(config, dest, configService.StatusFailure, err.Error())
 					configService.WriteBackupConfig(configPath, config)
 				}
 			} else {
-				fmt.Printf("  %s✅ Success:%s backup copied successfully\n", ColorGreen, ColorReset)
+				switch method {
+				case backupService.TransferMove:
+					fmt.Printf(i18nService.T("  %s✅ Success:%s backup moved into place (same file system, checksum verified)\n"), ColorGreen, ColorReset)
+				case backupService.TransferClone:
+					fmt.Printf(i18nService.T("  %s✅ Success:%s backup cloned (copy-on-write, checksum verified)\n"), ColorGreen, ColorReset)
+				case backupService.TransferCopy:
+					if transfer.SHA256 != "" {
+						fmt.Printf(i18nService.T("  %s✅ Success:%s backup copied (read back, checksum verified)\n"), ColorGreen, ColorReset)
+					} else {
+						fmt.Printf(i18nService.T("  %s✅ Success:%s backup copied successfully\n"), ColorGreen, ColorReset)
+					}
+				}
+
+				if immutable {
+					if locked, err := backupService.MakeImmutable(destFilePath); err != nil {
+						fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to protect backup -%s %v\n"), ColorYellow, ColorReset, err)
+					} else if locked {
+						fmt.Printf(i18nService.T("  %s🔒 Protected:%s backup is immutable\n"), ColorDim, ColorReset)
+					} else {
+						fmt.Printf(i18nService.T("  %s🔒 Protected:%s backup is read-only (the immutable flag needs root or a supporting file system)\n"), ColorDim, ColorReset)
+					}
+				}
+
+				// The .sha256 file next to the backup goes wherever it is copied, unlike SHA256SUMS
+				if sum, err := artifact.sha256(); err != nil {
+					fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to write checksum file -%s %v\n"), ColorYellow, ColorReset, err)
+				} else if err := backupService.WriteChecksumFile(destFilePath, sum); err != nil {
+					fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to write checksum file -%s %v\n"), ColorYellow, ColorReset, err)
+				}
+
+				// Keep the directory's SHA256SUMS current so backups can be verified with sha256sum -c
+				if !isFileTarget {
+					if err := recordChecksum(artifact, dest, filepath.Base(destFilePath)); err != nil {
+						fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to update %s -%s %v\n"), ColorYellow, backupService.ChecksumsFileName, ColorReset, err)
+					} else {
+						fmt.Printf(i18nService.T("  %s🔑 Checksum:%s Updated %s\n"), ColorDim, ColorReset, backupService.ChecksumsFileName)
+					}
+				}
+
+				// The manifest is encrypted like the archive, so the file listing never sits next to it in plaintext
+				manifestName := backupService.BackupBaseName(backupFileNameForTarget) + backupService.ManifestSuffix
+				if artifact.receiver != "" {
+					manifestName += ".gpg"
+				}
+				if sum, err := backupService.CopyFileVerified(artifact.manifestPath, filepath.Join(destDir, manifestName)); err != nil {
+					fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to copy manifest -%s %v\n"), ColorYellow, ColorReset, err)
+				} else {
+					fmt.Printf(i18nService.T("  %s📋 Manifest:%s %s\n"), ColorDim, ColorReset, manifestName)
+					// A damaged manifest breaks selective restore and salvage, so it is listed in SHA256SUMS too
+					if !isFileTarget {
+						if err := backupService.AddChecksum(dest, manifestName, sum); err != nil {
+							fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to update %s -%s %v\n"), ColorYellow, backupService.ChecksumsFileName, ColorReset, err)
+						}
+					}
+				}
+
+				// Parity files let 'go-backup repair' fix bit errors that accumulate on cold storage
+				if percent, _ := target.ParityPercent(); percent > 0 {
+					if files, err := backupService.CreateParity(destFilePath, percent); err != nil {
+						fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to create parity files -%s %v\n"), ColorYellow, ColorReset, err)
+					} else {
+						fmt.Printf(i18nService.T("  %s🛟 Parity:%s %d file(s) with %d%% redundancy\n"), ColorDim, ColorReset, len(files), percent)
+					}
+				}
 
 				// Update status to success
-				if configFile != "" {
-					configService.UpdateTargetStatus(config, dest, "Success", "Backup completed successfully")
+				statusMessage := "Backup completed successfully"
+				if len(warnings) > 0 {
+					statusMessage = fmt.Sprintf("Backup completed with %d warning(s)", len(warnings))
+				}
+				logTargetResult(dest, configService.StatusSuccess, statusMessage, len(warnings))
+				if persistConfig {
+					configService.UpdateTargetStatus(config, dest, configService.StatusSuccess, statusMessage)
 					// We will write the config later when adding the record, but if recording is skipped, we should write it here?
 					// The recording logic below handles the write.
 				}
 
-				// Get maxBackups value from config or use default
-				maxBackups := 7 // Default value
+				// ReadBackupConfig and ResolveTargets already default maxBackups to 7
+				maxBackups := target.MaxBackups
 
 				if configFile != "" || destination == "" {
 					// Only apply rotation if using config or default destination and not a file target
 					if !isFileTarget {
-						for _, target := range config.Targets {
-							if target.GetDestination() == dest {
-								// Always use maxBackups from target, as ReadBackupConfig
-								// already sets the default value of 7 if it was empty
-								maxBackups = target.MaxBackups
-								break
-							}
-						}
+						// Backups are named after the source folder, and the part of a split backup
+						prefix := currentDir + "-"
 
-						// Get the current folder name used as prefix from the source path
-						prefixName := filepath.Base(source)
-						if prefixName == "." || prefixName == "/" {
-							prefixName = "go-backup"
-						}
-						prefix := prefixName + "-"
-
-						// Cleanup old backups
-						if err := backupService.CleanupOldBackups(dest, prefix, maxBackups); err != nil {
-							fmt.Printf("  %s⚠️  Warning: Failed to cleanup old backups -%s %v\n", ColorYellow, ColorReset, err)
+						// Cleanup old backups, leaving those of similarly named sources and other machines alone
+						opts := keepOtherParts(rotationOptions(config, target.Backups, false), target.Backups)
+						opts.Host = backupService.CurrentHost()
+						opts.Source, _ = filepath.Abs(source)
+						if err := backupService.RotateBackups(dest, prefix, maxBackups, opts); err != nil {
+							fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to cleanup old backups -%s %v\n"), ColorYellow, ColorReset, err)
 						} else {
-							fmt.Printf("  %s🔄 Rotation:%s Keeping latest %d backups\n", ColorCyan, ColorReset, maxBackups)
+							fmt.Printf(i18nService.T("  %s🔄 Rotation:%s Keeping latest %d backups\n"), ColorCyan, ColorReset, maxBackups)
 						}
 					} else {
-						fmt.Printf("  %s📄 File target:%s No rotation applied (single file backup)\n", ColorCyan, ColorReset)
+						fmt.Printf(i18nService.T("  %s📄 File target:%s No rotation applied (single file backup)\n"), ColorCyan, ColorReset)
 					}
 
 					// Record this backup in the config file if we're using a config
-					if configFile != "" {
+					if persistConfig {
 						// Get file information for size
 						fileInfo, err := os.Stat(destFilePath)
 						if err == nil {
 							// Create a backup record
 							backupRecord := configService.BackupRecord{
-								Filename:  filepath.Base(destFilePath),
-								Source:    source,
-								CreatedAt: time.Now(),
-								Size:      fileInfo.Size(),
+								Filename:   filepath.Base(destFilePath),
+								Source:     source,
+								Host:       backupService.CurrentHost(),
+								RunID:      runID,
+								CreatedAt:  config.Now(),
+								Size:       fileInfo.Size(),
+								SHA256:     artifact.checksum,
+								Tags:       runTags,
+								Message:    runMessage,
+								Part:       splitRecordPart(),
+								Provenance: artifact.provenance,
 							}
 
 							// Add the record to the config
@@ -374,35 +1034,33 @@ This command will package and compress the specified sources.`,
 
 							// Save updated config
 							if err := configService.WriteBackupConfig(configPath, config); err != nil {
-								fmt.Printf("  %s⚠️  Warning: Failed to update backup history in config -%s %v\n", ColorYellow, ColorReset, err)
+								fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to update backup history in config -%s %v\n"), ColorYellow, ColorReset, err)
 							} else {
-								fmt.Printf("  %s📝 History:%s Updated backup history in %s\n", ColorDim, ColorReset, configPath)
+								fmt.Printf(i18nService.T("  %s📝 History:%s Updated backup history in %s\n"), ColorDim, ColorReset, configPath)
 							}
 
 							// Copy the config file to the destination with backup name prefix if enabled
 							if copyConfig {
-								configBaseName := filepath.Base(backupFileNameForTarget)
-								configBaseName = strings.TrimSuffix(configBaseName, ".tar.gz") // Remove .tar.gz
-								configBaseName = strings.TrimSuffix(configBaseName, ".gpg")    // Remove .gpg if encrypted
+								configBaseName := backupService.BackupBaseName(filepath.Base(backupFileNameForTarget))
 
 								// For file targets, copy config to the directory containing the file
 								// For directory targets, copy config to the destination directory
-								var destConfigDir string
-								if isFileTarget {
-									destConfigDir = filepath.Dir(dest)
-								} else {
-									destConfigDir = dest
-								}
-								destConfigPath := filepath.Join(destConfigDir, configBaseName+".backup.yaml")
+								destConfigPath := filepath.Join(destDir, configBaseName+".backup.yaml")
 
-								// Get the encryption receiver if encryption was used
-								currentEncryptionReceiver := encryptionReceiver
-
-								// Copy the config with added helpful comments
-								if err := configService.CopyConfigWithHelp(configPath, destConfigPath, useEncryption, currentEncryptionReceiver); err != nil {
-									fmt.Printf("  %s⚠️  Warning: Failed to copy config file to destination -%s %v\n", ColorYellow, ColorReset, err)
+								// Copy the config with added helpful comments; encrypted backups get an encrypted
+								// copy, since the config reveals paths, targets and excludes
+								if artifact.receiver != "" {
+									destConfigPath += ".gpg"
+								}
+								if sum, err := copyConfigVerified(configPath, destConfigPath, artifact.receiver); err != nil {
+									fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to copy config file to destination -%s %v\n"), ColorYellow, ColorReset, err)
 								} else {
-									fmt.Printf("  %s📄 Config:%s Copied config file with usage info to %s\n", ColorGreen, ColorReset, destConfigPath)
+									fmt.Printf(i18nService.T("  %s📄 Config:%s Copied config file with usage info to %s\n"), ColorGreen, ColorReset, destConfigPath)
+									if !isFileTarget {
+										if err := backupService.AddChecksum(dest, filepath.Base(destConfigPath), sum); err != nil {
+											fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to update %s -%s %v\n"), ColorYellow, backupService.ChecksumsFileName, ColorReset, err)
+										}
+									}
 								}
 							}
 						}
@@ -411,16 +1069,35 @@ This command will package and compress the specified sources.`,
 			}
 		}
 
-		// Clean up the temporary file
-		os.Remove(tempBackupPath)
+		// Custom steps of the last stages run once all targets are done
+		runPipelineStage(ctx, backupService.StageTransfer, pipeline, artifacts)
+		runPipelineStage(ctx, backupService.StageRecord, pipeline, artifacts)
+		runPipelineStage(ctx, backupService.StageRotate, pipeline, artifacts)
+
+		// Clean up the temporary files
+		removeBackupArtifacts(artifacts)
 
-		// Update global registry if ~/.backup.yaml exists
-		localConfigDir := filepath.Dir(configPath)
-		if err := configService.UpdateGlobalRegistry(localConfigDir); err != nil {
-			fmt.Printf("%s%s⚠️  Warning: Failed to update global backup registry:%s %v\n", ColorYellow, ColorBold, ColorReset, err)
+		// Update global registry if it exists; ad-hoc backups without a config are not tracked
+		if persistConfig {
+			localConfigDir := filepath.Dir(configPath)
+			// The home directory's .backup.yaml may be the legacy registry, so it is registered with the home profile
+			profile := ""
+			if runHome && !cmd.Flags().Changed("config") {
+				profile = configService.HomeProfile
+			}
+			if err := configService.UpdateGlobalRegistryProfile(localConfigDir, profile); err != nil {
+				fmt.Printf(i18nService.T("%s%s⚠️  Warning: Failed to update global backup registry:%s %v\n"), ColorYellow, ColorBold, ColorReset, err)
+			}
 		}
 
-		fmt.Printf("\n%s%s🎉 Backup completed successfully!%s\n", ColorGreen, ColorBold, ColorReset)
+		printCompressionReport(compressionStats)
+		printWarnings(warnings)
+
+		if overQuota > 0 {
+			fmt.Printf(i18nService.T("\n%s%s❌ Backup failed:%s %d target(s) would exceed their quota\n"), ColorRed, ColorBold, ColorReset, overQuota)
+			os.Exit(1)
+		}
+		fmt.Printf(i18nService.T("\n%s%s🎉 Backup completed successfully!%s\n"), ColorGreen, ColorBold, ColorReset)
 	},
 }
 
@@ -435,7 +1112,965 @@ func init() {
 	runCmd.Flags().StringSliceVar(&excludeDirs, "exclude", []string{".git", "node_modules", "bin"}, "Directories to exclude from backup")
 	runCmd.Flags().BoolVar(&copyConfig, "copy-config", true, "Copy the config file to the target directories with the same name prefix as the backup")
 	runCmd.Flags().BoolVar(&force, "force", false, "Force the backup operation, bypassing size warnings")
+	runCmd.Flags().BoolVar(&overwriteExisting, "overwrite-existing", false, "Replace an existing backup file with the same name in a directory target")
+	runCmd.Flags().IntVar(&compressionLevel, "compression-level", 0, "gzip level from 1 (fastest) to 9 (smallest) for all targets, overriding the config (also --compress-level)")
+	// --compress-level is accepted as another spelling of --compression-level
+	runCmd.Flags().StringVar(&archiveFormat, "format", "", "Archive format for all targets: tar.gz, tar or zip, overriding the config")
+	runCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "compress-level" {
+			name = "compression-level"
+		}
+		return pflag.NormalizedName(name)
+	})
+	runCmd.Flags().BoolVar(&runNice, "nice", false, "Run at a lower CPU and IO priority (also options.nice in the config)")
+	runCmd.Flags().IntVar(&runCPULimit, "cpu-limit", 0, "Maximum number of CPUs to use, overrides options.cpuLimit (0 means all)")
+	runCmd.Flags().BoolVar(&metadataSnapshot, "metadata-snapshot", false, "Store only a manifest when no file contents changed since the last backup (also options.metadataSnapshots)")
+	runCmd.Flags().BoolVar(&runNoChecksumCache, "no-checksum-cache", false, "Read every file to find changes for metadata snapshots instead of trusting cached checksums")
+	runCmd.Flags().BoolVar(&runHome, "home", false, "Back up the dotfiles in your home directory, using "+configService.HomeConfigFileName+" or curated defaults")
+	runCmd.Flags().DurationVar(&runTimeout, "timeout", 0, "Stop the backup if it takes longer than this, e.g. 2h (overrides options.timeout)")
+	runCmd.Flags().StringVar(&runOnError, "on-error", "", "What to do with unreadable files: skip (default) or fail (overrides options.onError)")
+	runCmd.Flags().BoolVar(&runIncludeVCS, "include-vcs", false, "Archive .git, .hg and .svn directories even if excluded (also options.includeVCS)")
+	runCmd.Flags().StringVarP(&runMessage, "message", "m", "", "Note stored with the backup, e.g. -m \"before dependency upgrade\"")
+	runCmd.Flags().StringArrayVar(&runTags, "tag", nil, "Label the backup, e.g. --tag pre-release; repeat for several tags")
+	runCmd.Flags().BoolVar(&runLogSyslog, "log-syslog", false, "Also write the results to syslog or journald (also options.syslog)")
+	runCmd.Flags().BoolVar(&saveConfig, "save-config", false, "Write the equivalent config file after an ad-hoc backup run without one")
+	runCmd.Flags().BoolVar(&runSplitByDir, "split-by-dir", false, "Create one archive per top-level directory of the source, each with its own history and rotation")
+	runCmd.Flags().StringVar(&runSplitPart, "split-part", "", "Back up a single part of a --split-by-dir backup")
+	runCmd.Flags().MarkHidden("split-part")
+	runCmd.Flags().StringVar(&runID, "run-id", "", "ID of this run in its output, system log, manifests and history (defaults to a new random UUID)")
+	runCmd.Flags().StringVar(&runFilesFrom, "files-from", "", "Archive only the paths listed in this file, relative to the source; - reads them from stdin")
+	runCmd.Flags().BoolVar(&runReflink, "reflink", false, "Clone backups into local targets on copy-on-write file systems like btrfs, XFS and APFS (also options.reflink)")
+	runCmd.Flags().BoolVar(&runScanSecrets, "scan-secrets", false, "Warn about files that look like secrets before an unencrypted backup goes to a remote target (also options.secretScan)")
+	runCmd.Flags().BoolVar(&runIgnorePower, "ignore-power", false, "Back up even on battery or a metered connection (overrides options.requireAC and options.skipOnMetered)")
+	runCmd.Flags().BoolVar(&runIgnorePause, "ignore-pause", false, "Back up even while backups are paused with 'go-backup pause'")
+	runCmd.Flags().BoolVar(&runStrict, "strict", false, "Fail targets whose backups would exceed their quota instead of only warning")
+	runCmd.Flags().BoolVarP(&runFilesNull, "null", "0", false, "Paths in the --files-from list are separated by NUL characters, e.g. from find -print0")
 
 	// Add command to root
 	rootCmd.AddCommand(runCmd)
 }
+
+// onlyListedFiles returns the paths that are in the --files-from list
+func onlyListedFiles(paths, listed []string) []string {
+	inList := make(map[string]bool, len(listed))
+	for _, path := range listed {
+		inList[path] = true
+	}
+	var kept []string
+	for _, path := range paths {
+		if inList[path] {
+			kept = append(kept, path)
+		}
+	}
+	return kept
+}
+
+// checkTargetQuota warns when the target's backups come close to or exceed its quota once the
+// artifact is added and rotation has run. With --strict a target over its quota is failed
+// without writing the backup, and false is returned.
+func checkTargetQuota(config *configService.BackupConfig, configPath string, persistConfig bool,
+	target configService.ResolvedTarget, artifact *backupArtifact) bool {
+	quota, _ := target.QuotaBytes()
+	if quota == 0 {
+		return true
+	}
+
+	// Metadata snapshots leave no archive behind, so they add next to nothing
+	record := configService.BackupRecord{Filename: artifact.fileName, Part: splitRecordPart()}
+	if target.IsFileTarget() {
+		record.Filename = filepath.Base(target.File)
+	}
+	if info, err := os.Stat(artifact.path); err == nil {
+		record.Size = info.Size()
+	}
+	usage := configService.UsageAfterBackup(config, target.BackupTarget, record)
+
+	dest := target.GetDestination()
+	switch configService.CheckQuota(usage, quota) {
+	case configService.QuotaNear:
+		fmt.Printf(i18nService.T("  %s⚠️  Warning: backups will use %s of the %s quota (%d%%)%s\n"), ColorYellow, formatFileSize(usage), formatFileSize(quota), usage*100/quota, ColorReset)
+	case configService.QuotaExceeded:
+		if !runStrict {
+			fmt.Printf(i18nService.T("  %s⚠️  Warning: backups will use %s, over the %s quota%s\n"), ColorYellow, formatFileSize(usage), formatFileSize(quota), ColorReset)
+			fmt.Printf(i18nService.T("  %sLower maxBackups or raise the quota; --strict fails targets over their quota%s\n"), ColorDim, ColorReset)
+			break
+		}
+		message := fmt.Sprintf("backups would use %s, over the %s quota", formatFileSize(usage), formatFileSize(quota))
+		fmt.Printf(i18nService.T("  %s❌ Error: backups would use %s, over the %s quota%s\n"), ColorRed, formatFileSize(usage), formatFileSize(quota), ColorReset)
+		logTargetResult(dest, configService.StatusFailure, message, 0)
+		if persistConfig {
+			configService.UpdateTargetStatus(config, dest, configService.StatusFailure, message)
+			configService.WriteBackupConfig(configPath, config)
+		}
+		return false
+	}
+	return true
+}
+
+// withoutHidden leaves hidden files out of the excludes when options.includeHidden is false,
+// keeping the version control directories that options.includeVCS or --include-vcs ask for
+func withoutHidden(options *configService.Options, excludes []string, includeVCS bool) []string {
+	if !options.ExcludesHidden() {
+		return excludes
+	}
+	allow := options.HiddenAllow
+	if includeVCS || options.IncludeVCS {
+		allow = append(append([]string{}, allow...), compressionService.VCSDirs...)
+	}
+	return compressionService.WithoutHidden(excludes, allow)
+}
+
+// logRunResult sends an outcome of the whole run to the system log, if enabled
+func logRunResult(priority systemlogService.Priority, message string, fields ...systemlogService.Field) {
+	fields = append([]systemlogService.Field{{Key: "SOURCE", Value: source}, {Key: "RUN_ID", Value: runID}}, fields...)
+	if err := runSyslog.Log(priority, message, fields...); err != nil {
+		fmt.Printf(i18nService.T("%s⚠️  Warning: Failed to write to the system log:%s %v\n"), ColorYellow, ColorReset, err)
+	}
+}
+
+// logTargetResult sends the outcome of a target to the system log, if enabled.
+// Failures are logged as errors, skips as notices and successes with warnings as warnings.
+func logTargetResult(dest, status, message string, warnings int) {
+	if status == configService.StatusSuccess {
+		runReceivedTargets[dest] = true
+	}
+	priority, outcome := systemlogService.PriorityInfo, "succeeded"
+	switch {
+	case status == configService.StatusFailure:
+		priority, outcome = systemlogService.PriorityErr, "failed"
+	case status == configService.StatusSkipped:
+		priority, outcome = systemlogService.PriorityNotice, "skipped"
+	case warnings > 0:
+		priority = systemlogService.PriorityWarning
+	}
+	logRunResult(priority, fmt.Sprintf("backup of %s to %s %s: %s", source, dest, outcome, message),
+		systemlogService.Field{Key: "TARGET", Value: dest},
+		systemlogService.Field{Key: "STATUS", Value: status},
+		systemlogService.Field{Key: "WARNINGS", Value: strconv.Itoa(warnings)})
+}
+
+// primaryTargetsReceived returns the targets other than fallback targets that received the backup in this run
+func primaryTargetsReceived(targets []configService.ResolvedTarget) []string {
+	var received []string
+	for _, target := range targets {
+		if !target.Fallback && runReceivedTargets[target.GetDestination()] {
+			received = append(received, target.GetDestination())
+		}
+	}
+	return received
+}
+
+// rotationOptions builds the rotation options from the config's rotation section for a target
+// with the given backup history. forceTrash enables the trash even if the config does not.
+func rotationOptions(config *configService.BackupConfig, backups []configService.BackupRecord, forceTrash bool) backupService.RotationOptions {
+	opts := backupService.RotationOptions{Trash: forceTrash}
+	if config != nil && config.Rotation != nil {
+		opts.Trash = opts.Trash || config.Rotation.Trash
+		opts.TrashGracePeriod = time.Duration(config.Rotation.TrashDays) * 24 * time.Hour
+		opts.Immutable = config.Rotation.Immutable
+		if config.Rotation.KeepTagged {
+			opts.Keep = configService.TaggedBackups(backups)
+		}
+	}
+	return opts
+}
+
+// runPipelineStage runs the custom steps registered for a stage. A failing step fails the run
+// like a built-in stage would, removing the temporary artifacts.
+func runPipelineStage(ctx context.Context, stage backupService.Stage, state *backupService.PipelineState, artifacts []*backupArtifact) {
+	ran, err := backupService.RunStage(ctx, stage, state)
+	for _, name := range ran {
+		fmt.Printf(i18nService.T("%s🧩 Step:%s %s (%s)\n"), ColorDim, ColorReset, name, stage)
+	}
+	if err != nil {
+		fmt.Printf(i18nService.T("%s%s❌ Error:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+		logRunResult(systemlogService.PriorityErr, "backup failed: "+err.Error())
+		removeBackupArtifacts(artifacts)
+		os.Exit(1)
+	}
+}
+
+// forgetChecksumsIfRewritten drops the checksums computed while writing the artifacts when custom
+// steps of the stage may have rewritten them, so they are computed afresh from the files
+func forgetChecksumsIfRewritten(stage backupService.Stage, artifacts []*backupArtifact) {
+	if len(backupService.RegisteredSteps(stage)) == 0 {
+		return
+	}
+	for _, artifact := range artifacts {
+		artifact.checksum = ""
+	}
+}
+
+// backupArtifact is one archive variant shared by all targets that need the same
+// format, compression level and encryption recipient
+type backupArtifact struct {
+	format    string // Archive format; empty for tar.gz
+	level     int
+	receiver  string // GPG recipient; empty for unencrypted artifacts
+	chunkSize int64  // Size of the separately readable chunks; 0 stores the archive in one piece
+	path      string // Temporary file holding the artifact
+	fileName  string // File name used in directory targets
+	checksum  string // SHA-256 of the artifact, computed while it is written or else on first use
+	moved     bool   // The artifact was moved into its last target, so path is no longer temporary
+
+	manifestPath string                            // Temporary manifest file, encrypted along with the artifact
+	chunks       []compressionService.ArchiveChunk // Chunks of the stored artifact, recorded in its manifest
+	provenance   *backupService.Provenance         // How the artifact is produced, recorded in its manifest and history
+}
+
+// planBackupArtifacts works out the distinct archive variants needed by the targets.
+// It returns the artifacts and, for each target, the index of the artifact it receives.
+// Returns an error if a target needs encryption but has no recipient, has invalid chunks, or
+// chunks a format other than tar.gz.
+func planBackupArtifacts(targets []configService.ResolvedTarget, defaultEncryption *configService.EncryptionConfig, baseName string) ([]*backupArtifact, []int, error) {
+	var artifacts []*backupArtifact
+	targetArtifacts := make([]int, len(targets))
+
+	for i, target := range targets {
+		chunkSize, err := target.ChunkSize()
+		if err != nil {
+			return nil, nil, err
+		}
+		format, err := target.ArchiveFormat()
+		if err != nil {
+			return nil, nil, err
+		}
+		if format == compressionService.FormatTarGz {
+			format = ""
+		}
+		if chunkSize > 0 && format != "" {
+			return nil, nil, fmt.Errorf("target %s: chunks are only supported for the %s format, not %s", target.GetDestination(), compressionService.FormatTarGz, format)
+		}
+		artifact := &backupArtifact{format: format, level: target.GetCompressionLevel(), chunkSize: chunkSize,
+			fileName: baseName + compressionService.FormatExtension(format)}
+		if encryption := target.EffectiveEncryption(defaultEncryption); encryption != nil {
+			if encryption.Receiver == "" {
+				return nil, nil, fmt.Errorf("GPG encryption enabled for %s but no recipient specified", target.GetDestination())
+			}
+			artifact.receiver = encryption.Receiver
+			artifact.fileName += ".gpg"
+		}
+
+		index := -1
+		for j, existing := range artifacts {
+			if existing.format == artifact.format && existing.level == artifact.level && existing.receiver == artifact.receiver && existing.chunkSize == artifact.chunkSize {
+				index = j
+				break
+			}
+		}
+		if index < 0 {
+			index = len(artifacts)
+			artifacts = append(artifacts, artifact)
+		}
+		targetArtifacts[i] = index
+	}
+
+	// Keep the familiar temp file name when only one variant is needed
+	for i, artifact := range artifacts {
+		ext := compressionService.FormatExtension(artifact.format)
+		if len(artifacts) == 1 {
+			artifact.path = filepath.Join(os.TempDir(), baseName+ext)
+		} else {
+			artifact.path = filepath.Join(os.TempDir(), fmt.Sprintf("%s.v%d%s", baseName, i+1, ext))
+		}
+	}
+
+	return artifacts, targetArtifacts, nil
+}
+
+// encryptArtifact encrypts the artifact for its recipient and returns the encrypted path.
+// A chunked artifact has each chunk encrypted on its own, so every chunk can still be read
+// without the others; the manifest is updated with where the encrypted chunks are.
+func encryptArtifact(artifact *backupArtifact) (string, error) {
+	if len(artifact.chunks) <= 1 {
+		return encryptionService.GPGEncrypt(artifact.path, artifact.receiver)
+	}
+
+	fmt.Printf(i18nService.T("%sEncrypting %d chunks separately%s\n"), ColorDim, len(artifact.chunks), ColorReset)
+	sizes := make([]int64, len(artifact.chunks))
+	for i, chunk := range artifact.chunks {
+		sizes[i] = chunk.Size
+	}
+	encryptedPath, encryptedSizes, err := encryptionService.GPGEncryptParts(artifact.path, artifact.receiver, sizes)
+	if err != nil {
+		return "", err
+	}
+
+	var offset int64
+	for i := range artifact.chunks {
+		artifact.chunks[i].Offset, artifact.chunks[i].Size = offset, encryptedSizes[i]
+		offset += encryptedSizes[i]
+	}
+	manifest, err := backupService.ReadManifest(artifact.manifestPath)
+	if err == nil {
+		manifest.Chunks = artifact.chunks
+		err = backupService.WriteManifest(artifact.manifestPath, manifest)
+	}
+	if err != nil {
+		os.Remove(encryptedPath)
+		return "", err
+	}
+	return encryptedPath, nil
+}
+
+// recordChecksum adds the artifact's checksum under fileName to the SHA256SUMS file in backupDir
+func recordChecksum(artifact *backupArtifact, backupDir, fileName string) error {
+	sum, err := artifact.sha256()
+	if err != nil {
+		return err
+	}
+	return backupService.AddChecksum(backupDir, fileName, sum)
+}
+
+// sha256 returns the SHA-256 of the artifact, computing it on first use
+func (a *backupArtifact) sha256() (string, error) {
+	if a.checksum == "" {
+		sum, err := backupService.FileSHA256(a.path)
+		if err != nil {
+			return "", err
+		}
+		a.checksum = sum
+	}
+	return a.checksum, nil
+}
+
+// uploadRemoteTarget uploads the backup with its manifest, checksum and config to a remote target
+// and records the outcome in the config. Failures are reported and recorded; the returned error lets
+// the caller stop on a timeout. Of the remote targets, only those of an s3 section are rotated, deleting
+// the backups named with the prefix beyond maxBackups.
+func uploadRemoteTarget(ctx context.Context, config *configService.BackupConfig, configPath string, persistConfig bool,
+	source, prefix string, target configService.ResolvedTarget, artifact *backupArtifact) error {
+	dest := target.GetDestination()
+	fail := func(err error) error {
+		fmt.Printf(i18nService.T("  %s❌ Error: failed to upload backup -%s %v\n"), ColorRed, ColorReset, err)
+		message := err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			message = timeoutReason
+		}
+		logTargetResult(dest, configService.StatusFailure, message, 0)
+		if persistConfig {
+			configService.UpdateTargetStatus(config, dest, configService.StatusFailure, message)
+			configService.WriteBackupConfig(configPath, config)
+		}
+		return err
+	}
+
+	remote, err := storageService.ParseRemote(dest)
+	if err != nil {
+		return fail(err)
+	}
+	remoteFile, remoteDir := remote, remote.Dir()
+	if !target.IsFileTarget() {
+		remoteDir = remote
+		remoteFile = remote.Join(artifact.fileName)
+	}
+	opts := uploadOptions(target)
+
+	// Never silently replace an existing backup in a directory target
+	var files []storageService.RemoteFile
+	if !target.IsFileTarget() {
+		if files, err = storageService.ListContext(ctx, remoteDir); err != nil {
+			return fail(err)
+		}
+		for _, file := range files {
+			if file.Name == remoteFile.Base() && (!overwriteExisting || target.IsAppendOnly()) {
+				fmt.Printf(i18nService.T("  %s❌ Error: backup file already exists -%s %s\n"), ColorRed, ColorReset, remoteFile.String())
+				if target.IsAppendOnly() {
+					fmt.Printf(i18nService.T("  %sThe target is append-only; backups on it are never replaced%s\n"), ColorDim, ColorReset)
+				} else {
+					fmt.Printf(i18nService.T("  %sUse --overwrite-existing to replace it%s\n"), ColorDim, ColorReset)
+				}
+				logTargetResult(dest, configService.StatusFailure, "backup file already exists: "+remoteFile.String(), 0)
+				if persistConfig {
+					configService.UpdateTargetStatus(config, dest, configService.StatusFailure, "backup file already exists: "+remoteFile.String())
+					configService.WriteBackupConfig(configPath, config)
+				}
+				return nil
+			}
+		}
+	}
+
+	fmt.Printf(i18nService.T("  %sUploading file:%s %s\n"), ColorDim, ColorReset, remoteFile.Base())
+	if err := storageService.UploadContext(ctx, artifact.path, remoteFile, opts); err != nil {
+		return fail(err)
+	}
+	fmt.Printf(i18nService.T("  %s✅ Success:%s backup uploaded successfully\n"), ColorGreen, ColorReset)
+	if err := uploadRemoteChecksumFile(artifact, remoteFile, remoteDir, opts); err != nil {
+		fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to write checksum file -%s %v\n"), ColorYellow, ColorReset, err)
+	}
+
+	// The manifest goes up first, so SHA256SUMS lists it along with the backup
+	sums := make(map[string]string)
+	baseName := backupService.BackupBaseName(remoteFile.Base())
+	manifestName := baseName + backupService.ManifestSuffix
+	if artifact.receiver != "" {
+		manifestName += ".gpg"
+	}
+	if err := storageService.Upload(artifact.manifestPath, remoteDir.Join(manifestName), opts); err != nil {
+		fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to upload manifest -%s %v\n"), ColorYellow, ColorReset, err)
+	} else {
+		fmt.Printf(i18nService.T("  %s📋 Manifest:%s %s\n"), ColorDim, ColorReset, manifestName)
+		if sum, err := backupService.FileSHA256(artifact.manifestPath); err == nil {
+			sums[manifestName] = sum
+		}
+	}
+
+	sumsExist := false
+	if !target.IsFileTarget() {
+		for _, file := range files {
+			sumsExist = sumsExist || file.Name == backupService.ChecksumsFileName
+		}
+		archiveSum, err := artifact.sha256()
+		if err == nil {
+			sums[remoteFile.Base()] = archiveSum
+			err = uploadRemoteChecksums(remoteDir, sumsExist, sums, opts)
+		}
+		if err != nil {
+			fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to update %s -%s %v\n"), ColorYellow, backupService.ChecksumsFileName, ColorReset, err)
+		} else {
+			sumsExist = true
+			fmt.Printf(i18nService.T("  %s🔑 Checksum:%s Updated %s\n"), ColorDim, ColorReset, backupService.ChecksumsFileName)
+		}
+	}
+
+	if percent, _ := target.ParityPercent(); percent > 0 {
+		if count, err := uploadRemoteParity(artifact, remoteFile, remoteDir, percent, opts); err != nil {
+			fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to upload parity files -%s %v\n"), ColorYellow, ColorReset, err)
+		} else {
+			fmt.Printf(i18nService.T("  %s🛟 Parity:%s %d file(s) with %d%% redundancy\n"), ColorDim, ColorReset, count, percent)
+		}
+	}
+
+	switch {
+	case opts.ObjectLock != nil:
+		fmt.Printf(i18nService.T("  %s🔒 Object lock:%s %s until %s\n"), ColorCyan, ColorReset,
+			target.Upload.ObjectLock.Mode, opts.ObjectLock.RetainUntil.Format("2006-01-02 15:04"))
+		fmt.Printf(i18nService.T("  %s🔄 Rotation:%s Append-only target; expire old backups with a bucket lifecycle rule\n"), ColorCyan, ColorReset)
+	case target.IsAppendOnly():
+		fmt.Printf(i18nService.T("  %s🔄 Rotation:%s Append-only target; expire old backups on the server side\n"), ColorCyan, ColorReset)
+	case target.RotatesRemotely():
+		keep := keepOtherParts(rotationOptions(config, target.Backups, false), target.Backups).Keep
+		if err := rotateRemoteTarget(remoteDir, files, remoteFile.Base(), prefix, target.MaxBackups, keep, opts); err != nil {
+			fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to cleanup old backups -%s %v\n"), ColorYellow, ColorReset, err)
+		} else {
+			fmt.Printf(i18nService.T("  %s🔄 Rotation:%s Keeping latest %d backups\n"), ColorCyan, ColorReset, target.MaxBackups)
+		}
+	case !target.IsFileTarget():
+		fmt.Printf(i18nService.T("  %s🔄 Rotation:%s Not applied to remote targets\n"), ColorCyan, ColorReset)
+	}
+	logTargetResult(dest, configService.StatusSuccess, "Backup completed successfully", 0)
+	if !persistConfig {
+		return nil
+	}
+
+	configService.UpdateTargetStatus(config, dest, configService.StatusSuccess, "Backup completed successfully")
+	if info, err := os.Stat(artifact.path); err == nil {
+		configService.AddBackupRecord(config, dest, configService.BackupRecord{
+			Filename:   remoteFile.Base(),
+			Source:     source,
+			Host:       backupService.CurrentHost(),
+			RunID:      runID,
+			CreatedAt:  config.Now(),
+			Size:       info.Size(),
+			SHA256:     artifact.checksum,
+			Tags:       runTags,
+			Message:    runMessage,
+			Part:       splitRecordPart(),
+			Provenance: artifact.provenance,
+		})
+	}
+	if err := configService.WriteBackupConfig(configPath, config); err != nil {
+		fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to update backup history in config -%s %v\n"), ColorYellow, ColorReset, err)
+	} else {
+		fmt.Printf(i18nService.T("  %s📝 History:%s Updated backup history in %s\n"), ColorDim, ColorReset, configPath)
+	}
+
+	if copyConfig {
+		configName := baseName + ".backup.yaml"
+		if artifact.receiver != "" {
+			configName += ".gpg"
+		}
+		if sum, err := uploadRemoteConfig(configPath, remoteDir.Join(configName), artifact.receiver, opts); err != nil {
+			fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to upload config file -%s %v\n"), ColorYellow, ColorReset, err)
+		} else {
+			fmt.Printf(i18nService.T("  %s📄 Config:%s Uploaded config file with usage info to %s\n"), ColorGreen, ColorReset, remoteDir.Join(configName))
+			if !target.IsFileTarget() {
+				if err := uploadRemoteChecksums(remoteDir, sumsExist, map[string]string{configName: sum}, opts); err != nil {
+					fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to update %s -%s %v\n"), ColorYellow, backupService.ChecksumsFileName, ColorReset, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// timeoutReason is the status message of targets not backed up because the run timed out
+const timeoutReason = "timeout"
+
+// recordTimeout reports a run that exceeded its timeout and records it as failed for the
+// targets that were not backed up
+func recordTimeout(config *configService.BackupConfig, configPath string, persistConfig bool, timeout time.Duration, targets []configService.ResolvedTarget) {
+	fmt.Printf(i18nService.T("\n%s%s❌ Backup timed out after %s%s\n"), ColorRed, ColorBold, timeout, ColorReset)
+	for _, target := range targets {
+		logTargetResult(target.GetDestination(), configService.StatusFailure, timeoutReason, 0)
+	}
+	if !persistConfig {
+		return
+	}
+	for _, target := range targets {
+		configService.UpdateTargetStatus(config, target.GetDestination(), configService.StatusFailure, timeoutReason)
+	}
+	if err := configService.WriteBackupConfig(configPath, config); err != nil {
+		fmt.Printf(i18nService.T("%s⚠️  Warning: Failed to record the timeout in %s:%s %v\n"), ColorYellow, configPath, ColorReset, err)
+	}
+}
+
+// uploadOptions returns the upload settings of a remote target.
+// Object-locked uploads are retained for the configured days from now.
+func uploadOptions(target configService.ResolvedTarget) storageService.UploadOptions {
+	if target.Upload == nil {
+		return storageService.UploadOptions{}
+	}
+	opts := storageService.UploadOptions{
+		PartSize:    int64(target.Upload.PartSize) << 20,
+		Concurrency: target.Upload.Concurrency,
+	}
+	if lock := target.Upload.ObjectLock; lock != nil {
+		opts.ObjectLock = &storageService.ObjectLock{
+			Mode:        strings.ToUpper(lock.Mode),
+			RetainUntil: time.Now().AddDate(0, 0, lock.Days),
+		}
+	}
+	return opts
+}
+
+// uploadRemoteChecksums adds checksums by file name to the SHA256SUMS file of a remote directory;
+// exists tells whether the directory has one already
+func uploadRemoteChecksums(remoteDir *storageService.Remote, exists bool, sums map[string]string, opts storageService.UploadOptions) error {
+	tmpDir, err := os.MkdirTemp("", "go-backup-sums-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sumsRemote := remoteDir.Join(backupService.ChecksumsFileName)
+	if exists {
+		if err := storageService.Download(sumsRemote, filepath.Join(tmpDir, backupService.ChecksumsFileName)); err != nil {
+			return err
+		}
+	}
+
+	if err := backupService.AddChecksums(tmpDir, sums); err != nil {
+		return err
+	}
+	return storageService.Upload(filepath.Join(tmpDir, backupService.ChecksumsFileName), sumsRemote, opts)
+}
+
+// uploadRemoteChecksumFile uploads the .sha256 file of the artifact next to it, under its name on the remote target
+func uploadRemoteChecksumFile(artifact *backupArtifact, remoteFile, remoteDir *storageService.Remote, opts storageService.UploadOptions) error {
+	sum, err := artifact.sha256()
+	if err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp("", "go-backup-sums-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	localPath := filepath.Join(tmpDir, remoteFile.Base())
+	if err := backupService.WriteChecksumFile(localPath, sum); err != nil {
+		return err
+	}
+	return storageService.Upload(localPath+backupService.ChecksumSuffix, remoteDir.Join(remoteFile.Base()+backupService.ChecksumSuffix), opts)
+}
+
+// uploadRemoteParity creates parity files for the artifact under its name on the remote target
+// and uploads them next to it. Returns the number of files uploaded.
+func uploadRemoteParity(artifact *backupArtifact, remoteFile, remoteDir *storageService.Remote, percent int, opts storageService.UploadOptions) (int, error) {
+	tmpDir, err := os.MkdirTemp("", "go-backup-parity-")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// par2 records the archive's name, so it works on a link or copy named like the upload
+	localPath := filepath.Join(tmpDir, remoteFile.Base())
+	if err := os.Link(artifact.path, localPath); err != nil {
+		if err := backupService.CopyFile(artifact.path, localPath); err != nil {
+			return 0, err
+		}
+	}
+	files, err := backupService.CreateParity(localPath, percent)
+	if err != nil {
+		return 0, err
+	}
+	for _, file := range files {
+		if err := storageService.Upload(file, remoteDir.Join(filepath.Base(file)), opts); err != nil {
+			return 0, err
+		}
+	}
+	return len(files), nil
+}
+
+// uploadRemoteConfig uploads the config with usage help, encrypted for the recipient if one is given.
+// Returns the checksum of the uploaded file.
+func uploadRemoteConfig(configPath string, remote *storageService.Remote, receiver string, opts storageService.UploadOptions) (string, error) {
+	tmpDir, err := os.MkdirTemp("", "go-backup-config-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	localPath := filepath.Join(tmpDir, remote.Base())
+	if err := writeConfigCopy(configPath, localPath, receiver); err != nil {
+		return "", err
+	}
+	sum, err := backupService.FileSHA256(localPath)
+	if err != nil {
+		return "", err
+	}
+	return sum, storageService.Upload(localPath, remote, opts)
+}
+
+// rotateRemoteTarget deletes the backups beyond maxBackups from a remote directory, with their config,
+// manifest and parity files, and drops them from its SHA256SUMS file. files is the directory's listing
+// from before fileName was uploaded; fileName is empty when nothing was.
+func rotateRemoteTarget(remoteDir *storageService.Remote, files []storageService.RemoteFile, fileName, prefix string,
+	maxBackups int, keep map[string]bool, opts storageService.UploadOptions) error {
+	listed := make([]backupService.ListedFile, 0, len(files)+1)
+	for _, file := range files {
+		if file.Name != fileName {
+			listed = append(listed, backupService.ListedFile{Name: file.Name, ModTime: file.ModTime})
+		}
+	}
+	if fileName != "" {
+		listed = append(listed, backupService.ListedFile{Name: fileName, ModTime: time.Now()})
+	}
+
+	var removed []string
+	for _, backup := range backupService.ExpiredBackups(listed, prefix, maxBackups, keep) {
+		if err := storageService.Delete(remoteDir.Join(backup.Name)); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", remoteDir.Join(backup.Name), err)
+		}
+		removed = append(removed, backup.Name)
+		fmt.Printf(i18nService.T("  Deleted old backup: %s\n"), remoteDir.Join(backup.Name))
+		for _, name := range backup.Associated {
+			if err := storageService.Delete(remoteDir.Join(name)); err != nil {
+				fmt.Printf(i18nService.T("  Warning: Failed to delete associated file %s: %v\n"), remoteDir.Join(name), err)
+			} else {
+				removed = append(removed, name)
+				fmt.Printf(i18nService.T("  Deleted associated file: %s\n"), remoteDir.Join(name))
+			}
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "go-backup-sums-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	sumsRemote := remoteDir.Join(backupService.ChecksumsFileName)
+	if err := storageService.Download(sumsRemote, filepath.Join(tmpDir, backupService.ChecksumsFileName)); err != nil {
+		return fmt.Errorf("failed to update %s: %w", backupService.ChecksumsFileName, err)
+	}
+	if err := backupService.RemoveChecksums(tmpDir, removed); err != nil {
+		return err
+	}
+	return storageService.Upload(filepath.Join(tmpDir, backupService.ChecksumsFileName), sumsRemote, opts)
+}
+
+// metadataSnapshotBase returns the archive holding the current file contents when the latest
+// backup in the directory has the same contents, so only times or permissions changed since
+func metadataSnapshotBase(dest string, history []configService.BackupRecord, entries []compressionService.ArchiveEntry) (string, bool) {
+	if len(history) == 0 {
+		return "", false
+	}
+
+	latest := history[0]
+	baseArchive := latest.Filename
+	manifestPath := filepath.Join(dest, backupService.BackupBaseName(latest.Filename)+backupService.ManifestSuffix)
+	if latest.SnapshotOf != "" {
+		baseArchive = latest.SnapshotOf
+		manifestPath = filepath.Join(dest, latest.Filename)
+	}
+	if _, err := os.Stat(filepath.Join(dest, baseArchive)); err != nil {
+		return "", false
+	}
+
+	previous, err := backupService.ReadManifest(manifestPath)
+	if err != nil {
+		return "", false
+	}
+	return baseArchive, backupService.SameContents(previous, entries)
+}
+
+// snapshotTargets reports whether every target could record a metadata snapshot instead of
+// storing an archive: they are local directories receiving an unencrypted variant
+func snapshotTargets(targets []configService.ResolvedTarget, artifacts []*backupArtifact, targetArtifacts []int) bool {
+	for i, target := range targets {
+		if target.IsFileTarget() || storageService.IsRemote(target.GetDestination()) || artifacts[targetArtifacts[i]].receiver != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// unencryptedRemoteTargets returns the remote targets that receive an unencrypted variant
+func unencryptedRemoteTargets(targets []configService.ResolvedTarget, artifacts []*backupArtifact, targetArtifacts []int) []string {
+	var remotes []string
+	for i, target := range targets {
+		if storageService.IsRemote(target.GetDestination()) && artifacts[targetArtifacts[i]].receiver == "" {
+			remotes = append(remotes, target.GetDestination())
+		}
+	}
+	return remotes
+}
+
+// scanSecrets lists the files of the backup that look like secrets and, unless --force is set
+// or the run is not interactive, asks whether to back them up unencrypted. Returns a warning
+// for each file.
+func scanSecrets(source string, excludes, listedFiles, remotes []string) []compressionService.Warning {
+	fmt.Printf(i18nService.T("%sScanning for secrets...%s\n"), ColorDim, ColorReset)
+	findings, err := secretsService.Scan(source, excludes)
+	if err != nil {
+		fmt.Printf(i18nService.T("%s%s⚠️  Warning: Unable to scan for secrets:%s %v\n"), ColorYellow, ColorBold, ColorReset, err)
+		return nil
+	}
+	if listedFiles != nil {
+		listed := make(map[string]bool, len(listedFiles))
+		for _, path := range listedFiles {
+			listed[filepath.ToSlash(path)] = true
+		}
+		kept := findings[:0]
+		for _, finding := range findings {
+			if listed[finding.Path] {
+				kept = append(kept, finding)
+			}
+		}
+		findings = kept
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+
+	fmt.Printf(i18nService.T("%s%s🔑 Warning: %d files look like secrets and would be stored unencrypted in %s:%s\n"),
+		ColorYellow, ColorBold, len(findings), strings.Join(remotes, ", "), ColorReset)
+	warnings := make([]compressionService.Warning, 0, len(findings))
+	for i, finding := range findings {
+		if i < maxWarningsShown {
+			fmt.Printf("  - %s %s(%s)%s\n", compressionService.QuotePath(finding.Path), ColorDim, finding.Reason, ColorReset)
+		} else if i == maxWarningsShown {
+			fmt.Printf(i18nService.T("  - ... and %d more\n"), len(findings)-maxWarningsShown)
+		}
+		warnings = append(warnings, compressionService.Warning{Kind: compressionService.WarningSecret, Path: finding.Path, Message: finding.Reason})
+	}
+	fmt.Printf(i18nService.T("%sEncrypt the remote targets, or exclude these files%s\n"), ColorDim, ColorReset)
+
+	if !force && stdinIsTerminal() {
+		reader := bufio.NewReader(os.Stdin)
+		fmt.Printf(i18nService.T("%sContinue with backup anyway? [y/N]:%s "), ColorYellow, ColorReset)
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "y" && response != "yes" {
+			fmt.Println(i18nService.T("Backup aborted."))
+			os.Exit(0)
+		}
+	}
+	return warnings
+}
+
+// unchangedForTargets reports whether the entries hold the same contents as the latest backup of every target
+func unchangedForTargets(targets []configService.ResolvedTarget, entries []compressionService.ArchiveEntry) bool {
+	for _, target := range targets {
+		if _, ok := metadataSnapshotBase(target.GetDestination(), splitPartBackups(target.Backups), entries); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// checksumCachePath returns the checksum cache file of a source in the state directory,
+// named like its run logs so sources with the same name never share a cache
+func checksumCachePath(source string) (string, error) {
+	stateDir, err := configService.StateDir()
+	if err != nil {
+		return "", err
+	}
+	absSource, err := filepath.Abs(source)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "checksums", logsService.LocationDirName(absSource)+".json"), nil
+}
+
+// recordMetadataSnapshot writes a manifest referring to the existing archive instead of
+// storing an identical archive again, and records it in the target's history
+func recordMetadataSnapshot(config *configService.BackupConfig, configPath, source, dest, baseArchive string,
+	artifact *backupArtifact, entries []compressionService.ArchiveEntry) {
+	manifestName := backupService.BackupBaseName(artifact.fileName) + backupService.ManifestSuffix
+	manifestPath := filepath.Join(dest, manifestName)
+
+	manifest := backupService.NewManifest(baseArchive, source, entries)
+	manifest.MetadataOnly = true
+	manifest.RunID = runID
+	manifest.Tags = runTags
+	manifest.Message = runMessage
+	manifest.Provenance = artifact.provenance
+	if err := backupService.WriteManifest(manifestPath, manifest); err != nil {
+		fmt.Printf(i18nService.T("  %s❌ Error: failed to write metadata snapshot -%s %v\n"), ColorRed, ColorReset, err)
+		logTargetResult(dest, configService.StatusFailure, err.Error(), 0)
+		configService.UpdateTargetStatus(config, dest, configService.StatusFailure, err.Error())
+		configService.WriteBackupConfig(configPath, config)
+		return
+	}
+	fmt.Printf(i18nService.T("  %s✅ Success:%s file contents unchanged since %s\n"), ColorGreen, ColorReset, baseArchive)
+	fmt.Printf(i18nService.T("  %s📋 Metadata snapshot:%s %s\n"), ColorDim, ColorReset, manifestName)
+
+	var size int64
+	if info, err := os.Stat(manifestPath); err == nil {
+		size = info.Size()
+	}
+	logTargetResult(dest, configService.StatusSuccess, "Metadata snapshot of "+baseArchive, 0)
+	configService.UpdateTargetStatus(config, dest, configService.StatusSuccess, "Metadata snapshot of "+baseArchive)
+	configService.AddBackupRecord(config, dest, configService.BackupRecord{
+		Filename:   manifestName,
+		Source:     source,
+		Host:       backupService.CurrentHost(),
+		RunID:      runID,
+		CreatedAt:  config.Now(),
+		Size:       size,
+		SnapshotOf: baseArchive,
+		Tags:       runTags,
+		Message:    runMessage,
+		Provenance: artifact.provenance,
+	})
+	if err := configService.WriteBackupConfig(configPath, config); err != nil {
+		fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to update backup history in config -%s %v\n"), ColorYellow, ColorReset, err)
+	} else {
+		fmt.Printf(i18nService.T("  %s📝 History:%s Updated backup history in %s\n"), ColorDim, ColorReset, configPath)
+	}
+}
+
+// printCompressionReport shows the time and space spent compressing file types that
+// barely shrink, and suggests noCompress patterns that would store them instead
+func printCompressionReport(stats *compressionService.ArchiveStats) {
+	incompressible := stats.Incompressible()
+	if len(incompressible) == 0 {
+		return
+	}
+
+	fmt.Printf(i18nService.T("\n%s%s📊 Incompressible files:%s\n"), ColorCyan, ColorBold, ColorReset)
+	patterns := make([]string, 0, len(incompressible))
+	for _, ext := range incompressible {
+		fmt.Printf(i18nService.T("  %s%s%s: %d file(s), %s, %s compressing, %.1f%% saved\n"),
+			ColorWhite, ext.Extension, ColorReset, ext.Files, formatFileSize(ext.Size),
+			formatEstimateDuration(ext.Duration), (1-ext.Ratio())*100)
+		patterns = append(patterns, fmt.Sprintf("%q", "*"+ext.Extension))
+	}
+	fmt.Printf(i18nService.T("%sSuggestion: store these without compression by adding to .backup.yaml:%s\n"), ColorYellow, ColorReset)
+	fmt.Printf("  noCompress: [%s]\n", strings.Join(patterns, ", "))
+}
+
+// maxWarningsShown limits how many warnings of each kind are listed at the end of a run; the manifest has them all
+const maxWarningsShown = 10
+
+// warningTitles describes each kind of warning in the run summary, in the order they are shown
+var warningTitles = []struct{ kind, title string }{
+	{compressionService.WarningUnreadable, "could not be read and were skipped"},
+	{compressionService.WarningVanished, "vanished during the backup and were skipped"},
+	{compressionService.WarningSymlinkLoop, "are symlink loops"},
+	{compressionService.WarningSpecialFile, "are sockets, pipes or devices and were skipped"},
+	{compressionService.WarningOversize, "exceed the recommended size for tar archives"},
+	{compressionService.WarningSecret, "look like secrets and were stored unencrypted in a remote target"},
+}
+
+// printWarnings lists the non-fatal issues of the run, grouped by kind
+func printWarnings(warnings []compressionService.Warning) {
+	if len(warnings) == 0 {
+		return
+	}
+
+	fmt.Printf(i18nService.T("\n%s%s⚠️  Warnings (%d):%s\n"), ColorYellow, ColorBold, len(warnings), ColorReset)
+	counts := compressionService.CountWarnings(warnings)
+	for _, group := range warningTitles {
+		if counts[group.kind] == 0 {
+			continue
+		}
+		fmt.Printf(i18nService.T("  %s%d path(s) %s:%s\n"), ColorYellow, counts[group.kind], i18nService.T(group.title), ColorReset)
+		shown := 0
+		for _, warning := range warnings {
+			if warning.Kind != group.kind {
+				continue
+			}
+			if shown == maxWarningsShown {
+				fmt.Printf(i18nService.T("    - ... and %d more (listed in the manifest)\n"), counts[group.kind]-maxWarningsShown)
+				break
+			}
+			fmt.Printf("    - %s %s(%s)%s\n", compressionService.QuotePath(warning.Path), ColorDim, warning.Message, ColorReset)
+			shown++
+		}
+	}
+	if counts[compressionService.WarningUnreadable]+counts[compressionService.WarningVanished] > 0 {
+		fmt.Printf(i18nService.T("%sUse options.onError: fail in .backup.yaml to fail the backup instead of skipping files%s\n"), ColorDim, ColorReset)
+	}
+}
+
+// removeBackupArtifacts deletes the temporary artifact and manifest files
+func removeBackupArtifacts(artifacts []*backupArtifact) {
+	for _, artifact := range artifacts {
+		if !artifact.moved {
+			os.Remove(artifact.path)
+		}
+		if artifact.manifestPath != "" {
+			os.Remove(artifact.manifestPath)
+		}
+	}
+}
+
+// copyConfigVerified copies the config with usage help next to a backup at destPath, encrypted for
+// the recipient if one is given, and checks the copy like the backup. Returns its checksum.
+func copyConfigVerified(configPath, destPath, receiver string) (string, error) {
+	tmpDir, err := os.MkdirTemp("", "go-backup-config-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	localPath := filepath.Join(tmpDir, filepath.Base(destPath))
+	if err := writeConfigCopy(configPath, localPath, receiver); err != nil {
+		return "", err
+	}
+	return backupService.CopyFileVerified(localPath, destPath)
+}
+
+// writeConfigCopy writes the config with usage help to path, encrypted for the recipient if one is given
+func writeConfigCopy(configPath, path, receiver string) error {
+	if receiver != "" {
+		return copyEncryptedConfig(configPath, path, receiver)
+	}
+	return configService.CopyConfigWithHelp(configPath, path, false, "")
+}
+
+// copyEncryptedConfig copies the config with usage help to destPath, encrypted for the recipient
+func copyEncryptedConfig(configPath, destPath, receiver string) error {
+	tmpDir, err := os.MkdirTemp("", "go-backup-config-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	plainPath := filepath.Join(tmpDir, strings.TrimSuffix(filepath.Base(destPath), ".gpg"))
+	if err := configService.CopyConfigWithHelp(configPath, plainPath, true, receiver); err != nil {
+		return err
+	}
+
+	encryptedPath, err := encryptionService.GPGEncrypt(plainPath, receiver)
+	if err != nil {
+		return err
+	}
+	return backupService.CopyFile(encryptedPath, destPath)
+}
@@ -1,24 +1,71 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"time"
 
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
 	configService "github.com/kennycyb/go-backup/internal/service/config"
+	i18nService "github.com/kennycyb/go-backup/internal/service/i18n"
+	logsService "github.com/kennycyb/go-backup/internal/service/logs"
+	notifyService "github.com/kennycyb/go-backup/internal/service/notify"
+	priorityService "github.com/kennycyb/go-backup/internal/service/priority"
+	systemlogService "github.com/kennycyb/go-backup/internal/service/systemlog"
 	"github.com/spf13/cobra"
 )
 
-var continueOnError bool
+var (
+	continueOnError      bool
+	runAllVerbose        bool
+	runAllIgnoreSchedule bool
+	runAllNice           bool
+	runAllFailFast       bool
+	runAllLogSyslog      bool
+	runAllNotifyWebhook  string
+	runAllNoJitter       bool
+)
+
+// runAllFailureTail is how many log lines run-all shows for a failed backup
+const runAllFailureTail = 10
 
 // runAllCmd represents the run-all command
 var runAllCmd = &cobra.Command{
 	Use:   "run-all",
 	Short: "Run backups for all locations in global registry",
-	Long: `Run backups for all locations tracked in ~/.backup.yaml.
-This command reads the global registry and executes backups for each
-tracked location. If a location no longer exists, an error is displayed.`,
+	Long: `Run backups for all locations tracked in the global registry,
+~/.config/go-backup/registry.yaml (or the older ~/.backup.yaml).
+This command executes backups for each tracked location. If a location no longer exists, an error is displayed.
+
+Registry entries may override how a location is backed up: enabled: false
+pauses it, profile selects .backup.<profile>.yaml, flags are passed to the
+run command and schedule (hourly, daily, weekly, monthly or a duration)
+skips locations that ran more recently than that.
+
+No backups are started in the registry's blackout windows (e.g. "Mon-Fri
+09:00-18:00") or those of a location, and with jitter (e.g. 30m) run-all
+first waits a random time of up to that long. Locations whose config sets
+options.requireAC or options.skipOnMetered are deferred while the machine
+runs on battery or a metered connection, and tried again on the next run.
+
+Locations run in order of their priority, highest first; those sharing a
+priority form a group, e.g. databases before app directories before large
+media folders. With --fail-fast a failing group is finished, but the
+groups after it are not started.
+
+The output of each backup is saved to a log file under
+~/.local/state/go-backup/logs/ and only a summary is printed; use
+'go-backup logs <location>' to view it, or --verbose to also print it live.
+
+With notify.webhook in the registry (or --notify-webhook), one summary of all
+locations, with their durations and backup sizes, is posted to the webhook
+after the run.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Color constants
 		const (
@@ -31,51 +78,143 @@ tracked location. If a location no longer exists, an error is displayed.`,
 			ColorDim    = "\033[2m"
 		)
 
-		fmt.Printf("%s%s\n======================================\n   📦  Running All Tracked Backups   \n======================================%s\n\n", ColorCyan, ColorBold, ColorReset)
+		fmt.Printf(i18nService.T("%s%s\n======================================\n   📦  Running All Tracked Backups   \n======================================%s\n\n"), ColorCyan, ColorBold, ColorReset)
 
 		// Read global registry
 		registry, err := configService.ReadGlobalRegistry()
 		if err != nil {
-			fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
-			fmt.Printf("%sHint:%s Create ~/.backup.yaml to track backup locations.\n", ColorDim, ColorReset)
-			fmt.Printf("%sSee docs/global-registry.md for more information.%s\n", ColorDim, ColorReset)
+			fmt.Printf(i18nService.T("%s%s❌ Error:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+			fmt.Printf(i18nService.T("%sHint:%s Create ~/.config/go-backup/registry.yaml to track backup locations.\n"), ColorDim, ColorReset)
+			fmt.Printf(i18nService.T("%sSee docs/global-registry.md for more information.%s\n"), ColorDim, ColorReset)
 			os.Exit(1)
 		}
 
 		if len(registry.Backups) == 0 {
-			fmt.Printf("%s%s⚠️  No backup locations found in global registry.%s\n", ColorYellow, ColorBold, ColorReset)
-			fmt.Printf("%sRun backups in directories with .backup.yaml to register them.%s\n", ColorDim, ColorReset)
+			fmt.Printf(i18nService.T("%s%s⚠️  No backup locations found in global registry.%s\n"), ColorYellow, ColorBold, ColorReset)
+			fmt.Printf(i18nService.T("%sRun backups in directories with .backup.yaml to register them.%s\n"), ColorDim, ColorReset)
 			return
 		}
 
-		fmt.Printf("%sFound %d backup location(s) in registry:%s\n\n", ColorDim, len(registry.Backups), ColorReset)
+		// Backups started below inherit the lower priority
+		if runAllNice {
+			if err := priorityService.Lower(); err != nil {
+				fmt.Printf(i18nService.T("%s⚠️  Warning:%s %v\n"), ColorYellow, ColorReset, err)
+			}
+		}
+
+		// Spread the start of machines that are scheduled at the same time
+		if !runAllNoJitter {
+			jitter, err := registry.JitterDuration()
+			if err != nil {
+				fmt.Printf(i18nService.T("%s%s❌ Error:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+				os.Exit(1)
+			}
+			if jitter > 0 {
+				delay := rand.N(jitter)
+				fmt.Printf(i18nService.T("%s⏳ Waiting %s before starting (jitter up to %s)%s\n\n"), ColorDim, delay.Round(time.Second), jitter, ColorReset)
+				time.Sleep(delay)
+			}
+		}
+
+		fmt.Printf(i18nService.T("%sFound %d backup location(s) in registry:%s\n\n"), ColorDim, len(registry.Backups), ColorReset)
+
+		// Every location's outcome goes into one summary notification at the end
+		summary := &notifyService.Summary{Host: backupService.CurrentHost(), StartedAt: time.Now()}
+		addResult := func(entry configService.GlobalBackupEntry, status, reason string, duration time.Duration) *notifyService.LocationResult {
+			summary.Locations = append(summary.Locations, notifyService.LocationResult{Location: entry.Location, Status: status, Reason: reason, Duration: duration})
+			return &summary.Locations[len(summary.Locations)-1]
+		}
 
 		successCount := 0
 		errorCount := 0
 		missingCount := 0
+		skippedCount := 0
+		deferredCount := 0
+		rehearsalFailures := 0
 
-		for i, entry := range registry.Backups {
-			fmt.Printf("%s[%d/%d]%s %s\n", ColorBold, i+1, len(registry.Backups), ColorReset, entry.Location)
+		// --fail-fast keeps going until the end of the failing group
+		keepGoing := continueOnError || runAllFailFast
+
+		backups := registry.OrderedBackups()
+		grouped := backups[0].Priority != backups[len(backups)-1].Priority
+		groupFailures := 0
+		for i, entry := range backups {
+			if grouped && (i == 0 || entry.Priority != backups[i-1].Priority) {
+				if runAllFailFast && errorCount+missingCount > groupFailures {
+					fmt.Printf(i18nService.T("%s%s⚠️  Stopping: a location with priority %d failed, so lower priorities are not run.%s\n"), ColorYellow, ColorBold, backups[i-1].Priority, ColorReset)
+					break
+				}
+				groupFailures = errorCount + missingCount
+				fmt.Printf(i18nService.T("%s── Priority %d ──%s\n"), ColorCyan, entry.Priority, ColorReset)
+			}
+			fmt.Printf("%s[%d/%d]%s %s\n", ColorBold, i+1, len(backups), ColorReset, entry.Location)
+
+			if !entry.IsEnabled() {
+				fmt.Printf(i18nService.T("  %s⏸️  Skipped:%s disabled in registry\n\n"), ColorDim, ColorReset)
+				addResult(entry, notifyService.StatusSkipped, "disabled", 0)
+				skippedCount++
+				continue
+			}
+
+			if pause, paused, err := configService.PausedAt(entry.Location, time.Now()); err == nil && paused {
+				fmt.Printf(i18nService.T("  %s⏸️  Skipped:%s %s\n\n"), ColorDim, ColorReset, pause.Describe())
+				addResult(entry, notifyService.StatusSkipped, pause.Describe(), 0)
+				skippedCount++
+				continue
+			}
+
+			if !runAllIgnoreSchedule {
+				window, blackout, err := configService.InBlackout(registry.BlackoutWindows(entry), time.Now())
+				if err == nil && blackout {
+					fmt.Printf(i18nService.T("  %s⏭️  Skipped:%s in blackout window %s\n\n"), ColorDim, ColorReset, window)
+					addResult(entry, notifyService.StatusSkipped, "blackout window "+window, 0)
+					skippedCount++
+					continue
+				}
+				due := false
+				if err == nil {
+					due, err = entry.IsDue(time.Now())
+				}
+				if err != nil {
+					fmt.Printf(i18nService.T("  %s%s❌ Error:%s %v\n"), ColorRed, ColorBold, ColorReset, err)
+					addResult(entry, notifyService.StatusFailed, err.Error(), 0)
+					errorCount++
+					if !keepGoing {
+						fmt.Printf(i18nService.T("\n%s%s⚠️  Stopping due to error. Use --continue to skip errors.%s\n"), ColorYellow, ColorBold, ColorReset)
+						break
+					}
+					fmt.Println()
+					continue
+				}
+				if !due {
+					fmt.Printf(i18nService.T("  %s⏭️  Skipped:%s not due yet (%s schedule, last run %s)\n\n"), ColorDim, ColorReset, entry.Schedule, entry.RunAt.Local().Format("2006-01-02 15:04"))
+					addResult(entry, notifyService.StatusSkipped, "not due yet", 0)
+					skippedCount++
+					continue
+				}
+			}
 
 			// Check if location exists
 			if _, err := os.Stat(entry.Location); os.IsNotExist(err) {
-				fmt.Printf("  %s%s❌ Error:%s Directory does not exist\n", ColorRed, ColorBold, ColorReset)
+				fmt.Printf(i18nService.T("  %s%s❌ Error:%s Directory does not exist\n"), ColorRed, ColorBold, ColorReset)
+				addResult(entry, notifyService.StatusMissing, "directory does not exist", 0)
 				missingCount++
-				if !continueOnError {
-					fmt.Printf("\n%s%s⚠️  Stopping due to error. Use --continue to skip errors.%s\n", ColorYellow, ColorBold, ColorReset)
+				if !keepGoing {
+					fmt.Printf(i18nService.T("\n%s%s⚠️  Stopping due to error. Use --continue to skip errors.%s\n"), ColorYellow, ColorBold, ColorReset)
 					break
 				}
 				fmt.Println()
 				continue
 			}
 
-			// Check if .backup.yaml exists in the location
-			configPath := filepath.Join(entry.Location, ".backup.yaml")
+			// Check if the config file exists in the location
+			configPath := entry.ConfigPath()
 			if _, err := os.Stat(configPath); os.IsNotExist(err) {
-				fmt.Printf("  %s%s❌ Error:%s .backup.yaml not found in directory\n", ColorRed, ColorBold, ColorReset)
+				fmt.Printf(i18nService.T("  %s%s❌ Error:%s %s not found in directory\n"), ColorRed, ColorBold, ColorReset, filepath.Base(configPath))
+				addResult(entry, notifyService.StatusMissing, filepath.Base(configPath)+" not found", 0)
 				missingCount++
-				if !continueOnError {
-					fmt.Printf("\n%s%s⚠️  Stopping due to error. Use --continue to skip errors.%s\n", ColorYellow, ColorBold, ColorReset)
+				if !keepGoing {
+					fmt.Printf(i18nService.T("\n%s%s⚠️  Stopping due to error. Use --continue to skip errors.%s\n"), ColorYellow, ColorBold, ColorReset)
 					break
 				}
 				fmt.Println()
@@ -89,21 +228,95 @@ tracked location. If a location no longer exists, an error is displayed.`,
 				execPath = "go-backup"
 			}
 
-			// Run backup for this location
-			backupCmd := exec.Command(execPath, "run", "-s", entry.Location, "-f", configPath, "--force")
-			backupCmd.Stdout = os.Stdout
-			backupCmd.Stderr = os.Stderr
+			// Run backup for this location, capturing its output in a log file. The run's ID
+			// is in the log, its records and the summary, so a failure can be traced back.
+			locationRunID := backupService.NewRunID()
+			runArgs := append([]string{"run", "-s", entry.Location, "-f", configPath, "--force", "--run-id", locationRunID}, entry.Flags...)
+			if runAllLogSyslog {
+				runArgs = append(runArgs, "--log-syslog")
+			}
+			if strictConfig {
+				runArgs = append(runArgs, "--strict-config")
+			}
+			backupCmd := exec.Command(execPath, runArgs...)
+			// run-all already checked for a newer release, so the runs do not repeat the notice
+			backupCmd.Env = append(os.Environ(), noUpdateCheckEnv+"=1")
+			started := time.Now()
+			logFile, logErr := logsService.CreateLog(entry.Location, started)
+			if logErr != nil {
+				fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to create log file, showing output instead:%s %v\n"), ColorYellow, ColorReset, logErr)
+				backupCmd.Stdout = os.Stdout
+				backupCmd.Stderr = os.Stderr
+			} else {
+				fmt.Fprintf(logFile, "# go-backup run %s for %s started %s\n", locationRunID, entry.Location, started.Format(time.RFC3339))
+				var output io.Writer = logFile
+				if runAllVerbose {
+					output = io.MultiWriter(os.Stdout, logFile)
+				}
+				backupCmd.Stdout = output
+				backupCmd.Stderr = output
+			}
 
 			err = backupCmd.Run()
-			if err != nil {
-				fmt.Printf("  %s%s❌ Error:%s Backup failed: %v\n", ColorRed, ColorBold, ColorReset, err)
+			duration := time.Since(started).Round(time.Second)
+			if logFile != nil {
+				fmt.Fprintf(logFile, "# finished after %s: %s\n", duration, runResult(err))
+				logFile.Close()
+				if err := logsService.PruneLogs(entry.Location, logsService.DefaultKeepLogs); err != nil {
+					fmt.Printf(i18nService.T("  %s⚠️  Warning: Failed to remove old logs:%s %v\n"), ColorYellow, ColorReset, err)
+				}
+			}
+
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && exitErr.ExitCode() == deferredExitCode {
+				// The run put the backup off and left run_at alone, so the next run-all tries again
+				reason := runDeferReason(configPath)
+				fmt.Printf(i18nService.T("  %s🔌 Deferred:%s %s\n"), ColorYellow, ColorReset, reason)
+				addResult(entry, notifyService.StatusDeferred, reason, 0).RunID = locationRunID
+				deferredCount++
+			} else if err != nil {
+				fmt.Printf(i18nService.T("  %s%s❌ Error:%s Backup failed: %v (%s)\n"), ColorRed, ColorBold, ColorReset, err, duration)
+				addResult(entry, notifyService.StatusFailed, err.Error(), duration).RunID = locationRunID
+				fmt.Printf(i18nService.T("  %sRun ID:%s %s\n"), ColorDim, ColorReset, locationRunID)
+				if logFile != nil {
+					// Show the end of the log, which usually holds the reason
+					if !runAllVerbose {
+						if lines, err := logsService.TailLines(logFile.Name(), runAllFailureTail); err == nil {
+							for _, line := range lines {
+								fmt.Printf("  %s│%s %s\n", ColorDim, ColorReset, line)
+							}
+						}
+					}
+					fmt.Printf(i18nService.T("  %sLog:%s %s\n"), ColorDim, ColorReset, logFile.Name())
+				}
 				errorCount++
-				if !continueOnError {
-					fmt.Printf("\n%s%s⚠️  Stopping due to error. Use --continue to skip errors.%s\n", ColorYellow, ColorBold, ColorReset)
+				if !keepGoing {
+					fmt.Printf(i18nService.T("\n%s%s⚠️  Stopping due to error. Use --continue to skip errors.%s\n"), ColorYellow, ColorBold, ColorReset)
 					break
 				}
 			} else {
+				fmt.Printf(i18nService.T("  %s✅ Success%s (%s)\n"), ColorGreen, ColorReset, duration)
+				result := addResult(entry, notifyService.StatusSuccess, "", duration)
+				result.RunID = locationRunID
+				result.Size = runBackupSize(configPath, started)
 				successCount++
+
+				logPath := ""
+				if logFile != nil {
+					logPath = logFile.Name()
+				}
+				rehearsed, err := runDueRehearsal(execPath, entry.Location, configPath, logPath)
+				switch {
+				case err != nil:
+					fmt.Printf(i18nService.T("  %s❌ Restore rehearsal failed:%s %v\n"), ColorRed, ColorReset, err)
+					result.RehearsalFailed = true
+					rehearsalFailures++
+				case rehearsed:
+					fmt.Printf(i18nService.T("  %s🧪 Restore rehearsal passed%s\n"), ColorGreen, ColorReset)
+				}
+				if logFile != nil {
+					fmt.Printf(i18nService.T("  %sLog:%s %s\n"), ColorDim, ColorReset, logFile.Name())
+				}
 			}
 
 			fmt.Println()
@@ -111,18 +324,42 @@ tracked location. If a location no longer exists, an error is displayed.`,
 
 		// Summary
 		fmt.Printf("%s%s======================================\n", ColorCyan, ColorBold)
-		fmt.Printf("             Summary\n")
+		fmt.Print(i18nService.T("             Summary\n"))
 		fmt.Printf("======================================%s\n", ColorReset)
-		fmt.Printf("%s✅ Successful:%s %d\n", ColorGreen, ColorReset, successCount)
+		fmt.Printf(i18nService.T("%s✅ Successful:%s %d\n"), ColorGreen, ColorReset, successCount)
 		if errorCount > 0 {
-			fmt.Printf("%s❌ Failed:%s %d\n", ColorRed, ColorReset, errorCount)
+			fmt.Printf(i18nService.T("%s❌ Failed:%s %d\n"), ColorRed, ColorReset, errorCount)
 		}
 		if missingCount > 0 {
-			fmt.Printf("%s⚠️  Missing:%s %d\n", ColorYellow, ColorReset, missingCount)
+			fmt.Printf(i18nService.T("%s⚠️  Missing:%s %d\n"), ColorYellow, ColorReset, missingCount)
+		}
+		if skippedCount > 0 {
+			fmt.Printf(i18nService.T("%s⏭️  Skipped:%s %d\n"), ColorDim, ColorReset, skippedCount)
+		}
+		if deferredCount > 0 {
+			fmt.Printf(i18nService.T("%s🔌 Deferred:%s %d\n"), ColorYellow, ColorReset, deferredCount)
+		}
+		if rehearsalFailures > 0 {
+			fmt.Printf(i18nService.T("%s🧪 Failed rehearsals:%s %d\n"), ColorRed, ColorReset, rehearsalFailures)
+		}
+		fmt.Printf(i18nService.T("%s📊 Total:%s %d\n"), ColorDim, ColorReset, len(backups))
+
+		// Each run logs its own targets; the summary tells whether the whole run-all went well
+		if runAllLogSyslog {
+			priority := systemlogService.PriorityInfo
+			if errorCount > 0 || missingCount > 0 || rehearsalFailures > 0 {
+				priority = systemlogService.PriorityErr
+			}
+			message := fmt.Sprintf("run-all finished: %d succeeded, %d failed, %d missing, %d skipped, %d deferred, %d failed rehearsals", successCount, errorCount, missingCount, skippedCount, deferredCount, rehearsalFailures)
+			if err := logToSyslog(priority, message); err != nil {
+				fmt.Printf(i18nService.T("%s⚠️  Warning:%s %v\n"), ColorYellow, ColorReset, err)
+			}
 		}
-		fmt.Printf("%s📊 Total:%s %d\n", ColorDim, ColorReset, len(registry.Backups))
 
-		if errorCount > 0 || missingCount > 0 {
+		summary.FinishedAt = time.Now()
+		sendRunAllSummary(registry.Notify, summary)
+
+		if errorCount > 0 || missingCount > 0 || rehearsalFailures > 0 {
 			os.Exit(1)
 		}
 	},
@@ -130,5 +367,129 @@ tracked location. If a location no longer exists, an error is displayed.`,
 
 func init() {
 	runAllCmd.Flags().BoolVar(&continueOnError, "continue", false, "Continue running backups even if one fails")
+	runAllCmd.Flags().BoolVar(&runAllFailFast, "fail-fast", false, "Finish the priority group of a failed backup, then stop before lower priorities")
+	runAllCmd.Flags().BoolVar(&runAllLogSyslog, "log-syslog", false, "Write the results of every backup and a summary to syslog or journald")
+	runAllCmd.Flags().BoolVar(&runAllIgnoreSchedule, "ignore-schedule", false, "Run locations whose schedule is not due yet or that are in a blackout window")
+	runAllCmd.Flags().BoolVar(&runAllNoJitter, "no-jitter", false, "Start right away instead of waiting a random time of up to the registry's jitter")
+	runAllCmd.Flags().BoolVar(&runAllNice, "nice", false, "Run all backups at a lower CPU and IO priority")
+	runAllCmd.Flags().StringVar(&runAllNotifyWebhook, "notify-webhook", "", "Post a summary of all locations to this URL after the run (overrides notify.webhook)")
+	runAllCmd.Flags().BoolVarP(&runAllVerbose, "verbose", "v", false, "Print the output of each backup as well as saving it to the log")
 	rootCmd.AddCommand(runAllCmd)
 }
+
+// logToSyslog writes a single entry to the system log
+func logToSyslog(priority systemlogService.Priority, message string) error {
+	logger, err := systemlogService.Open()
+	if err != nil {
+		return err
+	}
+	defer logger.Close()
+	return logger.Log(priority, message)
+}
+
+// sendRunAllSummary posts the summary of the run to the webhook of --notify-webhook or the
+// registry's notify settings, if any. A failure to send it is only reported.
+func sendRunAllSummary(notify *configService.NotifyConfig, summary *notifyService.Summary) {
+	if runAllNotifyWebhook != "" {
+		override := configService.NotifyConfig{Webhook: runAllNotifyWebhook}
+		if notify != nil {
+			override.On = notify.On
+		}
+		notify = &override
+	}
+	send, err := notify.ShouldNotify(summary.Failed())
+	if err == nil && send {
+		err = notifyService.Send(notify.Webhook, summary, notifyService.DefaultTimeout)
+		if err == nil {
+			fmt.Printf(i18nService.T("%s📣 Summary sent to %s%s\n"), ColorDim, webhookHost(notify.Webhook), ColorReset)
+		}
+	}
+	if err != nil {
+		fmt.Printf(i18nService.T("%s⚠️  Warning: Failed to send the summary:%s %v\n"), ColorYellow, ColorReset, err)
+	}
+}
+
+// webhookHost returns the host of a webhook URL, which unlike its path holds no secret token
+func webhookHost(webhook string) string {
+	u, err := url.Parse(webhook)
+	if err != nil || u.Host == "" {
+		return "the webhook"
+	}
+	return u.Host
+}
+
+// runBackupSize returns the size of the backup a run wrote, from the records it added to the
+// location's config. Returns 0 if none can be found, e.g. for a metadata snapshot.
+func runBackupSize(configPath string, started time.Time) int64 {
+	config, err := configService.ReadBackupConfig(configPath)
+	if err != nil {
+		return 0
+	}
+	// Every target records the same backup, possibly as differently compressed variants
+	var size int64
+	for _, target := range config.Targets {
+		for _, record := range target.Backups {
+			if !record.CreatedAt.Before(started) && record.SnapshotOf == "" {
+				size = max(size, record.Size)
+			}
+		}
+	}
+	return size
+}
+
+// runDeferReason returns why a run deferred the backup, as it recorded in the location's config
+func runDeferReason(configPath string) string {
+	config, err := configService.ReadBackupConfig(configPath)
+	if err == nil {
+		for _, target := range config.Targets {
+			if target.LastRun != nil && target.LastRun.Status == configService.StatusDeferred {
+				return target.LastRun.Message
+			}
+		}
+	}
+	return "on battery or a metered connection"
+}
+
+// runResult describes the outcome of a backup run for the log file
+func runResult(err error) string {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == deferredExitCode {
+		return "deferred"
+	}
+	if err != nil {
+		return "failed (" + err.Error() + ")"
+	}
+	return "success"
+}
+
+// runDueRehearsal runs 'go-backup rehearse' for a location whose config asks for a restore
+// rehearsal that is due, appending its output to the run's log file if there is one.
+// Returns whether a rehearsal ran; the error is set if it failed.
+func runDueRehearsal(execPath, location, configPath, logPath string) (bool, error) {
+	config, err := configService.ReadBackupConfig(configPath)
+	if err != nil {
+		return false, err
+	}
+	due, err := config.RehearsalDue(time.Now())
+	if err != nil || !due {
+		return false, err
+	}
+
+	rehearseCmd := exec.Command(execPath, "rehearse", "-s", location, "--config", configPath)
+	rehearseCmd.Env = append(os.Environ(), noUpdateCheckEnv+"=1")
+	var output io.Writer = os.Stdout
+	if logPath != "" {
+		logFile, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0644)
+		if err == nil {
+			defer logFile.Close()
+			fmt.Fprintf(logFile, "# restore rehearsal started %s\n", time.Now().Format(time.RFC3339))
+			output = logFile
+			if runAllVerbose {
+				output = io.MultiWriter(os.Stdout, logFile)
+			}
+		}
+	}
+	rehearseCmd.Stdout = output
+	rehearseCmd.Stderr = output
+	return true, rehearseCmd.Run()
+}
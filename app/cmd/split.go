@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+)
+
+// splitRootPart is the part of a split backup holding the files directly in the source
+const splitRootPart = "."
+
+// runSplitParts backs up every top-level directory of the source as its own archive, by running
+// 'go-backup run' with the same flags for each part, so one part can be restored without the others.
+// Files directly in the source form one more part, named after the source like an unsplit backup.
+// Returns the exit code of the run: 1 if any part failed.
+func runSplitParts(source string, excludes []string) int {
+	parts, err := splitParts(source, excludes)
+	if err != nil {
+		fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+		return 1
+	}
+	if len(parts) == 0 {
+		fmt.Printf("%s⚠️  Nothing to back up: the source has no directories or files that are not excluded%s\n", ColorYellow, ColorReset)
+		return 0
+	}
+	fmt.Printf("%sSplitting the backup into %d part(s)%s\n", ColorDim, len(parts), ColorReset)
+
+	execPath, err := os.Executable()
+	if err != nil {
+		execPath = "go-backup"
+	}
+	var args []string
+	for _, arg := range os.Args[1:] {
+		if arg != "--split-by-dir" && !strings.HasPrefix(arg, "--split-by-dir=") {
+			args = append(args, arg)
+		}
+	}
+	// All parts belong to the same run
+	args = append(args, "--run-id", runID)
+
+	var failed []string
+	for i, part := range parts {
+		fmt.Printf("\n%s%s[%d/%d] Part:%s %s\n", ColorCyan, ColorBold, i+1, len(parts), ColorReset, splitPartLabel(part))
+		partCmd := exec.Command(execPath, append(args, "--split-part", part)...)
+		partCmd.Stdin = os.Stdin
+		partCmd.Stdout = os.Stdout
+		partCmd.Stderr = os.Stderr
+		// The parent run already checked for a newer release
+		partCmd.Env = append(os.Environ(), noUpdateCheckEnv+"=1")
+		if err := partCmd.Run(); err != nil {
+			failed = append(failed, splitPartLabel(part))
+		}
+	}
+
+	fmt.Println()
+	if len(failed) > 0 {
+		fmt.Printf("%s%s❌ %d of %d part(s) failed:%s %s\n", ColorRed, ColorBold, len(failed), len(parts), ColorReset, strings.Join(failed, ", "))
+		return 1
+	}
+	fmt.Printf("%s%s🎉 All %d part(s) backed up successfully!%s\n", ColorGreen, ColorBold, len(parts), ColorReset)
+	return 0
+}
+
+// splitParts returns the parts of a split backup: the top-level directories that are not excluded,
+// followed by splitRootPart if files directly in the source are backed up
+func splitParts(source string, excludes []string) ([]string, error) {
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return nil, fmt.Errorf("error reading source directory: %w", err)
+	}
+
+	matcher := compressionService.NewExcludeMatcher(excludes)
+	var parts []string
+	looseFiles := false
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			// An excluded directory may still have included paths inside, e.g. with includes
+			if !matcher.Excluded(name) || !matcher.CanSkipDir(name) {
+				parts = append(parts, name)
+			}
+		} else if !matcher.Excluded(name) {
+			looseFiles = true
+		}
+	}
+	if looseFiles {
+		parts = append(parts, splitRootPart)
+	}
+	return parts, nil
+}
+
+// splitPartExcludes returns the exclude patterns that leave only the part in the archive: the other
+// top-level entries of the source, or all top-level directories for splitRootPart.
+// Paths in the archive stay relative to the source, so a part restores into its own directory.
+func splitPartExcludes(source, part string) ([]string, error) {
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return nil, fmt.Errorf("error reading source directory: %w", err)
+	}
+
+	var excludes []string
+	found := part == splitRootPart
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case name == part:
+			found = true
+		case part != splitRootPart || entry.IsDir():
+			excludes = append(excludes, "/"+escapeExcludePattern(name))
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("part %s not found in %s", part, source)
+	}
+	return excludes, nil
+}
+
+// escapeExcludePattern escapes the characters exclude patterns treat as wildcards, so a file
+// name matches only itself
+func escapeExcludePattern(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(`\*?[`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// splitPartLabel describes a part for the output
+func splitPartLabel(part string) string {
+	if part == splitRootPart {
+		return "files in the source directory"
+	}
+	return part
+}
+
+// splitRecordPart returns the part recorded with the backups of this run; the files directly in
+// the source share the history of unsplit backups, whose archives are named the same way
+func splitRecordPart() string {
+	if runSplitPart == splitRootPart {
+		return ""
+	}
+	return runSplitPart
+}
+
+// splitPartBackups returns the records of the part this run backs up, or all records of an unsplit run
+func splitPartBackups(backups []configService.BackupRecord) []configService.BackupRecord {
+	if runSplitPart == "" {
+		return backups
+	}
+	var own []configService.BackupRecord
+	for _, backup := range backups {
+		if backup.Part == splitRecordPart() {
+			own = append(own, backup)
+		}
+	}
+	return own
+}
+
+// keepOtherParts protects the backups of other parts from the rotation of this part, since
+// part names can share a prefix, e.g. "client" and "client-old"
+func keepOtherParts(opts backupService.RotationOptions, backups []configService.BackupRecord) backupService.RotationOptions {
+	if runSplitPart == "" {
+		return opts
+	}
+	for _, backup := range backups {
+		if backup.Part == splitRecordPart() {
+			continue
+		}
+		if opts.Keep == nil {
+			opts.Keep = make(map[string]bool)
+		}
+		opts.Keep[backup.Filename] = true
+	}
+	return opts
+}
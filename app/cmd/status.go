@@ -7,6 +7,7 @@ import (
 	"time"
 
 	configService "github.com/kennycyb/go-backup/internal/service/config"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
 	"github.com/spf13/cobra"
 )
 
@@ -24,18 +25,72 @@ const (
 	ColorDim    = "\033[2m"
 )
 
+var (
+	statusCheck  bool
+	statusMaxAge time.Duration
+	statusAll    bool
+	statusWide   bool
+	statusStrict bool
+)
+
 // statusCmd represents the status command
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show backup status",
 	Long: `Show the status of backups, including the last backup time
-and the latest backup files for each target.`,
+and the latest backup files for each target.
+
+With --check nothing is printed while all is well, so the command can run
+from cron or a monitoring system. Every target without backups, whose latest
+backup is older than --max-age, or whose latest local backup is missing on
+disk is printed on one line, as is a failed restore rehearsal, and the
+command exits with status 1.
+
+Targets with a quota show how much of it their backups use, with a warning
+from 90%. With --strict a target over its quota makes the command exit with
+status 1, and --check reports it as a problem.
+Add --all to check every enabled location in the global registry at once:
+
+  0 8 * * * go-backup status --check --all --max-age 26h`,
 	Run: func(cmd *cobra.Command, args []string) {
 		configFile := ".backup.yaml"
 		if cfgFile != "" {
 			configFile = cfgFile
 		}
 
+		if statusCheck {
+			if statusMaxAge <= 0 {
+				fmt.Printf("invalid --max-age %s: use a positive duration like 26h\n", statusMaxAge)
+				os.Exit(1)
+			}
+			configFiles := []string{configFile}
+			if statusAll {
+				registry, err := configService.ReadGlobalRegistry()
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+				configFiles = nil
+				for _, entry := range registry.OrderedBackups() {
+					if entry.IsEnabled() {
+						configFiles = append(configFiles, entry.ConfigPath())
+					}
+				}
+			}
+
+			failed := false
+			for _, path := range configFiles {
+				for _, problem := range checkBackupStatus(path, statusMaxAge, statusStrict, time.Now()) {
+					fmt.Printf("%s: %s\n", path, problem)
+					failed = true
+				}
+			}
+			if failed {
+				os.Exit(1)
+			}
+			return
+		}
+
 		// Check if config file exists
 		if _, err := os.Stat(configFile); os.IsNotExist(err) {
 			fmt.Printf("%s%sError:%s Configuration file '%s' does not exist.\n", ColorRed, ColorBold, ColorReset, configFile)
@@ -56,6 +111,12 @@ and the latest backup files for each target.`,
 			return
 		}
 
+		targets, err := configService.ResolveTargets(config, configService.TargetFlags{})
+		if err != nil {
+			fmt.Printf("%s%sError in configuration file:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			return
+		}
+
 		// Header
 		fmt.Printf("%s%s\n==============================\n   📦  Backup Status Report   \n==============================%s\n", ColorCyan, ColorBold, ColorReset)
 
@@ -68,38 +129,110 @@ and the latest backup files for each target.`,
 			fmt.Printf("\n%s🔓  Encryption: %sDisabled%s\n", ColorYellow, ColorRed, ColorReset)
 		}
 
+		if config.Rehearsal != nil {
+			fmt.Printf("\n%s🧪  Restore rehearsal:%s every %s\n", ColorYellow, ColorReset, config.Rehearsal.Every)
+			if config.LastRehearsal != nil {
+				printOutcome("Last rehearsal", config.LastRehearsal)
+			} else {
+				fmt.Printf("%s  • Last rehearsal:%s never\n", ColorDim, ColorReset)
+			}
+		}
+
 		hasAnyBackups := false
 
-		for _, target := range config.Targets {
-			fmt.Printf("\n%s%s📁 Target:%s %s%s\n", ColorBlue, ColorBold, ColorReset, ColorWhite, target.Path)
-			fmt.Printf("%s  • Maximum backups:%s %d\n", ColorDim, ColorReset, target.MaxBackups)
+		columns := []tableColumn{
+			{title: "TARGET", maxWidth: 32},
+			{title: "LATEST BACKUP", maxWidth: 44},
+			{title: "SIZE", right: true},
+			{title: "AGE", right: true},
+			{title: "BACKUPS", right: true},
+			{title: "VERIFIED"},
+			{title: "LAST RUN"},
+		}
+		// The note given with run -m is shown when a latest backup has one
+		withMessage := false
+		for _, target := range targets {
+			withMessage = withMessage || (len(target.Backups) > 0 && target.Backups[0].Message != "")
+		}
+		// Quota usage is shown when a target has a quota
+		withQuota := false
+		for _, target := range targets {
+			withQuota = withQuota || target.Quota != ""
+		}
+		if withQuota {
+			columns = append(columns, tableColumn{title: "QUOTA", right: true})
+		}
+		if withMessage {
+			columns = append(columns, tableColumn{title: "MESSAGE", maxWidth: 40})
+		}
+		targetTable := newTable(statusWide, columns...)
+		checksums := make(map[string]map[string]string)
+		var notes []string
+		overQuota := false
+		for _, target := range targets {
+			dest := target.GetDestination()
+			count := fmt.Sprintf("%d/%d", len(target.Backups), target.MaxBackups)
+			if target.IsFileTarget() {
+				count = "file"
+			}
+			lastRun := tableCell{text: "never", color: ColorDim}
+			if target.LastRun != nil {
+				lastRun = outcomeCell(target.LastRun.Status)
+				if target.LastRun.Status != configService.StatusSuccess {
+					notes = append(notes, fmt.Sprintf("%s: %s %s ago: %s", dest, target.LastRun.Status, formatTimeSince(time.Since(target.LastRun.Timestamp)), target.LastRun.Message))
+				}
+			}
+
+			// The optional columns follow the fixed ones: the quota, then the message
+			var optional []tableCell
+			if withQuota {
+				quota, level := quotaCell(target.BackupTarget)
+				optional = append(optional, quota)
+				switch level {
+				case configService.QuotaNear:
+					notes = append(notes, fmt.Sprintf("%s: backups use %s of the %s quota", dest, quota.text, target.Quota))
+				case configService.QuotaExceeded:
+					notes = append(notes, fmt.Sprintf("%s: backups use %s of the %s quota, over the limit", dest, quota.text, target.Quota))
+					overQuota = true
+				}
+			}
 
 			if len(target.Backups) == 0 {
-				fmt.Printf("%s%s  ⚠️  Status: No backups found%s\n", ColorYellow, ColorBold, ColorReset)
+				targetTable.addRow(append([]tableCell{{text: dest, color: ColorBlue}, {text: "none", color: ColorYellow}, {}, {}, {text: count}, {}, lastRun}, optional...)...)
 				continue
 			}
-
 			hasAnyBackups = true
 
-			// The first backup in the list is the most recent one
-			latestBackup := target.Backups[0]
-			timeSinceBackup := time.Since(latestBackup.CreatedAt)
-
-			fmt.Printf("%s  • Latest backup:%s %s%s\n", ColorDim, ColorReset, ColorGreen, latestBackup.Filename)
-			fmt.Printf("%s  • Source:%s %s\n", ColorDim, ColorReset, latestBackup.Source)
-			fmt.Printf("%s  • Created:%s %s (%s ago)\n", ColorDim, ColorReset, latestBackup.CreatedAt.Format("2006-01-02 15:04:05"), formatTimeSince(timeSinceBackup))
-			fmt.Printf("%s  • Size:%s %s\n", ColorDim, ColorReset, formatFileSize(latestBackup.Size))
-
-			// Check if the backup file exists
-			backupFilePath := filepath.Join(target.Path, latestBackup.Filename)
-			if _, err := os.Stat(backupFilePath); os.IsNotExist(err) {
-				fmt.Printf("%s%s  ❌  Status: WARNING - Backup file not found on disk!%s\n", ColorRed, ColorBold, ColorReset)
-			} else {
-				fmt.Printf("%s%s  ✅  Status: OK%s\n", ColorGreen, ColorBold, ColorReset)
+			// The first backup in the list is the most recent one; remote targets are not checked to keep status fast and offline
+			latest := target.Backups[0]
+			path := filepath.Join(dest, latest.Filename)
+			if target.IsFileTarget() {
+				path = target.File
+			}
+			verified := verificationCell(dest, Backup{Name: filepath.Base(path), Path: path, SnapshotOf: latest.SnapshotOf}, checksums)
+			if verified.text == "missing" {
+				notes = append(notes, fmt.Sprintf("%s: latest backup %s not found on disk", dest, latest.Filename))
 			}
+			optional = append(optional, tableCell{text: latest.Message})
+			targetTable.addRow(append([]tableCell{
+				{text: dest, color: ColorBlue},
+				{text: latest.Filename, color: ColorGreen},
+				{text: formatFileSize(latest.Size)},
+				{text: formatTimeSince(time.Since(latest.CreatedAt))},
+				{text: count},
+				verified,
+				lastRun,
+			}, optional...)...)
+		}
+
+		fmt.Println()
+		targetTable.print("")
+		for _, note := range notes {
+			fmt.Printf("%s⚠️  %s%s\n", ColorYellow, note, ColorReset)
+		}
 
-			// Show the total number of available backups
-			fmt.Printf("%s  • Total backups:%s %d/%d\n", ColorDim, ColorReset, len(target.Backups), target.MaxBackups)
+		if overQuota && statusStrict {
+			os.Exit(1)
 		}
 
 		if !hasAnyBackups {
@@ -109,6 +242,103 @@ and the latest backup files for each target.`,
 	},
 }
 
+// checkBackupStatus returns the problems of the targets in a config: no backups, a latest
+// backup older than maxAge, or a latest local backup that is missing on disk; and a failed
+// last restore rehearsal. With strict, a target whose backups exceed its quota is a problem too.
+// Remote targets are not checked for the file, to keep the check fast and offline.
+func checkBackupStatus(configPath string, maxAge time.Duration, strict bool, now time.Time) []string {
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return []string{"config file not found"}
+	}
+	config, err := configService.ReadBackupConfig(configPath)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	targets, err := configService.ResolveTargets(config, configService.TargetFlags{})
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	var problems []string
+	if config.LastRehearsal != nil && config.LastRehearsal.Status == configService.StatusFailure {
+		problems = append(problems, fmt.Sprintf("restore rehearsal failed: %s", config.LastRehearsal.Message))
+	}
+	for _, target := range targets {
+		dest := target.GetDestination()
+		if quota, _ := target.QuotaBytes(); strict && configService.CheckQuota(target.Usage(), quota) == configService.QuotaExceeded {
+			problems = append(problems, fmt.Sprintf("%s: backups use %s, over the %s quota", dest, formatFileSize(target.Usage()), target.Quota))
+		}
+		if len(target.Backups) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: no backups", dest))
+			continue
+		}
+
+		// The first backup in the list is the most recent one
+		latest := target.Backups[0]
+		if age := now.Sub(latest.CreatedAt); age > maxAge {
+			problems = append(problems, fmt.Sprintf("%s: latest backup %s is stale (%s old)", dest, latest.Filename, formatTimeSince(age)))
+		}
+
+		if storageService.IsRemote(dest) {
+			continue
+		}
+		backupFilePath := filepath.Join(dest, latest.Filename)
+		if target.IsFileTarget() {
+			backupFilePath = target.File
+		}
+		if _, err := os.Stat(backupFilePath); os.IsNotExist(err) {
+			problems = append(problems, fmt.Sprintf("%s: latest backup %s is missing on disk", dest, latest.Filename))
+		}
+	}
+	return problems
+}
+
+// quotaCell shows the share of the target's quota its backups use, in yellow from
+// QuotaWarnPercent and in red over the quota. Targets without a quota show nothing.
+func quotaCell(target configService.BackupTarget) (tableCell, configService.QuotaLevel) {
+	quota, _ := target.QuotaBytes()
+	if quota == 0 {
+		return tableCell{}, configService.QuotaOK
+	}
+	usage := target.Usage()
+	cell := tableCell{text: fmt.Sprintf("%d%%", usage*100/quota)}
+	level := configService.CheckQuota(usage, quota)
+	switch level {
+	case configService.QuotaNear:
+		cell.color = ColorYellow
+	case configService.QuotaExceeded:
+		cell.color = ColorRed
+	}
+	return cell, level
+}
+
+// printOutcome prints the outcome of the last restore rehearsal,
+// so an intentional skip is not mistaken for a failure or something that never ran
+func printOutcome(label string, outcome *configService.BackupStatus) {
+	when := fmt.Sprintf("%s (%s ago)", outcome.Timestamp.Format("2006-01-02 15:04:05"), formatTimeSince(time.Since(outcome.Timestamp)))
+	switch outcome.Status {
+	case configService.StatusSuccess:
+		fmt.Printf("%s  • %s:%s %s, %ssucceeded%s\n", ColorDim, label, ColorReset, when, ColorGreen, ColorReset)
+	case configService.StatusSkipped:
+		fmt.Printf("%s  • %s:%s %s, %sskipped%s: %s\n", ColorDim, label, ColorReset, when, ColorYellow, ColorReset, outcome.Message)
+	case configService.StatusDeferred:
+		fmt.Printf("%s  • %s:%s %s, %sdeferred%s: %s\n", ColorDim, label, ColorReset, when, ColorYellow, ColorReset, outcome.Message)
+	default:
+		fmt.Printf("%s  • %s:%s %s, %sfailed%s: %s\n", ColorDim, label, ColorReset, when, ColorRed, ColorReset, outcome.Message)
+	}
+}
+
+// outcomeCell shows the status of a run or rehearsal in its color
+func outcomeCell(status string) tableCell {
+	switch status {
+	case configService.StatusSuccess:
+		return tableCell{text: status, color: ColorGreen}
+	case configService.StatusSkipped, configService.StatusDeferred:
+		return tableCell{text: status, color: ColorYellow}
+	}
+	return tableCell{text: status, color: ColorRed}
+}
+
 // formatTimeSince formats a duration into a human-readable string
 func formatTimeSince(duration time.Duration) string {
 	days := int(duration.Hours() / 24)
@@ -145,6 +375,11 @@ func formatFileSize(size int64) string {
 }
 
 func init() {
+	statusCmd.Flags().BoolVar(&statusCheck, "check", false, "Print only problems and exit with status 1 if there are any")
+	statusCmd.Flags().DurationVar(&statusMaxAge, "max-age", 48*time.Hour, "With --check, latest backups older than this are stale")
+	statusCmd.Flags().BoolVar(&statusAll, "all", false, "With --check, check every enabled location in the global registry")
+	statusCmd.Flags().BoolVar(&statusStrict, "strict", false, "Exit with status 1 if the backups of a target exceed its quota")
+	statusCmd.Flags().BoolVarP(&statusWide, "wide", "w", false, "Show targets and backup names in full instead of shortening long ones")
 	// Add status command to root
 	rootCmd.AddCommand(statusCmd)
 }
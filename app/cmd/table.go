@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+)
+
+// tableColumn describes a column of the tables printed by list and status
+type tableColumn struct {
+	title    string
+	maxWidth int  // Longer values are shortened in the middle unless the table is wide; 0 means no limit
+	right    bool // Align right, e.g. sizes and ages
+}
+
+// tableCell is a value in a table, printed in its color
+type tableCell struct {
+	text  string
+	color string
+}
+
+// table prints rows of values aligned under their column titles
+type table struct {
+	columns []tableColumn
+	rows    [][]tableCell
+	wide    bool // Print values in full instead of shortening them to the column's maxWidth
+}
+
+// newTable returns an empty table; with wide set, no value is shortened
+func newTable(wide bool, columns ...tableColumn) *table {
+	return &table{columns: columns, wide: wide}
+}
+
+// addRow appends a row with a cell for each column; cells beyond the last column are dropped
+// and missing ones left empty, so optional columns can be left off the end.
+// Values with control characters, e.g. hostile file names, are quoted so they cannot break the table.
+func (t *table) addRow(cells ...tableCell) {
+	row := make([]tableCell, len(t.columns))
+	copy(row, cells)
+	for i := range row {
+		row[i].text = compressionService.QuotePath(row[i].text)
+	}
+	t.rows = append(t.rows, row)
+}
+
+// print writes the table with every line indented, sizing each column to its widest value
+func (t *table) print(indent string) {
+	widths := make([]int, len(t.columns))
+	for i, column := range t.columns {
+		widths[i] = utf8.RuneCountInString(column.title)
+	}
+	for _, row := range t.rows {
+		for i := range row {
+			row[i].text = t.fit(i, row[i].text)
+			widths[i] = max(widths[i], utf8.RuneCountInString(row[i].text))
+		}
+	}
+
+	titles := make([]tableCell, len(t.columns))
+	for i, column := range t.columns {
+		titles[i] = tableCell{text: column.title, color: ColorDim + ColorBold}
+	}
+	t.printRow(indent, titles, widths)
+	for _, row := range t.rows {
+		t.printRow(indent, row, widths)
+	}
+}
+
+// fit shortens a value to the column's maxWidth, keeping its start and end, e.g. a backup's source and timestamp
+func (t *table) fit(column int, text string) string {
+	limit := t.columns[column].maxWidth
+	if t.wide || limit == 0 || utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	tail := (limit - 1) / 2
+	head := limit - 1 - tail
+	return string(runes[:head]) + "…" + string(runes[len(runes)-tail:])
+}
+
+func (t *table) printRow(indent string, cells []tableCell, widths []int) {
+	var line strings.Builder
+	line.WriteString(indent)
+	for i, cell := range cells {
+		if i > 0 {
+			line.WriteString("  ")
+		}
+		padding := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell.text))
+		text := cell.text
+		if cell.color != "" && text != "" {
+			text = cell.color + text + ColorReset
+		}
+		switch {
+		case t.columns[i].right:
+			line.WriteString(padding + text)
+		case i < len(cells)-1:
+			line.WriteString(text + padding)
+		default:
+			line.WriteString(text)
+		}
+	}
+	fmt.Println(strings.TrimRight(line.String(), " "))
+}
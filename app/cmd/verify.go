@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	verifyService "github.com/kennycyb/go-backup/internal/service/verify"
+	"github.com/spf13/cobra"
+)
+
+var verifyTargets []string
+
+// verifyCmd checks backups for corruption without restoring them
+var verifyCmd = &cobra.Command{
+	Use:   "verify [file...]",
+	Short: "Check backups for corruption",
+	Long: `Read backups to their end and check them for corruption: each archive is
+compared with its checksum in the directory's SHA256SUMS and in the .sha256 file
+next to it, and its gzip, tar or zip structure is read through, including the
+contents of every file. The manifest and config copy listed in SHA256SUMS next
+to a backup are checked too.
+
+Without arguments, the backups of the current directory on its local targets
+are checked; --target checks every backup in a directory. Encrypted backups are
+only compared with their checksum, as reading them needs the private key.
+Backups on remote targets must be fetched first.
+
+Exits with status 1 if a backup is damaged, so it can run as a scheduled job:
+
+  go-backup verify --target /mnt/nas/backups || notify-send "Backup damaged"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		files := args
+		switch {
+		case len(verifyTargets) > 0:
+			for _, dir := range verifyTargets {
+				found, err := verifyService.InDirectory(dir, "")
+				if err != nil {
+					fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+					os.Exit(1)
+				}
+				files = append(files, found...)
+			}
+		case len(files) == 0:
+			files = configuredBackups()
+		}
+		if len(files) == 0 {
+			fmt.Printf("%sNo backups to verify%s\n", ColorYellow, ColorReset)
+			return
+		}
+
+		damaged := 0
+		for _, path := range files {
+			fmt.Printf("%s→ Verifying%s %s\n", ColorBlue, ColorReset, path)
+			result := verifyService.Backup(path)
+			printVerification(result)
+			if !result.Intact() {
+				damaged++
+			}
+		}
+		if damaged > 0 {
+			fmt.Printf("\n%s%s❌ %d of %d backup(s) damaged%s\n", ColorRed, ColorBold, damaged, len(files), ColorReset)
+			os.Exit(1)
+		}
+		fmt.Printf("\n%s%s🎉 %d backup(s) intact%s\n", ColorGreen, ColorBold, len(files), ColorReset)
+	},
+}
+
+func init() {
+	verifyCmd.Flags().StringSliceVarP(&verifyTargets, "target", "t", nil, "Check every backup in this directory (repeatable)")
+	verifyCmd.Flags().StringVarP(&source, "source", "s", "", "Source directory whose backups are checked (defaults to current directory)")
+	rootCmd.AddCommand(verifyCmd)
+}
+
+// configuredBackups returns the backups of the source on the local directory targets of its config
+func configuredBackups() []string {
+	verifySource := source
+	if verifySource == "" {
+		var err error
+		if verifySource, err = os.Getwd(); err != nil {
+			fmt.Printf("%s%s❌ Error getting current directory:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+			os.Exit(1)
+		}
+	}
+	configPath := filepath.Join(verifySource, ".backup.yaml")
+	if cfgFile != "" {
+		configPath = cfgFile
+	}
+	config, err := configService.ReadBackupConfig(configPath)
+	if err != nil {
+		fmt.Printf("%s%s❌ Error reading config file %s:%s %v\n", ColorRed, ColorBold, configPath, ColorReset, err)
+		os.Exit(1)
+	}
+	targets, err := configService.ResolveTargets(config, configService.TargetFlags{})
+	if err != nil {
+		fmt.Printf("%s%s❌ Error:%s %v\n", ColorRed, ColorBold, ColorReset, err)
+		os.Exit(1)
+	}
+
+	// Backups are prefixed with the source folder name, as in the run command
+	absSource, _ := filepath.Abs(verifySource)
+	prefixName := filepath.Base(absSource)
+	if prefixName == "." || prefixName == "/" {
+		prefixName = "go-backup"
+	}
+
+	files, skipped := verifyService.Backups(targets, prefixName+"-")
+	for _, target := range skipped {
+		if target.Remote {
+			fmt.Printf("%sSkipping %s: fetch backups from remote targets to verify them%s\n", ColorDim, target.Dest, ColorReset)
+		} else {
+			fmt.Printf("%s⚠️  Skipping %s:%s %v\n", ColorYellow, target.Dest, ColorReset, target.Err)
+		}
+	}
+	return files
+}
+
+// printVerification shows what was checked of a backup and what was found damaged
+func printVerification(result *verifyService.Result) {
+	if result.SumsErr != nil {
+		fmt.Printf("  %s⚠️  Warning: Failed to read %s -%s %v\n", ColorYellow, backupService.ChecksumsFileName, ColorReset, result.SumsErr)
+	}
+	if result.ChecksumFileErr != nil {
+		fmt.Printf("  %s❌ Checksum:%s %v\n", ColorRed, ColorReset, result.ChecksumFileErr)
+	}
+	for _, checksum := range result.Checksums {
+		if checksum.Err != nil {
+			fmt.Printf("  %s❌ Checksum:%s %v (from %s)\n", ColorRed, ColorReset, checksum.Err, checksum.From)
+		} else {
+			fmt.Printf("  %s✅ Checksum:%s matches %s\n", ColorGreen, ColorReset, checksum.From)
+		}
+	}
+	if len(result.Checksums) == 0 {
+		fmt.Printf("  %sNo checksum stored for it in %s or a %s file%s\n", ColorDim, backupService.ChecksumsFileName, backupService.ChecksumSuffix, ColorReset)
+	}
+
+	switch {
+	case result.Encrypted:
+		fmt.Printf("  %sEncrypted: the archive structure is not checked%s\n", ColorDim, ColorReset)
+	case result.ArchiveErr != nil:
+		fmt.Printf("  %s❌ Archive:%s %v\n", ColorRed, ColorReset, result.ArchiveErr)
+	default:
+		fmt.Printf("  %s✅ Archive:%s %d entries, %s read\n", ColorGreen, ColorReset, result.Archive.Entries, compressionService.FormatFileSize(result.Archive.Size))
+	}
+
+	for _, sidecar := range result.Sidecars {
+		if sidecar.Err != nil {
+			fmt.Printf("  %s❌ Sidecar:%s %v\n", ColorRed, ColorReset, sidecar.Err)
+		}
+	}
+}
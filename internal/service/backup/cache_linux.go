@@ -0,0 +1,22 @@
+//go:build linux
+
+package backup
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// dropCachedPages asks the kernel to forget the cached pages of a file, so reading it again comes
+// from the drive rather than memory. Only written-back pages are dropped, hence the Sync first.
+// Best effort: a failure only means the read may be served from the cache.
+func dropCachedPages(path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	file.Sync()
+	unix.Fadvise(int(file.Fd()), 0, 0, unix.FADV_DONTNEED)
+}
@@ -0,0 +1,6 @@
+//go:build !linux
+
+package backup
+
+// dropCachedPages does nothing on this platform; reading a file again may be served from the cache
+func dropCachedPages(path string) {}
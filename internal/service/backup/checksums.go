@@ -0,0 +1,195 @@
+package backup
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ChecksumsFileName is the checksum list kept in each directory target.
+// It uses the sha256sum format, so backups can be verified with `sha256sum -c SHA256SUMS`.
+const ChecksumsFileName = "SHA256SUMS"
+
+// ChecksumSuffix is appended to a backup's file name for the checksum file written next to it,
+// e.g. project-20250520-123045.tar.gz.sha256. Unlike SHA256SUMS it goes wherever the backup is
+// copied, so the backup can be checked with `sha256sum -c <name>.sha256` on any machine.
+const ChecksumSuffix = ".sha256"
+
+// FileSHA256 returns the hex encoded SHA-256 checksum of a file
+func FileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ReadChecksums reads the SHA256SUMS file in the backup directory as a map of file name to checksum.
+// Returns an empty map if the file does not exist.
+func ReadChecksums(backupDir string) (map[string]string, error) {
+	sums := make(map[string]string)
+
+	file, err := os.Open(filepath.Join(backupDir, ChecksumsFileName))
+	if os.IsNotExist(err) {
+		return sums, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Lines are "<checksum>  <name>", or "<checksum> *<name>" in binary mode
+		sum, name, found := strings.Cut(line, " ")
+		if !found {
+			continue
+		}
+		name = strings.TrimPrefix(strings.TrimLeft(name, " "), "*")
+		sums[name] = sum
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", ChecksumsFileName, err)
+	}
+
+	return sums, nil
+}
+
+// writeChecksums writes the checksums sorted by file name, replacing the file atomically
+func writeChecksums(backupDir string, sums map[string]string) error {
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", sums[name], name)
+	}
+
+	path := filepath.Join(backupDir, ChecksumsFileName)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(b.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// AddChecksum records the checksum of a backup file in the backup directory's SHA256SUMS file,
+// replacing any previous entry for the same file name
+func AddChecksum(backupDir, fileName, sum string) error {
+	return AddChecksums(backupDir, map[string]string{fileName: sum})
+}
+
+// AddChecksums records several checksums by file name at once, e.g. of a backup and its manifest
+func AddChecksums(backupDir string, entries map[string]string) error {
+	sums, err := ReadChecksums(backupDir)
+	if err != nil {
+		return err
+	}
+	for fileName, sum := range entries {
+		sums[fileName] = sum
+	}
+	return writeChecksums(backupDir, sums)
+}
+
+// RemoveChecksums drops the named files from the backup directory's SHA256SUMS file, e.g. for a
+// copy of the file whose backups were removed elsewhere. Nothing is written if none of them is listed.
+func RemoveChecksums(backupDir string, names []string) error {
+	sums, err := ReadChecksums(backupDir)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, name := range names {
+		if _, ok := sums[name]; ok {
+			delete(sums, name)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return writeChecksums(backupDir, sums)
+}
+
+// PruneChecksums removes entries for files that no longer exist in the backup directory.
+// Nothing is written if the directory has no SHA256SUMS file.
+func PruneChecksums(backupDir string) error {
+	if _, err := os.Stat(filepath.Join(backupDir, ChecksumsFileName)); os.IsNotExist(err) {
+		return nil
+	}
+
+	sums, err := ReadChecksums(backupDir)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for name := range sums {
+		if _, err := os.Stat(filepath.Join(backupDir, name)); os.IsNotExist(err) {
+			delete(sums, name)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return writeChecksums(backupDir, sums)
+}
+
+// VerifyChecksum checks that the file has the expected SHA-256 checksum.
+// Returns an error if the file cannot be read or the checksum does not match.
+func VerifyChecksum(path, expected string) error {
+	sum, err := FileSHA256(path)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(sum, expected) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", filepath.Base(path), expected, sum)
+	}
+	return nil
+}
+
+// WriteChecksumFile writes the checksum file of a backup next to it, in sha256sum format
+func WriteChecksumFile(backupPath, sum string) error {
+	line := fmt.Sprintf("%s  %s\n", sum, filepath.Base(backupPath))
+	return os.WriteFile(backupPath+ChecksumSuffix, []byte(line), 0644)
+}
+
+// ReadChecksumFile returns the checksum in the checksum file next to a backup
+func ReadChecksumFile(backupPath string) (string, error) {
+	data, err := os.ReadFile(backupPath + ChecksumSuffix)
+	if err != nil {
+		return "", err
+	}
+	sum, _, _ := strings.Cut(strings.TrimSpace(string(data)), " ")
+	if _, err := hex.DecodeString(sum); err != nil || len(sum) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid checksum file %s", filepath.Base(backupPath)+ChecksumSuffix)
+	}
+	return sum, nil
+}
+
+// checksumArchiveName returns the name of the backup a checksum file belongs to
+func checksumArchiveName(name string) (string, bool) {
+	archive, ok := strings.CutSuffix(name, ChecksumSuffix)
+	return archive, ok && archive != ""
+}
@@ -0,0 +1,171 @@
+package backup_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/kennycyb/go-backup/internal/service/backup"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checksums", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "checksums-test")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	Describe("FileSHA256", func() {
+		It("should return the hex encoded checksum", func() {
+			path := filepath.Join(tmpDir, "hello.txt")
+			Expect(os.WriteFile(path, []byte("hello\n"), 0644)).To(Succeed())
+
+			sum, err := FileSHA256(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sum).To(Equal("5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"))
+		})
+	})
+
+	Describe("AddChecksum", func() {
+		It("should write entries in sha256sum format sorted by name", func() {
+			Expect(AddChecksum(tmpDir, "b.tar.gz", "bbbb")).To(Succeed())
+			Expect(AddChecksum(tmpDir, "a.tar.gz", "aaaa")).To(Succeed())
+
+			data, err := os.ReadFile(filepath.Join(tmpDir, ChecksumsFileName))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("aaaa  a.tar.gz\nbbbb  b.tar.gz\n"))
+		})
+
+		It("should replace an existing entry for the same file", func() {
+			Expect(AddChecksum(tmpDir, "a.tar.gz", "old")).To(Succeed())
+			Expect(AddChecksum(tmpDir, "a.tar.gz", "new")).To(Succeed())
+
+			sums, err := ReadChecksums(tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sums).To(Equal(map[string]string{"a.tar.gz": "new"}))
+		})
+
+		It("should record a backup along with its sidecar files", func() {
+			Expect(AddChecksum(tmpDir, "a.tar.gz", "aaaa")).To(Succeed())
+			Expect(AddChecksums(tmpDir, map[string]string{"b.tar.gz": "bbbb", "b.manifest.json": "cccc"})).To(Succeed())
+
+			sums, err := ReadChecksums(tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sums).To(Equal(map[string]string{"a.tar.gz": "aaaa", "b.tar.gz": "bbbb", "b.manifest.json": "cccc"}))
+		})
+	})
+
+	Describe("WriteChecksumFile", func() {
+		It("should write a checksum file sha256sum can check", func() {
+			backupPath := filepath.Join(tmpDir, "a.tar.gz")
+			Expect(os.WriteFile(backupPath, []byte("archive"), 0644)).To(Succeed())
+			sum, err := FileSHA256(backupPath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(WriteChecksumFile(backupPath, sum)).To(Succeed())
+			data, err := os.ReadFile(backupPath + ChecksumSuffix)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal(sum + "  a.tar.gz\n"))
+			Expect(ReadChecksumFile(backupPath)).To(Equal(sum))
+		})
+
+		It("should refuse a checksum file without a checksum", func() {
+			backupPath := filepath.Join(tmpDir, "a.tar.gz")
+			Expect(os.WriteFile(backupPath+ChecksumSuffix, []byte("not a checksum\n"), 0644)).To(Succeed())
+			_, err := ReadChecksumFile(backupPath)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ReadChecksums", func() {
+		It("should return an empty map when there is no checksum file", func() {
+			sums, err := ReadChecksums(tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sums).To(BeEmpty())
+		})
+
+		It("should accept binary mode entries", func() {
+			Expect(os.WriteFile(filepath.Join(tmpDir, ChecksumsFileName), []byte("abcd *backup.tar.gz\n"), 0644)).To(Succeed())
+
+			sums, err := ReadChecksums(tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sums).To(HaveKeyWithValue("backup.tar.gz", "abcd"))
+		})
+	})
+
+	Describe("PruneChecksums", func() {
+		It("should drop entries for missing files", func() {
+			Expect(os.WriteFile(filepath.Join(tmpDir, "kept.tar.gz"), []byte("x"), 0644)).To(Succeed())
+			Expect(AddChecksum(tmpDir, "kept.tar.gz", "1111")).To(Succeed())
+			Expect(AddChecksum(tmpDir, "gone.tar.gz", "2222")).To(Succeed())
+
+			Expect(PruneChecksums(tmpDir)).To(Succeed())
+
+			sums, err := ReadChecksums(tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sums).To(Equal(map[string]string{"kept.tar.gz": "1111"}))
+		})
+
+		It("should not create a checksum file", func() {
+			Expect(PruneChecksums(tmpDir)).To(Succeed())
+			_, err := os.Stat(filepath.Join(tmpDir, ChecksumsFileName))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Describe("RemoveChecksums", func() {
+		It("should drop the named entries whether or not the files exist", func() {
+			Expect(os.WriteFile(filepath.Join(tmpDir, "gone.tar.gz"), []byte("x"), 0644)).To(Succeed())
+			Expect(AddChecksum(tmpDir, "kept.tar.gz", "1111")).To(Succeed())
+			Expect(AddChecksum(tmpDir, "gone.tar.gz", "2222")).To(Succeed())
+
+			Expect(RemoveChecksums(tmpDir, []string{"gone.tar.gz", "unknown.tar.gz"})).To(Succeed())
+
+			sums, err := ReadChecksums(tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sums).To(Equal(map[string]string{"kept.tar.gz": "1111"}))
+		})
+	})
+
+	Describe("RotateBackups", func() {
+		It("should remove rotated backups from the checksum list", func() {
+			now := time.Now()
+			for i, name := range []string{"proj-1.tar.gz", "proj-2.tar.gz", "proj-3.tar.gz"} {
+				path := filepath.Join(tmpDir, name)
+				Expect(os.WriteFile(path, []byte(name), 0644)).To(Succeed())
+				modTime := now.Add(time.Duration(i-3) * time.Hour)
+				Expect(os.Chtimes(path, modTime, modTime)).To(Succeed())
+				Expect(AddChecksum(tmpDir, name, "sum")).To(Succeed())
+			}
+
+			Expect(RotateBackups(tmpDir, "proj-", 2, RotationOptions{})).To(Succeed())
+
+			sums, err := ReadChecksums(tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sums).To(HaveLen(2))
+			Expect(sums).NotTo(HaveKey("proj-1.tar.gz"))
+		})
+	})
+
+	Describe("VerifyChecksum", func() {
+		It("should accept a matching checksum and reject a different one", func() {
+			path := filepath.Join(tmpDir, "hello.txt")
+			Expect(os.WriteFile(path, []byte("hello\n"), 0644)).To(Succeed())
+
+			Expect(VerifyChecksum(path, "5891B5B522D5DF086D0FF0B110FBD9D21BB4FC7163AF34D08286A2E846F6BE03")).To(Succeed())
+
+			err := VerifyChecksum(path, strings.Repeat("0", 64))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("checksum mismatch"))
+		})
+	})
+})
@@ -0,0 +1,18 @@
+//go:build darwin
+
+package backup
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// cloneFile creates dst as a copy-on-write clone of src with cp -c, which uses clonefile on APFS
+func cloneFile(src, dst string) error {
+	output, err := exec.Command("cp", "-c", src, dst).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cp -c failed: %w, details: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
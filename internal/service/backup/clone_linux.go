@@ -0,0 +1,32 @@
+//go:build linux
+
+package backup
+
+import (
+	"os"
+	"syscall"
+)
+
+// ficlone is the FICLONE ioctl, which makes the destination share the blocks of the source
+// on file systems with reflinks such as btrfs and XFS
+const ficlone = 0x40049409
+
+// cloneFile creates dst as a copy-on-write clone of src
+func cloneFile(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dstFile.Fd(), ficlone, srcFile.Fd()); errno != 0 {
+		dstFile.Close()
+		os.Remove(dst)
+		return errno
+	}
+	return dstFile.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package backup
+
+import "errors"
+
+// cloneFile reports that files cannot be cloned on this platform; they are copied instead
+func cloneFile(src, dst string) error {
+	return errors.New("cloning files is not supported on this platform")
+}
@@ -0,0 +1,63 @@
+package backup
+
+import (
+	"github.com/kennycyb/go-backup/internal/service/compress"
+)
+
+// Drift lists how the source differs from a backup's manifest
+type Drift struct {
+	Added    []compress.ArchiveEntry // Only in the source: lost if the disk failed now
+	Modified []compress.ArchiveEntry // Changed since the backup: only the backed up version would survive
+	Removed  []compress.ArchiveEntry // Only in the backup, e.g. files deleted since
+}
+
+// AtRisk reports whether the source holds anything the backup does not
+func (d Drift) AtRisk() bool {
+	return len(d.Added) > 0 || len(d.Modified) > 0
+}
+
+// CompareWithSource compares the current entries of the source, e.g. from compress.ScanSourceContext,
+// with the manifest of its latest backup. Files compare by content when both sides have checksums,
+// and by size and modification time otherwise; directories only by whether they exist.
+func CompareWithSource(manifest *Manifest, entries []compress.ArchiveEntry) Drift {
+	backedUp := make(map[string]compress.ArchiveEntry, len(manifest.Entries))
+	for _, entry := range manifest.Entries {
+		backedUp[entry.Path] = entry
+	}
+
+	var drift Drift
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		seen[entry.Path] = true
+		old, ok := backedUp[entry.Path]
+		switch {
+		case !ok:
+			drift.Added = append(drift.Added, entry)
+		case entryChanged(old, entry):
+			drift.Modified = append(drift.Modified, entry)
+		}
+	}
+	for _, entry := range manifest.Entries {
+		if !seen[entry.Path] {
+			drift.Removed = append(drift.Removed, entry)
+		}
+	}
+	return drift
+}
+
+// entryChanged reports whether the current entry differs from the backed up one
+func entryChanged(old, current compress.ArchiveEntry) bool {
+	if old.IsDir != current.IsDir || old.Mode.Type() != current.Mode.Type() {
+		return true
+	}
+	switch {
+	case current.IsDir:
+		return false
+	case current.Link != "" || old.Link != "":
+		return old.Link != current.Link
+	case old.SHA256 != "" && current.SHA256 != "":
+		return old.SHA256 != current.SHA256
+	default:
+		return old.Size != current.Size || !old.ModTime.Equal(current.ModTime)
+	}
+}
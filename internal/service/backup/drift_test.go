@@ -0,0 +1,73 @@
+package backup_test
+
+import (
+	"os"
+	"time"
+
+	. "github.com/kennycyb/go-backup/internal/service/backup"
+	"github.com/kennycyb/go-backup/internal/service/compress"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Drift", func() {
+	backedUp := time.Date(2025, 5, 20, 12, 0, 0, 0, time.UTC)
+
+	entries := func() []compress.ArchiveEntry {
+		return []compress.ArchiveEntry{
+			{Path: "docs", IsDir: true, Mode: os.ModeDir | 0755, ModTime: backedUp},
+			{Path: "docs/notes.txt", Size: 10, Mode: 0644, ModTime: backedUp, SHA256: "aaaa"},
+			{Path: "link", Mode: os.ModeSymlink | 0777, Link: "docs/notes.txt"},
+			{Path: "main.go", Size: 100, Mode: 0644, ModTime: backedUp},
+		}
+	}
+
+	paths := func(entries []compress.ArchiveEntry) []string {
+		var paths []string
+		for _, entry := range entries {
+			paths = append(paths, entry.Path)
+		}
+		return paths
+	}
+
+	It("should find no drift in an unchanged source", func() {
+		drift := CompareWithSource(NewManifest("backup.tar.gz", "/src", entries()), entries())
+		Expect(drift.AtRisk()).To(BeFalse())
+		Expect(drift.Removed).To(BeEmpty())
+	})
+
+	It("should report files only in the source and files only in the backup", func() {
+		current := append(entries()[1:], compress.ArchiveEntry{Path: "new.txt", Size: 1, Mode: 0644})
+		drift := CompareWithSource(NewManifest("backup.tar.gz", "/src", entries()), current)
+		Expect(paths(drift.Added)).To(Equal([]string{"new.txt"}))
+		Expect(paths(drift.Removed)).To(Equal([]string{"docs"}))
+		Expect(drift.AtRisk()).To(BeTrue())
+	})
+
+	It("should compare files by checksum when both sides have one", func() {
+		current := entries()
+		current[1].ModTime = backedUp.Add(time.Hour)
+		current[0].ModTime = backedUp.Add(time.Hour)
+		drift := CompareWithSource(NewManifest("backup.tar.gz", "/src", entries()), current)
+		Expect(drift.Modified).To(BeEmpty())
+
+		current[1].SHA256 = "bbbb"
+		drift = CompareWithSource(NewManifest("backup.tar.gz", "/src", entries()), current)
+		Expect(paths(drift.Modified)).To(Equal([]string{"docs/notes.txt"}))
+	})
+
+	It("should compare files without checksums by size and modification time", func() {
+		current := entries()
+		current[3].ModTime = backedUp.Add(time.Second)
+		drift := CompareWithSource(NewManifest("backup.tar.gz", "/src", entries()), current)
+		Expect(paths(drift.Modified)).To(Equal([]string{"main.go"}))
+	})
+
+	It("should report changed symlinks and type changes", func() {
+		current := entries()
+		current[2].Link = "main.go"
+		current[3] = compress.ArchiveEntry{Path: "main.go", IsDir: true, Mode: os.ModeDir | 0755}
+		drift := CompareWithSource(NewManifest("backup.tar.gz", "/src", entries()), current)
+		Expect(paths(drift.Modified)).To(Equal([]string{"link", "main.go"}))
+	})
+})
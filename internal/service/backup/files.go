@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 // CopyFile copies a file from src to dst
@@ -31,3 +33,69 @@ func CopyFile(src, dst string) error {
 	// Sync the file to ensure it's written to disk
 	return dstFile.Sync()
 }
+
+// DestinationsInSource returns the destinations that live inside the source directory,
+// as paths relative to the source. These must be excluded from the archive, otherwise
+// every backup would also contain all previous backups.
+func DestinationsInSource(sourceDir string, destinations []string) ([]string, error) {
+	absSource, err := filepath.Abs(sourceDir)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving source path: %w", err)
+	}
+
+	var inside []string
+	for _, dest := range destinations {
+		absDest, err := filepath.Abs(dest)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving destination path %s: %w", dest, err)
+		}
+
+		relPath, err := filepath.Rel(absSource, absDest)
+		if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			continue // Destination is outside the source
+		}
+		if relPath == "." {
+			return nil, fmt.Errorf("destination %s is the source directory itself", dest)
+		}
+
+		inside = append(inside, relPath)
+	}
+
+	return inside, nil
+}
+
+// UniqueBackupName returns a backup base name (without extension) that does not collide
+// with an existing file in any of the given directories. If baseName+ext already exists
+// for one of the extensions, a sequence suffix is appended ("name_2", "name_3", ...).
+func UniqueBackupName(dirs []string, baseName string, extensions []string) string {
+	exists := func(name string) bool {
+		for _, dir := range dirs {
+			for _, ext := range extensions {
+				if _, err := os.Stat(filepath.Join(dir, name+ext)); err == nil {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	name := baseName
+	for seq := 2; exists(name); seq++ {
+		name = fmt.Sprintf("%s_%d", baseName, seq)
+	}
+	return name
+}
+
+// MatchesBackupName reports whether fileName is the backup archive called name.
+// The name may be the full file name or the base name without the archive extensions,
+// e.g. "project-20250520-123045" matches both the .tar.gz and the .tar.gz.gpg archive, as well as
+// archives of the other formats.
+func MatchesBackupName(fileName, name string) bool {
+	if fileName == name {
+		return true
+	}
+	if !IsArchiveName(fileName) {
+		return false
+	}
+	return BackupBaseName(fileName) == name
+}
@@ -73,4 +73,82 @@ var _ = Describe("Files", func() {
 			Expect(err).To(HaveOccurred())
 		})
 	})
+
+	Describe("DestinationsInSource", func() {
+		It("should return destinations inside the source as relative paths", func() {
+			inside, err := backup.DestinationsInSource("/projects/foo", []string{
+				"/projects/foo/.backups",
+				"/mnt/nas/backups",
+				"/projects/foo/archive/single.tar.gz",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(inside).To(Equal([]string{".backups", filepath.Join("archive", "single.tar.gz")}))
+		})
+
+		It("should ignore sibling directories sharing the source prefix", func() {
+			inside, err := backup.DestinationsInSource("/projects/foo", []string{"/projects/foo-backups", "/projects"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(inside).To(BeEmpty())
+		})
+
+		It("should return an error when a destination is the source itself", func() {
+			_, err := backup.DestinationsInSource("/projects/foo", []string{"/projects/foo/"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("UniqueBackupName", func() {
+		var tempDir string
+
+		BeforeEach(func() {
+			var err error
+			tempDir, err = os.MkdirTemp("", "unique-name-test")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(tempDir)
+		})
+
+		extensions := []string{".tar.gz", ".tar.gz.gpg"}
+
+		It("should return the base name when nothing collides", func() {
+			name := backup.UniqueBackupName([]string{tempDir}, "src-20240101-120000", extensions)
+			Expect(name).To(Equal("src-20240101-120000"))
+		})
+
+		It("should append a sequence suffix when a backup with the same name exists", func() {
+			Expect(os.WriteFile(filepath.Join(tempDir, "src-20240101-120000.tar.gz"), []byte("x"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(tempDir, "src-20240101-120000_2.tar.gz.gpg"), []byte("x"), 0644)).To(Succeed())
+
+			name := backup.UniqueBackupName([]string{tempDir}, "src-20240101-120000", extensions)
+			Expect(name).To(Equal("src-20240101-120000_3"))
+		})
+
+		It("should ignore directories that do not exist", func() {
+			name := backup.UniqueBackupName([]string{filepath.Join(tempDir, "missing")}, "src-20240101-120000", extensions)
+			Expect(name).To(Equal("src-20240101-120000"))
+		})
+	})
+
+	Describe("MatchesBackupName", func() {
+		It("should match the full file name", func() {
+			Expect(backup.MatchesBackupName("src-20240101-120000.tar.gz", "src-20240101-120000.tar.gz")).To(BeTrue())
+		})
+
+		It("should match the base name of plain and encrypted archives", func() {
+			Expect(backup.MatchesBackupName("src-20240101-120000.tar.gz", "src-20240101-120000")).To(BeTrue())
+			Expect(backup.MatchesBackupName("src-20240101-120000.tar.gz.gpg", "src-20240101-120000")).To(BeTrue())
+		})
+
+		It("should match the base name of tar and zip archives", func() {
+			Expect(backup.MatchesBackupName("src-20240101-120000.tar", "src-20240101-120000")).To(BeTrue())
+			Expect(backup.MatchesBackupName("src-20240101-120000.zip.gpg", "src-20240101-120000")).To(BeTrue())
+		})
+
+		It("should not match sidecar files or other backups", func() {
+			Expect(backup.MatchesBackupName("src-20240101-120000.backup.yaml", "src-20240101-120000")).To(BeFalse())
+			Expect(backup.MatchesBackupName("src-20240101-120000_2.tar.gz", "src-20240101-120000")).To(BeFalse())
+		})
+	})
 })
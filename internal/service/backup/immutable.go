@@ -0,0 +1,39 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+)
+
+// MakeImmutable protects a finished backup against being deleted or overwritten by accident,
+// e.g. by a stray rm -rf. The file is made read-only and, where the system allows it, immutable:
+// chattr +i on Linux, which needs root or CAP_LINUX_IMMUTABLE, and chflags uchg on macOS and BSD.
+// Returns true if the file was made immutable, false if it is only read-only.
+func MakeImmutable(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if err := os.Chmod(path, info.Mode().Perm()&^0222); err != nil {
+		return false, fmt.Errorf("error making %s read-only: %w", path, err)
+	}
+	// Filesystems and users that cannot set the flag still get the read-only file
+	return setImmutable(path, true) == nil, nil
+}
+
+// MakeMutable lifts the protection set by MakeImmutable, so the file can be removed or replaced
+func MakeMutable(path string) error {
+	flagErr := setImmutable(path, false)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, info.Mode().Perm()|0200); err != nil {
+		if flagErr != nil {
+			return fmt.Errorf("error lifting the immutable flag of %s: %w", path, flagErr)
+		}
+		return fmt.Errorf("error making %s writable: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package backup
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// setImmutable sets or clears the user immutable flag with chflags, which the owner may change
+func setImmutable(path string, immutable bool) error {
+	flag := "nouchg"
+	if immutable {
+		flag = "uchg"
+	}
+	output, err := exec.Command("chflags", flag, path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("chflags %s failed: %w, details: %s", flag, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build linux
+
+package backup
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// setImmutable sets or clears the immutable attribute with chattr
+func setImmutable(path string, immutable bool) error {
+	flag := "-i"
+	if immutable {
+		flag = "+i"
+	}
+	output, err := exec.Command("chattr", flag, path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("chattr %s failed: %w, details: %s", flag, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
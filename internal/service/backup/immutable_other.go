@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package backup
+
+import "errors"
+
+// setImmutable reports that files cannot be made immutable on this platform; they are only made read-only
+func setImmutable(path string, immutable bool) error {
+	return errors.New("immutable files are not supported on this platform")
+}
@@ -0,0 +1,69 @@
+package backup_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/kennycyb/go-backup/internal/service/backup"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Immutable backups", func() {
+	var (
+		tmpDir string
+		path   string
+	)
+
+	BeforeEach(func() {
+		tmpDir = GinkgoT().TempDir()
+		path = filepath.Join(tmpDir, "project-20250101-120000.tar.gz")
+		Expect(os.WriteFile(path, []byte("backup"), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		// The temp dir cleanup cannot remove immutable files
+		MakeMutable(path)
+	})
+
+	It("should make backups read-only and writable again", func() {
+		_, err := MakeImmutable(path)
+		Expect(err).NotTo(HaveOccurred())
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0444)))
+
+		Expect(MakeMutable(path)).To(Succeed())
+		info, err = os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0644)))
+	})
+
+	It("should lift the protection when rotating backups out", func() {
+		newer := filepath.Join(tmpDir, "project-20250102-120000.tar.gz")
+		Expect(os.WriteFile(newer, []byte("backup"), 0644)).To(Succeed())
+		older := time.Now().Add(-time.Hour)
+		Expect(os.Chtimes(path, older, older)).To(Succeed())
+		_, err := MakeImmutable(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(RotateBackups(tmpDir, "project-", 1, RotationOptions{Immutable: true})).To(Succeed())
+		Expect(path).NotTo(BeAnExistingFile())
+		Expect(newer).To(BeAnExistingFile())
+	})
+
+	It("should lift the protection when trashing backups", func() {
+		newer := filepath.Join(tmpDir, "project-20250102-120000.tar.gz")
+		Expect(os.WriteFile(newer, []byte("backup"), 0644)).To(Succeed())
+		older := time.Now().Add(-time.Hour)
+		Expect(os.Chtimes(path, older, older)).To(Succeed())
+		_, err := MakeImmutable(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(RotateBackups(tmpDir, "project-", 1, RotationOptions{Immutable: true, Trash: true})).To(Succeed())
+		trashed, err := os.Stat(filepath.Join(tmpDir, TrashDirName, filepath.Base(path)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(trashed.Mode().Perm() & 0200).NotTo(BeZero())
+	})
+})
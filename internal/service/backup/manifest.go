@@ -0,0 +1,151 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kennycyb/go-backup/internal/service/compress"
+)
+
+// ManifestSuffix is appended to a backup's base name for its manifest file.
+// Encrypted backups get an encrypted manifest with an additional .gpg suffix.
+const ManifestSuffix = ".manifest.json"
+
+// Manifest lists the contents of a backup archive, so a backup can be inspected
+// without extracting it. A metadata snapshot is a manifest without an archive of its own:
+// the file contents are those of Archive, only times and permissions differ.
+type Manifest struct {
+	Archive      string                  `json:"archive"`
+	Source       string                  `json:"source"`
+	Host         string                  `json:"host,omitempty"`  // Machine the backup was made on
+	RunID        string                  `json:"runId,omitempty"` // Run that made the backup, as in its log and notifications
+	CreatedAt    time.Time               `json:"createdAt"`
+	Files        int                     `json:"files"`
+	TotalSize    int64                   `json:"totalSize"`
+	MetadataOnly bool                    `json:"metadataOnly,omitempty"`
+	Tags         []string                `json:"tags,omitempty"`    // Labels given with run --tag
+	Message      string                  `json:"message,omitempty"` // Note given with run --message
+	Entries      []compress.ArchiveEntry `json:"entries"`
+	Warnings     []compress.Warning      `json:"warnings,omitempty"`   // Non-fatal issues, e.g. unreadable files left out of the archive
+	Chunks       []compress.ArchiveChunk `json:"chunks,omitempty"`     // Separately readable parts of the stored archive, for targets with chunks
+	Provenance   *Provenance             `json:"provenance,omitempty"` // How the archive was produced
+}
+
+// NewManifest builds the manifest for an archive from the entries written to it
+func NewManifest(archive, source string, entries []compress.ArchiveEntry) *Manifest {
+	manifest := &Manifest{
+		Archive:   archive,
+		Source:    source,
+		Host:      CurrentHost(),
+		CreatedAt: time.Now(),
+		Entries:   entries,
+	}
+	for _, entry := range entries {
+		if !entry.IsDir {
+			manifest.Files++
+			manifest.TotalSize += entry.Size
+		}
+	}
+	return manifest
+}
+
+// WriteManifest writes the manifest as JSON to the given path
+func WriteManifest(path string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding manifest: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing manifest: %w", err)
+	}
+	return nil
+}
+
+// ReadManifest reads a JSON manifest from the given path
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("error parsing manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// MissingEntries returns the files and symlinks in the manifest that are not among the given paths,
+// e.g. what a salvaged restore of a damaged archive could not recover. Directories are left out,
+// since they are recreated for any file inside them.
+func MissingEntries(manifest *Manifest, paths []string) []compress.ArchiveEntry {
+	found := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		found[path] = true
+	}
+
+	missing := []compress.ArchiveEntry{}
+	for _, entry := range manifest.Entries {
+		if !entry.IsDir && !found[entry.Path] {
+			missing = append(missing, entry)
+		}
+	}
+	return missing
+}
+
+// VerifyRestore compares a restored directory with the manifest and describes every difference:
+// missing entries, entries of another type, symlinks pointing elsewhere, and files whose size or
+// SHA-256 differ from the manifest. Checksums are only compared when the manifest has them.
+// Returns nil if the directory matches the manifest.
+func VerifyRestore(manifest *Manifest, dir string) []string {
+	var problems []string
+	for _, entry := range manifest.Entries {
+		path := filepath.Join(dir, filepath.FromSlash(entry.Path))
+		info, err := os.Lstat(path)
+		if err != nil {
+			problems = append(problems, entry.Path+": missing")
+			continue
+		}
+
+		switch {
+		case entry.IsDir:
+			if !info.IsDir() {
+				problems = append(problems, entry.Path+": not a directory")
+			}
+		case entry.Link != "":
+			if target, err := os.Readlink(path); err != nil || target != entry.Link {
+				problems = append(problems, entry.Path+": symlink does not point to "+entry.Link)
+			}
+		case !info.Mode().IsRegular():
+			problems = append(problems, entry.Path+": not a regular file")
+		case info.Size() != entry.Size:
+			problems = append(problems, fmt.Sprintf("%s: size is %d bytes, expected %d", entry.Path, info.Size(), entry.Size))
+		case entry.SHA256 != "":
+			if sum, err := FileSHA256(path); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", entry.Path, err))
+			} else if sum != entry.SHA256 {
+				problems = append(problems, entry.Path+": checksum does not match")
+			}
+		}
+	}
+	return problems
+}
+
+// BackupBaseName strips the archive extensions from a backup file name,
+// e.g. "project-20250520-123045.tar.gz.gpg" or "project-20250520-123045.zip" becomes "project-20250520-123045".
+// Archives made by other tools lose their extension too, e.g. "photos-2019.tgz" becomes "photos-2019".
+func BackupBaseName(fileName string) string {
+	baseName := strings.TrimSuffix(fileName, ".gpg")
+	if ext := compress.ArchiveExtension(baseName); ext != "" {
+		return strings.TrimSuffix(baseName, ext)
+	}
+	if ext := compress.ForeignExtension(baseName); ext != "" {
+		return strings.TrimSuffix(baseName, ext)
+	}
+	baseName = strings.TrimSuffix(baseName, ".gz")
+	return strings.TrimSuffix(baseName, ".tar")
+}
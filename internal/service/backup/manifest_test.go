@@ -0,0 +1,145 @@
+package backup_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/kennycyb/go-backup/internal/service/backup"
+	"github.com/kennycyb/go-backup/internal/service/compress"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Manifest", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "manifest-test")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	Describe("NewManifest", func() {
+		It("should count files and their total size, but not directories", func() {
+			entries := []compress.ArchiveEntry{
+				{Path: "src", IsDir: true, Size: 4096},
+				{Path: "src/main.go", Size: 100},
+				{Path: "README.md", Size: 20},
+			}
+			manifest := NewManifest("project-20250520-123045.tar.gz", "/src/project", entries)
+			Expect(manifest.Files).To(Equal(2))
+			Expect(manifest.TotalSize).To(Equal(int64(120)))
+			Expect(manifest.Entries).To(HaveLen(3))
+		})
+	})
+
+	Describe("WriteManifest and ReadManifest", func() {
+		It("should roundtrip a manifest", func() {
+			path := filepath.Join(tmpDir, "backup"+ManifestSuffix)
+			manifest := NewManifest("backup.tar.gz", "/src", []compress.ArchiveEntry{{Path: "a.txt", Size: 3, Mode: 0644}})
+			manifest.Tags = []string{"pre-release"}
+			manifest.Message = "before dependency upgrade"
+			Expect(WriteManifest(path, manifest)).To(Succeed())
+
+			read, err := ReadManifest(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(read.Archive).To(Equal("backup.tar.gz"))
+			Expect(read.Source).To(Equal("/src"))
+			Expect(read.Tags).To(Equal([]string{"pre-release"}))
+			Expect(read.Message).To(Equal("before dependency upgrade"))
+			Expect(read.Entries).To(HaveLen(1))
+			Expect(read.Entries[0].Path).To(Equal("a.txt"))
+			Expect(read.Entries[0].Mode).To(Equal(os.FileMode(0644)))
+		})
+
+		It("should keep hostile and non-UTF-8 file names exactly", func() {
+			path := filepath.Join(tmpDir, "names"+ManifestSuffix)
+			names := []string{"line\nbreak.txt", "\x1b[31mred", "latin1-\xe9t\xe9.txt"}
+			var entries []compress.ArchiveEntry
+			for _, name := range names {
+				entries = append(entries, compress.ArchiveEntry{Path: name})
+			}
+			manifest := NewManifest("backup.tar.gz", "/src", entries)
+			manifest.Warnings = []compress.Warning{{Kind: compress.WarningUnreadable, Path: "locked-\xff"}}
+			Expect(WriteManifest(path, manifest)).To(Succeed())
+
+			read, err := ReadManifest(path)
+			Expect(err).NotTo(HaveOccurred())
+			for i, name := range names {
+				Expect(read.Entries[i].Path).To(Equal(name))
+			}
+			Expect(read.Warnings[0].Path).To(Equal("locked-\xff"))
+		})
+
+		It("should return an error for an invalid manifest", func() {
+			path := filepath.Join(tmpDir, "broken"+ManifestSuffix)
+			Expect(os.WriteFile(path, []byte("not json"), 0644)).To(Succeed())
+
+			_, err := ReadManifest(path)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("MissingEntries", func() {
+		It("should return the files that were not restored", func() {
+			manifest := NewManifest("backup.tar.gz", "/src", []compress.ArchiveEntry{
+				{Path: "docs", IsDir: true},
+				{Path: "docs/a.txt", Size: 1},
+				{Path: "docs/b.txt", Size: 2},
+				{Path: "link", Link: "docs/a.txt"},
+			})
+
+			missing := MissingEntries(manifest, []string{"docs/a.txt"})
+			Expect(missing).To(HaveLen(2))
+			Expect(missing[0].Path).To(Equal("docs/b.txt"))
+			Expect(missing[1].Path).To(Equal("link"))
+			Expect(MissingEntries(manifest, []string{"docs/a.txt", "docs/b.txt", "link"})).To(BeEmpty())
+		})
+	})
+
+	Describe("VerifyRestore", func() {
+		It("should accept a directory matching the manifest and report every difference", func() {
+			dir := GinkgoT().TempDir()
+			Expect(os.MkdirAll(filepath.Join(dir, "docs"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "docs/a.txt"), []byte("a"), 0644)).To(Succeed())
+			Expect(os.Symlink("docs/a.txt", filepath.Join(dir, "link"))).To(Succeed())
+			sum, err := FileSHA256(filepath.Join(dir, "docs/a.txt"))
+			Expect(err).NotTo(HaveOccurred())
+
+			manifest := NewManifest("backup.tar.gz", "/src", []compress.ArchiveEntry{
+				{Path: "docs", IsDir: true},
+				{Path: "docs/a.txt", Size: 1, SHA256: sum},
+				{Path: "link", Link: "docs/a.txt"},
+			})
+			Expect(VerifyRestore(manifest, dir)).To(BeEmpty())
+
+			manifest.Entries = append(manifest.Entries,
+				compress.ArchiveEntry{Path: "docs/b.txt", Size: 2},
+				compress.ArchiveEntry{Path: "docs/a.txt", Size: 2},
+				compress.ArchiveEntry{Path: "docs/a.txt", Size: 1, SHA256: "0000"},
+				compress.ArchiveEntry{Path: "link", Link: "elsewhere"},
+			)
+			Expect(VerifyRestore(manifest, dir)).To(Equal([]string{
+				"docs/b.txt: missing",
+				"docs/a.txt: size is 1 bytes, expected 2",
+				"docs/a.txt: checksum does not match",
+				"link: symlink does not point to elsewhere",
+			}))
+		})
+	})
+
+	Describe("BackupBaseName", func() {
+		It("should strip archive extensions", func() {
+			Expect(BackupBaseName("project-20250520-123045.tar.gz.gpg")).To(Equal("project-20250520-123045"))
+			Expect(BackupBaseName("project-20250520-123045.tar.gz")).To(Equal("project-20250520-123045"))
+			Expect(BackupBaseName("latest.tar.gz")).To(Equal("latest"))
+			Expect(BackupBaseName("project-20250520-123045.tar")).To(Equal("project-20250520-123045"))
+			Expect(BackupBaseName("project-20250520-123045.zip.gpg")).To(Equal("project-20250520-123045"))
+			Expect(BackupBaseName("photos-2019.tgz")).To(Equal("photos-2019"))
+		})
+	})
+})
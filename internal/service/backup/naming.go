@@ -0,0 +1,104 @@
+package backup
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultTimestampFormat is the Go time layout of the timestamp in backup file names,
+// e.g. project-20250520-123045.tar.gz
+const DefaultTimestampFormat = "20060102-150405"
+
+// sequenceSuffix matches the "_N" suffix UniqueBackupName appends to a taken name
+var sequenceSuffix = regexp.MustCompile(`_\d+$`)
+
+// Naming describes the timestamps in backup file names and records
+type Naming struct {
+	Format   string         // Go time layout of the timestamp in file names
+	Location *time.Location // Time zone of the timestamps
+}
+
+// DefaultNaming returns the naming of backups without timestamp options: DefaultTimestampFormat in local time
+func DefaultNaming() Naming {
+	return Naming{Format: DefaultTimestampFormat, Location: time.Local}
+}
+
+// NewNaming returns the naming for a timestamp layout and time zone; empty values use the defaults.
+// The zone is "UTC", "Local" or an IANA name like "Europe/Berlin". Returns an error for an unknown
+// zone or a layout whose timestamps cannot be part of a file name or cannot be read back from it.
+func NewNaming(format, timezone string) (Naming, error) {
+	naming := DefaultNaming()
+	if timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return Naming{}, fmt.Errorf("invalid timezone %q: use UTC, Local or a name like Europe/Berlin", timezone)
+		}
+		naming.Location = location
+	}
+	if format != "" {
+		naming.Format = format
+	}
+
+	sample := naming.Timestamp(time.Date(2025, 5, 20, 12, 30, 45, 0, time.UTC))
+	if sample == naming.Format {
+		return Naming{}, fmt.Errorf("invalid timestampFormat %q: use a Go time layout like %s", format, DefaultTimestampFormat)
+	}
+	if strings.ContainsAny(sample, `/\:`) {
+		return Naming{}, fmt.Errorf("invalid timestampFormat %q: timestamps may not contain '/', '\\' or ':'", format)
+	}
+	if _, err := time.ParseInLocation(naming.Format, sample, naming.Location); err != nil {
+		return Naming{}, fmt.Errorf("invalid timestampFormat %q: timestamps cannot be read back: %w", format, err)
+	}
+	return naming, nil
+}
+
+// Now returns the current time in the naming's time zone
+func (n Naming) Now() time.Time {
+	return time.Now().In(n.location())
+}
+
+// Timestamp formats a time for a backup file name
+func (n Naming) Timestamp(t time.Time) string {
+	return t.In(n.location()).Format(n.format())
+}
+
+// ParseName splits a backup file name of the form <source>-<timestamp>.tar.gz, optionally
+// encrypted or with a "_N" sequence suffix, into the source name and the creation time.
+// The source may contain dashes itself; the first split that leaves a valid timestamp wins.
+// Returns false if the name has no timestamp in the naming's layout.
+func (n Naming) ParseName(fileName string) (string, time.Time, bool) {
+	base := BackupBaseName(fileName)
+	candidates := []string{base}
+	if stripped := sequenceSuffix.ReplaceAllString(base, ""); stripped != base {
+		candidates = append(candidates, stripped)
+	}
+
+	for _, name := range candidates {
+		for i := 0; i < len(name); i++ {
+			if name[i] != '-' || i == 0 {
+				continue
+			}
+			created, err := time.ParseInLocation(n.format(), name[i+1:], n.location())
+			if err == nil {
+				return name[:i], created, true
+			}
+		}
+	}
+	return "", time.Time{}, false
+}
+
+func (n Naming) format() string {
+	if n.Format == "" {
+		return DefaultTimestampFormat
+	}
+	return n.Format
+}
+
+func (n Naming) location() *time.Location {
+	if n.Location == nil {
+		return time.Local
+	}
+	return n.Location
+}
@@ -0,0 +1,91 @@
+package backup_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kennycyb/go-backup/internal/service/backup"
+)
+
+var _ = Describe("Naming", func() {
+	created := time.Date(2025, 5, 20, 12, 30, 45, 0, time.UTC)
+
+	Describe("NewNaming", func() {
+		It("should default to the standard layout in local time", func() {
+			naming, err := backup.NewNaming("", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(naming.Format).To(Equal(backup.DefaultTimestampFormat))
+			Expect(naming.Location).To(Equal(time.Local))
+		})
+
+		It("should format timestamps in the configured time zone", func() {
+			naming, err := backup.NewNaming("2006-01-02T150405Z0700", "UTC")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(naming.Timestamp(created.In(time.FixedZone("CEST", 2*60*60)))).To(Equal("2025-05-20T123045Z"))
+		})
+
+		It("should reject unknown time zones", func() {
+			_, err := backup.NewNaming("", "Mars/Olympus")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject layouts that do not work in file names", func() {
+			_, err := backup.NewNaming("backup", "")
+			Expect(err).To(HaveOccurred())
+			_, err = backup.NewNaming("2006/01/02", "")
+			Expect(err).To(HaveOccurred())
+			_, err = backup.NewNaming("15:04:05", "")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ParseName", func() {
+		naming := backup.Naming{Format: backup.DefaultTimestampFormat, Location: time.UTC}
+
+		It("should split the source and the timestamp", func() {
+			source, createdAt, ok := naming.ParseName("my-project-20250520-123045.tar.gz.gpg")
+			Expect(ok).To(BeTrue())
+			Expect(source).To(Equal("my-project"))
+			Expect(createdAt).To(Equal(created))
+		})
+
+		It("should ignore the sequence suffix", func() {
+			source, createdAt, ok := naming.ParseName("project-20250520-123045_2.tar.gz")
+			Expect(ok).To(BeTrue())
+			Expect(source).To(Equal("project"))
+			Expect(createdAt).To(Equal(created))
+		})
+
+		It("should read the time in the naming's time zone", func() {
+			berlin, err := time.LoadLocation("Europe/Berlin")
+			Expect(err).NotTo(HaveOccurred())
+			_, createdAt, ok := backup.Naming{Format: backup.DefaultTimestampFormat, Location: berlin}.ParseName("project-20250520-143045.tar.gz")
+			Expect(ok).To(BeTrue())
+			Expect(createdAt.Equal(created)).To(BeTrue())
+		})
+
+		It("should parse custom layouts", func() {
+			custom := backup.Naming{Format: "2006-01-02_150405", Location: time.UTC}
+			source, createdAt, ok := custom.ParseName("project-2025-05-20_123045_3.tar.gz")
+			Expect(ok).To(BeTrue())
+			Expect(source).To(Equal("project"))
+			Expect(createdAt).To(Equal(created))
+		})
+
+		It("should round-trip timestamps it formats", func() {
+			source, createdAt, ok := naming.ParseName("project-" + naming.Timestamp(created) + ".tar.gz")
+			Expect(ok).To(BeTrue())
+			Expect(source).To(Equal("project"))
+			Expect(createdAt).To(Equal(created))
+		})
+
+		It("should reject names without a timestamp", func() {
+			_, _, ok := naming.ParseName("project.tar.gz")
+			Expect(ok).To(BeFalse())
+			_, _, ok = naming.ParseName("project-latest.tar.gz")
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,128 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kennycyb/go-backup/internal/service/compress"
+)
+
+// Kinds of unexpected files found by FindOrphans
+const (
+	OrphanUnknown   = "unknown"   // Matches no backup naming scheme
+	OrphanSidecar   = "sidecar"   // Config, manifest, checksum or parity file whose backup archive no longer exists
+	OrphanUntracked = "untracked" // Backup archive that is not in the backup history
+)
+
+// sidecarSuffixes are appended to a backup's base name for the files kept next to it.
+// Longer suffixes come first, so "x.tar.gz.backup.yaml" belongs to "x" rather than "x.tar.gz".
+var sidecarSuffixes = []string{
+	".tar.gz.backup.yaml",
+	".gpg.backup.yaml",
+	".backup.yaml.gpg",
+	".backup.yaml",
+	ManifestSuffix + ".gpg",
+	ManifestSuffix,
+}
+
+// OrphanFile is an unexpected file in a backup directory
+type OrphanFile struct {
+	Name string // File name within the backup directory
+	Kind string // OrphanUnknown, OrphanSidecar or OrphanUntracked
+}
+
+// FindOrphans looks for files in the backup directory that do not belong to a backup: files matching
+// no backup naming scheme, configs and manifests left behind without their archive, and archives
+// with the given prefix that are missing from the history. History lists the tracked file names.
+// Metadata snapshots are only orphaned once the archive they refer to is gone.
+func FindOrphans(backupDir, prefix string, history map[string]bool) ([]OrphanFile, error) {
+	files, err := os.ReadDir(backupDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading backup directory: %w", err)
+	}
+
+	archives := make(map[string]bool)
+	for _, file := range files {
+		if !file.IsDir() && IsArchiveName(file.Name()) {
+			archives[BackupBaseName(file.Name())] = true
+		}
+	}
+
+	orphans := []OrphanFile{}
+	for _, file := range files {
+		name := file.Name()
+		switch {
+		case file.IsDir():
+			if name != TrashDirName {
+				orphans = append(orphans, OrphanFile{Name: name, Kind: OrphanUnknown})
+			}
+		case name == ChecksumsFileName:
+		case IsArchiveName(name):
+			if strings.HasPrefix(name, prefix) && !history[name] {
+				orphans = append(orphans, OrphanFile{Name: name, Kind: OrphanUntracked})
+			}
+		default:
+			base, ok := sidecarBaseName(name)
+			if !ok {
+				orphans = append(orphans, OrphanFile{Name: name, Kind: OrphanUnknown})
+			} else if !archives[base] && !isLiveSnapshot(backupDir, name) {
+				orphans = append(orphans, OrphanFile{Name: name, Kind: OrphanSidecar})
+			}
+		}
+	}
+
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].Name < orphans[j].Name })
+	return orphans, nil
+}
+
+// RemoveOrphanedSidecars removes the orphaned configs, manifests and parity files, or moves them into the trash
+// when opts.Trash is set. Unknown files and untracked archives are never removed.
+func RemoveOrphanedSidecars(backupDir string, orphans []OrphanFile, opts RotationOptions) error {
+	for _, orphan := range orphans {
+		if orphan.Kind != OrphanSidecar {
+			continue
+		}
+		path := filepath.Join(backupDir, orphan.Name)
+		if err := removeBackupFile(path, opts); err != nil {
+			return fmt.Errorf("error removing %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// IsArchiveName reports whether the file name is a backup archive of any format, encrypted or not
+func IsArchiveName(name string) bool {
+	return compress.ArchiveExtension(name) != ""
+}
+
+// sidecarBaseName returns the base name of the backup a sidecar file belongs to
+func sidecarBaseName(name string) (string, bool) {
+	if archive, ok := parityArchiveName(name); ok && IsArchiveName(archive) {
+		return BackupBaseName(archive), true
+	}
+	if archive, ok := checksumArchiveName(name); ok && IsArchiveName(archive) {
+		return BackupBaseName(archive), true
+	}
+	for _, suffix := range sidecarSuffixes {
+		if base := strings.TrimSuffix(name, suffix); base != name && base != "" {
+			return base, true
+		}
+	}
+	return "", false
+}
+
+// isLiveSnapshot reports whether the file is a metadata snapshot whose archive still exists
+func isLiveSnapshot(backupDir, name string) bool {
+	if !strings.HasSuffix(name, ManifestSuffix) {
+		return false
+	}
+	manifest, err := ReadManifest(filepath.Join(backupDir, name))
+	if err != nil || !manifest.MetadataOnly {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(backupDir, manifest.Archive))
+	return err == nil
+}
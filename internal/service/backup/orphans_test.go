@@ -0,0 +1,95 @@
+package backup_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/kennycyb/go-backup/internal/service/backup"
+	"github.com/kennycyb/go-backup/internal/service/compress"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FindOrphans", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "orphans-test")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	writeFiles := func(names ...string) {
+		for _, name := range names {
+			Expect(os.WriteFile(filepath.Join(tmpDir, name), []byte("test"), 0644)).To(Succeed())
+		}
+	}
+
+	It("should accept backups with their sidecars", func() {
+		writeFiles("proj-20240101-120000.tar.gz", "proj-20240101-120000.backup.yaml", "proj-20240101-120000.manifest.json",
+			"proj-20240102-120000.tar.gz.gpg", "proj-20240102-120000.backup.yaml.gpg", "proj-20240102-120000.manifest.json.gpg",
+			ChecksumsFileName)
+		Expect(os.Mkdir(filepath.Join(tmpDir, TrashDirName), 0755)).To(Succeed())
+
+		orphans, err := FindOrphans(tmpDir, "proj-", map[string]bool{"proj-20240101-120000.tar.gz": true, "proj-20240102-120000.tar.gz.gpg": true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphans).To(BeEmpty())
+	})
+
+	It("should flag unknown files, orphaned sidecars and untracked archives", func() {
+		writeFiles("notes.txt", "proj-20240101-120000.backup.yaml", "proj-20240101-120000.manifest.json.gpg",
+			"proj-20240102-120000.tar.gz", "other-20240102-120000.tar.gz")
+
+		orphans, err := FindOrphans(tmpDir, "proj-", map[string]bool{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphans).To(Equal([]OrphanFile{
+			{Name: "notes.txt", Kind: OrphanUnknown},
+			{Name: "proj-20240101-120000.backup.yaml", Kind: OrphanSidecar},
+			{Name: "proj-20240101-120000.manifest.json.gpg", Kind: OrphanSidecar},
+			{Name: "proj-20240102-120000.tar.gz", Kind: OrphanUntracked},
+		}))
+	})
+
+	It("should treat parity and checksum files as sidecars", func() {
+		writeFiles("proj-20240101-120000.tar.gz", "proj-20240101-120000.tar.gz.par2", "proj-20240101-120000.tar.gz.vol000+100.par2",
+			"proj-20240101-120000.tar.gz.sha256", "proj-20240102-120000.tar.gz.gpg.par2", "proj-20240102-120000.tar.gz.gpg.sha256")
+
+		orphans, err := FindOrphans(tmpDir, "proj-", map[string]bool{"proj-20240101-120000.tar.gz": true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphans).To(Equal([]OrphanFile{
+			{Name: "proj-20240102-120000.tar.gz.gpg.par2", Kind: OrphanSidecar},
+			{Name: "proj-20240102-120000.tar.gz.gpg.sha256", Kind: OrphanSidecar},
+		}))
+	})
+
+	It("should keep metadata snapshots while their archive exists", func() {
+		writeFiles("proj-20240101-120000.tar.gz")
+		snapshot := NewManifest("proj-20240101-120000.tar.gz", "/src", []compress.ArchiveEntry{})
+		snapshot.MetadataOnly = true
+		Expect(WriteManifest(filepath.Join(tmpDir, "proj-20240102-120000"+ManifestSuffix), snapshot)).To(Succeed())
+
+		orphans, err := FindOrphans(tmpDir, "proj-", map[string]bool{"proj-20240101-120000.tar.gz": true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphans).To(BeEmpty())
+
+		Expect(os.Remove(filepath.Join(tmpDir, "proj-20240101-120000.tar.gz"))).To(Succeed())
+		orphans, err = FindOrphans(tmpDir, "proj-", map[string]bool{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphans).To(Equal([]OrphanFile{{Name: "proj-20240102-120000" + ManifestSuffix, Kind: OrphanSidecar}}))
+	})
+
+	It("should only remove orphaned sidecars", func() {
+		writeFiles("notes.txt", "proj-20240101-120000.backup.yaml", "proj-20240102-120000.tar.gz")
+		orphans, err := FindOrphans(tmpDir, "proj-", map[string]bool{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(RemoveOrphanedSidecars(tmpDir, orphans, RotationOptions{})).To(Succeed())
+		Expect(filepath.Join(tmpDir, "proj-20240101-120000.backup.yaml")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(tmpDir, "notes.txt")).To(BeARegularFile())
+		Expect(filepath.Join(tmpDir, "proj-20240102-120000.tar.gz")).To(BeARegularFile())
+	})
+})
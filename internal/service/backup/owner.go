@@ -0,0 +1,89 @@
+package backup
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Owner is the machine and source directory a backup was made from
+type Owner struct {
+	Host   string // Empty for backups made before hosts were recorded
+	Source string
+}
+
+// CurrentHost returns the host name recorded with new backups, or "" if it is unknown
+func CurrentHost() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+// ReadOwner returns who made a backup in a local directory, from its manifest or else from its
+// sidecar config. ok is false when neither can be read, e.g. for encrypted backups.
+func ReadOwner(backupDir, fileName string) (Owner, bool) {
+	baseName := BackupBaseName(fileName)
+
+	if data, err := os.ReadFile(filepath.Join(backupDir, baseName+ManifestSuffix)); err == nil {
+		var manifest struct {
+			Host   string `json:"host"`
+			Source string `json:"source"`
+		}
+		if json.Unmarshal(data, &manifest) == nil && manifest.Source != "" {
+			return Owner{Host: manifest.Host, Source: manifest.Source}, true
+		}
+	}
+
+	// The sidecar config holds the backup history, including this backup's record
+	data, err := os.ReadFile(filepath.Join(backupDir, baseName+".backup.yaml"))
+	if err != nil {
+		return Owner{}, false
+	}
+	var sidecar struct {
+		Targets []struct {
+			Backups []struct {
+				Filename string `yaml:"filename"`
+				Source   string `yaml:"source"`
+				Host     string `yaml:"host"`
+			} `yaml:"backups"`
+		} `yaml:"target"`
+	}
+	if yaml.Unmarshal(data, &sidecar) != nil {
+		return Owner{}, false
+	}
+	for _, target := range sidecar.Targets {
+		for _, record := range target.Backups {
+			if record.Filename == fileName && record.Source != "" {
+				return Owner{Host: record.Host, Source: record.Source}, true
+			}
+		}
+	}
+	return Owner{}, false
+}
+
+// BelongsTo reports whether the backup could have been made from the source on the host.
+// Hosts are only compared when both are known, and sources only when both are absolute paths.
+func (o Owner) BelongsTo(host, source string) bool {
+	if o.Host != "" && host != "" && o.Host != host {
+		return false
+	}
+	if filepath.IsAbs(o.Source) && filepath.IsAbs(source) && filepath.Clean(o.Source) != filepath.Clean(source) {
+		return false
+	}
+	return true
+}
+
+// OwnedBy reports whether the backup in a local directory belongs to the source on the host,
+// by its recorded owner. Backups without a readable owner are assumed to belong to it, as
+// their name already matched.
+func OwnedBy(backupDir, fileName, host, source string) bool {
+	if host == "" && source == "" {
+		return true
+	}
+	owner, ok := ReadOwner(backupDir, fileName)
+	return !ok || owner.BelongsTo(host, source)
+}
@@ -0,0 +1,89 @@
+package backup_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/kennycyb/go-backup/internal/service/backup"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Owner", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		tmpDir = GinkgoT().TempDir()
+	})
+
+	writeBackup := func(name string, age time.Duration) {
+		path := filepath.Join(tmpDir, name)
+		Expect(os.WriteFile(path, []byte("archive"), 0644)).To(Succeed())
+		modTime := time.Now().Add(-age)
+		Expect(os.Chtimes(path, modTime, modTime)).To(Succeed())
+	}
+
+	writeManifest := func(baseName, host, source string) {
+		manifest := NewManifest(baseName+".tar.gz", source, nil)
+		manifest.Host = host
+		Expect(WriteManifest(filepath.Join(tmpDir, baseName+ManifestSuffix), manifest)).To(Succeed())
+	}
+
+	Describe("ReadOwner", func() {
+		It("should read the host and source from the manifest", func() {
+			writeManifest("project-20250520-123045", "desktop", "/home/kenny/project")
+			owner, ok := ReadOwner(tmpDir, "project-20250520-123045.tar.gz")
+			Expect(ok).To(BeTrue())
+			Expect(owner).To(Equal(Owner{Host: "desktop", Source: "/home/kenny/project"}))
+		})
+
+		It("should fall back to the backup's record in the sidecar config", func() {
+			sidecar := "target:\n  - path: /mnt/nas\n    backups:\n" +
+				"      - filename: project-20250519-123045.tar.gz\n        source: /home/kenny/old\n" +
+				"      - filename: project-20250520-123045.tar.gz\n        source: /home/kenny/project\n        host: laptop\n"
+			Expect(os.WriteFile(filepath.Join(tmpDir, "project-20250520-123045.backup.yaml"), []byte(sidecar), 0644)).To(Succeed())
+			owner, ok := ReadOwner(tmpDir, "project-20250520-123045.tar.gz")
+			Expect(ok).To(BeTrue())
+			Expect(owner).To(Equal(Owner{Host: "laptop", Source: "/home/kenny/project"}))
+		})
+
+		It("should report an unknown owner without a manifest or sidecar config", func() {
+			_, ok := ReadOwner(tmpDir, "project-20250520-123045.tar.gz.gpg")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	DescribeTable("BelongsTo",
+		func(owner Owner, expected bool) {
+			Expect(owner.BelongsTo("laptop", "/home/kenny/project")).To(Equal(expected))
+		},
+		Entry("Same host and source", Owner{Host: "laptop", Source: "/home/kenny/project"}, true),
+		Entry("Another host", Owner{Host: "desktop", Source: "/home/kenny/project"}, false),
+		Entry("Another source", Owner{Host: "laptop", Source: "/srv/project"}, false),
+		Entry("Unknown host", Owner{Source: "/home/kenny/project/"}, true),
+		Entry("Relative source", Owner{Host: "laptop", Source: "project"}, true),
+	)
+
+	Describe("RotateBackups", func() {
+		It("should leave the backups of other machines alone", func() {
+			for i, host := range []string{"desktop", "laptop", "desktop", "laptop", "laptop"} {
+				baseName := "project-2025052" + string(rune('0'+i)) + "-123045"
+				writeBackup(baseName+".tar.gz", time.Duration(5-i)*time.Hour)
+				writeManifest(baseName, host, "/home/kenny/project")
+			}
+			writeBackup("project-20250525-123045.tar.gz", time.Minute)
+
+			Expect(RotateBackups(tmpDir, "project-", 2, RotationOptions{Host: "laptop", Source: "/home/kenny/project"})).To(Succeed())
+
+			remaining, err := filepath.Glob(filepath.Join(tmpDir, "*.tar.gz"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remaining).To(ConsistOf(
+				filepath.Join(tmpDir, "project-20250520-123045.tar.gz"),
+				filepath.Join(tmpDir, "project-20250522-123045.tar.gz"),
+				filepath.Join(tmpDir, "project-20250524-123045.tar.gz"),
+				filepath.Join(tmpDir, "project-20250525-123045.tar.gz"),
+			))
+		})
+	})
+})
@@ -0,0 +1,160 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ParitySuffix is appended to an archive's file name for the PAR2 index file next to it.
+// The recovery data is in "<archive>.vol<first>+<count>.par2" files.
+const ParitySuffix = ".par2"
+
+// ParityStatus is the outcome of checking an archive against its parity files
+type ParityStatus int
+
+const (
+	ParityIntact       ParityStatus = iota // The archive matches its parity files
+	ParityRepairable                       // The archive is damaged, but the parity files can repair it
+	ParityUnrepairable                     // The archive is damaged beyond what the parity files can repair
+)
+
+// CreateParity writes PAR2 parity files next to an archive, able to repair damage to up to
+// percent of its size, e.g. bit rot on optical or archive media. Parity files left by an earlier
+// archive of the same name, such as a file target replaced on every run, are removed first.
+// Returns the paths of the written files.
+func CreateParity(archivePath string, percent int) ([]string, error) {
+	if percent < 1 || percent > 100 {
+		return nil, fmt.Errorf("invalid parity redundancy %d%%", percent)
+	}
+	if err := RemoveParity(archivePath); err != nil {
+		return nil, err
+	}
+
+	name := filepath.Base(archivePath)
+	if _, err := runPar2(filepath.Dir(archivePath), "create", "-q", fmt.Sprintf("-r%d", percent), "-n1", name+ParitySuffix, name); err != nil {
+		return nil, err
+	}
+	return ParityFiles(archivePath)
+}
+
+// VerifyParity checks an archive against its parity files.
+// Returns an error if the archive has no parity files or par2 fails to run.
+func VerifyParity(archivePath string) (ParityStatus, error) {
+	if _, err := ParityFiles(archivePath); err != nil {
+		return ParityIntact, err
+	}
+	status, err := runPar2(filepath.Dir(archivePath), "verify", "-q", filepath.Base(archivePath)+ParitySuffix)
+	if err != nil && status == ParityIntact {
+		return status, err
+	}
+	return status, nil
+}
+
+// RepairWithParity repairs a damaged archive in place from its parity files.
+// par2 keeps the damaged original as "<archive>.1"; it is removed once the repair succeeded.
+func RepairWithParity(archivePath string) error {
+	if _, err := ParityFiles(archivePath); err != nil {
+		return err
+	}
+	if _, err := runPar2(filepath.Dir(archivePath), "repair", "-q", filepath.Base(archivePath)+ParitySuffix); err != nil {
+		return err
+	}
+	os.Remove(archivePath + ".1")
+	return nil
+}
+
+// ParityFiles returns the parity files of an archive, index file first.
+// Returns an error if the archive has no parity files.
+func ParityFiles(archivePath string) ([]string, error) {
+	dir := filepath.Dir(archivePath)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading backup directory: %w", err)
+	}
+
+	name := filepath.Base(archivePath)
+	var volumes []string
+	index := ""
+	for _, file := range files {
+		archive, ok := parityArchiveName(file.Name())
+		switch {
+		case file.IsDir() || !ok || archive != name:
+		case file.Name() == name+ParitySuffix:
+			index = filepath.Join(dir, file.Name())
+		default:
+			volumes = append(volumes, filepath.Join(dir, file.Name()))
+		}
+	}
+	if index == "" && len(volumes) == 0 {
+		return nil, fmt.Errorf("no parity files found for %s", archivePath)
+	}
+	sort.Strings(volumes)
+	if index == "" {
+		return volumes, nil
+	}
+	return append([]string{index}, volumes...), nil
+}
+
+// RemoveParity deletes the parity files of an archive, if it has any
+func RemoveParity(archivePath string) error {
+	files, err := ParityFiles(archivePath)
+	if err != nil {
+		return nil
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil {
+			return fmt.Errorf("error removing parity file: %w", err)
+		}
+	}
+	return nil
+}
+
+// parityArchiveName returns the file name of the archive a parity file belongs to:
+// "x.tar.gz.par2" and "x.tar.gz.vol00+10.par2" both belong to "x.tar.gz".
+func parityArchiveName(name string) (string, bool) {
+	base, ok := strings.CutSuffix(name, ParitySuffix)
+	if !ok || base == "" {
+		return "", false
+	}
+	if i := strings.LastIndex(base, ".vol"); i > 0 && strings.Contains(base[i:], "+") {
+		base = base[:i]
+	}
+	return base, true
+}
+
+// runPar2 runs par2 in the archive's directory, since it records file names relative to it.
+// par2 exits with 1 if a damaged archive can be repaired and 2 if it cannot; these are returned
+// as the status along with the error.
+func runPar2(dir string, args ...string) (ParityStatus, error) {
+	cmd := exec.Command("par2", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return ParityIntact, nil
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return ParityIntact, fmt.Errorf("par2 not found: install par2cmdline, e.g. 'apt install par2' or 'brew install par2'")
+	}
+
+	status := ParityIntact
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		switch exitErr.ExitCode() {
+		case 1:
+			status = ParityRepairable
+		case 2:
+			status = ParityUnrepairable
+		}
+	}
+	return status, fmt.Errorf("par2 %s failed: %w, details: %s", args[0], err, strings.TrimSpace(string(output)))
+}
+
+// TestHelperParityArchiveName exposes the parityArchiveName function for testing
+func TestHelperParityArchiveName(name string) (string, bool) {
+	return parityArchiveName(name)
+}
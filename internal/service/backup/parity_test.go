@@ -0,0 +1,115 @@
+package backup_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/kennycyb/go-backup/internal/service/backup"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakePar2 stands in for par2cmdline: its recovery volume is a copy of the archive,
+// verify compares the two and repair copies the volume back
+const fakePar2 = `#!/bin/sh
+cmd="$1"; shift
+while [ "${1#-}" != "$1" ]; do shift; done
+index="$1"
+archive="${index%.par2}"
+volume="$archive.vol000+100.par2"
+case "$cmd" in
+create)
+	[ -e "$index" ] && exit 3
+	echo index > "$index"
+	cp "$archive" "$volume"
+	;;
+verify)
+	[ -e "$volume" ] || exit 2
+	cmp -s "$archive" "$volume" || exit 1
+	;;
+repair)
+	[ -e "$volume" ] || exit 2
+	cmp -s "$archive" "$volume" && exit 0
+	mv "$archive" "$archive.1"
+	cp "$volume" "$archive"
+	;;
+esac
+`
+
+var _ = Describe("Parity", func() {
+	var (
+		tmpDir  string
+		archive string
+	)
+
+	BeforeEach(func() {
+		tmpDir = GinkgoT().TempDir()
+		binDir := filepath.Join(tmpDir, "bin")
+		Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binDir, "par2"), []byte(fakePar2), 0755)).To(Succeed())
+		GinkgoT().Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+		archive = filepath.Join(tmpDir, "project-20250101-120000.tar.gz")
+		Expect(os.WriteFile(archive, []byte("backup contents"), 0644)).To(Succeed())
+	})
+
+	It("should write parity files next to the archive", func() {
+		files, err := CreateParity(archive, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(Equal([]string{archive + ".par2", archive + ".vol000+100.par2"}))
+
+		Expect(ParityFiles(archive)).To(Equal(files))
+		Expect(VerifyParity(archive)).To(Equal(ParityIntact))
+	})
+
+	It("should replace the parity files of an earlier archive with the same name", func() {
+		Expect(CreateParity(archive, 10)).Error().NotTo(HaveOccurred())
+		Expect(os.WriteFile(archive, []byte("newer contents"), 0644)).To(Succeed())
+
+		Expect(CreateParity(archive, 10)).Error().NotTo(HaveOccurred())
+		Expect(VerifyParity(archive)).To(Equal(ParityIntact))
+	})
+
+	It("should repair a damaged archive", func() {
+		Expect(CreateParity(archive, 10)).Error().NotTo(HaveOccurred())
+		Expect(os.WriteFile(archive, []byte("backup c0ntents"), 0644)).To(Succeed())
+		Expect(VerifyParity(archive)).To(Equal(ParityRepairable))
+
+		Expect(RepairWithParity(archive)).To(Succeed())
+		Expect(os.ReadFile(archive)).To(Equal([]byte("backup contents")))
+		Expect(archive + ".1").NotTo(BeAnExistingFile())
+	})
+
+	It("should report damage the parity files cannot repair", func() {
+		Expect(CreateParity(archive, 10)).Error().NotTo(HaveOccurred())
+		Expect(os.Remove(archive + ".vol000+100.par2")).To(Succeed())
+
+		Expect(VerifyParity(archive)).To(Equal(ParityUnrepairable))
+		Expect(RepairWithParity(archive)).NotTo(Succeed())
+	})
+
+	It("should fail for archives without parity files", func() {
+		_, err := VerifyParity(archive)
+		Expect(err).To(MatchError(ContainSubstring("no parity files found")))
+		Expect(RepairWithParity(archive)).NotTo(Succeed())
+		Expect(RemoveParity(archive)).To(Succeed())
+	})
+
+	It("should reject invalid redundancy", func() {
+		Expect(CreateParity(archive, 0)).Error().To(HaveOccurred())
+		Expect(CreateParity(archive, 101)).Error().To(HaveOccurred())
+	})
+
+	DescribeTable("parity file names",
+		func(name, archive string, ok bool) {
+			got, gotOK := TestHelperParityArchiveName(name)
+			Expect(gotOK).To(Equal(ok))
+			Expect(got).To(Equal(archive))
+		},
+		Entry("index file", "p-1.tar.gz.par2", "p-1.tar.gz", true),
+		Entry("recovery volume", "p-1.tar.gz.vol000+100.par2", "p-1.tar.gz", true),
+		Entry("encrypted archive", "p-1.tar.gz.gpg.vol00+10.par2", "p-1.tar.gz.gpg", true),
+		Entry("other file", "p-1.tar.gz", "", false),
+		Entry("bare suffix", ".par2", "", false),
+	)
+})
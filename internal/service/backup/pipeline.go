@@ -0,0 +1,113 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Stage is a point in the backup pipeline at which steps run
+type Stage string
+
+// The stages of a backup, in the order they run
+const (
+	StageScan     Stage = "scan"     // Before anything is archived, e.g. to scan the source for viruses
+	StageFilter   Stage = "filter"   // Steps may add exclude patterns to State.Excludes
+	StageArchive  Stage = "archive"  // The archives and their manifests exist, unencrypted
+	StageEncrypt  Stage = "encrypt"  // The archives are encrypted for targets that need it
+	StageTransfer Stage = "transfer" // Every target has received its archive or failed
+	StageRecord   Stage = "record"   // The history and status of the targets are recorded
+	StageRotate   Stage = "rotate"   // Old backups are rotated out
+)
+
+// Stages lists the stages in the order a backup runs them
+var Stages = []Stage{StageScan, StageFilter, StageArchive, StageEncrypt, StageTransfer, StageRecord, StageRotate}
+
+// PipelineArchive is one archive variant made by the run, shared by the targets with the same
+// compression and encryption
+type PipelineArchive struct {
+	Path      string // Temporary file; steps may rewrite it in place, e.g. to scan or re-encrypt it
+	FileName  string // Name the archive is stored under in directory targets
+	Encrypted bool   // Whether the archive is encrypted, from the encrypt stage on
+}
+
+// PipelineState is what the steps of a run see of the backup and may change
+type PipelineState struct {
+	Source   string            // Directory being backed up
+	BaseName string            // Name of the backup without extensions, e.g. project-20250520-123045
+	Excludes []string          // Exclude patterns of the archive; filter steps may add their own
+	Archives []PipelineArchive // Archive variants, from the archive stage on; none when every target records a metadata snapshot
+	Targets  []string          // Destinations of the run
+}
+
+// PipelineStep is a custom step of the backup pipeline, such as a virus scan or an extra
+// notification. Steps run after the built-in work of their stage, in the order they were
+// registered; an error fails the backup.
+type PipelineStep interface {
+	Name() string
+	Stage() Stage
+	Run(ctx context.Context, state *PipelineState) error
+}
+
+var (
+	stepsMu sync.Mutex
+	steps   []PipelineStep
+)
+
+// RegisterStep adds a step to the pipeline of every run, typically from the init function of a
+// package compiled into go-backup. Returns an error for an unknown stage or a name already taken.
+func RegisterStep(step PipelineStep) error {
+	known := false
+	for _, stage := range Stages {
+		known = known || stage == step.Stage()
+	}
+	if !known {
+		return fmt.Errorf("pipeline step %s: unknown stage %q", step.Name(), step.Stage())
+	}
+
+	stepsMu.Lock()
+	defer stepsMu.Unlock()
+	for _, existing := range steps {
+		if existing.Name() == step.Name() {
+			return fmt.Errorf("pipeline step %s is already registered", step.Name())
+		}
+	}
+	steps = append(steps, step)
+	return nil
+}
+
+// RegisteredSteps returns the steps of a stage in the order they were registered
+func RegisteredSteps(stage Stage) []PipelineStep {
+	stepsMu.Lock()
+	defer stepsMu.Unlock()
+	var staged []PipelineStep
+	for _, step := range steps {
+		if step.Stage() == stage {
+			staged = append(staged, step)
+		}
+	}
+	return staged
+}
+
+// RunStage runs the registered steps of a stage, stopping at the first that fails or when the
+// context is done. Returns the names of the steps that ran.
+func RunStage(ctx context.Context, stage Stage, state *PipelineState) ([]string, error) {
+	var ran []string
+	for _, step := range RegisteredSteps(stage) {
+		if err := ctx.Err(); err != nil {
+			return ran, err
+		}
+		if err := step.Run(ctx, state); err != nil {
+			return ran, fmt.Errorf("pipeline step %s failed: %w", step.Name(), err)
+		}
+		ran = append(ran, step.Name())
+	}
+	return ran, nil
+}
+
+// TestHelperResetSteps removes all registered steps for testing
+func TestHelperResetSteps() {
+	stepsMu.Lock()
+	defer stepsMu.Unlock()
+	steps = nil
+}
@@ -0,0 +1,89 @@
+package backup_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kennycyb/go-backup/internal/service/backup"
+)
+
+// testStep records its runs and fails when err is set
+type testStep struct {
+	name  string
+	stage backup.Stage
+	err   error
+	runs  *[]string
+}
+
+func (s testStep) Name() string        { return s.name }
+func (s testStep) Stage() backup.Stage { return s.stage }
+func (s testStep) Run(ctx context.Context, state *backup.PipelineState) error {
+	*s.runs = append(*s.runs, s.name)
+	if s.stage == backup.StageFilter {
+		state.Excludes = append(state.Excludes, "*.iso")
+	}
+	return s.err
+}
+
+var _ = Describe("Pipeline", func() {
+	var runs []string
+
+	BeforeEach(func() {
+		runs = nil
+		backup.TestHelperResetSteps()
+	})
+
+	AfterEach(func() {
+		backup.TestHelperResetSteps()
+	})
+
+	It("should run the steps of a stage in the order they were registered", func() {
+		Expect(backup.RegisterStep(testStep{name: "virus-scan", stage: backup.StageScan, runs: &runs})).To(Succeed())
+		Expect(backup.RegisterStep(testStep{name: "notify", stage: backup.StageRecord, runs: &runs})).To(Succeed())
+		Expect(backup.RegisterStep(testStep{name: "inventory", stage: backup.StageScan, runs: &runs})).To(Succeed())
+
+		ran, err := backup.RunStage(context.Background(), backup.StageScan, &backup.PipelineState{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ran).To(Equal([]string{"virus-scan", "inventory"}))
+		Expect(runs).To(Equal([]string{"virus-scan", "inventory"}))
+	})
+
+	It("should let filter steps add excludes", func() {
+		Expect(backup.RegisterStep(testStep{name: "no-images", stage: backup.StageFilter, runs: &runs})).To(Succeed())
+
+		state := &backup.PipelineState{Excludes: []string{"node_modules"}}
+		_, err := backup.RunStage(context.Background(), backup.StageFilter, state)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state.Excludes).To(Equal([]string{"node_modules", "*.iso"}))
+	})
+
+	It("should stop at the first failing step", func() {
+		Expect(backup.RegisterStep(testStep{name: "virus-scan", stage: backup.StageScan, err: errors.New("infected"), runs: &runs})).To(Succeed())
+		Expect(backup.RegisterStep(testStep{name: "inventory", stage: backup.StageScan, runs: &runs})).To(Succeed())
+
+		ran, err := backup.RunStage(context.Background(), backup.StageScan, &backup.PipelineState{})
+		Expect(err).To(MatchError(ContainSubstring("virus-scan failed: infected")))
+		Expect(ran).To(BeEmpty())
+		Expect(runs).To(Equal([]string{"virus-scan"}))
+	})
+
+	It("should not run steps once the context is done", func() {
+		Expect(backup.RegisterStep(testStep{name: "virus-scan", stage: backup.StageScan, runs: &runs})).To(Succeed())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := backup.RunStage(ctx, backup.StageScan, &backup.PipelineState{})
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(runs).To(BeEmpty())
+	})
+
+	It("should reject unknown stages and duplicate names", func() {
+		Expect(backup.RegisterStep(testStep{name: "upload", stage: "publish", runs: &runs})).NotTo(Succeed())
+		Expect(backup.RegisterStep(testStep{name: "virus-scan", stage: backup.StageScan, runs: &runs})).To(Succeed())
+		Expect(backup.RegisterStep(testStep{name: "virus-scan", stage: backup.StageArchive, runs: &runs})).NotTo(Succeed())
+		Expect(backup.RegisteredSteps(backup.StageArchive)).To(BeEmpty())
+	})
+})
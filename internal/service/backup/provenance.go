@@ -0,0 +1,98 @@
+package backup
+
+import (
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Provenance records how a backup was produced, so that an old archive can be explained
+// when a restore has to be debugged long after it was made
+type Provenance struct {
+	Version          string `json:"version" yaml:"version"`                               // go-backup version that made the backup
+	Platform         string `json:"platform" yaml:"platform"`                             // Operating system and architecture, e.g. linux/amd64
+	CompressionLevel int    `json:"compressionLevel" yaml:"compressionLevel"`             // gzip level of the archive
+	Encryption       string `json:"encryption,omitempty" yaml:"encryption,omitempty"`     // GPG recipient; empty if not encrypted
+	ChunkSize        int64  `json:"chunkSize,omitempty" yaml:"chunkSize,omitempty"`       // Size of the separately readable chunks, if any
+	Excludes         int    `json:"excludes" yaml:"excludes"`                             // Number of exclude patterns applied
+	ExcludesHash     string `json:"excludesHash,omitempty" yaml:"excludesHash,omitempty"` // See ExcludesHash
+}
+
+// NewProvenance returns the provenance of a backup made on this machine by the given version
+// of go-backup. A compression level of 0 stands for gzip's default level.
+func NewProvenance(version string, level int, receiver string, chunkSize int64, excludes []string) *Provenance {
+	if level == 0 || level == gzip.DefaultCompression {
+		level = 6 // What gzip.DefaultCompression compresses with
+	}
+	return &Provenance{
+		Version:          version,
+		Platform:         CurrentPlatform(),
+		CompressionLevel: level,
+		Encryption:       receiver,
+		ChunkSize:        chunkSize,
+		Excludes:         len(excludes),
+		ExcludesHash:     ExcludesHash(excludes),
+	}
+}
+
+// NewRunID returns a random version 4 UUID identifying one run, so its log, history records,
+// manifests and notifications can be matched up
+func NewRunID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40 // Version 4
+	id[8] = id[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
+
+// ValidRunID checks a run ID given on the command line: it may not be empty or contain whitespace,
+// as it is written into log lines
+func ValidRunID(id string) error {
+	if id == "" || strings.ContainsFunc(id, unicode.IsSpace) {
+		return fmt.Errorf("invalid run ID %q: it must be non-empty and contain no whitespace", id)
+	}
+	return nil
+}
+
+// CurrentPlatform returns the operating system and architecture go-backup runs on
+func CurrentPlatform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// ExcludesHash returns a short hash of a set of exclude patterns, regardless of their order,
+// to tell whether two backups were made with the same excludes. Empty without excludes.
+func ExcludesHash(excludes []string) string {
+	if len(excludes) == 0 {
+		return ""
+	}
+	sorted := append([]string{}, excludes...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Describe returns the provenance as one line, e.g. "go-backup 1.4.0 on linux/amd64, gzip -6, 3 excludes"
+func (p *Provenance) Describe() string {
+	parts := []string{fmt.Sprintf("go-backup %s on %s", p.Version, p.Platform), fmt.Sprintf("gzip -%d", p.CompressionLevel)}
+	if p.Encryption != "" {
+		parts = append(parts, "encrypted for "+p.Encryption)
+	}
+	if p.ChunkSize > 0 {
+		parts = append(parts, fmt.Sprintf("%d-byte chunks", p.ChunkSize))
+	}
+	switch p.Excludes {
+	case 0:
+		parts = append(parts, "no excludes")
+	case 1:
+		parts = append(parts, "1 exclude ("+p.ExcludesHash+")")
+	default:
+		parts = append(parts, fmt.Sprintf("%d excludes (%s)", p.Excludes, p.ExcludesHash))
+	}
+	return strings.Join(parts, ", ")
+}
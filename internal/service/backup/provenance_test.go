@@ -0,0 +1,58 @@
+package backup_test
+
+import (
+	"path/filepath"
+	"runtime"
+
+	. "github.com/kennycyb/go-backup/internal/service/backup"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Provenance", func() {
+	It("should record the version, platform and options of a backup", func() {
+		provenance := NewProvenance("1.4.0", 9, "me@example.com", 64<<20, []string{".git", "node_modules"})
+		Expect(provenance.Version).To(Equal("1.4.0"))
+		Expect(provenance.Platform).To(Equal(runtime.GOOS + "/" + runtime.GOARCH))
+		Expect(provenance.CompressionLevel).To(Equal(9))
+		Expect(provenance.Encryption).To(Equal("me@example.com"))
+		Expect(provenance.ChunkSize).To(Equal(int64(64 << 20)))
+		Expect(provenance.Excludes).To(Equal(2))
+		Expect(provenance.ExcludesHash).To(HaveLen(16))
+	})
+
+	It("should record gzip's default level for level 0", func() {
+		Expect(NewProvenance("1.4.0", 0, "", 0, nil).CompressionLevel).To(Equal(6))
+	})
+
+	It("should create distinct version 4 UUIDs as run IDs", func() {
+		id := NewRunID()
+		Expect(id).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+		Expect(NewRunID()).NotTo(Equal(id))
+		Expect(ValidRunID(id)).To(Succeed())
+		Expect(ValidRunID("nightly 42")).NotTo(Succeed())
+		Expect(ValidRunID("")).NotTo(Succeed())
+	})
+
+	It("should hash excludes regardless of their order", func() {
+		Expect(ExcludesHash([]string{"bin", ".git"})).To(Equal(ExcludesHash([]string{".git", "bin"})))
+		Expect(ExcludesHash([]string{"bin", ".git"})).NotTo(Equal(ExcludesHash([]string{".git"})))
+		Expect(ExcludesHash(nil)).To(BeEmpty())
+	})
+
+	It("should describe the backup in one line", func() {
+		provenance := &Provenance{Version: "1.4.0", Platform: "linux/amd64", CompressionLevel: 6, Encryption: "me@example.com", Excludes: 1, ExcludesHash: "0123456789abcdef"}
+		Expect(provenance.Describe()).To(Equal("go-backup 1.4.0 on linux/amd64, gzip -6, encrypted for me@example.com, 1 exclude (0123456789abcdef)"))
+	})
+
+	It("should be kept in the manifest", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "project"+ManifestSuffix)
+		manifest := NewManifest("project.tar.gz", "/src/project", nil)
+		manifest.Provenance = NewProvenance("1.4.0", 6, "", 0, []string{".git"})
+		Expect(WriteManifest(path, manifest)).To(Succeed())
+
+		read, err := ReadManifest(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(read.Provenance).To(Equal(manifest.Provenance))
+	})
+})
@@ -7,24 +7,74 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 )
 
+// TrashDirName is the subfolder of a backup directory that receives trashed backups
+const TrashDirName = ".trash"
+
+// DefaultTrashGracePeriod is how long trashed backups are kept when no grace period is configured
+const DefaultTrashGracePeriod = 30 * 24 * time.Hour
+
+// RotationOptions controls how expired backups are removed during rotation
+type RotationOptions struct {
+	Trash            bool            // Move expired backups into the .trash subfolder instead of deleting them
+	TrashGracePeriod time.Duration   // How long trashed backups are kept; defaults to DefaultTrashGracePeriod
+	Keep             map[string]bool // File names of backups that are never removed and do not count towards the limit, e.g. tagged backups
+	Immutable        bool            // Backups were protected with MakeImmutable; lift it before removing them
+	// Host and Source, when set, leave alone the backups whose manifest or sidecar config records another
+	// host or source, e.g. a similarly named project on another machine sharing the target
+	Host   string
+	Source string
+}
+
 // CleanupOldBackups removes older backups, keeping only the specified number of most recent ones
 // It deletes older backups that match the prefix and extension pattern.
 func CleanupOldBackups(backupDir string, prefix string, maxBackups int) error {
+	return RotateBackups(backupDir, prefix, maxBackups, RotationOptions{})
+}
+
+// RotateBackups keeps only the specified number of most recent backups matching the prefix.
+// Older backups and their associated config, manifest, checksum and parity files are deleted, or moved into the .trash
+// subfolder when opts.Trash is set, and removed from the directory's SHA256SUMS file. Metadata snapshots of removed
+// backups are removed too. With opts.Trash, trashed backups older than the grace period are purged as well.
+// Backups listed in opts.Keep, and those of another host or source (see OwnedBy), are left alone and not counted.
+func RotateBackups(backupDir string, prefix string, maxBackups int, opts RotationOptions) error {
+	if opts.Trash {
+		if err := EmptyTrash(backupDir, opts.TrashGracePeriod); err != nil {
+			fmt.Printf("  Warning: Failed to empty trash in %s: %v\n", backupDir, err)
+		}
+	}
+
 	// Read all files in the backup directory
 	files, err := os.ReadDir(backupDir)
 	if err != nil {
 		return fmt.Errorf("error reading backup directory: %w", err)
 	}
 
-	// Filter for backup files with matching prefix and .tar.gz extension (possibly with .gpg)
+	// Drop removed (or otherwise missing) backups from the checksum list once rotation is done
+	defer func() {
+		if err := PruneChecksums(backupDir); err != nil {
+			fmt.Printf("  Warning: Failed to update %s in %s: %v\n", ChecksumsFileName, backupDir, err)
+		}
+	}()
+
+	// Metadata snapshots are only useful while their archive exists
+	defer func() {
+		if err := PruneSnapshots(backupDir, opts); err != nil {
+			fmt.Printf("  Warning: Failed to remove old metadata snapshots in %s: %v\n", backupDir, err)
+		}
+	}()
+
+	// Filter for backup archives with matching prefix, of any format and possibly with .gpg
 	var backupFiles []os.DirEntry
 	for _, file := range files {
 		fileName := file.Name()
 		if !file.IsDir() &&
 			strings.HasPrefix(fileName, prefix) &&
-			(strings.HasSuffix(fileName, ".tar.gz") || strings.HasSuffix(fileName, ".tar.gz.gpg")) {
+			IsArchiveName(fileName) &&
+			!opts.Keep[fileName] &&
+			OwnedBy(backupDir, fileName, opts.Host, opts.Source) {
 			backupFiles = append(backupFiles, file)
 		}
 	}
@@ -54,40 +104,24 @@ func CleanupOldBackups(backupDir string, prefix string, maxBackups int) error {
 		backupFilePath := filepath.Join(backupDir, fileName)
 
 		// Delete the backup file
-		if err := os.Remove(backupFilePath); err != nil {
+		if err := removeBackupFile(backupFilePath, opts); err != nil {
 			fmt.Printf("  Warning: Failed to delete old backup %s: %v\n", backupFilePath, err)
+		} else if opts.Trash {
+			fmt.Printf("  Trashed old backup: %s\n", backupFilePath)
 		} else {
 			fmt.Printf("  Deleted old backup: %s\n", backupFilePath)
 		}
 
 		// Check for and delete any associated config file
 		// Extract the base name for the config file by removing extensions
-		configBaseName := fileName
-		// Handle .tar.gz.gpg case
-		if strings.HasSuffix(configBaseName, ".tar.gz.gpg") {
-			configBaseName = strings.TrimSuffix(configBaseName, ".tar.gz.gpg")
-		} else if strings.HasSuffix(configBaseName, ".tar.gz") {
-			// Handle .tar.gz case
-			configBaseName = strings.TrimSuffix(configBaseName, ".tar.gz")
-		} else {
-			// Handle other cases by removing extensions one by one
-			if strings.HasSuffix(configBaseName, ".gpg") {
-				configBaseName = strings.TrimSuffix(configBaseName, ".gpg")
-			}
-			if strings.HasSuffix(configBaseName, ".gz") {
-				configBaseName = strings.TrimSuffix(configBaseName, ".gz")
-			}
-			if strings.HasSuffix(configBaseName, ".tar") {
-				configBaseName = strings.TrimSuffix(configBaseName, ".tar")
-			}
-		}
+		configBaseName := BackupBaseName(fileName)
 
 		// Create the config file path
 		configFilePath := filepath.Join(backupDir, configBaseName+".backup.yaml")
 
 		// Check if the config file exists and delete it
 		if _, err := os.Stat(configFilePath); err == nil {
-			if err := os.Remove(configFilePath); err != nil {
+			if err := removeBackupFile(configFilePath, opts); err != nil {
 				fmt.Printf("  Warning: Failed to delete associated config file %s: %v\n", configFilePath, err)
 			} else {
 				fmt.Printf("  Deleted associated config file: %s\n", configFilePath)
@@ -99,6 +133,9 @@ func CleanupOldBackups(backupDir string, prefix string, maxBackups int) error {
 			configBaseName + ".backup.yaml",        // Standard format
 			configBaseName + ".tar.gz.backup.yaml", // Possible format with extension
 			configBaseName + ".gpg.backup.yaml",    // Possible format with gpg extension
+			configBaseName + ".backup.yaml.gpg",    // Encrypted config of an encrypted backup
+			configBaseName + ManifestSuffix,        // Manifest
+			configBaseName + ManifestSuffix + ".gpg",
 		}
 
 		for _, possibleName := range possibleConfigNames {
@@ -108,13 +145,159 @@ func CleanupOldBackups(backupDir string, prefix string, maxBackups int) error {
 
 			possiblePath := filepath.Join(backupDir, possibleName)
 			if _, err := os.Stat(possiblePath); err == nil {
-				if err := os.Remove(possiblePath); err != nil {
-					fmt.Printf("  Warning: Failed to delete associated config file %s: %v\n", possiblePath, err)
+				if err := removeBackupFile(possiblePath, opts); err != nil {
+					fmt.Printf("  Warning: Failed to delete associated file %s: %v\n", possiblePath, err)
 				} else {
-					fmt.Printf("  Deleted associated config file: %s\n", possiblePath)
+					fmt.Printf("  Deleted associated file: %s\n", possiblePath)
 				}
 			}
 		}
+
+		// Checksum and parity files are named after the archive itself
+		if _, err := os.Stat(backupFilePath + ChecksumSuffix); err == nil {
+			if err := removeBackupFile(backupFilePath+ChecksumSuffix, opts); err != nil {
+				fmt.Printf("  Warning: Failed to delete associated file %s: %v\n", backupFilePath+ChecksumSuffix, err)
+			} else {
+				fmt.Printf("  Deleted associated file: %s\n", backupFilePath+ChecksumSuffix)
+			}
+		}
+		if parityFiles, err := ParityFiles(backupFilePath); err == nil {
+			for _, parityPath := range parityFiles {
+				if err := removeBackupFile(parityPath, opts); err != nil {
+					fmt.Printf("  Warning: Failed to delete associated file %s: %v\n", parityPath, err)
+				} else {
+					fmt.Printf("  Deleted associated file: %s\n", parityPath)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// ListedFile is a file of a backup directory known only from a listing, e.g. on a remote target
+type ListedFile struct {
+	Name    string
+	ModTime time.Time // Zero if the listing does not report it
+}
+
+// ExpiredBackup is a backup that rotation removes, with the config, manifest, checksum and parity files next to it
+type ExpiredBackup struct {
+	Name       string
+	Associated []string
+}
+
+// ExpiredBackups returns the backups RotateBackups would remove from a directory known only from a
+// listing, oldest first, keeping the maxBackups most recent ones matching the prefix. Backups listed in
+// keep are left alone and not counted. Unlike RotateBackups, the host and source recorded with a backup
+// are not checked, since that takes downloading its manifest.
+func ExpiredBackups(files []ListedFile, prefix string, maxBackups int, keep map[string]bool) []ExpiredBackup {
+	present := make(map[string]bool, len(files))
+	var backups []ListedFile
+	for _, file := range files {
+		present[file.Name] = true
+		if strings.HasPrefix(file.Name, prefix) &&
+			IsArchiveName(file.Name) &&
+			!keep[file.Name] {
+			backups = append(backups, file)
+		}
+	}
+	if len(backups) <= maxBackups {
+		return nil
+	}
+
+	// Oldest first; backup names carry their timestamp, which orders files without a modification time
+	sort.Slice(backups, func(i, j int) bool {
+		a, b := backups[i].ModTime, backups[j].ModTime
+		if !a.IsZero() && !b.IsZero() && !a.Equal(b) {
+			return a.Before(b)
+		}
+		return backups[i].Name < backups[j].Name
+	})
+
+	var expired []ExpiredBackup
+	for _, backup := range backups[:len(backups)-maxBackups] {
+		baseName := BackupBaseName(backup.Name)
+		entry := ExpiredBackup{Name: backup.Name}
+		for _, name := range []string{
+			baseName + ".backup.yaml",
+			baseName + ".tar.gz.backup.yaml",
+			baseName + ".gpg.backup.yaml",
+			baseName + ".backup.yaml.gpg",
+			baseName + ManifestSuffix,
+			baseName + ManifestSuffix + ".gpg",
+			backup.Name + ChecksumSuffix,
+		} {
+			if present[name] {
+				entry.Associated = append(entry.Associated, name)
+			}
+		}
+		for _, file := range files {
+			if archive, ok := parityArchiveName(file.Name); ok && archive == backup.Name {
+				entry.Associated = append(entry.Associated, file.Name)
+			}
+		}
+		expired = append(expired, entry)
+	}
+	return expired
+}
+
+// removeBackupFile deletes a backup file, or moves it into the trash folder next to it.
+// Trashed files get their modification time reset so the grace period starts now,
+// and lose their immutable protection so the trash can be emptied.
+func removeBackupFile(path string, opts RotationOptions) error {
+	if opts.Immutable {
+		if err := MakeMutable(path); err != nil {
+			return err
+		}
+	}
+	if !opts.Trash {
+		return os.Remove(path)
+	}
+
+	trashDir := filepath.Join(filepath.Dir(path), TrashDirName)
+	if err := os.MkdirAll(trashDir, 0755); err != nil {
+		return fmt.Errorf("error creating trash directory: %w", err)
+	}
+
+	trashPath := filepath.Join(trashDir, filepath.Base(path))
+	if err := os.Rename(path, trashPath); err != nil {
+		return fmt.Errorf("error moving file to trash: %w", err)
+	}
+
+	now := time.Now()
+	return os.Chtimes(trashPath, now, now)
+}
+
+// EmptyTrash permanently deletes files in the backup directory's trash folder that were
+// trashed longer ago than the grace period. A zero grace period uses DefaultTrashGracePeriod.
+func EmptyTrash(backupDir string, gracePeriod time.Duration) error {
+	if gracePeriod <= 0 {
+		gracePeriod = DefaultTrashGracePeriod
+	}
+
+	trashDir := filepath.Join(backupDir, TrashDirName)
+	files, err := os.ReadDir(trashDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading trash directory: %w", err)
+	}
+
+	cutoff := time.Now().Add(-gracePeriod)
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil || file.IsDir() || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		trashPath := filepath.Join(trashDir, file.Name())
+		if err := os.Remove(trashPath); err != nil {
+			fmt.Printf("  Warning: Failed to purge trashed file %s: %v\n", trashPath, err)
+		} else {
+			fmt.Printf("  Purged trashed file: %s\n", trashPath)
+		}
 	}
 
 	return nil
@@ -240,6 +240,172 @@ var _ = Describe("Backup", func() {
 				Expect(remainingFiles).NotTo(ContainElement(testPrefix + "-20240101-120000.backup.yaml"))
 				Expect(remainingFiles).NotTo(ContainElement(testPrefix + "-20240102-120000.backup.yaml"))
 			})
+
+			It("deletes encrypted config and manifest files with the backup", func() {
+				now := time.Now()
+
+				createTestFile(testPrefix+"-20240101-120000.tar.gz.gpg", now.Add(-10*24*time.Hour))        // To be deleted
+				createTestFile(testPrefix+"-20240101-120000.backup.yaml.gpg", now.Add(-10*24*time.Hour))   // To be deleted
+				createTestFile(testPrefix+"-20240101-120000.manifest.json.gpg", now.Add(-10*24*time.Hour)) // To be deleted
+				createTestFile(testPrefix+"-20240102-120000.tar.gz", now.Add(-9*24*time.Hour))             // Keep
+				createTestFile(testPrefix+"-20240102-120000.manifest.json", now.Add(-9*24*time.Hour))      // Keep
+
+				err := CleanupOldBackups(tmpDir, testPrefix+"-", 1)
+				Expect(err).NotTo(HaveOccurred())
+
+				files, err := os.ReadDir(tmpDir)
+				Expect(err).NotTo(HaveOccurred())
+
+				var remainingFiles []string
+				for _, file := range files {
+					remainingFiles = append(remainingFiles, file.Name())
+				}
+
+				Expect(remainingFiles).To(ConsistOf(
+					testPrefix+"-20240102-120000.tar.gz",
+					testPrefix+"-20240102-120000.manifest.json",
+				))
+			})
+
+			It("deletes parity files with the backup", func() {
+				now := time.Now()
+
+				createTestFile(testPrefix+"-20240101-120000.tar.gz", now.Add(-10*24*time.Hour))                 // To be deleted
+				createTestFile(testPrefix+"-20240101-120000.tar.gz.par2", now.Add(-10*24*time.Hour))            // To be deleted
+				createTestFile(testPrefix+"-20240101-120000.tar.gz.vol000+100.par2", now.Add(-10*24*time.Hour)) // To be deleted
+				createTestFile(testPrefix+"-20240102-120000.tar.gz", now.Add(-9*24*time.Hour))                  // Keep
+				createTestFile(testPrefix+"-20240102-120000.tar.gz.par2", now.Add(-9*24*time.Hour))             // Keep
+
+				Expect(CleanupOldBackups(tmpDir, testPrefix+"-", 1)).To(Succeed())
+
+				files, err := os.ReadDir(tmpDir)
+				Expect(err).NotTo(HaveOccurred())
+
+				var remainingFiles []string
+				for _, file := range files {
+					remainingFiles = append(remainingFiles, file.Name())
+				}
+
+				Expect(remainingFiles).To(ConsistOf(
+					testPrefix+"-20240102-120000.tar.gz",
+					testPrefix+"-20240102-120000.tar.gz.par2",
+				))
+			})
+		})
+	})
+
+	Describe("RotateBackups", func() {
+		writeFile := func(name string, modTime time.Time) {
+			filePath := filepath.Join(tmpDir, name)
+			Expect(os.MkdirAll(filepath.Dir(filePath), 0755)).To(Succeed())
+			Expect(os.WriteFile(filePath, []byte("test backup content"), 0644)).To(Succeed())
+			Expect(os.Chtimes(filePath, modTime, modTime)).To(Succeed())
+		}
+
+		Context("when trash is enabled", func() {
+			It("moves expired backups and their config files into the trash folder", func() {
+				now := time.Now()
+				writeFile("test-backup-20240101-120000.tar.gz", now.Add(-3*24*time.Hour))
+				writeFile("test-backup-20240101-120000.backup.yaml", now.Add(-3*24*time.Hour))
+				writeFile("test-backup-20240102-120000.tar.gz", now.Add(-2*24*time.Hour))
+
+				err := RotateBackups(tmpDir, "test-backup-", 1, RotationOptions{Trash: true})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(tmpDir, "test-backup-20240101-120000.tar.gz")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(tmpDir, "test-backup-20240102-120000.tar.gz")).To(BeARegularFile())
+				Expect(filepath.Join(tmpDir, TrashDirName, "test-backup-20240101-120000.tar.gz")).To(BeARegularFile())
+				Expect(filepath.Join(tmpDir, TrashDirName, "test-backup-20240101-120000.backup.yaml")).To(BeARegularFile())
+			})
+
+			It("purges trashed files older than the grace period", func() {
+				now := time.Now()
+				writeFile(filepath.Join(TrashDirName, "test-backup-20230101-120000.tar.gz"), now.Add(-10*24*time.Hour))
+				writeFile(filepath.Join(TrashDirName, "test-backup-20230102-120000.tar.gz"), now.Add(-1*24*time.Hour))
+
+				err := RotateBackups(tmpDir, "test-backup-", 5, RotationOptions{Trash: true, TrashGracePeriod: 7 * 24 * time.Hour})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(tmpDir, TrashDirName, "test-backup-20230101-120000.tar.gz")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(tmpDir, TrashDirName, "test-backup-20230102-120000.tar.gz")).To(BeARegularFile())
+			})
+		})
+
+		It("rotates backups of every archive format together", func() {
+			now := time.Now()
+			writeFile("test-backup-20240101-120000.zip", now.Add(-3*24*time.Hour))
+			writeFile("test-backup-20240101-120000.backup.yaml", now.Add(-3*24*time.Hour))
+			writeFile("test-backup-20240102-120000.tar", now.Add(-2*24*time.Hour))
+			writeFile("test-backup-20240103-120000.tar.gz", now.Add(-1*24*time.Hour))
+
+			err := RotateBackups(tmpDir, "test-backup-", 2, RotationOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(tmpDir, "test-backup-20240101-120000.zip")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(tmpDir, "test-backup-20240101-120000.backup.yaml")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(tmpDir, "test-backup-20240102-120000.tar")).To(BeARegularFile())
+			Expect(filepath.Join(tmpDir, "test-backup-20240103-120000.tar.gz")).To(BeARegularFile())
+		})
+
+		It("keeps the listed backups without counting them", func() {
+			now := time.Now()
+			writeFile("test-backup-20240101-120000.tar.gz", now.Add(-3*24*time.Hour))
+			writeFile("test-backup-20240102-120000.tar.gz", now.Add(-2*24*time.Hour))
+			writeFile("test-backup-20240103-120000.tar.gz", now.Add(-1*24*time.Hour))
+
+			err := RotateBackups(tmpDir, "test-backup-", 1, RotationOptions{Keep: map[string]bool{"test-backup-20240101-120000.tar.gz": true}})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(tmpDir, "test-backup-20240101-120000.tar.gz")).To(BeARegularFile())
+			Expect(filepath.Join(tmpDir, "test-backup-20240102-120000.tar.gz")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(tmpDir, "test-backup-20240103-120000.tar.gz")).To(BeARegularFile())
+		})
+	})
+
+	Describe("EmptyTrash", func() {
+		It("returns nil when there is no trash folder", func() {
+			Expect(EmptyTrash(tmpDir, 0)).To(Succeed())
+		})
+	})
+
+	Describe("ExpiredBackups", func() {
+		day := func(d int) time.Time { return time.Date(2025, 5, d, 12, 0, 0, 0, time.UTC) }
+
+		It("returns the oldest backups beyond the limit with their associated files", func() {
+			files := []ListedFile{
+				{Name: "project-20250503-120000.tar.gz", ModTime: day(3)},
+				{Name: "project-20250501-120000.tar.gz.gpg", ModTime: day(1)},
+				{Name: "project-20250501-120000.backup.yaml.gpg", ModTime: day(1)},
+				{Name: "project-20250501-120000" + ManifestSuffix + ".gpg", ModTime: day(1)},
+				{Name: "project-20250501-120000.tar.gz.gpg.par2", ModTime: day(1)},
+				{Name: "project-20250502-120000.tar.gz", ModTime: day(2)},
+				{Name: "project-20250502-120000.backup.yaml", ModTime: day(2)},
+				{Name: "project-20250502-120000.tar.gz" + ChecksumSuffix, ModTime: day(2)},
+				{Name: "other-20250501-120000.tar.gz", ModTime: day(1)},
+				{Name: ChecksumsFileName, ModTime: day(3)},
+			}
+			Expect(ExpiredBackups(files, "project-", 1, nil)).To(Equal([]ExpiredBackup{
+				{Name: "project-20250501-120000.tar.gz.gpg", Associated: []string{
+					"project-20250501-120000.backup.yaml.gpg",
+					"project-20250501-120000" + ManifestSuffix + ".gpg",
+					"project-20250501-120000.tar.gz.gpg.par2",
+				}},
+				{Name: "project-20250502-120000.tar.gz", Associated: []string{
+					"project-20250502-120000.backup.yaml",
+					"project-20250502-120000.tar.gz" + ChecksumSuffix,
+				}},
+			}))
+			Expect(ExpiredBackups(files, "project-", 3, nil)).To(BeEmpty())
+		})
+
+		It("orders backups without a modification time by name and leaves kept ones alone", func() {
+			files := []ListedFile{
+				{Name: "project-20250503-120000.tar.gz"},
+				{Name: "project-20250501-120000.tar.gz"},
+				{Name: "project-20250502-120000.tar.gz"},
+			}
+			expired := ExpiredBackups(files, "project-", 1, map[string]bool{"project-20250501-120000.tar.gz": true})
+			Expect(expired).To(Equal([]ExpiredBackup{{Name: "project-20250502-120000.tar.gz"}}))
 		})
 	})
 })
@@ -0,0 +1,62 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kennycyb/go-backup/internal/service/compress"
+)
+
+// SameContents reports whether the entries hold the same paths and file contents as the manifest,
+// ignoring modification times and permissions. It is false when either side lacks content hashes,
+// e.g. for manifests written before hashes were recorded.
+func SameContents(previous *Manifest, entries []compress.ArchiveEntry) bool {
+	if len(previous.Entries) != len(entries) {
+		return false
+	}
+
+	byPath := make(map[string]compress.ArchiveEntry, len(previous.Entries))
+	for _, entry := range previous.Entries {
+		byPath[entry.Path] = entry
+	}
+	for _, entry := range entries {
+		old, ok := byPath[entry.Path]
+		if !ok || old.IsDir != entry.IsDir || old.Size != entry.Size || old.Link != entry.Link {
+			return false
+		}
+		if entry.Mode.IsRegular() && (entry.SHA256 == "" || old.SHA256 != entry.SHA256) {
+			return false
+		}
+	}
+	return true
+}
+
+// PruneSnapshots removes the metadata snapshots in the backup directory whose archive no longer
+// exists, e.g. after it was rotated out. Removed snapshots are trashed when opts.Trash is set.
+func PruneSnapshots(backupDir string, opts RotationOptions) error {
+	files, err := os.ReadDir(backupDir)
+	if err != nil {
+		return fmt.Errorf("error reading backup directory: %w", err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ManifestSuffix) {
+			continue
+		}
+		path := filepath.Join(backupDir, file.Name())
+		manifest, err := ReadManifest(path)
+		if err != nil || !manifest.MetadataOnly {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(backupDir, manifest.Archive)); !os.IsNotExist(err) {
+			continue
+		}
+		if err := removeBackupFile(path, opts); err != nil {
+			return fmt.Errorf("error removing snapshot %s: %w", path, err)
+		}
+		fmt.Printf("  Deleted metadata snapshot of rotated backup: %s\n", path)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package backup_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/kennycyb/go-backup/internal/service/backup"
+	"github.com/kennycyb/go-backup/internal/service/compress"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metadata snapshots", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "snapshot-test")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	entries := func() []compress.ArchiveEntry {
+		return []compress.ArchiveEntry{
+			{Path: "src", IsDir: true, Mode: os.ModeDir | 0755},
+			{Path: "src/main.go", Size: 100, Mode: 0644, SHA256: "aaaa"},
+		}
+	}
+
+	Describe("SameContents", func() {
+		It("should ignore modification times and permissions", func() {
+			previous := NewManifest("backup.tar.gz", "/src", entries())
+			current := entries()
+			current[1].ModTime = time.Now()
+			current[1].Mode = 0600
+			Expect(SameContents(previous, current)).To(BeTrue())
+		})
+
+		It("should detect changed contents", func() {
+			previous := NewManifest("backup.tar.gz", "/src", entries())
+			current := entries()
+			current[1].SHA256 = "bbbb"
+			Expect(SameContents(previous, current)).To(BeFalse())
+		})
+
+		It("should detect added and removed files", func() {
+			previous := NewManifest("backup.tar.gz", "/src", entries())
+			Expect(SameContents(previous, entries()[:1])).To(BeFalse())
+
+			renamed := entries()
+			renamed[1].Path = "src/other.go"
+			Expect(SameContents(previous, renamed)).To(BeFalse())
+		})
+
+		It("should not match manifests without content hashes", func() {
+			old := entries()
+			old[1].SHA256 = ""
+			Expect(SameContents(NewManifest("backup.tar.gz", "/src", old), entries())).To(BeFalse())
+		})
+	})
+
+	Describe("PruneSnapshots", func() {
+		writeSnapshot := func(name, archive string) string {
+			manifest := NewManifest(archive, "/src", entries())
+			manifest.MetadataOnly = true
+			path := filepath.Join(tmpDir, name+ManifestSuffix)
+			Expect(WriteManifest(path, manifest)).To(Succeed())
+			return path
+		}
+
+		It("should remove snapshots whose archive no longer exists", func() {
+			Expect(os.WriteFile(filepath.Join(tmpDir, "project-1.tar.gz"), []byte("archive"), 0644)).To(Succeed())
+			kept := writeSnapshot("project-2", "project-1.tar.gz")
+			orphan := writeSnapshot("project-3", "project-0.tar.gz")
+			archiveManifest := filepath.Join(tmpDir, "project-0"+ManifestSuffix)
+			Expect(WriteManifest(archiveManifest, NewManifest("project-0.tar.gz", "/src", entries()))).To(Succeed())
+
+			Expect(PruneSnapshots(tmpDir, RotationOptions{})).To(Succeed())
+			Expect(kept).To(BeAnExistingFile())
+			Expect(orphan).NotTo(BeAnExistingFile())
+			Expect(archiveManifest).To(BeAnExistingFile())
+		})
+
+		It("should remove snapshots of rotated backups", func() {
+			for i, name := range []string{"project-1.tar.gz", "project-2.tar.gz"} {
+				path := filepath.Join(tmpDir, name)
+				Expect(os.WriteFile(path, []byte("archive"), 0644)).To(Succeed())
+				modTime := time.Now().Add(time.Duration(i-2) * time.Hour)
+				Expect(os.Chtimes(path, modTime, modTime)).To(Succeed())
+			}
+			orphan := writeSnapshot("project-3", "project-1.tar.gz")
+
+			Expect(RotateBackups(tmpDir, "project-", 1, RotationOptions{})).To(Succeed())
+			Expect(filepath.Join(tmpDir, "project-1.tar.gz")).NotTo(BeAnExistingFile())
+			Expect(orphan).NotTo(BeAnExistingFile())
+		})
+	})
+})
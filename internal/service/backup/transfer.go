@@ -0,0 +1,113 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+)
+
+// Ways TransferFile stores a file at its destination
+const (
+	TransferCopy  = "copy"  // The contents were copied
+	TransferClone = "clone" // A copy-on-write clone (reflink) shares the blocks of the source
+	TransferMove  = "move"  // The source was renamed to the destination and is gone
+)
+
+// TransferOptions select the cheaper ways TransferFile may use instead of a copy
+type TransferOptions struct {
+	Move   bool   // Rename the source when it is on the destination's file system
+	Clone  bool   // Clone the source where the file system supports it, e.g. btrfs, XFS or APFS
+	SHA256 string // Checksum of the source; the file at the destination is checked against it
+}
+
+// partialSuffix names the file a transfer is staged in next to its destination until it is verified
+const partialSuffix = ".partial"
+
+// TransferFile stores src at dst the cheapest way opts allow: a rename, then a clone, then a
+// copy. A rename or clone whose result does not match opts.SHA256 is undone, and the file is
+// copied instead. A copy that does not match, e.g. one truncated by a full or flaky NFS or USB
+// drive, is removed and fails. The file is staged next to dst and only replaces it once verified,
+// so the previous backup of a file target survives a failed transfer. Returns the way the file
+// was stored.
+func TransferFile(src, dst string, opts TransferOptions) (string, error) {
+	partial := dst + partialSuffix
+
+	// A rename fails across file systems, e.g. from a tmpfs temp dir, and a clone or copy follows
+	if opts.Move && os.Rename(src, partial) == nil {
+		err := verifyTransfer(partial, opts.SHA256)
+		if err == nil {
+			if err := os.Rename(partial, dst); err != nil {
+				os.Rename(partial, src)
+				return "", fmt.Errorf("error moving backup into place: %w", err)
+			}
+			return TransferMove, nil
+		}
+		if undoErr := os.Rename(partial, src); undoErr != nil {
+			return "", fmt.Errorf("%w; moving it back failed: %v", err, undoErr)
+		}
+	}
+
+	if opts.Clone {
+		if err := cloneFile(src, partial); err == nil {
+			if err := verifyTransfer(partial, opts.SHA256); err == nil && os.Rename(partial, dst) == nil {
+				return TransferClone, nil
+			}
+		}
+		os.Remove(partial)
+	}
+
+	if err := copyVerified(src, dst, opts.SHA256); err != nil {
+		return TransferCopy, err
+	}
+	return TransferCopy, nil
+}
+
+// CopyFileVerified copies src to dst and reads the copy back to check it against the checksum of
+// src, so sidecar files such as manifests get the same check as moved or cloned backups. A copy
+// that does not match is removed, leaving any previous file at dst in place. Returns the checksum
+// for the target's SHA256SUMS.
+func CopyFileVerified(src, dst string) (string, error) {
+	sum, err := FileSHA256(src)
+	if err != nil {
+		return "", fmt.Errorf("error reading source file: %w", err)
+	}
+	if err := copyVerified(src, dst, sum); err != nil {
+		return "", err
+	}
+	return sum, nil
+}
+
+// copyVerified copies src next to dst, checks the copy against the checksum and renames it over dst
+func copyVerified(src, dst, sha256 string) error {
+	partial := dst + partialSuffix
+	if err := CopyFile(src, partial); err != nil {
+		os.Remove(partial)
+		return err
+	}
+	if err := verifyTransfer(partial, sha256); err != nil {
+		os.Remove(partial)
+		return fmt.Errorf("copy does not match the source and was removed: %w", err)
+	}
+	if err := os.Rename(partial, dst); err != nil {
+		os.Remove(partial)
+		return fmt.Errorf("error moving copy into place: %w", err)
+	}
+	return nil
+}
+
+// verifyTransfer checks the file against the expected checksum; an empty checksum is not checked.
+// On Linux the file's cached pages are dropped first, so a copy damaged on its way to the drive
+// is read back as stored rather than from memory.
+func verifyTransfer(path, sha256 string) error {
+	if sha256 == "" {
+		return nil
+	}
+	dropCachedPages(path)
+	sum, err := FileSHA256(path)
+	if err != nil {
+		return fmt.Errorf("error verifying %s: %w", path, err)
+	}
+	if sum != sha256 {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", path, sha256, sum)
+	}
+	return nil
+}
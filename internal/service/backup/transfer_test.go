@@ -0,0 +1,106 @@
+package backup_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/kennycyb/go-backup/internal/service/backup"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TransferFile", func() {
+	var tempDir, src, dst, sum string
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "transfer-test")
+		Expect(err).NotTo(HaveOccurred())
+		src = filepath.Join(tempDir, "archive.tar.gz")
+		dst = filepath.Join(tempDir, "target", "archive.tar.gz")
+		Expect(os.WriteFile(src, []byte("archive contents"), 0644)).To(Succeed())
+		Expect(os.Mkdir(filepath.Dir(dst), 0755)).To(Succeed())
+		sum, err = FileSHA256(src)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	It("should copy by default", func() {
+		method, err := TransferFile(src, dst, TransferOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(method).To(Equal(TransferCopy))
+		Expect(os.ReadFile(dst)).To(Equal([]byte("archive contents")))
+		Expect(src).To(BeAnExistingFile())
+	})
+
+	It("should move a file on the same file system", func() {
+		method, err := TransferFile(src, dst, TransferOptions{Move: true, SHA256: sum})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(method).To(Equal(TransferMove))
+		Expect(os.ReadFile(dst)).To(Equal([]byte("archive contents")))
+		Expect(src).NotTo(BeAnExistingFile())
+	})
+
+	It("should check a copy against the checksum", func() {
+		method, err := TransferFile(src, dst, TransferOptions{SHA256: sum})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(method).To(Equal(TransferCopy))
+		Expect(os.ReadFile(dst)).To(Equal([]byte("archive contents")))
+	})
+
+	It("should undo a move that fails verification and remove a copy that fails it too", func() {
+		method, err := TransferFile(src, dst, TransferOptions{Move: true, SHA256: "0000"})
+		Expect(err).To(MatchError(ContainSubstring("does not match the source")))
+		Expect(method).To(Equal(TransferCopy))
+		Expect(src).To(BeAnExistingFile())
+		Expect(dst).NotTo(BeAnExistingFile())
+	})
+
+	It("should keep the previous backup at the destination when a transfer fails verification", func() {
+		Expect(os.WriteFile(dst, []byte("previous backup"), 0644)).To(Succeed())
+
+		for _, opts := range []TransferOptions{
+			{SHA256: "0000"},
+			{Move: true, SHA256: "0000"},
+			{Clone: true, SHA256: "0000"},
+		} {
+			_, err := TransferFile(src, dst, opts)
+			Expect(err).To(MatchError(ContainSubstring("does not match the source")))
+			Expect(os.ReadFile(dst)).To(Equal([]byte("previous backup")))
+			Expect(dst + ".partial").NotTo(BeAnExistingFile())
+			Expect(src).To(BeAnExistingFile())
+		}
+	})
+
+	It("should clone or, where cloning is not supported, copy", func() {
+		method, err := TransferFile(src, dst, TransferOptions{Clone: true, SHA256: sum})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(method).To(BeElementOf(TransferClone, TransferCopy))
+		Expect(os.ReadFile(dst)).To(Equal([]byte("archive contents")))
+		Expect(src).To(BeAnExistingFile())
+	})
+})
+
+var _ = Describe("CopyFileVerified", func() {
+	It("should copy a file and return its checksum", func() {
+		tempDir := GinkgoT().TempDir()
+		src := filepath.Join(tempDir, "project-20250520-123045.manifest.json")
+		dst := filepath.Join(tempDir, "copy.manifest.json")
+		Expect(os.WriteFile(src, []byte(`{"files":[]}`), 0644)).To(Succeed())
+
+		sum, err := CopyFileVerified(src, dst)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(FileSHA256(src)).To(Equal(sum))
+		Expect(os.ReadFile(dst)).To(Equal([]byte(`{"files":[]}`)))
+	})
+
+	It("should fail without leaving a file when the source is missing", func() {
+		tempDir := GinkgoT().TempDir()
+		_, err := CopyFileVerified(filepath.Join(tempDir, "missing"), filepath.Join(tempDir, "copy"))
+		Expect(err).To(HaveOccurred())
+		Expect(filepath.Join(tempDir, "copy")).NotTo(BeAnExistingFile())
+	})
+})
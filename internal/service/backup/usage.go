@@ -0,0 +1,95 @@
+package backup
+
+import (
+	"sort"
+	"time"
+)
+
+// ArchiveFile is a backup archive of a target, found on it or recorded in the backup history
+type ArchiveFile struct {
+	Name    string
+	Size    int64
+	Missing bool // In the history but not found on the target; not counted in the usage
+}
+
+// SourceUsage is the space taken by the archives of one source in a target
+type SourceUsage struct {
+	Source   string
+	Archives int
+	Bytes    int64
+	Missing  int // Archives in the history that were not found on the target
+
+	// Growth is the size of the newest archive minus the size of the oldest of the last archives
+	// compared, and GrowthFrom the size of that oldest one. Growth needs two archives to compare.
+	Growth     int64
+	GrowthFrom int64
+	GrowthOver int // Number of archives compared; below 2 there is no growth
+}
+
+// Average returns the average size of the source's archives
+func (u SourceUsage) Average() int64 {
+	if u.Archives == 0 {
+		return 0
+	}
+	return u.Bytes / int64(u.Archives)
+}
+
+// GrowthPercent returns Growth as a percentage of the size it grew from, or 0 without growth
+func (u SourceUsage) GrowthPercent() float64 {
+	if u.GrowthOver < 2 || u.GrowthFrom == 0 {
+		return 0
+	}
+	return float64(u.Growth) * 100 / float64(u.GrowthFrom)
+}
+
+// SummarizeUsage groups the archives of a target by the source in their name, like
+// project-20250520-123045.tar.gz, sorted by source. Growth is measured over the last archives of
+// each source, newest first by the timestamp in their name. Names that follow neither the naming
+// nor the default naming are left out.
+func SummarizeUsage(files []ArchiveFile, naming Naming, last int) []SourceUsage {
+	type dated struct {
+		ArchiveFile
+		createdAt time.Time
+	}
+	bySource := make(map[string][]dated)
+	for _, file := range files {
+		source, createdAt, ok := naming.ParseName(file.Name)
+		if !ok {
+			source, createdAt, ok = DefaultNaming().ParseName(file.Name)
+		}
+		if ok {
+			bySource[source] = append(bySource[source], dated{file, createdAt})
+		}
+	}
+
+	usages := make([]SourceUsage, 0, len(bySource))
+	for source, archives := range bySource {
+		sort.Slice(archives, func(i, j int) bool {
+			if archives[i].createdAt.Equal(archives[j].createdAt) {
+				// Same second: the "_N" sequence suffix sorts after the plain name
+				return archives[i].Name > archives[j].Name
+			}
+			return archives[i].createdAt.After(archives[j].createdAt)
+		})
+
+		usage := SourceUsage{Source: source}
+		var present []dated
+		for _, archive := range archives {
+			if archive.Missing {
+				usage.Missing++
+				continue
+			}
+			usage.Archives++
+			usage.Bytes += archive.Size
+			present = append(present, archive)
+		}
+		if window := min(last, len(present)); window >= 2 {
+			usage.GrowthOver = window
+			usage.GrowthFrom = present[window-1].Size
+			usage.Growth = present[0].Size - usage.GrowthFrom
+		}
+		usages = append(usages, usage)
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Source < usages[j].Source })
+	return usages
+}
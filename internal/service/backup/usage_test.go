@@ -0,0 +1,50 @@
+package backup_test
+
+import (
+	. "github.com/kennycyb/go-backup/internal/service/backup"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SummarizeUsage", func() {
+	files := []ArchiveFile{
+		{Name: "app-20250520-120000.tar.gz", Size: 100},
+		{Name: "app-20250522-120000.tar.gz.gpg", Size: 150},
+		{Name: "app-20250521-120000.tar.gz", Size: 120},
+		{Name: "app-20250519-120000.tar.gz", Size: 80, Missing: true},
+		{Name: "docs-20250520-120000.tar.gz", Size: 40},
+		{Name: "notes.tar.gz", Size: 999},
+	}
+
+	It("should group the archives by source", func() {
+		usages := SummarizeUsage(files, DefaultNaming(), 10)
+		Expect(usages).To(HaveLen(2))
+
+		app := usages[0]
+		Expect(app.Source).To(Equal("app"))
+		Expect(app.Archives).To(Equal(3))
+		Expect(app.Bytes).To(Equal(int64(370)))
+		Expect(app.Average()).To(Equal(int64(123)))
+		Expect(app.Missing).To(Equal(1))
+
+		Expect(usages[1].Source).To(Equal("docs"))
+		Expect(usages[1].Bytes).To(Equal(int64(40)))
+	})
+
+	It("should measure growth over the last archives, newest first", func() {
+		app := SummarizeUsage(files, DefaultNaming(), 10)[0]
+		Expect(app.GrowthOver).To(Equal(3))
+		Expect(app.Growth).To(Equal(int64(50)))
+		Expect(app.GrowthPercent()).To(BeNumerically("~", 50.0))
+
+		app = SummarizeUsage(files, DefaultNaming(), 2)[0]
+		Expect(app.GrowthOver).To(Equal(2))
+		Expect(app.Growth).To(Equal(int64(30)))
+	})
+
+	It("should not measure growth of a single archive", func() {
+		docs := SummarizeUsage(files, DefaultNaming(), 10)[1]
+		Expect(docs.GrowthOver).To(BeZero())
+		Expect(docs.GrowthPercent()).To(BeZero())
+	})
+})
@@ -0,0 +1,135 @@
+// Package command runs external tools such as git and gpg with a time limit, so a git command
+// waiting for credentials or a hung gpg-agent cannot hold up a scheduled backup forever
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is how long a quick query, like listing keys or reading git status, may take
+const DefaultTimeout = time.Minute
+
+// waitDelay is how long output is still read after a tool that timed out was killed
+const waitDelay = 5 * time.Second
+
+// ErrTimeout is wrapped by the Error of a tool that ran longer than its timeout
+var ErrTimeout = errors.New("timed out")
+
+// Error is a tool that failed: it exited with an error, timed out or could not be started
+type Error struct {
+	Name     string   // The tool, e.g. "gpg"
+	Args     []string // Its arguments
+	ExitCode int      // Exit status of the tool, or -1 if it did not exit by itself
+	Stderr   string   // Its error output, trimmed
+	Err      error    // *exec.ExitError, an error wrapping ErrTimeout, or why it could not be started
+}
+
+// Error describes the failure followed by the tool's error output, if any
+func (e *Error) Error() string {
+	if e.Stderr == "" {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + ", details: " + e.Stderr
+}
+
+// Unwrap returns the underlying error, so errors.Is and errors.As see through to it
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Exited reports whether err is from a tool that ran and exited with an error status, e.g. gpg
+// finding no matching key, rather than one that timed out or could not be started
+func Exited(err error) bool {
+	var failure *Error
+	return errors.As(err, &failure) && failure.ExitCode > 0
+}
+
+// Options are how a tool is run
+type Options struct {
+	Timeout time.Duration // Longest the tool may run before it is killed; 0 means no limit
+	Retries int           // Times a run that timed out is tried again; only for tools without Stdin
+	Stdin   io.Reader     // Input of the tool; nil for none
+	Stdout  io.Writer     // Receives the output instead of it being returned
+	Stderr  io.Writer     // Also receives the error output, for tools that report on it
+	Env     []string      // Variables added to the environment, e.g. "GIT_TERMINAL_PROMPT=0"
+}
+
+// Runner runs external tools. Services run their tools through one, so tests can fake the
+// tools and other implementations can take their place behind the same service API.
+type Runner interface {
+	Run(opts Options, name string, args ...string) ([]byte, error)
+}
+
+// RunnerFunc lets a function be used as a Runner
+type RunnerFunc func(opts Options, name string, args ...string) ([]byte, error)
+
+// Run calls f
+func (f RunnerFunc) Run(opts Options, name string, args ...string) ([]byte, error) {
+	return f(opts, name, args...)
+}
+
+// ExecRunner is the Runner that starts the tools as processes, with Run
+type ExecRunner struct{}
+
+// Run runs the tool with Run
+func (ExecRunner) Run(opts Options, name string, args ...string) ([]byte, error) {
+	return Run(opts, name, args...)
+}
+
+// Run runs the tool and returns its output. Returns an *Error if it fails or times out; the
+// output written before that is returned too.
+func Run(opts Options, name string, args ...string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		output, err := run(opts, name, args)
+		if err == nil || !errors.Is(err, ErrTimeout) || attempt >= opts.Retries {
+			return output, err
+		}
+	}
+}
+
+// run runs the tool once
+func run(opts Options, name string, args []string) ([]byte, error) {
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = waitDelay
+	cmd.Stdin = opts.Stdin
+	if opts.Env != nil {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	if opts.Stdout != nil {
+		cmd.Stdout = opts.Stdout
+	}
+	cmd.Stderr = &stderr
+	if opts.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(&stderr, opts.Stderr)
+	}
+
+	err := cmd.Run()
+	if err == nil {
+		return stdout.Bytes(), nil
+	}
+	failure := &Error{Name: name, Args: args, ExitCode: -1, Stderr: strings.TrimSpace(stderr.String()), Err: err}
+	var exitErr *exec.ExitError
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		failure.Err = fmt.Errorf("%s %w after %s", name, ErrTimeout, opts.Timeout)
+	} else if errors.As(err, &exitErr) {
+		failure.ExitCode = exitErr.ExitCode()
+	}
+	return stdout.Bytes(), failure
+}
@@ -0,0 +1,79 @@
+package command_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kennycyb/go-backup/internal/service/command"
+)
+
+var _ = Describe("Run", func() {
+	It("should return the output of the tool", func() {
+		output, err := command.Run(command.Options{Timeout: 10 * time.Second}, "sh", "-c", "echo hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(output)).To(Equal("hello\n"))
+	})
+
+	It("should pass input and environment variables", func() {
+		output, err := command.Run(command.Options{Stdin: strings.NewReader("input"), Env: []string{"GREETING=hi"}},
+			"sh", "-c", `printf "%s " "$GREETING"; cat`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(output)).To(Equal("hi input"))
+	})
+
+	It("should return the exit status and error output of a failed tool", func() {
+		_, err := command.Run(command.Options{}, "sh", "-c", "echo 'no such key' >&2; exit 2")
+		var failure *command.Error
+		Expect(errors.As(err, &failure)).To(BeTrue())
+		Expect(failure.ExitCode).To(Equal(2))
+		Expect(command.Exited(err)).To(BeTrue())
+		Expect(failure.Stderr).To(Equal("no such key"))
+		Expect(err).To(MatchError("exit status 2, details: no such key"))
+
+		var exitErr *exec.ExitError
+		Expect(errors.As(err, &exitErr)).To(BeTrue())
+	})
+
+	It("should stop a tool that runs longer than its timeout", func() {
+		started := time.Now()
+		_, err := command.Run(command.Options{Timeout: 100 * time.Millisecond}, "sleep", "10")
+		Expect(err).To(MatchError(command.ErrTimeout))
+		Expect(err).To(MatchError(ContainSubstring("sleep timed out after 100ms")))
+		Expect(time.Since(started)).To(BeNumerically("<", 5*time.Second))
+
+		var failure *command.Error
+		Expect(errors.As(err, &failure)).To(BeTrue())
+		Expect(failure.ExitCode).To(Equal(-1))
+		Expect(command.Exited(err)).To(BeFalse())
+	})
+
+	It("should retry a tool that timed out", func() {
+		// The first attempt hangs; the second finds the marker and succeeds
+		marker := filepath.Join(GinkgoT().TempDir(), "attempted")
+		output, err := command.Run(command.Options{Timeout: 500 * time.Millisecond, Retries: 1},
+			"sh", "-c", `if [ -e "$0" ]; then echo done; else touch "$0"; exec sleep 10; fi`, marker)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(output)).To(Equal("done\n"))
+	})
+
+	It("should not retry a tool that failed", func() {
+		marker := filepath.Join(GinkgoT().TempDir(), "attempts")
+		_, err := command.Run(command.Options{Retries: 2}, "sh", "-c", `echo x >> "$0"; exit 1`, marker)
+		Expect(err).To(HaveOccurred())
+		data, err := os.ReadFile(marker)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("x\n"))
+	})
+
+	It("should report a tool that cannot be found", func() {
+		_, err := command.Run(command.Options{}, "go-backup-no-such-tool")
+		Expect(err).To(MatchError(exec.ErrNotFound))
+	})
+})
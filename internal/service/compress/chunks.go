@@ -0,0 +1,33 @@
+package compress
+
+// ArchiveChunk is a part of an archive that can be decompressed and read without the rest:
+// whole gzip members holding whole tar entries. Chunks follow each other without gaps, so
+// concatenating any of them in order gives a valid archive of their entries.
+type ArchiveChunk struct {
+	Offset  int64 `json:"offset"`  // Byte offset in the stored file
+	Size    int64 `json:"size"`    // Bytes in the stored file
+	First   int   `json:"first"`   // Index of the chunk's first entry in the archive's entries
+	Entries int   `json:"entries"` // Entries in the chunk
+}
+
+// SelectChunks returns the chunks, in order, holding the entries that are listed in paths or
+// inside a listed directory, or match one of the patterns, like ExtractOptions.Files and
+// ExtractOptions.Patterns select them.
+// Returns an error for paths that are absolute or leave the archive's root, and invalid patterns.
+func SelectChunks(chunks []ArchiveChunk, entries []ArchiveEntry, paths, patterns []string) ([]ArchiveChunk, error) {
+	selected, err := newEntrySelection(paths, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []ArchiveChunk
+	for _, chunk := range chunks {
+		for i := chunk.First; i < chunk.First+chunk.Entries && i < len(entries); i++ {
+			if selected.match(entries[i].Path) {
+				result = append(result, chunk)
+				break
+			}
+		}
+	}
+	return result, nil
+}
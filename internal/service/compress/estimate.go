@@ -0,0 +1,220 @@
+package compress
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// DefaultEstimateSampleSize is how much file content EstimateArchive reads by default
+	DefaultEstimateSampleSize int64 = 256 * MB
+
+	// estimateChunkSize is the size of each sampled block of file content
+	estimateChunkSize int64 = 1 * MB
+
+	// tarHeaderSize is the per-entry overhead of a tar archive
+	tarHeaderSize int64 = 512
+)
+
+// DefaultEstimateLevels are the gzip levels estimated when none are given: fastest, gzip's default and smallest
+var DefaultEstimateLevels = []int{gzip.BestSpeed, 6, gzip.BestCompression}
+
+// EstimateOptions controls how EstimateArchive samples the source
+type EstimateOptions struct {
+	Levels     []int // gzip levels to estimate; defaults to DefaultEstimateLevels
+	SampleSize int64 // Bytes of file content to sample; 0 uses DefaultEstimateSampleSize
+	Full       bool  // Read all file content instead of sampling
+}
+
+// ArchiveEstimate is the predicted outcome of archiving a source directory
+type ArchiveEstimate struct {
+	Files       int
+	TotalSize   int64         // Total size of the files that would be archived
+	SampledSize int64         // Bytes of file content actually read
+	ReadTime    time.Duration // Predicted time to read all files
+	Levels      []LevelEstimate
+}
+
+// LevelEstimate is the predicted archive size and duration for one gzip level
+type LevelEstimate struct {
+	Level         int
+	Ratio         float64 // Compressed size divided by original size
+	EstimatedSize int64
+	EstimatedTime time.Duration // Including the time to read the files
+}
+
+// EstimateArchive predicts the archive size and duration per gzip level without writing an archive.
+// It lists the files that would be archived, then compresses evenly spaced blocks of their
+// content (or all of it with opts.Full) with each level and extrapolates to the full size.
+// Returns an error if the source cannot be read.
+func EstimateArchive(sourceDir string, excludes []string, opts EstimateOptions) (*ArchiveEstimate, error) {
+	levels := opts.Levels
+	if len(levels) == 0 {
+		levels = DefaultEstimateLevels
+	}
+	for _, level := range levels {
+		if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+			return nil, fmt.Errorf("invalid compression level %d: %w", level, err)
+		}
+	}
+
+	type sourceFile struct {
+		path string
+		size int64
+	}
+
+	// List the files that would end up in the archive
+	matcher := NewExcludeMatcher(excludes)
+	estimate := &ArchiveEstimate{}
+	var files []sourceFile
+	err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return fmt.Errorf("error getting relative path: %w", err)
+		}
+		if relPath == "." {
+			return nil
+		}
+
+		if matcher.Excluded(relPath) {
+			if info.IsDir() && matcher.CanSkipDir(relPath) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		estimate.Files++
+		if info.Mode().IsRegular() {
+			files = append(files, sourceFile{path: path, size: info.Size()})
+			estimate.TotalSize += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Space the sampled blocks evenly over the total content
+	sampleSize := opts.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = DefaultEstimateSampleSize
+	}
+	step := estimateChunkSize
+	if !opts.Full && estimate.TotalSize > sampleSize {
+		step = estimate.TotalSize / (sampleSize / estimateChunkSize)
+	}
+
+	// Compress the samples as one stream per level, like the files in a real archive
+	counters := make([]*discardCounter, len(levels))
+	gzWriters := make([]*gzip.Writer, len(levels))
+	compressTimes := make([]time.Duration, len(levels))
+	for i, level := range levels {
+		counters[i] = &discardCounter{}
+		gzWriters[i], _ = gzip.NewWriterLevel(counters[i], level)
+	}
+
+	var readTime time.Duration
+	buf := make([]byte, estimateChunkSize)
+
+	// A sample window starts every step bytes and may run on into the following files
+	var offset, nextSample, windowLeft int64
+	for _, f := range files {
+		var pos int64
+		var file *os.File
+
+		for pos < f.size {
+			if windowLeft == 0 {
+				if nextSample >= offset+f.size {
+					break
+				}
+				if nextSample > offset+pos {
+					pos = nextSample - offset
+				}
+				windowLeft = estimateChunkSize
+				nextSample += step
+			}
+
+			if file == nil {
+				var err error
+				if file, err = os.Open(f.path); err != nil {
+					return nil, fmt.Errorf("error opening file %s: %w", f.path, err)
+				}
+			}
+
+			start := time.Now()
+			n, err := file.ReadAt(buf[:min(windowLeft, f.size-pos)], pos)
+			readTime += time.Since(start)
+			if err != nil && err != io.EOF {
+				file.Close()
+				return nil, fmt.Errorf("error reading file %s: %w", f.path, err)
+			}
+			if n == 0 {
+				break // File shrank since it was listed
+			}
+
+			for i := range gzWriters {
+				start := time.Now()
+				gzWriters[i].Write(buf[:n])
+				compressTimes[i] += time.Since(start)
+			}
+
+			estimate.SampledSize += int64(n)
+			pos += int64(n)
+			windowLeft -= int64(n)
+		}
+
+		if file != nil {
+			file.Close()
+		}
+		offset += f.size
+	}
+
+	compressedSizes := make([]int64, len(levels))
+	for i := range gzWriters {
+		start := time.Now()
+		gzWriters[i].Close()
+		compressTimes[i] += time.Since(start)
+		compressedSizes[i] = counters[i].n
+	}
+
+	// Extrapolate from the sample to the whole source, including tar headers
+	totalArchived := estimate.TotalSize + int64(estimate.Files)*tarHeaderSize
+	scale := 1.0
+	if estimate.SampledSize > 0 {
+		scale = float64(estimate.TotalSize) / float64(estimate.SampledSize)
+	}
+	estimate.ReadTime = time.Duration(float64(readTime) * scale)
+
+	for i, level := range levels {
+		ratio := 1.0
+		if estimate.SampledSize > 0 {
+			ratio = float64(compressedSizes[i]) / float64(estimate.SampledSize)
+		}
+		estimate.Levels = append(estimate.Levels, LevelEstimate{
+			Level:         level,
+			Ratio:         ratio,
+			EstimatedSize: int64(float64(totalArchived) * ratio),
+			EstimatedTime: estimate.ReadTime + time.Duration(float64(compressTimes[i])*scale),
+		})
+	}
+
+	return estimate, nil
+}
+
+// discardCounter counts the bytes written to it and discards them
+type discardCounter struct {
+	n int64
+}
+
+func (w *discardCounter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
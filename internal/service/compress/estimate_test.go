@@ -0,0 +1,96 @@
+package compress_test
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+
+	"github.com/kennycyb/go-backup/internal/service/compress"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EstimateArchive", func() {
+	var sourceDir string
+
+	writeFile := func(name string, data []byte) {
+		path := filepath.Join(sourceDir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, data, 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		sourceDir, err = os.MkdirTemp("", "estimate-test-")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(sourceDir)
+	})
+
+	It("should predict a small archive for compressible content", func() {
+		writeFile("zeros.bin", make([]byte, 4*compress.MB))
+
+		estimate, err := compress.EstimateArchive(sourceDir, nil, compress.EstimateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(estimate.TotalSize).To(Equal(4 * compress.MB))
+		Expect(estimate.Levels).To(HaveLen(len(compress.DefaultEstimateLevels)))
+		for _, level := range estimate.Levels {
+			Expect(level.Ratio).To(BeNumerically("<", 0.01))
+			Expect(level.EstimatedSize).To(BeNumerically("<", compress.MB/10))
+		}
+	})
+
+	It("should predict no savings for random content", func() {
+		data := make([]byte, 2*compress.MB)
+		_, err := rand.Read(data)
+		Expect(err).NotTo(HaveOccurred())
+		writeFile("random.bin", data)
+
+		estimate, err := compress.EstimateArchive(sourceDir, nil, compress.EstimateOptions{Levels: []int{6}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(estimate.Levels).To(HaveLen(1))
+		Expect(estimate.Levels[0].Ratio).To(BeNumerically(">", 0.99))
+		Expect(estimate.Levels[0].EstimatedSize).To(BeNumerically(">=", 2*compress.MB))
+	})
+
+	It("should only sample part of the content by default", func() {
+		writeFile("a.bin", make([]byte, 4*compress.MB))
+		writeFile("b.bin", make([]byte, 4*compress.MB))
+
+		estimate, err := compress.EstimateArchive(sourceDir, nil, compress.EstimateOptions{SampleSize: 2 * compress.MB})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(estimate.SampledSize).To(Equal(2 * compress.MB))
+
+		full, err := compress.EstimateArchive(sourceDir, nil, compress.EstimateOptions{SampleSize: 2 * compress.MB, Full: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(full.SampledSize).To(Equal(8 * compress.MB))
+	})
+
+	It("should read all content of many small files when under the sample size", func() {
+		for i := 0; i < 50; i++ {
+			writeFile(filepath.Join("docs", string(rune('a'+i%26))+string(rune('a'+i/26))+".txt"), make([]byte, 1000))
+		}
+
+		estimate, err := compress.EstimateArchive(sourceDir, nil, compress.EstimateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(estimate.SampledSize).To(Equal(int64(50 * 1000)))
+	})
+
+	It("should skip excluded paths", func() {
+		writeFile("keep.txt", []byte("keep"))
+		writeFile("node_modules/dep.js", make([]byte, compress.MB))
+
+		estimate, err := compress.EstimateArchive(sourceDir, []string{"node_modules"}, compress.EstimateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(estimate.Files).To(Equal(1))
+		Expect(estimate.TotalSize).To(Equal(int64(4)))
+	})
+
+	It("should return an error for an invalid compression level", func() {
+		_, err := compress.EstimateArchive(sourceDir, nil, compress.EstimateOptions{Levels: []int{19}})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("invalid compression level"))
+	})
+})
@@ -0,0 +1,248 @@
+package compress
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ExcludeMatcher decides which paths are excluded from a backup.
+// It is shared by the archiver and the file size checks so both agree on what is backed up.
+//
+// Patterns are matched against paths relative to the source directory:
+//   - a pattern without a slash (e.g. "node_modules", "*.iso") matches any file or
+//     directory with that name at any depth
+//   - a pattern with a slash (e.g. "build/cache", "docs/*.pdf", "/TODO") is matched from the
+//     source root; "**" matches any number of directories (e.g. "**/.git/**")
+//   - a trailing slash is ignored, and excluding a directory excludes everything inside it
+//   - a pattern starting with "!" re-includes paths excluded by earlier patterns
+//     (e.g. "node_modules/**" followed by "!node_modules/.keep")
+//
+// Patterns are applied in order and the last matching pattern wins.
+type ExcludeMatcher struct {
+	rules       []excludeRule
+	hasNegation bool
+}
+
+// excludeRule is a single parsed exclude pattern
+type excludeRule struct {
+	segments []string // Pattern split on "/"
+	anchored bool     // Pattern contains a slash and is matched from the source root
+	negate   bool     // Pattern started with "!"
+}
+
+// NewExcludeMatcher parses the exclude patterns. Invalid patterns never match;
+// use ValidateExcludes to report them.
+func NewExcludeMatcher(patterns []string) *ExcludeMatcher {
+	m := &ExcludeMatcher{}
+	for _, pattern := range patterns {
+		rule, ok := parseExcludePattern(pattern)
+		if !ok {
+			continue
+		}
+		m.rules = append(m.rules, rule)
+		m.hasNegation = m.hasNegation || rule.negate
+	}
+	return m
+}
+
+// ValidateExcludes checks the exclude patterns for syntax errors, such as an unterminated
+// character class or a negation without a pattern.
+// Returns an error describing the first invalid pattern.
+func ValidateExcludes(patterns []string) error {
+	for _, pattern := range patterns {
+		rule, ok := parseExcludePattern(pattern)
+		if !ok {
+			return fmt.Errorf("invalid exclude pattern %q: pattern is empty", pattern)
+		}
+		for _, segment := range rule.segments {
+			if _, err := filepath.Match(segment, ""); err != nil {
+				return fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parseExcludePattern parses a single pattern; ok is false for empty patterns
+func parseExcludePattern(pattern string) (excludeRule, bool) {
+	rule := excludeRule{}
+	pattern = strings.TrimSpace(pattern)
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	}
+
+	pattern = filepath.ToSlash(pattern)
+	pattern = strings.TrimPrefix(pattern, "./")
+	leadingSlash := strings.HasPrefix(pattern, "/")
+	pattern = strings.Trim(pattern, "/")
+	if pattern == "" {
+		return rule, false
+	}
+
+	rule.anchored = leadingSlash || strings.Contains(pattern, "/")
+	rule.segments = strings.Split(pattern, "/")
+	return rule, true
+}
+
+// IncludeOnly returns exclude patterns that back up only the included paths, relative to the
+// source root, minus what the excludes remove from them (e.g. caches inside an included directory).
+// Without includes the excludes are returned unchanged.
+func IncludeOnly(includes, excludes []string) []string {
+	if len(includes) == 0 {
+		return excludes
+	}
+
+	patterns := []string{"*"}
+	for _, include := range includes {
+		patterns = append(patterns, "!/"+strings.TrimPrefix(filepath.ToSlash(include), "/"))
+	}
+	return append(patterns, excludes...)
+}
+
+// WithoutHidden returns exclude patterns that leave out every hidden file and directory, whose
+// name starts with a dot, except the paths matched by the allow patterns. The excludes follow,
+// so they still remove allowed paths.
+func WithoutHidden(excludes, allow []string) []string {
+	patterns := []string{".*"}
+	for _, pattern := range allow {
+		patterns = append(patterns, "!"+pattern)
+	}
+	return append(patterns, excludes...)
+}
+
+// VCSDirs are the version control directories that the default excludes leave out
+var VCSDirs = []string{".git", ".hg", ".svn"}
+
+// WithoutVCS returns the exclude patterns without those that exclude a version control directory,
+// such as ".git", "/.hg" or "**/.svn/**", so repositories are archived with their full history.
+// Patterns that only match part of a repository (e.g. ".git/lfs") are kept.
+func WithoutVCS(patterns []string) []string {
+	kept := []string{}
+	for _, pattern := range patterns {
+		rule, ok := parseExcludePattern(pattern)
+		if ok && !rule.negate && isVCSRule(rule) {
+			continue
+		}
+		kept = append(kept, pattern)
+	}
+	return kept
+}
+
+// isVCSRule reports whether the rule names a version control directory, with nothing but "**" around it
+func isVCSRule(rule excludeRule) bool {
+	named := false
+	for _, segment := range rule.segments {
+		switch {
+		case segment == "**":
+		case !named && isVCSDir(segment):
+			named = true
+		default:
+			return false
+		}
+	}
+	return named
+}
+
+// isVCSDir reports whether the name is one of VCSDirs
+func isVCSDir(name string) bool {
+	for _, dir := range VCSDirs {
+		if name == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// Excluded reports whether the path, relative to the source directory, is excluded
+func (m *ExcludeMatcher) Excluded(relPath string) bool {
+	segments := strings.Split(filepath.ToSlash(relPath), "/")
+
+	excluded := false
+	for _, rule := range m.rules {
+		if rule.matches(segments) {
+			excluded = !rule.negate
+		}
+	}
+	return excluded
+}
+
+// CanSkipDir reports whether an excluded directory can be skipped without looking inside it.
+// This is false when a negation pattern could re-include something below the directory.
+func (m *ExcludeMatcher) CanSkipDir(relPath string) bool {
+	if !m.hasNegation {
+		return true
+	}
+
+	segments := strings.Split(filepath.ToSlash(relPath), "/")
+	for _, rule := range m.rules {
+		if !rule.negate {
+			continue
+		}
+		if !rule.anchored || couldMatchBelow(rule.segments, segments) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether the rule matches the path or one of its parent directories
+func (r excludeRule) matches(path []string) bool {
+	if !r.anchored {
+		for _, segment := range path {
+			if ok, _ := filepath.Match(r.segments[0], segment); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := 1; i <= len(path); i++ {
+		if matchSegments(r.segments, path[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments reports whether the path segments fully match the pattern segments
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(path) == 0 {
+		return false
+	}
+	if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], path[1:])
+}
+
+// couldMatchBelow reports whether the pattern could match a path inside the directory
+func couldMatchBelow(pattern, dir []string) bool {
+	if len(dir) == 0 {
+		return len(pattern) > 0
+	}
+	if len(pattern) == 0 {
+		return false
+	}
+	if pattern[0] == "**" {
+		return true
+	}
+	if ok, _ := filepath.Match(pattern[0], dir[0]); !ok {
+		return false
+	}
+	return couldMatchBelow(pattern[1:], dir[1:])
+}
@@ -0,0 +1,119 @@
+package compress_test
+
+import (
+	"github.com/kennycyb/go-backup/internal/service/compress"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ExcludeMatcher", func() {
+	DescribeTable("matching paths against exclude patterns",
+		func(path string, excludes []string, expected bool) {
+			Expect(compress.NewExcludeMatcher(excludes).Excluded(path)).To(Equal(expected),
+				"Path %s with excludes %v should return %v", path, excludes, expected)
+		},
+		Entry("Name at any depth", "a/b/node_modules/pkg.js", []string{"node_modules"}, true),
+		Entry("Basename glob", "photos/big.iso", []string{"*.iso"}, true),
+		Entry("Anchored pattern", "build/cache/x", []string{"build/cache"}, true),
+		Entry("Anchored pattern at depth", "src/build/cache/x", []string{"build/cache"}, false),
+		Entry("Trailing slash", "cache/x", []string{"cache/"}, true),
+		Entry("Double star prefix", "a/b/.git/config", []string{"**/.git/**"}, true),
+		Entry("Double star suffix", "node_modules/a/b/c.js", []string{"node_modules/**"}, true),
+		Entry("Partial name does not match", "cabinet.txt", []string{"bin"}, false),
+		Entry("Negated file", "node_modules/.keep", []string{"node_modules/**", "!node_modules/.keep"}, false),
+		Entry("Sibling of negated file", "node_modules/pkg.js", []string{"node_modules/**", "!node_modules/.keep"}, true),
+		Entry("Negated basename", "isos/important.iso", []string{"*.iso", "!important.iso"}, false),
+		Entry("Last match wins", "isos/important.iso", []string{"!important.iso", "*.iso"}, true),
+		Entry("Negation without earlier match", "file.txt", []string{"!file.txt"}, false),
+		Entry("Leading slash anchors", "TODO", []string{"/TODO"}, true),
+		Entry("Leading slash at depth", "docs/TODO", []string{"/TODO"}, false),
+	)
+
+	Describe("CanSkipDir", func() {
+		It("should allow skipping when there are no negations", func() {
+			Expect(compress.NewExcludeMatcher([]string{"node_modules"}).CanSkipDir("node_modules")).To(BeTrue())
+		})
+
+		It("should not skip a directory containing a negated path", func() {
+			matcher := compress.NewExcludeMatcher([]string{"node_modules", "!node_modules/.keep"})
+			Expect(matcher.CanSkipDir("node_modules")).To(BeFalse())
+			Expect(matcher.CanSkipDir("vendor")).To(BeTrue())
+		})
+
+		It("should not skip any directory for an unanchored negation", func() {
+			matcher := compress.NewExcludeMatcher([]string{"build", "!important.iso"})
+			Expect(matcher.CanSkipDir("build")).To(BeFalse())
+		})
+	})
+
+	Describe("IncludeOnly", func() {
+		matcher := compress.NewExcludeMatcher(compress.IncludeOnly([]string{".bashrc", ".config"}, []string{"google-chrome"}))
+
+		DescribeTable("backing up only the included paths",
+			func(path string, expected bool) {
+				Expect(matcher.Excluded(path)).To(Equal(expected), "Path %s should be excluded: %v", path, expected)
+			},
+			Entry("Included file", ".bashrc", false),
+			Entry("Included directory", ".config", false),
+			Entry("File in an included directory", ".config/git/config", false),
+			Entry("Excluded inside an included directory", ".config/google-chrome/Default/History", true),
+			Entry("Not included", "Downloads/movie.mp4", true),
+			Entry("Included name at depth", "projects/.bashrc", true),
+		)
+
+		It("should skip directories that contain nothing included", func() {
+			Expect(matcher.CanSkipDir("Downloads")).To(BeTrue())
+		})
+
+		It("should return the excludes unchanged without includes", func() {
+			Expect(compress.IncludeOnly(nil, []string{"node_modules"})).To(Equal([]string{"node_modules"}))
+		})
+	})
+
+	Describe("WithoutHidden", func() {
+		matcher := compress.NewExcludeMatcher(compress.WithoutHidden([]string{"*.log", "config/.env.example"}, []string{".env.example", ".github/workflows"}))
+
+		DescribeTable("leaving out hidden files except the allowed ones",
+			func(path string, expected bool) {
+				Expect(matcher.Excluded(path)).To(Equal(expected), "Path %s should be excluded: %v", path, expected)
+			},
+			Entry("Hidden file", ".env", true),
+			Entry("Hidden directory", ".ssh", true),
+			Entry("File in a hidden directory", ".ssh/id_ed25519", true),
+			Entry("Hidden file at depth", "src/.idea/workspace.xml", true),
+			Entry("Visible file", "src/main.go", false),
+			Entry("Allowed file", ".env.example", false),
+			Entry("Allowed file at depth", "web/.env.example", false),
+			Entry("Allowed directory", ".github/workflows/ci.yml", false),
+			Entry("Allowed but excluded", "config/.env.example", true),
+			Entry("Visible but excluded", "app.log", true),
+		)
+	})
+
+	Describe("WithoutVCS", func() {
+		It("should drop patterns that exclude version control directories", func() {
+			Expect(compress.WithoutVCS([]string{".git", "node_modules", "/.hg/", "**/.svn/**", "!.git"})).To(
+				Equal([]string{"node_modules", "!.git"}))
+		})
+
+		It("should keep patterns that only match part of a repository", func() {
+			Expect(compress.WithoutVCS([]string{".git/lfs", "*.git", "src/.git"})).To(Equal([]string{".git/lfs", "*.git", "src/.git"}))
+		})
+	})
+
+	Describe("ValidateExcludes", func() {
+		It("should accept valid patterns", func() {
+			Expect(compress.ValidateExcludes([]string{"node_modules", "**/.git/**", "!keep.txt", "*.[ch]"})).To(Succeed())
+		})
+
+		It("should reject malformed globs", func() {
+			err := compress.ValidateExcludes([]string{"docs/[abc"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("docs/[abc"))
+		})
+
+		It("should reject a negation without a pattern", func() {
+			Expect(compress.ValidateExcludes([]string{"!"})).NotTo(Succeed())
+		})
+	})
+})
@@ -0,0 +1,404 @@
+package compress
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ExtractOptions controls how ExtractTarGzArchive writes files
+type ExtractOptions struct {
+	Overwrite bool // Replace existing files; otherwise they are left alone and reported as skipped
+	Salvage   bool // Keep what was extracted before a damaged part of the archive instead of failing
+	// Files, when not nil, are the only paths extracted, relative to the archive's root;
+	// a listed directory is extracted with its contents
+	Files []string
+	// Patterns, when not nil, extract the entries they match, with the syntax of excludes
+	// (e.g. "src/**" or "*.go"); together with Files, entries picked by either are extracted
+	Patterns []string
+	// SkipAttributes leaves out capabilities and chattr flags, e.g. for a copy that is removed
+	// again, which an immutable file would prevent
+	SkipAttributes bool
+}
+
+// ExtractResult describes what ExtractTarGzArchive restored
+type ExtractResult struct {
+	Restored []string  // Paths restored, relative to the target directory with forward slashes
+	Skipped  []string  // Paths left alone: existing ones when Overwrite is not set, and unsupported entry types
+	Damaged  string    // Entry being read when the archive turned out damaged; removed since it is incomplete
+	Damage   error     // Why a salvage extraction stopped early; nil if the whole archive was read
+	Missing  []string  // Paths of opts.Files that are not in the archive in the order listed, then patterns that matched nothing
+	Warnings []Warning // Paths restored without their capabilities or chattr flags, e.g. when not run as root
+}
+
+// archiveReadError marks errors reading the archive, as opposed to writing the extracted files
+type archiveReadError struct{ err error }
+
+func (e *archiveReadError) Error() string { return e.err.Error() }
+func (e *archiveReadError) Unwrap() error { return e.err }
+
+// damagedReader wraps the archive contents so read errors can be told apart from write errors
+type damagedReader struct{ r io.Reader }
+
+func (d *damagedReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = &archiveReadError{err: err}
+	}
+	return n, err
+}
+
+// dirTimes remembers extracted directories, whose mode, time and attributes are set once their contents exist
+type dirTimes struct {
+	path    string
+	name    string
+	mode    os.FileMode
+	modTime time.Time
+	header  *tar.Header // Set when the directory's capabilities and chattr flags are restored
+}
+
+// ExtractTarGzArchive extracts an archive into the target directory, restoring file modes,
+// modification times, symlinks, hard links and, on Linux, capabilities and chattr flags. Entries
+// that would end up outside the target directory are refused. Besides tar.gz, plain tar, tar.bz2
+// and zip archives are extracted, including those made by other tools (see openArchive).
+//
+// A truncated or corrupted archive normally fails the extraction. With opts.Salvage the entries
+// read before the damage are kept: the entry being read when it was found is removed, since its
+// contents are incomplete, and the result records the damage instead of returning an error.
+func ExtractTarGzArchive(archiveFile, targetDir string, opts ExtractOptions) (*ExtractResult, error) {
+	file, err := os.Open(archiveFile)
+	if err != nil {
+		return nil, fmt.Errorf("error opening archive: %w", err)
+	}
+	defer file.Close()
+
+	selected, err := newEntrySelection(opts.Files, opts.Patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ExtractResult{}
+	damaged := func(entry string, err error) (*ExtractResult, error) {
+		if !opts.Salvage {
+			if entry != "" {
+				return nil, fmt.Errorf("archive is damaged at %s: %w", entry, err)
+			}
+			return nil, fmt.Errorf("archive is damaged: %w", err)
+		}
+		result.Damaged = entry
+		result.Damage = err
+		return result, nil
+	}
+
+	tarReader, err := openArchive(file, func(r io.Reader) io.Reader { return &damagedReader{r: r} })
+	if errors.Is(err, ErrUnsupportedArchive) {
+		return nil, err
+	}
+	if err != nil {
+		return damaged("", err)
+	}
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating target directory: %w", err)
+	}
+
+	var dirs []dirTimes
+	restoredFiles := make(map[string]bool) // Regular files extracted so far, which hard links may refer to
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			result.Warnings = append(result.Warnings, setDirTimes(dirs)...)
+			return damaged("", fmt.Errorf("error reading tar archive: %w", err))
+		}
+
+		name := filepath.ToSlash(filepath.Clean(header.Name))
+		if !selected.match(name) {
+			continue
+		}
+		path, err := extractPath(targetDir, header.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		var restored bool
+		if header.Typeflag == tar.TypeLink {
+			restored, err = extractHardLink(targetDir, header, path, opts.Overwrite, restoredFiles)
+		} else {
+			restored, err = extractEntry(tarReader, header, path, opts.Overwrite)
+		}
+		var readErr *archiveReadError
+		if errors.As(err, &readErr) {
+			os.Remove(path)
+			result.Warnings = append(result.Warnings, setDirTimes(dirs)...)
+			return damaged(name, readErr.err)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case !restored:
+			result.Skipped = append(result.Skipped, name)
+		case header.Typeflag == tar.TypeDir:
+			dir := dirTimes{path: path, name: name, mode: header.FileInfo().Mode().Perm(), modTime: header.ModTime}
+			if !opts.SkipAttributes {
+				dir.header = header
+			}
+			dirs = append(dirs, dir)
+			result.Restored = append(result.Restored, name)
+		default:
+			// Attributes come last, since an immutable file cannot have its time set
+			if header.Typeflag == tar.TypeReg && !opts.SkipAttributes {
+				if err := applyFileAttrs(header, path); err != nil {
+					result.Warnings = append(result.Warnings, Warning{Kind: WarningAttributes, Path: name, Message: err.Error()})
+				}
+			}
+			if header.Typeflag == tar.TypeReg {
+				restoredFiles[name] = true
+			}
+			result.Restored = append(result.Restored, name)
+		}
+	}
+
+	result.Warnings = append(result.Warnings, setDirTimes(dirs)...)
+	result.Missing = selected.missing(opts.Files)
+	return result, nil
+}
+
+// fileSelection holds the paths of ExtractOptions.Files and whether an entry in the archive matched
+// each of them; a nil selection matches every entry
+type fileSelection map[string]bool
+
+// newFileSelection cleans the listed paths, e.g. "./docs/" becomes "docs".
+// Returns an error for paths that are absolute or leave the archive's root.
+func newFileSelection(paths []string) (fileSelection, error) {
+	if paths == nil {
+		return nil, nil
+	}
+	selection := make(fileSelection, len(paths))
+	for _, listed := range paths {
+		name, err := cleanListedPath(listed)
+		if err != nil {
+			return nil, err
+		}
+		selection[name] = false
+	}
+	return selection, nil
+}
+
+// cleanListedPath returns a listed path the way names are compared with the archive's entries
+func cleanListedPath(listed string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(listed))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("listed path %s is not relative to the archive's root", listed)
+	}
+	return filepath.ToSlash(clean), nil
+}
+
+// match reports whether an entry is a listed path or inside a listed directory, and marks it found
+func (s fileSelection) match(name string) bool {
+	if s == nil {
+		return true
+	}
+	if _, ok := s["."]; ok {
+		s["."] = true
+		return true
+	}
+	for parent := name; ; {
+		if _, ok := s[parent]; ok {
+			s[parent] = true
+			return true
+		}
+		i := strings.LastIndex(parent, "/")
+		if i < 0 {
+			return false
+		}
+		parent = parent[:i]
+	}
+}
+
+// missing returns the listed paths that matched no entry
+func (s fileSelection) missing(paths []string) []string {
+	var missing []string
+	for _, listed := range paths {
+		if name, err := cleanListedPath(listed); err == nil && !s[name] {
+			missing = append(missing, listed)
+			s[name] = true // Report duplicates once
+		}
+	}
+	return missing
+}
+
+// extractPath returns where an entry is extracted, refusing names that leave the target
+// directory and paths that pass through a symlink, which an earlier entry could have planted
+func extractPath(targetDir, name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing to extract %s: path is outside the target directory", name)
+	}
+
+	dir := targetDir
+	parts := strings.Split(clean, string(filepath.Separator))
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("refusing to extract %s: path goes through the symlink %s", name, dir)
+		}
+	}
+	return filepath.Join(targetDir, clean), nil
+}
+
+// extractEntry writes a single entry. Returns false if the path exists and overwrite is not set.
+// Other entry types than directories, regular files and symlinks are ignored.
+func extractEntry(tarReader io.Reader, header *tar.Header, path string, overwrite bool) (bool, error) {
+	mode := header.FileInfo().Mode()
+	existing, err := os.Lstat(path)
+	exists := err == nil
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if exists && existing.IsDir() {
+			return true, nil
+		}
+		if exists && !overwrite {
+			return false, nil
+		}
+		if exists {
+			if err := os.Remove(path); err != nil {
+				return false, fmt.Errorf("error replacing %s: %w", path, err)
+			}
+		}
+		// Keep the directory writable until its contents are extracted
+		if err := os.MkdirAll(path, mode.Perm()|0700); err != nil {
+			return false, fmt.Errorf("error creating directory %s: %w", path, err)
+		}
+		return true, nil
+
+	case tar.TypeReg:
+		if exists && !overwrite {
+			return false, nil
+		}
+		if err := replaceable(path, existing, exists); err != nil {
+			return false, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return false, fmt.Errorf("error creating directory for %s: %w", path, err)
+		}
+		out, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm())
+		if err != nil {
+			return false, fmt.Errorf("error creating %s: %w", path, err)
+		}
+		if _, err := io.Copy(out, tarReader); err != nil {
+			out.Close()
+			// The tar reader reports an archive that ends inside the file as an unexpected EOF
+			var readErr *archiveReadError
+			if errors.As(err, &readErr) {
+				return false, err
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return false, &archiveReadError{err: err}
+			}
+			return false, fmt.Errorf("error writing %s: %w", path, err)
+		}
+		if err := out.Close(); err != nil {
+			return false, fmt.Errorf("error writing %s: %w", path, err)
+		}
+		if err := os.Chmod(path, mode.Perm()); err != nil {
+			return false, fmt.Errorf("error setting the mode of %s: %w", path, err)
+		}
+		os.Chtimes(path, header.ModTime, header.ModTime)
+		return true, nil
+
+	case tar.TypeSymlink:
+		if exists && !overwrite {
+			return false, nil
+		}
+		if err := replaceable(path, existing, exists); err != nil {
+			return false, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return false, fmt.Errorf("error creating directory for %s: %w", path, err)
+		}
+		if err := os.Symlink(header.Linkname, path); err != nil {
+			return false, fmt.Errorf("error creating symlink %s: %w", path, err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// extractHardLink links an entry to the file it shares its contents with, as tar and borg store a
+// file with several names once. Returns false if the path exists and overwrite is not set, or if
+// that file was not extracted in this run, e.g. because the selection left it out.
+func extractHardLink(targetDir string, header *tar.Header, path string, overwrite bool, restoredFiles map[string]bool) (bool, error) {
+	if !restoredFiles[header.Linkname] {
+		return false, nil
+	}
+	target, err := extractPath(targetDir, header.Linkname)
+	if err != nil {
+		return false, err
+	}
+	existing, err := os.Lstat(path)
+	exists := err == nil
+	if exists && !overwrite {
+		return false, nil
+	}
+	if err := replaceable(path, existing, exists); err != nil {
+		return false, err
+	}
+	if exists && existing.Mode().IsRegular() {
+		if err := os.Remove(path); err != nil {
+			return false, fmt.Errorf("error replacing %s: %w", path, err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	if err := os.Link(target, path); err != nil {
+		return false, fmt.Errorf("error creating hard link %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// replaceable removes an existing file or symlink so it can be written again.
+// Symlinks are always removed rather than written through; directories are never replaced by files.
+func replaceable(path string, existing os.FileInfo, exists bool) error {
+	if !exists {
+		return nil
+	}
+	if existing.IsDir() {
+		return fmt.Errorf("error restoring %s: a directory is in the way", path)
+	}
+	if existing.Mode()&os.ModeSymlink != 0 || !existing.Mode().IsRegular() {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("error replacing %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// setDirTimes applies the archived mode, time and attributes to the extracted directories, deepest
+// first, since creating files inside a directory changes its modification time.
+// Returns a warning for each directory whose attributes could not be set.
+func setDirTimes(dirs []dirTimes) []Warning {
+	var warnings []Warning
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Chmod(dirs[i].path, dirs[i].mode)
+		os.Chtimes(dirs[i].path, dirs[i].modTime, dirs[i].modTime)
+		if dirs[i].header == nil {
+			continue
+		}
+		if err := applyFileAttrs(dirs[i].header, dirs[i].path); err != nil {
+			warnings = append(warnings, Warning{Kind: WarningAttributes, Path: dirs[i].name, Message: err.Error()})
+		}
+	}
+	return warnings
+}
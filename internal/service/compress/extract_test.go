@@ -0,0 +1,240 @@
+package compress_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kennycyb/go-backup/internal/service/compress"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ExtractTarGzArchive", func() {
+	var (
+		sourceDir  string
+		outputDir  string
+		restoreDir string
+		archive    string
+	)
+
+	BeforeEach(func() {
+		var err error
+		sourceDir, err = os.MkdirTemp("", "extract-test-src")
+		Expect(err).NotTo(HaveOccurred())
+		outputDir, err = os.MkdirTemp("", "extract-test-out")
+		Expect(err).NotTo(HaveOccurred())
+		restoreDir = filepath.Join(outputDir, "restored")
+		archive = filepath.Join(outputDir, "backup.tar.gz")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(sourceDir)
+		os.RemoveAll(outputDir)
+	})
+
+	It("should restore files, directories and symlinks", func() {
+		Expect(os.MkdirAll(filepath.Join(sourceDir, "docs", "notes"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sourceDir, "docs", "notes", "todo.txt"), []byte("todo"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sourceDir, "run.sh"), []byte("#!/bin/sh"), 0755)).To(Succeed())
+		Expect(os.Symlink("docs/notes/todo.txt", filepath.Join(sourceDir, "todo"))).To(Succeed())
+		Expect(compress.CreateTarGzArchive(sourceDir, archive, nil)).To(Succeed())
+
+		result, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Restored).To(ConsistOf("docs", "docs/notes", "docs/notes/todo.txt", "run.sh", "todo"))
+		Expect(result.Damage).NotTo(HaveOccurred())
+
+		data, err := os.ReadFile(filepath.Join(restoreDir, "docs", "notes", "todo.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("todo"))
+
+		info, err := os.Stat(filepath.Join(restoreDir, "run.sh"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+
+		link, err := os.Readlink(filepath.Join(restoreDir, "todo"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(link).To(Equal("docs/notes/todo.txt"))
+	})
+
+	It("should restore modification times of files and directories", func() {
+		modTime := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+		Expect(os.MkdirAll(filepath.Join(sourceDir, "docs"), 0750)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sourceDir, "docs", "readme.txt"), []byte("readme"), 0640)).To(Succeed())
+		Expect(os.Chtimes(filepath.Join(sourceDir, "docs", "readme.txt"), modTime, modTime)).To(Succeed())
+		Expect(os.Chtimes(filepath.Join(sourceDir, "docs"), modTime, modTime)).To(Succeed())
+		Expect(compress.CreateTarGzArchive(sourceDir, archive, nil)).To(Succeed())
+
+		_, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		for path, mode := range map[string]os.FileMode{"docs": 0750, "docs/readme.txt": 0640} {
+			info, err := os.Stat(filepath.Join(restoreDir, path))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.ModTime().Equal(modTime)).To(BeTrue(), path)
+			Expect(info.Mode().Perm()).To(Equal(mode), path)
+		}
+	})
+
+	It("should leave existing files alone unless overwrite is set", func() {
+		Expect(os.WriteFile(filepath.Join(sourceDir, "a.txt"), []byte("archived"), 0644)).To(Succeed())
+		Expect(compress.CreateTarGzArchive(sourceDir, archive, nil)).To(Succeed())
+		Expect(os.MkdirAll(restoreDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(restoreDir, "a.txt"), []byte("local"), 0644)).To(Succeed())
+
+		result, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Skipped).To(Equal([]string{"a.txt"}))
+		data, _ := os.ReadFile(filepath.Join(restoreDir, "a.txt"))
+		Expect(string(data)).To(Equal("local"))
+
+		result, err = compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{Overwrite: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Restored).To(Equal([]string{"a.txt"}))
+		data, _ = os.ReadFile(filepath.Join(restoreDir, "a.txt"))
+		Expect(string(data)).To(Equal("archived"))
+	})
+
+	Context("with a file list", func() {
+		BeforeEach(func() {
+			Expect(os.MkdirAll(filepath.Join(sourceDir, "docs", "notes"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sourceDir, "docs", "notes", "todo.txt"), []byte("todo"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sourceDir, "docs", "index.md"), []byte("index"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sourceDir, "run.sh"), []byte("#!/bin/sh"), 0755)).To(Succeed())
+			Expect(compress.CreateTarGzArchive(sourceDir, archive, nil)).To(Succeed())
+		})
+
+		It("should restore only the listed files", func() {
+			result, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{Files: []string{"./docs/index.md"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Restored).To(ConsistOf("docs/index.md"))
+			Expect(result.Missing).To(BeEmpty())
+			Expect(filepath.Join(restoreDir, "docs", "index.md")).To(BeAnExistingFile())
+			Expect(filepath.Join(restoreDir, "run.sh")).NotTo(BeAnExistingFile())
+		})
+
+		It("should restore a listed directory with its contents", func() {
+			result, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{Files: []string{"docs/notes/"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Restored).To(ConsistOf("docs/notes", "docs/notes/todo.txt"))
+		})
+
+		It("should report listed paths that are not in the archive", func() {
+			result, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{Files: []string{"run.sh", "gone.txt", "docs/gone"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Restored).To(ConsistOf("run.sh"))
+			Expect(result.Missing).To(Equal([]string{"gone.txt", "docs/gone"}))
+		})
+
+		It("should refuse listed paths outside the archive's root", func() {
+			_, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{Files: []string{"../etc/passwd"}})
+			Expect(err).To(MatchError(ContainSubstring("not relative to the archive's root")))
+		})
+	})
+
+	Context("with patterns", func() {
+		BeforeEach(func() {
+			Expect(os.MkdirAll(filepath.Join(sourceDir, "src", "vendor"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sourceDir, "src", "main.go"), []byte("package main"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sourceDir, "src", "vendor", "lib.go"), []byte("package lib"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sourceDir, "tool.go"), []byte("package tool"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sourceDir, "README.md"), []byte("readme"), 0644)).To(Succeed())
+			Expect(compress.CreateTarGzArchive(sourceDir, archive, nil)).To(Succeed())
+		})
+
+		It("should restore only the entries under a directory pattern", func() {
+			result, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{Patterns: []string{"/src/**"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Restored).To(ConsistOf("src", "src/main.go", "src/vendor", "src/vendor/lib.go"))
+			Expect(filepath.Join(restoreDir, "src", "main.go")).To(BeAnExistingFile())
+			Expect(filepath.Join(restoreDir, "README.md")).NotTo(BeAnExistingFile())
+		})
+
+		It("should match an unanchored pattern at any depth and honor negations", func() {
+			result, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{Patterns: []string{"*.go", "!src/vendor/**"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Restored).To(ConsistOf("src/main.go", "tool.go"))
+			Expect(result.Missing).To(BeEmpty())
+		})
+
+		It("should restore entries picked by either a listed path or a pattern", func() {
+			result, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{Files: []string{"README.md"}, Patterns: []string{"/tool.go", "*.txt"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Restored).To(ConsistOf("README.md", "tool.go"))
+			Expect(result.Missing).To(Equal([]string{"*.txt"}))
+		})
+
+		It("should refuse an invalid pattern", func() {
+			_, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{Patterns: []string{"src/[b"}})
+			Expect(err).To(MatchError(ContainSubstring("invalid pattern")))
+		})
+	})
+
+	It("should refuse entries outside the target directory", func() {
+		file, err := os.Create(archive)
+		Expect(err).NotTo(HaveOccurred())
+		gzWriter := gzip.NewWriter(file)
+		tarWriter := tar.NewWriter(gzWriter)
+		Expect(tarWriter.WriteHeader(&tar.Header{Name: "../escape.txt", Mode: 0644, Size: 4, Typeflag: tar.TypeReg})).To(Succeed())
+		_, err = tarWriter.Write([]byte("evil"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tarWriter.Close()).To(Succeed())
+		Expect(gzWriter.Close()).To(Succeed())
+		Expect(file.Close()).To(Succeed())
+
+		_, err = compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{Salvage: true})
+		Expect(err).To(MatchError(ContainSubstring("outside the target directory")))
+		Expect(filepath.Join(outputDir, "escape.txt")).NotTo(BeAnExistingFile())
+	})
+
+	Context("with a truncated archive", func() {
+		BeforeEach(func() {
+			random := make([]byte, 512*1024)
+			_, err := rand.Read(random)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(sourceDir, "a.txt"), []byte("intact"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sourceDir, "b.bin"), random, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sourceDir, "c.txt"), []byte("lost"), 0644)).To(Succeed())
+			Expect(compress.CreateTarGzArchive(sourceDir, archive, nil)).To(Succeed())
+
+			info, err := os.Stat(archive)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Truncate(archive, info.Size()/2)).To(Succeed())
+		})
+
+		It("should fail without salvage", func() {
+			_, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{})
+			Expect(err).To(MatchError(ContainSubstring("archive is damaged at b.bin")))
+		})
+
+		It("should keep the entries before the damage with salvage", func() {
+			result, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{Salvage: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Restored).To(Equal([]string{"a.txt"}))
+			Expect(result.Damaged).To(Equal("b.bin"))
+			Expect(result.Damage).To(HaveOccurred())
+
+			Expect(filepath.Join(restoreDir, "a.txt")).To(BeARegularFile())
+			Expect(filepath.Join(restoreDir, "b.bin")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(restoreDir, "c.txt")).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Context("with a corrupted gzip header", func() {
+		It("should report the damage with salvage", func() {
+			Expect(os.WriteFile(archive, []byte("not a gzip stream"), 0644)).To(Succeed())
+
+			_, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{})
+			Expect(err).To(HaveOccurred())
+
+			result, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{Salvage: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Restored).To(BeEmpty())
+			Expect(result.Damage).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,54 @@
+package compress
+
+import "strings"
+
+// PAX records holding Linux file attributes. Capabilities use the record GNU tar writes with
+// --xattrs, so tar can restore them as well; chattr flags have no common record.
+const (
+	paxCapability = "SCHILY.xattr.security.capability"
+	paxFileFlags  = "GOBACKUP.chattr"
+)
+
+// fileFlags are the chattr flags kept in backups, by their letter in lsattr output. Flags that
+// the file system manages itself, such as extents (e), are left out.
+var fileFlags = []struct {
+	letter byte
+	flag   uint32
+}{
+	{'s', 0x00000001}, // Secure deletion
+	{'u', 0x00000002}, // Undeletable
+	{'S', 0x00000008}, // Synchronous updates
+	{'D', 0x00010000}, // Synchronous directory updates
+	{'i', 0x00000010}, // Immutable
+	{'a', 0x00000020}, // Append only
+	{'d', 0x00000040}, // No dump
+	{'A', 0x00000080}, // No access time updates
+	{'c', 0x00000004}, // Compressed
+	{'j', 0x00004000}, // Data journaling
+	{'t', 0x00008000}, // No tail merging
+	{'T', 0x00020000}, // Top of directory hierarchies
+	{'C', 0x00800000}, // No copy on write
+}
+
+// formatFileFlags returns the letters of the kept flags that are set, e.g. "ia"
+func formatFileFlags(flags uint32) string {
+	var letters strings.Builder
+	for _, f := range fileFlags {
+		if flags&f.flag != 0 {
+			letters.WriteByte(f.letter)
+		}
+	}
+	return letters.String()
+}
+
+// parseFileFlags returns the flags of the letters and the mask of all kept flags.
+// Unknown letters are ignored.
+func parseFileFlags(letters string) (flags, mask uint32) {
+	for _, f := range fileFlags {
+		mask |= f.flag
+		if strings.IndexByte(letters, f.letter) >= 0 {
+			flags |= f.flag
+		}
+	}
+	return flags, mask
+}
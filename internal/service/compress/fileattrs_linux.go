@@ -0,0 +1,108 @@
+package compress
+
+import (
+	"archive/tar"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// xattrCapability is the extended attribute holding a file's capabilities, as set by setcap
+const xattrCapability = "security.capability"
+
+// FS_IOC_GETFLAGS and FS_IOC_SETFLAGS, encoded as _IOR and _IOW('f', 1 or 2, long)
+var (
+	ioctlGetFlags = uintptr(2<<30 | unsafe.Sizeof(uintptr(0))<<16 | 'f'<<8 | 1)
+	ioctlSetFlags = uintptr(1<<30 | unsafe.Sizeof(uintptr(0))<<16 | 'f'<<8 | 2)
+)
+
+// readFileAttrs adds the capabilities and chattr flags of a file or directory to its header.
+// Attributes that cannot be read, e.g. on file systems without them, are left out.
+func readFileAttrs(header *tar.Header, path string, file *os.File) {
+	if header.Typeflag == tar.TypeReg {
+		if capability, err := getxattr(path, xattrCapability); err == nil && len(capability) > 0 {
+			setPAXRecord(header, paxCapability, string(capability))
+		}
+	}
+
+	if file == nil {
+		if header.Typeflag != tar.TypeDir {
+			return
+		}
+		dir, err := os.Open(path)
+		if err != nil {
+			return
+		}
+		defer dir.Close()
+		file = dir
+	}
+	var flags uint32
+	if ioctlFlags(file, ioctlGetFlags, &flags) != nil {
+		return
+	}
+	if letters := formatFileFlags(flags); letters != "" {
+		setPAXRecord(header, paxFileFlags, letters)
+	}
+}
+
+// applyFileAttrs sets the capabilities and chattr flags recorded in the header on an extracted
+// file or directory. Capabilities need root, and immutable and append-only flags need root or
+// CAP_LINUX_IMMUTABLE.
+func applyFileAttrs(header *tar.Header, path string) error {
+	if capability, ok := header.PAXRecords[paxCapability]; ok {
+		if err := syscall.Setxattr(path, xattrCapability, []byte(capability), 0); err != nil {
+			return fmt.Errorf("error setting capabilities: %w", err)
+		}
+	}
+
+	letters, ok := header.PAXRecords[paxFileFlags]
+	if !ok {
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return fmt.Errorf("error setting chattr flags: %w", err)
+	}
+	defer file.Close()
+	var flags uint32
+	if err := ioctlFlags(file, ioctlGetFlags, &flags); err != nil {
+		return fmt.Errorf("error setting chattr flags: %w", err)
+	}
+	archived, mask := parseFileFlags(letters)
+	flags = flags&^mask | archived
+	if err := ioctlFlags(file, ioctlSetFlags, &flags); err != nil {
+		return fmt.Errorf("error setting chattr flags %s: %w", letters, err)
+	}
+	return nil
+}
+
+// getxattr returns the value of an extended attribute of a file
+func getxattr(path, name string) ([]byte, error) {
+	size, err := syscall.Getxattr(path, name, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	value := make([]byte, size)
+	size, err = syscall.Getxattr(path, name, value)
+	if err != nil {
+		return nil, err
+	}
+	return value[:size], nil
+}
+
+// ioctlFlags reads or writes the chattr flags of an open file
+func ioctlFlags(file *os.File, request uintptr, flags *uint32) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), request, uintptr(unsafe.Pointer(flags))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// setPAXRecord adds a record to the header
+func setPAXRecord(header *tar.Header, key, value string) {
+	if header.PAXRecords == nil {
+		header.PAXRecords = make(map[string]string)
+	}
+	header.PAXRecords[key] = value
+}
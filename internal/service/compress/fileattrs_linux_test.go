@@ -0,0 +1,71 @@
+package compress_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/kennycyb/go-backup/internal/service/compress"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Linux file attributes", func() {
+	// cap_net_bind_service in the permitted and effective sets, as written by setcap cap_net_bind_service=ep
+	capability := []byte{0x01, 0x00, 0x00, 0x02, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+
+	var (
+		sourceDir string
+		outputDir string
+		archive   string
+	)
+
+	lsattr := func(path string) string {
+		output, err := exec.Command("lsattr", "-d", path).Output()
+		Expect(err).NotTo(HaveOccurred())
+		return strings.Fields(string(output))[0]
+	}
+
+	BeforeEach(func() {
+		sourceDir = GinkgoT().TempDir()
+		outputDir = GinkgoT().TempDir()
+		archive = filepath.Join(outputDir, "backup.tar.gz")
+
+		Expect(os.MkdirAll(filepath.Join(sourceDir, "bin"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sourceDir, "bin", "server"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+		if err := syscall.Setxattr(filepath.Join(sourceDir, "bin", "server"), "security.capability", capability, 0); err != nil {
+			Skip("cannot set capabilities: " + err.Error())
+		}
+		if output, err := exec.Command("chattr", "+dA", filepath.Join(sourceDir, "bin", "server"), filepath.Join(sourceDir, "bin")).CombinedOutput(); err != nil {
+			Skip("cannot set chattr flags: " + string(output))
+		}
+		_, err := compress.CreateTarGzArchives(sourceDir, []compress.ArchiveOutput{{Path: archive}}, nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should restore capabilities and chattr flags", func() {
+		restoreDir := filepath.Join(outputDir, "restored")
+		result, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Warnings).To(BeEmpty())
+
+		value := make([]byte, 64)
+		size, err := syscall.Getxattr(filepath.Join(restoreDir, "bin", "server"), "security.capability", value)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value[:size]).To(Equal(capability))
+		Expect(lsattr(filepath.Join(restoreDir, "bin", "server"))).To(And(ContainSubstring("d"), ContainSubstring("A")))
+		Expect(lsattr(filepath.Join(restoreDir, "bin"))).To(And(ContainSubstring("d"), ContainSubstring("A")))
+	})
+
+	It("should leave them out with SkipAttributes", func() {
+		restoreDir := filepath.Join(outputDir, "rehearsal")
+		_, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{SkipAttributes: true})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = syscall.Getxattr(filepath.Join(restoreDir, "bin", "server"), "security.capability", make([]byte, 64))
+		Expect(err).To(HaveOccurred())
+		Expect(lsattr(filepath.Join(restoreDir, "bin", "server"))).NotTo(ContainSubstring("d"))
+	})
+})
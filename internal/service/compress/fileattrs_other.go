@@ -0,0 +1,22 @@
+//go:build !linux
+
+package compress
+
+import (
+	"archive/tar"
+	"fmt"
+	"os"
+)
+
+// readFileAttrs does nothing: capabilities and chattr flags only exist on Linux
+func readFileAttrs(header *tar.Header, path string, file *os.File) {}
+
+// applyFileAttrs reports the Linux attributes recorded in the header, which cannot be set here
+func applyFileAttrs(header *tar.Header, path string) error {
+	_, capability := header.PAXRecords[paxCapability]
+	_, flags := header.PAXRecords[paxFileFlags]
+	if capability || flags {
+		return fmt.Errorf("capabilities and chattr flags can only be restored on Linux")
+	}
+	return nil
+}
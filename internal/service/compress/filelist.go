@@ -0,0 +1,81 @@
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// ReadFileList reads a list of paths, one per line or, with nullDelimited, separated by NUL
+// characters as written by 'find -print0'. Empty entries are ignored; a trailing carriage
+// return is removed from lines, so lists written on Windows work too.
+func ReadFileList(r io.Reader, nullDelimited bool) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	if nullDelimited {
+		scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			if i := bytes.IndexByte(data, 0); i >= 0 {
+				return i + 1, data[:i], nil
+			}
+			if atEOF && len(data) > 0 {
+				return len(data), data, nil
+			}
+			return 0, nil, nil
+		})
+	}
+
+	var paths []string
+	for scanner.Scan() {
+		path := scanner.Text()
+		if !nullDelimited {
+			path = strings.TrimSuffix(path, "\r")
+		}
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file list: %w", err)
+	}
+	return paths, nil
+}
+
+// CleanFileList turns listed paths into clean paths relative to the source directory, without
+// duplicates and in the order first listed. Paths may be relative to the source, e.g. "./src/main.go"
+// from 'find .', or absolute paths inside it. Returns an error for a path outside the source.
+func CleanFileList(sourceDir string, paths []string) ([]string, error) {
+	absSource, err := filepath.Abs(sourceDir)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving source directory: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	cleaned := []string{}
+	for _, path := range paths {
+		relPath := filepath.Clean(path)
+		if filepath.IsAbs(relPath) {
+			if relPath, err = filepath.Rel(absSource, relPath); err != nil {
+				return nil, fmt.Errorf("listed path %s is not inside the source %s", path, sourceDir)
+			}
+		}
+		if relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("listed path %s is not inside the source %s", path, sourceDir)
+		}
+		// The source itself is never an entry of the archive
+		if relPath == "." || seen[relPath] {
+			continue
+		}
+		seen[relPath] = true
+		cleaned = append(cleaned, relPath)
+	}
+	return cleaned, nil
+}
+
+// InFileList reports whether an archive entry is selected by the paths of ExtractOptions.Files:
+// it is one of them or inside a listed directory
+func InFileList(name string, paths []string) bool {
+	return InSelection(name, paths, nil)
+}
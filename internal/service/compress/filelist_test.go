@@ -0,0 +1,64 @@
+package compress_test
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/kennycyb/go-backup/internal/service/compress"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("File lists", func() {
+	Describe("ReadFileList", func() {
+		It("should read one path per line, ignoring empty lines", func() {
+			paths, err := compress.ReadFileList(strings.NewReader("src/main.go\n\nREADME.md\r\nwith space.txt\n"), false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(paths).To(Equal([]string{"src/main.go", "README.md", "with space.txt"}))
+		})
+
+		It("should read NUL-delimited paths, which may contain newlines", func() {
+			paths, err := compress.ReadFileList(strings.NewReader("./a\nb\x00./c\x00"), true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(paths).To(Equal([]string{"./a\nb", "./c"}))
+		})
+
+		It("should read a last path without a delimiter", func() {
+			paths, err := compress.ReadFileList(strings.NewReader("a\x00b"), true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(paths).To(Equal([]string{"a", "b"}))
+		})
+	})
+
+	Describe("CleanFileList", func() {
+		source := filepath.Join("/", "data", "project")
+
+		It("should clean relative paths and drop duplicates and the source itself", func() {
+			paths, err := compress.CleanFileList(source, []string{"./src/main.go", ".", "src//main.go", "docs/"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(paths).To(Equal([]string{filepath.Join("src", "main.go"), "docs"}))
+		})
+
+		It("should make absolute paths inside the source relative", func() {
+			paths, err := compress.CleanFileList(source, []string{filepath.Join(source, "src", "main.go")})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(paths).To(Equal([]string{filepath.Join("src", "main.go")}))
+		})
+
+		It("should return an error for paths outside the source", func() {
+			_, err := compress.CleanFileList(source, []string{"../other/secret"})
+			Expect(err).To(MatchError(ContainSubstring("not inside the source")))
+
+			_, err = compress.CleanFileList(source, []string{filepath.Join("/", "etc", "passwd")})
+			Expect(err).To(MatchError(ContainSubstring("not inside the source")))
+		})
+	})
+	Describe("InFileList", func() {
+		It("should select listed paths and the contents of listed directories", func() {
+			paths := []string{"docs", "./run.sh"}
+			Expect(compress.InFileList("docs/notes/todo.txt", paths)).To(BeTrue())
+			Expect(compress.InFileList("run.sh", paths)).To(BeTrue())
+			Expect(compress.InFileList("docsite/index.md", paths)).To(BeFalse())
+		})
+	})
+})
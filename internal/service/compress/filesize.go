@@ -5,7 +5,6 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
-	"strings"
 	"time"
 )
 
@@ -28,24 +27,7 @@ type LargeFileInfo struct {
 
 // checkExcluded checks if a path should be excluded based on the provided patterns
 func checkExcluded(relPath string, excludes []string) bool {
-	for _, exclude := range excludes {
-		// Try exact match
-		matched, _ := filepath.Match(exclude, relPath)
-
-		// Try prefix match (directory)
-		if !matched && strings.HasPrefix(relPath, exclude) {
-			// Check if the relative path starts with the exclude pattern followed by path separator
-			if len(relPath) == len(exclude) || (len(relPath) > len(exclude) && relPath[len(exclude)] == filepath.Separator) {
-				return true
-			}
-		}
-
-		if matched {
-			return true
-		}
-	}
-
-	return false
+	return NewExcludeMatcher(excludes).Excluded(relPath)
 }
 
 // TestHelperCheckExcluded exposes the checkExcluded function for testing
@@ -59,10 +41,15 @@ func CheckFileSizes(sourceDir string, excludes []string, maxSizeGB int64) (*File
 	// Convert GB to bytes using our constant
 	maxSize := maxSizeGB * GB
 	summary := &FileSizeSummary{}
+	matcher := NewExcludeMatcher(excludes)
 
 	err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			return err
+			// Unreadable paths are reported by the archive walk; only an unreadable source is fatal here
+			if path == sourceDir {
+				return err
+			}
+			return nil
 		}
 
 		// Skip directories
@@ -77,7 +64,7 @@ func CheckFileSizes(sourceDir string, excludes []string, maxSizeGB int64) (*File
 		}
 
 		// Skip excluded directories and files
-		if checkExcluded(relPath, excludes) {
+		if matcher.Excluded(relPath) {
 			return nil
 		}
 
@@ -106,6 +93,7 @@ func CheckFileSizes(sourceDir string, excludes []string, maxSizeGB int64) (*File
 func ListLargeFiles(sourceDir string, excludes []string, thresholdMB int64) ([]LargeFileInfo, error) {
 	thresholdBytes := thresholdMB * MB
 	var largeFiles []LargeFileInfo
+	matcher := NewExcludeMatcher(excludes)
 
 	err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -124,7 +112,7 @@ func ListLargeFiles(sourceDir string, excludes []string, thresholdMB int64) ([]L
 		}
 
 		// Skip excluded directories and files
-		if checkExcluded(relPath, excludes) {
+		if matcher.Excluded(relPath) {
 			return nil
 		}
 
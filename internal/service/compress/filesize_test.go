@@ -6,7 +6,7 @@ import (
 	"time"
 
 	"github.com/kennycyb/go-backup/internal/service/compress"
-	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
@@ -16,6 +16,22 @@ var _ = Describe("Filesize", func() {
 		cleanup func()
 	)
 
+	// createTestFile creates a test file of the specified size
+	createTestFile := func(path string, size int64) {
+		file, err := os.Create(path)
+		Expect(err).NotTo(HaveOccurred(), "Failed to create test file %s", path)
+		defer file.Close()
+
+		// Set the file size
+		err = file.Truncate(size)
+		Expect(err).NotTo(HaveOccurred(), "Failed to resize file %s to %d bytes", path, size)
+
+		// Set file modification time to ensure deterministic testing
+		modTime := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+		err = os.Chtimes(path, modTime, modTime)
+		Expect(err).NotTo(HaveOccurred(), "Failed to set modification time for %s", path)
+	}
+
 	// setupTestFileSystem creates a temporary file system structure for testing
 	setupTestFileSystem := func() (string, func()) {
 		// Create a temporary directory that will be cleaned up after the test
@@ -52,22 +68,6 @@ var _ = Describe("Filesize", func() {
 		return tempDir, cleanup
 	}
 
-	// createTestFile creates a test file of the specified size
-	createTestFile := func(path string, size int64) {
-		file, err := os.Create(path)
-		Expect(err).NotTo(HaveOccurred(), "Failed to create test file %s", path)
-		defer file.Close()
-
-		// Set the file size
-		err = file.Truncate(size)
-		Expect(err).NotTo(HaveOccurred(), "Failed to resize file %s to %d bytes", path, size)
-
-		// Set file modification time to ensure deterministic testing
-		modTime := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
-		err = os.Chtimes(path, modTime, modTime)
-		Expect(err).NotTo(HaveOccurred(), "Failed to set modification time for %s", path)
-	}
-
 	BeforeEach(func() {
 		// Setup test file system before each test
 		tempDir, cleanup = setupTestFileSystem()
@@ -97,10 +97,22 @@ var _ = Describe("Filesize", func() {
 					}
 				},
 				Entry("No files over 1 GB", []string{}, int64(1), ""),
-				Entry("Large file over 50 MB", []string{}, int64(0), "verylarge.dat"), // 0 = 50 MB threshold
-				Entry("With node_modules excluded", []string{"node_modules", ".git"}, int64(0), "verylarge.dat"),
+				Entry("No size check with a 0 GB limit", []string{}, int64(0), ""),
+				Entry("With node_modules excluded", []string{"node_modules", ".git"}, int64(1), ""),
 			)
 		})
+
+		It("should skip directories it cannot read", func() {
+			if os.Geteuid() == 0 {
+				Skip("root can read directories without permissions")
+			}
+			Expect(os.Chmod(filepath.Join(tempDir, "project"), 0000)).To(Succeed())
+			DeferCleanup(os.Chmod, filepath.Join(tempDir, "project"), os.FileMode(0755))
+
+			summary, err := compress.CheckFileSizes(tempDir, nil, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(summary.LargestFile).To(Equal("verylarge.dat"))
+		})
 	})
 
 	Describe("ListLargeFiles", func() {
@@ -132,9 +144,9 @@ var _ = Describe("Filesize", func() {
 			},
 			Entry("List files over 50 MB", []string{}, int64(50), 2,
 				[]string{"verylarge.dat", "large.dat"}),
-			Entry("List files over 1 MB", []string{}, int64(1), 4,
+			Entry("List files over 1 MB", []string{}, int64(1), 5,
 				[]string{"verylarge.dat", "large.dat", "medium.dat", "project/docs.md"}),
-			Entry("With exclusions", []string{"node_modules", ".git", "project"}, int64(1), 2,
+			Entry("With exclusions", []string{"node_modules", ".git", "project"}, int64(1), 3,
 				[]string{"verylarge.dat", "large.dat"}),
 		)
 	})
@@ -146,7 +158,7 @@ var _ = Describe("Filesize", func() {
 				Expect(result).To(ContainSubstring(expected),
 					"Formatted file size should contain %s", expected)
 			},
-			Entry("512 bytes", int64(512), "0.50 KB"),
+			Entry("512 bytes", int64(512), "512 B"),
 			Entry("1 KB", int64(1024), "1.00 KB"),
 			Entry("1 MB", int64(1024*1024), "1.00 MB"),
 			Entry("1 GB", int64(1024*1024*1024), "1.00 GB"),
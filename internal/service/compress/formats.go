@@ -0,0 +1,292 @@
+package compress
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Archive formats of backups
+const (
+	FormatTarGz = "tar.gz" // gzip-compressed tar, the default
+	FormatTar   = "tar"    // Uncompressed tar, for sources such as media that gain nothing from gzip
+	FormatZip   = "zip"    // Deflate-compressed zip, which Windows opens without extra tools
+)
+
+// ArchiveExtensions are the file extensions of the archive formats. None is a suffix of another
+// one listed before it, so the first that matches a name is its extension.
+var ArchiveExtensions = []string{".tar.gz", ".tar", ".zip"}
+
+// ForeignExtensions are the extensions of archives made by other tools that restore and inspect read,
+// e.g. "tar czf" or "borg export-tar". go-backup never writes them, so rotation leaves them alone.
+var ForeignExtensions = []string{".tgz", ".tar.bz2", ".tbz2", ".tbz"}
+
+// ValidateFormat checks an archive format; empty stands for FormatTarGz
+func ValidateFormat(format string) error {
+	switch format {
+	case "", FormatTarGz, FormatTar, FormatZip:
+		return nil
+	}
+	return fmt.Errorf("invalid archive format %q: use %s, %s or %s", format, FormatTarGz, FormatTar, FormatZip)
+}
+
+// FormatExtension returns the file extension of an archive format, e.g. ".zip"
+func FormatExtension(format string) string {
+	if format == "" {
+		format = FormatTarGz
+	}
+	return "." + format
+}
+
+// ArchiveExtension returns the archive extension of a file name, ignoring a trailing .gpg,
+// or "" if the name is not that of an archive
+func ArchiveExtension(name string) string {
+	name = strings.TrimSuffix(name, ".gpg")
+	for _, ext := range ArchiveExtensions {
+		if strings.HasSuffix(name, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// ForeignExtension returns the extension of an archive made by another tool, ignoring a trailing .gpg,
+// or "" if the name has none of ForeignExtensions
+func ForeignExtension(name string) string {
+	name = strings.TrimSuffix(name, ".gpg")
+	for _, ext := range ForeignExtensions {
+		if strings.HasSuffix(name, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// ErrUnsupportedArchive is returned for archives go-backup recognizes but cannot read
+var ErrUnsupportedArchive = errors.New("unsupported archive")
+
+// unreadableCompressions are compressions of tar archives found by their first bytes that
+// go-backup cannot read, with the command that decompresses them
+var unreadableCompressions = []struct {
+	magic   []byte
+	name    string
+	command string
+}{
+	{[]byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, "xz", "xz -dk"},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, "zstd", "zstd -d"},
+	{[]byte{0x04, 0x22, 0x4d, 0x18}, "lz4", "lz4 -d"},
+}
+
+// archiveReader reads the entries of an archive in order, as tar.Reader does: Next returns the
+// header of the next entry and Read its contents
+type archiveReader interface {
+	Next() (*tar.Header, error)
+	Read(p []byte) (int, error)
+}
+
+// openArchive opens an archive for reading its entries, telling tar.gz, tar, tar.bz2 and zip apart
+// by their first bytes. Archives made by other tools are read as well, with their entry names
+// cleaned up (see cleanEntries). wrap, when not nil, wraps the streams the entries are read from,
+// so read errors can be told apart. The archive stays open as long as file does.
+func openArchive(file *os.File, wrap func(io.Reader) io.Reader) (archiveReader, error) {
+	if wrap == nil {
+		wrap = func(r io.Reader) io.Reader { return r }
+	}
+	magic := make([]byte, 6)
+	n, _ := io.ReadFull(file, magic)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("error reading archive: %w", err)
+	}
+	magic = magic[:n]
+
+	for _, compression := range unreadableCompressions {
+		if bytes.HasPrefix(magic, compression.magic) {
+			return nil, fmt.Errorf("%w: compressed with %s, which go-backup cannot read; decompress it first, e.g. with %s %s",
+				ErrUnsupportedArchive, compression.name, compression.command, filepath.Base(file.Name()))
+		}
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gzReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading gzip stream: %w", err)
+		}
+		return cleanEntries{tar.NewReader(wrap(gzReader))}, nil
+	case isBzip2(magic):
+		return cleanEntries{tar.NewReader(wrap(bzip2.NewReader(file)))}, nil
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")) || bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		info, err := file.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("error reading archive: %w", err)
+		}
+		zipReader, err := zip.NewReader(file, info.Size())
+		if err != nil {
+			return nil, fmt.Errorf("error reading zip archive: %w", err)
+		}
+		return cleanEntries{&zipEntries{files: zipReader.File, wrap: wrap}}, nil
+	}
+	return cleanEntries{tar.NewReader(wrap(file))}, nil
+}
+
+// isBzip2 reports whether an archive starting with magic is compressed with bzip2. Besides "BZh",
+// which a tar whose first file is named "BZh..." starts with as well, the block size and the start
+// of the first block are checked.
+func isBzip2(magic []byte) bool {
+	if len(magic) < 6 || !bytes.HasPrefix(magic, []byte("BZh")) || magic[3] < '1' || magic[3] > '9' {
+		return false
+	}
+	return bytes.Equal(magic[4:6], []byte{0x31, 0x41}) || bytes.Equal(magic[4:6], []byte{0x17, 0x72})
+}
+
+// cleanEntries evens out the entries of archives made by other tools to the ones go-backup writes:
+// names such as "./src/main.go" from tar or "/home/me/notes.txt" from tar -P become relative
+// ("src/main.go", "home/me/notes.txt"), and GNU sparse files become regular files. The root
+// directory entry and pax global headers, which describe no file, are skipped.
+type cleanEntries struct {
+	archiveReader
+}
+
+// Next returns the header of the next entry that describes a file
+func (c cleanEntries) Next() (*tar.Header, error) {
+	for {
+		header, err := c.archiveReader.Next()
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		header.Name = cleanEntryName(header.Name)
+		if header.Name == "." {
+			continue
+		}
+		switch header.Typeflag {
+		case tar.TypeLink:
+			header.Linkname = cleanEntryName(header.Linkname)
+		case tar.TypeGNUSparse:
+			header.Typeflag = tar.TypeReg
+		}
+		return header, nil
+	}
+}
+
+// cleanEntryName makes an entry name relative to the archive's root. Names leaving the root,
+// such as "../x", are kept so extraction still refuses them.
+func cleanEntryName(name string) string {
+	return path.Clean(strings.TrimLeft(name, "/"))
+}
+
+// zipEntries reads the entries of a zip archive as tar headers, so zip archives are listed and
+// extracted like tar archives
+type zipEntries struct {
+	files   []*zip.File
+	next    int
+	wrap    func(io.Reader) io.Reader
+	current io.ReadCloser
+	reader  io.Reader
+}
+
+// Next returns the header of the next entry; directories, regular files and symlinks become the
+// tar entries of the same type, anything else an entry extraction ignores
+func (z *zipEntries) Next() (*tar.Header, error) {
+	if z.current != nil {
+		z.current.Close()
+		z.current, z.reader = nil, nil
+	}
+	if z.next >= len(z.files) {
+		return nil, io.EOF
+	}
+	file := z.files[z.next]
+	z.next++
+
+	mode := file.Mode()
+	header := &tar.Header{
+		Name:    strings.TrimSuffix(file.Name, "/"),
+		Mode:    int64(mode.Perm()),
+		ModTime: file.Modified,
+		Format:  tar.FormatPAX,
+	}
+	switch {
+	case mode.IsDir() || strings.HasSuffix(file.Name, "/"):
+		header.Typeflag = tar.TypeDir
+		return header, nil
+	case mode&os.ModeSymlink != 0:
+		header.Typeflag = tar.TypeSymlink
+	case mode.IsRegular():
+		header.Typeflag = tar.TypeReg
+		header.Size = int64(file.UncompressedSize64)
+	default:
+		header.Typeflag = tar.TypeFifo
+		return header, nil
+	}
+
+	current, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error reading zip entry %s: %w", file.Name, err)
+	}
+	z.current, z.reader = current, z.wrap(current)
+	if header.Typeflag == tar.TypeSymlink {
+		// Zip stores the target of a symlink as its contents
+		link, err := io.ReadAll(z.reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading zip entry %s: %w", file.Name, err)
+		}
+		header.Linkname = string(link)
+	}
+	return header, nil
+}
+
+// Read reads the contents of the current entry
+func (z *zipEntries) Read(p []byte) (int, error) {
+	if z.reader == nil {
+		return 0, io.EOF
+	}
+	return z.reader.Read(p)
+}
+
+// newZipWriter returns a zip writer that deflates at the given gzip level
+func newZipWriter(w io.Writer, level int) (*zip.Writer, error) {
+	if _, err := flate.NewWriter(io.Discard, level); err != nil {
+		return nil, err
+	}
+	zipWriter := zip.NewWriter(w)
+	zipWriter.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, level)
+	})
+	return zipWriter, nil
+}
+
+// writeZipEntry starts the zip entry of a tar header and returns the writer of its contents.
+// Symlinks are stored with their target as contents; stored entries are not compressed.
+func writeZipEntry(zipWriter *zip.Writer, header *tar.Header, store bool) (io.Writer, error) {
+	info := header.FileInfo()
+	zipHeader := &zip.FileHeader{Name: filepath.ToSlash(header.Name), Method: zip.Deflate, Modified: header.ModTime}
+	zipHeader.SetMode(info.Mode())
+	if info.IsDir() {
+		zipHeader.Name += "/"
+	}
+	if store || !info.Mode().IsRegular() {
+		zipHeader.Method = zip.Store
+	}
+	w, err := zipWriter.CreateHeader(zipHeader)
+	if err != nil {
+		return nil, fmt.Errorf("error writing zip entry %s: %w", header.Name, err)
+	}
+	if header.Typeflag == tar.TypeSymlink {
+		if _, err := io.WriteString(w, header.Linkname); err != nil {
+			return nil, fmt.Errorf("error writing zip entry %s: %w", header.Name, err)
+		}
+	}
+	return w, nil
+}
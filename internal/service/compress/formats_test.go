@@ -0,0 +1,276 @@
+package compress_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/kennycyb/go-backup/internal/service/compress"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Archive formats", func() {
+	var (
+		sourceDir string
+		outputDir string
+	)
+
+	BeforeEach(func() {
+		sourceDir = GinkgoT().TempDir()
+		outputDir = GinkgoT().TempDir()
+
+		Expect(os.MkdirAll(filepath.Join(sourceDir, "docs"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sourceDir, "docs", "todo.txt"), []byte("todo"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sourceDir, "run.sh"), []byte("#!/bin/sh"), 0755)).To(Succeed())
+		Expect(os.Symlink("docs/todo.txt", filepath.Join(sourceDir, "todo"))).To(Succeed())
+	})
+
+	DescribeTable("should write archives that are listed, read and restored like tar.gz",
+		func(format string) {
+			archive := filepath.Join(outputDir, "backup"+compress.FormatExtension(format))
+			written, err := compress.CreateTarGzArchives(sourceDir, []compress.ArchiveOutput{{Path: archive, Format: format}}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			listed, err := compress.ListTarGzArchive(archive)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(listed).To(HaveLen(len(written)))
+			for i := range written {
+				Expect(listed[i].Path).To(Equal(written[i].Path))
+				Expect(listed[i].Size).To(Equal(written[i].Size))
+			}
+
+			var contents bytes.Buffer
+			Expect(compress.CatTarGzFile(archive, "docs/todo.txt", &contents)).To(Succeed())
+			Expect(contents.String()).To(Equal("todo"))
+
+			restoreDir := filepath.Join(outputDir, "restored")
+			result, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Restored).To(ConsistOf("docs", "docs/todo.txt", "run.sh", "todo"))
+
+			info, err := os.Stat(filepath.Join(restoreDir, "run.sh"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+
+			link, err := os.Readlink(filepath.Join(restoreDir, "todo"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link).To(Equal("docs/todo.txt"))
+		},
+		Entry("tar.gz", compress.FormatTarGz),
+		Entry("tar", compress.FormatTar),
+		Entry("zip", compress.FormatZip),
+	)
+
+	It("should write a zip archive other tools can read", func() {
+		archive := filepath.Join(outputDir, "backup.zip")
+		_, err := compress.CreateTarGzArchives(sourceDir, []compress.ArchiveOutput{{Path: archive, Format: compress.FormatZip}}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		reader, err := zip.OpenReader(archive)
+		Expect(err).NotTo(HaveOccurred())
+		defer reader.Close()
+		var names []string
+		for _, file := range reader.File {
+			names = append(names, file.Name)
+		}
+		Expect(names).To(ConsistOf("docs/", "docs/todo.txt", "run.sh", "todo"))
+	})
+
+	It("should write files over 4 GiB to zip archives with ZIP64 records", func() {
+		if testing.Short() {
+			Skip("reads a file over 4 GiB")
+		}
+		// A sparse file takes no disk space, and its zeros deflate to a few MB
+		const size = 4<<30 + 1
+		bigDir := GinkgoT().TempDir()
+		file, err := os.Create(filepath.Join(bigDir, "disk.img"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(file.Truncate(size)).To(Succeed())
+		Expect(file.Close()).To(Succeed())
+
+		archive := filepath.Join(outputDir, "big.zip")
+		_, err = compress.CreateTarGzArchives(bigDir, []compress.ArchiveOutput{{Path: archive, Format: compress.FormatZip, Level: 1}}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		reader, err := zip.OpenReader(archive)
+		Expect(err).NotTo(HaveOccurred())
+		defer reader.Close()
+		Expect(reader.File).To(HaveLen(1))
+		entry := reader.File[0]
+		Expect(entry.UncompressedSize64).To(Equal(uint64(size)))
+
+		// The central directory holds the real size in a ZIP64 extra field (tag 0x0001)
+		zip64 := false
+		for extra := entry.Extra; len(extra) >= 4; {
+			tag, length := binary.LittleEndian.Uint16(extra), int(binary.LittleEndian.Uint16(extra[2:]))
+			if tag == 0x0001 {
+				zip64 = true
+			}
+			extra = extra[min(4+length, len(extra)):]
+		}
+		Expect(zip64).To(BeTrue(), "expected a ZIP64 extra field")
+
+		contents, err := entry.Open()
+		Expect(err).NotTo(HaveOccurred())
+		defer contents.Close()
+		Expect(io.Copy(io.Discard, contents)).To(Equal(int64(size)))
+	})
+
+	It("should write several formats in one pass", func() {
+		outputs := []compress.ArchiveOutput{
+			{Path: filepath.Join(outputDir, "backup.tar.gz")},
+			{Path: filepath.Join(outputDir, "backup.tar"), Format: compress.FormatTar},
+			{Path: filepath.Join(outputDir, "backup.zip"), Format: compress.FormatZip},
+		}
+		_, err := compress.CreateTarGzArchives(sourceDir, outputs, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, output := range outputs {
+			listed, err := compress.ListTarGzArchive(output.Path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(listed).To(HaveLen(4))
+		}
+	})
+
+	It("should compute the checksum of each archive while writing it", func() {
+		var tarGzSum, zipSum string
+		outputs := []compress.ArchiveOutput{
+			{Path: filepath.Join(outputDir, "backup.tar.gz"), SHA256: &tarGzSum},
+			{Path: filepath.Join(outputDir, "backup.zip"), Format: compress.FormatZip, SHA256: &zipSum},
+		}
+		_, err := compress.CreateTarGzArchives(sourceDir, outputs, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		for i, sum := range []string{tarGzSum, zipSum} {
+			data, err := os.ReadFile(outputs[i].Path)
+			Expect(err).NotTo(HaveOccurred())
+			expected := sha256.Sum256(data)
+			Expect(sum).To(Equal(hex.EncodeToString(expected[:])))
+		}
+	})
+
+	It("should refuse chunks for formats other than tar.gz", func() {
+		var chunks []compress.ArchiveChunk
+		outputs := []compress.ArchiveOutput{{Path: filepath.Join(outputDir, "backup.zip"), Format: compress.FormatZip, ChunkSize: 1 << 20, Chunks: &chunks}}
+		_, err := compress.CreateTarGzArchives(sourceDir, outputs, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should validate formats", func() {
+		Expect(compress.ValidateFormat("")).To(Succeed())
+		Expect(compress.ValidateFormat(compress.FormatZip)).To(Succeed())
+		Expect(compress.ValidateFormat("7z")).To(MatchError(ContainSubstring(`invalid archive format "7z"`)))
+	})
+
+	It("should tell the archive extension of a file name", func() {
+		Expect(compress.ArchiveExtension("project-20250520-123045.tar.gz")).To(Equal(".tar.gz"))
+		Expect(compress.ArchiveExtension("project-20250520-123045.tar.gz.gpg")).To(Equal(".tar.gz"))
+		Expect(compress.ArchiveExtension("project-20250520-123045.tar")).To(Equal(".tar"))
+		Expect(compress.ArchiveExtension("project-20250520-123045.zip.gpg")).To(Equal(".zip"))
+		Expect(compress.ArchiveExtension("project-20250520-123045.backup.yaml")).To(BeEmpty())
+		Expect(compress.FormatExtension("")).To(Equal(".tar.gz"))
+	})
+
+	Describe("archives made by other tools", func() {
+		var archive string
+
+		// writeForeignTar writes a tar laid out like "tar cf x.tar ." or borg export-tar make them:
+		// a root entry, "./" names, a hard link, an absolute name and a pax global header
+		writeForeignTar := func(path string) {
+			file, err := os.Create(path)
+			Expect(err).NotTo(HaveOccurred())
+			defer file.Close()
+			tarWriter := tar.NewWriter(file)
+			for _, header := range []*tar.Header{
+				{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header", PAXRecords: map[string]string{"comment": "exported"}},
+				{Typeflag: tar.TypeDir, Name: "./", Mode: 0755},
+				{Typeflag: tar.TypeDir, Name: "./docs/", Mode: 0755},
+				{Typeflag: tar.TypeReg, Name: "./docs/todo.txt", Mode: 0644, Size: 4},
+				{Typeflag: tar.TypeLink, Name: "./docs/same.txt", Linkname: "./docs/todo.txt"},
+				{Typeflag: tar.TypeReg, Name: "/home/me/notes.txt", Mode: 0600, Size: 5},
+			} {
+				Expect(tarWriter.WriteHeader(header)).To(Succeed())
+				if header.Size > 0 {
+					_, err := tarWriter.Write([]byte("todo\n")[:header.Size])
+					Expect(err).NotTo(HaveOccurred())
+				}
+			}
+			Expect(tarWriter.Close()).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			archive = filepath.Join(outputDir, "legacy.tar")
+			writeForeignTar(archive)
+		})
+
+		It("should list entries with names relative to the archive's root", func() {
+			listed, err := compress.ListTarGzArchive(archive)
+			Expect(err).NotTo(HaveOccurred())
+			var names []string
+			for _, entry := range listed {
+				names = append(names, entry.Path)
+			}
+			Expect(names).To(Equal([]string{"docs", "docs/todo.txt", "docs/same.txt", "home/me/notes.txt"}))
+
+			var contents bytes.Buffer
+			Expect(compress.CatTarGzFile(archive, "home/me/notes.txt", &contents)).To(Succeed())
+			Expect(contents.String()).To(Equal("todo\n"))
+		})
+
+		It("should restore hard links and absolute names inside the target directory", func() {
+			restoreDir := filepath.Join(outputDir, "restored")
+			result, err := compress.ExtractTarGzArchive(archive, restoreDir, compress.ExtractOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Restored).To(ConsistOf("docs", "docs/todo.txt", "docs/same.txt", "home/me/notes.txt"))
+			Expect(result.Skipped).To(BeEmpty())
+
+			original, err := os.Stat(filepath.Join(restoreDir, "docs", "todo.txt"))
+			Expect(err).NotTo(HaveOccurred())
+			link, err := os.Stat(filepath.Join(restoreDir, "docs", "same.txt"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.SameFile(original, link)).To(BeTrue())
+			Expect(filepath.Join(restoreDir, "home", "me", "notes.txt")).To(BeARegularFile())
+		})
+
+		It("should skip a hard link whose file was not selected", func() {
+			result, err := compress.ExtractTarGzArchive(archive, filepath.Join(outputDir, "restored"), compress.ExtractOptions{Files: []string{"docs/same.txt"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Restored).To(BeEmpty())
+			Expect(result.Skipped).To(ConsistOf("docs/same.txt"))
+		})
+
+		It("should read a tar compressed with bzip2", func() {
+			if err := exec.Command("bzip2", archive).Run(); err != nil {
+				Skip("cannot run bzip2: " + err.Error())
+			}
+			listed, err := compress.ListTarGzArchive(archive + ".bz2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(listed).To(HaveLen(4))
+		})
+
+		It("should tell how to decompress a tar compressed with xz", func() {
+			if err := exec.Command("xz", archive).Run(); err != nil {
+				Skip("cannot run xz: " + err.Error())
+			}
+			_, err := compress.ExtractTarGzArchive(archive+".xz", filepath.Join(outputDir, "restored"), compress.ExtractOptions{})
+			Expect(err).To(MatchError(compress.ErrUnsupportedArchive))
+			Expect(err).To(MatchError(ContainSubstring("xz -dk legacy.tar.xz")))
+			Expect(filepath.Join(outputDir, "restored")).NotTo(BeADirectory())
+		})
+
+		It("should tell the extension of a name made by another tool", func() {
+			Expect(compress.ForeignExtension("photos-2019.tgz")).To(Equal(".tgz"))
+			Expect(compress.ForeignExtension("home.tar.bz2.gpg")).To(Equal(".tar.bz2"))
+			Expect(compress.ForeignExtension("project-20250520-123045.tar.gz")).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,154 @@
+package compress
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+)
+
+// hashCacheVersion is the format of the checksum cache file; files of other versions are ignored
+const hashCacheVersion = 1
+
+// racyWindow is how recently a file may have been modified and still be cached. A file written
+// again within the file system's timestamp resolution of being hashed could keep its size and
+// modification time, so such files are hashed again on the next run.
+const racyWindow = 2 * time.Second
+
+// HashCache remembers the SHA-256 of a source's files by their size, modification time and,
+// where the platform has them, inode and change time. With a cache, ScanSourceContext only
+// reads the files that changed since they were cached. It is not safe for concurrent use.
+type HashCache struct {
+	path   string
+	files  map[string]hashCacheEntry
+	used   map[string]bool
+	dirty  bool
+	cached int
+	hashed int
+}
+
+// hashCacheEntry is what the cache knows about one file
+type hashCacheEntry struct {
+	Size    int64       `json:"size"`
+	ModTime int64       `json:"mtime"` // Unix nanoseconds
+	Mode    os.FileMode `json:"mode"`
+	Inode   uint64      `json:"inode,omitempty"`
+	Change  int64       `json:"ctime,omitempty"` // Inode change time in Unix nanoseconds
+	SHA256  string      `json:"sha256"`
+}
+
+// hashCacheFile is the JSON file holding a cache
+type hashCacheFile struct {
+	Version int                       `json:"version"`
+	Files   map[string]hashCacheEntry `json:"files"`
+}
+
+// LoadHashCache reads the checksum cache stored at path. A missing, unreadable or damaged
+// file gives an empty cache, since every checksum can be computed again.
+func LoadHashCache(path string) *HashCache {
+	cache := &HashCache{path: path, files: make(map[string]hashCacheEntry), used: make(map[string]bool)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	var file hashCacheFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != hashCacheVersion {
+		cache.dirty = true
+		return cache
+	}
+	for name, entry := range file.Files {
+		cache.files[name] = entry
+	}
+	return cache
+}
+
+// newHashCacheEntry describes the file as it is now, with its checksum
+func newHashCacheEntry(info os.FileInfo, sum string) hashCacheEntry {
+	inode, change := fileIdentity(info)
+	return hashCacheEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Mode:    info.Mode(),
+		Inode:   inode,
+		Change:  change,
+		SHA256:  sum,
+	}
+}
+
+// lookup returns the cached checksum of the file at relPath if the file is unchanged since it was cached
+func (c *HashCache) lookup(relPath string, info os.FileInfo) (string, bool) {
+	entry, ok := c.files[relPath]
+	if !ok || entry.SHA256 == "" || entry != newHashCacheEntry(info, entry.SHA256) {
+		return "", false
+	}
+	c.used[relPath] = true
+	c.cached++
+	return entry.SHA256, true
+}
+
+// store records the checksum of the file at relPath, read starting at hashedAt. Files modified
+// shortly before that are not cached, and names that are not valid UTF-8 cannot be stored in JSON.
+func (c *HashCache) store(relPath string, info os.FileInfo, sum string, hashedAt time.Time) {
+	c.hashed++
+	if !utf8.ValidString(relPath) || info.ModTime().After(hashedAt.Add(-racyWindow)) {
+		if _, ok := c.files[relPath]; ok {
+			delete(c.files, relPath)
+			c.dirty = true
+		}
+		return
+	}
+	entry := newHashCacheEntry(info, sum)
+	if c.files[relPath] != entry {
+		c.files[relPath] = entry
+		c.dirty = true
+	}
+	c.used[relPath] = true
+}
+
+// Stats returns how many checksums were taken from the cache and how many files were read
+func (c *HashCache) Stats() (cached, hashed int) {
+	return c.cached, c.hashed
+}
+
+// Save writes the cache back to its file, replacing it atomically. Only the files seen since the
+// cache was loaded are kept, so deleted and excluded files do not accumulate.
+func (c *HashCache) Save() error {
+	for name := range c.files {
+		if !c.used[name] {
+			delete(c.files, name)
+			c.dirty = true
+		}
+	}
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(hashCacheFile{Version: hashCacheVersion, Files: c.files})
+	if err != nil {
+		return fmt.Errorf("error encoding checksum cache: %w", err)
+	}
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error creating checksum cache directory: %w", err)
+	}
+	tmpFile, err := os.CreateTemp(dir, "."+filepath.Base(c.path)+".tmp-")
+	if err != nil {
+		return fmt.Errorf("error writing checksum cache: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("error writing checksum cache: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("error writing checksum cache: %w", err)
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		return fmt.Errorf("error writing checksum cache: %w", err)
+	}
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,16 @@
+package compress
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileIdentity returns the inode and change time of a file. The change time moves whenever the
+// contents or metadata change, even when a tool such as 'touch -r' restores the modification time.
+func fileIdentity(info os.FileInfo) (uint64, int64) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0
+	}
+	return stat.Ino, stat.Ctim.Nano()
+}
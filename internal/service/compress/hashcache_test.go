@@ -0,0 +1,135 @@
+package compress_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/kennycyb/go-backup/internal/service/compress"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checksum cache", func() {
+	var (
+		sourceDir string
+		cachePath string
+		archive   string
+		old       time.Time
+	)
+
+	// writeOld writes a file last modified an hour ago, so its checksum can be cached
+	writeOld := func(name, contents string) {
+		path := filepath.Join(sourceDir, name)
+		Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		Expect(os.Chtimes(path, old, old)).To(Succeed())
+	}
+
+	// scan scans the source with the cache stored at cachePath and saves it again
+	scan := func() (*compress.ArchiveResult, int, int) {
+		cache := compress.LoadHashCache(cachePath)
+		result, err := compress.ScanSourceContext(context.Background(), sourceDir, compress.ArchiveOptions{Checksums: cache})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cache.Save()).To(Succeed())
+		cached, hashed := cache.Stats()
+		return result, cached, hashed
+	}
+
+	BeforeEach(func() {
+		sourceDir = GinkgoT().TempDir()
+		stateDir := GinkgoT().TempDir()
+		cachePath = filepath.Join(stateDir, "checksums", "project.json")
+		archive = filepath.Join(stateDir, "backup.tar.gz")
+		old = time.Now().Add(-time.Hour).Truncate(time.Second)
+
+		Expect(os.Mkdir(filepath.Join(sourceDir, "docs"), 0755)).To(Succeed())
+		writeOld("a.txt", "alpha")
+		writeOld(filepath.Join("docs", "b.txt"), "bravo")
+	})
+
+	It("should reuse the checksums cached while archiving", func() {
+		cache := compress.LoadHashCache(cachePath)
+		archived, err := compress.CreateTarGzArchivesContext(context.Background(), sourceDir,
+			[]compress.ArchiveOutput{{Path: archive}}, compress.ArchiveOptions{Checksums: cache})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cache.Save()).To(Succeed())
+
+		result, cached, hashed := scan()
+		Expect(cached).To(Equal(2))
+		Expect(hashed).To(BeZero())
+		Expect(result.Entries).To(Equal(archived.Entries))
+	})
+
+	It("should return the same entries as an uncached scan", func() {
+		uncached, err := compress.ScanSourceContext(context.Background(), sourceDir, compress.ArchiveOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		first, _, hashed := scan()
+		Expect(hashed).To(Equal(2))
+		second, cached, _ := scan()
+		Expect(cached).To(Equal(2))
+		Expect(first.Entries).To(Equal(uncached.Entries))
+		Expect(second.Entries).To(Equal(uncached.Entries))
+	})
+
+	It("should read files whose size or modification time changed", func() {
+		scan()
+		writeOld("a.txt", "ALPHA")
+		later := old.Add(time.Minute)
+		Expect(os.Chtimes(filepath.Join(sourceDir, "a.txt"), later, later)).To(Succeed())
+
+		result, cached, hashed := scan()
+		Expect(cached).To(Equal(1))
+		Expect(hashed).To(Equal(1))
+
+		uncached, err := compress.ScanSourceContext(context.Background(), sourceDir, compress.ArchiveOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Entries).To(Equal(uncached.Entries))
+	})
+
+	It("should read files changed without a new modification time", func() {
+		if runtime.GOOS != "linux" {
+			Skip("change times are only compared on Linux")
+		}
+		first, _, _ := scan()
+		writeOld("a.txt", "ALPHA")
+
+		result, _, hashed := scan()
+		Expect(hashed).To(Equal(1))
+		Expect(result.Entries[0].SHA256).NotTo(Equal(first.Entries[0].SHA256))
+	})
+
+	It("should not cache files modified just before they were read", func() {
+		Expect(os.WriteFile(filepath.Join(sourceDir, "new.txt"), []byte("new"), 0644)).To(Succeed())
+		scan()
+
+		_, cached, hashed := scan()
+		Expect(cached).To(Equal(2))
+		Expect(hashed).To(Equal(1))
+	})
+
+	It("should forget deleted files", func() {
+		scan()
+		Expect(os.Remove(filepath.Join(sourceDir, "a.txt"))).To(Succeed())
+		scan()
+
+		data, err := os.ReadFile(cachePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).NotTo(ContainSubstring("a.txt"))
+		Expect(string(data)).To(ContainSubstring("docs/b.txt"))
+	})
+
+	It("should start over from a damaged cache", func() {
+		Expect(os.MkdirAll(filepath.Dir(cachePath), 0700)).To(Succeed())
+		Expect(os.WriteFile(cachePath, []byte("{not json"), 0600)).To(Succeed())
+
+		_, cached, hashed := scan()
+		Expect(cached).To(BeZero())
+		Expect(hashed).To(Equal(2))
+
+		_, cached, _ = scan()
+		Expect(cached).To(Equal(2))
+	})
+})
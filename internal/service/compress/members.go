@@ -0,0 +1,79 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// storeMinSize is the smallest file that starts a stored gzip member. Smaller files stay in
+// the current member, since each switch costs a member header and the compression context.
+const storeMinSize = 64 << 10
+
+// gzipMembers writes a gzip stream as a series of members, so incompressible files can be
+// stored without compression while the rest is compressed. gzip readers, including gzip -d
+// and tar, decompress concatenated members as a single stream.
+type gzipMembers struct {
+	w      io.Writer
+	level  int  // Level of compressed members
+	stored bool // Whether the current member is stored
+	gz     *gzip.Writer
+}
+
+// newGzipMembers returns a writer starting with a compressed member at the given level
+func newGzipMembers(w io.Writer, level int) (*gzipMembers, error) {
+	gz, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, err
+	}
+	return &gzipMembers{w: w, level: level, gz: gz}, nil
+}
+
+// Write compresses or stores p in the current member
+func (g *gzipMembers) Write(p []byte) (int, error) {
+	return g.gz.Write(p)
+}
+
+// SetStored finishes the current member and starts a new one when switching
+// between stored and compressed data
+func (g *gzipMembers) SetStored(stored bool) error {
+	if stored == g.stored {
+		return nil
+	}
+	if err := g.gz.Close(); err != nil {
+		return err
+	}
+
+	level := g.level
+	if stored {
+		level = gzip.NoCompression
+	}
+	gz, err := gzip.NewWriterLevel(g.w, level)
+	if err != nil {
+		return err
+	}
+	g.gz, g.stored = gz, stored
+	return nil
+}
+
+// Restart finishes the current member and starts a new one of the same kind, so the data
+// written from here on can be decompressed without what came before
+func (g *gzipMembers) Restart() error {
+	if err := g.gz.Close(); err != nil {
+		return err
+	}
+	level := g.level
+	if g.stored {
+		level = gzip.NoCompression
+	}
+	gz, err := gzip.NewWriterLevel(g.w, level)
+	if err != nil {
+		return err
+	}
+	g.gz = gz
+	return nil
+}
+
+// Close finishes the last member
+func (g *gzipMembers) Close() error {
+	return g.gz.Close()
+}
@@ -0,0 +1,90 @@
+package compress
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// QuotePath returns a path for printing. Paths with control characters, such as newlines or
+// ANSI escapes, or bytes that are not UTF-8 are printed as a Go string literal, e.g. "a\nb",
+// so a hostile file name cannot break the output or the terminal. Other paths are unchanged.
+func QuotePath(path string) string {
+	if strings.HasPrefix(path, `"`) || !utf8.ValidString(path) {
+		return strconv.Quote(path)
+	}
+	for _, r := range path {
+		if !strconv.IsPrint(r) {
+			return strconv.Quote(path)
+		}
+	}
+	return path
+}
+
+// rawString holds a string that is not valid UTF-8 as base64 in JSON, since encoding/json
+// would replace its invalid bytes; it is empty for valid strings
+func rawString(s string) []byte {
+	if utf8.ValidString(s) {
+		return nil
+	}
+	return []byte(s)
+}
+
+// MarshalJSON writes the entry with its path and link target exactly: names that are not
+// valid UTF-8 are stored again as base64 in rawPath and rawLink
+func (e ArchiveEntry) MarshalJSON() ([]byte, error) {
+	type entry ArchiveEntry
+	return json.Marshal(struct {
+		entry
+		RawPath []byte `json:"rawPath,omitempty"`
+		RawLink []byte `json:"rawLink,omitempty"`
+	}{entry: entry(e), RawPath: rawString(e.Path), RawLink: rawString(e.Link)})
+}
+
+// UnmarshalJSON reads an entry written by MarshalJSON
+func (e *ArchiveEntry) UnmarshalJSON(data []byte) error {
+	type entry ArchiveEntry
+	var decoded struct {
+		entry
+		RawPath []byte `json:"rawPath"`
+		RawLink []byte `json:"rawLink"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*e = ArchiveEntry(decoded.entry)
+	if decoded.RawPath != nil {
+		e.Path = string(decoded.RawPath)
+	}
+	if decoded.RawLink != nil {
+		e.Link = string(decoded.RawLink)
+	}
+	return nil
+}
+
+// MarshalJSON writes the warning with its path exactly, like ArchiveEntry.MarshalJSON
+func (w Warning) MarshalJSON() ([]byte, error) {
+	type warning Warning
+	return json.Marshal(struct {
+		warning
+		RawPath []byte `json:"rawPath,omitempty"`
+	}{warning: warning(w), RawPath: rawString(w.Path)})
+}
+
+// UnmarshalJSON reads a warning written by MarshalJSON
+func (w *Warning) UnmarshalJSON(data []byte) error {
+	type warning Warning
+	var decoded struct {
+		warning
+		RawPath []byte `json:"rawPath"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*w = Warning(decoded.warning)
+	if decoded.RawPath != nil {
+		w.Path = string(decoded.RawPath)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package compress_test
+
+import (
+	"encoding/json"
+
+	"github.com/kennycyb/go-backup/internal/service/compress"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Paths", func() {
+	Describe("QuotePath", func() {
+		It("should leave ordinary paths unchanged", func() {
+			Expect(compress.QuotePath("src/main.go")).To(Equal("src/main.go"))
+			Expect(compress.QuotePath("docs/résumé with spaces.txt")).To(Equal("docs/résumé with spaces.txt"))
+		})
+
+		It("should quote paths with control characters", func() {
+			Expect(compress.QuotePath("a\nb")).To(Equal(`"a\nb"`))
+			Expect(compress.QuotePath("\x1b[2Jclear")).To(Equal(`"\x1b[2Jclear"`))
+		})
+
+		It("should quote paths that are not UTF-8", func() {
+			Expect(compress.QuotePath("caf\xe9")).To(Equal(`"caf\xe9"`))
+		})
+
+		It("should quote paths starting with a quote, so quoted paths are unambiguous", func() {
+			Expect(compress.QuotePath(`"a\nb"`)).To(Equal(`"\"a\\nb\""`))
+		})
+	})
+
+	Describe("ArchiveEntry JSON", func() {
+		It("should store valid UTF-8 names as plain strings", func() {
+			data, err := json.Marshal(compress.ArchiveEntry{Path: "a\nb", Link: "target"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring(`"path":"a\nb"`))
+			Expect(string(data)).NotTo(ContainSubstring("rawPath"))
+		})
+
+		It("should roundtrip names that are not UTF-8", func() {
+			data, err := json.Marshal(compress.ArchiveEntry{Path: "bad\xffname", Link: "to\xfe", Size: 3})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring("rawPath"))
+
+			var entry compress.ArchiveEntry
+			Expect(json.Unmarshal(data, &entry)).To(Succeed())
+			Expect(entry.Path).To(Equal("bad\xffname"))
+			Expect(entry.Link).To(Equal("to\xfe"))
+			Expect(entry.Size).To(Equal(int64(3)))
+		})
+	})
+})
@@ -0,0 +1,122 @@
+package compress
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// entrySelection picks the entries of ExtractOptions.Files and ExtractOptions.Patterns; an entry
+// picked by either is extracted. Without files or patterns it picks every entry.
+type entrySelection struct {
+	files    fileSelection     // nil without listed files
+	patterns *patternSelection // nil without patterns
+}
+
+// newEntrySelection parses the listed paths and patterns.
+// Returns an error for paths that leave the archive's root and for invalid patterns.
+func newEntrySelection(files, patterns []string) (entrySelection, error) {
+	var s entrySelection
+	var err error
+	if s.files, err = newFileSelection(files); err != nil {
+		return s, err
+	}
+	if patterns != nil {
+		if s.patterns, err = newPatternSelection(patterns); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+// match reports whether the entry is picked, marking the paths and patterns that picked it
+func (s entrySelection) match(name string) bool {
+	if s.files == nil && s.patterns == nil {
+		return true
+	}
+	picked := s.files != nil && s.files.match(name)
+	if s.patterns != nil && s.patterns.match(name) {
+		picked = true
+	}
+	return picked
+}
+
+// missing returns the listed paths and the patterns that picked no entry
+func (s entrySelection) missing(files []string) []string {
+	var missing []string
+	if s.files != nil {
+		missing = s.files.missing(files)
+	}
+	if s.patterns != nil {
+		missing = append(missing, s.patterns.missing()...)
+	}
+	return missing
+}
+
+// patternSelection picks the entries matching patterns with the syntax of excludes, e.g.
+// "src/**", "docs/*.md" or "*.go"; the last matching pattern wins, so "!src/vendor/**" after
+// "src/**" leaves the vendor directory out again
+type patternSelection struct {
+	patterns []string
+	rules    []excludeRule
+	matched  []bool // Whether each pattern picked an entry
+}
+
+// newPatternSelection parses the patterns. Returns an error for an invalid pattern.
+func newPatternSelection(patterns []string) (*patternSelection, error) {
+	s := &patternSelection{patterns: patterns, matched: make([]bool, len(patterns))}
+	for _, pattern := range patterns {
+		rule, ok := parseExcludePattern(pattern)
+		if !ok {
+			return nil, fmt.Errorf("invalid pattern %q: pattern is empty", pattern)
+		}
+		for _, segment := range rule.segments {
+			if _, err := filepath.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+		s.rules = append(s.rules, rule)
+	}
+	return s, nil
+}
+
+// match reports whether the last pattern matching the entry picks it
+func (s *patternSelection) match(name string) bool {
+	segments := strings.Split(name, "/")
+	last := -1
+	for i, rule := range s.rules {
+		if rule.matches(segments) {
+			last = i
+		}
+	}
+	if last < 0 || s.rules[last].negate {
+		return false
+	}
+	s.matched[last] = true
+	return true
+}
+
+// missing returns the patterns, other than negations, that picked no entry
+func (s *patternSelection) missing() []string {
+	var missing []string
+	for i, pattern := range s.patterns {
+		if !s.matched[i] && !s.rules[i].negate {
+			missing = append(missing, pattern)
+		}
+	}
+	return missing
+}
+
+// InSelection reports whether an archive entry is picked by the paths of ExtractOptions.Files or
+// the patterns of ExtractOptions.Patterns. Without either every entry is picked.
+func InSelection(name string, files, patterns []string) bool {
+	selection, err := newEntrySelection(files, patterns)
+	return err == nil && selection.match(name)
+}
+
+// ValidatePatterns checks patterns for ExtractOptions.Patterns.
+// Returns an error describing the first invalid pattern.
+func ValidatePatterns(patterns []string) error {
+	_, err := newPatternSelection(patterns)
+	return err
+}
@@ -0,0 +1,94 @@
+package compress
+
+import (
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Thresholds for reporting a file type as incompressible
+const (
+	incompressibleRatio   = 0.97    // Less than 3% saved
+	incompressibleMinSize = 10 * MB // Smaller totals are not worth a suggestion
+)
+
+// ArchiveStats collects per-extension compression statistics while an archive is written.
+// Compressed sizes are attributed to the file being added when the bytes reach the archive,
+// so they are approximate for small files but accurate in aggregate.
+type ArchiveStats struct {
+	Extensions map[string]*ExtensionStats // Keyed by lower-case extension, e.g. ".jpg"
+}
+
+// ExtensionStats is the compression outcome for all archived files with one extension
+type ExtensionStats struct {
+	Extension      string // Lower-case extension including the dot; empty for files without one
+	Files          int
+	Size           int64         // Original size of the files
+	CompressedSize int64         // Bytes the files took in the archive
+	Duration       time.Duration // Time spent reading and compressing the files
+	StoredSize     int64         // Bytes of files stored without compression (see ArchiveOutput.NoCompress)
+}
+
+// Ratio returns the compressed size divided by the original size of the files that were compressed
+func (s *ExtensionStats) Ratio() float64 {
+	if s.Size-s.StoredSize <= 0 {
+		return 1
+	}
+	return float64(s.CompressedSize) / float64(s.Size-s.StoredSize)
+}
+
+// record adds one archived file to the statistics of its extension
+func (a *ArchiveStats) record(relPath string, size, compressedSize int64, duration time.Duration, stored bool) {
+	if a.Extensions == nil {
+		a.Extensions = make(map[string]*ExtensionStats)
+	}
+	ext := strings.ToLower(filepath.Ext(relPath))
+	stats, ok := a.Extensions[ext]
+	if !ok {
+		stats = &ExtensionStats{Extension: ext}
+		a.Extensions[ext] = stats
+	}
+
+	stats.Files++
+	stats.Size += size
+	stats.Duration += duration
+	if stored {
+		stats.StoredSize += size
+	} else {
+		stats.CompressedSize += compressedSize
+	}
+}
+
+// Incompressible returns the extensions whose compressed files saved almost no space,
+// largest first. Files without an extension and files already stored are not included.
+func (a *ArchiveStats) Incompressible() []ExtensionStats {
+	var result []ExtensionStats
+	for _, stats := range a.Extensions {
+		compressed := stats.Size - stats.StoredSize
+		if stats.Extension == "" || compressed < incompressibleMinSize || stats.Ratio() < incompressibleRatio {
+			continue
+		}
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Size == result[j].Size {
+			return result[i].Extension < result[j].Extension
+		}
+		return result[i].Size > result[j].Size
+	})
+	return result
+}
+
+// countingWriter passes writes through to w and counts the bytes written
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
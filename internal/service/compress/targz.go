@@ -2,37 +2,222 @@ package compress
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
+// ArchiveEntry describes a file or directory written to an archive
+type ArchiveEntry struct {
+	Path    string      `json:"path"` // Path relative to the source directory, with forward slashes
+	Size    int64       `json:"size"`
+	Mode    os.FileMode `json:"mode"`
+	ModTime time.Time   `json:"modTime"`
+	IsDir   bool        `json:"dir,omitempty"`
+	Link    string      `json:"link,omitempty"`   // Target of a symlink
+	SHA256  string      `json:"sha256,omitempty"` // Hex SHA-256 of a regular file's contents; empty when listed from an archive
+}
+
+// ArchiveOutput describes one archive produced by CreateTarGzArchives
+type ArchiveOutput struct {
+	Path       string        // Target file to write
+	Format     string        // FormatTarGz (the default), FormatTar or FormatZip
+	Level      int           // gzip compression level from 1 (fastest) to 9 (smallest); 0 uses the gzip default
+	NoCompress []string      // Patterns of files stored without compression, matched like excludes (e.g. "*.jpg")
+	Stats      *ArchiveStats // When set, filled with per-extension compression statistics
+	// ChunkSize, when positive, splits a tar.gz archive into chunks that can be read on their own:
+	// a new chunk starts with the first entry after about this many compressed bytes
+	ChunkSize int64
+	Chunks    *[]ArchiveChunk // When set with ChunkSize, filled with the chunks written
+	SHA256    *string         // When set, filled with the hex SHA-256 of the archive, computed while it is written
+}
+
+// ArchiveOptions controls which paths CreateTarGzArchivesContext archives and how unreadable ones are handled
+type ArchiveOptions struct {
+	Excludes       []string // Exclude patterns, see ExcludeMatcher
+	SkipUnreadable bool     // Leave out files and directories that cannot be read instead of failing
+	// Files, when not nil, are the only paths archived, relative to the source and in this order
+	// (see CleanFileList). A listed directory is archived without its contents; excludes still apply.
+	Files []string
+	// Checksums, when set, caches the checksum of every file read, so ScanSourceContext can reuse them
+	Checksums *HashCache
+}
+
+// ArchiveResult describes what CreateTarGzArchivesContext wrote
+type ArchiveResult struct {
+	Entries  []ArchiveEntry // Entries written to every archive, in archive order
+	Warnings []Warning      // Non-fatal issues, e.g. paths left out because they could not be read
+}
+
 // CreateTarGzArchive creates a compressed tar archive from the source directory,
-// excluding the specified paths. Returns an error if the operation fails.
+// excluding the paths matched by the exclude patterns (see ExcludeMatcher). The target file itself is never added to the
+// archive, even when it is written inside the source directory.
+// Returns an error if the operation fails.
 func CreateTarGzArchive(sourceDir, targetFile string, excludes []string) error {
-	// Create the target file
-	tarFile, err := os.Create(targetFile)
+	_, err := CreateTarGzArchives(sourceDir, []ArchiveOutput{{Path: targetFile}}, excludes)
+	return err
+}
+
+// CreateTarGzArchives creates one compressed tar archive per output from a single walk
+// of the source directory, so several variants (e.g. different compression levels)
+// cost only one scan. The outputs never include each other.
+// It returns the entries written to every archive, in archive order.
+// Returns an error if the operation fails.
+func CreateTarGzArchives(sourceDir string, outputs []ArchiveOutput, excludes []string) ([]ArchiveEntry, error) {
+	result, err := CreateTarGzArchivesContext(context.Background(), sourceDir, outputs, ArchiveOptions{Excludes: excludes})
 	if err != nil {
-		return fmt.Errorf("error creating target file: %w", err)
+		return nil, err
+	}
+	return result.Entries, nil
+}
+
+// CreateTarGzArchivesContext is CreateTarGzArchives with options, that stops when the context is done,
+// e.g. when a run times out. The error then wraps the context's error and the outputs are incomplete.
+// With opts.SkipUnreadable, paths that cannot be read are reported as warnings instead of failing.
+// Symlinks are stored as links; sockets, pipes and devices are always left out with a warning.
+func CreateTarGzArchivesContext(ctx context.Context, sourceDir string, outputs []ArchiveOutput, opts ArchiveOptions) (*ArchiveResult, error) {
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("no archive outputs specified")
+	}
+	return writeArchives(ctx, sourceDir, outputs, opts)
+}
+
+// ScanSourceContext returns the entries and warnings CreateTarGzArchivesContext would produce with
+// the options, without writing an archive. With opts.Checksums, files unchanged since their checksum
+// was cached are not read, so scanning an unchanged source only looks at its metadata.
+func ScanSourceContext(ctx context.Context, sourceDir string, opts ArchiveOptions) (*ArchiveResult, error) {
+	return writeArchives(ctx, sourceDir, nil, opts)
+}
+
+// writeArchives walks the source once, writing every path to each output; without outputs it only
+// collects the entries
+func writeArchives(ctx context.Context, sourceDir string, outputs []ArchiveOutput, opts ArchiveOptions) (*ArchiveResult, error) {
+	skipPaths := make(map[string]bool)
+	for _, output := range outputs {
+		absTarget, err := filepath.Abs(output.Path)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving target file path: %w", err)
+		}
+		skipPaths[absTarget] = true
 	}
-	defer tarFile.Close()
 
-	// Create a gzip writer
-	gzWriter := gzip.NewWriter(tarFile)
-	defer gzWriter.Close()
+	var files []*os.File
+	var counters []*countingWriter
+	var hashes []hash.Hash       // nil for outputs whose checksum is not wanted
+	var gzWriters []*gzipMembers // nil for outputs that are not tar.gz
+	var noCompress []*ExcludeMatcher
+	var tarWriters []*tar.Writer // nil for zip outputs
+	var zipWriters []*zip.Writer // nil for tar outputs
+	var chunks [][]ArchiveChunk
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
 
-	// Create a tar writer with PAX format for large file support
-	tarWriter := tar.NewWriter(gzWriter)
-	defer tarWriter.Close()
+	for _, output := range outputs {
+		level := output.Level
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
 
-	// Walk the source directory
-	return filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+		// Create the target file
+		tarFile, err := os.Create(output.Path)
 		if err != nil {
+			return nil, fmt.Errorf("error creating target file: %w", err)
+		}
+		files = append(files, tarFile)
+
+		// The counter measures the compressed bytes of each file for the stats
+		counter := &countingWriter{w: tarFile}
+		counters = append(counters, counter)
+		var sum hash.Hash
+		if output.SHA256 != nil {
+			sum = sha256.New()
+			counter.w = io.MultiWriter(tarFile, sum)
+		}
+		hashes = append(hashes, sum)
+
+		var matcher *ExcludeMatcher
+		if len(output.NoCompress) > 0 {
+			matcher = NewExcludeMatcher(output.NoCompress)
+		}
+		noCompress = append(noCompress, matcher)
+		chunks = append(chunks, []ArchiveChunk{{}})
+
+		// Create the writer of the format; tar entries are written in PAX format
+		if err := ValidateFormat(output.Format); err != nil {
+			return nil, err
+		}
+		if output.ChunkSize > 0 && output.Format != "" && output.Format != FormatTarGz {
+			return nil, fmt.Errorf("chunks need the %s format, not %s", FormatTarGz, output.Format)
+		}
+		var gzWriter *gzipMembers
+		var tarWriter *tar.Writer
+		var zipWriter *zip.Writer
+		switch output.Format {
+		case FormatTar:
+			tarWriter = tar.NewWriter(counter)
+		case FormatZip:
+			if zipWriter, err = newZipWriter(counter, level); err != nil {
+				return nil, fmt.Errorf("invalid compression level %d: %w", output.Level, err)
+			}
+		default:
+			if gzWriter, err = newGzipMembers(counter, level); err != nil {
+				return nil, fmt.Errorf("invalid compression level %d: %w", output.Level, err)
+			}
+			tarWriter = tar.NewWriter(gzWriter)
+		}
+		gzWriters = append(gzWriters, gzWriter)
+		tarWriters = append(tarWriters, tarWriter)
+		zipWriters = append(zipWriters, zipWriter)
+	}
+
+	// endChunk records the end of the output's current chunk, once the tar stream is at an
+	// entry boundary, and starts the next one with entry first
+	endChunk := func(i, first int) {
+		chunk := &chunks[i][len(chunks[i])-1]
+		chunk.Size = counters[i].n - chunk.Offset
+		chunk.Entries = first - chunk.First
+		chunks[i] = append(chunks[i], ArchiveChunk{Offset: counters[i].n, First: first})
+	}
+
+	matcher := NewExcludeMatcher(opts.Excludes)
+	var entries []ArchiveEntry
+	var warnings []Warning
+
+	// skip records a path that cannot be read, or returns the error when unreadable paths fail the archive
+	skip := func(relPath string, err error) error {
+		if !opts.SkipUnreadable {
 			return err
 		}
+		warnings = append(warnings, newPathWarning(relPath, err))
+		return nil
+	}
+
+	// addPath archives one path of the source; it is called for every path the walk visits,
+	// or for each listed path with opts.Files
+	addPath := func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("archiving stopped: %w", ctxErr)
+		}
+		if err != nil {
+			// The path vanished or its directory cannot be listed; excluded paths do not matter
+			relPath, relErr := filepath.Rel(sourceDir, path)
+			if relErr != nil || relPath == "." || matcher.Excluded(relPath) {
+				return err
+			}
+			return skip(relPath, err)
+		}
 
 		// Get the relative path for exclusion checking
 		relPath, err := filepath.Rel(sourceDir, path)
@@ -45,25 +230,50 @@ func CreateTarGzArchive(sourceDir, targetFile string, excludes []string) error {
 			return nil
 		}
 
-		// Skip excluded directories and files
-		for _, exclude := range excludes {
-			// Check for exact match, prefix match with /, or glob pattern
-			matched, _ := filepath.Match(exclude, relPath)
-			if matched || strings.Contains(relPath, exclude) || strings.HasPrefix(relPath, exclude) {
-				if info.IsDir() {
-					return filepath.SkipDir
-				}
-				return nil
+		// Skip excluded directories and files; excluded directories are still walked
+		// when a negation pattern could re-include something inside them
+		if matcher.Excluded(relPath) {
+			if info.IsDir() && matcher.CanSkipDir(relPath) {
+				return filepath.SkipDir
 			}
+			return nil
 		}
 
-		// Skip the temporary directory
-		if strings.HasPrefix(path, os.TempDir()) {
+		// Skip the archives being written
+		if absPath, err := filepath.Abs(path); err == nil && skipPaths[absPath] {
+			return nil
+		}
+
+		// Open files and read links before writing their header, so unreadable ones can be left out cleanly
+		var file *os.File
+		var link, cachedSum string
+		if info.Mode().IsRegular() && len(outputs) == 0 && opts.Checksums != nil {
+			cachedSum, _ = opts.Checksums.lookup(filepath.ToSlash(relPath), info)
+		}
+		switch mode := info.Mode(); {
+		case mode.IsRegular() && cachedSum != "":
+			// A scan takes the checksum of an unchanged file from the cache instead of reading it
+		case mode.IsRegular():
+			file, err = os.Open(path)
+			if err != nil {
+				return skip(relPath, fmt.Errorf("error opening file %s: %w", path, err))
+			}
+			defer file.Close()
+		case mode&os.ModeSymlink != 0:
+			link, err = os.Readlink(path)
+			if err != nil {
+				return skip(relPath, fmt.Errorf("error reading symlink %s: %w", path, err))
+			}
+			if _, err := os.Stat(path); err != nil && isSymlinkLoop(err) {
+				warnings = append(warnings, Warning{Kind: WarningSymlinkLoop, Path: filepath.ToSlash(relPath), Message: "symlink stored, but it resolves to a loop"})
+			}
+		case !mode.IsDir():
+			warnings = append(warnings, Warning{Kind: WarningSpecialFile, Path: filepath.ToSlash(relPath), Message: "sockets, pipes and devices are not backed up"})
 			return nil
 		}
 
 		// Create a header based on the file info
-		header, err := tar.FileInfoHeader(info, relPath)
+		header, err := tar.FileInfoHeader(info, link)
 		if err != nil {
 			return fmt.Errorf("error creating tar header: %w", err)
 		}
@@ -71,33 +281,251 @@ func CreateTarGzArchive(sourceDir, targetFile string, excludes []string) error {
 		// Update the header name to use the relative path
 		header.Name = relPath
 
-		// Use PAX format for large files
-		if info.Size() > RecommendedMaxFileSize {
-			header.Format = tar.FormatPAX
+		// Always use PAX format so long paths, non-ASCII names and files over 8GB are
+		// stored exactly instead of depending on per-entry format selection
+		header.Format = tar.FormatPAX
+
+		// Keep capabilities and chattr flags, so restored binaries and locked files work as before
+		if len(outputs) > 0 {
+			readFileAttrs(header, path, file)
 		}
 
-		// Write the header to the archive
-		if err := tarWriter.WriteHeader(header); err != nil {
-			return fmt.Errorf("error writing tar header for %s: %w", path, err)
+		// Write the header to every archive. Large incompressible files are stored in their own
+		// gzip member, starting with their header; small ones never force a switch. Zip stores
+		// them as entries without compression.
+		contentWriters := make([]io.Writer, 0, len(outputs))
+		startSizes := make([]int64, len(outputs))
+		stored := make([]bool, len(outputs))
+		for i, tarWriter := range tarWriters {
+			startSizes[i] = counters[i].n
+			if zipWriters[i] != nil {
+				stored[i] = noCompress[i] != nil && info.Mode().IsRegular() && noCompress[i].Excluded(relPath)
+				w, err := writeZipEntry(zipWriters[i], header, stored[i])
+				if err != nil {
+					return err
+				}
+				contentWriters = append(contentWriters, w)
+				continue
+			}
+			if gzWriters[i] == nil {
+				// Plain tar compresses nothing
+				if err := tarWriter.WriteHeader(header); err != nil {
+					return fmt.Errorf("error writing tar header for %s: %w", path, err)
+				}
+				contentWriters = append(contentWriters, tarWriter)
+				stored[i] = true
+				continue
+			}
+
+			current := chunks[i][len(chunks[i])-1]
+			if outputs[i].ChunkSize > 0 && counters[i].n-current.Offset >= outputs[i].ChunkSize {
+				if err := tarWriter.Flush(); err != nil {
+					return fmt.Errorf("error writing tar archive: %w", err)
+				}
+				if err := gzWriters[i].Restart(); err != nil {
+					return fmt.Errorf("error writing gzip stream: %w", err)
+				}
+				endChunk(i, len(entries))
+				startSizes[i] = counters[i].n
+			}
+			if noCompress[i] != nil && info.Mode().IsRegular() {
+				store := noCompress[i].Excluded(relPath)
+				if !store || info.Size() >= storeMinSize {
+					if err := tarWriter.Flush(); err != nil {
+						return fmt.Errorf("error writing tar archive: %w", err)
+					}
+					if err := gzWriters[i].SetStored(store); err != nil {
+						return fmt.Errorf("error writing gzip stream: %w", err)
+					}
+				}
+			}
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return fmt.Errorf("error writing tar header for %s: %w", path, err)
+			}
+			contentWriters = append(contentWriters, tarWriter)
+			stored[i] = gzWriters[i].stored
 		}
 
+		entries = append(entries, ArchiveEntry{
+			Path:    filepath.ToSlash(relPath),
+			Size:    header.Size,
+			Mode:    info.Mode(),
+			ModTime: info.ModTime(),
+			IsDir:   info.IsDir(),
+			Link:    link,
+			SHA256:  cachedSum,
+		})
+
 		// If it's a regular file, write its contents
-		if !info.IsDir() {
-			file, err := os.Open(path)
-			if err != nil {
-				return fmt.Errorf("error opening file %s: %w", path, err)
-			}
-			defer file.Close()
+		if file != nil {
+			// Hash the contents while writing them, so later runs can tell whether only metadata changed
+			hasher := sha256.New()
+			contentWriters = append(contentWriters, hasher)
 
 			// Create a wrapper to handle files that might be too large
-			if _, err := io.Copy(tarWriter, file); err != nil {
+			start := time.Now()
+			if _, err := io.Copy(io.MultiWriter(contentWriters...), &contextReader{ctx: ctx, r: file}); err != nil {
+				if ctx.Err() != nil {
+					return fmt.Errorf("archiving stopped: %w", ctx.Err())
+				}
 				if strings.Contains(err.Error(), "write too long") {
 					return fmt.Errorf("file %s is too large for tar format (consider splitting large files): %w", path, err)
 				}
 				return fmt.Errorf("error writing file contents to tar: %w", err)
 			}
+			duration := time.Since(start)
+			entries[len(entries)-1].SHA256 = hex.EncodeToString(hasher.Sum(nil))
+			if opts.Checksums != nil {
+				opts.Checksums.store(filepath.ToSlash(relPath), info, entries[len(entries)-1].SHA256, start)
+			}
+
+			for i, output := range outputs {
+				if output.Stats != nil {
+					output.Stats.record(relPath, info.Size(), counters[i].n-startSizes[i], duration, stored[i])
+				}
+			}
 		}
 
 		return nil
-	})
+	}
+
+	if opts.Files != nil {
+		for _, relPath := range opts.Files {
+			path := filepath.Join(sourceDir, relPath)
+			info, err := os.Lstat(path)
+			if err := addPath(path, info, err); err != nil && err != filepath.SkipDir {
+				return nil, err
+			}
+		}
+	} else if err := filepath.Walk(sourceDir, addPath); err != nil {
+		return nil, err
+	}
+
+	// Flush the tar, gzip and zip footers; errors here mean a truncated archive
+	for i := range outputs {
+		if zipWriters[i] != nil {
+			if err := zipWriters[i].Close(); err != nil {
+				return nil, fmt.Errorf("error finalizing zip archive %s: %w", outputs[i].Path, err)
+			}
+			continue
+		}
+		if err := tarWriters[i].Close(); err != nil {
+			return nil, fmt.Errorf("error finalizing tar archive %s: %w", outputs[i].Path, err)
+		}
+		if gzWriters[i] == nil {
+			continue
+		}
+		if err := gzWriters[i].Close(); err != nil {
+			return nil, fmt.Errorf("error finalizing gzip stream %s: %w", outputs[i].Path, err)
+		}
+		if outputs[i].Chunks != nil && outputs[i].ChunkSize > 0 {
+			endChunk(i, len(entries))
+			*outputs[i].Chunks = chunks[i][:len(chunks[i])-1]
+		}
+	}
+	for i, sum := range hashes {
+		if sum != nil {
+			*outputs[i].SHA256 = hex.EncodeToString(sum.Sum(nil))
+		}
+	}
+
+	return &ArchiveResult{Entries: entries, Warnings: warnings}, nil
+}
+
+// contextReader stops reading once the context is done, so large files do not delay cancellation
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// ListTarGzArchive reads the entries of an archive without extracting it. Besides tar.gz,
+// plain tar, tar.bz2 and zip archives are read, including those made by other tools (see openArchive).
+// Returns an error if the file is not a valid archive.
+func ListTarGzArchive(archiveFile string) ([]ArchiveEntry, error) {
+	file, err := os.Open(archiveFile)
+	if err != nil {
+		return nil, fmt.Errorf("error opening archive: %w", err)
+	}
+	defer file.Close()
+
+	tarReader, err := openArchive(file, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ArchiveEntry
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading tar archive: %w", err)
+		}
+
+		info := header.FileInfo()
+		entries = append(entries, ArchiveEntry{
+			Path:    header.Name,
+			Size:    header.Size,
+			Mode:    info.Mode(),
+			ModTime: header.ModTime,
+			IsDir:   info.IsDir(),
+			Link:    header.Linkname,
+		})
+	}
+
+	return entries, nil
+}
+
+// CatTarGzFile writes the contents of the regular file at name, relative to the archive's root,
+// to w. A symlink's target is written instead of its contents.
+// Like ListTarGzArchive, it reads archives of every format go-backup knows.
+// Returns an error if the archive has no such file or is not a valid archive.
+func CatTarGzFile(archiveFile, name string, w io.Writer) error {
+	name, err := cleanListedPath(name)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(archiveFile)
+	if err != nil {
+		return fmt.Errorf("error opening archive: %w", err)
+	}
+	defer file.Close()
+
+	tarReader, err := openArchive(file, nil)
+	if err != nil {
+		return err
+	}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return fmt.Errorf("%s is not in the backup", QuotePath(name))
+		}
+		if err != nil {
+			return fmt.Errorf("error reading tar archive: %w", err)
+		}
+		if filepath.ToSlash(header.Name) != name {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeReg:
+			if _, err := io.Copy(w, tarReader); err != nil {
+				return fmt.Errorf("error reading %s: %w", QuotePath(name), err)
+			}
+			return nil
+		case tar.TypeSymlink:
+			_, err := fmt.Fprintln(w, header.Linkname)
+			return err
+		}
+		return fmt.Errorf("%s is not a file", QuotePath(name))
+	}
 }
@@ -0,0 +1,595 @@
+package compress_test
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kennycyb/go-backup/internal/service/compress"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TarGz", func() {
+	var (
+		sourceDir string
+		outputDir string
+	)
+
+	// readArchive returns the entry names and regular file contents of a tar.gz archive
+	readArchive := func(path string) ([]string, map[string]string) {
+		file, err := os.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		gzReader, err := gzip.NewReader(file)
+		Expect(err).NotTo(HaveOccurred())
+		tarReader := tar.NewReader(gzReader)
+
+		var names []string
+		contents := make(map[string]string)
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+			names = append(names, header.Name)
+			if header.Typeflag == tar.TypeReg {
+				data, err := io.ReadAll(tarReader)
+				Expect(err).NotTo(HaveOccurred())
+				contents[header.Name] = string(data)
+			}
+		}
+		sort.Strings(names)
+		return names, contents
+	}
+
+	BeforeEach(func() {
+		var err error
+		sourceDir, err = os.MkdirTemp("", "targz-source-")
+		Expect(err).NotTo(HaveOccurred())
+		outputDir, err = os.MkdirTemp("", "targz-output-")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(sourceDir, "src"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(sourceDir, "node_modules"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sourceDir, "README.md"), []byte(strings.Repeat("readme ", 100)), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sourceDir, "src", "main.go"), []byte("package main"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sourceDir, "node_modules", "dep.js"), []byte("dep"), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(sourceDir)
+		os.RemoveAll(outputDir)
+	})
+
+	Describe("CreateTarGzArchive", func() {
+		It("should archive the source directory without excluded paths", func() {
+			target := filepath.Join(outputDir, "backup.tar.gz")
+			Expect(compress.CreateTarGzArchive(sourceDir, target, []string{"node_modules"})).To(Succeed())
+
+			names, contents := readArchive(target)
+			Expect(names).To(Equal([]string{"README.md", "src", "src/main.go"}))
+			Expect(contents["src/main.go"]).To(Equal("package main"))
+		})
+
+		It("should keep paths re-included by a negation pattern", func() {
+			Expect(os.WriteFile(filepath.Join(sourceDir, "node_modules", ".keep"), []byte(""), 0644)).To(Succeed())
+
+			target := filepath.Join(outputDir, "backup.tar.gz")
+			Expect(compress.CreateTarGzArchive(sourceDir, target, []string{"node_modules/**", "!node_modules/.keep"})).To(Succeed())
+
+			names, _ := readArchive(target)
+			Expect(names).To(ContainElement("node_modules/.keep"))
+			Expect(names).NotTo(ContainElement("node_modules/dep.js"))
+		})
+
+		It("should not include the target file when it is inside the source", func() {
+			target := filepath.Join(sourceDir, "backup.tar.gz")
+			Expect(compress.CreateTarGzArchive(sourceDir, target, []string{"node_modules"})).To(Succeed())
+
+			names, _ := readArchive(target)
+			Expect(names).NotTo(ContainElement("backup.tar.gz"))
+		})
+	})
+
+	Describe("CreateTarGzArchives", func() {
+		It("should write every output with the same contents from one scan", func() {
+			fast := filepath.Join(outputDir, "fast.tar.gz")
+			small := filepath.Join(outputDir, "small.tar.gz")
+			outputs := []compress.ArchiveOutput{{Path: fast, Level: 1}, {Path: small, Level: 9}}
+			entries, err := compress.CreateTarGzArchives(sourceDir, outputs, []string{"node_modules"})
+			Expect(err).NotTo(HaveOccurred())
+
+			var entryPaths []string
+			for _, entry := range entries {
+				entryPaths = append(entryPaths, entry.Path)
+			}
+			Expect(entryPaths).To(ConsistOf("README.md", "src", "src/main.go"))
+
+			fastNames, fastContents := readArchive(fast)
+			smallNames, smallContents := readArchive(small)
+			Expect(fastNames).To(Equal(smallNames))
+			Expect(fastContents).To(Equal(smallContents))
+		})
+
+		It("should not include any of the outputs in each other", func() {
+			first := filepath.Join(sourceDir, "first.tar.gz")
+			second := filepath.Join(sourceDir, "second.tar.gz")
+			outputs := []compress.ArchiveOutput{{Path: first}, {Path: second}}
+			_, err := compress.CreateTarGzArchives(sourceDir, outputs, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			names, _ := readArchive(second)
+			Expect(names).NotTo(ContainElement("first.tar.gz"))
+			Expect(names).NotTo(ContainElement("second.tar.gz"))
+		})
+
+		It("should return an error for an invalid compression level", func() {
+			outputs := []compress.ArchiveOutput{{Path: filepath.Join(outputDir, "bad.tar.gz"), Level: 42}}
+			_, err := compress.CreateTarGzArchives(sourceDir, outputs, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid compression level"))
+		})
+
+		It("should store files matching the no-compress patterns without compression", func() {
+			// Compressible content, so a stored copy is easy to tell apart
+			photo := strings.Repeat("not really a photo ", 10000)
+			Expect(os.WriteFile(filepath.Join(sourceDir, "src", "photo.jpg"), []byte(photo), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sourceDir, "src", "tiny.jpg"), []byte("tiny"), 0644)).To(Succeed())
+
+			compressed := filepath.Join(outputDir, "compressed.tar.gz")
+			stored := filepath.Join(outputDir, "stored.tar.gz")
+			outputs := []compress.ArchiveOutput{{Path: compressed}, {Path: stored, NoCompress: []string{"*.jpg"}}}
+			_, err := compress.CreateTarGzArchives(sourceDir, outputs, []string{"node_modules"})
+			Expect(err).NotTo(HaveOccurred())
+
+			compressedNames, compressedContents := readArchive(compressed)
+			storedNames, storedContents := readArchive(stored)
+			Expect(storedNames).To(Equal(compressedNames))
+			Expect(storedContents).To(Equal(compressedContents))
+			Expect(storedContents["src/photo.jpg"]).To(Equal(photo))
+
+			compressedInfo, err := os.Stat(compressed)
+			Expect(err).NotTo(HaveOccurred())
+			storedInfo, err := os.Stat(stored)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(compressedInfo.Size()).To(BeNumerically("<", len(photo)/10))
+			Expect(storedInfo.Size()).To(BeNumerically(">", len(photo)))
+
+			// The stored file gets its own gzip member between compressed ones
+			file, err := os.Open(stored)
+			Expect(err).NotTo(HaveOccurred())
+			defer file.Close()
+			buffered := bufio.NewReader(file)
+			gzReader, err := gzip.NewReader(buffered)
+			Expect(err).NotTo(HaveOccurred())
+			members := 0
+			for {
+				gzReader.Multistream(false)
+				_, err := io.Copy(io.Discard, gzReader)
+				Expect(err).NotTo(HaveOccurred())
+				members++
+				if err := gzReader.Reset(buffered); err == io.EOF {
+					break
+				}
+			}
+			Expect(members).To(BeNumerically(">=", 2))
+		})
+
+		It("should collect per-extension stats and report incompressible types", func() {
+			random := make([]byte, 12*compress.MB)
+			_, err := rand.Read(random)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(sourceDir, "src", "video.MP4"), random, 0644)).To(Succeed())
+			text := strings.Repeat("compressible log line\n", 600000)
+			Expect(os.WriteFile(filepath.Join(sourceDir, "src", "app.log"), []byte(text), 0644)).To(Succeed())
+
+			stats := &compress.ArchiveStats{}
+			outputs := []compress.ArchiveOutput{{Path: filepath.Join(outputDir, "stats.tar.gz"), Stats: stats}}
+			_, err = compress.CreateTarGzArchives(sourceDir, outputs, []string{"node_modules"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stats.Extensions).To(HaveKey(".mp4"))
+			video := stats.Extensions[".mp4"]
+			Expect(video.Files).To(Equal(1))
+			Expect(video.Size).To(Equal(12 * compress.MB))
+			Expect(video.Ratio()).To(BeNumerically(">", 0.97))
+			Expect(stats.Extensions[".log"].Ratio()).To(BeNumerically("<", 0.1))
+
+			incompressible := stats.Incompressible()
+			Expect(incompressible).To(HaveLen(1))
+			Expect(incompressible[0].Extension).To(Equal(".mp4"))
+		})
+
+		It("should not report files that are already stored as incompressible", func() {
+			random := make([]byte, 12*compress.MB)
+			_, err := rand.Read(random)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(sourceDir, "src", "video.mp4"), random, 0644)).To(Succeed())
+
+			stats := &compress.ArchiveStats{}
+			outputs := []compress.ArchiveOutput{{Path: filepath.Join(outputDir, "stats.tar.gz"), NoCompress: []string{"*.mp4"}, Stats: stats}}
+			_, err = compress.CreateTarGzArchives(sourceDir, outputs, []string{"node_modules"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stats.Extensions[".mp4"].StoredSize).To(Equal(12 * compress.MB))
+			Expect(stats.Incompressible()).To(BeEmpty())
+		})
+
+		It("should record the SHA-256 of each regular file", func() {
+			entries, err := compress.CreateTarGzArchives(sourceDir, []compress.ArchiveOutput{{Path: filepath.Join(outputDir, "hashed.tar.gz")}}, []string{"node_modules"})
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, entry := range entries {
+				if entry.IsDir {
+					Expect(entry.SHA256).To(BeEmpty())
+					continue
+				}
+				data, err := os.ReadFile(filepath.Join(sourceDir, filepath.FromSlash(entry.Path)))
+				Expect(err).NotTo(HaveOccurred())
+				sum := sha256.Sum256(data)
+				Expect(entry.SHA256).To(Equal(hex.EncodeToString(sum[:])), entry.Path)
+			}
+		})
+
+		It("should stop when the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err := compress.CreateTarGzArchivesContext(ctx, sourceDir, []compress.ArchiveOutput{{Path: filepath.Join(outputDir, "stopped.tar.gz")}}, compress.ArchiveOptions{})
+			Expect(err).To(MatchError(context.Canceled))
+		})
+
+		Context("with unreadable files", func() {
+			BeforeEach(func() {
+				if os.Geteuid() == 0 {
+					Skip("root can read files without permissions")
+				}
+				Expect(os.WriteFile(filepath.Join(sourceDir, "secret.txt"), []byte("secret"), 0000)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(sourceDir, "locked", "inner"), 0755)).To(Succeed())
+				Expect(os.Chmod(filepath.Join(sourceDir, "locked"), 0000)).To(Succeed())
+				DeferCleanup(os.Chmod, filepath.Join(sourceDir, "locked"), os.FileMode(0755))
+			})
+
+			It("should skip them and report why", func() {
+				target := filepath.Join(outputDir, "skipped.tar.gz")
+				result, err := compress.CreateTarGzArchivesContext(context.Background(), sourceDir, []compress.ArchiveOutput{{Path: target}},
+					compress.ArchiveOptions{Excludes: []string{"node_modules"}, SkipUnreadable: true})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Warnings).To(ConsistOf(
+					compress.Warning{Kind: compress.WarningUnreadable, Path: "locked", Message: "permission denied"},
+					compress.Warning{Kind: compress.WarningUnreadable, Path: "secret.txt", Message: "permission denied"},
+				))
+
+				listed, err := compress.ListTarGzArchive(target)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(listed).To(HaveLen(len(result.Entries)))
+				for _, entry := range listed {
+					Expect(entry.Path).NotTo(Equal("secret.txt"))
+				}
+			})
+
+			It("should fail without SkipUnreadable", func() {
+				_, err := compress.CreateTarGzArchivesContext(context.Background(), sourceDir, []compress.ArchiveOutput{{Path: filepath.Join(outputDir, "failed.tar.gz")}},
+					compress.ArchiveOptions{Excludes: []string{"node_modules"}})
+				Expect(err).To(MatchError(os.ErrPermission))
+			})
+		})
+
+		It("should store symlinks as links and warn about loops", func() {
+			Expect(os.Symlink("file1.txt", filepath.Join(sourceDir, "link.txt"))).To(Succeed())
+			Expect(os.Symlink("loop-b", filepath.Join(sourceDir, "loop-a"))).To(Succeed())
+			Expect(os.Symlink("loop-a", filepath.Join(sourceDir, "loop-b"))).To(Succeed())
+
+			target := filepath.Join(outputDir, "links.tar.gz")
+			result, err := compress.CreateTarGzArchivesContext(context.Background(), sourceDir, []compress.ArchiveOutput{{Path: target}},
+				compress.ArchiveOptions{Excludes: []string{"node_modules"}, SkipUnreadable: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Warnings).To(ConsistOf(
+				HaveField("Path", "loop-a"),
+				HaveField("Path", "loop-b"),
+			))
+			Expect(result.Warnings[0].Kind).To(Equal(compress.WarningSymlinkLoop))
+
+			listed, err := compress.ListTarGzArchive(target)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(listed).To(ContainElement(SatisfyAll(HaveField("Path", "link.txt"), HaveField("Link", "file1.txt"), HaveField("Size", int64(0)))))
+		})
+
+		It("should leave out special files with a warning", func() {
+			fifo := filepath.Join(sourceDir, "pipe")
+			if err := exec.Command("mkfifo", fifo).Run(); err != nil {
+				Skip("cannot create a named pipe: " + err.Error())
+			}
+
+			result, err := compress.CreateTarGzArchivesContext(context.Background(), sourceDir, []compress.ArchiveOutput{{Path: filepath.Join(outputDir, "special.tar.gz")}},
+				compress.ArchiveOptions{Excludes: []string{"node_modules"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Warnings).To(ConsistOf(HaveField("Kind", compress.WarningSpecialFile)))
+			Expect(result.Entries).NotTo(ContainElement(HaveField("Path", "pipe")))
+		})
+
+		Context("with a file list", func() {
+			It("should archive only the listed paths, in list order", func() {
+				target := filepath.Join(outputDir, "listed.tar.gz")
+				result, err := compress.CreateTarGzArchivesContext(context.Background(), sourceDir, []compress.ArchiveOutput{{Path: target}},
+					compress.ArchiveOptions{Files: []string{"src/main.go", "README.md"}})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Entries).To(HaveLen(2))
+				Expect(result.Entries[0].Path).To(Equal("src/main.go"))
+				Expect(result.Entries[1].Path).To(Equal("README.md"))
+				_, contents := readArchive(target)
+				Expect(contents["src/main.go"]).To(Equal("package main"))
+			})
+
+			It("should archive a listed directory without its contents", func() {
+				target := filepath.Join(outputDir, "dir.tar.gz")
+				_, err := compress.CreateTarGzArchivesContext(context.Background(), sourceDir, []compress.ArchiveOutput{{Path: target}},
+					compress.ArchiveOptions{Files: []string{"src"}})
+				Expect(err).NotTo(HaveOccurred())
+
+				names, _ := readArchive(target)
+				Expect(names).To(Equal([]string{"src"}))
+			})
+
+			It("should leave out listed paths that are excluded", func() {
+				target := filepath.Join(outputDir, "excluded.tar.gz")
+				_, err := compress.CreateTarGzArchivesContext(context.Background(), sourceDir, []compress.ArchiveOutput{{Path: target}},
+					compress.ArchiveOptions{Excludes: []string{"node_modules"}, Files: []string{"README.md", "node_modules/dep.js"}})
+				Expect(err).NotTo(HaveOccurred())
+
+				names, _ := readArchive(target)
+				Expect(names).To(Equal([]string{"README.md"}))
+			})
+
+			It("should report a missing listed path as a warning with SkipUnreadable", func() {
+				target := filepath.Join(outputDir, "missing.tar.gz")
+				result, err := compress.CreateTarGzArchivesContext(context.Background(), sourceDir, []compress.ArchiveOutput{{Path: target}},
+					compress.ArchiveOptions{SkipUnreadable: true, Files: []string{"gone.txt", "README.md"}})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Entries).To(HaveLen(1))
+				Expect(result.Warnings).To(HaveLen(1))
+				Expect(result.Warnings[0].Path).To(Equal("gone.txt"))
+			})
+
+			It("should fail for a missing listed path without SkipUnreadable", func() {
+				_, err := compress.CreateTarGzArchivesContext(context.Background(), sourceDir, []compress.ArchiveOutput{{Path: filepath.Join(outputDir, "failed.tar.gz")}},
+					compress.ArchiveOptions{Files: []string{"gone.txt"}})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		It("should return an error when no outputs are given", func() {
+			_, err := compress.CreateTarGzArchives(sourceDir, nil, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ListTarGzArchive", func() {
+		It("should return the entries written by CreateTarGzArchives", func() {
+			target := filepath.Join(outputDir, "backup.tar.gz")
+			written, err := compress.CreateTarGzArchives(sourceDir, []compress.ArchiveOutput{{Path: target}}, []string{"node_modules"})
+			Expect(err).NotTo(HaveOccurred())
+
+			listed, err := compress.ListTarGzArchive(target)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(listed).To(HaveLen(len(written)))
+			for i := range written {
+				Expect(listed[i].Path).To(Equal(written[i].Path))
+				Expect(listed[i].Size).To(Equal(written[i].Size))
+				Expect(listed[i].IsDir).To(Equal(written[i].IsDir))
+			}
+		})
+
+		It("should return an error for a file that is not an archive", func() {
+			path := filepath.Join(outputDir, "not-an-archive.tar.gz")
+			Expect(os.WriteFile(path, []byte("plain text"), 0644)).To(Succeed())
+
+			_, err := compress.ListTarGzArchive(path)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Chunks", func() {
+		var target string
+		var chunks []compress.ArchiveChunk
+		var entries []compress.ArchiveEntry
+
+		BeforeEach(func() {
+			// Random contents do not compress, so each file fills a chunk of its own
+			for _, name := range []string{"a.bin", "b.bin", "c.bin"} {
+				data := make([]byte, 256<<10)
+				_, err := rand.Read(data)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(os.WriteFile(filepath.Join(sourceDir, "src", name), data, 0644)).To(Succeed())
+			}
+
+			target = filepath.Join(outputDir, "chunked.tar.gz")
+			var err error
+			outputs := []compress.ArchiveOutput{{Path: target, ChunkSize: 64 << 10, Chunks: &chunks}}
+			entries, err = compress.CreateTarGzArchives(sourceDir, outputs, []string{"node_modules"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		// chunkFile writes the chunk of the archive to a file of its own
+		chunkFile := func(chunk compress.ArchiveChunk) string {
+			data, err := os.ReadFile(target)
+			Expect(err).NotTo(HaveOccurred())
+			path := filepath.Join(outputDir, "chunk.tar.gz")
+			Expect(os.WriteFile(path, data[chunk.Offset:chunk.Offset+chunk.Size], 0644)).To(Succeed())
+			return path
+		}
+
+		It("should split the archive into chunks covering every byte and entry", func() {
+			Expect(len(chunks)).To(BeNumerically(">=", 3))
+			info, err := os.Stat(target)
+			Expect(err).NotTo(HaveOccurred())
+
+			var offset int64
+			first := 0
+			for _, chunk := range chunks {
+				Expect(chunk.Offset).To(Equal(offset))
+				Expect(chunk.First).To(Equal(first))
+				offset += chunk.Size
+				first += chunk.Entries
+			}
+			Expect(offset).To(Equal(info.Size()))
+			Expect(first).To(Equal(len(entries)))
+		})
+
+		It("should make each chunk readable on its own", func() {
+			for _, chunk := range chunks {
+				listed, err := compress.ListTarGzArchive(chunkFile(chunk))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(listed).To(HaveLen(chunk.Entries))
+				for i, entry := range listed {
+					Expect(entry.Path).To(Equal(entries[chunk.First+i].Path))
+				}
+			}
+		})
+
+		It("should select the chunks holding the listed paths", func() {
+			selected, err := compress.SelectChunks(chunks, entries, []string{"./src/b.bin"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(selected).To(HaveLen(1))
+
+			var out strings.Builder
+			Expect(compress.CatTarGzFile(chunkFile(selected[0]), "src/b.bin", &out)).To(Succeed())
+			data, err := os.ReadFile(filepath.Join(sourceDir, "src", "b.bin"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out.String()).To(Equal(string(data)))
+
+			selected, err = compress.SelectChunks(chunks, entries, []string{"src"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(len(selected)).To(BeNumerically(">=", 3))
+
+			_, err = compress.SelectChunks(chunks, entries, []string{"../etc"}, nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should select the chunks holding entries matching the patterns", func() {
+			selected, err := compress.SelectChunks(chunks, entries, nil, []string{"src/b.*"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(selected).To(HaveLen(1))
+
+			_, err = compress.SelectChunks(chunks, entries, nil, []string{"src/[b"})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should not record chunks without a chunk size", func() {
+			var unchunked []compress.ArchiveChunk
+			outputs := []compress.ArchiveOutput{{Path: filepath.Join(outputDir, "plain.tar.gz"), Chunks: &unchunked}}
+			_, err := compress.CreateTarGzArchives(sourceDir, outputs, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(unchunked).To(BeEmpty())
+		})
+	})
+
+	Describe("CatTarGzFile", func() {
+		var target string
+
+		BeforeEach(func() {
+			target = filepath.Join(outputDir, "backup.tar.gz")
+			_, err := compress.CreateTarGzArchives(sourceDir, []compress.ArchiveOutput{{Path: target}}, nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should write the contents of a file", func() {
+			var out strings.Builder
+			Expect(compress.CatTarGzFile(target, "src/main.go", &out)).To(Succeed())
+			Expect(out.String()).To(Equal("package main"))
+		})
+
+		It("should fail for a missing path or a directory", func() {
+			Expect(compress.CatTarGzFile(target, "src/missing.go", io.Discard)).To(MatchError(ContainSubstring("not in the backup")))
+			Expect(compress.CatTarGzFile(target, "src", io.Discard)).To(MatchError(ContainSubstring("not a file")))
+		})
+	})
+
+	Describe("PAX format", func() {
+		// firstHeader returns the first tar header of a tar.gz archive without reading its contents
+		firstHeader := func(path string) *tar.Header {
+			file, err := os.Open(path)
+			Expect(err).NotTo(HaveOccurred())
+			defer file.Close()
+
+			gzReader, err := gzip.NewReader(file)
+			Expect(err).NotTo(HaveOccurred())
+			header, err := tar.NewReader(gzReader).Next()
+			Expect(err).NotTo(HaveOccurred())
+			return header
+		}
+
+		BeforeEach(func() {
+			// Start from an empty source for each format case
+			os.RemoveAll(sourceDir)
+			Expect(os.MkdirAll(sourceDir, 0755)).To(Succeed())
+		})
+
+		It("should store paths longer than 100 characters without truncating them", func() {
+			longDir := filepath.Join(strings.Repeat("nested-directory-", 4), strings.Repeat("deeper-directory-", 4))
+			longName := strings.Repeat("long-file-name-", 10) + ".txt"
+			Expect(os.MkdirAll(filepath.Join(sourceDir, longDir), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sourceDir, longDir, longName), []byte("long"), 0644)).To(Succeed())
+
+			target := filepath.Join(outputDir, "long.tar.gz")
+			Expect(compress.CreateTarGzArchive(sourceDir, target, nil)).To(Succeed())
+
+			fullName := filepath.ToSlash(filepath.Join(longDir, longName))
+			Expect(len(fullName)).To(BeNumerically(">", 100))
+			_, contents := readArchive(target)
+			Expect(contents).To(HaveKeyWithValue(fullName, "long"))
+		})
+
+		It("should store unicode file names exactly", func() {
+			name := "résumé-日本語-🚀.txt"
+			Expect(os.WriteFile(filepath.Join(sourceDir, name), []byte("unicode"), 0644)).To(Succeed())
+
+			target := filepath.Join(outputDir, "unicode.tar.gz")
+			Expect(compress.CreateTarGzArchive(sourceDir, target, nil)).To(Succeed())
+
+			header := firstHeader(target)
+			Expect(header.Name).To(Equal(name))
+			Expect(header.Format & tar.FormatPAX).NotTo(BeZero())
+		})
+
+		It("should archive files larger than 8GB", func() {
+			if testing.Short() {
+				Skip("skipping >8GB sparse file test in short mode")
+			}
+
+			// A sparse file takes no disk space but is read back as zeros
+			hugePath := filepath.Join(sourceDir, "huge.bin")
+			hugeSize := compress.StandardTarSizeLimit + 10*compress.MB
+			file, err := os.Create(hugePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(file.Truncate(hugeSize)).To(Succeed())
+			Expect(file.Close()).To(Succeed())
+
+			target := filepath.Join(outputDir, "huge.tar.gz")
+			outputs := []compress.ArchiveOutput{{Path: target, Level: gzip.BestSpeed}}
+			_, err = compress.CreateTarGzArchives(sourceDir, outputs, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			header := firstHeader(target)
+			Expect(header.Name).To(Equal("huge.bin"))
+			Expect(header.Size).To(Equal(hugeSize))
+			Expect(header.Format & tar.FormatPAX).NotTo(BeZero())
+		})
+	})
+})
@@ -0,0 +1,62 @@
+package compress
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// ArchiveCheck describes an archive VerifyArchive read to its end
+type ArchiveCheck struct {
+	Entries int   // Entries in the archive
+	Size    int64 // Total size of the file contents
+}
+
+// VerifyArchive reads an archive to its end without extracting it. The contents of every entry
+// are read, so a truncated or corrupted archive fails, as does a gzip or zip checksum that does
+// not match. Like ListTarGzArchive, it reads archives of every format go-backup knows.
+func VerifyArchive(archiveFile string) (*ArchiveCheck, error) {
+	file, err := os.Open(archiveFile)
+	if err != nil {
+		return nil, fmt.Errorf("error opening archive: %w", err)
+	}
+	defer file.Close()
+
+	// Tar formats wrap their one stream when the archive is opened, zip each entry as it is read
+	var stream io.Reader
+	reader, err := openArchive(file, func(r io.Reader) io.Reader {
+		if stream == nil {
+			stream = r
+		}
+		return r
+	})
+	if err != nil {
+		return nil, err
+	}
+	container := stream
+
+	check := &ArchiveCheck{}
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("damaged after %d entries: %w", check.Entries, err)
+		}
+		n, err := io.Copy(io.Discard, reader)
+		if err != nil {
+			return nil, fmt.Errorf("damaged at %s: %w", QuotePath(header.Name), err)
+		}
+		check.Entries++
+		check.Size += n
+	}
+
+	// The gzip checksum is only checked once the stream is read past the end of the tar archive
+	if container != nil {
+		if _, err := io.Copy(io.Discard, container); err != nil {
+			return nil, fmt.Errorf("damaged after the last entry: %w", err)
+		}
+	}
+	return check, nil
+}
@@ -0,0 +1,77 @@
+package compress_test
+
+import (
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+
+	"github.com/kennycyb/go-backup/internal/service/compress"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("VerifyArchive", func() {
+	var sourceDir, outputDir string
+
+	BeforeEach(func() {
+		sourceDir = GinkgoT().TempDir()
+		outputDir = GinkgoT().TempDir()
+
+		data := make([]byte, 256<<10)
+		random := rand.New(rand.NewPCG(1, 2))
+		for i := range data {
+			data[i] = byte(random.IntN(16)) // Compressible, but not to nothing
+		}
+		Expect(os.WriteFile(filepath.Join(sourceDir, "data.bin"), data, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sourceDir, "notes.txt"), []byte("notes"), 0644)).To(Succeed())
+	})
+
+	// archive writes the source in the format and returns the archive's path
+	archive := func(format string) string {
+		path := filepath.Join(outputDir, "backup"+compress.FormatExtension(format))
+		_, err := compress.CreateTarGzArchives(sourceDir, []compress.ArchiveOutput{{Path: path, Format: format}}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		return path
+	}
+
+	// damage flips a byte in the middle of a file
+	damage := func(path string) {
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		data[len(data)/2] ^= 0xff
+		Expect(os.WriteFile(path, data, 0644)).To(Succeed())
+	}
+
+	DescribeTable("should read an intact archive to its end",
+		func(format string) {
+			check, err := compress.VerifyArchive(archive(format))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(check.Entries).To(Equal(2))
+			Expect(check.Size).To(Equal(int64(256<<10 + 5)))
+		},
+		Entry("tar.gz", compress.FormatTarGz),
+		Entry("tar", compress.FormatTar),
+		Entry("zip", compress.FormatZip),
+	)
+
+	DescribeTable("should fail on a damaged archive",
+		func(format string) {
+			path := archive(format)
+			damage(path)
+			_, err := compress.VerifyArchive(path)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("tar.gz", compress.FormatTarGz),
+		Entry("zip", compress.FormatZip),
+	)
+
+	It("should fail on a truncated archive", func() {
+		path := archive(compress.FormatTarGz)
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Truncate(path, info.Size()-100)).To(Succeed())
+
+		_, err = compress.VerifyArchive(path)
+		Expect(err).To(HaveOccurred())
+	})
+})
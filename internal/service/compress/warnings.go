@@ -0,0 +1,55 @@
+package compress
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"syscall"
+)
+
+// Kinds of non-fatal issues found while walking the source directory
+const (
+	WarningUnreadable  = "unreadable"   // The path could not be read and was skipped
+	WarningVanished    = "vanished"     // The path was deleted while the backup ran and was skipped
+	WarningSymlinkLoop = "symlink-loop" // The symlink was stored, but resolving it loops
+	WarningSpecialFile = "special-file" // Sockets, pipes and devices are not backed up
+	WarningOversize    = "oversize"     // The file is larger than the recommended tar file size
+	WarningSecret      = "secret"       // The file looks like it holds a secret and goes unencrypted to a remote target
+	WarningAttributes  = "attributes"   // The file was restored without its capabilities or chattr flags
+)
+
+// Warning is a non-fatal issue with one path of the source directory
+type Warning struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path"` // Path relative to the source directory, with forward slashes
+	Message string `json:"message"`
+}
+
+// newPathWarning builds the warning for a path that could not be read, telling vanished paths
+// apart from unreadable ones. The message is the underlying error without the path.
+func newPathWarning(relPath string, err error) Warning {
+	kind := WarningUnreadable
+	if errors.Is(err, fs.ErrNotExist) {
+		kind = WarningVanished
+	}
+	message := err.Error()
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		message = pathErr.Err.Error()
+	}
+	return Warning{Kind: kind, Path: filepath.ToSlash(relPath), Message: message}
+}
+
+// isSymlinkLoop reports whether resolving a symlink failed because the links form a loop
+func isSymlinkLoop(err error) bool {
+	return errors.Is(err, syscall.ELOOP)
+}
+
+// CountWarnings returns the number of warnings of each kind
+func CountWarnings(warnings []Warning) map[string]int {
+	counts := make(map[string]int)
+	for _, warning := range warnings {
+		counts[warning.Kind]++
+	}
+	return counts
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Alias returns the arguments an alias of the registry stands for, e.g. ["run", "--tag", "nightly"]
+// for "nightly: run --tag nightly". Reports false if there is no such alias. Returns an error if
+// the alias is empty or its quotes are not closed.
+func (r *GlobalBackupRegistry) Alias(name string) ([]string, bool, error) {
+	if r == nil {
+		return nil, false, nil
+	}
+	command, ok := r.Aliases[name]
+	if !ok {
+		return nil, false, nil
+	}
+	args, err := SplitArgs(command)
+	if err == nil && len(args) == 0 {
+		err = fmt.Errorf("is empty")
+	}
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid alias %s: %w", name, err)
+	}
+	return args, true, nil
+}
+
+// DefaultArgs returns the arguments of the registry's default command, or nil if none is set.
+// Returns an error if the command's quotes are not closed.
+func (r *GlobalBackupRegistry) DefaultArgs() ([]string, error) {
+	if r == nil || strings.TrimSpace(r.DefaultCommand) == "" {
+		return nil, nil
+	}
+	args, err := SplitArgs(r.DefaultCommand)
+	if err != nil {
+		return nil, fmt.Errorf("invalid defaultCommand: %w", err)
+	}
+	return args, nil
+}
+
+// SplitArgs splits a command line into arguments like a POSIX shell does for simple words:
+// at unquoted white space, with single quotes taken literally and, inside double quotes or
+// outside quotes, a backslash escaping the next character. Nothing is expanded.
+func SplitArgs(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,75 @@
+package config_test
+
+import (
+	. "github.com/kennycyb/go-backup/internal/service/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Aliases", func() {
+	Describe("SplitArgs", func() {
+		It("should split at white space", func() {
+			Expect(SplitArgs("  run --tag\tnightly \n")).To(Equal([]string{"run", "--tag", "nightly"}))
+		})
+
+		It("should keep quoted and escaped white space", func() {
+			Expect(SplitArgs(`run --message "nightly run" -d '/mnt/my backups' a\ b`)).To(Equal(
+				[]string{"run", "--message", "nightly run", "-d", "/mnt/my backups", "a b"}))
+		})
+
+		It("should keep empty quoted arguments", func() {
+			Expect(SplitArgs(`run --message ""`)).To(Equal([]string{"run", "--message", ""}))
+		})
+
+		It("should take single quotes literally", func() {
+			Expect(SplitArgs(`'a\b' "it's"`)).To(Equal([]string{`a\b`, "it's"}))
+		})
+
+		It("should return an error for unterminated quotes", func() {
+			_, err := SplitArgs(`run --message "nightly`)
+			Expect(err).To(MatchError(ContainSubstring("unterminated")))
+		})
+	})
+
+	Describe("Alias", func() {
+		registry := &GlobalBackupRegistry{Aliases: map[string]string{
+			"nightly": "run --tag nightly",
+			"empty":   " ",
+			"broken":  "run '",
+		}}
+
+		It("should return the arguments of an alias", func() {
+			args, ok, err := registry.Alias("nightly")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(args).To(Equal([]string{"run", "--tag", "nightly"}))
+		})
+
+		It("should report unknown aliases", func() {
+			_, ok, err := registry.Alias("weekly")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+
+			_, ok, _ = (*GlobalBackupRegistry)(nil).Alias("nightly")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should return an error for empty and malformed aliases", func() {
+			_, _, err := registry.Alias("empty")
+			Expect(err).To(MatchError(ContainSubstring("invalid alias empty")))
+			_, _, err = registry.Alias("broken")
+			Expect(err).To(MatchError(ContainSubstring("invalid alias broken")))
+		})
+	})
+
+	Describe("DefaultArgs", func() {
+		It("should split the default command", func() {
+			Expect((&GlobalBackupRegistry{DefaultCommand: "run --force"}).DefaultArgs()).To(Equal([]string{"run", "--force"}))
+		})
+
+		It("should return nil without a default command", func() {
+			Expect((&GlobalBackupRegistry{}).DefaultArgs()).To(BeNil())
+			Expect((*GlobalBackupRegistry)(nil).DefaultArgs()).To(BeNil())
+		})
+	})
+})
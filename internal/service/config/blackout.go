@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// weekdayNames are the day names accepted in blackout windows
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// BlackoutWindow is a time of day in which run-all starts no backups, e.g. during working hours.
+// A window whose end is before its start runs past midnight into the next day.
+type BlackoutWindow struct {
+	Days  [7]bool       // Weekdays the window starts on, indexed by time.Weekday
+	Start time.Duration // Time of day the window starts
+	End   time.Duration // Time of day the window ends
+}
+
+// ParseBlackoutWindow parses a window like "09:00-18:00", optionally preceded by the days it
+// applies to: "Mon-Fri 09:00-18:00", "Sat,Sun 10:00-12:00" or "Sun 22:00-06:00".
+func ParseBlackoutWindow(value string) (BlackoutWindow, error) {
+	var window BlackoutWindow
+	invalid := func(reason string) (BlackoutWindow, error) {
+		return BlackoutWindow{}, fmt.Errorf("invalid blackout window %q: %s", value, reason)
+	}
+
+	fields := strings.Fields(value)
+	switch len(fields) {
+	case 1:
+		for day := range window.Days {
+			window.Days[day] = true
+		}
+	case 2:
+		for _, part := range strings.Split(fields[0], ",") {
+			first, last, isRange := strings.Cut(part, "-")
+			from, ok := weekdayNames[strings.ToLower(first)]
+			if !ok {
+				return invalid(fmt.Sprintf("unknown day %q, use Mon, Tue, Wed, Thu, Fri, Sat or Sun", first))
+			}
+			to := from
+			if isRange {
+				if to, ok = weekdayNames[strings.ToLower(last)]; !ok {
+					return invalid(fmt.Sprintf("unknown day %q, use Mon, Tue, Wed, Thu, Fri, Sat or Sun", last))
+				}
+			}
+			for day := from; ; day = (day + 1) % 7 {
+				window.Days[day] = true
+				if day == to {
+					break
+				}
+			}
+		}
+	default:
+		return invalid("use [days] HH:MM-HH:MM, e.g. Mon-Fri 09:00-18:00")
+	}
+
+	start, end, ok := strings.Cut(fields[len(fields)-1], "-")
+	if !ok {
+		return invalid("use [days] HH:MM-HH:MM, e.g. Mon-Fri 09:00-18:00")
+	}
+	var err error
+	if window.Start, err = parseTimeOfDay(start); err != nil {
+		return invalid(err.Error())
+	}
+	if window.End, err = parseTimeOfDay(end); err != nil {
+		return invalid(err.Error())
+	}
+	if window.Start == window.End {
+		return invalid("start and end are the same")
+	}
+	return window, nil
+}
+
+// parseTimeOfDay parses HH:MM into the time since midnight; 24:00 is the end of the day
+func parseTimeOfDay(value string) (time.Duration, error) {
+	if value == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether the window covers the time, in the time's location
+func (w BlackoutWindow) Contains(t time.Time) bool {
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	day := t.Weekday()
+	if w.Start < w.End {
+		return w.Days[day] && sinceMidnight >= w.Start && sinceMidnight < w.End
+	}
+	// The window runs past midnight: its start is today, or it started yesterday
+	yesterday := (day + 6) % 7
+	return (w.Days[day] && sinceMidnight >= w.Start) || (w.Days[yesterday] && sinceMidnight < w.End)
+}
+
+// InBlackout returns the first of the windows that covers the time.
+// Returns an error for a window that cannot be parsed.
+func InBlackout(windows []string, t time.Time) (string, bool, error) {
+	for _, value := range windows {
+		window, err := ParseBlackoutWindow(value)
+		if err != nil {
+			return "", false, err
+		}
+		if window.Contains(t) {
+			return value, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// BlackoutWindows returns the windows in which run-all starts no backup of the entry: those of
+// the registry and the entry's own
+func (r *GlobalBackupRegistry) BlackoutWindows(entry GlobalBackupEntry) []string {
+	return append(append([]string(nil), r.Blackout...), entry.Blackout...)
+}
+
+// JitterDuration returns the longest random delay before run-all starts, or 0 without jitter
+func (r *GlobalBackupRegistry) JitterDuration() (time.Duration, error) {
+	if r.Jitter == "" {
+		return 0, nil
+	}
+	jitter, err := time.ParseDuration(r.Jitter)
+	if err != nil || jitter < 0 {
+		return 0, fmt.Errorf("invalid jitter %q: use a duration like 30m", r.Jitter)
+	}
+	return jitter, nil
+}
@@ -0,0 +1,83 @@
+package config_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/kennycyb/go-backup/internal/service/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Blackout", func() {
+	// 2025-05-19 is a Monday
+	at := func(day int, clock string) time.Time {
+		t, err := time.ParseInLocation("2006-01-02 15:04", fmt.Sprintf("2025-05-%02d %s", day, clock), time.Local)
+		Expect(err).NotTo(HaveOccurred())
+		return t
+	}
+
+	DescribeTable("Contains",
+		func(value string, day int, clock string, expected bool) {
+			window, err := ParseBlackoutWindow(value)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(window.Contains(at(day, clock))).To(Equal(expected), "%s at %d %s", value, day, clock)
+		},
+		Entry("Inside a daily window", "09:00-18:00", 24, "12:30", true),
+		Entry("At the start", "09:00-18:00", 19, "09:00", true),
+		Entry("At the end", "09:00-18:00", 19, "18:00", false),
+		Entry("Before a daily window", "09:00-18:00", 19, "02:00", false),
+		Entry("Weekday inside a weekday range", "Mon-Fri 09:00-18:00", 23, "10:00", true),
+		Entry("Weekend outside a weekday range", "Mon-Fri 09:00-18:00", 24, "10:00", false),
+		Entry("Listed day", "Sat,Sun 10:00-12:00", 25, "11:00", true),
+		Entry("Day range past the week's end", "Fri-Mon 10:00-12:00", 25, "11:00", true),
+		Entry("Before midnight of an overnight window", "22:00-06:00", 19, "23:00", true),
+		Entry("After midnight of an overnight window", "22:00-06:00", 20, "05:59", true),
+		Entry("Outside an overnight window", "22:00-06:00", 20, "12:00", false),
+		Entry("After midnight of an overnight window that started on a listed day", "Sun 22:00-06:00", 19, "03:00", true),
+		Entry("Overnight window that started on another day", "Sun 22:00-06:00", 20, "03:00", false),
+		Entry("Until the end of the day", "20:00-24:00", 19, "23:59", true),
+	)
+
+	DescribeTable("rejecting invalid windows",
+		func(value string) {
+			_, err := ParseBlackoutWindow(value)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("Missing end", "09:00"),
+		Entry("Bad time", "9am-5pm"),
+		Entry("Unknown day", "Weekdays 09:00-18:00"),
+		Entry("Empty window", "09:00-09:00"),
+		Entry("Extra fields", "Mon 09:00 - 18:00"),
+	)
+
+	Describe("InBlackout", func() {
+		It("should return the window that covers the time", func() {
+			window, ok, err := InBlackout([]string{"Sat 00:00-24:00", "09:00-18:00"}, at(19, "10:00"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(window).To(Equal("09:00-18:00"))
+		})
+
+		It("should fail for an invalid window", func() {
+			_, _, err := InBlackout([]string{"soon"}, at(19, "10:00"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should add a location's windows to the registry's", func() {
+			registry := &GlobalBackupRegistry{Blackout: []string{"09:00-18:00"}}
+			entry := GlobalBackupEntry{Location: "/media", Blackout: []string{"Sun 20:00-22:00"}}
+			Expect(registry.BlackoutWindows(entry)).To(Equal([]string{"09:00-18:00", "Sun 20:00-22:00"}))
+			Expect(registry.Blackout).To(HaveLen(1))
+		})
+	})
+
+	Describe("JitterDuration", func() {
+		It("should parse the jitter as a duration", func() {
+			Expect((&GlobalBackupRegistry{Jitter: "30m"}).JitterDuration()).To(Equal(30 * time.Minute))
+			Expect((&GlobalBackupRegistry{}).JitterDuration()).To(BeZero())
+			_, err := (&GlobalBackupRegistry{Jitter: "a while"}).JitterDuration()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
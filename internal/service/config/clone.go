@@ -0,0 +1,114 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// CloneConfig returns a copy of the config suitable for another project directory.
+// All settings are kept, including per-target overrides. Backup history and last run status
+// are dropped, and paths referring to the source project are rewritten for the new one:
+//   - absolute paths inside fromDir are moved under toDir
+//   - path components equal to the project name are replaced with the new project name
+//     (e.g. /mnt/nas/foo -> /mnt/nas/other, /backups/foo.tar.gz -> /backups/other.tar.gz)
+func CloneConfig(config *BackupConfig, fromDir, toDir string) *BackupConfig {
+	clone := &BackupConfig{}
+
+	for _, exclude := range config.Excludes {
+		clone.Excludes = append(clone.Excludes, rewriteProjectPath(exclude, fromDir, toDir))
+	}
+
+	clone.Includes = append(clone.Includes, config.Includes...)
+	clone.NoCompress = append(clone.NoCompress, config.NoCompress...)
+
+	for _, target := range config.Targets {
+		// Keep every per-target setting, so e.g. an encryption override is not lost
+		t := target
+		t.Path = rewriteProjectPath(target.Path, fromDir, toDir)
+		t.File = rewriteProjectPath(target.File, fromDir, toDir)
+		t.S3 = cloneS3Target(target.S3, fromDir, toDir)
+		t.SFTP = cloneSFTPTarget(target.SFTP, fromDir, toDir)
+		t.Compression = clonePointer(target.Compression)
+		t.Encryption = clonePointer(target.Encryption)
+		t.Upload = clonePointer(target.Upload)
+		if t.Upload != nil {
+			t.Upload.ObjectLock = clonePointer(target.Upload.ObjectLock)
+		}
+		t.Backups = nil
+		t.LastRun = nil
+		clone.Targets = append(clone.Targets, t)
+	}
+
+	clone.Encryption = clonePointer(config.Encryption)
+	clone.Options = clonePointer(config.Options)
+	clone.Rotation = clonePointer(config.Rotation)
+	clone.Rehearsal = clonePointer(config.Rehearsal)
+
+	return clone
+}
+
+// clonePointer returns a copy of the value p points to, so the clone does not share it; nil stays nil
+func clonePointer[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	clone := *p
+	return &clone
+}
+
+// cloneS3Target copies an S3 target, rewriting its prefix like a path
+func cloneS3Target(s3 *S3TargetConfig, fromDir, toDir string) *S3TargetConfig {
+	if s3 == nil {
+		return nil
+	}
+	clone := *s3
+	clone.Prefix = rewriteProjectPath(s3.Prefix, fromDir, toDir)
+	return &clone
+}
+
+// cloneSFTPTarget copies an SFTP target, rewriting its directory like a path
+func cloneSFTPTarget(sftp *SFTPTargetConfig, fromDir, toDir string) *SFTPTargetConfig {
+	if sftp == nil {
+		return nil
+	}
+	clone := *sftp
+	clone.Path = rewriteProjectPath(sftp.Path, fromDir, toDir)
+	return &clone
+}
+
+// rewriteProjectPath rewrites a path that refers to the fromDir project so it refers to toDir
+func rewriteProjectPath(path, fromDir, toDir string) string {
+	if path == "" {
+		return path
+	}
+
+	// Absolute paths inside the source project move along with it
+	if filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(fromDir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.Join(toDir, rel)
+		}
+	}
+
+	fromName := filepath.Base(fromDir)
+	toName := filepath.Base(toDir)
+	if fromName == toName || fromName == "." || fromName == string(filepath.Separator) {
+		return path
+	}
+
+	parts := strings.Split(path, string(filepath.Separator))
+	for i, part := range parts {
+		if part == fromName {
+			parts[i] = toName
+			continue
+		}
+
+		// Single file targets are usually named after the project, e.g. foo.tar.gz or foo-latest.tar.gz
+		if i == len(parts)-1 && strings.HasPrefix(part, fromName) {
+			rest := part[len(fromName):]
+			if strings.HasPrefix(rest, ".") || strings.HasPrefix(rest, "-") || strings.HasPrefix(rest, "_") {
+				parts[i] = toName + rest
+			}
+		}
+	}
+	return strings.Join(parts, string(filepath.Separator))
+}
@@ -0,0 +1,92 @@
+package config_test
+
+import (
+	"time"
+
+	. "github.com/kennycyb/go-backup/internal/service/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CloneConfig", func() {
+	var source *BackupConfig
+
+	BeforeEach(func() {
+		source = &BackupConfig{
+			Excludes: []string{"node_modules", "/home/user/projects/foo/tmp"},
+			Targets: []BackupTarget{
+				{
+					Path:       "/mnt/nas/foo",
+					MaxBackups: 5,
+					Backups:    []BackupRecord{{Filename: "foo-20240101-120000.tar.gz", CreatedAt: time.Now()}},
+					LastRun:    &BackupStatus{Status: "Success"},
+				},
+				{File: "/backups/foo.tar.gz.gpg"},
+				{Path: "/home/user/projects/foo/.backups"},
+			},
+			Encryption: &EncryptionConfig{Method: "gpg", Receiver: "user@example.com"},
+		}
+	})
+
+	It("should drop backup history and last run status", func() {
+		clone := CloneConfig(source, "/home/user/projects/foo", "/home/user/projects/bar")
+		Expect(clone.Targets[0].Backups).To(BeEmpty())
+		Expect(clone.Targets[0].LastRun).To(BeNil())
+		Expect(clone.Targets[0].MaxBackups).To(Equal(5))
+	})
+
+	It("should rewrite paths that refer to the source project", func() {
+		clone := CloneConfig(source, "/home/user/projects/foo", "/home/user/projects/bar")
+		Expect(clone.Targets[0].Path).To(Equal("/mnt/nas/bar"))
+		Expect(clone.Targets[1].File).To(Equal("/backups/bar.tar.gz.gpg"))
+		Expect(clone.Targets[2].Path).To(Equal("/home/user/projects/bar/.backups"))
+		Expect(clone.Excludes).To(Equal([]string{"node_modules", "/home/user/projects/bar/tmp"}))
+	})
+
+	It("should copy encryption settings without sharing them", func() {
+		clone := CloneConfig(source, "/home/user/projects/foo", "/home/user/projects/bar")
+		Expect(clone.Encryption).To(Equal(source.Encryption))
+		clone.Encryption.Receiver = "other@example.com"
+		Expect(source.Encryption.Receiver).To(Equal("user@example.com"))
+	})
+
+	It("should keep the per-target overrides and the rehearsal settings", func() {
+		source.Targets = []BackupTarget{{
+			Path:        "/mnt/cloud/foo",
+			MaxBackups:  3,
+			Compression: &CompressionConfig{Level: 9, Format: "zip"},
+			Encryption:  &EncryptionConfig{Method: "gpg", Receiver: "cloud@example.com"},
+			Upload:      &UploadConfig{PartSize: 64, AppendOnly: true, ObjectLock: &ObjectLockConfig{Mode: "governance", Days: 30}},
+			Parity:      "10%",
+			Quota:       "10G",
+			Chunks:      "64M",
+			Backups:     []BackupRecord{{Filename: "foo-20240101-120000.tar.gz"}},
+			LastRun:     &BackupStatus{Status: "Success"},
+		}}
+		source.Rehearsal = &RehearsalConfig{Every: "weekly", Sample: 2}
+
+		clone := CloneConfig(source, "/home/user/projects/foo", "/home/user/projects/bar")
+		target := clone.Targets[0]
+		Expect(target.Path).To(Equal("/mnt/cloud/bar"))
+		Expect(target.Compression).To(Equal(&CompressionConfig{Level: 9, Format: "zip"}))
+		Expect(target.Encryption).To(Equal(&EncryptionConfig{Method: "gpg", Receiver: "cloud@example.com"}))
+		Expect(target.Upload).To(Equal(source.Targets[0].Upload))
+		Expect(target.Parity).To(Equal("10%"))
+		Expect(target.Quota).To(Equal("10G"))
+		Expect(target.Chunks).To(Equal("64M"))
+		Expect(target.Backups).To(BeEmpty())
+		Expect(target.LastRun).To(BeNil())
+		Expect(clone.Rehearsal).To(Equal(&RehearsalConfig{Every: "weekly", Sample: 2}))
+
+		target.Encryption.Receiver = "other@example.com"
+		target.Upload.ObjectLock.Days = 1
+		Expect(source.Targets[0].Encryption.Receiver).To(Equal("cloud@example.com"))
+		Expect(source.Targets[0].Upload.ObjectLock.Days).To(Equal(30))
+	})
+
+	It("should not rewrite names that merely start with the project name", func() {
+		source.Targets = []BackupTarget{{Path: "/mnt/foobar"}}
+		clone := CloneConfig(source, "/home/user/projects/foo", "/home/user/projects/bar")
+		Expect(clone.Targets[0].Path).To(Equal("/mnt/foobar"))
+	})
+})
@@ -2,38 +2,194 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	"github.com/kennycyb/go-backup/internal/service/command"
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+	powerService "github.com/kennycyb/go-backup/internal/service/power"
 	"gopkg.in/yaml.v3"
 )
 
 // BackupRecord represents an individual backup entry
 type BackupRecord struct {
-	Filename  string    `yaml:"filename"`
-	Source    string    `yaml:"source"`
-	CreatedAt time.Time `yaml:"createdAt"`
-	Size      int64     `yaml:"size"`
+	Filename   string    `yaml:"filename"`
+	Source     string    `yaml:"source"`
+	Host       string    `yaml:"host,omitempty"`  // Machine the backup was made on, telling apart backups of a shared target
+	RunID      string    `yaml:"runId,omitempty"` // Run that made the backup, as in its log and notifications
+	CreatedAt  time.Time `yaml:"createdAt"`
+	Size       int64     `yaml:"size"`
+	SHA256     string    `yaml:"sha256,omitempty"`     // Checksum of the backup file, as in its .sha256 file
+	SnapshotOf string    `yaml:"snapshotOf,omitempty"` // Archive holding the contents when the record is a metadata snapshot
+	Tags       []string  `yaml:"tags,omitempty"`       // Labels given with run --tag, e.g. "pre-release"
+	Message    string    `yaml:"message,omitempty"`    // Note given with run --message, like a commit message
+	Part       string    `yaml:"part,omitempty"`       // Top-level directory held by a run --split-by-dir backup
+
+	Provenance *backupService.Provenance `yaml:"provenance,omitempty"` // How the backup was produced
 }
 
+// HasTag reports whether the backup was labelled with the tag
+func (r BackupRecord) HasTag(tag string) bool {
+	for _, t := range r.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTags checks backup tags: they must be non-empty and may not contain whitespace or commas
+func ValidateTags(tags []string) error {
+	for _, tag := range tags {
+		if tag == "" || strings.ContainsAny(tag, " \t\n,") {
+			return fmt.Errorf("invalid tag %q: tags must be non-empty and contain no spaces or commas", tag)
+		}
+	}
+	return nil
+}
+
+// TaggedBackups returns the file names of the tagged backups in the history.
+// A tagged metadata snapshot also needs the archive holding its contents.
+func TaggedBackups(records []BackupRecord) map[string]bool {
+	tagged := make(map[string]bool)
+	for _, record := range records {
+		if len(record.Tags) > 0 {
+			tagged[record.Filename] = true
+			if record.SnapshotOf != "" {
+				tagged[record.SnapshotOf] = true
+			}
+		}
+	}
+	return tagged
+}
+
+// Values of BackupStatus.Status
+const (
+	StatusSuccess  = "Success"
+	StatusFailure  = "Failure"
+	StatusSkipped  = "Skipped"  // Intentionally not backed up; Message holds the reason
+	StatusDeferred = "Deferred" // Put off until the machine is on AC power or an unmetered connection; Message holds why
+)
+
 // BackupStatus represents the status of the last backup run
 type BackupStatus struct {
 	Timestamp time.Time `yaml:"timestamp"`
-	Status    string    `yaml:"status"` // "Success" or "Failure"
+	Status    string    `yaml:"status"` // StatusSuccess, StatusFailure, StatusSkipped or StatusDeferred
 	Message   string    `yaml:"message,omitempty"`
 }
 
-// BackupTarget represents a target destination for backups
+// BackupTarget represents a target destination for backups.
+// Compression and Encryption override the top-level settings for this target only,
+// e.g. a fast unencrypted local copy next to a small encrypted cloud copy.
 type BackupTarget struct {
-	Path       string         `yaml:"path,omitempty"`
-	File       string         `yaml:"file,omitempty"`
-	MaxBackups int            `yaml:"maxBackups,omitempty"`
-	Backups    []BackupRecord `yaml:"backups,omitempty"`
-	LastRun    *BackupStatus  `yaml:"lastRun,omitempty"`
+	Type        string             `yaml:"type,omitempty"`    // "exec" for a destination handled by Command; empty otherwise
+	Command     string             `yaml:"command,omitempty"` // Exec targets only: the executable speaking the exec protocol, with its arguments
+	Path        string             `yaml:"path,omitempty"`
+	File        string             `yaml:"file,omitempty"`
+	S3          *S3TargetConfig    `yaml:"s3,omitempty"`       // Bucket the backups are uploaded to, instead of path or file
+	SFTP        *SFTPTargetConfig  `yaml:"sftp,omitempty"`     // SSH server the backups are uploaded to, instead of path or file
+	Fallback    bool               `yaml:"fallback,omitempty"` // Only receives the backup when no other target did, e.g. a local disk for an unreachable NAS
+	MaxBackups  int                `yaml:"maxBackups,omitempty"`
+	Compression *CompressionConfig `yaml:"compression,omitempty"`
+	Encryption  *EncryptionConfig  `yaml:"encryption,omitempty"` // Method "none" stores this target unencrypted
+	Upload      *UploadConfig      `yaml:"upload,omitempty"`     // Remote targets only
+	Parity      string             `yaml:"parity,omitempty"`     // PAR2 redundancy written next to each backup, e.g. "10%"
+	Quota       string             `yaml:"quota,omitempty"`      // Size the backups on this target should stay within, e.g. "200G"
+	Chunks      string             `yaml:"chunks,omitempty"`     // Remote targets only: size of the separately readable chunks of each backup, e.g. "64M"
+	Backups     []BackupRecord     `yaml:"backups,omitempty"`
+	LastRun     *BackupStatus      `yaml:"lastRun,omitempty"`
+}
+
+// S3TargetConfig represents a directory in an S3 bucket, or in an S3-compatible service such as MinIO.
+// Unlike path: s3://... targets, backups beyond maxBackups are deleted from it.
+type S3TargetConfig struct {
+	Bucket   string `yaml:"bucket"`
+	Prefix   string `yaml:"prefix,omitempty"`   // Directory within the bucket, e.g. "backups/laptop"
+	Region   string `yaml:"region,omitempty"`   // Region of the bucket; empty uses the aws CLI's configuration
+	Endpoint string `yaml:"endpoint,omitempty"` // URL of an S3-compatible service, e.g. "http://minio.local:9000"
+}
+
+// Location returns the s3:// location of the directory, with the region and endpoint as parameters
+func (s S3TargetConfig) Location() string {
+	location := "s3://" + s.Bucket + "/"
+	if prefix := strings.Trim(s.Prefix, "/"); prefix != "" {
+		location += prefix + "/"
+	}
+	query := url.Values{}
+	if s.Region != "" {
+		query.Set("region", s.Region)
+	}
+	if s.Endpoint != "" {
+		query.Set("endpoint", s.Endpoint)
+	}
+	if len(query) > 0 {
+		location += "?" + query.Encode()
+	}
+	return location
+}
+
+// SFTPTargetConfig represents a directory on a server reachable over SSH, such as a NAS.
+// Unlike path: sftp://... targets, backups beyond maxBackups are deleted from it.
+type SFTPTargetConfig struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port,omitempty"` // Empty uses ssh's configuration, usually 22
+	User string `yaml:"user,omitempty"` // Empty uses ssh's configuration, usually the local user
+	Key  string `yaml:"key,omitempty"`  // Private key to log in with, e.g. "~/.ssh/nas_ed25519"; empty uses ssh's configuration
+	Path string `yaml:"path"`           // Absolute directory on the server, e.g. "/volume1/backups/laptop"
+}
+
+// Location returns the sftp:// location of the directory, with the key as a parameter
+func (s SFTPTargetConfig) Location() string {
+	host := s.Host
+	if s.User != "" {
+		host = s.User + "@" + host
+	}
+	if s.Port != 0 {
+		host += ":" + strconv.Itoa(s.Port)
+	}
+	location := "sftp://" + host + "/"
+	if dir := strings.Trim(s.Path, "/"); dir != "" {
+		location += dir + "/"
+	}
+	if s.Key != "" {
+		location += "?" + url.Values{"key": {s.Key}}.Encode()
+	}
+	return location
+}
+
+// UploadConfig represents how backups are sent to a remote target (s3://, sftp://, rclone:).
+// Large files are uploaded in parts, several at a time.
+type UploadConfig struct {
+	PartSize    int               `yaml:"partSize,omitempty"`    // Part size in MB; 0 uses the default
+	Concurrency int               `yaml:"concurrency,omitempty"` // Parts uploaded in parallel; 0 uses the default
+	AppendOnly  bool              `yaml:"appendOnly,omitempty"`  // Never replace or delete backups on the target
+	ObjectLock  *ObjectLockConfig `yaml:"objectLock,omitempty"`  // S3 only: retention for every uploaded object
+}
+
+// ObjectLockConfig represents the S3 object lock retention of uploaded backups.
+// The bucket must have object lock enabled; a target with object lock is append-only.
+type ObjectLockConfig struct {
+	Mode string `yaml:"mode"` // "governance" or "compliance"
+	Days int    `yaml:"days"` // Days each uploaded object is retained
+}
+
+// Values of ObjectLockConfig.Mode
+const (
+	ObjectLockGovernance = "governance" // Retention can be lifted by users allowed to bypass it
+	ObjectLockCompliance = "compliance" // Retention cannot be lifted by anyone until it expires
+)
+
+// CompressionConfig represents the archive compression settings
+type CompressionConfig struct {
+	Level  int    `yaml:"level,omitempty"`  // gzip level from 1 (fastest) to 9 (smallest); 0 uses the default
+	Format string `yaml:"format,omitempty"` // Archive format: tar.gz (default), tar or zip
 }
 
 // EncryptionConfig represents the encryption configuration
@@ -43,6 +199,35 @@ type EncryptionConfig struct {
 	Passphrase string `yaml:"passphrase,omitempty"`
 }
 
+// UnmarshalYAML also accepts the shorthands of per-target overrides: "encryption: false" stores the
+// target unencrypted, "encryption: true" encrypts it for the top-level receiver, and a receiver
+// without a method means GPG. At the top level there is no receiver to inherit, which
+// CheckEncryptionReceivers reports.
+func (e *EncryptionConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode && value.Tag == "!!bool" {
+		var enabled bool
+		if err := value.Decode(&enabled); err != nil {
+			return err
+		}
+		*e = EncryptionConfig{Method: "none"}
+		if enabled {
+			e.Method = "gpg"
+		}
+		return nil
+	}
+
+	type encryptionConfig EncryptionConfig
+	var decoded encryptionConfig
+	if err := value.Decode(&decoded); err != nil {
+		return err
+	}
+	*e = EncryptionConfig(decoded)
+	if e.Method == "" && e.Receiver != "" {
+		e.Method = "gpg"
+	}
+	return nil
+}
+
 // GitOptions represents git-related options for backup automation.
 // When Enable is true, backups are skipped if there are no uncommitted changes.
 // Branch specifies the branch name for auto-pull (e.g., "main" or "master").
@@ -52,33 +237,175 @@ type GitOptions struct {
 	Enable bool   `yaml:"enable"`
 	Branch string `yaml:"branch,omitempty"`
 	Pull   string `yaml:"pull,omitempty"` // Valid values: "" (default, no auto-pull) or "auto" to enable auto-pull.
+	// Implementation is "git" for the git binary or "go-git" for the one built into go-backup;
+	// empty uses the git binary when it is installed
+	Implementation string `yaml:"implementation,omitempty"`
 }
 
-// Options represents optional backup settings
+// Options represents optional backup settings.
+// Nice and CPULimit keep background backups from slowing down interactive work.
 type Options struct {
-	Git GitOptions `yaml:"git,omitempty"`
+	Git               GitOptions         `yaml:"git,omitempty"`
+	Nice              bool               `yaml:"nice,omitempty"`              // Run at a lower CPU and IO priority
+	CPULimit          int                `yaml:"cpuLimit,omitempty"`          // Maximum number of CPUs used for compression; 0 means all
+	MetadataSnapshots bool               `yaml:"metadataSnapshots,omitempty"` // Store only a manifest when no file contents changed
+	Timeout           string             `yaml:"timeout,omitempty"`           // Maximum duration of a run, e.g. "2h"; empty means no limit
+	OnError           string             `yaml:"onError,omitempty"`           // What to do with unreadable files: skip (default) or fail
+	IncludeVCS        bool               `yaml:"includeVCS,omitempty"`        // Archive .git, .hg and .svn directories even when excluded
+	IncludeHidden     *bool              `yaml:"includeHidden,omitempty"`     // Set to false to leave out dotfiles and dot directories; nil means included
+	HiddenAllow       []string           `yaml:"hiddenAllow,omitempty"`       // Hidden paths still backed up with includeHidden: false, as exclude patterns (e.g. ".env.example")
+	Syslog            bool               `yaml:"syslog,omitempty"`            // Also write the results of each run to syslog or journald
+	TimestampFormat   string             `yaml:"timestampFormat,omitempty"`   // Go time layout of the timestamp in backup file names; defaults to 20060102-150405
+	Timezone          string             `yaml:"timezone,omitempty"`          // Time zone of file names and recorded times, e.g. "UTC"; defaults to local time
+	Reflink           bool               `yaml:"reflink,omitempty"`           // Clone backups into local targets on copy-on-write file systems instead of copying them
+	SecretScan        bool               `yaml:"secretScan,omitempty"`        // Warn about files that look like secrets before an unencrypted backup goes to a remote target
+	RequireAC         bool               `yaml:"requireAC,omitempty"`         // Defer the backup while the machine runs on battery
+	MinBattery        int                `yaml:"minBattery,omitempty"`        // With requireAC, back up on battery anyway from this charge in percent
+	SkipOnMetered     bool               `yaml:"skipOnMetered,omitempty"`     // Defer the backup while the network connection is metered
+	Compression       *CompressionConfig `yaml:"compression,omitempty"`       // gzip level and archive format of the targets without their own
+}
+
+// ExcludesHidden reports whether dotfiles and dot directories are left out of the backup
+func (o *Options) ExcludesHidden() bool {
+	return o != nil && o.IncludeHidden != nil && !*o.IncludeHidden
+}
+
+// Values for Options.OnError
+const (
+	OnErrorSkip = "skip" // Leave unreadable files out of the backup with a warning
+	OnErrorFail = "fail" // Fail the backup at the first unreadable file
+)
+
+// SkipUnreadable reports whether unreadable files are left out of the backup instead of failing it
+func (o *Options) SkipUnreadable() (bool, error) {
+	if o == nil {
+		return true, nil
+	}
+	switch o.OnError {
+	case "", OnErrorSkip:
+		return true, nil
+	case OnErrorFail:
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid onError %q: use %s or %s", o.OnError, OnErrorSkip, OnErrorFail)
+}
+
+// RunTimeout returns the maximum duration of a run, or 0 if there is no limit
+func (o *Options) RunTimeout() (time.Duration, error) {
+	if o == nil || o.Timeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(o.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q: use a duration like 2h or 30m", o.Timeout)
+	}
+	return timeout, nil
+}
+
+// CompressionLevel returns the gzip level of the targets without a level of their own, or 0 for the default
+func (o *Options) CompressionLevel() (int, error) {
+	if o == nil || o.Compression == nil {
+		return 0, nil
+	}
+	if level := o.Compression.Level; level < 0 || level > 9 {
+		return 0, fmt.Errorf("invalid compression level %d: use 1 (fastest) to 9 (smallest)", level)
+	}
+	return o.Compression.Level, nil
+}
+
+// ArchiveFormat returns the archive format of the targets without a format of their own, or "" for tar.gz
+func (o *Options) ArchiveFormat() (string, error) {
+	if o == nil || o.Compression == nil {
+		return "", nil
+	}
+	if err := compressionService.ValidateFormat(o.Compression.Format); err != nil {
+		return "", err
+	}
+	return o.Compression.Format, nil
+}
+
+// Naming returns how backup file names and recorded times are timestamped
+func (o *Options) Naming() (backupService.Naming, error) {
+	if o == nil {
+		return backupService.DefaultNaming(), nil
+	}
+	return backupService.NewNaming(o.TimestampFormat, o.Timezone)
+}
+
+// PowerPolicy returns when the backup is deferred to save battery or metered data
+func (o *Options) PowerPolicy() (powerService.Policy, error) {
+	if o == nil {
+		return powerService.Policy{}, nil
+	}
+	if o.MinBattery < 0 || o.MinBattery > 100 {
+		return powerService.Policy{}, fmt.Errorf("invalid minBattery %d: use a percentage from 0 to 100", o.MinBattery)
+	}
+	return powerService.Policy{RequireAC: o.RequireAC, MinBattery: o.MinBattery, SkipOnMetered: o.SkipOnMetered}, nil
+}
+
+// RotationConfig represents how expired backups are removed during rotation.
+// When Trash is true, expired backups are moved into a .trash subfolder of the target
+// and only deleted for good once they have been there for TrashDays days.
+type RotationConfig struct {
+	Trash      bool `yaml:"trash,omitempty"`
+	TrashDays  int  `yaml:"trashDays,omitempty"`  // Grace period for trashed backups; defaults to 30 days
+	KeepTagged bool `yaml:"keepTagged,omitempty"` // Never rotate out backups created with run --tag
+	Immutable  bool `yaml:"immutable,omitempty"`  // Make local backups read-only, and immutable where permitted; rotation lifts it
+}
+
+// RehearsalConfig represents scheduled restore rehearsals: every so often a few random
+// backups are restored to a temporary directory and checked against their manifest,
+// so a backup that cannot be restored is found before it is needed.
+type RehearsalConfig struct {
+	Every  string `yaml:"every"`            // Time between rehearsals: daily, weekly, monthly, or a duration like 30d or 72h
+	Sample int    `yaml:"sample,omitempty"` // Backups restored per rehearsal; defaults to 1
+}
+
+// Interval returns the time between rehearsals
+func (r *RehearsalConfig) Interval() (time.Duration, error) {
+	interval, err := parseInterval(r.Every)
+	if err != nil || interval == 0 {
+		return 0, fmt.Errorf("invalid rehearsal interval %q: use daily, weekly, monthly or a duration like 30d or 72h", r.Every)
+	}
+	return interval, nil
+}
+
+// SampleSize returns the number of backups restored per rehearsal
+func (r *RehearsalConfig) SampleSize() int {
+	if r.Sample <= 0 {
+		return 1
+	}
+	return r.Sample
+}
+
+// RehearsalDue reports whether the config schedules rehearsals and the last one is older than
+// the interval. Configs that never had a rehearsal are due.
+func (c *BackupConfig) RehearsalDue(now time.Time) (bool, error) {
+	if c.Rehearsal == nil {
+		return false, nil
+	}
+	interval, err := c.Rehearsal.Interval()
+	if err != nil {
+		return false, err
+	}
+	if c.LastRehearsal == nil {
+		return true, nil
+	}
+	return !now.Before(c.LastRehearsal.Timestamp.Add(interval)), nil
 }
 
 // BackupConfig represents the structure of the backup configuration file
 type BackupConfig struct {
 	Excludes   []string          `yaml:"excludes"`
+	Includes   []string          `yaml:"includes,omitempty"`   // When set, only these paths (from the source root) are backed up
+	NoCompress []string          `yaml:"noCompress,omitempty"` // Files stored without compression, matched like excludes
 	Targets    []BackupTarget    `yaml:"target"`
 	Encryption *EncryptionConfig `yaml:"encryption,omitempty"`
 	Options    *Options          `yaml:"options,omitempty"`
-}
-
-// GlobalBackupEntry represents a single backup location tracked in the global registry
-type GlobalBackupEntry struct {
-	Location string    `yaml:"location"` // Full path to the directory containing .backup.yaml
-	RunAt    time.Time `yaml:"run_at"`   // Last run timestamp
-}
+	Rotation   *RotationConfig   `yaml:"rotation,omitempty"`
+	Rehearsal  *RehearsalConfig  `yaml:"rehearsal,omitempty"`
 
-// GlobalBackupRegistry represents the structure of ~/.backup.yaml global config
-type GlobalBackupRegistry struct {
-	Default struct {
-		Encryption *EncryptionConfig `yaml:"encryption,omitempty"`
-	} `yaml:"default,omitempty"`
-	Backups []GlobalBackupEntry `yaml:"backups,omitempty"`
+	LastRehearsal *BackupStatus `yaml:"lastRehearsal,omitempty"` // Outcome of the last restore rehearsal
 }
 
 // ReadBackupConfig reads the backup configuration from the specified file
@@ -89,7 +416,7 @@ func ReadBackupConfig(filePath string) (*BackupConfig, error) {
 	}
 
 	var config BackupConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	if err := decodeBackupConfig(data, &config, strictDecoding); err != nil {
 		return nil, err
 	}
 
@@ -103,7 +430,11 @@ func ReadBackupConfig(filePath string) (*BackupConfig, error) {
 	return &config, nil
 }
 
-// WriteBackupConfig writes the backup configuration to the specified file
+// backupConfigHeader are the starts of the header lines WriteBackupConfig writes above the config
+var backupConfigHeader = []string{"# Backup configuration file", "# WARNING: Do not manually edit this file", "# Created/updated by go-backup on:"}
+
+// WriteBackupConfig writes the backup configuration to the specified file.
+// The comments, key order and layout of an existing file are kept.
 func WriteBackupConfig(filePath string, config *BackupConfig) error {
 	// Create the directory for the output path if it doesn't exist
 	outputDir := filepath.Dir(filePath)
@@ -113,8 +444,9 @@ func WriteBackupConfig(filePath string, config *BackupConfig) error {
 		}
 	}
 
-	// Marshal the config to YAML
-	data, err := yaml.Marshal(config)
+	// Marshal the config to YAML, keeping the comments and layout of the existing file
+	previous, _ := os.ReadFile(filePath)
+	data, err := marshalPreserving(config, previous, backupConfigHeader...)
 	if err != nil {
 		return err
 	}
@@ -133,12 +465,257 @@ func (t BackupTarget) IsFileTarget() bool {
 	return t.File != ""
 }
 
-// GetDestination returns the destination path for this target
+// RotatesRemotely returns true if old backups are deleted from the remote target after each run,
+// as for s3 and sftp sections; other remote targets are not rotated
+func (t BackupTarget) RotatesRemotely() bool {
+	return t.S3 != nil || t.SFTP != nil
+}
+
+// TargetTypeExec is the type of targets whose backups are stored by a user's command, e.g. a
+// corporate blob store; the command receives JSON requests on stdin and answers on stdout
+const TargetTypeExec = "exec"
+
+// GetDestination returns the destination path for this target.
+// Exec targets return the remote location exec:<command>:<path>, and S3 and SFTP targets their
+// s3:// and sftp:// locations.
+// Returns "" if neither Path, File, S3 nor SFTP is set; ResolveTargets reports such targets.
 func (t BackupTarget) GetDestination() string {
+	if t.S3 != nil {
+		return t.S3.Location()
+	}
+	if t.SFTP != nil {
+		return t.SFTP.Location()
+	}
+	dest := t.Path
 	if t.IsFileTarget() {
-		return t.File
+		dest = t.File
+	}
+	if dest == "" {
+		return ""
+	}
+	if t.Type == TargetTypeExec {
+		return TargetTypeExec + ":" + t.Command + ":" + dest
+	}
+	return dest
+}
+
+// validateType checks the type of the target and the command of exec targets
+func (t BackupTarget) validateType() error {
+	if t.S3 != nil {
+		switch {
+		case t.Type != "":
+			return fmt.Errorf("target %s: s3 cannot be combined with type %s", t.GetDestination(), t.Type)
+		case t.Path != "" || t.File != "":
+			return fmt.Errorf("target %s: s3 replaces path and file; set only one of them", t.GetDestination())
+		case t.S3.Bucket == "" || strings.Contains(t.S3.Bucket, "/"):
+			return fmt.Errorf("target %s: s3 needs a bucket name, without slashes", t.GetDestination())
+		}
+		if t.S3.Endpoint != "" {
+			if u, err := url.Parse(t.S3.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("target %s: invalid s3 endpoint %q (expected a URL like https://minio.local:9000)", t.GetDestination(), t.S3.Endpoint)
+			}
+		}
+	}
+	if t.SFTP != nil {
+		switch {
+		case t.S3 != nil:
+			return fmt.Errorf("target %s: set only one of s3 and sftp", t.GetDestination())
+		case t.Type != "":
+			return fmt.Errorf("target %s: sftp cannot be combined with type %s", t.GetDestination(), t.Type)
+		case t.Path != "" || t.File != "":
+			return fmt.Errorf("target %s: sftp replaces path and file; set only one of them", t.GetDestination())
+		case t.SFTP.Host == "" || strings.ContainsAny(t.SFTP.Host, "@:/"):
+			return fmt.Errorf("target %s: sftp needs a host name, with user and port set on their own", t.GetDestination())
+		case t.SFTP.Port < 0 || t.SFTP.Port > 65535:
+			return fmt.Errorf("target %s: invalid sftp port %d", t.GetDestination(), t.SFTP.Port)
+		case !strings.HasPrefix(t.SFTP.Path, "/") || strings.Trim(t.SFTP.Path, "/") == "":
+			return fmt.Errorf("target %s: sftp needs an absolute directory below / as path", t.GetDestination())
+		}
+	}
+	switch t.Type {
+	case "":
+		if t.Command != "" {
+			return fmt.Errorf("target %s: command is only used with type %s", t.GetDestination(), TargetTypeExec)
+		}
+	case TargetTypeExec:
+		if strings.TrimSpace(t.Command) == "" {
+			return fmt.Errorf("target %s: type %s needs a command", t.GetDestination(), TargetTypeExec)
+		}
+		if strings.Contains(t.Command, ":") {
+			return fmt.Errorf("target %s: the command may not contain ':'", t.GetDestination())
+		}
+	default:
+		return fmt.Errorf("target %s: unknown type %q (expected %s, or none for paths and remote locations)", t.GetDestination(), t.Type, TargetTypeExec)
+	}
+	return nil
+}
+
+// GetCompressionLevel returns the gzip compression level for this target, or 0 for the default
+func (t BackupTarget) GetCompressionLevel() int {
+	if t.Compression == nil {
+		return 0
+	}
+	return t.Compression.Level
+}
+
+// ArchiveFormat returns the archive format of this target, or "" for the default
+func (t BackupTarget) ArchiveFormat() (string, error) {
+	if t.Compression == nil {
+		return "", nil
+	}
+	if err := compressionService.ValidateFormat(t.Compression.Format); err != nil {
+		return "", fmt.Errorf("target %s: %w", t.GetDestination(), err)
 	}
-	return t.Path
+	return t.Compression.Format, nil
+}
+
+// IsAppendOnly returns true if go-backup must never replace or delete backups on this target,
+// either because it is configured append-only or because uploads are object-locked
+func (t BackupTarget) IsAppendOnly() bool {
+	return t.Upload != nil && (t.Upload.AppendOnly || t.Upload.ObjectLock != nil)
+}
+
+// validateUpload checks the upload settings of the target.
+// Append-only needs a remote directory target, since a file target is replaced on every run,
+// and object lock needs an S3 location.
+func (t BackupTarget) validateUpload() error {
+	if t.Upload == nil {
+		return nil
+	}
+	dest := t.GetDestination()
+	if t.IsAppendOnly() {
+		if t.IsFileTarget() {
+			return fmt.Errorf("target %s: append-only and object lock need a directory target (path), since a file target is replaced on every run", dest)
+		}
+		if !isRemoteDestination(dest) {
+			return fmt.Errorf("target %s: append-only and object lock are only supported for remote targets", dest)
+		}
+	}
+	if lock := t.Upload.ObjectLock; lock != nil {
+		if !strings.HasPrefix(dest, "s3://") {
+			return fmt.Errorf("target %s: object lock is only supported for s3:// targets", dest)
+		}
+		if lock.Mode != ObjectLockGovernance && lock.Mode != ObjectLockCompliance {
+			return fmt.Errorf("target %s: invalid object lock mode %q (expected %q or %q)", dest, lock.Mode, ObjectLockGovernance, ObjectLockCompliance)
+		}
+		if lock.Days <= 0 {
+			return fmt.Errorf("target %s: object lock days must be positive, got %d", dest, lock.Days)
+		}
+	}
+	return nil
+}
+
+// ParityPercent returns the PAR2 redundancy of the target's backups as a percentage of
+// their size, or 0 if no parity files are written. "10%" and "10" both mean 10 percent.
+func (t BackupTarget) ParityPercent() (int, error) {
+	if t.Parity == "" {
+		return 0, nil
+	}
+	percent, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(t.Parity), "%"))
+	if err != nil || percent < 1 || percent > 100 {
+		return 0, fmt.Errorf("target %s: invalid parity %q (expected a percentage from 1%% to 100%%)", t.GetDestination(), t.Parity)
+	}
+	return percent, nil
+}
+
+// MinChunkSize is the smallest chunk size of a target, since every chunk costs a request
+// and, for encrypted backups, a GPG message
+const MinChunkSize = 1 << 20
+
+// ChunkSize returns the size of the chunks the target's backups are split into, or 0 if they
+// are stored in one piece. Chunks let restore --files-from and cat read a remote backup in part.
+func (t BackupTarget) ChunkSize() (int64, error) {
+	if t.Chunks == "" {
+		return 0, nil
+	}
+	dest := t.GetDestination()
+	size, err := ParseSize(t.Chunks)
+	if err != nil {
+		return 0, fmt.Errorf("target %s: invalid chunks: %w", dest, err)
+	}
+	if size < MinChunkSize {
+		return 0, fmt.Errorf("target %s: chunks must be at least 1M, got %q", dest, t.Chunks)
+	}
+	if t.IsFileTarget() || !isRemoteDestination(dest) {
+		return 0, fmt.Errorf("target %s: chunks are only supported for remote directory targets (path), since only they are read in part", dest)
+	}
+	return size, nil
+}
+
+// isRemoteDestination reports whether the destination is a remote location (s3://, sftp://, rclone:, exec:)
+func isRemoteDestination(dest string) bool {
+	return strings.HasPrefix(dest, "s3://") || strings.HasPrefix(dest, "sftp://") || strings.HasPrefix(dest, "rclone:") || strings.HasPrefix(dest, "exec:")
+}
+
+// EffectiveEncryption returns the encryption settings that apply to this target,
+// taking the target's override into account. A target override without a receiver
+// inherits the receiver from defaultEncryption.
+// Returns nil if backups for this target are not encrypted.
+func (t BackupTarget) EffectiveEncryption(defaultEncryption *EncryptionConfig) *EncryptionConfig {
+	encryption := defaultEncryption
+	if t.Encryption != nil {
+		override := *t.Encryption
+		if override.Receiver == "" && defaultEncryption != nil {
+			override.Receiver = defaultEncryption.Receiver
+		}
+		encryption = &override
+	}
+
+	return encryption.effective()
+}
+
+// EncryptionRecipients returns the distinct GPG recipients used by the config, from the
+// top-level encryption and the per-target overrides.
+// Returns an error if an encryption method other than "gpg" or "none" is configured,
+// since such a target would otherwise silently be stored unencrypted.
+func EncryptionRecipients(config *BackupConfig) ([]string, error) {
+	settings := []*EncryptionConfig{config.Encryption}
+	for _, target := range config.Targets {
+		settings = append(settings, target.Encryption)
+	}
+	for _, encryption := range settings {
+		if encryption != nil && encryption.Method != "gpg" && encryption.Method != "none" {
+			return nil, fmt.Errorf("unsupported encryption method %q (expected \"gpg\" or \"none\")", encryption.Method)
+		}
+	}
+
+	var recipients []string
+	seen := make(map[string]bool)
+	add := func(encryption *EncryptionConfig) {
+		if encryption != nil && encryption.Receiver != "" && !seen[encryption.Receiver] {
+			seen[encryption.Receiver] = true
+			recipients = append(recipients, encryption.Receiver)
+		}
+	}
+
+	add(config.Encryption.effective())
+	for _, target := range config.Targets {
+		add(target.EffectiveEncryption(config.Encryption))
+	}
+	return recipients, nil
+}
+
+// CheckEncryptionReceivers returns an error if GPG encryption is enabled without a receiver to
+// encrypt for, e.g. by "encryption: true" at the top level or for a target of a config without a
+// top-level receiver. run can still be given one with --encrypt-to, so reading the config allows it.
+func CheckEncryptionReceivers(config *BackupConfig) error {
+	if encryption := config.Encryption.effective(); encryption != nil && encryption.Receiver == "" {
+		return errors.New("encryption is enabled without a receiver; set encryption.receiver")
+	}
+	for _, target := range config.Targets {
+		if encryption := target.EffectiveEncryption(config.Encryption); encryption != nil && encryption.Receiver == "" {
+			return fmt.Errorf("target %s: encryption is enabled without a receiver; set encryption.receiver", target.GetDestination())
+		}
+	}
+	return nil
+}
+
+// effective returns the encryption settings if they enable GPG encryption, or nil
+func (e *EncryptionConfig) effective() *EncryptionConfig {
+	if e == nil || e.Method != "gpg" {
+		return nil
+	}
+	return e
 }
 
 // AddBackupRecord adds a new backup record to the specified target in the config
@@ -146,7 +723,7 @@ func AddBackupRecord(config *BackupConfig, targetPath string, record BackupRecor
 	// Find the target index
 	targetIndex := -1
 	for i, target := range config.Targets {
-		if target.GetDestination() == targetPath {
+		if target.hasDestination(targetPath) {
 			targetIndex = i
 			break
 		}
@@ -159,24 +736,80 @@ func AddBackupRecord(config *BackupConfig, targetPath string, record BackupRecor
 			config.Targets[targetIndex].Backups = []BackupRecord{record}
 		} else {
 			// Add the new backup to the beginning of the list for the target
-			config.Targets[targetIndex].Backups = append(
+			config.Targets[targetIndex].Backups = config.trimHistory(&config.Targets[targetIndex], append(
 				[]BackupRecord{record},
 				config.Targets[targetIndex].Backups...,
-			)
+			), record.Part)
+		}
+	}
+}
 
-			// Ensure we have a valid maxBackups value
-			maxBackups := config.Targets[targetIndex].MaxBackups
-			if maxBackups <= 0 {
-				maxBackups = 7 // Default value
-				config.Targets[targetIndex].MaxBackups = maxBackups
-			}
+// InsertBackupRecord adds a backup made earlier to the history of a directory target, in the
+// order the backups were created, e.g. one copied over from a fallback target. Returns false,
+// leaving the history unchanged, if KeepsBackupRecord does.
+func InsertBackupRecord(config *BackupConfig, targetPath string, record BackupRecord) bool {
+	i, backups, ok := config.historyWith(targetPath, record)
+	if ok {
+		config.Targets[i].Backups = backups
+	}
+	return ok
+}
 
-			// Trim the list to match the maxBackups value if needed
-			if len(config.Targets[targetIndex].Backups) > maxBackups {
-				config.Targets[targetIndex].Backups = config.Targets[targetIndex].Backups[:maxBackups]
+// KeepsBackupRecord reports whether the history of a directory target would keep a backup made
+// earlier, rather than drop it again for being older than the maxBackups backups it keeps
+func KeepsBackupRecord(config *BackupConfig, targetPath string, record BackupRecord) bool {
+	_, _, ok := config.historyWith(targetPath, record)
+	return ok
+}
+
+// historyWith returns the index of the directory target and its history with the record inserted
+// by creation time and trimmed. ok is false if the target is not found or the record was trimmed.
+func (c *BackupConfig) historyWith(targetPath string, record BackupRecord) (int, []BackupRecord, bool) {
+	for i, target := range c.Targets {
+		if !target.hasDestination(targetPath) || target.IsFileTarget() {
+			continue
+		}
+		// The history is kept newest first
+		position := len(target.Backups)
+		for j, backup := range target.Backups {
+			if backup.CreatedAt.Before(record.CreatedAt) {
+				position = j
+				break
+			}
+		}
+		backups := append(append(append([]BackupRecord{}, target.Backups[:position]...), record), target.Backups[position:]...)
+		backups = c.trimHistory(&c.Targets[i], backups, record.Part)
+		for _, backup := range backups {
+			if backup.Filename == record.Filename {
+				return i, backups, true
 			}
 		}
+		return i, nil, false
+	}
+	return -1, nil, false
+}
+
+// trimHistory trims the history of a target, newest first, to its maxBackups records. Tagged backups
+// kept by rotation stay in the history and, like in rotation, do not count towards the limit.
+// Each part of a split backup keeps its own maxBackups records, so only those of part are trimmed.
+func (c *BackupConfig) trimHistory(target *BackupTarget, history []BackupRecord, part string) []BackupRecord {
+	// Ensure we have a valid maxBackups value
+	if target.MaxBackups <= 0 {
+		target.MaxBackups = 7 // Default value
+	}
+
+	keepTagged := c.Rotation != nil && c.Rotation.KeepTagged
+	backups := []BackupRecord{}
+	untagged := 0
+	for _, backup := range history {
+		if backup.Part != part || (keepTagged && len(backup.Tags) > 0) {
+			backups = append(backups, backup)
+		} else if untagged < target.MaxBackups {
+			backups = append(backups, backup)
+			untagged++
+		}
 	}
+	return backups
 }
 
 // EnableEncryption sets up GPG encryption in the config file
@@ -210,10 +843,10 @@ func DisableEncryption(config *BackupConfig) bool {
 
 // ValidateGPGReceiver checks if the specified GPG recipient exists in the keyring
 func ValidateGPGReceiver(recipient string) (bool, string, error) {
-	cmd := exec.Command("gpg", "--list-keys", recipient)
-	output, err := cmd.CombinedOutput()
+	output, err := command.Run(command.Options{Timeout: command.DefaultTimeout}, "gpg", "--list-keys", recipient)
 	if err != nil {
-		if strings.Contains(string(output), "No public key") {
+		var failure *command.Error
+		if errors.As(err, &failure) && strings.Contains(failure.Stderr, "No public key") {
 			return false, "", nil
 		}
 		return false, "", fmt.Errorf("error checking GPG key: %w", err)
@@ -225,7 +858,7 @@ func ValidateGPGReceiver(recipient string) (bool, string, error) {
 func DeleteTarget(config *BackupConfig, targetPath string) bool {
 	idx := -1
 	for i, t := range config.Targets {
-		if t.GetDestination() == targetPath {
+		if t.hasDestination(targetPath) {
 			idx = i
 			break
 		}
@@ -237,10 +870,20 @@ func DeleteTarget(config *BackupConfig, targetPath string) bool {
 	return true
 }
 
+// Now returns the current time in the config's timezone, for the times recorded in the config.
+// An invalid timezone falls back to local time; run reports it before anything is recorded.
+func (c *BackupConfig) Now() time.Time {
+	naming, err := c.Options.Naming()
+	if err != nil {
+		return time.Now()
+	}
+	return naming.Now()
+}
+
 // AddTarget adds a new backup target to the config if it does not already exist.
 func AddTarget(config *BackupConfig, target BackupTarget) bool {
 	for _, t := range config.Targets {
-		if t.GetDestination() == target.GetDestination() {
+		if t.hasDestination(target.GetDestination()) {
 			return false // Already exists
 		}
 	}
@@ -248,12 +891,34 @@ func AddTarget(config *BackupConfig, target BackupTarget) bool {
 	return true
 }
 
+// MarkTargetsSkipped records a skipped run with the given reason for every target
+func MarkTargetsSkipped(config *BackupConfig, reason string) {
+	markTargets(config, StatusSkipped, reason)
+}
+
+// MarkTargetsDeferred records a deferred run with the given reason for every target
+func MarkTargetsDeferred(config *BackupConfig, reason string) {
+	markTargets(config, StatusDeferred, reason)
+}
+
+// markTargets records a run that did not back up with its status and reason for every target
+func markTargets(config *BackupConfig, status, reason string) {
+	now := config.Now()
+	for i := range config.Targets {
+		config.Targets[i].LastRun = &BackupStatus{
+			Timestamp: now,
+			Status:    status,
+			Message:   reason,
+		}
+	}
+}
+
 // UpdateTargetStatus updates the last run status for a specific target
 func UpdateTargetStatus(config *BackupConfig, targetPath string, status string, message string) {
 	for i, target := range config.Targets {
-		if target.GetDestination() == targetPath {
+		if target.hasDestination(targetPath) {
 			config.Targets[i].LastRun = &BackupStatus{
-				Timestamp: time.Now(),
+				Timestamp: config.Now(),
 				Status:    status,
 				Message:   message,
 			}
@@ -261,101 +926,3 @@ func UpdateTargetStatus(config *BackupConfig, targetPath string, status string,
 		}
 	}
 }
-
-// UpdateGlobalRegistry updates the global ~/.backup.yaml file to track backup locations
-// If the file doesn't exist, this function returns nil without creating it
-func UpdateGlobalRegistry(localConfigDir string) error {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	globalConfigPath := filepath.Join(homeDir, ".backup.yaml")
-
-	// Check if global config exists
-	if _, err := os.Stat(globalConfigPath); os.IsNotExist(err) {
-		// Global config doesn't exist, silently return
-		return nil
-	}
-
-	// Read existing global config
-	data, err := os.ReadFile(globalConfigPath)
-	if err != nil {
-		return fmt.Errorf("failed to read global config: %w", err)
-	}
-
-	var registry GlobalBackupRegistry
-	if err := yaml.Unmarshal(data, &registry); err != nil {
-		return fmt.Errorf("failed to parse global config: %w", err)
-	}
-
-	// Get absolute path of the local config directory
-	absPath, err := filepath.Abs(localConfigDir)
-	if err != nil {
-		return fmt.Errorf("failed to get absolute path: %w", err)
-	}
-
-	// Update or add entry for this backup location
-	now := time.Now()
-	found := false
-	for i := range registry.Backups {
-		if registry.Backups[i].Location == absPath {
-			registry.Backups[i].RunAt = now
-			found = true
-			break
-		}
-	}
-
-	if !found {
-		// Add new entry
-		registry.Backups = append(registry.Backups, GlobalBackupEntry{
-			Location: absPath,
-			RunAt:    now,
-		})
-	}
-
-	// Write updated config
-	updatedData, err := yaml.Marshal(&registry)
-	if err != nil {
-		return fmt.Errorf("failed to marshal global config: %w", err)
-	}
-
-	// Add header comment
-	header := "# Global backup registry\n# Tracks all backup locations and their last run times\n"
-	finalData := []byte(header)
-	finalData = append(finalData, updatedData...)
-
-	if err := os.WriteFile(globalConfigPath, finalData, 0644); err != nil {
-		return fmt.Errorf("failed to write global config: %w", err)
-	}
-
-	return nil
-}
-
-// ReadGlobalRegistry reads the global backup registry from ~/.backup.yaml
-func ReadGlobalRegistry() (*GlobalBackupRegistry, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	globalConfigPath := filepath.Join(homeDir, ".backup.yaml")
-
-	// Check if global config exists
-	if _, err := os.Stat(globalConfigPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("global config file ~/.backup.yaml does not exist")
-	}
-
-	// Read global config
-	data, err := os.ReadFile(globalConfigPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read global config: %w", err)
-	}
-
-	var registry GlobalBackupRegistry
-	if err := yaml.Unmarshal(data, &registry); err != nil {
-		return nil, fmt.Errorf("failed to parse global config: %w", err)
-	}
-
-	return &registry, nil
-}
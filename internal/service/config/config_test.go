@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"time"
 
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
 	. "github.com/kennycyb/go-backup/internal/service/config"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -80,6 +81,53 @@ var _ = Describe("Config", func() {
 			Expect(config.Targets[0].Backups[1].Filename).To(Equal("test-backup-20230101.tar.gz"))
 		})
 
+		It("should keep tagged backups beyond the limit with rotation.keepTagged", func() {
+			config := &BackupConfig{
+				Rotation: &RotationConfig{KeepTagged: true},
+				Targets: []BackupTarget{
+					{
+						Path:       "/backup/path",
+						MaxBackups: 1,
+						Backups: []BackupRecord{
+							{Filename: "test-backup-20230102.tar.gz"},
+							{Filename: "test-backup-20230101.tar.gz", Tags: []string{"pre-release"}},
+						},
+					},
+				},
+			}
+
+			AddBackupRecord(config, "/backup/path", BackupRecord{Filename: "test-backup-20230103.tar.gz"})
+
+			Expect(config.Targets[0].Backups).To(HaveLen(2))
+			Expect(config.Targets[0].Backups[0].Filename).To(Equal("test-backup-20230103.tar.gz"))
+			Expect(config.Targets[0].Backups[1].Filename).To(Equal("test-backup-20230101.tar.gz"))
+			Expect(TaggedBackups(config.Targets[0].Backups)).To(Equal(map[string]bool{"test-backup-20230101.tar.gz": true}))
+		})
+
+		It("should limit the history of each part of a split backup separately", func() {
+			config := &BackupConfig{
+				Targets: []BackupTarget{
+					{
+						Path:       "/backup/path",
+						MaxBackups: 1,
+						Backups: []BackupRecord{
+							{Filename: "src.clientA-20230101.tar.gz", Part: "clientA"},
+							{Filename: "src.clientB-20230101.tar.gz", Part: "clientB"},
+							{Filename: "src-20230101.tar.gz"},
+						},
+					},
+				},
+			}
+
+			AddBackupRecord(config, "/backup/path", BackupRecord{Filename: "src.clientA-20230102.tar.gz", Part: "clientA"})
+
+			var names []string
+			for _, backup := range config.Targets[0].Backups {
+				names = append(names, backup.Filename)
+			}
+			Expect(names).To(Equal([]string{"src.clientA-20230102.tar.gz", "src.clientB-20230101.tar.gz", "src-20230101.tar.gz"}))
+		})
+
 		It("should do nothing when target is not found", func() {
 			// Create a config with a target
 			config := &BackupConfig{
@@ -144,6 +192,259 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	Describe("InsertBackupRecord", func() {
+		var config *BackupConfig
+
+		BeforeEach(func() {
+			config = &BackupConfig{
+				Targets: []BackupTarget{
+					{
+						Path:       "/backup/nas",
+						MaxBackups: 2,
+						Backups: []BackupRecord{
+							{Filename: "src-20230103.tar.gz", CreatedAt: time.Date(2023, 1, 3, 12, 0, 0, 0, time.UTC)},
+							{Filename: "src-20230101.tar.gz", CreatedAt: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)},
+						},
+					},
+				},
+			}
+		})
+
+		It("should insert an earlier backup by creation time", func() {
+			record := BackupRecord{Filename: "src-20230102.tar.gz", CreatedAt: time.Date(2023, 1, 2, 12, 0, 0, 0, time.UTC)}
+			Expect(KeepsBackupRecord(config, "/backup/nas", record)).To(BeTrue())
+
+			Expect(InsertBackupRecord(config, "/backup/nas", record)).To(BeTrue())
+			Expect(config.Targets[0].Backups).To(HaveLen(2))
+			Expect(config.Targets[0].Backups[0].Filename).To(Equal("src-20230103.tar.gz"))
+			Expect(config.Targets[0].Backups[1].Filename).To(Equal("src-20230102.tar.gz"))
+		})
+
+		It("should leave out a backup older than those the target keeps", func() {
+			record := BackupRecord{Filename: "src-20221231.tar.gz", CreatedAt: time.Date(2022, 12, 31, 12, 0, 0, 0, time.UTC)}
+			Expect(KeepsBackupRecord(config, "/backup/nas", record)).To(BeFalse())
+
+			Expect(InsertBackupRecord(config, "/backup/nas", record)).To(BeFalse())
+			Expect(config.Targets[0].Backups).To(HaveLen(2))
+			Expect(config.Targets[0].Backups[1].Filename).To(Equal("src-20230101.tar.gz"))
+		})
+
+		It("should not insert into an unknown target", func() {
+			record := BackupRecord{Filename: "src-20230102.tar.gz", CreatedAt: time.Date(2023, 1, 2, 12, 0, 0, 0, time.UTC)}
+			Expect(InsertBackupRecord(config, "/backup/usb", record)).To(BeFalse())
+		})
+	})
+
+	Describe("ValidateTags", func() {
+		It("should accept simple labels", func() {
+			Expect(ValidateTags([]string{"pre-release", "v2.3"})).To(Succeed())
+		})
+
+		It("should reject empty tags and tags with spaces or commas", func() {
+			Expect(ValidateTags([]string{""})).NotTo(Succeed())
+			Expect(ValidateTags([]string{"pre release"})).NotTo(Succeed())
+			Expect(ValidateTags([]string{"a,b"})).NotTo(Succeed())
+		})
+	})
+
+	Describe("MarkTargetsSkipped", func() {
+		It("should record a skipped run with the reason for every target", func() {
+			config := &BackupConfig{
+				Targets: []BackupTarget{
+					{Path: "/backup/one", LastRun: &BackupStatus{Status: StatusFailure, Message: "disk full"}},
+					{File: "/backup/two.tar.gz"},
+				},
+			}
+
+			MarkTargetsSkipped(config, "no changes")
+
+			for _, target := range config.Targets {
+				Expect(target.LastRun).NotTo(BeNil())
+				Expect(target.LastRun.Status).To(Equal(StatusSkipped))
+				Expect(target.LastRun.Message).To(Equal("no changes"))
+				Expect(target.LastRun.Timestamp).NotTo(BeZero())
+			}
+		})
+	})
+
+	Describe("MarkTargetsDeferred", func() {
+		It("should record a deferred run with the reason for every target", func() {
+			config := &BackupConfig{Targets: []BackupTarget{{Path: "/backup/one"}, {Path: "/backup/two"}}}
+
+			MarkTargetsDeferred(config, "on a metered connection")
+
+			for _, target := range config.Targets {
+				Expect(target.LastRun.Status).To(Equal(StatusDeferred))
+				Expect(target.LastRun.Message).To(Equal("on a metered connection"))
+			}
+		})
+	})
+
+	Describe("PowerPolicy", func() {
+		It("should return the configured policy", func() {
+			policy, err := (&Options{RequireAC: true, MinBattery: 50, SkipOnMetered: true}).PowerPolicy()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy.RequireAC).To(BeTrue())
+			Expect(policy.MinBattery).To(Equal(50))
+			Expect(policy.SkipOnMetered).To(BeTrue())
+		})
+
+		It("should defer nothing without options", func() {
+			var options *Options
+			policy, err := options.PowerPolicy()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy.Enabled()).To(BeFalse())
+		})
+
+		It("should reject a minBattery that is not a percentage", func() {
+			_, err := (&Options{RequireAC: true, MinBattery: 120}).PowerPolicy()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("RunTimeout", func() {
+		It("should parse the configured timeout", func() {
+			timeout, err := (&Options{Timeout: "2h"}).RunTimeout()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(timeout).To(Equal(2 * time.Hour))
+		})
+
+		It("should return no limit without a timeout", func() {
+			var options *Options
+			Expect(options.RunTimeout()).To(BeZero())
+			Expect((&Options{}).RunTimeout()).To(BeZero())
+		})
+
+		It("should reject invalid timeouts", func() {
+			_, err := (&Options{Timeout: "soon"}).RunTimeout()
+			Expect(err).To(HaveOccurred())
+			_, err = (&Options{Timeout: "-1h"}).RunTimeout()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("CompressionLevel", func() {
+		It("should return the configured level", func() {
+			level, err := (&Options{Compression: &CompressionConfig{Level: 9}}).CompressionLevel()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(level).To(Equal(9))
+		})
+
+		It("should return the default level without a compression section", func() {
+			var options *Options
+			Expect(options.CompressionLevel()).To(BeZero())
+			Expect((&Options{}).CompressionLevel()).To(BeZero())
+		})
+
+		It("should reject levels gzip does not have", func() {
+			_, err := (&Options{Compression: &CompressionConfig{Level: 12}}).CompressionLevel()
+			Expect(err).To(HaveOccurred())
+			_, err = (&Options{Compression: &CompressionConfig{Level: -1}}).CompressionLevel()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ArchiveFormat", func() {
+		It("should return the configured format, or tar.gz by default", func() {
+			var options *Options
+			Expect(options.ArchiveFormat()).To(BeEmpty())
+			Expect((&Options{Compression: &CompressionConfig{Format: "zip"}}).ArchiveFormat()).To(Equal("zip"))
+		})
+
+		It("should reject formats it cannot write", func() {
+			_, err := (&Options{Compression: &CompressionConfig{Format: "7z"}}).ArchiveFormat()
+			Expect(err).To(MatchError(ContainSubstring("use tar.gz, tar or zip")))
+		})
+	})
+
+	Describe("Naming", func() {
+		It("should use the default naming without options", func() {
+			var options *Options
+			naming, err := options.Naming()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(naming.Format).To(Equal(backupService.DefaultTimestampFormat))
+		})
+
+		It("should use the configured layout and time zone", func() {
+			naming, err := (&Options{TimestampFormat: "2006-01-02_150405", Timezone: "UTC"}).Naming()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(naming.Format).To(Equal("2006-01-02_150405"))
+			Expect(naming.Location).To(Equal(time.UTC))
+		})
+
+		It("should reject an invalid time zone", func() {
+			_, err := (&Options{Timezone: "Nowhere"}).Naming()
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should record times in the configured time zone", func() {
+			config := &BackupConfig{Options: &Options{Timezone: "UTC"}}
+			Expect(config.Now().Location()).To(Equal(time.UTC))
+		})
+	})
+
+	Describe("RehearsalDue", func() {
+		now := time.Date(2025, 5, 20, 12, 0, 0, 0, time.UTC)
+
+		It("should never be due without a rehearsal config", func() {
+			Expect((&BackupConfig{}).RehearsalDue(now)).To(BeFalse())
+		})
+
+		It("should be due without a previous rehearsal", func() {
+			config := &BackupConfig{Rehearsal: &RehearsalConfig{Every: "30d"}}
+			Expect(config.RehearsalDue(now)).To(BeTrue())
+		})
+
+		It("should be due once the interval has passed", func() {
+			config := &BackupConfig{
+				Rehearsal:     &RehearsalConfig{Every: "30d"},
+				LastRehearsal: &BackupStatus{Timestamp: now.Add(-29 * 24 * time.Hour), Status: StatusSuccess},
+			}
+			Expect(config.RehearsalDue(now)).To(BeFalse())
+			Expect(config.RehearsalDue(now.Add(24 * time.Hour))).To(BeTrue())
+		})
+
+		It("should reject invalid intervals", func() {
+			_, err := (&BackupConfig{Rehearsal: &RehearsalConfig{Every: "sometimes"}}).RehearsalDue(now)
+			Expect(err).To(HaveOccurred())
+			_, err = (&BackupConfig{Rehearsal: &RehearsalConfig{}}).RehearsalDue(now)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should restore one backup by default", func() {
+			Expect((&RehearsalConfig{Every: "weekly"}).SampleSize()).To(Equal(1))
+			Expect((&RehearsalConfig{Every: "weekly", Sample: 3}).SampleSize()).To(Equal(3))
+		})
+	})
+
+	Describe("SkipUnreadable", func() {
+		It("should skip unreadable files by default", func() {
+			var options *Options
+			Expect(options.SkipUnreadable()).To(BeTrue())
+			Expect((&Options{OnError: "skip"}).SkipUnreadable()).To(BeTrue())
+		})
+
+		It("should fail on unreadable files with onError fail", func() {
+			Expect((&Options{OnError: "fail"}).SkipUnreadable()).To(BeFalse())
+		})
+
+		It("should reject unknown values", func() {
+			_, err := (&Options{OnError: "ignore"}).SkipUnreadable()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ExcludesHidden", func() {
+		It("should include hidden files unless includeHidden is false", func() {
+			var options *Options
+			Expect(options.ExcludesHidden()).To(BeFalse())
+			Expect((&Options{}).ExcludesHidden()).To(BeFalse())
+			included, excluded := true, false
+			Expect((&Options{IncludeHidden: &included}).ExcludesHidden()).To(BeFalse())
+			Expect((&Options{IncludeHidden: &excluded}).ExcludesHidden()).To(BeTrue())
+		})
+	})
+
 	Describe("AddTarget", func() {
 		It("should add a new target if it does not exist", func() {
 			cfg := &BackupConfig{}
@@ -433,11 +734,183 @@ target:
 				Expect(target.GetDestination()).To(Equal("/path/to/backup/dir"))
 			})
 
-			It("should panic when both path and file are empty (invalid target)", func() {
-				target := BackupTarget{}
-				Expect(func() { target.GetDestination() }).To(Panic())
+			It("should return an empty destination when both path and file are empty (invalid target)", func() {
+				Expect(BackupTarget{}.GetDestination()).To(BeEmpty())
+				Expect(BackupTarget{Type: TargetTypeExec, Command: "blobctl"}.GetDestination()).To(BeEmpty())
 			})
 		})
+
+		Describe("GetCompressionLevel", func() {
+			It("should return 0 when no compression override is set", func() {
+				Expect(BackupTarget{Path: "/backups"}.GetCompressionLevel()).To(Equal(0))
+			})
+
+			It("should return the configured level", func() {
+				target := BackupTarget{Path: "/backups", Compression: &CompressionConfig{Level: 9}}
+				Expect(target.GetCompressionLevel()).To(Equal(9))
+			})
+		})
+
+		Describe("ChunkSize", func() {
+			It("should return 0 without chunks", func() {
+				Expect(BackupTarget{Path: "s3://bucket/backups"}.ChunkSize()).To(BeZero())
+			})
+
+			It("should parse the chunk size of a remote directory target", func() {
+				Expect(BackupTarget{Path: "s3://bucket/backups", Chunks: "64M"}.ChunkSize()).To(Equal(int64(64 << 20)))
+			})
+
+			It("should reject chunks below the minimum", func() {
+				_, err := BackupTarget{Path: "s3://bucket/backups", Chunks: "512K"}.ChunkSize()
+				Expect(err).To(MatchError(ContainSubstring("at least 1M")))
+			})
+
+			It("should reject chunks for local and file targets", func() {
+				_, err := BackupTarget{Path: "/backups", Chunks: "64M"}.ChunkSize()
+				Expect(err).To(MatchError(ContainSubstring("only supported for remote directory targets")))
+				_, err = BackupTarget{File: "s3://bucket/latest.tar.gz", Chunks: "64M"}.ChunkSize()
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Describe("EffectiveEncryption", func() {
+			defaultEncryption := &EncryptionConfig{Method: "gpg", Receiver: "user@example.com"}
+
+			It("should use the default encryption without an override", func() {
+				target := BackupTarget{Path: "/backups"}
+				Expect(target.EffectiveEncryption(defaultEncryption)).To(Equal(defaultEncryption))
+				Expect(target.EffectiveEncryption(nil)).To(BeNil())
+			})
+
+			It("should disable encryption when the override method is none", func() {
+				target := BackupTarget{Path: "/backups", Encryption: &EncryptionConfig{Method: "none"}}
+				Expect(target.EffectiveEncryption(defaultEncryption)).To(BeNil())
+			})
+
+			It("should enable encryption for a single target", func() {
+				target := BackupTarget{Path: "/cloud", Encryption: &EncryptionConfig{Method: "gpg", Receiver: "cloud@example.com"}}
+				encryption := target.EffectiveEncryption(nil)
+				Expect(encryption).NotTo(BeNil())
+				Expect(encryption.Receiver).To(Equal("cloud@example.com"))
+			})
+
+			It("should inherit the default receiver when the override has none", func() {
+				target := BackupTarget{Path: "/cloud", Encryption: &EncryptionConfig{Method: "gpg"}}
+				encryption := target.EffectiveEncryption(defaultEncryption)
+				Expect(encryption.Receiver).To(Equal("user@example.com"))
+				Expect(target.Encryption.Receiver).To(BeEmpty())
+			})
+		})
+
+		Describe("Encryption shorthands", func() {
+			readTargets := func(content string) []BackupTarget {
+				path := filepath.Join(GinkgoT().TempDir(), ".backup.yaml")
+				Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+				cfg, err := ReadBackupConfig(path)
+				Expect(err).NotTo(HaveOccurred())
+				return cfg.Targets
+			}
+
+			It("should read encryption: false and true per target", func() {
+				targets := readTargets(`
+encryption:
+  method: gpg
+  receiver: user@example.com
+target:
+  - path: /mnt/nas
+    encryption: false
+  - path: /mnt/cloud
+    encryption: true
+`)
+				Expect(targets[0].Encryption).To(Equal(&EncryptionConfig{Method: "none"}))
+				Expect(targets[0].EffectiveEncryption(&EncryptionConfig{Method: "gpg", Receiver: "user@example.com"})).To(BeNil())
+				encryption := targets[1].EffectiveEncryption(&EncryptionConfig{Method: "gpg", Receiver: "user@example.com"})
+				Expect(encryption).NotTo(BeNil())
+				Expect(encryption.Receiver).To(Equal("user@example.com"))
+			})
+
+			It("should encrypt with GPG for a receiver without a method", func() {
+				targets := readTargets(`
+target:
+  - path: /mnt/cloud
+    encryption:
+      receiver: cloud@example.com
+`)
+				Expect(targets[0].Encryption).To(Equal(&EncryptionConfig{Method: "gpg", Receiver: "cloud@example.com"}))
+			})
+
+			It("should reject other scalars", func() {
+				path := filepath.Join(GinkgoT().TempDir(), ".backup.yaml")
+				Expect(os.WriteFile(path, []byte("target:\n  - path: /mnt/nas\n    encryption: maybe\n"), 0644)).To(Succeed())
+				_, err := ReadBackupConfig(path)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("EncryptionRecipients", func() {
+		It("should return the distinct recipients of the config and its targets", func() {
+			cfg := &BackupConfig{
+				Encryption: &EncryptionConfig{Method: "gpg", Receiver: "user@example.com"},
+				Targets: []BackupTarget{
+					{Path: "/local", Encryption: &EncryptionConfig{Method: "none"}},
+					{Path: "/nas"},
+					{Path: "/cloud", Encryption: &EncryptionConfig{Method: "gpg", Receiver: "cloud@example.com"}},
+				},
+			}
+			recipients, err := EncryptionRecipients(cfg)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recipients).To(Equal([]string{"user@example.com", "cloud@example.com"}))
+		})
+
+		It("should return no recipients without encryption", func() {
+			recipients, err := EncryptionRecipients(&BackupConfig{Targets: []BackupTarget{{Path: "/local"}}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recipients).To(BeEmpty())
+		})
+
+		It("should reject unsupported encryption methods", func() {
+			cfg := &BackupConfig{Targets: []BackupTarget{{Path: "/cloud", Encryption: &EncryptionConfig{Method: "age"}}}}
+			_, err := EncryptionRecipients(cfg)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unsupported encryption method"))
+		})
+
+		It("should reject a method in the wrong case, as config validate reports it", func() {
+			configPath := filepath.Join(GinkgoT().TempDir(), ".backup.yaml")
+			data := "target:\n  - path: /backups\nencryption:\n  method: GPG\n  receiver: user@example.com\n"
+			Expect(os.WriteFile(configPath, []byte(data), 0644)).To(Succeed())
+
+			cfg, err := ValidateConfigFile(configPath)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = EncryptionRecipients(cfg)
+			Expect(err).To(MatchError(ContainSubstring(`unsupported encryption method "GPG"`)))
+		})
+	})
+
+	Describe("CheckEncryptionReceivers", func() {
+		It("should accept encryption with a receiver to inherit", func() {
+			cfg := &BackupConfig{
+				Encryption: &EncryptionConfig{Method: "gpg", Receiver: "user@example.com"},
+				Targets:    []BackupTarget{{Path: "/nas", Encryption: &EncryptionConfig{Method: "gpg"}}},
+			}
+			Expect(CheckEncryptionReceivers(cfg)).To(Succeed())
+		})
+
+		It("should report encryption: true at the top level", func() {
+			configPath := filepath.Join(GinkgoT().TempDir(), ".backup.yaml")
+			data := "target:\n  - path: /backups\nencryption: true\n"
+			Expect(os.WriteFile(configPath, []byte(data), 0644)).To(Succeed())
+
+			cfg, err := ValidateConfigFile(configPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(CheckEncryptionReceivers(cfg)).To(MatchError(ContainSubstring("without a receiver")))
+		})
+
+		It("should report a target encrypted without a top-level receiver", func() {
+			cfg := &BackupConfig{Targets: []BackupTarget{{Path: "/nas", Encryption: &EncryptionConfig{Method: "gpg"}}}}
+			Expect(CheckEncryptionReceivers(cfg)).To(MatchError(ContainSubstring("target /nas")))
+		})
 	})
 
 	Describe("Options", func() {
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// discoverSkipDirs are directories never searched for projects
+var discoverSkipDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+}
+
+// DiscoverConfigDirs walks root and returns the absolute paths of all directories
+// containing a .backup.yaml file, in walk order. Hidden directories, dependency
+// directories and directories that cannot be read are skipped, as is the home
+// directory, whose .backup.yaml is the global registry.
+func DiscoverConfigDirs(root string) ([]string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	if info, err := os.Stat(absRoot); err != nil {
+		return nil, fmt.Errorf("failed to access %s: %w", absRoot, err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", absRoot)
+	}
+
+	// Without a home directory there is no registry to skip
+	globalConfigPath, _ := GlobalRegistryPath()
+
+	dirs := []string{}
+	err = filepath.WalkDir(absRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories are skipped rather than aborting the walk
+			if d != nil && d.IsDir() && path != absRoot {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != absRoot && (strings.HasPrefix(d.Name(), ".") || discoverSkipDirs[d.Name()]) {
+			return filepath.SkipDir
+		}
+		configPath := filepath.Join(path, ".backup.yaml")
+		if configPath == globalConfigPath {
+			return nil
+		}
+		if info, err := os.Stat(configPath); err == nil && info.Mode().IsRegular() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s: %w", absRoot, err)
+	}
+	return dirs, nil
+}
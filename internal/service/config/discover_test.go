@@ -0,0 +1,72 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kennycyb/go-backup/internal/service/config"
+)
+
+var _ = Describe("DiscoverConfigDirs", func() {
+	var root string
+
+	writeConfig := func(dir string) {
+		Expect(os.MkdirAll(filepath.Join(root, dir), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, dir, ".backup.yaml"), []byte("target: []\n"), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		root = GinkgoT().TempDir()
+	})
+
+	It("should find every directory with a .backup.yaml file", func() {
+		writeConfig("alpha")
+		writeConfig("group/beta")
+		writeConfig("group/beta/nested")
+		Expect(os.MkdirAll(filepath.Join(root, "empty"), 0755)).To(Succeed())
+
+		dirs, err := config.DiscoverConfigDirs(root)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dirs).To(Equal([]string{
+			filepath.Join(root, "alpha"),
+			filepath.Join(root, "group/beta"),
+			filepath.Join(root, "group/beta/nested"),
+		}))
+	})
+
+	It("should include the root itself", func() {
+		writeConfig(".")
+
+		dirs, err := config.DiscoverConfigDirs(root)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dirs).To(Equal([]string{root}))
+	})
+
+	It("should skip hidden and dependency directories", func() {
+		writeConfig(".cache/project")
+		writeConfig("app/node_modules/pkg")
+		writeConfig("app/vendor/lib")
+
+		dirs, err := config.DiscoverConfigDirs(root)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dirs).To(BeEmpty())
+	})
+
+	It("should skip the global registry in the home directory", func() {
+		GinkgoT().Setenv("HOME", root)
+		writeConfig(".")
+		writeConfig("project")
+
+		dirs, err := config.DiscoverConfigDirs(root)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dirs).To(Equal([]string{filepath.Join(root, "project")}))
+	})
+
+	It("should fail when the root is not a directory", func() {
+		_, err := config.DiscoverConfigDirs(filepath.Join(root, "missing"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,514 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GlobalBackupEntry represents a single backup location tracked in the global registry
+type GlobalBackupEntry struct {
+	Location string    `yaml:"location"`         // Full path to the directory containing .backup.yaml
+	RunAt    time.Time `yaml:"run_at,omitempty"` // Last run timestamp; zero if registered but never run
+
+	// Overrides applied by run-all, so projects can be tuned without touching their config
+	Enabled  *bool    `yaml:"enabled,omitempty"`  // Set to false to pause the location; nil means enabled
+	Profile  string   `yaml:"profile,omitempty"`  // Use .backup.<profile>.yaml instead of .backup.yaml
+	Flags    []string `yaml:"flags,omitempty"`    // Extra flags passed to the run command
+	Schedule string   `yaml:"schedule,omitempty"` // Minimum time between runs: hourly, daily, weekly, monthly or a duration like 12h
+	Priority int      `yaml:"priority,omitempty"` // Locations with a higher priority run first; equal priorities form a group
+	Blackout []string `yaml:"blackout,omitempty"` // Times no backup of the location is started, added to the registry's
+}
+
+// IsEnabled reports whether run-all should back up this location
+func (e GlobalBackupEntry) IsEnabled() bool {
+	return e.Enabled == nil || *e.Enabled
+}
+
+// ConfigPath returns the config file run-all uses for this location
+func (e GlobalBackupEntry) ConfigPath() string {
+	if e.Profile == "" {
+		return filepath.Join(e.Location, ".backup.yaml")
+	}
+	return filepath.Join(e.Location, ".backup."+e.Profile+".yaml")
+}
+
+// ScheduleInterval returns the minimum time between runs, or 0 if the entry has no schedule
+func (e GlobalBackupEntry) ScheduleInterval() (time.Duration, error) {
+	interval, err := parseInterval(e.Schedule)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schedule %q: use hourly, daily, weekly, monthly or a duration like 12h or 7d", e.Schedule)
+	}
+	return interval, nil
+}
+
+// parseInterval parses hourly, daily, weekly, monthly, a number of days like 30d or a duration
+// like 12h. An empty interval is 0.
+func parseInterval(value string) (time.Duration, error) {
+	switch strings.ToLower(value) {
+	case "":
+		return 0, nil
+	case "hourly":
+		return time.Hour, nil
+	case "daily":
+		return 24 * time.Hour, nil
+	case "weekly":
+		return 7 * 24 * time.Hour, nil
+	case "monthly":
+		return 30 * 24 * time.Hour, nil
+	}
+
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid number of days %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return interval, nil
+}
+
+// IsDue reports whether the entry's schedule allows a run at the given time.
+// Entries without a schedule or that never ran are always due.
+func (e GlobalBackupEntry) IsDue(now time.Time) (bool, error) {
+	interval, err := e.ScheduleInterval()
+	if err != nil {
+		return false, err
+	}
+	if interval == 0 || e.RunAt.IsZero() {
+		return true, nil
+	}
+	return !now.Before(e.RunAt.Add(interval)), nil
+}
+
+// GlobalBackupRegistry represents the structure of the global registry (see GlobalRegistryPath)
+type GlobalBackupRegistry struct {
+	Default struct {
+		Encryption *EncryptionConfig `yaml:"encryption,omitempty"`
+	} `yaml:"default,omitempty"`
+	Backups     []GlobalBackupEntry `yaml:"backups,omitempty"`
+	UpdateCheck *bool               `yaml:"updateCheck,omitempty"` // Set to false to stop checking for newer releases; nil means enabled
+
+	Aliases        map[string]string `yaml:"aliases,omitempty"`        // Commands run by name, e.g. nightly: run --tag nightly (see Alias)
+	DefaultCommand string            `yaml:"defaultCommand,omitempty"` // Run by a plain 'go-backup' in a directory with a .backup.yaml, e.g. "run"
+	Language       string            `yaml:"language,omitempty"`       // Language of the messages, e.g. de; defaults to the locale (LC_ALL, LC_MESSAGES, LANG)
+	Notify         *NotifyConfig     `yaml:"notify,omitempty"`         // Where run-all sends a summary of each run
+
+	// Keep scheduled runs out of the way: no backups start in a blackout window (see ParseBlackoutWindow),
+	// and run-all waits a random time of up to Jitter first, so machines sharing a target do not start together
+	Blackout []string `yaml:"blackout,omitempty"`
+	Jitter   string   `yaml:"jitter,omitempty"`
+}
+
+// NotifyConfig sets where run-all sends one summary of all locations after each run
+type NotifyConfig struct {
+	Webhook string `yaml:"webhook"`      // URL the summary is posted to as JSON
+	On      string `yaml:"on,omitempty"` // When to send it: always (default) or failure
+}
+
+// Values for NotifyConfig.On
+const (
+	NotifyAlways  = "always"  // Send a summary after every run
+	NotifyFailure = "failure" // Only send one when a location failed
+)
+
+// ShouldNotify reports whether a run that failed or not is summarised.
+// Returns an error for an unknown value of On.
+func (n *NotifyConfig) ShouldNotify(failed bool) (bool, error) {
+	if n == nil || n.Webhook == "" {
+		return false, nil
+	}
+	switch n.On {
+	case "", NotifyAlways:
+		return true, nil
+	case NotifyFailure:
+		return failed, nil
+	}
+	return false, fmt.Errorf("invalid notify.on %q: use %s or %s", n.On, NotifyAlways, NotifyFailure)
+}
+
+// UpdateCheckEnabled reports whether go-backup may check for newer releases.
+// Checking is enabled when the registry is missing or does not disable it.
+func (r *GlobalBackupRegistry) UpdateCheckEnabled() bool {
+	return r == nil || r.UpdateCheck == nil || *r.UpdateCheck
+}
+
+// OrderedBackups returns the entries in the order run-all backs them up: highest priority
+// first, and in registry order within a priority
+func (r *GlobalBackupRegistry) OrderedBackups() []GlobalBackupEntry {
+	ordered := append([]GlobalBackupEntry(nil), r.Backups...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority > ordered[j].Priority
+	})
+	return ordered
+}
+
+// GlobalRegistryPath returns the path of the global registry: registry.yaml in ConfigDir, or the
+// legacy ~/.backup.yaml while only that exists. The file may not exist; the registry is optional.
+func GlobalRegistryPath() (string, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(configDir, RegistryFileName)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	legacyPath, err := LegacyGlobalRegistryPath()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(legacyPath); err == nil {
+		return legacyPath, nil
+	}
+	return path, nil
+}
+
+// LegacyGlobalRegistryPath returns where the global registry was kept before it moved to
+// ConfigDir: ~/.backup.yaml
+func LegacyGlobalRegistryPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".backup.yaml"), nil
+}
+
+// errNoGlobalRegistry reports a missing global registry
+func errNoGlobalRegistry(globalConfigPath string) error {
+	return fmt.Errorf("global registry %s does not exist", globalConfigPath)
+}
+
+// Locking of the global registry, so concurrent runs (e.g. parallel run-all) do not lose each other's updates
+const (
+	registryLockTimeout = 30 * time.Second      // How long to wait for another process to finish its update
+	registryLockRetry   = 50 * time.Millisecond // How often to try the lock while waiting
+	registryMaxMerges   = 3                     // How often an update is applied again when the file changed meanwhile
+)
+
+// errRegistryChanged reports that the registry file was changed by another writer while being updated
+var errRegistryChanged = errors.New("global config was changed while updating it")
+
+// readGlobalRegistryFile reads and parses the global registry at the given path
+func readGlobalRegistryFile(globalConfigPath string) (*GlobalBackupRegistry, error) {
+	registry, _, err := readGlobalRegistryData(globalConfigPath)
+	return registry, err
+}
+
+// readGlobalRegistryData reads and parses the global registry, also returning the file's contents
+func readGlobalRegistryData(globalConfigPath string) (*GlobalBackupRegistry, []byte, error) {
+	data, err := os.ReadFile(globalConfigPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read global config: %w", err)
+	}
+
+	var registry GlobalBackupRegistry
+	if err := yaml.Unmarshal(data, &registry); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse global config: %w", err)
+	}
+
+	return &registry, data, nil
+}
+
+// writeGlobalRegistryFile writes the global registry to the given path with its header comment,
+// keeping the comments and layout of previous.
+// The file is replaced atomically, so readers never see a partly written registry; a symlinked
+// registry, e.g. from a dotfiles repository, is written through the link. The write fails with
+// errRegistryChanged if the file no longer holds previous, the contents the update started from.
+func writeGlobalRegistryFile(globalConfigPath string, registry *GlobalBackupRegistry, previous []byte) error {
+	updatedData, err := marshalPreserving(registry, previous, "# Global backup registry", "# Tracks all backup locations")
+	if err != nil {
+		return fmt.Errorf("failed to marshal global config: %w", err)
+	}
+
+	// Add header comment
+	header := "# Global backup registry\n# Tracks all backup locations and their last run times\n"
+	finalData := []byte(header)
+	finalData = append(finalData, updatedData...)
+
+	path := globalConfigPath
+	if target, err := filepath.EvalSymlinks(globalConfigPath); err == nil {
+		path = target
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write global config: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	_, err = tmpFile.Write(finalData)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, mode)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write global config: %w", err)
+	}
+
+	// Processes that do not take the lock, e.g. an editor or an older go-backup, may have written meanwhile
+	if current, err := os.ReadFile(path); err != nil || !bytes.Equal(current, previous) {
+		return errRegistryChanged
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write global config: %w", err)
+	}
+
+	return nil
+}
+
+// lockGlobalRegistry takes the lock of the global registry, registry.lock in StateDir, waiting up to
+// registryLockTimeout for another process to release it. Call the returned function to release the lock.
+func lockGlobalRegistry() (func(), error) {
+	stateDir, err := StateDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to lock global config: %w", err)
+	}
+	lockPath := filepath.Join(stateDir, "registry.lock")
+	deadline := time.Now().Add(registryLockTimeout)
+	for {
+		file, locked, err := tryLockFile(lockPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock global config: %w", err)
+		}
+		if locked {
+			return func() { unlockFile(file, lockPath) }, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, lockHeldError(lockPath)
+		}
+		time.Sleep(registryLockRetry)
+	}
+}
+
+// modifyGlobalRegistry applies an update to the global registry while holding its lock.
+// The update is made to the registry as currently on disk and returns false if nothing changed.
+// If another writer changes the file meanwhile, the update is applied again on top of its changes.
+func modifyGlobalRegistry(globalConfigPath string, update func(registry *GlobalBackupRegistry) bool) error {
+	unlock, err := lockGlobalRegistry()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for merges := 0; ; merges++ {
+		registry, data, err := readGlobalRegistryData(globalConfigPath)
+		if err != nil {
+			return err
+		}
+		if !update(registry) {
+			return nil
+		}
+		err = writeGlobalRegistryFile(globalConfigPath, registry, data)
+		if !errors.Is(err, errRegistryChanged) || merges == registryMaxMerges {
+			return err
+		}
+	}
+}
+
+// UpdateGlobalRegistry updates the global registry to track backup locations
+// If the file doesn't exist, this function returns nil without creating it
+func UpdateGlobalRegistry(localConfigDir string) error {
+	return updateGlobalRegistryEntry(localConfigDir, "", true)
+}
+
+// UpdateGlobalRegistryProfile is UpdateGlobalRegistry for a location backed up with the
+// .backup.<profile>.yaml config (or .backup.yaml for an empty profile), e.g. the home directory.
+// A new entry records the profile, so run-all uses the same config; existing entries keep theirs.
+func UpdateGlobalRegistryProfile(localConfigDir, profile string) error {
+	return updateGlobalRegistryEntry(localConfigDir, profile, true)
+}
+
+// RegisterGlobalLocation adds a backup location to the global registry
+// without recording a run. Existing entries are left untouched.
+// If the file doesn't exist, this function returns nil without creating it
+func RegisterGlobalLocation(localConfigDir string) error {
+	return updateGlobalRegistryEntry(localConfigDir, "", false)
+}
+
+// RegisterGlobalLocations adds backup locations to the global registry
+// without recording a run, and returns the locations that were not registered yet.
+// Unlike RegisterGlobalLocation, it fails if the registry does not exist.
+func RegisterGlobalLocations(localConfigDirs []string) ([]string, error) {
+	globalConfigPath, err := GlobalRegistryPath()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(globalConfigPath); os.IsNotExist(err) {
+		return nil, errNoGlobalRegistry(globalConfigPath)
+	}
+
+	absPaths := make([]string, 0, len(localConfigDirs))
+	for _, dir := range localConfigDirs {
+		absPath, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		absPaths = append(absPaths, absPath)
+	}
+
+	var added []string
+	err = modifyGlobalRegistry(globalConfigPath, func(registry *GlobalBackupRegistry) bool {
+		known := make(map[string]bool, len(registry.Backups))
+		for _, entry := range registry.Backups {
+			known[entry.Location] = true
+		}
+
+		added = []string{}
+		for _, absPath := range absPaths {
+			if known[absPath] {
+				continue
+			}
+			known[absPath] = true
+			registry.Backups = append(registry.Backups, GlobalBackupEntry{Location: absPath})
+			added = append(added, absPath)
+		}
+		return len(added) > 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// updateGlobalRegistryEntry adds or updates the registry entry for a location.
+// A new entry gets the profile; when markRun is true, the entry's run timestamp is set to now,
+// unless another run recorded a later one meanwhile.
+func updateGlobalRegistryEntry(localConfigDir, profile string, markRun bool) error {
+	globalConfigPath, err := GlobalRegistryPath()
+	if err != nil {
+		return err
+	}
+
+	// Check if global config exists
+	if _, err := os.Stat(globalConfigPath); os.IsNotExist(err) {
+		// Global config doesn't exist, silently return
+		return nil
+	}
+
+	// Get absolute path of the local config directory
+	absPath, err := filepath.Abs(localConfigDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	entry := GlobalBackupEntry{Location: absPath, Profile: profile}
+	if markRun {
+		entry.RunAt = time.Now()
+	}
+	return modifyGlobalRegistry(globalConfigPath, func(registry *GlobalBackupRegistry) bool {
+		// Update or add entry for this backup location
+		for i := range registry.Backups {
+			if registry.Backups[i].Location == absPath {
+				if markRun && entry.RunAt.After(registry.Backups[i].RunAt) {
+					registry.Backups[i].RunAt = entry.RunAt
+					return true
+				}
+				return false
+			}
+		}
+
+		// Add new entry
+		registry.Backups = append(registry.Backups, entry)
+		return true
+	})
+}
+
+// ReadGlobalRegistry reads the global backup registry from GlobalRegistryPath
+func ReadGlobalRegistry() (*GlobalBackupRegistry, error) {
+	globalConfigPath, err := GlobalRegistryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if global config exists
+	if _, err := os.Stat(globalConfigPath); os.IsNotExist(err) {
+		return nil, errNoGlobalRegistry(globalConfigPath)
+	}
+
+	return readGlobalRegistryFile(globalConfigPath)
+}
+
+// MigrateGlobalRegistry moves the legacy registry ~/.backup.yaml to registry.yaml in ConfigDir,
+// keeping its contents exactly, comments included. Returns both paths. Returns an error if there
+// is no legacy registry, or if the new one exists already, since the two would have to be merged.
+func MigrateGlobalRegistry() (string, string, error) {
+	legacyPath, err := LegacyGlobalRegistryPath()
+	if err != nil {
+		return "", "", err
+	}
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", "", err
+	}
+	path := filepath.Join(configDir, RegistryFileName)
+
+	unlock, err := lockGlobalRegistry()
+	if err != nil {
+		return "", "", err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(legacyPath)
+	if os.IsNotExist(err) {
+		return "", "", fmt.Errorf("there is no registry at %s to migrate", legacyPath)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read global config: %w", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return "", "", fmt.Errorf("%s exists already: merge %s into it and remove it", path, legacyPath)
+	}
+
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create %s: %w", configDir, err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return "", "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return "", "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Remove(legacyPath); err != nil {
+		return "", "", fmt.Errorf("copied the registry to %s, but failed to remove %s: %w", path, legacyPath, err)
+	}
+	return legacyPath, path, nil
+}
+
+// TestHelperLockGlobalRegistry exposes lockGlobalRegistry for testing
+func TestHelperLockGlobalRegistry() (func(), error) {
+	return lockGlobalRegistry()
+}
+
+// TestHelperModifyGlobalRegistry exposes modifyGlobalRegistry for testing
+func TestHelperModifyGlobalRegistry(globalConfigPath string, update func(registry *GlobalBackupRegistry) bool) error {
+	return modifyGlobalRegistry(globalConfigPath, update)
+}
@@ -1,8 +1,10 @@
 package config_test
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -28,8 +30,10 @@ var _ = Describe("Global Registry", func() {
 
 		globalConfigPath = filepath.Join(homeDir, ".backup.yaml")
 
-		// Override home directory for testing
+		// Override home directory for testing; the XDG directories default to it
 		os.Setenv("HOME", homeDir)
+		GinkgoT().Setenv("XDG_CONFIG_HOME", "")
+		GinkgoT().Setenv("XDG_STATE_HOME", "")
 	})
 
 	AfterEach(func() {
@@ -91,6 +95,18 @@ backups: []
 				Expect(registry.Backups[0].RunAt).To(BeTemporally("~", time.Now(), time.Second))
 			})
 
+			It("should record the profile of a new location", func() {
+				homeDir := filepath.Join(tempDir, "home")
+				Expect(os.MkdirAll(homeDir, 0755)).To(Succeed())
+
+				Expect(config.UpdateGlobalRegistryProfile(homeDir, config.HomeProfile)).To(Succeed())
+
+				registry, err := config.ReadGlobalRegistry()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(registry.Backups).To(HaveLen(1))
+				Expect(registry.Backups[0].ConfigPath()).To(Equal(filepath.Join(homeDir, config.HomeConfigFileName)))
+			})
+
 			It("should update existing backup location", func() {
 				backupDir := filepath.Join(tempDir, "my-backup")
 				err := os.MkdirAll(backupDir, 0755)
@@ -169,4 +185,340 @@ backups: []
 			})
 		})
 	})
+
+	Describe("RegisterGlobalLocation", func() {
+		It("should add the location without a run timestamp", func() {
+			err := os.WriteFile(globalConfigPath, []byte("backups: []\n"), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			projectDir := filepath.Join(tempDir, "project")
+			Expect(config.RegisterGlobalLocation(projectDir)).To(Succeed())
+			Expect(config.RegisterGlobalLocation(projectDir)).To(Succeed())
+
+			registry, err := config.ReadGlobalRegistry()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(registry.Backups).To(HaveLen(1))
+			Expect(registry.Backups[0].RunAt.IsZero()).To(BeTrue())
+		})
+
+		It("should do nothing when the global config does not exist", func() {
+			Expect(config.RegisterGlobalLocation(filepath.Join(tempDir, "project"))).To(Succeed())
+			_, err := os.Stat(globalConfigPath)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+	Describe("RegisterGlobalLocations", func() {
+		It("should add only locations that are not registered yet", func() {
+			existing := filepath.Join(tempDir, "existing")
+			initialConfig := "backups:\n  - location: " + existing + "\n    enabled: false\n"
+			Expect(os.WriteFile(globalConfigPath, []byte(initialConfig), 0644)).To(Succeed())
+
+			added, err := config.RegisterGlobalLocations([]string{existing, filepath.Join(tempDir, "new"), filepath.Join(tempDir, "new")})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(added).To(Equal([]string{filepath.Join(tempDir, "new")}))
+
+			added, err = config.RegisterGlobalLocations([]string{filepath.Join(tempDir, "new")})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(added).To(BeEmpty())
+
+			registry, err := config.ReadGlobalRegistry()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(registry.Backups).To(HaveLen(2))
+			Expect(registry.Backups[0].IsEnabled()).To(BeFalse())
+		})
+
+		It("should fail when the global config does not exist", func() {
+			_, err := config.RegisterGlobalLocations([]string{filepath.Join(tempDir, "project")})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Concurrent updates", func() {
+		BeforeEach(func() {
+			Expect(os.WriteFile(globalConfigPath, []byte("backups: []\n"), 0644)).To(Succeed())
+		})
+
+		readRegistry := func() *config.GlobalBackupRegistry {
+			registry, err := config.ReadGlobalRegistry()
+			Expect(err).NotTo(HaveOccurred())
+			return registry
+		}
+
+		It("should keep every location recorded by parallel runs", func() {
+			const runs = 20
+			var wg sync.WaitGroup
+			errs := make(chan error, runs)
+			for i := 0; i < runs; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					errs <- config.UpdateGlobalRegistry(filepath.Join(tempDir, fmt.Sprintf("project-%d", i)))
+				}(i)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(readRegistry().Backups).To(HaveLen(runs))
+			entries, err := os.ReadDir(filepath.Dir(globalConfigPath))
+			Expect(err).NotTo(HaveOccurred())
+			for _, entry := range entries {
+				Expect(entry.Name()).NotTo(ContainSubstring(".tmp-"))
+			}
+		})
+
+		It("should wait for the lock held by another update", func() {
+			unlock, err := config.TestHelperLockGlobalRegistry()
+			Expect(err).NotTo(HaveOccurred())
+
+			done := make(chan error, 1)
+			go func() { done <- config.UpdateGlobalRegistry(filepath.Join(tempDir, "waiting")) }()
+			Consistently(done, 200*time.Millisecond).ShouldNot(Receive())
+			Expect(readRegistry().Backups).To(BeEmpty())
+
+			unlock()
+			Eventually(done).Should(Receive(BeNil()))
+			Expect(readRegistry().Backups).To(HaveLen(1))
+		})
+
+		It("should merge with changes written meanwhile without the lock", func() {
+			attempts := 0
+			err := config.TestHelperModifyGlobalRegistry(globalConfigPath, func(registry *config.GlobalBackupRegistry) bool {
+				attempts++
+				if attempts == 1 {
+					// An editor saves the registry while the update is being made
+					Expect(os.WriteFile(globalConfigPath, []byte("backups:\n  - location: /edited\n"), 0644)).To(Succeed())
+				}
+				registry.Backups = append(registry.Backups, config.GlobalBackupEntry{Location: "/updated"})
+				return true
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(attempts).To(Equal(2))
+
+			registry := readRegistry()
+			Expect(registry.Backups).To(HaveLen(2))
+			Expect(registry.Backups[0].Location).To(Equal("/edited"))
+			Expect(registry.Backups[1].Location).To(Equal("/updated"))
+		})
+
+		It("should not move a run time recorded by a later run backwards", func() {
+			later := time.Now().Add(time.Hour).Truncate(time.Second)
+			backupDir := filepath.Join(tempDir, "project")
+			registry := config.GlobalBackupRegistry{Backups: []config.GlobalBackupEntry{{Location: backupDir, RunAt: later}}}
+			data, err := yaml.Marshal(registry)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(globalConfigPath, data, 0644)).To(Succeed())
+
+			Expect(config.UpdateGlobalRegistry(backupDir)).To(Succeed())
+			Expect(readRegistry().Backups[0].RunAt).To(BeTemporally("==", later))
+		})
+
+		It("should write a symlinked registry through the link", func() {
+			dotfiles := filepath.Join(tempDir, "dotfiles")
+			Expect(os.MkdirAll(dotfiles, 0755)).To(Succeed())
+			target := filepath.Join(dotfiles, "backup.yaml")
+			Expect(os.Rename(globalConfigPath, target)).To(Succeed())
+			Expect(os.Symlink(target, globalConfigPath)).To(Succeed())
+
+			Expect(config.UpdateGlobalRegistry(filepath.Join(tempDir, "project"))).To(Succeed())
+
+			info, err := os.Lstat(globalConfigPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode() & os.ModeSymlink).NotTo(BeZero())
+			Expect(readRegistry().Backups).To(HaveLen(1))
+		})
+	})
+
+	Describe("Registry location", func() {
+		var configDir string
+
+		BeforeEach(func() {
+			configDir = filepath.Join(tempDir, "home", ".config", "go-backup")
+		})
+
+		It("should use the config directory when there is no registry yet", func() {
+			path, err := config.GlobalRegistryPath()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(configDir, config.RegistryFileName)))
+		})
+
+		It("should keep using the legacy registry while only that exists", func() {
+			Expect(os.WriteFile(globalConfigPath, []byte("backups: []\n"), 0644)).To(Succeed())
+
+			path, err := config.GlobalRegistryPath()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(globalConfigPath))
+		})
+
+		It("should prefer the registry in XDG_CONFIG_HOME over the legacy one", func() {
+			xdgConfig := filepath.Join(tempDir, "xdg-config")
+			GinkgoT().Setenv("XDG_CONFIG_HOME", xdgConfig)
+			Expect(os.WriteFile(globalConfigPath, []byte("backups: []\n"), 0644)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(xdgConfig, "go-backup"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(xdgConfig, "go-backup", config.RegistryFileName), []byte("backups:\n  - location: /new\n"), 0644)).To(Succeed())
+
+			registry, err := config.ReadGlobalRegistry()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(registry.Backups[0].Location).To(Equal("/new"))
+		})
+
+		It("should keep the lock in the state directory", func() {
+			stateDir := filepath.Join(tempDir, "xdg-state")
+			GinkgoT().Setenv("XDG_STATE_HOME", stateDir)
+			Expect(os.WriteFile(globalConfigPath, []byte("backups: []\n"), 0644)).To(Succeed())
+
+			Expect(config.UpdateGlobalRegistry(filepath.Join(tempDir, "project"))).To(Succeed())
+			Expect(filepath.Join(stateDir, "go-backup", "registry.lock")).To(BeAnExistingFile())
+		})
+
+		Describe("MigrateGlobalRegistry", func() {
+			It("should move the legacy registry with its comments", func() {
+				legacy := "# my registry\nbackups:\n  - location: /projects/app # nightly\n"
+				Expect(os.WriteFile(globalConfigPath, []byte(legacy), 0644)).To(Succeed())
+
+				from, to, err := config.MigrateGlobalRegistry()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(from).To(Equal(globalConfigPath))
+				Expect(to).To(Equal(filepath.Join(configDir, config.RegistryFileName)))
+
+				data, err := os.ReadFile(to)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).To(Equal(legacy))
+				Expect(globalConfigPath).NotTo(BeAnExistingFile())
+
+				path, err := config.GlobalRegistryPath()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(path).To(Equal(to))
+			})
+
+			It("should fail without a legacy registry", func() {
+				_, _, err := config.MigrateGlobalRegistry()
+				Expect(err).To(MatchError(ContainSubstring("no registry")))
+			})
+
+			It("should not overwrite a registry in the config directory", func() {
+				Expect(os.WriteFile(globalConfigPath, []byte("backups: []\n"), 0644)).To(Succeed())
+				Expect(os.MkdirAll(configDir, 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(configDir, config.RegistryFileName), []byte("backups: []\n"), 0644)).To(Succeed())
+
+				_, _, err := config.MigrateGlobalRegistry()
+				Expect(err).To(MatchError(ContainSubstring("exists already")))
+				Expect(globalConfigPath).To(BeAnExistingFile())
+			})
+		})
+	})
+
+	Describe("GlobalBackupEntry overrides", func() {
+		It("should keep overrides when the run timestamp is updated", func() {
+			projectDir := filepath.Join(tempDir, "project")
+			initialConfig := `backups:
+  - location: ` + projectDir + `
+    enabled: false
+    profile: nightly
+    flags: ["--exclude", "tmp"]
+    schedule: daily
+`
+			Expect(os.WriteFile(globalConfigPath, []byte(initialConfig), 0644)).To(Succeed())
+
+			Expect(config.UpdateGlobalRegistry(projectDir)).To(Succeed())
+
+			registry, err := config.ReadGlobalRegistry()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(registry.Backups).To(HaveLen(1))
+			entry := registry.Backups[0]
+			Expect(entry.RunAt.IsZero()).To(BeFalse())
+			Expect(entry.IsEnabled()).To(BeFalse())
+			Expect(entry.Profile).To(Equal("nightly"))
+			Expect(entry.Flags).To(Equal([]string{"--exclude", "tmp"}))
+			Expect(entry.Schedule).To(Equal("daily"))
+		})
+
+		It("should be enabled unless disabled explicitly", func() {
+			enabled := true
+			Expect(config.GlobalBackupEntry{}.IsEnabled()).To(BeTrue())
+			Expect(config.GlobalBackupEntry{Enabled: &enabled}.IsEnabled()).To(BeTrue())
+		})
+
+		It("should check for updates unless disabled in the registry", func() {
+			Expect(os.WriteFile(globalConfigPath, []byte("updateCheck: false\nbackups: []\n"), 0644)).To(Succeed())
+			registry, err := config.ReadGlobalRegistry()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(registry.UpdateCheckEnabled()).To(BeFalse())
+
+			Expect(config.UpdateGlobalRegistry(filepath.Join(tempDir, "project"))).To(Succeed())
+			registry, err = config.ReadGlobalRegistry()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(registry.UpdateCheckEnabled()).To(BeFalse())
+
+			var missing *config.GlobalBackupRegistry
+			Expect(missing.UpdateCheckEnabled()).To(BeTrue())
+			Expect((&config.GlobalBackupRegistry{}).UpdateCheckEnabled()).To(BeTrue())
+		})
+
+		It("should order backups by priority, keeping the registry order within a priority", func() {
+			registry := &config.GlobalBackupRegistry{Backups: []config.GlobalBackupEntry{
+				{Location: "/media"},
+				{Location: "/db", Priority: 10},
+				{Location: "/app"},
+				{Location: "/archive", Priority: -1},
+				{Location: "/cache", Priority: 10},
+			}}
+
+			var locations []string
+			for _, entry := range registry.OrderedBackups() {
+				locations = append(locations, entry.Location)
+			}
+			Expect(locations).To(Equal([]string{"/db", "/cache", "/media", "/app", "/archive"}))
+			Expect(registry.Backups[0].Location).To(Equal("/media"))
+		})
+
+		It("should pick the config file from the profile", func() {
+			Expect(config.GlobalBackupEntry{Location: "/p"}.ConfigPath()).To(Equal("/p/.backup.yaml"))
+			Expect(config.GlobalBackupEntry{Location: "/p", Profile: "work"}.ConfigPath()).To(Equal("/p/.backup.work.yaml"))
+		})
+
+		It("should parse named and duration schedules", func() {
+			interval, err := config.GlobalBackupEntry{Schedule: "Weekly"}.ScheduleInterval()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(interval).To(Equal(7 * 24 * time.Hour))
+
+			interval, err = config.GlobalBackupEntry{Schedule: "12h"}.ScheduleInterval()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(interval).To(Equal(12 * time.Hour))
+
+			interval, err = config.GlobalBackupEntry{Schedule: "7d"}.ScheduleInterval()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(interval).To(Equal(7 * 24 * time.Hour))
+
+			_, err = config.GlobalBackupEntry{Schedule: "sometimes"}.ScheduleInterval()
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should be due once the schedule interval has passed", func() {
+			now := time.Now()
+			entry := config.GlobalBackupEntry{Schedule: "daily", RunAt: now.Add(-2 * time.Hour)}
+			Expect(entry.IsDue(now)).To(BeFalse())
+			Expect(entry.IsDue(now.Add(22 * time.Hour))).To(BeTrue())
+
+			Expect(config.GlobalBackupEntry{Schedule: "daily"}.IsDue(now)).To(BeTrue())
+			Expect(config.GlobalBackupEntry{RunAt: now}.IsDue(now)).To(BeTrue())
+		})
+
+		It("should send run-all summaries always or only on failure", func() {
+			var none *config.NotifyConfig
+			Expect(none.ShouldNotify(true)).To(BeFalse())
+
+			always := &config.NotifyConfig{Webhook: "https://hooks.example.com/x"}
+			Expect(always.ShouldNotify(false)).To(BeTrue())
+
+			onFailure := &config.NotifyConfig{Webhook: "https://hooks.example.com/x", On: config.NotifyFailure}
+			Expect(onFailure.ShouldNotify(false)).To(BeFalse())
+			Expect(onFailure.ShouldNotify(true)).To(BeTrue())
+
+			_, err := (&config.NotifyConfig{Webhook: "https://hooks.example.com/x", On: "sometimes"}).ShouldNotify(true)
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
@@ -0,0 +1,65 @@
+package config
+
+// HomeProfile is the profile of home directory backups (run --home). Its config file,
+// HomeConfigFileName, does not clash with ~/.backup.yaml, which may be the legacy global registry.
+const (
+	HomeProfile        = "home"
+	HomeConfigFileName = ".backup." + HomeProfile + ".yaml"
+)
+
+// HomeIncludes are the dotfiles and settings backed up from a home directory by default.
+// SSH and GPG keys are left out, since backups are not always encrypted; add them to
+// includes when they should be backed up.
+var HomeIncludes = []string{
+	".bashrc",
+	".bash_profile",
+	".bash_aliases",
+	".profile",
+	".zshrc",
+	".zprofile",
+	".zshenv",
+	".inputrc",
+	".gitconfig",
+	".gitignore_global",
+	".vimrc",
+	".vim",
+	".tmux.conf",
+	".editorconfig",
+	".ssh/config",
+	".config",
+	".local/bin",
+}
+
+// HomeExcludes remove caches, browser profiles and other bulky or machine-specific
+// data from the included paths
+var HomeExcludes = []string{
+	"Cache",
+	"cache",
+	"Caches",
+	"CachedData",
+	"Code Cache",
+	"GPUCache",
+	"Service Worker",
+	"node_modules",
+	"*.sock",
+	".vim/swap",
+	".vim/undo",
+	".config/google-chrome",
+	".config/chromium",
+	".config/BraveSoftware",
+	".config/microsoft-edge",
+	".config/vivaldi",
+	".config/opera",
+	".config/Slack",
+	".config/discord",
+	".config/Code/User/workspaceStorage",
+	".config/pulse",
+}
+
+// NewHomeConfig returns a config for backing up a home directory's dotfiles
+func NewHomeConfig() *BackupConfig {
+	return &BackupConfig{
+		Includes: append([]string{}, HomeIncludes...),
+		Excludes: append([]string{}, HomeExcludes...),
+	}
+}
@@ -0,0 +1,32 @@
+package config_test
+
+import (
+	"github.com/kennycyb/go-backup/internal/service/compress"
+	"github.com/kennycyb/go-backup/internal/service/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewHomeConfig", func() {
+	homeConfig := config.NewHomeConfig()
+
+	It("should only use valid patterns", func() {
+		Expect(compress.ValidateExcludes(homeConfig.Includes)).To(Succeed())
+		Expect(compress.ValidateExcludes(homeConfig.Excludes)).To(Succeed())
+	})
+
+	DescribeTable("backing up a home directory",
+		func(path string, expected bool) {
+			matcher := compress.NewExcludeMatcher(compress.IncludeOnly(homeConfig.Includes, homeConfig.Excludes))
+			Expect(matcher.Excluded(path)).To(Equal(expected), "Path %s should be excluded: %v", path, expected)
+		},
+		Entry("Shell config", ".bashrc", false),
+		Entry("Application config", ".config/git/config", false),
+		Entry("SSH config", ".ssh/config", false),
+		Entry("SSH key", ".ssh/id_ed25519", true),
+		Entry("Browser profile", ".config/google-chrome/Default/History", true),
+		Entry("Cache inside config", ".config/Code/Cache/data_0", true),
+		Entry("Cache directory", ".cache/pip/wheel", true),
+		Entry("Documents", "Documents/report.pdf", true),
+	)
+})
@@ -0,0 +1,34 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// tryLockFile takes the lock by creating the lock file, which fails while another process holds it.
+// Returns false if the lock file exists.
+func tryLockFile(lockPath string) (*os.File, bool, error) {
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return file, true, nil
+}
+
+// unlockFile releases a lock taken by tryLockFile by removing the lock file
+func unlockFile(file *os.File, lockPath string) {
+	file.Close()
+	os.Remove(lockPath)
+}
+
+// lockHeldError reports a lock that stayed taken. A process that exits without unlocking leaves
+// the lock file behind, so it can be removed when no go-backup process is running.
+func lockHeldError(lockPath string) error {
+	return fmt.Errorf("global config is locked by another go-backup process; remove %s if none is running", lockPath)
+}
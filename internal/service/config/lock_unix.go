@@ -0,0 +1,39 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package config
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// tryLockFile takes an exclusive advisory lock on the lock file without waiting.
+// Returns false if another process holds the lock.
+func tryLockFile(lockPath string) (*os.File, bool, error) {
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return file, true, nil
+}
+
+// unlockFile releases a lock taken by tryLockFile. The lock file stays, so waiting processes
+// always lock the same file.
+func unlockFile(file *os.File, lockPath string) {
+	syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	file.Close()
+}
+
+// lockHeldError reports a lock that stayed taken. The kernel releases the lock of a process that
+// exits, so the lock file must not be removed: that would let two processes write at once.
+func lockHeldError(lockPath string) error {
+	return errors.New("global config is locked: another go-backup process holds the registry lock")
+}
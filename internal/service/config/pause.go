@@ -0,0 +1,200 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PauseFileName is the control file in StateDir that holds the paused backups
+const PauseFileName = "pause.yaml"
+
+// Pause keeps backups from running, e.g. during a large refactor or while a disk is nearly full
+type Pause struct {
+	Since  time.Time `yaml:"since"`
+	Until  time.Time `yaml:"until,omitempty"` // Zero pauses until resumed
+	Reason string    `yaml:"reason,omitempty"`
+}
+
+// Active reports whether the pause still holds at the given time
+func (p Pause) Active(now time.Time) bool {
+	return p.Until.IsZero() || now.Before(p.Until)
+}
+
+// Describe returns why backups are paused, for skipped runs and messages
+func (p Pause) Describe() string {
+	description := "paused"
+	if !p.Until.IsZero() {
+		description += " until " + p.Until.Local().Format("2006-01-02 15:04")
+	}
+	if p.Reason != "" {
+		description += ": " + p.Reason
+	}
+	return description
+}
+
+// Pauses is the content of the pause control file
+type Pauses struct {
+	All       *Pause           `yaml:"all,omitempty"`       // Pauses every backup on the machine
+	Locations map[string]Pause `yaml:"locations,omitempty"` // Pauses of single locations, by absolute path
+}
+
+// PauseFilePath returns the path of the pause control file: pause.yaml in StateDir
+func PauseFilePath() (string, error) {
+	stateDir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, PauseFileName), nil
+}
+
+// ReadPauses reads the pause control file. Without the file nothing is paused.
+func ReadPauses() (*Pauses, error) {
+	path, err := PauseFilePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Pauses{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var pauses Pauses
+	if err := yaml.Unmarshal(data, &pauses); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &pauses, nil
+}
+
+// writePauses writes the pause control file, leaving out pauses that ended before now.
+// The file is removed once nothing is paused.
+func writePauses(pauses *Pauses, now time.Time) error {
+	path, err := PauseFilePath()
+	if err != nil {
+		return err
+	}
+	if pauses.All != nil && !pauses.All.Active(now) {
+		pauses.All = nil
+	}
+	for location, pause := range pauses.Locations {
+		if !pause.Active(now) {
+			delete(pauses.Locations, location)
+		}
+	}
+	if pauses.All == nil && len(pauses.Locations) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		return nil
+	}
+
+	data, err := yaml.Marshal(pauses)
+	if err != nil {
+		return fmt.Errorf("failed to encode pauses: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// pauseKey returns the key of a location in Pauses.Locations
+func pauseKey(location string) (string, error) {
+	absLocation, err := filepath.Abs(location)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", location, err)
+	}
+	return absLocation, nil
+}
+
+// PauseBackups pauses the backups of a location, or of the whole machine if location is empty,
+// for the given duration; a duration of 0 pauses them until ResumeBackups
+func PauseBackups(location string, duration time.Duration, reason string, now time.Time) (Pause, error) {
+	pause := Pause{Since: now, Reason: reason}
+	if duration > 0 {
+		pause.Until = now.Add(duration)
+	}
+
+	pauses, err := ReadPauses()
+	if err != nil {
+		return Pause{}, err
+	}
+	if location == "" {
+		pauses.All = &pause
+	} else {
+		key, err := pauseKey(location)
+		if err != nil {
+			return Pause{}, err
+		}
+		if pauses.Locations == nil {
+			pauses.Locations = make(map[string]Pause)
+		}
+		pauses.Locations[key] = pause
+	}
+	return pause, writePauses(pauses, now)
+}
+
+// ResumeBackups ends the pause of a location, or the machine-wide one if location is empty.
+// Returns false if it was not paused.
+func ResumeBackups(location string, now time.Time) (bool, error) {
+	pauses, err := ReadPauses()
+	if err != nil {
+		return false, err
+	}
+	resumed := false
+	if location == "" {
+		resumed = pauses.All != nil && pauses.All.Active(now)
+		pauses.All = nil
+	} else {
+		key, err := pauseKey(location)
+		if err != nil {
+			return false, err
+		}
+		pause, ok := pauses.Locations[key]
+		resumed = ok && pause.Active(now)
+		delete(pauses.Locations, key)
+	}
+	return resumed, writePauses(pauses, now)
+}
+
+// PausedAt returns the pause that keeps the location from being backed up at the given time:
+// the machine-wide pause, or else the location's own
+func PausedAt(location string, now time.Time) (Pause, bool, error) {
+	pauses, err := ReadPauses()
+	if err != nil {
+		return Pause{}, false, err
+	}
+	if pauses.All != nil && pauses.All.Active(now) {
+		return *pauses.All, true, nil
+	}
+	key, err := pauseKey(location)
+	if err != nil {
+		return Pause{}, false, err
+	}
+	if pause, ok := pauses.Locations[key]; ok && pause.Active(now) {
+		return pause, true, nil
+	}
+	return Pause{}, false, nil
+}
+
+// ParsePauseDuration parses how long to pause: a number of days like 3d or a duration like 2h
+func ParsePauseDuration(value string) (time.Duration, error) {
+	duration, err := parseInterval(value)
+	if err != nil || duration == 0 {
+		return 0, fmt.Errorf("invalid pause duration %q: use a duration like 2h, 90m or 3d", value)
+	}
+	return duration, nil
+}
@@ -0,0 +1,107 @@
+package config_test
+
+import (
+	"path/filepath"
+	"time"
+
+	. "github.com/kennycyb/go-backup/internal/service/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pause", func() {
+	var (
+		stateDir string
+		project  string
+		other    string
+		now      time.Time
+	)
+
+	BeforeEach(func() {
+		stateDir = GinkgoT().TempDir()
+		GinkgoT().Setenv("XDG_STATE_HOME", stateDir)
+		project = filepath.Join(GinkgoT().TempDir(), "project")
+		other = filepath.Join(GinkgoT().TempDir(), "other")
+		now = time.Date(2025, 5, 20, 12, 0, 0, 0, time.Local)
+	})
+
+	It("should not pause anything without the control file", func() {
+		_, paused, err := PausedAt(project, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(paused).To(BeFalse())
+	})
+
+	It("should pause a single location until the duration ends", func() {
+		_, err := PauseBackups(project, 2*time.Hour, "large refactor", now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filepath.Join(stateDir, "go-backup", PauseFileName)).To(BeAnExistingFile())
+
+		pause, paused, err := PausedAt(project, now.Add(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(paused).To(BeTrue())
+		Expect(pause.Reason).To(Equal("large refactor"))
+		Expect(pause.Describe()).To(Equal("paused until 2025-05-20 14:00: large refactor"))
+
+		_, paused, _ = PausedAt(other, now.Add(time.Hour))
+		Expect(paused).To(BeFalse())
+		_, paused, _ = PausedAt(project, now.Add(2*time.Hour))
+		Expect(paused).To(BeFalse())
+	})
+
+	It("should pause every location until resumed", func() {
+		_, err := PauseBackups("", 0, "", now)
+		Expect(err).NotTo(HaveOccurred())
+
+		pause, paused, err := PausedAt(other, now.Add(365*24*time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(paused).To(BeTrue())
+		Expect(pause.Describe()).To(Equal("paused"))
+
+		resumed, err := ResumeBackups("", now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resumed).To(BeTrue())
+		_, paused, _ = PausedAt(other, now)
+		Expect(paused).To(BeFalse())
+		Expect(filepath.Join(stateDir, "go-backup", PauseFileName)).NotTo(BeAnExistingFile())
+	})
+
+	It("should keep the machine-wide pause when a location is resumed", func() {
+		_, err := PauseBackups("", 0, "disk full", now)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = PauseBackups(project, 0, "", now)
+		Expect(err).NotTo(HaveOccurred())
+
+		resumed, err := ResumeBackups(project, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resumed).To(BeTrue())
+		pause, paused, _ := PausedAt(project, now)
+		Expect(paused).To(BeTrue())
+		Expect(pause.Reason).To(Equal("disk full"))
+	})
+
+	It("should report resuming a pause that already ended", func() {
+		_, err := PauseBackups(project, time.Hour, "", now)
+		Expect(err).NotTo(HaveOccurred())
+
+		resumed, err := ResumeBackups(project, now.Add(2*time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resumed).To(BeFalse())
+	})
+
+	DescribeTable("ParsePauseDuration",
+		func(value string, expected time.Duration, valid bool) {
+			duration, err := ParsePauseDuration(value)
+			if !valid {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(duration).To(Equal(expected))
+		},
+		Entry("Hours", "2h", 2*time.Hour, true),
+		Entry("Days", "3d", 72*time.Hour, true),
+		Entry("Empty", "", time.Duration(0), false),
+		Entry("Negative", "-1h", time.Duration(0), false),
+		Entry("Not a duration", "soon", time.Duration(0), false),
+	)
+})
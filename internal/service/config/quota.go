@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// QuotaWarnPercent is the share of a target's quota from which its usage is reported as close to it
+const QuotaWarnPercent = 90
+
+// QuotaLevel tells how close the backups of a target are to its quota
+type QuotaLevel int
+
+// Values of QuotaLevel
+const (
+	QuotaOK       QuotaLevel = iota // No quota, or usage below QuotaWarnPercent of it
+	QuotaNear                       // Usage at or above QuotaWarnPercent of the quota
+	QuotaExceeded                   // Usage above the quota
+)
+
+// sizeUnits are the suffixes of ParseSize, in powers of 1024 like the sizes go-backup prints
+var sizeUnits = []struct {
+	suffix string
+	factor float64
+}{
+	{"T", 1 << 40},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+}
+
+// ParseSize parses a size like 200G, 1.5T, 500MB or 750MiB into bytes. Units are powers of
+// 1024; a number without a unit is a number of bytes.
+func ParseSize(value string) (int64, error) {
+	number := strings.ToUpper(strings.TrimSpace(value))
+	number = strings.TrimSuffix(strings.TrimSuffix(number, "IB"), "B")
+	factor := 1.0
+	for _, unit := range sizeUnits {
+		if trimmed, ok := strings.CutSuffix(number, unit.suffix); ok {
+			number, factor = trimmed, unit.factor
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q: use a size like 200G, 500M or 1.5T", value)
+	}
+	return int64(n * factor), nil
+}
+
+// QuotaBytes returns the quota of the target in bytes, or 0 if it has none
+func (t BackupTarget) QuotaBytes() (int64, error) {
+	if t.Quota == "" {
+		return 0, nil
+	}
+	quota, err := ParseSize(t.Quota)
+	if err != nil {
+		return 0, fmt.Errorf("target %s: invalid quota: %w", t.GetDestination(), err)
+	}
+	return quota, nil
+}
+
+// Usage returns the total size of the backups recorded for the target
+func (t BackupTarget) Usage() int64 {
+	var usage int64
+	for _, record := range t.Backups {
+		usage += record.Size
+	}
+	return usage
+}
+
+// UsageAfterBackup returns the total size of the target's backups once record is added and
+// rotation removed the backups beyond maxBackups, like AddBackupRecord does for the history
+func UsageAfterBackup(config *BackupConfig, target BackupTarget, record BackupRecord) int64 {
+	target.Backups = append([]BackupRecord(nil), target.Backups...)
+	projected := &BackupConfig{Targets: []BackupTarget{target}}
+	if config != nil {
+		projected.Rotation = config.Rotation
+	}
+	AddBackupRecord(projected, target.GetDestination(), record)
+	return projected.Targets[0].Usage()
+}
+
+// CheckQuota returns how close usage is to quota; a quota of 0 means none
+func CheckQuota(usage, quota int64) QuotaLevel {
+	switch {
+	case quota <= 0:
+		return QuotaOK
+	case usage > quota:
+		return QuotaExceeded
+	case usage*100 >= quota*QuotaWarnPercent:
+		return QuotaNear
+	}
+	return QuotaOK
+}
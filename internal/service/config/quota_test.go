@@ -0,0 +1,79 @@
+package config_test
+
+import (
+	. "github.com/kennycyb/go-backup/internal/service/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Quota", func() {
+	Describe("ParseSize", func() {
+		It("should parse sizes with units in powers of 1024", func() {
+			for value, expected := range map[string]int64{
+				"200G":   200 << 30,
+				"200GB":  200 << 30,
+				"750MiB": 750 << 20,
+				"1.5T":   3 << 39,
+				"64k":    64 << 10,
+				"4096":   4096,
+			} {
+				size, err := ParseSize(value)
+				Expect(err).NotTo(HaveOccurred(), value)
+				Expect(size).To(Equal(expected), value)
+			}
+		})
+
+		It("should reject invalid sizes", func() {
+			for _, value := range []string{"", "G", "-5G", "0", "lots"} {
+				_, err := ParseSize(value)
+				Expect(err).To(HaveOccurred(), value)
+			}
+		})
+	})
+
+	Describe("QuotaBytes", func() {
+		It("should return no quota when none is set", func() {
+			quota, err := BackupTarget{Path: "/backups"}.QuotaBytes()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(quota).To(BeZero())
+		})
+
+		It("should name the target of an invalid quota", func() {
+			_, err := BackupTarget{Path: "/backups", Quota: "big"}.QuotaBytes()
+			Expect(err).To(MatchError(ContainSubstring("target /backups: invalid quota")))
+		})
+
+		It("should be validated when resolving targets", func() {
+			config := &BackupConfig{Targets: []BackupTarget{{Path: "/backups", Quota: "big"}}}
+			_, err := ResolveTargets(config, TargetFlags{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("UsageAfterBackup", func() {
+		It("should leave out the backups that rotation removes", func() {
+			target := BackupTarget{Path: "/backups", MaxBackups: 2, Backups: []BackupRecord{
+				{Filename: "b.tar.gz", Size: 200},
+				{Filename: "a.tar.gz", Size: 100},
+			}}
+			Expect(target.Usage()).To(Equal(int64(300)))
+			Expect(UsageAfterBackup(nil, target, BackupRecord{Filename: "c.tar.gz", Size: 50})).To(Equal(int64(250)))
+			Expect(target.Backups).To(HaveLen(2))
+		})
+
+		It("should replace the backup of a file target", func() {
+			target := BackupTarget{File: "/backups/latest.tar.gz", Backups: []BackupRecord{{Filename: "latest.tar.gz", Size: 200}}}
+			Expect(UsageAfterBackup(nil, target, BackupRecord{Filename: "latest.tar.gz", Size: 50})).To(Equal(int64(50)))
+		})
+	})
+
+	Describe("CheckQuota", func() {
+		It("should tell how close usage is to the quota", func() {
+			Expect(CheckQuota(500, 0)).To(Equal(QuotaOK))
+			Expect(CheckQuota(89, 100)).To(Equal(QuotaOK))
+			Expect(CheckQuota(90, 100)).To(Equal(QuotaNear))
+			Expect(CheckQuota(100, 100)).To(Equal(QuotaNear))
+			Expect(CheckQuota(101, 100)).To(Equal(QuotaExceeded))
+		})
+	})
+})
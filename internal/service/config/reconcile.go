@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+)
+
+// StaleBackupRecords returns the records whose backup is not among the files of the target,
+// e.g. archives deleted or moved by hand. A metadata snapshot is stale once the archive
+// holding its contents is gone as well.
+func StaleBackupRecords(records []BackupRecord, files map[string]bool) []BackupRecord {
+	var stale []BackupRecord
+	for _, record := range records {
+		if !files[record.Filename] || (record.SnapshotOf != "" && !files[record.SnapshotOf]) {
+			stale = append(stale, record)
+		}
+	}
+	return stale
+}
+
+// RemoveBackupRecords drops the records of the named backups from the history of a target.
+// Returns the number of records removed.
+func RemoveBackupRecords(config *BackupConfig, targetPath string, names map[string]bool) int {
+	removed := 0
+	for i, target := range config.Targets {
+		if !target.hasDestination(targetPath) {
+			continue
+		}
+		backups := []BackupRecord{}
+		for _, record := range target.Backups {
+			if names[record.Filename] {
+				removed++
+				continue
+			}
+			backups = append(backups, record)
+		}
+		config.Targets[i].Backups = backups
+	}
+	return removed
+}
+
+// RecordFromSidecars rebuilds the history record of a backup in a local directory from the files
+// kept next to it: its record in the sidecar config, which holds the history as of the backup, or
+// else its manifest. ok is false when neither can be read, e.g. for encrypted backups.
+func RecordFromSidecars(backupDir, fileName string) (BackupRecord, bool) {
+	baseName := backupService.BackupBaseName(fileName)
+
+	// Sidecars of older versions may have keys strict mode rejects
+	var sidecar BackupConfig
+	if data, err := os.ReadFile(filepath.Join(backupDir, baseName+".backup.yaml")); err == nil && decodeBackupConfig(data, &sidecar, false) == nil {
+		for _, target := range sidecar.Targets {
+			for _, record := range target.Backups {
+				if record.Filename == fileName {
+					return record, true
+				}
+			}
+		}
+	}
+
+	manifest, err := backupService.ReadManifest(filepath.Join(backupDir, baseName+backupService.ManifestSuffix))
+	if err != nil || manifest.Source == "" {
+		return BackupRecord{}, false
+	}
+	return BackupRecord{
+		Filename:   fileName,
+		Source:     manifest.Source,
+		Host:       manifest.Host,
+		RunID:      manifest.RunID,
+		CreatedAt:  manifest.CreatedAt,
+		Tags:       manifest.Tags,
+		Message:    manifest.Message,
+		Provenance: manifest.Provenance,
+	}, true
+}
@@ -0,0 +1,87 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	. "github.com/kennycyb/go-backup/internal/service/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reconciling the backup history", func() {
+	created := time.Date(2025, 5, 20, 12, 30, 45, 0, time.UTC)
+
+	It("should find the records of backups that are gone", func() {
+		records := []BackupRecord{
+			{Filename: "project-20250521-080000.tar.gz"},
+			{Filename: "project-20250521-090000.manifest.json", SnapshotOf: "project-20250520-123045.tar.gz"},
+			{Filename: "project-20250520-123045.tar.gz"},
+		}
+		files := map[string]bool{"project-20250521-090000.manifest.json": true}
+
+		stale := StaleBackupRecords(records, files)
+		Expect(stale).To(HaveLen(3))
+
+		files["project-20250520-123045.tar.gz"] = true
+		stale = StaleBackupRecords(records, files)
+		Expect(stale).To(HaveLen(1))
+		Expect(stale[0].Filename).To(Equal("project-20250521-080000.tar.gz"))
+	})
+
+	It("should remove records from the history of one target", func() {
+		config := &BackupConfig{Targets: []BackupTarget{
+			{Path: "/mnt/nas", Backups: []BackupRecord{{Filename: "a.tar.gz"}, {Filename: "b.tar.gz"}}},
+			{Path: "/mnt/usb", Backups: []BackupRecord{{Filename: "a.tar.gz"}}},
+		}}
+
+		Expect(RemoveBackupRecords(config, "/mnt/nas", map[string]bool{"a.tar.gz": true})).To(Equal(1))
+		Expect(config.Targets[0].Backups).To(Equal([]BackupRecord{{Filename: "b.tar.gz"}}))
+		Expect(config.Targets[1].Backups).To(HaveLen(1))
+	})
+
+	Describe("RecordFromSidecars", func() {
+		var backupDir string
+
+		BeforeEach(func() {
+			backupDir = GinkgoT().TempDir()
+		})
+
+		It("should take the record from the sidecar config", func() {
+			sidecar := &BackupConfig{Targets: []BackupTarget{{Path: "/mnt/nas", Backups: []BackupRecord{
+				{Filename: "project-20250520-123045.tar.gz", Source: "/home/me/project", CreatedAt: created, Size: 42, Tags: []string{"release"}},
+			}}}}
+			Expect(WriteBackupConfig(filepath.Join(backupDir, "project-20250520-123045.backup.yaml"), sidecar)).To(Succeed())
+
+			record, ok := RecordFromSidecars(backupDir, "project-20250520-123045.tar.gz")
+			Expect(ok).To(BeTrue())
+			Expect(record.Source).To(Equal("/home/me/project"))
+			Expect(record.CreatedAt.Equal(created)).To(BeTrue())
+			Expect(record.Tags).To(Equal([]string{"release"}))
+		})
+
+		It("should fall back to the manifest", func() {
+			manifest := backupService.NewManifest("project-20250520-123045.tar.gz", "/home/me/project", nil)
+			manifest.CreatedAt = created
+			manifest.Message = "before the upgrade"
+			manifest.RunID = "5d0c2b1e-8f4a-4c3e-9b7d-2a6e1f0c9d84"
+			Expect(backupService.WriteManifest(filepath.Join(backupDir, "project-20250520-123045"+backupService.ManifestSuffix), manifest)).To(Succeed())
+
+			record, ok := RecordFromSidecars(backupDir, "project-20250520-123045.tar.gz")
+			Expect(ok).To(BeTrue())
+			Expect(record.Filename).To(Equal("project-20250520-123045.tar.gz"))
+			Expect(record.Source).To(Equal("/home/me/project"))
+			Expect(record.Host).To(Equal(backupService.CurrentHost()))
+			Expect(record.Message).To(Equal("before the upgrade"))
+			Expect(record.RunID).To(Equal("5d0c2b1e-8f4a-4c3e-9b7d-2a6e1f0c9d84"))
+		})
+
+		It("should report backups without readable sidecars", func() {
+			Expect(os.WriteFile(filepath.Join(backupDir, "project-20250520-123045"+backupService.ManifestSuffix+".gpg"), []byte("encrypted"), 0644)).To(Succeed())
+			_, ok := RecordFromSidecars(backupDir, "project-20250520-123045.tar.gz.gpg")
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
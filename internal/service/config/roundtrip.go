@@ -0,0 +1,185 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// marshalPreserving encodes v as YAML laid out like previous, the file it was read from, so that
+// rewriting a config keeps the user's comments, key order, quoting and indentation. Only changed
+// values are replaced; new keys are added at the end of their mapping and keys v no longer has are
+// removed. Leading lines of previous starting with one of headerPrefixes are left out, so that a
+// generated header can be written afresh. Without a readable previous, v is encoded as usual.
+func marshalPreserving(v any, previous []byte, headerPrefixes ...string) ([]byte, error) {
+	var updated yaml.Node
+	if err := updated.Encode(v); err != nil {
+		return nil, err
+	}
+
+	previous = stripHeader(previous, headerPrefixes)
+	var original yaml.Node
+	if err := yaml.Unmarshal(previous, &original); err != nil || original.Kind != yaml.DocumentNode || len(original.Content) == 0 {
+		return yaml.Marshal(v)
+	}
+	document := &yaml.Node{Kind: yaml.DocumentNode, HeadComment: original.HeadComment, FootComment: original.FootComment,
+		Content: []*yaml.Node{mergeNodes(original.Content[0], &updated)}}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(detectIndent(previous))
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// stripHeader removes the leading lines of data that start with one of the prefixes
+func stripHeader(data []byte, prefixes []string) []byte {
+	for len(prefixes) > 0 {
+		line, rest, _ := bytes.Cut(data, []byte("\n"))
+		matched := false
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(string(line), prefix) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			break
+		}
+		data = rest
+	}
+	return data
+}
+
+// detectIndent returns the indentation of the first indented line of a YAML file, or 4 as
+// yaml.Marshal writes it. A sequence item directly under its key ("- a") does not count.
+func detectIndent(data []byte) int {
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		indent := len(line) - len(trimmed)
+		if indent > 0 && trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			if indent < 2 || indent > 9 {
+				break
+			}
+			return indent
+		}
+	}
+	return 4
+}
+
+// mergeNodes returns updated laid out like original: original nodes are kept, with their
+// comments and style, wherever updated has the same key, item or value
+func mergeNodes(original, updated *yaml.Node) *yaml.Node {
+	if original.Kind != updated.Kind {
+		keepComments(updated, original)
+		return updated
+	}
+
+	switch updated.Kind {
+	case yaml.MappingNode:
+		updatedKeys := make(map[string]int, len(updated.Content)/2)
+		for i := 0; i+1 < len(updated.Content); i += 2 {
+			updatedKeys[updated.Content[i].Value] = i
+		}
+		// Keys keep their original order; new keys follow in the order of the struct
+		var content []*yaml.Node
+		kept := make(map[string]bool)
+		for i := 0; i+1 < len(original.Content); i += 2 {
+			key := original.Content[i]
+			j, ok := updatedKeys[key.Value]
+			if !ok || kept[key.Value] {
+				continue
+			}
+			kept[key.Value] = true
+			content = append(content, key, mergeNodes(original.Content[i+1], updated.Content[j+1]))
+		}
+		for i := 0; i+1 < len(updated.Content); i += 2 {
+			if !kept[updated.Content[i].Value] {
+				content = append(content, updated.Content[i], updated.Content[i+1])
+			}
+		}
+		original.Content = content
+		return original
+
+	case yaml.SequenceNode:
+		used := make([]bool, len(original.Content))
+		content := make([]*yaml.Node, 0, len(updated.Content))
+		for i, item := range updated.Content {
+			match := matchingItem(original.Content, used, item, i)
+			if match < 0 {
+				content = append(content, item)
+				continue
+			}
+			used[match] = true
+			content = append(content, mergeNodes(original.Content[match], item))
+		}
+		original.Content = content
+		return original
+
+	case yaml.ScalarNode:
+		if original.Value == updated.Value && original.ShortTag() == updated.ShortTag() {
+			return original
+		}
+		keepComments(updated, original)
+		return updated
+	}
+	return updated
+}
+
+// matchingItem returns the index of the unused original sequence item that updated item i stands
+// for, or -1 if none does. Items are matched by value, mappings by their first key, e.g. the path
+// of a target or the filename of a backup record; otherwise an item of the same kind at the
+// same position is taken.
+func matchingItem(original []*yaml.Node, used []bool, item *yaml.Node, i int) int {
+	identity := func(node *yaml.Node, key string) (string, bool) {
+		switch {
+		case node.Kind == yaml.ScalarNode && key == "":
+			return node.Value, true
+		case node.Kind == yaml.MappingNode:
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				if node.Content[j].Value == key && node.Content[j+1].Kind == yaml.ScalarNode {
+					return node.Content[j+1].Value, true
+				}
+			}
+		}
+		return "", false
+	}
+
+	key := ""
+	if item.Kind == yaml.MappingNode {
+		if len(item.Content) < 2 || item.Content[1].Kind != yaml.ScalarNode {
+			key = "\x00" // No identity, matched by position only
+		} else {
+			key = item.Content[0].Value
+		}
+	}
+	if value, ok := identity(item, key); ok {
+		for j, candidate := range original {
+			if other, ok := identity(candidate, key); !used[j] && ok && other == value {
+				return j
+			}
+		}
+		if item.Kind == yaml.ScalarNode {
+			return -1
+		}
+	}
+	if i < len(original) && !used[i] && original[i].Kind == item.Kind {
+		if _, ok := identity(original[i], key); !ok || key == "\x00" {
+			return i
+		}
+	}
+	return -1
+}
+
+// keepComments copies the comments of the node being replaced to its replacement
+func keepComments(replacement, original *yaml.Node) {
+	replacement.HeadComment = original.HeadComment
+	replacement.LineComment = original.LineComment
+	replacement.FootComment = original.FootComment
+}
@@ -0,0 +1,89 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/kennycyb/go-backup/internal/service/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WriteBackupConfig", func() {
+	var configPath string
+
+	const original = `# My laptop backups
+excludes:
+  - "*.tmp" # editor junk
+  - node_modules
+target:
+  # the NAS
+  - path: "/mnt/nas"
+    maxBackups: 3 # keep it small
+  # thumb drive
+  - maxBackups: 2
+    path: /mnt/usb
+`
+
+	read := func() string {
+		data, err := os.ReadFile(configPath)
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	BeforeEach(func() {
+		configPath = filepath.Join(GinkgoT().TempDir(), ".backup.yaml")
+		Expect(os.WriteFile(configPath, []byte(original), 0644)).To(Succeed())
+	})
+
+	It("should keep comments, key order, quoting and indentation", func() {
+		config, err := ReadBackupConfig(configPath)
+		Expect(err).NotTo(HaveOccurred())
+		AddBackupRecord(config, "/mnt/usb", BackupRecord{Filename: "laptop-20250520-123045.tar.gz", Source: "/home/me"})
+		Expect(WriteBackupConfig(configPath, config)).To(Succeed())
+
+		content := read()
+		Expect(content).To(ContainSubstring("# My laptop backups\nexcludes:\n  - \"*.tmp\" # editor junk\n  - node_modules\n"))
+		Expect(content).To(ContainSubstring("  # the NAS\n  - path: \"/mnt/nas\"\n    maxBackups: 3 # keep it small\n"))
+		Expect(content).To(ContainSubstring("  # thumb drive\n  - maxBackups: 2\n    path: /mnt/usb\n    backups:\n      - filename: laptop-20250520-123045.tar.gz\n"))
+
+		reread, err := ReadBackupConfig(configPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reread.Targets[1].Backups).To(HaveLen(1))
+	})
+
+	It("should write its header only once", func() {
+		config, err := ReadBackupConfig(configPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(WriteBackupConfig(configPath, config)).To(Succeed())
+		Expect(WriteBackupConfig(configPath, config)).To(Succeed())
+
+		content := read()
+		Expect(strings.Count(content, "# Backup configuration file")).To(Equal(1))
+		Expect(strings.Count(content, "# My laptop backups")).To(Equal(1))
+	})
+
+	It("should drop a removed target with its comments and keep those of the others", func() {
+		config, err := ReadBackupConfig(configPath)
+		Expect(err).NotTo(HaveOccurred())
+		config.Targets = config.Targets[1:]
+		config.Excludes = append(config.Excludes, "dist")
+		Expect(WriteBackupConfig(configPath, config)).To(Succeed())
+
+		content := read()
+		Expect(content).NotTo(ContainSubstring("the NAS"))
+		Expect(content).NotTo(ContainSubstring("/mnt/nas"))
+		Expect(content).To(ContainSubstring("  # thumb drive\n  - maxBackups: 2\n    path: /mnt/usb\n"))
+		Expect(content).To(ContainSubstring("  - node_modules\n  - dist\n"))
+	})
+
+	It("should write a new file as before", func() {
+		newPath := filepath.Join(GinkgoT().TempDir(), ".backup.yaml")
+		Expect(WriteBackupConfig(newPath, &BackupConfig{Targets: []BackupTarget{{Path: "/mnt/nas", MaxBackups: 7}}})).To(Succeed())
+
+		data, err := os.ReadFile(newPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("target:\n    - path: /mnt/nas\n      maxBackups: 7\n"))
+	})
+})
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// strictDecoding makes ReadBackupConfig reject unknown keys, see SetStrict
+var strictDecoding bool
+
+// SetStrict makes ReadBackupConfig reject keys it does not know instead of ignoring them,
+// so a typo like maxBackup: fails the run rather than silently keeping the default retention
+func SetStrict(strict bool) {
+	strictDecoding = strict
+}
+
+// decodeBackupConfig decodes a config file, rejecting unknown keys if strict is set.
+// An empty file decodes to an empty config.
+func decodeBackupConfig(data []byte, config *BackupConfig, strict bool) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(strict)
+	err := decoder.Decode(config)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	var typeErr *yaml.TypeError
+	if strict && errors.As(err, &typeErr) {
+		return unknownKeysError(typeErr)
+	}
+	return err
+}
+
+// ValidateConfigFile reads a config file strictly, reporting unknown keys along with the other
+// errors that keep it from being read
+func ValidateConfigFile(filePath string) (*BackupConfig, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var config BackupConfig
+	if err := decodeBackupConfig(data, &config, true); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// UnknownKeysError reports the keys of a config file that go-backup does not know
+type UnknownKeysError struct {
+	Problems []string // One per key, e.g. `line 5: unknown key "maxBackup", did you mean "maxBackups"?`
+}
+
+func (e *UnknownKeysError) Error() string {
+	return "invalid config:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// unknownFieldPattern matches the errors yaml.v3 reports for unknown keys
+var unknownFieldPattern = regexp.MustCompile(`^line (\d+): field (\S+) not found in type (\S+)$`)
+
+// unknownKeysError rewrites the unknown key errors of a strict decode in the terms of the config
+// file, suggesting the key that was probably meant
+func unknownKeysError(typeErr *yaml.TypeError) error {
+	keys := knownKeys()
+	var problems []string
+	for _, message := range typeErr.Errors {
+		match := unknownFieldPattern.FindStringSubmatch(message)
+		if match == nil {
+			problems = append(problems, message)
+			continue
+		}
+		problem := fmt.Sprintf("line %s: unknown key %q", match[1], match[2])
+		if suggestion := closestKey(match[2], keys[match[3]]); suggestion != "" {
+			problem += fmt.Sprintf(", did you mean %q?", suggestion)
+		}
+		problems = append(problems, problem)
+	}
+	return &UnknownKeysError{Problems: problems}
+}
+
+// knownKeys returns the YAML keys of BackupConfig and the types it contains, by Go type name
+// as yaml.v3 reports it, e.g. "config.BackupTarget"
+func knownKeys() map[string][]string {
+	keys := make(map[string][]string)
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct || keys[t.String()] != nil {
+			return
+		}
+		keys[t.String()] = []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			keys[t.String()] = append(keys[t.String()], name)
+			walk(field.Type)
+		}
+	}
+	walk(reflect.TypeOf(BackupConfig{}))
+	return keys
+}
+
+// closestKey returns the known key the unknown one is most likely a typo of, or "" if none is close
+func closestKey(key string, known []string) string {
+	best, bestDistance := "", 3 // Up to two edits away
+	for _, candidate := range known {
+		if distance := editDistance(strings.ToLower(key), strings.ToLower(candidate)); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,63 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/kennycyb/go-backup/internal/service/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Strict config", func() {
+	var configPath string
+
+	write := func(content string) {
+		Expect(os.WriteFile(configPath, []byte(content), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		configPath = filepath.Join(GinkgoT().TempDir(), ".backup.yaml")
+		DeferCleanup(SetStrict, false)
+	})
+
+	It("should ignore unknown keys unless strict", func() {
+		write("target:\n  - path: /backup\n    maxBackup: 3\n")
+		config, err := ReadBackupConfig(configPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Targets[0].MaxBackups).To(Equal(7))
+	})
+
+	It("should reject unknown keys with the key that was probably meant", func() {
+		write("exclude: [x]\ntarget:\n  - path: /backup\n    maxBackup: 3\n    compression:\n      levle: 3\n    weird: true\n")
+		SetStrict(true)
+		_, err := ReadBackupConfig(configPath)
+
+		var unknownKeys *UnknownKeysError
+		Expect(errors.As(err, &unknownKeys)).To(BeTrue())
+		Expect(unknownKeys.Problems).To(Equal([]string{
+			`line 1: unknown key "exclude", did you mean "excludes"?`,
+			`line 4: unknown key "maxBackup", did you mean "maxBackups"?`,
+			`line 6: unknown key "levle", did you mean "level"?`,
+			`line 7: unknown key "weird"`,
+		}))
+	})
+
+	It("should accept a valid config and an empty one", func() {
+		write("excludes: [x]\noptions:\n  compression:\n    level: 9\ntarget:\n  - path: /backup\n    maxBackups: 3\n    encryption: false\n")
+		SetStrict(true)
+		_, err := ReadBackupConfig(configPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		write("")
+		_, err = ValidateConfigFile(configPath)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should validate strictly regardless of SetStrict", func() {
+		write("target:\n  - path: /backup\n    maxbackups: 3\n")
+		_, err := ValidateConfigFile(configPath)
+		Expect(err).To(MatchError(ContainSubstring(`did you mean "maxBackups"?`)))
+	})
+})
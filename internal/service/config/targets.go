@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TargetFlags holds the command-line options that influence which targets a command operates on
+type TargetFlags struct {
+	Destination string // Explicit destination from --dest; overrides the configured targets when set
+}
+
+// ResolvedTarget is a backup target resolved from the config file and command-line flags
+type ResolvedTarget struct {
+	BackupTarget
+	FromConfig bool // True if the target is defined in the config file
+}
+
+// ResolveTargets returns the typed backup targets a command should operate on.
+// If flags.Destination is set, only that destination is returned, using the matching
+// config target when one exists and inferring the target type otherwise.
+// Otherwise all targets from the config are returned.
+// Returns an error if a configured target is invalid or no targets are available.
+func ResolveTargets(config *BackupConfig, flags TargetFlags) ([]ResolvedTarget, error) {
+	var configTargets []BackupTarget
+	if config != nil {
+		configTargets = config.Targets
+	}
+
+	for i, t := range configTargets {
+		if t.Path == "" && t.File == "" && t.S3 == nil && t.SFTP == nil {
+			return nil, fmt.Errorf("target %d in config has neither path nor file set, nor an s3 or sftp section", i+1)
+		}
+		if err := t.validateType(); err != nil {
+			return nil, err
+		}
+		if err := t.validateUpload(); err != nil {
+			return nil, err
+		}
+		if _, err := t.ParityPercent(); err != nil {
+			return nil, err
+		}
+		if _, err := t.QuotaBytes(); err != nil {
+			return nil, err
+		}
+		if _, err := t.ChunkSize(); err != nil {
+			return nil, err
+		}
+		if _, err := t.ArchiveFormat(); err != nil {
+			return nil, err
+		}
+	}
+
+	if flags.Destination != "" {
+		for _, t := range configTargets {
+			if t.hasDestination(flags.Destination) {
+				return []ResolvedTarget{{BackupTarget: t, FromConfig: true}}, nil
+			}
+		}
+		return []ResolvedTarget{{BackupTarget: inferTarget(flags.Destination)}}, nil
+	}
+
+	if len(configTargets) == 0 {
+		return nil, fmt.Errorf("no backup destinations found in config file and no destination specified")
+	}
+
+	resolved := make([]ResolvedTarget, 0, len(configTargets))
+	for _, t := range configTargets {
+		resolved = append(resolved, ResolvedTarget{BackupTarget: t, FromConfig: true})
+	}
+	return resolved, nil
+}
+
+// inferTarget builds a target for a destination that is not defined in the config.
+// Existing directories and paths ending with a separator are treated as directory
+// targets; anything else is treated as a single file target.
+func inferTarget(dest string) BackupTarget {
+	if info, err := os.Stat(dest); err == nil && info.IsDir() {
+		return BackupTarget{Path: dest, MaxBackups: 7}
+	}
+	if strings.HasSuffix(dest, string(os.PathSeparator)) {
+		return BackupTarget{Path: dest, MaxBackups: 7}
+	}
+	return BackupTarget{File: dest}
+}
+
+// hasDestination reports whether the target writes to the given destination.
+// Targets with neither path, file, s3 nor sftp set write nowhere and never match.
+func (t BackupTarget) hasDestination(dest string) bool {
+	if t.Path == "" && t.File == "" && t.S3 == nil && t.SFTP == nil {
+		return false
+	}
+	return t.GetDestination() == dest
+}
@@ -0,0 +1,234 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/kennycyb/go-backup/internal/service/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ResolveTargets", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "targets-test")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	Context("when no destination flag is given", func() {
+		It("should return all configured targets with their types", func() {
+			config := &BackupConfig{
+				Targets: []BackupTarget{
+					{Path: "/backups/dir", MaxBackups: 3},
+					{File: "/backups/single.tar.gz"},
+				},
+			}
+
+			targets, err := ResolveTargets(config, TargetFlags{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(targets).To(HaveLen(2))
+			Expect(targets[0].IsFileTarget()).To(BeFalse())
+			Expect(targets[0].MaxBackups).To(Equal(3))
+			Expect(targets[0].FromConfig).To(BeTrue())
+			Expect(targets[1].IsFileTarget()).To(BeTrue())
+			Expect(targets[1].GetDestination()).To(Equal("/backups/single.tar.gz"))
+		})
+
+		It("should return an error when the config has no targets", func() {
+			_, err := ResolveTargets(&BackupConfig{}, TargetFlags{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("no backup destinations"))
+		})
+
+		It("should return an error for a target with neither path nor file", func() {
+			config := &BackupConfig{Targets: []BackupTarget{{MaxBackups: 3}}}
+			_, err := ResolveTargets(config, TargetFlags{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("neither path nor file"))
+		})
+	})
+
+	Context("when targets are append-only", func() {
+		lock := func(mode string, days int) *UploadConfig {
+			return &UploadConfig{ObjectLock: &ObjectLockConfig{Mode: mode, Days: days}}
+		}
+
+		It("should accept append-only remote directory targets", func() {
+			config := &BackupConfig{Targets: []BackupTarget{
+				{Path: "sftp://nas/backups/", Upload: &UploadConfig{AppendOnly: true}},
+				{Path: "s3://bucket/backups/", Upload: lock(ObjectLockCompliance, 30)},
+			}}
+			targets, err := ResolveTargets(config, TargetFlags{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(targets[0].IsAppendOnly()).To(BeTrue())
+			Expect(targets[1].IsAppendOnly()).To(BeTrue())
+		})
+
+		It("should not treat other targets as append-only", func() {
+			Expect(BackupTarget{Path: "s3://bucket/backups/"}.IsAppendOnly()).To(BeFalse())
+			Expect(BackupTarget{Path: "s3://bucket/backups/", Upload: &UploadConfig{PartSize: 8}}.IsAppendOnly()).To(BeFalse())
+		})
+
+		DescribeTable("should reject invalid settings",
+			func(target BackupTarget, message string) {
+				_, err := ResolveTargets(&BackupConfig{Targets: []BackupTarget{target}}, TargetFlags{})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(message))
+			},
+			Entry("file target", BackupTarget{File: "s3://bucket/backup.tar.gz", Upload: &UploadConfig{AppendOnly: true}}, "directory target"),
+			Entry("local target", BackupTarget{Path: "/backups/", Upload: &UploadConfig{AppendOnly: true}}, "remote targets"),
+			Entry("object lock outside S3", BackupTarget{Path: "sftp://nas/backups/", Upload: lock(ObjectLockGovernance, 7)}, "s3://"),
+			Entry("unknown mode", BackupTarget{Path: "s3://bucket/backups/", Upload: lock("legal-hold", 7)}, "invalid object lock mode"),
+			Entry("no retention", BackupTarget{Path: "s3://bucket/backups/", Upload: lock(ObjectLockGovernance, 0)}, "days must be positive"),
+		)
+	})
+
+	Context("when targets write parity files", func() {
+		It("should parse the redundancy", func() {
+			Expect(BackupTarget{Path: "/backups/"}.ParityPercent()).To(Equal(0))
+			Expect(BackupTarget{Path: "/backups/", Parity: "10%"}.ParityPercent()).To(Equal(10))
+			Expect(BackupTarget{Path: "/backups/", Parity: "25"}.ParityPercent()).To(Equal(25))
+		})
+
+		DescribeTable("should reject invalid redundancy",
+			func(parity string) {
+				_, err := ResolveTargets(&BackupConfig{Targets: []BackupTarget{{Path: "/backups/", Parity: parity}}}, TargetFlags{})
+				Expect(err).To(MatchError(ContainSubstring("invalid parity")))
+			},
+			Entry("zero", "0%"),
+			Entry("more than the archive", "150%"),
+			Entry("not a number", "some"),
+		)
+	})
+
+	Context("when targets are handled by a command", func() {
+		It("should use an exec location as the destination", func() {
+			config := &BackupConfig{Targets: []BackupTarget{{Type: TargetTypeExec, Command: "corp-blob --bucket team", Path: "backups/"}}}
+			targets, err := ResolveTargets(config, TargetFlags{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(targets[0].GetDestination()).To(Equal("exec:corp-blob --bucket team:backups/"))
+
+			UpdateTargetStatus(config, targets[0].GetDestination(), StatusSuccess, "done")
+			Expect(config.Targets[0].LastRun).NotTo(BeNil())
+		})
+
+		DescribeTable("should reject invalid settings",
+			func(target BackupTarget, message string) {
+				_, err := ResolveTargets(&BackupConfig{Targets: []BackupTarget{target}}, TargetFlags{})
+				Expect(err).To(MatchError(ContainSubstring(message)))
+			},
+			Entry("no command", BackupTarget{Type: TargetTypeExec, Path: "backups/"}, "needs a command"),
+			Entry("colon in the command", BackupTarget{Type: TargetTypeExec, Command: "C:/bin/up.exe", Path: "backups/"}, "may not contain"),
+			Entry("command without type", BackupTarget{Command: "corp-blob", Path: "backups/"}, "only used with type"),
+			Entry("unknown type", BackupTarget{Type: "ftp", Path: "backups/"}, "unknown type"),
+			Entry("unknown archive format", BackupTarget{Path: "backups/", Compression: &CompressionConfig{Format: "rar"}}, `invalid archive format "rar"`),
+		)
+	})
+
+	Context("when targets are S3 buckets", func() {
+		It("should use the bucket's s3:// location as the destination", func() {
+			config := &BackupConfig{Targets: []BackupTarget{
+				{S3: &S3TargetConfig{Bucket: "backups", Prefix: "/laptop/project/"}},
+				{S3: &S3TargetConfig{Bucket: "backups", Region: "eu-central-1", Endpoint: "http://minio.local:9000"}},
+			}}
+			targets, err := ResolveTargets(config, TargetFlags{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(targets[0].GetDestination()).To(Equal("s3://backups/laptop/project/"))
+			Expect(targets[1].GetDestination()).To(Equal("s3://backups/?endpoint=http%3A%2F%2Fminio.local%3A9000&region=eu-central-1"))
+			Expect(targets[1].IsFileTarget()).To(BeFalse())
+
+			targets, err = ResolveTargets(config, TargetFlags{Destination: "s3://backups/laptop/project/"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(targets[0].FromConfig).To(BeTrue())
+		})
+
+		DescribeTable("should reject invalid settings",
+			func(target BackupTarget, message string) {
+				_, err := ResolveTargets(&BackupConfig{Targets: []BackupTarget{target}}, TargetFlags{})
+				Expect(err).To(MatchError(ContainSubstring(message)))
+			},
+			Entry("no bucket", BackupTarget{S3: &S3TargetConfig{Prefix: "backups"}}, "needs a bucket"),
+			Entry("path in the bucket name", BackupTarget{S3: &S3TargetConfig{Bucket: "bucket/backups"}}, "without slashes"),
+			Entry("path as well", BackupTarget{Path: "/backups/", S3: &S3TargetConfig{Bucket: "bucket"}}, "replaces path and file"),
+			Entry("exec type", BackupTarget{Type: TargetTypeExec, Command: "corp-blob", S3: &S3TargetConfig{Bucket: "bucket"}}, "cannot be combined"),
+			Entry("endpoint without scheme", BackupTarget{S3: &S3TargetConfig{Bucket: "bucket", Endpoint: "minio.local:9000"}}, "invalid s3 endpoint"),
+		)
+	})
+
+	Context("when targets are SFTP servers", func() {
+		It("should use the server's sftp:// location as the destination", func() {
+			config := &BackupConfig{Targets: []BackupTarget{
+				{SFTP: &SFTPTargetConfig{Host: "nas.local", Path: "/volume1/backups/project"}},
+				{SFTP: &SFTPTargetConfig{Host: "nas.local", Port: 2222, User: "backup", Key: "~/.ssh/nas_ed25519", Path: "/backups/"}},
+			}}
+			targets, err := ResolveTargets(config, TargetFlags{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(targets[0].GetDestination()).To(Equal("sftp://nas.local/volume1/backups/project/"))
+			Expect(targets[1].GetDestination()).To(Equal("sftp://backup@nas.local:2222/backups/?key=~%2F.ssh%2Fnas_ed25519"))
+			Expect(targets[1].RotatesRemotely()).To(BeTrue())
+			Expect(BackupTarget{Path: "sftp://nas.local/backups/"}.RotatesRemotely()).To(BeFalse())
+		})
+
+		DescribeTable("should reject invalid settings",
+			func(target BackupTarget, message string) {
+				_, err := ResolveTargets(&BackupConfig{Targets: []BackupTarget{target}}, TargetFlags{})
+				Expect(err).To(MatchError(ContainSubstring(message)))
+			},
+			Entry("no host", BackupTarget{SFTP: &SFTPTargetConfig{Path: "/backups"}}, "needs a host"),
+			Entry("user in the host", BackupTarget{SFTP: &SFTPTargetConfig{Host: "backup@nas", Path: "/backups"}}, "needs a host"),
+			Entry("relative path", BackupTarget{SFTP: &SFTPTargetConfig{Host: "nas", Path: "backups"}}, "absolute directory"),
+			Entry("root", BackupTarget{SFTP: &SFTPTargetConfig{Host: "nas", Path: "/"}}, "absolute directory"),
+			Entry("invalid port", BackupTarget{SFTP: &SFTPTargetConfig{Host: "nas", Port: 70000, Path: "/backups"}}, "invalid sftp port"),
+			Entry("path as well", BackupTarget{Path: "/backups/", SFTP: &SFTPTargetConfig{Host: "nas", Path: "/backups"}}, "replaces path and file"),
+			Entry("s3 as well", BackupTarget{S3: &S3TargetConfig{Bucket: "bucket"}, SFTP: &SFTPTargetConfig{Host: "nas", Path: "/backups"}}, "only one of s3 and sftp"),
+		)
+	})
+
+	Context("when a destination flag is given", func() {
+		It("should use the matching file target from config", func() {
+			config := &BackupConfig{
+				Targets: []BackupTarget{
+					{Path: "/backups/dir", MaxBackups: 3},
+					{File: "/backups/single.tar.gz"},
+				},
+			}
+
+			targets, err := ResolveTargets(config, TargetFlags{Destination: "/backups/single.tar.gz"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(targets).To(HaveLen(1))
+			Expect(targets[0].IsFileTarget()).To(BeTrue())
+			Expect(targets[0].FromConfig).To(BeTrue())
+		})
+
+		It("should infer a directory target for an existing directory", func() {
+			targets, err := ResolveTargets(&BackupConfig{}, TargetFlags{Destination: tmpDir})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(targets).To(HaveLen(1))
+			Expect(targets[0].IsFileTarget()).To(BeFalse())
+			Expect(targets[0].MaxBackups).To(Equal(7))
+			Expect(targets[0].FromConfig).To(BeFalse())
+		})
+
+		It("should infer a directory target for a path ending with a separator", func() {
+			dest := filepath.Join(tmpDir, "missing") + string(os.PathSeparator)
+			targets, err := ResolveTargets(nil, TargetFlags{Destination: dest})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(targets[0].IsFileTarget()).To(BeFalse())
+		})
+
+		It("should infer a file target otherwise", func() {
+			dest := filepath.Join(tmpDir, "backup.tar.gz")
+			targets, err := ResolveTargets(nil, TargetFlags{Destination: dest})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(targets[0].IsFileTarget()).To(BeTrue())
+			Expect(targets[0].GetDestination()).To(Equal(dest))
+		})
+	})
+})
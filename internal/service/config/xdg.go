@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RegistryFileName is the name of the global registry in ConfigDir
+const RegistryFileName = "registry.yaml"
+
+// ConfigDir returns the directory of go-backup's own configuration, such as the global registry:
+// $XDG_CONFIG_HOME/go-backup, or ~/.config/go-backup if it is not set
+func ConfigDir() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// StateDir returns the directory of go-backup's machine state, such as run logs and locks:
+// $XDG_STATE_HOME/go-backup, or ~/.local/state/go-backup if it is not set
+func StateDir() (string, error) {
+	return xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state"))
+}
+
+// xdgDir returns the go-backup directory below the base directory named by the environment
+// variable, or below the default relative to the home directory. Relative values of the
+// variable are ignored, as the XDG Base Directory specification requires.
+func xdgDir(env, homeDefault string) (string, error) {
+	base := os.Getenv(env)
+	if !filepath.IsAbs(base) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		base = filepath.Join(homeDir, homeDefault)
+	}
+	return filepath.Join(base, "go-backup"), nil
+}
@@ -1,47 +1,54 @@
 package encrypt
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/kennycyb/go-backup/internal/service/command"
 )
 
+// runner runs gpg; tests replace it with SetRunner
+var runner command.Runner = command.ExecRunner{}
+
+// SetRunner makes the package run gpg through the runner and returns the previous one, e.g. to
+// test without a gpg binary or to put another OpenPGP implementation behind the same functions
+func SetRunner(r command.Runner) command.Runner {
+	previous := runner
+	runner = r
+	return previous
+}
+
+// minThroughput is the slowest gpg is expected to encrypt or decrypt, in bytes per second,
+// which sizes the time limit for large archives
+const minThroughput = 1 << 20
+
+// dataTimeout is how long gpg may take to encrypt or decrypt a file of the size
+func dataTimeout(size int64) time.Duration {
+	return command.DefaultTimeout + time.Duration(size/minThroughput)*time.Second
+}
+
 // GPGEncrypt encrypts a file using GPG with the specified recipient's public key.
 // It returns the path to the encrypted file.
 func GPGEncrypt(sourceFile, recipient string) (string, error) {
 	// Ensure the source file exists
-	if _, err := os.Stat(sourceFile); err != nil {
+	info, err := os.Stat(sourceFile)
+	if err != nil {
 		return "", fmt.Errorf("source file doesn't exist: %w", err)
 	}
 
 	// Create the output file path by appending .gpg extension
 	encryptedFile := sourceFile + ".gpg"
 
-	// Build and execute gpg command
-	cmd := exec.Command("gpg", "--batch", "--yes", "--trust-model", "always",
+	_, err = runner.Run(command.Options{Timeout: dataTimeout(info.Size())}, "gpg", "--batch", "--yes", "--trust-model", "always",
 		"--recipient", recipient, "--output", encryptedFile,
 		"--encrypt", sourceFile)
-
-	// Capture the standard error
-	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		return "", fmt.Errorf("failed to get stderr pipe: %w", err)
-	}
-
-	// Start the command
-	if err := cmd.Start(); err != nil {
-		return "", fmt.Errorf("failed to start gpg command: %w", err)
-	}
-
-	// Read the error output
-	errorOutput := make([]byte, 1024)
-	stderr.Read(errorOutput)
-
-	// Wait for the command to finish
-	if err := cmd.Wait(); err != nil {
-		return "", fmt.Errorf("gpg encryption failed: %w, details: %s", err, errorOutput)
+		return "", fmt.Errorf("gpg encryption failed: %w", err)
 	}
 
 	// Verify the encrypted file was created
@@ -58,7 +65,8 @@ func GPGEncrypt(sourceFile, recipient string) (string, error) {
 // If passphrase is empty, GPG will use the agent or prompt for a passphrase.
 func GPGDecrypt(encryptedFile, outputFile string, passphrase string) (string, error) {
 	// Ensure the encrypted file exists
-	if _, err := os.Stat(encryptedFile); err != nil {
+	info, err := os.Stat(encryptedFile)
+	if err != nil {
 		return "", fmt.Errorf("encrypted file doesn't exist: %w", err)
 	}
 
@@ -72,71 +80,29 @@ func GPGDecrypt(encryptedFile, outputFile string, passphrase string) (string, er
 		}
 	}
 
-	var cmd *exec.Cmd
-
-	if passphrase != "" {
-		// Use passphrase-fd=0 to read the passphrase from stdin
-		cmd = exec.Command("gpg", "--batch", "--yes", "--passphrase-fd", "0",
-			"--output", outputFile, "--decrypt", encryptedFile)
-
-		// Create a pipe to send the passphrase
-		stdin, err := cmd.StdinPipe()
-		if err != nil {
-			return "", fmt.Errorf("failed to get stdin pipe: %w", err)
-		}
-
-		// Capture the standard error
-		stderr, err := cmd.StderrPipe()
-		if err != nil {
-			stdin.Close()
-			return "", fmt.Errorf("failed to get stderr pipe: %w", err)
-		}
-
-		// Start the command before writing to stdin
-		if err := cmd.Start(); err != nil {
-			stdin.Close()
-			return "", fmt.Errorf("failed to start gpg command: %w", err)
-		}
-
-		// Write the passphrase to stdin and close the pipe
-		_, err = stdin.Write([]byte(passphrase + "\n"))
-		if err != nil {
-			return "", fmt.Errorf("failed to write passphrase: %w", err)
-		}
-		stdin.Close()
-
-		// Read the error output
-		errorOutput := make([]byte, 1024)
-		stderr.Read(errorOutput)
-
-		// Wait for the command to finish
-		if err := cmd.Wait(); err != nil {
-			return "", fmt.Errorf("gpg decryption failed: %w, details: %s", err, errorOutput)
-		}
-	} else {
-		// Default command without passphrase support
-		cmd = exec.Command("gpg", "--batch", "--yes", "--output", outputFile,
-			"--decrypt", encryptedFile)
-
-		// Capture the standard error
-		stderr, err := cmd.StderrPipe()
-		if err != nil {
-			return "", fmt.Errorf("failed to get stderr pipe: %w", err)
-		}
-
-		// Start the command
-		if err := cmd.Start(); err != nil {
-			return "", fmt.Errorf("failed to start gpg command: %w", err)
-		}
-
-		// Read the error output
-		errorOutput := make([]byte, 1024)
-		stderr.Read(errorOutput)
+	opts := command.Options{Timeout: dataTimeout(info.Size())}
+	args := []string{"--batch", "--yes"}
+	switch {
+	case passphrase != "":
+		// Loopback lets gpg take the passphrase from stdin instead of asking pinentry
+		args = append(args, "--pinentry-mode", "loopback", "--passphrase-fd", "0")
+		opts.Stdin = strings.NewReader(passphrase + "\n")
+	case !stdinIsTerminal():
+		// Without a terminal pinentry cannot ask for a passphrase or smartcard PIN and may hang,
+		// so fail fast unless gpg-agent already has it cached
+		args = append(args, "--pinentry-mode", "error")
+	default:
+		// Someone may be typing a PIN, so there is no time limit
+		opts.Timeout = 0
+	}
+	args = append(args, "--output", outputFile, "--decrypt", encryptedFile)
 
-		// Wait for the command to finish
-		if err := cmd.Wait(); err != nil {
-			return "", fmt.Errorf("gpg decryption failed: %w, details: %s", err, errorOutput)
+	if _, err := runner.Run(opts, "gpg", args...); err != nil {
+		var failure *command.Error
+		if passphrase == "" && errors.As(err, &failure) && strings.Contains(strings.ToLower(failure.Stderr), "pinentry") {
+			return "", fmt.Errorf("gpg decryption failed: %w, details: %s", ErrPinentryUnavailable, failure.Stderr)
 		}
+		return "", fmt.Errorf("gpg decryption failed: %w", err)
 	}
 
 	// Verify the decrypted file was created
@@ -156,10 +122,7 @@ func ValidateGPGReceiver(recipient string) (bool, string, error) {
 	}
 
 	// Execute gpg command to list keys matching the recipient
-	cmd := exec.Command("gpg", "--batch", "--list-keys", recipient)
-
-	// Capture the output
-	output, err := cmd.CombinedOutput()
+	output, err := runner.Run(command.Options{Timeout: command.DefaultTimeout}, "gpg", "--batch", "--list-keys", recipient)
 	if err != nil {
 		return false, "", fmt.Errorf("failed to validate GPG recipient: %w", err)
 	}
@@ -173,3 +136,82 @@ func ValidateGPGReceiver(recipient string) (bool, string, error) {
 	// If we found a key, return the output for informational purposes
 	return true, outputStr, nil
 }
+
+// PublicKeyStatus describes the public key that backups are encrypted to for a recipient
+type PublicKeyStatus struct {
+	Found      bool      // A public key matches the recipient
+	CanEncrypt bool      // The key, or one of its subkeys, can currently encrypt
+	Expired    bool      // The primary key has expired
+	Revoked    bool      // The primary key has been revoked
+	Expires    time.Time // When the primary key expires; zero if it never does
+}
+
+// GPGPublicKeyStatus looks up the public key for the recipient in the keyring and reports
+// whether it can still be used for encryption.
+// Returns a status with Found false if no key matches.
+func GPGPublicKeyStatus(recipient string) (*PublicKeyStatus, error) {
+	if recipient == "" {
+		return nil, fmt.Errorf("recipient cannot be empty")
+	}
+
+	output, err := runner.Run(command.Options{Timeout: command.DefaultTimeout}, "gpg", "--batch", "--with-colons", "--list-keys", recipient)
+	if err != nil {
+		if command.Exited(err) {
+			// gpg exits non-zero when there is no matching key
+			return &PublicKeyStatus{}, nil
+		}
+		return nil, fmt.Errorf("failed to list GPG keys: %w", err)
+	}
+
+	return parsePublicKeyStatus(string(output)), nil
+}
+
+// parsePublicKeyStatus parses `gpg --with-colons --list-keys` output.
+// Field 2 of a "pub" line holds the validity ("e" expired, "r" revoked), field 7 the
+// expiration time and field 12 the capabilities, where an uppercase "E" means the key
+// as a whole can encrypt. When several keys match, the first usable one is reported.
+func parsePublicKeyStatus(output string) *PublicKeyStatus {
+	var found *PublicKeyStatus
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) < 12 || fields[0] != "pub" {
+			continue
+		}
+
+		status := newPublicKeyStatus(fields)
+		if status.Usable() {
+			return &status
+		}
+		if found == nil {
+			found = &status
+		}
+	}
+	if found == nil {
+		return &PublicKeyStatus{}
+	}
+	return found
+}
+
+// newPublicKeyStatus builds the status from the fields of a "pub" line
+func newPublicKeyStatus(fields []string) PublicKeyStatus {
+	status := PublicKeyStatus{
+		Found:      true,
+		Expired:    fields[1] == "e",
+		Revoked:    fields[1] == "r",
+		CanEncrypt: strings.Contains(fields[11], "E"),
+	}
+	if seconds, err := strconv.ParseInt(fields[6], 10, 64); err == nil && seconds > 0 {
+		status.Expires = time.Unix(seconds, 0)
+	}
+	return status
+}
+
+// Usable reports whether backups can be encrypted to the key
+func (s PublicKeyStatus) Usable() bool {
+	return s.CanEncrypt && !s.Expired && !s.Revoked
+}
+
+// TestHelperParsePublicKeyStatus exposes the parsePublicKeyStatus function for testing
+func TestHelperParsePublicKeyStatus(output string) *PublicKeyStatus {
+	return parsePublicKeyStatus(output)
+}
@@ -140,4 +140,39 @@ var _ = Describe("GPG", func() {
 			})
 		})
 	})
+
+	Describe("parsePublicKeyStatus", func() {
+		It("should report a key that can encrypt", func() {
+			output := "pub:u:255:22:AAAAAAAAAAAAAAAA:1700000000:1900000000::u:::scESC::::::23::0:\n" +
+				"sub:u:255:18:BBBBBBBBBBBBBBBB:1700000000:1900000000:::::e::::::23:\n"
+			status := encrypt.TestHelperParsePublicKeyStatus(output)
+			Expect(status.Found).To(BeTrue())
+			Expect(status.CanEncrypt).To(BeTrue())
+			Expect(status.Expired).To(BeFalse())
+			Expect(status.Expires.Unix()).To(Equal(int64(1900000000)))
+		})
+
+		It("should report expired and revoked keys", func() {
+			status := encrypt.TestHelperParsePublicKeyStatus("pub:e:255:22:AAAAAAAAAAAAAAAA:1600000000:1650000000::u:::sc::::::23::0:\n")
+			Expect(status.Found).To(BeTrue())
+			Expect(status.Expired).To(BeTrue())
+			Expect(status.CanEncrypt).To(BeFalse())
+
+			status = encrypt.TestHelperParsePublicKeyStatus("pub:r:255:22:AAAAAAAAAAAAAAAA:1600000000:::u:::sc::::::23::0:\n")
+			Expect(status.Revoked).To(BeTrue())
+			Expect(status.Expires.IsZero()).To(BeTrue())
+		})
+
+		It("should prefer a usable key when several match", func() {
+			output := "pub:e:255:22:AAAAAAAAAAAAAAAA:1600000000:1650000000::u:::sc::::::23::0:\n" +
+				"pub:u:255:22:CCCCCCCCCCCCCCCC:1700000000:::u:::scESC::::::23::0:\n"
+			status := encrypt.TestHelperParsePublicKeyStatus(output)
+			Expect(status.CanEncrypt).To(BeTrue())
+			Expect(status.Expired).To(BeFalse())
+		})
+
+		It("should report no key for empty output", func() {
+			Expect(encrypt.TestHelperParsePublicKeyStatus("").Found).To(BeFalse())
+		})
+	})
 })
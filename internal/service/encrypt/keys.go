@@ -0,0 +1,151 @@
+package encrypt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kennycyb/go-backup/internal/service/command"
+)
+
+// GPGKey is a public key in the keyring that backups could be encrypted to
+type GPGKey struct {
+	PublicKeyStatus
+	Fingerprint string   // Fingerprint of the primary key
+	UserIDs     []string // Valid user IDs, e.g. "Jane Doe <jane@example.com>"
+	Emails      []string // Email addresses from the user IDs
+	HasSecret   bool     // The secret key is in the keyring or on a smartcard, so backups can be restored here
+}
+
+// ListGPGKeys returns the public keys in the keyring, noting which have a secret key
+func ListGPGKeys() ([]GPGKey, error) {
+	output, err := runner.Run(command.Options{Timeout: command.DefaultTimeout}, "gpg", "--batch", "--with-colons", "--list-keys")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list GPG keys: %w", err)
+	}
+	keys := parseGPGKeys(string(output))
+
+	secretOutput, err := runner.Run(command.Options{Timeout: command.DefaultTimeout}, "gpg", "--batch", "--with-colons", "--list-secret-keys")
+	if err != nil {
+		if !command.Exited(err) {
+			return nil, fmt.Errorf("failed to list GPG secret keys: %w", err)
+		}
+	}
+	secrets := parseGPGKeys(string(secretOutput))
+	hasSecret := make(map[string]bool)
+	for _, key := range secrets {
+		hasSecret[key.Fingerprint] = true
+	}
+	for i := range keys {
+		keys[i].HasSecret = hasSecret[keys[i].Fingerprint]
+	}
+	return keys, nil
+}
+
+// parseGPGKeys parses `gpg --with-colons --list-keys` (or --list-secret-keys) output.
+// A "pub" or "sec" line starts a key, the first "fpr" line after it holds the primary key's
+// fingerprint in field 10 (later ones belong to subkeys), and "uid" lines hold the user ID
+// in field 10; revoked user IDs are left out.
+func parseGPGKeys(output string) []GPGKey {
+	var keys []GPGKey
+	var current *GPGKey
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) < 10 {
+			continue
+		}
+
+		switch fields[0] {
+		case "pub", "sec":
+			if len(fields) < 12 {
+				current = nil
+				continue
+			}
+			keys = append(keys, GPGKey{PublicKeyStatus: newPublicKeyStatus(fields)})
+			current = &keys[len(keys)-1]
+		case "fpr":
+			if current != nil && current.Fingerprint == "" {
+				current.Fingerprint = fields[9]
+			}
+		case "uid":
+			if current == nil || fields[1] == "r" {
+				continue
+			}
+			userID := strings.ReplaceAll(fields[9], `\x3a`, ":")
+			current.UserIDs = append(current.UserIDs, userID)
+			if email := emailFromUserID(userID); email != "" {
+				current.Emails = append(current.Emails, email)
+			}
+		}
+	}
+	return keys
+}
+
+// emailFromUserID returns the address between angle brackets in a user ID, if any
+func emailFromUserID(userID string) string {
+	start := strings.LastIndex(userID, "<")
+	end := strings.LastIndex(userID, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return userID[start+1 : end]
+}
+
+// TestHelperParseGPGKeys exposes the parseGPGKeys function for testing
+func TestHelperParseGPGKeys(output string) []GPGKey {
+	return parseGPGKeys(output)
+}
+
+// ExportGPGKey writes the ASCII-armored key of the recipient to the output file.
+// With secret set, the secret key is exported too, so backups can be restored on another
+// machine; gpg may ask for the key's passphrase and the file is only readable by the owner.
+// Returns an error if no key matches the recipient.
+func ExportGPGKey(recipient, outputFile string, secret bool) error {
+	if recipient == "" {
+		return fmt.Errorf("recipient cannot be empty")
+	}
+
+	args := []string{"--armor", "--export", recipient}
+	perm := os.FileMode(0644)
+	if secret {
+		args = []string{"--armor", "--export-secret-keys", recipient}
+		perm = 0600
+	} else {
+		args = append([]string{"--batch"}, args...)
+	}
+
+	// Exporting a secret key may ask for its passphrase, which has no time limit
+	opts := command.Options{Timeout: command.DefaultTimeout, Stdin: os.Stdin}
+	if secret {
+		opts.Timeout = 0
+	}
+	output, err := runner.Run(opts, "gpg", args...)
+	if err != nil {
+		return fmt.Errorf("gpg export failed: %w", err)
+	}
+	// gpg succeeds without output when nothing matches
+	if len(output) == 0 {
+		return fmt.Errorf("no GPG key found for recipient: %s", recipient)
+	}
+
+	if err := os.WriteFile(outputFile, output, perm); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	return nil
+}
+
+// ImportGPGKeys imports the public or secret keys in the file into the keyring.
+// Returns gpg's summary of what was imported.
+func ImportGPGKeys(inputFile string) (string, error) {
+	if _, err := os.Stat(inputFile); err != nil {
+		return "", fmt.Errorf("key file doesn't exist: %w", err)
+	}
+
+	// gpg reports what it imported on its error output
+	var summary bytes.Buffer
+	if _, err := runner.Run(command.Options{Timeout: command.DefaultTimeout, Stderr: &summary}, "gpg", "--batch", "--import", inputFile); err != nil {
+		return "", fmt.Errorf("gpg import failed: %w", err)
+	}
+	return strings.TrimSpace(summary.String()), nil
+}
@@ -0,0 +1,63 @@
+package encrypt_test
+
+import (
+	"github.com/kennycyb/go-backup/internal/service/encrypt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Keys", func() {
+	Describe("parseGPGKeys", func() {
+		It("should read the fingerprint, user IDs and capabilities of each key", func() {
+			output := "tru::1:1792002834:1792866833:3:1:5\n" +
+				"pub:u:3072:1:95B77C51CA33C373:1792002833:1792866833::u:::scESC::::::23::0:\n" +
+				"fpr:::::::::BD36756B1DE2162930367BD895B77C51CA33C373:\n" +
+				"uid:u::::1792002833::38FE81345608D55723A53EE6A903B0374EE5D1EF::Doc <doc@example.invalid>::::::::::0:\n" +
+				"uid:r::::1792002833::48FE81345608D55723A53EE6A903B0374EE5D1EF::Old <old@example.invalid>::::::::::0:\n" +
+				"sub:u:3072:1:AD6A31083EFB6A89:1792002833::::::e::::::23:\n" +
+				"fpr:::::::::33107662800F22DDF991A513AD6A31083EFB6A89:\n" +
+				"pub:e:255:22:CCCCCCCCCCCCCCCC:1600000000:1650000000::u:::sc::::::23::0:\n" +
+				"fpr:::::::::CCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCC:\n" +
+				"uid:e::::1600000000::58FE81345608D55723A53EE6A903B0374EE5D1EF::Backup Key\\x3a Home::::::::::0:\n"
+
+			keys := encrypt.TestHelperParseGPGKeys(output)
+			Expect(keys).To(HaveLen(2))
+
+			Expect(keys[0].Fingerprint).To(Equal("BD36756B1DE2162930367BD895B77C51CA33C373"))
+			Expect(keys[0].UserIDs).To(Equal([]string{"Doc <doc@example.invalid>"}))
+			Expect(keys[0].Emails).To(Equal([]string{"doc@example.invalid"}))
+			Expect(keys[0].Usable()).To(BeTrue())
+
+			Expect(keys[1].Fingerprint).To(Equal("CCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCC"))
+			Expect(keys[1].UserIDs).To(Equal([]string{"Backup Key: Home"}))
+			Expect(keys[1].Emails).To(BeEmpty())
+			Expect(keys[1].Expired).To(BeTrue())
+			Expect(keys[1].Usable()).To(BeFalse())
+		})
+
+		It("should read secret key listings", func() {
+			output := "sec:u:3072:1:95B77C51CA33C373:1792002833:1792866833::u:::scESC:::+:::23::0:\n" +
+				"fpr:::::::::BD36756B1DE2162930367BD895B77C51CA33C373:\n" +
+				"grp:::::::::1CDCACE00E8BF680BEE1F9341C8EEA1702EDDC5B:\n" +
+				"uid:u::::1792002833::38FE81345608D55723A53EE6A903B0374EE5D1EF::Doc <doc@example.invalid>::::::::::0:\n"
+
+			keys := encrypt.TestHelperParseGPGKeys(output)
+			Expect(keys).To(HaveLen(1))
+			Expect(keys[0].Fingerprint).To(Equal("BD36756B1DE2162930367BD895B77C51CA33C373"))
+		})
+	})
+
+	Describe("ExportGPGKey", func() {
+		It("should return an error for an empty recipient", func() {
+			Expect(encrypt.ExportGPGKey("", "/tmp/unused.asc", false)).NotTo(Succeed())
+		})
+	})
+
+	Describe("ImportGPGKeys", func() {
+		It("should return an error for a missing key file", func() {
+			_, err := encrypt.ImportGPGKeys("/nonexistent/key.asc")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("key file doesn't exist"))
+		})
+	})
+})
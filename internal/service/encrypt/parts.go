@@ -0,0 +1,146 @@
+package encrypt
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// GPGEncryptParts encrypts consecutive parts of a file separately, so each part can be decrypted
+// without the others, e.g. after reading only that part of a remote backup. sizes are the
+// plaintext sizes of the parts and must add up to the file's size. The encrypted parts are
+// written one after another to the source path with a .gpg extension.
+// It returns the encrypted file's path and the encrypted size of each part.
+func GPGEncryptParts(sourceFile, recipient string, sizes []int64) (string, []int64, error) {
+	source, err := os.Open(sourceFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("source file doesn't exist: %w", err)
+	}
+	defer source.Close()
+
+	encryptedFile := sourceFile + ".gpg"
+	output, err := os.Create(encryptedFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("error creating encrypted file: %w", err)
+	}
+	defer output.Close()
+
+	tmpDir, err := os.MkdirTemp("", "go-backup-parts-")
+	if err != nil {
+		return "", nil, fmt.Errorf("error creating temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	encryptedSizes := make([]int64, 0, len(sizes))
+	for i, size := range sizes {
+		partPath := filepath.Join(tmpDir, fmt.Sprintf("part%d", i))
+		if err := copyToFile(partPath, io.LimitReader(source, size), size); err != nil {
+			os.Remove(encryptedFile)
+			return "", nil, err
+		}
+		encryptedPart, err := GPGEncrypt(partPath, recipient)
+		if err != nil {
+			os.Remove(encryptedFile)
+			return "", nil, err
+		}
+		n, err := appendFile(output, encryptedPart)
+		if err != nil {
+			os.Remove(encryptedFile)
+			return "", nil, err
+		}
+		encryptedSizes = append(encryptedSizes, n)
+		os.Remove(partPath)
+		os.Remove(encryptedPart)
+	}
+
+	if n, _ := source.Read(make([]byte, 1)); n > 0 {
+		os.Remove(encryptedFile)
+		return "", nil, fmt.Errorf("parts do not cover %s", sourceFile)
+	}
+	if err := output.Close(); err != nil {
+		os.Remove(encryptedFile)
+		return "", nil, fmt.Errorf("error writing encrypted file: %w", err)
+	}
+	return encryptedFile, encryptedSizes, nil
+}
+
+// GPGDecryptParts decrypts a file written by GPGEncryptParts, given the encrypted size of each
+// part, into outputFile; the parts may also be any of the original parts in order. gpg itself
+// only decrypts the first part of such a file. The passphrase is used like with GPGDecrypt.
+// It returns the path to the decrypted file.
+func GPGDecryptParts(encryptedFile, outputFile, passphrase string, sizes []int64) (string, error) {
+	source, err := os.Open(encryptedFile)
+	if err != nil {
+		return "", fmt.Errorf("encrypted file doesn't exist: %w", err)
+	}
+	defer source.Close()
+
+	output, err := os.Create(outputFile)
+	if err != nil {
+		return "", fmt.Errorf("error creating decrypted file: %w", err)
+	}
+	defer output.Close()
+
+	tmpDir, err := os.MkdirTemp("", "go-backup-parts-")
+	if err != nil {
+		return "", fmt.Errorf("error creating temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for i, size := range sizes {
+		partPath := filepath.Join(tmpDir, fmt.Sprintf("part%d.gpg", i))
+		if err := copyToFile(partPath, io.LimitReader(source, size), size); err != nil {
+			os.Remove(outputFile)
+			return "", err
+		}
+		decryptedPart, err := GPGDecrypt(partPath, "", passphrase)
+		if err != nil {
+			os.Remove(outputFile)
+			return "", fmt.Errorf("part %d of %d: %w", i+1, len(sizes), err)
+		}
+		if _, err := appendFile(output, decryptedPart); err != nil {
+			os.Remove(outputFile)
+			return "", err
+		}
+		os.Remove(partPath)
+		os.Remove(decryptedPart)
+	}
+
+	if err := output.Close(); err != nil {
+		os.Remove(outputFile)
+		return "", fmt.Errorf("error writing decrypted file: %w", err)
+	}
+	return outputFile, nil
+}
+
+// copyToFile writes size bytes from r to a new file at path
+func copyToFile(path string, r io.Reader, size int64) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating part: %w", err)
+	}
+	defer file.Close()
+	n, err := io.Copy(file, r)
+	if err != nil {
+		return fmt.Errorf("error writing part: %w", err)
+	}
+	if n != size {
+		return fmt.Errorf("file ends after %d of %d bytes of a part", n, size)
+	}
+	return file.Close()
+}
+
+// appendFile copies the file at path to w and returns the number of bytes copied
+func appendFile(w io.Writer, path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("error reading part: %w", err)
+	}
+	defer file.Close()
+	n, err := io.Copy(w, file)
+	if err != nil {
+		return n, fmt.Errorf("error writing part: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,80 @@
+package encrypt_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/kennycyb/go-backup/internal/service/encrypt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GPG parts", func() {
+	var tmpDir, source string
+
+	BeforeEach(func() {
+		if _, err := exec.LookPath("gpg"); err != nil {
+			Skip("gpg is not installed")
+		}
+
+		var err error
+		tmpDir, err = os.MkdirTemp("", "gpg-parts-test-")
+		Expect(err).NotTo(HaveOccurred())
+		gnupgHome := filepath.Join(tmpDir, "gnupg")
+		Expect(os.Mkdir(gnupgHome, 0700)).To(Succeed())
+		GinkgoT().Setenv("GNUPGHOME", gnupgHome)
+
+		genKey := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-gen-key",
+			"parts@example.com", "future-default", "default", "never")
+		if output, err := genKey.CombinedOutput(); err != nil {
+			Skip("unable to generate a test key: " + string(output))
+		}
+
+		source = filepath.Join(tmpDir, "archive.tar.gz")
+		Expect(os.WriteFile(source, []byte("first|second|third"), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+		os.RemoveAll(tmpDir)
+	})
+
+	It("should encrypt each part on its own and decrypt them all", func() {
+		encrypted, sizes, err := encrypt.GPGEncryptParts(source, "parts@example.com", []int64{6, 7, 5})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(encrypted).To(Equal(source + ".gpg"))
+		Expect(sizes).To(HaveLen(3))
+
+		info, err := os.Stat(encrypted)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sizes[0] + sizes[1] + sizes[2]).To(Equal(info.Size()))
+
+		decrypted, err := encrypt.GPGDecryptParts(encrypted, filepath.Join(tmpDir, "out.tar.gz"), "", sizes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.ReadFile(decrypted)).To(Equal([]byte("first|second|third")))
+	})
+
+	It("should decrypt a single part read on its own", func() {
+		encrypted, sizes, err := encrypt.GPGEncryptParts(source, "parts@example.com", []int64{6, 7, 5})
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := os.ReadFile(encrypted)
+		Expect(err).NotTo(HaveOccurred())
+		second := filepath.Join(tmpDir, "second.gpg")
+		Expect(os.WriteFile(second, data[sizes[0]:sizes[0]+sizes[1]], 0644)).To(Succeed())
+
+		decrypted, err := encrypt.GPGDecrypt(second, filepath.Join(tmpDir, "second"), "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.ReadFile(decrypted)).To(Equal([]byte("second|")))
+	})
+
+	It("should fail when the parts do not cover the file", func() {
+		_, _, err := encrypt.GPGEncryptParts(source, "parts@example.com", []int64{6})
+		Expect(err).To(MatchError(ContainSubstring("do not cover")))
+		Expect(source + ".gpg").NotTo(BeAnExistingFile())
+
+		_, _, err = encrypt.GPGEncryptParts(source, "parts@example.com", []int64{6, 100})
+		Expect(err).To(MatchError(ContainSubstring("ends after")))
+	})
+})
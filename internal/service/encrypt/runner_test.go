@@ -0,0 +1,58 @@
+package encrypt_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kennycyb/go-backup/internal/service/command"
+	"github.com/kennycyb/go-backup/internal/service/encrypt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Runner", func() {
+	It("should list keys through the runner", func() {
+		DeferCleanup(encrypt.SetRunner, encrypt.SetRunner(command.RunnerFunc(func(opts command.Options, name string, args ...string) ([]byte, error) {
+			Expect(name).To(Equal("gpg"))
+			Expect(opts.Timeout).To(Equal(command.DefaultTimeout))
+			if args[len(args)-1] == "--list-secret-keys" {
+				return nil, &command.Error{Name: name, Args: args, ExitCode: 2, Err: fmt.Errorf("exit status 2")}
+			}
+			return []byte("pub:u:3072:1:95B77C51CA33C373:1792002833:::u:::scESC::::::23::0:\n" +
+				"fpr:::::::::BD36756B1DE2162930367BD895B77C51CA33C373:\n" +
+				"uid:u::::1792002833::38FE81345608D55723A53EE6A903B0374EE5D1EF::Doc <doc@example.invalid>::::::::::0:\n"), nil
+		})))
+
+		keys, err := encrypt.ListGPGKeys()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(HaveLen(1))
+		Expect(keys[0].Emails).To(Equal([]string{"doc@example.invalid"}))
+		Expect(keys[0].HasSecret).To(BeFalse())
+	})
+
+	It("should return gpg's import summary", func() {
+		keyFile := filepath.Join(GinkgoT().TempDir(), "key.asc")
+		Expect(os.WriteFile(keyFile, []byte("key"), 0600)).To(Succeed())
+		DeferCleanup(encrypt.SetRunner, encrypt.SetRunner(command.RunnerFunc(func(opts command.Options, name string, args ...string) ([]byte, error) {
+			Expect(args).To(Equal([]string{"--batch", "--import", keyFile}))
+			fmt.Fprintln(opts.Stderr, "gpg: Total number processed: 1")
+			return nil, nil
+		})))
+
+		summary, err := encrypt.ImportGPGKeys(keyFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(summary).To(Equal("gpg: Total number processed: 1"))
+	})
+
+	It("should report a decryption that needs pinentry", func() {
+		encrypted := filepath.Join(GinkgoT().TempDir(), "backup.tar.gz.gpg")
+		Expect(os.WriteFile(encrypted, []byte("data"), 0600)).To(Succeed())
+		DeferCleanup(encrypt.SetRunner, encrypt.SetRunner(command.RunnerFunc(func(opts command.Options, name string, args ...string) ([]byte, error) {
+			return nil, &command.Error{Name: name, Args: args, Stderr: "gpg: problem with the agent: No pinentry", ExitCode: 2, Err: fmt.Errorf("exit status 2")}
+		})))
+
+		_, err := encrypt.GPGDecrypt(encrypted, "", "")
+		Expect(err).To(MatchError(encrypt.ErrPinentryUnavailable))
+	})
+})
@@ -0,0 +1,153 @@
+package encrypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kennycyb/go-backup/internal/service/command"
+)
+
+// ErrPinentryUnavailable is returned when gpg needs a passphrase or smartcard PIN
+// but cannot prompt for it because there is no terminal
+var ErrPinentryUnavailable = errors.New("gpg needs a passphrase or smartcard PIN but cannot prompt without a terminal; " +
+	"run interactively, or unlock the key in gpg-agent first (e.g. decrypt any file once from a terminal)")
+
+// SecretKeyStatus describes the secret key that can decrypt backups for a recipient
+type SecretKeyStatus struct {
+	Available  bool   // A secret encryption key is in the keyring or on a smartcard
+	OnCard     bool   // The secret key lives on a smartcard such as a YubiKey
+	CardSerial string // Serial number of the smartcard, if known
+}
+
+// RoundtripResult is the outcome of an encryption roundtrip check
+type RoundtripResult struct {
+	Key        *SecretKeyStatus
+	Decrypted  bool   // The encrypted test data was decrypted and matched
+	SkipReason string // Why decryption was not attempted, if it was not
+}
+
+// GPGSecretKeyStatus looks up the secret key for the recipient in the keyring.
+// Returns a status with Available false if only the public key is present.
+func GPGSecretKeyStatus(recipient string) (*SecretKeyStatus, error) {
+	if recipient == "" {
+		return nil, fmt.Errorf("recipient cannot be empty")
+	}
+
+	output, err := runner.Run(command.Options{Timeout: command.DefaultTimeout}, "gpg", "--batch", "--with-colons", "--list-secret-keys", recipient)
+	if err != nil {
+		if command.Exited(err) {
+			// gpg exits non-zero when there is no matching secret key
+			return &SecretKeyStatus{}, nil
+		}
+		return nil, fmt.Errorf("failed to list GPG secret keys: %w", err)
+	}
+
+	return parseSecretKeyStatus(string(output)), nil
+}
+
+// parseSecretKeyStatus parses `gpg --with-colons --list-secret-keys` output.
+// Field 12 holds the key capabilities and field 15 the smartcard serial number,
+// "#" for a stub without the secret part, or "+" for a secret key on disk.
+func parseSecretKeyStatus(output string) *SecretKeyStatus {
+	status := &SecretKeyStatus{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) < 15 || (fields[0] != "sec" && fields[0] != "ssb") {
+			continue
+		}
+
+		// Only keys that can decrypt matter; lowercase capabilities belong to the key itself
+		if !strings.Contains(fields[11], "e") {
+			continue
+		}
+
+		serial := fields[14]
+		if serial == "#" {
+			continue
+		}
+
+		status.Available = true
+		if serial != "" && serial != "+" {
+			status.OnCard = true
+			status.CardSerial = serial
+		}
+	}
+	return status
+}
+
+// TestHelperParseSecretKeyStatus exposes the parseSecretKeyStatus function for testing
+func TestHelperParseSecretKeyStatus(output string) *SecretKeyStatus {
+	return parseSecretKeyStatus(output)
+}
+
+// GPGRoundtrip encrypts random test data for the recipient and, if the secret key is
+// available, decrypts it again and compares the result.
+// Decryption is skipped when only the public key is present, or when the key is on a
+// smartcard and there is no terminal to enter the PIN.
+// Returns an error if encryption or decryption fails.
+func GPGRoundtrip(recipient string) (*RoundtripResult, error) {
+	tmpDir, err := os.MkdirTemp("", "go-backup-gpg-test-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	data := make([]byte, 1024)
+	if _, err := rand.Read(data); err != nil {
+		return nil, fmt.Errorf("failed to generate test data: %w", err)
+	}
+	plainFile := filepath.Join(tmpDir, "roundtrip.bin")
+	if err := os.WriteFile(plainFile, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write test data: %w", err)
+	}
+
+	encryptedFile, err := GPGEncrypt(plainFile, recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := GPGSecretKeyStatus(recipient)
+	if err != nil {
+		return nil, err
+	}
+	result := &RoundtripResult{Key: key}
+
+	if !key.Available {
+		result.SkipReason = "no secret key for the recipient in this keyring; backups can be created here but must be restored where the secret key is"
+		return result, nil
+	}
+	if key.OnCard && !stdinIsTerminal() {
+		result.SkipReason = "the secret key is on a smartcard and there is no terminal to enter the PIN"
+		return result, nil
+	}
+
+	decryptedFile, err := GPGDecrypt(encryptedFile, filepath.Join(tmpDir, "roundtrip.out"), "")
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted, err := os.ReadFile(decryptedFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read decrypted data: %w", err)
+	}
+	if !bytes.Equal(decrypted, data) {
+		return nil, fmt.Errorf("decrypted data does not match the original")
+	}
+
+	result.Decrypted = true
+	return result, nil
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,81 @@
+package encrypt_test
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/kennycyb/go-backup/internal/service/encrypt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Smartcard", func() {
+	Describe("parseSecretKeyStatus", func() {
+		It("should detect a secret key on disk", func() {
+			output := "sec:u:255:22:AAAAAAAAAAAAAAAA:1700000000:::u:::scESC:::+:::ed25519:::0:\n" +
+				"ssb:u:255:18:BBBBBBBBBBBBBBBB:1700000000::::::e:::+:::cv25519::\n"
+			status := encrypt.TestHelperParseSecretKeyStatus(output)
+			Expect(status.Available).To(BeTrue())
+			Expect(status.OnCard).To(BeFalse())
+		})
+
+		It("should detect an encryption key on a smartcard", func() {
+			output := "sec#:u:4096:1:AAAAAAAAAAAAAAAA:1700000000:::u:::scESC:::#:::::0:\n" +
+				"ssb:u:4096:1:BBBBBBBBBBBBBBBB:1700000000::::::e:::D2760001240103040006123456780000:::::\n"
+			status := encrypt.TestHelperParseSecretKeyStatus(output)
+			Expect(status.Available).To(BeTrue())
+			Expect(status.OnCard).To(BeTrue())
+			Expect(status.CardSerial).To(Equal("D2760001240103040006123456780000"))
+		})
+
+		It("should ignore stubs without the secret part", func() {
+			output := "sec:u:255:22:AAAAAAAAAAAAAAAA:1700000000:::u:::scESC:::#:::ed25519:::0:\n" +
+				"ssb:u:255:18:BBBBBBBBBBBBBBBB:1700000000::::::e:::#:::cv25519::\n"
+			status := encrypt.TestHelperParseSecretKeyStatus(output)
+			Expect(status.Available).To(BeFalse())
+		})
+	})
+
+	Describe("GPGRoundtrip", func() {
+		It("should return an error for an unknown recipient", func() {
+			_, err := encrypt.GPGRoundtrip("nonexistent-recipient@invalid-domain-12345.com")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("gpg encryption failed"))
+		})
+
+		Context("with a throwaway keyring", func() {
+			var gnupgHome string
+
+			BeforeEach(func() {
+				if _, err := exec.LookPath("gpg"); err != nil {
+					Skip("gpg is not installed")
+				}
+
+				var err error
+				gnupgHome, err = os.MkdirTemp("", "gnupg-")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(os.Chmod(gnupgHome, 0700)).To(Succeed())
+				GinkgoT().Setenv("GNUPGHOME", gnupgHome)
+
+				genKey := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-gen-key",
+					"roundtrip@example.com", "future-default", "default", "never")
+				if output, err := genKey.CombinedOutput(); err != nil {
+					Skip("unable to generate a test key: " + string(output))
+				}
+			})
+
+			AfterEach(func() {
+				exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+				os.RemoveAll(gnupgHome)
+			})
+
+			It("should encrypt and decrypt test data", func() {
+				result, err := encrypt.GPGRoundtrip("roundtrip@example.com")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Key.Available).To(BeTrue())
+				Expect(result.Key.OnCard).To(BeFalse())
+				Expect(result.Decrypted).To(BeTrue())
+			})
+		})
+	})
+})
@@ -0,0 +1,56 @@
+// Package extract restores backups into a directory: an encrypted archive is decrypted into a
+// temporary file first, then the archive is unpacked with compress.ExtractTarGzArchive, which
+// honors Overwrite and restores file modes and modification times.
+package extract
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+	encryptionService "github.com/kennycyb/go-backup/internal/service/encrypt"
+)
+
+// Options controls how Archive decrypts and writes a backup
+type Options struct {
+	compressionService.ExtractOptions
+	Passphrase string // Passphrase for an encrypted archive; empty leaves it to gpg-agent
+	// Chunks of an encrypted archive stored in chunks, as recorded in its manifest; each chunk
+	// is decrypted on its own
+	Chunks []compressionService.ArchiveChunk
+}
+
+// Archive unpacks a backup into targetDir. An archive ending in .gpg is decrypted into a
+// temporary file, which is removed again once the archive is unpacked.
+func Archive(archiveFile, targetDir string, opts Options) (*compressionService.ExtractResult, error) {
+	if !strings.HasSuffix(archiveFile, ".gpg") {
+		return compressionService.ExtractTarGzArchive(archiveFile, targetDir, opts.ExtractOptions)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "go-backup-extract-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	plainPath := filepath.Join(tmpDir, strings.TrimSuffix(filepath.Base(archiveFile), ".gpg"))
+	if _, err := Decrypt(archiveFile, plainPath, opts.Passphrase, opts.Chunks); err != nil {
+		return nil, err
+	}
+	return compressionService.ExtractTarGzArchive(plainPath, targetDir, opts.ExtractOptions)
+}
+
+// Decrypt decrypts an encrypted archive into outputFile. An archive stored in chunks is
+// decrypted chunk by chunk, since gpg alone only decrypts the first chunk of it.
+func Decrypt(archiveFile, outputFile, passphrase string, chunks []compressionService.ArchiveChunk) (string, error) {
+	if len(chunks) <= 1 {
+		return encryptionService.GPGDecrypt(archiveFile, outputFile, passphrase)
+	}
+	sizes := make([]int64, len(chunks))
+	for i, chunk := range chunks {
+		sizes[i] = chunk.Size
+	}
+	return encryptionService.GPGDecryptParts(archiveFile, outputFile, passphrase, sizes)
+}
@@ -0,0 +1,92 @@
+package extract_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+	. "github.com/kennycyb/go-backup/internal/service/extract"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Archive", func() {
+	var sourceDir, restoreDir, archive string
+	modTime := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		sourceDir = filepath.Join(tempDir, "source")
+		restoreDir = filepath.Join(tempDir, "restored")
+		archive = filepath.Join(tempDir, "project-20250520-123045.tar.gz")
+
+		Expect(os.MkdirAll(filepath.Join(sourceDir, "bin"), 0750)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sourceDir, "bin", "run.sh"), []byte("#!/bin/sh"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sourceDir, "notes.txt"), []byte("archived"), 0600)).To(Succeed())
+		for _, path := range []string{"bin/run.sh", "notes.txt", "bin"} {
+			Expect(os.Chtimes(filepath.Join(sourceDir, path), modTime, modTime)).To(Succeed())
+		}
+		Expect(compressionService.CreateTarGzArchive(sourceDir, archive, nil)).To(Succeed())
+	})
+
+	expectRestored := func() {
+		for path, mode := range map[string]os.FileMode{"bin": 0750, "bin/run.sh": 0755, "notes.txt": 0600} {
+			info, err := os.Stat(filepath.Join(restoreDir, path))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(mode), path)
+			Expect(info.ModTime().Equal(modTime)).To(BeTrue(), path)
+		}
+	}
+
+	It("should unpack an archive with its modes and modification times", func() {
+		result, err := Archive(archive, restoreDir, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Restored).To(ConsistOf("bin", "bin/run.sh", "notes.txt"))
+		expectRestored()
+	})
+
+	It("should keep existing files unless overwrite is set", func() {
+		Expect(os.MkdirAll(restoreDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(restoreDir, "notes.txt"), []byte("local"), 0644)).To(Succeed())
+
+		result, err := Archive(archive, restoreDir, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Skipped).To(Equal([]string{"notes.txt"}))
+		Expect(os.ReadFile(filepath.Join(restoreDir, "notes.txt"))).To(Equal([]byte("local")))
+
+		opts := Options{ExtractOptions: compressionService.ExtractOptions{Overwrite: true}}
+		_, err = Archive(archive, restoreDir, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.ReadFile(filepath.Join(restoreDir, "notes.txt"))).To(Equal([]byte("archived")))
+		expectRestored()
+	})
+
+	Context("with an encrypted archive", func() {
+		BeforeEach(func() {
+			if _, err := exec.LookPath("gpg"); err != nil {
+				Skip("gpg is not installed")
+			}
+			home := GinkgoT().TempDir()
+			GinkgoT().Setenv("GNUPGHOME", home)
+			DeferCleanup(func() { exec.Command("gpgconf", "--kill", "gpg-agent").Run() })
+			encrypt := exec.Command("gpg", "--batch", "--yes", "--pinentry-mode", "loopback", "--passphrase", "secret",
+				"--symmetric", "--output", archive+".gpg", archive)
+			Expect(encrypt.Run()).To(Succeed())
+		})
+
+		It("should decrypt it into a temporary file first", func() {
+			result, err := Archive(archive+".gpg", restoreDir, Options{Passphrase: "secret"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Restored).To(ConsistOf("bin", "bin/run.sh", "notes.txt"))
+			expectRestored()
+		})
+
+		It("should fail before unpacking anything when it cannot be decrypted", func() {
+			_, err := Archive(archive+".gpg", restoreDir, Options{Passphrase: "wrong"})
+			Expect(err).To(MatchError(ContainSubstring("gpg decryption failed")))
+			Expect(restoreDir).NotTo(BeADirectory())
+		})
+	})
+})
@@ -7,21 +7,82 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/kennycyb/go-backup/internal/service/command"
+)
+
+// pullTimeout is how long git pull may take; a pull that times out is tried once more
+const pullTimeout = 5 * time.Minute
+
+// runner runs git; tests replace it with SetRunner
+var runner command.Runner = command.ExecRunner{}
+
+// SetRunner makes the package run git through the runner and returns the previous one, e.g. to
+// test without a git binary or to put another git implementation behind the same functions
+func SetRunner(r command.Runner) command.Runner {
+	previous := runner
+	runner = r
+	return previous
+}
+
+// Implementations of HasUncommittedChanges, GetCurrentBranch and PullLatest
+const (
+	ImplementationAuto  = ""       // The git binary when it is installed, go-git otherwise
+	ImplementationGit   = "git"    // The git binary
+	ImplementationGoGit = "go-git" // go-git, built into go-backup, for machines without git
 )
 
+// implementation is the implementation selected with SetImplementation
+var implementation = ImplementationAuto
+
+// SetImplementation selects how HasUncommittedChanges, GetCurrentBranch and PullLatest reach the repository.
+// Returns an error for an unknown implementation.
+func SetImplementation(name string) error {
+	switch name {
+	case ImplementationAuto, ImplementationGit, ImplementationGoGit:
+		implementation = name
+		return nil
+	}
+	return fmt.Errorf("unknown git implementation %q (expected %s or %s)", name, ImplementationGit, ImplementationGoGit)
+}
+
+// usesGoGit reports whether the operations go through go-git. Automatically, go-git is only used
+// when the git binary is missing and the runner was not replaced, e.g. by a test's fake git.
+func usesGoGit() bool {
+	switch implementation {
+	case ImplementationGit:
+		return false
+	case ImplementationGoGit:
+		return true
+	}
+	if _, ok := runner.(command.ExecRunner); !ok {
+		return false
+	}
+	_, err := exec.LookPath("git")
+	return err != nil
+}
+
+// git runs a git command in dir, stopping it after command.DefaultTimeout, and returns its output
+func git(dir string, args ...string) ([]byte, error) {
+	return runner.Run(command.Options{Timeout: command.DefaultTimeout}, "git", append([]string{"-C", dir}, args...)...)
+}
+
 // HasUncommittedChanges checks if the directory has uncommitted changes in git
 // Returns true if there are uncommitted changes, false otherwise
 // Returns an error if the directory is not a git repository or git command fails
 func HasUncommittedChanges(dir string) (bool, error) {
+	if usesGoGit() {
+		return goGitHasUncommittedChanges(dir)
+	}
+
 	// Check if directory is a git repository
-	cmd := exec.Command("git", "-C", dir, "rev-parse", "--git-dir")
-	if err := cmd.Run(); err != nil {
+	if _, err := git(dir, "rev-parse", "--git-dir"); err != nil {
 		return false, fmt.Errorf("not a git repository: %w", err)
 	}
 
 	// Check git status - porcelain format for machine-readable output
-	cmd = exec.Command("git", "-C", dir, "status", "--porcelain")
-	output, err := cmd.Output()
+	output, err := git(dir, "status", "--porcelain")
 	if err != nil {
 		return false, fmt.Errorf("failed to check git status: %w", err)
 	}
@@ -35,15 +96,17 @@ func HasUncommittedChanges(dir string) (bool, error) {
 // Returns the branch name as a string. In a detached HEAD state, returns "HEAD".
 // Returns an error if the directory is not a git repository or git command fails.
 func GetCurrentBranch(dir string) (string, error) {
+	if usesGoGit() {
+		return goGitCurrentBranch(dir)
+	}
+
 	// Check if directory is a git repository
-	cmd := exec.Command("git", "-C", dir, "rev-parse", "--git-dir")
-	if err := cmd.Run(); err != nil {
+	if _, err := git(dir, "rev-parse", "--git-dir"); err != nil {
 		return "", fmt.Errorf("not a git repository: %w", err)
 	}
 
 	// Get current branch name
-	cmd = exec.Command("git", "-C", dir, "rev-parse", "--abbrev-ref", "HEAD")
-	output, err := cmd.Output()
+	output, err := git(dir, "rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
 		return "", fmt.Errorf("failed to get current branch: %w", err)
 	}
@@ -56,15 +119,17 @@ func GetCurrentBranch(dir string) (string, error) {
 // Returns true if the pull brought new changes, false if already up-to-date
 // Returns an error if the directory is not a git repository or git command fails
 func PullLatest(dir string) (bool, error) {
+	if usesGoGit() {
+		return goGitPullLatest(dir)
+	}
+
 	// Check if directory is a git repository
-	cmd := exec.Command("git", "-C", dir, "rev-parse", "--git-dir")
-	if err := cmd.Run(); err != nil {
+	if _, err := git(dir, "rev-parse", "--git-dir"); err != nil {
 		return false, fmt.Errorf("not a git repository: %w", err)
 	}
 
 	// Get git directory path to check for ongoing operations
-	cmd = exec.Command("git", "-C", dir, "rev-parse", "--git-dir")
-	gitDirOutput, err := cmd.Output()
+	gitDirOutput, err := git(dir, "rev-parse", "--git-dir")
 	if err != nil {
 		return false, fmt.Errorf("failed to get git directory: %w", err)
 	}
@@ -75,40 +140,23 @@ func PullLatest(dir string) (bool, error) {
 		gitDir = filepath.Join(dir, gitDir)
 	}
 
-	// Check for ongoing operations that would prevent pull
-	// Check for rebase
-	if _, err := os.Stat(filepath.Join(gitDir, "rebase-merge")); err == nil {
-		return false, fmt.Errorf("repository is in the middle of a rebase operation; please complete or abort it before running backup")
-	}
-	if _, err := os.Stat(filepath.Join(gitDir, "rebase-apply")); err == nil {
-		return false, fmt.Errorf("repository is in the middle of a rebase operation; please complete or abort it before running backup")
-	}
-	
-	// Check for merge
-	if _, err := os.Stat(filepath.Join(gitDir, "MERGE_HEAD")); err == nil {
-		return false, fmt.Errorf("repository is in the middle of a merge operation; please complete or abort it before running backup")
-	}
-	
-	// Check for cherry-pick
-	if _, err := os.Stat(filepath.Join(gitDir, "CHERRY_PICK_HEAD")); err == nil {
-		return false, fmt.Errorf("repository is in the middle of a cherry-pick operation; please complete or abort it before running backup")
+	if err := checkNoOperationInProgress(gitDir); err != nil {
+		return false, err
 	}
 
 	// Get the current HEAD commit before pull
-	cmd = exec.Command("git", "-C", dir, "rev-parse", "HEAD")
-	beforeOutput, err := cmd.Output()
+	beforeOutput, err := git(dir, "rev-parse", "HEAD")
 	if err != nil {
 		return false, fmt.Errorf("failed to get current HEAD: %w", err)
 	}
 	beforeCommit := strings.TrimSpace(string(beforeOutput))
 
-	// Pull latest changes
-	cmd = exec.Command("git", "-C", dir, "pull")
-	output, err := cmd.CombinedOutput()
+	// Pull latest changes, failing instead of waiting for credentials nobody types in a scheduled run
+	output, err := runner.Run(command.Options{Timeout: pullTimeout, Retries: 1, Env: []string{"GIT_TERMINAL_PROMPT=0"}},
+		"git", "-C", dir, "pull")
 	if err != nil {
 		// Check if the pull failed due to merge conflicts
-		conflictCmd := exec.Command("git", "-C", dir, "diff", "--name-only", "--diff-filter=U")
-		conflictOutput, conflictErr := conflictCmd.Output()
+		conflictOutput, conflictErr := git(dir, "diff", "--name-only", "--diff-filter=U")
 		if conflictErr == nil && strings.TrimSpace(string(conflictOutput)) != "" {
 			return false, fmt.Errorf("git pull resulted in merge conflicts in repository %s; please resolve them and commit the changes: %w (output: %s)", dir, err, string(output))
 		}
@@ -116,8 +164,7 @@ func PullLatest(dir string) (bool, error) {
 	}
 
 	// Get the HEAD commit after pull
-	cmd = exec.Command("git", "-C", dir, "rev-parse", "HEAD")
-	afterOutput, err := cmd.Output()
+	afterOutput, err := git(dir, "rev-parse", "HEAD")
 	if err != nil {
 		return false, fmt.Errorf("failed to get current HEAD after pull: %w", err)
 	}
@@ -127,3 +174,19 @@ func PullLatest(dir string) (bool, error) {
 	hasUpdates := beforeCommit != afterCommit
 	return hasUpdates, nil
 }
+
+// checkNoOperationInProgress returns an error if a rebase, merge or cherry-pick in the git directory
+// is unfinished, which would make a pull fail or mix into it
+func checkNoOperationInProgress(gitDir string) error {
+	for _, operation := range []struct{ path, name string }{
+		{"rebase-merge", "rebase"},
+		{"rebase-apply", "rebase"},
+		{"MERGE_HEAD", "merge"},
+		{"CHERRY_PICK_HEAD", "cherry-pick"},
+	} {
+		if _, err := os.Stat(filepath.Join(gitDir, operation.path)); err == nil {
+			return fmt.Errorf("repository is in the middle of a %s operation; please complete or abort it before running backup", operation.name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// openRepository opens the repository dir is in, like git -C dir would
+func openRepository(dir string) (*gogit.Repository, error) {
+	repo, err := gogit.PlainOpenWithOptions(dir, &gogit.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, fmt.Errorf("not a git repository: %w", err)
+	}
+	return repo, nil
+}
+
+// goGitHasUncommittedChanges is HasUncommittedChanges with go-git
+func goGitHasUncommittedChanges(dir string) (bool, error) {
+	repo, err := openRepository(dir)
+	if err != nil {
+		return false, err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return false, fmt.Errorf("failed to check git status: %w", err)
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return false, fmt.Errorf("failed to check git status: %w", err)
+	}
+	return !status.IsClean(), nil
+}
+
+// goGitCurrentBranch is GetCurrentBranch with go-git. A branch without commits yet is returned too.
+func goGitCurrentBranch(dir string) (string, error) {
+	repo, err := openRepository(dir)
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to get current branch: %w", err)
+	}
+	if head.Type() == plumbing.SymbolicReference && head.Target().IsBranch() {
+		return head.Target().Short(), nil
+	}
+	return "HEAD", nil
+}
+
+// goGitPullLatest is PullLatest with go-git, which only fast-forwards: a branch that has diverged
+// from its upstream is reported as an error rather than merged
+func goGitPullLatest(dir string) (bool, error) {
+	repo, err := openRepository(dir)
+	if err != nil {
+		return false, err
+	}
+	if storage, ok := repo.Storer.(*filesystem.Storage); ok {
+		if err := checkNoOperationInProgress(storage.Filesystem().Root()); err != nil {
+			return false, err
+		}
+	}
+
+	before, err := repo.Head()
+	if err != nil {
+		return false, fmt.Errorf("failed to get current HEAD: %w", err)
+	}
+	if !before.Name().IsBranch() {
+		return false, fmt.Errorf("failed to pull: HEAD is not on a branch")
+	}
+
+	// Pull the upstream of the branch, as git pull does, rather than the remote's default branch
+	config, err := repo.Config()
+	if err != nil {
+		return false, fmt.Errorf("failed to read git config: %w", err)
+	}
+	branch, ok := config.Branches[before.Name().Short()]
+	if !ok || branch.Remote == "" || branch.Merge == "" {
+		return false, fmt.Errorf("failed to pull: branch %s has no upstream branch", before.Name().Short())
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return false, fmt.Errorf("failed to pull: %w", err)
+	}
+
+	// Like the git binary's pull, one that times out is tried once more
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), pullTimeout)
+		err = worktree.PullContext(ctx, &gogit.PullOptions{RemoteName: branch.Remote, ReferenceName: branch.Merge})
+		timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+		cancel()
+		if !timedOut || attempt > 0 {
+			break
+		}
+	}
+	switch {
+	case errors.Is(err, gogit.NoErrAlreadyUpToDate):
+		return false, nil
+	case errors.Is(err, gogit.ErrNonFastForwardUpdate):
+		return false, fmt.Errorf("failed to pull: branch %s has diverged from its upstream, and go-git can only fast-forward; merge or rebase it with git", before.Name().Short())
+	case err != nil:
+		return false, fmt.Errorf("failed to pull: %w", err)
+	}
+
+	after, err := repo.Head()
+	if err != nil {
+		return false, fmt.Errorf("failed to get current HEAD after pull: %w", err)
+	}
+	return before.Hash() != after.Hash(), nil
+}
@@ -0,0 +1,100 @@
+package git_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/kennycyb/go-backup/internal/service/git"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("go-git implementation", func() {
+	var originDir, cloneDir string
+
+	git := func(dir string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(output))
+	}
+
+	commit := func(dir, name, contents string) {
+		Expect(os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644)).To(Succeed())
+		git(dir, "add", name)
+		git(dir, "commit", "-m", "update "+name)
+	}
+
+	BeforeEach(func() {
+		Expect(SetImplementation(ImplementationGoGit)).To(Succeed())
+		DeferCleanup(func() { Expect(SetImplementation(ImplementationAuto)).To(Succeed()) })
+
+		originDir = GinkgoT().TempDir()
+		git(originDir, "init", "-b", "main")
+		git(originDir, "config", "user.email", "test@example.com")
+		git(originDir, "config", "user.name", "Test User")
+		commit(originDir, "README.md", "v1")
+
+		cloneDir = filepath.Join(GinkgoT().TempDir(), "clone")
+		git(filepath.Dir(cloneDir), "clone", originDir, cloneDir)
+		git(cloneDir, "config", "user.email", "test@example.com")
+		git(cloneDir, "config", "user.name", "Test User")
+	})
+
+	It("rejects an unknown implementation", func() {
+		Expect(SetImplementation("libgit2")).To(MatchError(ContainSubstring(`unknown git implementation "libgit2"`)))
+	})
+
+	It("returns an error outside a git repository", func() {
+		_, err := HasUncommittedChanges(GinkgoT().TempDir())
+		Expect(err).To(MatchError(ContainSubstring("not a git repository")))
+	})
+
+	It("reports uncommitted changes", func() {
+		hasChanges, err := HasUncommittedChanges(cloneDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hasChanges).To(BeFalse())
+
+		Expect(os.WriteFile(filepath.Join(cloneDir, "new.txt"), []byte("new"), 0644)).To(Succeed())
+		hasChanges, err = HasUncommittedChanges(cloneDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hasChanges).To(BeTrue())
+	})
+
+	It("returns the current branch, also from a subdirectory", func() {
+		git(cloneDir, "checkout", "-b", "feature")
+		Expect(os.MkdirAll(filepath.Join(cloneDir, "sub"), 0755)).To(Succeed())
+
+		branch, err := GetCurrentBranch(filepath.Join(cloneDir, "sub"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(branch).To(Equal("feature"))
+	})
+
+	It("fast-forwards to the upstream branch", func() {
+		hasUpdates, err := PullLatest(cloneDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hasUpdates).To(BeFalse())
+
+		commit(originDir, "README.md", "v2")
+		hasUpdates, err = PullLatest(cloneDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hasUpdates).To(BeTrue())
+		Expect(os.ReadFile(filepath.Join(cloneDir, "README.md"))).To(Equal([]byte("v2")))
+	})
+
+	It("refuses to merge a branch that has diverged", func() {
+		commit(originDir, "README.md", "v2")
+		commit(cloneDir, "local.txt", "local")
+
+		_, err := PullLatest(cloneDir)
+		Expect(err).To(MatchError(ContainSubstring("go-git can only fast-forward")))
+	})
+
+	It("refuses to pull during a merge", func() {
+		Expect(os.WriteFile(filepath.Join(cloneDir, ".git", "MERGE_HEAD"), []byte("0000000000000000000000000000000000000000\n"), 0644)).To(Succeed())
+
+		_, err := PullLatest(cloneDir)
+		Expect(err).To(MatchError(ContainSubstring("in the middle of a merge")))
+	})
+})
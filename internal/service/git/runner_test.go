@@ -0,0 +1,68 @@
+package git_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kennycyb/go-backup/internal/service/command"
+	. "github.com/kennycyb/go-backup/internal/service/git"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Runner", func() {
+	var calls []string
+
+	// fakeGit answers git commands from the map, keyed by their arguments after -C <dir>
+	fakeGit := func(answers map[string]string) command.Runner {
+		return command.RunnerFunc(func(opts command.Options, name string, args ...string) ([]byte, error) {
+			Expect(name).To(Equal("git"))
+			key := strings.Join(args[2:], " ")
+			calls = append(calls, key)
+			answer, ok := answers[key]
+			if !ok {
+				return nil, &command.Error{Name: name, Args: args, ExitCode: 128, Err: fmt.Errorf("exit status 128")}
+			}
+			return []byte(answer), nil
+		})
+	}
+
+	BeforeEach(func() {
+		calls = nil
+	})
+
+	It("should run git through the runner", func() {
+		DeferCleanup(SetRunner, SetRunner(fakeGit(map[string]string{
+			"rev-parse --git-dir":         ".git\n",
+			"rev-parse --abbrev-ref HEAD": "release\n",
+			"status --porcelain":          " M main.go\n",
+		})))
+
+		branch, err := GetCurrentBranch("/src")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(branch).To(Equal("release"))
+
+		changed, err := HasUncommittedChanges("/src")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(calls).To(Equal([]string{"rev-parse --git-dir", "rev-parse --abbrev-ref HEAD", "rev-parse --git-dir", "status --porcelain"}))
+	})
+
+	It("should report a pull that timed out", func() {
+		runner := fakeGit(map[string]string{
+			"rev-parse --git-dir": GinkgoT().TempDir() + "\n",
+			"rev-parse HEAD":      "abc123\n",
+		})
+		DeferCleanup(SetRunner, SetRunner(command.RunnerFunc(func(opts command.Options, name string, args ...string) ([]byte, error) {
+			if args[2] == "pull" {
+				Expect(opts.Timeout).To(BeNumerically(">", 0))
+				Expect(opts.Env).To(ContainElement("GIT_TERMINAL_PROMPT=0"))
+				return nil, &command.Error{Name: name, Args: args, ExitCode: -1, Err: fmt.Errorf("git %w after 5m0s", command.ErrTimeout)}
+			}
+			return runner.Run(opts, name, args...)
+		})))
+
+		_, err := PullLatest("/src")
+		Expect(err).To(MatchError(command.ErrTimeout))
+	})
+})
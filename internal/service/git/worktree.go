@@ -0,0 +1,75 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FileState is what git knows about a file in a working tree
+type FileState string
+
+// States of files in a working tree
+const (
+	FileCommitted FileState = "committed" // Tracked and unchanged since the last commit, so git can bring it back
+	FileModified  FileState = "modified"  // Tracked with changes that are not committed, staged or not
+	FileUntracked FileState = "untracked" // Not tracked by git
+	FileIgnored   FileState = "ignored"   // Matched by .gitignore, or otherwise unknown to git
+)
+
+// WorkTree describes the files below a directory of a git working tree
+type WorkTree struct {
+	tracked   map[string]bool
+	changed   map[string]bool
+	untracked map[string]bool
+}
+
+// ReadWorkTree reads the state of the files below dir, which must be inside a git working tree.
+// Returns an error if dir is not in a git repository or git fails.
+func ReadWorkTree(dir string) (*WorkTree, error) {
+	if output, err := git(dir, "rev-parse", "--is-inside-work-tree"); err != nil || strings.TrimSpace(string(output)) != "true" {
+		return nil, fmt.Errorf("not a git working tree: %s", dir)
+	}
+
+	tree := &WorkTree{}
+	var err error
+	if tree.tracked, err = gitPaths(dir, "ls-files", "-z"); err != nil {
+		return nil, fmt.Errorf("failed to list tracked files: %w", err)
+	}
+	if tree.untracked, err = gitPaths(dir, "ls-files", "-z", "--others", "--exclude-standard"); err != nil {
+		return nil, fmt.Errorf("failed to list untracked files: %w", err)
+	}
+	// Compare with HEAD rather than the index, so staged changes count as uncommitted too.
+	// Without a first commit, nothing is committed yet.
+	if tree.changed, err = gitPaths(dir, "diff", "HEAD", "--name-only", "-z", "--relative"); err != nil {
+		tree.changed = tree.tracked
+	}
+	return tree, nil
+}
+
+// State returns the state of a file, by its path relative to the directory read, with forward slashes
+func (t *WorkTree) State(path string) FileState {
+	switch {
+	case t.changed[path]:
+		return FileModified
+	case t.tracked[path]:
+		return FileCommitted
+	case t.untracked[path]:
+		return FileUntracked
+	}
+	return FileIgnored
+}
+
+// gitPaths runs a git command in dir that prints NUL-separated paths relative to dir
+func gitPaths(dir string, args ...string) (map[string]bool, error) {
+	output, err := git(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]bool)
+	for _, path := range strings.Split(string(output), "\x00") {
+		if path != "" {
+			paths[path] = true
+		}
+	}
+	return paths, nil
+}
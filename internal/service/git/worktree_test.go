@@ -0,0 +1,85 @@
+package git_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/kennycyb/go-backup/internal/service/git"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Working tree states", func() {
+	var tmpDir string
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tmpDir
+		output, err := cmd.CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(output))
+	}
+
+	write := func(name, contents string) {
+		path := filepath.Join(tmpDir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		tmpDir = GinkgoT().TempDir()
+		git("init")
+		git("config", "user.email", "test@example.com")
+		git("config", "user.name", "Test User")
+	})
+
+	It("returns an error outside a git repository", func() {
+		_, err := ReadWorkTree(GinkgoT().TempDir())
+		Expect(err).To(MatchError(ContainSubstring("not a git working tree")))
+	})
+
+	It("tells committed, modified, untracked and ignored files apart", func() {
+		write(".gitignore", "*.log\n")
+		write("clean.txt", "clean")
+		write("changed.txt", "v1")
+		write("staged.txt", "v1")
+		git("add", ".")
+		git("commit", "-m", "initial")
+
+		write("changed.txt", "v2")
+		write("staged.txt", "v2")
+		git("add", "staged.txt")
+		write("new.txt", "new")
+		write("debug.log", "log")
+
+		tree, err := ReadWorkTree(tmpDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tree.State("clean.txt")).To(Equal(FileCommitted))
+		Expect(tree.State("changed.txt")).To(Equal(FileModified))
+		Expect(tree.State("staged.txt")).To(Equal(FileModified))
+		Expect(tree.State("new.txt")).To(Equal(FileUntracked))
+		Expect(tree.State("debug.log")).To(Equal(FileIgnored))
+	})
+
+	It("uses paths relative to a subdirectory", func() {
+		write("src/main.go", "package main")
+		write("src/changed.go", "v1")
+		git("add", ".")
+		git("commit", "-m", "initial")
+		write("src/changed.go", "v2")
+
+		tree, err := ReadWorkTree(filepath.Join(tmpDir, "src"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tree.State("main.go")).To(Equal(FileCommitted))
+		Expect(tree.State("changed.go")).To(Equal(FileModified))
+	})
+
+	It("treats tracked files as modified before the first commit", func() {
+		write("first.txt", "first")
+		git("add", "first.txt")
+
+		tree, err := ReadWorkTree(tmpDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tree.State("first.txt")).To(Equal(FileModified))
+	})
+})
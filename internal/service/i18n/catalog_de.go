@@ -0,0 +1,201 @@
+package i18n
+
+// german holds the German translations, keyed by the English message
+var german = map[string]string{
+	// run
+	"%s%s\n==============================\n   📦  Starting Backup Job    \n==============================%s\n": "%s%s\n==============================\n   📦  Sicherung wird gestartet \n==============================%s\n",
+	"%s%s❌ Error:%s --home cannot be combined with --source\n":                                                "%s%s❌ Fehler:%s --home kann nicht mit --source kombiniert werden\n",
+	"%s%s❌ Error getting home directory:%s %v\n":                                                              "%s%s❌ Fehler beim Ermitteln des Home-Verzeichnisses:%s %v\n",
+	"%s%s❌ Error getting current directory:%s %v\n":                                                           "%s%s❌ Fehler beim Ermitteln des aktuellen Verzeichnisses:%s %v\n",
+	"%sRun ID:%s %s\n":                   "%sLauf-ID:%s %s\n",
+	"  %sRun ID:%s %s\n":                 "  %sLauf-ID:%s %s\n",
+	"%sSource:%s %s\n":                   "%sQuelle:%s %s\n",
+	"Error reading config file %s: %v\n": "Fehler beim Lesen der Konfigurationsdatei %s: %v\n",
+	"Run 'go-backup init' to create a config file, or use --dest for an ad-hoc backup.": "Mit 'go-backup init' eine Konfigurationsdatei anlegen oder mit --dest eine einmalige Sicherung starten.",
+	"%sNo config file found at %s, running ad-hoc backup from flags%s\n":                "%sKeine Konfigurationsdatei unter %s gefunden, einmalige Sicherung mit den Optionen%s\n",
+	"%sUsing the default home directory includes and excludes%s\n":                      "%sStandard-Ein- und Ausschlüsse für Home-Verzeichnisse werden verwendet%s\n",
+	"%s%s❌ Error in configuration file:%s %v\n":                                         "%s%s❌ Fehler in der Konfigurationsdatei:%s %v\n",
+	"%sTimeout:%s %s\n":                                                                             "%sZeitlimit:%s %s\n",
+	"%s⚠️  Warning:%s %v\n":                                                                         "%s⚠️  Warnung:%s %v\n",
+	"%sRunning at low CPU and IO priority%s\n":                                                      "%sLäuft mit niedriger CPU- und IO-Priorität%s\n",
+	"%sUsing at most %d CPU(s)%s\n":                                                                 "%sHöchstens %d CPU(s) werden verwendet%s\n",
+	"%s🔍 Checking git status...%s\n":                                                                "%s🔍 Git-Status wird geprüft...%s\n",
+	"%s⚠️  Warning: Failed to get current branch:%s %v\n":                                           "%s⚠️  Warnung: Aktueller Branch konnte nicht ermittelt werden:%s %v\n",
+	"%sContinuing with backup anyway...%s\n":                                                        "%sSicherung wird trotzdem fortgesetzt...%s\n",
+	"%s⚠️  Warning: Current branch '%s' does not match configured branch '%s'%s\n":                  "%s⚠️  Warnung: Aktueller Branch '%s' entspricht nicht dem konfigurierten Branch '%s'%s\n",
+	"%sSkipping auto-pull. Continuing with backup...%s\n":                                           "%sAuto-Pull wird übersprungen. Sicherung wird fortgesetzt...%s\n",
+	"%s🔄 Auto-pull enabled on branch '%s'. Pulling latest changes...%s\n":                           "%s🔄 Auto-Pull auf Branch '%s' aktiviert. Neueste Änderungen werden geholt...%s\n",
+	"%s⚠️  Warning: Failed to pull latest changes:%s %v\n":                                          "%s⚠️  Warnung: Neueste Änderungen konnten nicht geholt werden:%s %v\n",
+	"%s✓ Pulled latest changes successfully.%s\n":                                                   "%s✓ Neueste Änderungen erfolgreich geholt.%s\n",
+	"%s✓ Already up-to-date.%s\n":                                                                   "%s✓ Bereits auf dem neuesten Stand.%s\n",
+	"%s⚠️  Warning: Git check failed:%s %v\n":                                                       "%s⚠️  Warnung: Git-Prüfung fehlgeschlagen:%s %v\n",
+	"%s✨ No uncommitted changes or updates detected. Backup skipped.%s\n":                           "%s✨ Keine nicht committeten Änderungen oder Updates gefunden. Sicherung übersprungen.%s\n",
+	"%sTo run backup anyway, disable git check in .backup.yaml (options.git.enable: false)%s\n":     "%sUm trotzdem zu sichern, die Git-Prüfung in .backup.yaml abschalten (options.git.enable: false)%s\n",
+	"%s⚠️  Warning: Failed to record skipped run in config -%s %v\n":                                "%s⚠️  Warnung: Übersprungener Lauf konnte nicht in der Konfiguration vermerkt werden -%s %v\n",
+	"%s⚠️  Warning: Failed to record deferred run in config -%s %v\n":                               "%s⚠️  Warnung: Verschobener Lauf konnte nicht in der Konfiguration vermerkt werden -%s %v\n",
+	"%s⚠️  Warning:%s cannot tell whether the connection is metered, backing up anyway\n":           "%s⚠️  Warnung:%s Ob die Verbindung getaktet ist, lässt sich nicht feststellen, Sicherung läuft trotzdem\n",
+	"%s⏸️  Backup skipped:%s %s\n":                                                                  "%s⏸️  Sicherung übersprungen:%s %s\n",
+	"%sUse 'go-backup resume' to end the pause, or --ignore-pause to back up now%s\n":               "%sMit 'go-backup resume' endet die Pause, --ignore-pause sichert sofort%s\n",
+	"%s🔌 Backup deferred:%s %s\n":                                                                   "%s🔌 Sicherung verschoben:%s %s\n",
+	"%sIt runs again on AC power or an unmetered connection; use --ignore-power to back up now%s\n": "%sSie läuft erneut am Netzteil oder über eine ungetaktete Verbindung; --ignore-power sichert sofort%s\n",
+	"%s✓ Uncommitted changes detected. Proceeding with backup...%s\n":                               "%s✓ Nicht committete Änderungen gefunden. Sicherung wird fortgesetzt...%s\n",
+	"%s✓ Updates pulled from remote. Proceeding with backup...%s\n":                                 "%s✓ Updates vom Remote geholt. Sicherung wird fortgesetzt...%s\n",
+	"%sUsing excludes from config:%s %v\n":                                                          "%sAusschlüsse aus der Konfiguration:%s %v\n",
+	"%sUsing default excludes:%s %v\n":                                                              "%sStandard-Ausschlüsse:%s %v\n",
+	"%sIncluding version control directories (%s)%s\n":                                              "%sVersionskontroll-Verzeichnisse werden mitgesichert (%s)%s\n",
+	"%sLeaving out hidden files except:%s %v\n":                                                     "%sVersteckte Dateien werden ausgelassen, außer:%s %v\n",
+	"%sLeaving out hidden files%s\n":                                                                "%sVersteckte Dateien werden ausgelassen%s\n",
+	"%s%s❌ Error:%s %v\n":                                                                           "%s%s❌ Fehler:%s %v\n",
+	"%sUsing includes from config:%s %v\n":                                                          "%sEinschlüsse aus der Konfiguration:%s %v\n",
+	"%s%s❌ Error in includes:%s %v\n":                                                               "%s%s❌ Fehler in includes:%s %v\n",
+	"%s%s❌ Error in noCompress:%s %v\n":                                                             "%s%s❌ Fehler in noCompress:%s %v\n",
+	"%sTags:%s %s\n":                                                                                "%sTags:%s %s\n",
+	"%sMessage:%s %s\n":                                                                             "%sNachricht:%s %s\n",
+	"%s%s❌ Error:%s --compression-level must be between 1 (fastest) and 9 (smallest)\n":             "%s%s❌ Fehler:%s --compression-level muss zwischen 1 (am schnellsten) und 9 (am kleinsten) liegen\n",
+	"%s⚠️  Warning: Destination '%s' is inside the source, excluding it from the backup%s\n":        "%s⚠️  Warnung: Ziel '%s' liegt in der Quelle und wird von der Sicherung ausgeschlossen%s\n",
+	"%s%s❌ Error:%s --split-by-dir cannot be combined with --files-from\n":                          "%s%s❌ Fehler:%s --split-by-dir kann nicht mit --files-from kombiniert werden\n",
+	"%s%s❌ Error:%s --split-by-dir needs directory targets, but %s is a file target\n":              "%s%s❌ Fehler:%s --split-by-dir braucht Verzeichnisziele, aber %s ist ein Dateiziel\n",
+	"%sPart:%s %s\n": "%sTeil:%s %s\n",
+	"%s⚠️  Nothing to back up: the file list is empty%s\n":                             "%s⚠️  Nichts zu sichern: die Dateiliste ist leer%s\n",
+	"%sFiles:%s %d listed in %s\n":                                                     "%sDateien:%s %d aufgeführt in %s\n",
+	"Please specify a recipient using --encrypt-to flag or in the config file":         "Bitte einen Empfänger mit --encrypt-to oder in der Konfigurationsdatei angeben",
+	"%sBackup name:%s %s\n":                                                            "%sName der Sicherung:%s %s\n",
+	"%sTemporary backup file:%s %s\n":                                                  "%sTemporäre Sicherungsdatei:%s %s\n",
+	"%sAnalyzing files for potential size issues...%s\n":                               "%sDateien werden auf mögliche Größenprobleme geprüft...%s\n",
+	"%s%s⚠️ Warning: Unable to analyze file sizes:%s %v\n":                             "%s%s⚠️ Warnung: Dateigrößen konnten nicht geprüft werden:%s %v\n",
+	"%s%s⚠️ Warning: %d files exceed the recommended size limit for tar archives:%s\n": "%s%s⚠️ Warnung: %d Dateien überschreiten die empfohlene Größe für tar-Archive:%s\n",
+	"  - ... and %d more\n":                                                            "  - ... und %d weitere\n",
+	"%sConsider excluding these files or using the --split option for large files%s\n": "%sDiese Dateien ausschließen oder für große Dateien die Option --split verwenden%s\n",
+	"%sContinue with backup anyway? [y/N]:%s ":                                         "%sSicherung trotzdem fortsetzen? [y/N]:%s ",
+	"Backup aborted.":                                     "Sicherung abgebrochen.",
+	"%sScanning for secrets...%s\n":                       "%sNach Geheimnissen wird gesucht...%s\n",
+	"%s%s⚠️  Warning: Unable to scan for secrets:%s %v\n": "%s%s⚠️  Warnung: Suche nach Geheimnissen fehlgeschlagen:%s %v\n",
+	"%s%s🔑 Warning: %d files look like secrets and would be stored unencrypted in %s:%s\n":                                   "%s%s🔑 Warnung: %d Dateien sehen nach Geheimnissen aus und würden unverschlüsselt in %s gespeichert:%s\n",
+	"%sEncrypt the remote targets, or exclude these files%s\n":                                                               "%sDie entfernten Ziele verschlüsseln oder diese Dateien ausschließen%s\n",
+	"%sConfig file %s already exists, --save-config has no effect%s\n":                                                       "%sKonfigurationsdatei %s existiert bereits, --save-config hat keine Wirkung%s\n",
+	"%sScanning for changes since the last backup...%s\n":                                                                    "%sÄnderungen seit der letzten Sicherung werden gesucht...%s\n",
+	"%sChecksums:%s %d cached, %d read\n":                                                                                    "%sPrüfsummen:%s %d aus dem Cache, %d gelesen\n",
+	"%sNo file contents changed: recording metadata snapshots without an archive%s\n":                                        "%sKeine Dateiinhalte geändert: Metadaten-Snapshots werden ohne Archiv gespeichert%s\n",
+	"%sCreating %d archive variants in one pass...%s\n":                                                                      "%s%d Archivvarianten werden in einem Durchgang erstellt...%s\n",
+	"%s%s❌ Error creating backup archive:%s %v\n":                                                                            "%s%s❌ Fehler beim Erstellen des Sicherungsarchivs:%s %v\n",
+	"%sSuggestion: Use --exclude to skip large files or consider using a different backup strategy for very large files%s\n": "%sVorschlag: Große Dateien mit --exclude überspringen oder für sehr große Dateien eine andere Sicherungsstrategie wählen%s\n",
+	"%s%s⚠️  Warning: Failed to update the checksum cache:%s %v\n":                                                           "%s%s⚠️  Warnung: Prüfsummen-Cache konnte nicht aktualisiert werden:%s %v\n",
+	"%s%s❌ Error writing backup manifest:%s %v\n":                                                                            "%s%s❌ Fehler beim Schreiben des Sicherungsmanifests:%s %v\n",
+	"%s🔒 Encrypting backup with GPG for recipient:%s %s\n":                                                                   "%s🔒 Sicherung wird mit GPG verschlüsselt für Empfänger:%s %s\n",
+	"%sEncrypting %d chunks separately%s\n":                                                                                  "%sVerschlüssele %d Blöcke einzeln%s\n",
+	"%sKey is on smartcard %s: encrypting needs no PIN, restoring will need the card%s\n":                                    "%sSchlüssel liegt auf Smartcard %s: Verschlüsseln braucht keine PIN, Wiederherstellen braucht die Karte%s\n",
+	"%s%s❌ Error encrypting backup:%s %v\n":                                                                                  "%s%s❌ Fehler beim Verschlüsseln der Sicherung:%s %v\n",
+	"%s%s❌ Error encrypting backup manifest:%s %v\n":                                                                         "%s%s❌ Fehler beim Verschlüsseln des Sicherungsmanifests:%s %v\n",
+	"\n%s%sProcessing backup destinations:%s\n":                                                                              "\n%s%sSicherungsziele werden bearbeitet:%s\n",
+	"\n%s→ Destination:%s %s":                                                                                                "\n%s→ Ziel:%s %s",
+	" %s(file)%s":                                                                                                            " %s(Datei)%s",
+	"  %s⚠️  Skipping: directory does not exist%s\n":                                                                         "  %s⚠️  Übersprungen: Verzeichnis existiert nicht%s\n",
+	"  %s⏭️  Skipping: fallback target, the backup went to %s%s\n":                                                           "  %s⏭️  Übersprungen: Ausweichziel, die Sicherung ging an %s%s\n",
+	"  %s↪️  Fallback:%s no other target received the backup; use 'go-backup replicate' once they are back%s\n":              "  %s↪️  Ausweichziel:%s kein anderes Ziel hat die Sicherung erhalten; 'go-backup replicate' ausführen, sobald sie wieder erreichbar sind%s\n",
+	"  %s❌ Error: failed to create destination directory -%s %v\n":                                                           "  %s❌ Fehler: Zielverzeichnis konnte nicht angelegt werden -%s %v\n",
+	"  %s❌ Error: backup file already exists -%s %s\n":                                                                       "  %s❌ Fehler: Sicherungsdatei existiert bereits -%s %s\n",
+	"  %sUse --overwrite-existing to replace it%s\n":                                                                         "  %sMit --overwrite-existing ersetzen%s\n",
+	"  %s⚠️  Warning: Failed to lift protection of existing backup -%s %v\n":                                                 "  %s⚠️  Warnung: Schutz der vorhandenen Sicherung konnte nicht aufgehoben werden -%s %v\n",
+	"  %sCopying file:%s %s\n":                                                                                               "  %sDatei wird kopiert:%s %s\n",
+	"  %s❌ Error: failed to copy backup -%s %v\n":                                                                            "  %s❌ Fehler: Sicherung konnte nicht kopiert werden -%s %v\n",
+	"  %s✅ Success:%s backup copied successfully\n":                                                                          "  %s✅ Erfolg:%s Sicherung erfolgreich kopiert\n",
+	"  %s✅ Success:%s backup moved into place (same file system, checksum verified)\n":                                       "  %s✅ Erfolg:%s Sicherung verschoben (gleiches Dateisystem, Prüfsumme bestätigt)\n",
+	"  %s✅ Success:%s backup copied (read back, checksum verified)\n":                                                        "  %s✅ Erfolg:%s Sicherung kopiert (zurückgelesen, Prüfsumme bestätigt)\n",
+	"  %s✅ Success:%s backup cloned (copy-on-write, checksum verified)\n":                                                    "  %s✅ Erfolg:%s Sicherung geklont (Copy-on-Write, Prüfsumme bestätigt)\n",
+	"  %s⚠️  Warning: Failed to protect backup -%s %v\n":                                                                     "  %s⚠️  Warnung: Sicherung konnte nicht geschützt werden -%s %v\n",
+	"  %s🔒 Protected:%s backup is immutable\n":                                                                               "  %s🔒 Geschützt:%s Sicherung ist unveränderlich\n",
+	"  %s🔒 Protected:%s backup is read-only (the immutable flag needs root or a supporting file system)\n":                   "  %s🔒 Geschützt:%s Sicherung ist schreibgeschützt (das Immutable-Flag braucht root oder ein passendes Dateisystem)\n",
+	"  %s⚠️  Warning: Failed to update %s -%s %v\n":                                                                          "  %s⚠️  Warnung: %s konnte nicht aktualisiert werden -%s %v\n",
+	"  %s🔑 Checksum:%s Updated %s\n":                                                                                         "  %s🔑 Prüfsumme:%s %s aktualisiert\n",
+	"  %s⚠️  Warning: Failed to copy manifest -%s %v\n":                                                                      "  %s⚠️  Warnung: Manifest konnte nicht kopiert werden -%s %v\n",
+	"  %s⚠️  Warning: Failed to write checksum file -%s %v\n":                                                                "  %s⚠️  Warnung: Prüfsummendatei konnte nicht geschrieben werden -%s %v\n",
+	"  %s📋 Manifest:%s %s\n":                                                                                                 "  %s📋 Manifest:%s %s\n",
+	"  %s⚠️  Warning: Failed to create parity files -%s %v\n":                                                                "  %s⚠️  Warnung: Paritätsdateien konnten nicht erstellt werden -%s %v\n",
+	"  %s🛟 Parity:%s %d file(s) with %d%% redundancy\n":                                                                      "  %s🛟 Parität:%s %d Datei(en) mit %d%% Redundanz\n",
+	"  %s⚠️  Warning: Failed to cleanup old backups -%s %v\n":                                                                "  %s⚠️  Warnung: Alte Sicherungen konnten nicht aufgeräumt werden -%s %v\n",
+	"  %s🔄 Rotation:%s Keeping latest %d backups\n":                                                                          "  %s🔄 Rotation:%s Die neuesten %d Sicherungen werden behalten\n",
+	"  %s📄 File target:%s No rotation applied (single file backup)\n":                                                        "  %s📄 Dateiziel:%s Keine Rotation (Sicherung in eine einzelne Datei)\n",
+	"  %s⚠️  Warning: Failed to update backup history in config -%s %v\n":                                                    "  %s⚠️  Warnung: Sicherungsverlauf in der Konfiguration konnte nicht aktualisiert werden -%s %v\n",
+	"  %s📝 History:%s Updated backup history in %s\n":                                                                        "  %s📝 Verlauf:%s Sicherungsverlauf in %s aktualisiert\n",
+	"  %s⚠️  Warning: Failed to copy config file to destination -%s %v\n":                                                    "  %s⚠️  Warnung: Konfigurationsdatei konnte nicht ins Ziel kopiert werden -%s %v\n",
+	"  %s📄 Config:%s Copied config file with usage info to %s\n":                                                             "  %s📄 Konfiguration:%s Konfigurationsdatei mit Nutzungshinweisen nach %s kopiert\n",
+	"%s%s⚠️  Warning: Failed to update global backup registry:%s %v\n":                                                       "%s%s⚠️  Warnung: Globales Sicherungsregister konnte nicht aktualisiert werden:%s %v\n",
+	"\n%s%s🎉 Backup completed successfully!%s\n":                                                                             "\n%s%s🎉 Sicherung erfolgreich abgeschlossen!%s\n",
+	"\n%s%s❌ Backup failed:%s %d target(s) would exceed their quota\n":                                                       "\n%s%s❌ Sicherung fehlgeschlagen:%s %d Ziel(e) würden ihr Kontingent überschreiten\n",
+	"  %s⚠️  Warning: backups will use %s of the %s quota (%d%%)%s\n":                                                        "  %s⚠️  Warnung: Sicherungen belegen dann %s des Kontingents von %s (%d%%)%s\n",
+	"  %s⚠️  Warning: backups will use %s, over the %s quota%s\n":                                                            "  %s⚠️  Warnung: Sicherungen belegen dann %s, mehr als das Kontingent von %s%s\n",
+	"  %sLower maxBackups or raise the quota; --strict fails targets over their quota%s\n":                                   "  %smaxBackups senken oder das Kontingent erhöhen; mit --strict schlagen Ziele über ihrem Kontingent fehl%s\n",
+	"  %s❌ Error: backups would use %s, over the %s quota%s\n":                                                               "  %s❌ Fehler: Sicherungen würden %s belegen, mehr als das Kontingent von %s%s\n",
+	"%s⚠️  Warning: Failed to write to the system log:%s %v\n":                                                               "%s⚠️  Warnung: Schreiben ins Systemprotokoll fehlgeschlagen:%s %v\n",
+	"%s🧩 Step:%s %s (%s)\n":                                                                                                  "%s🧩 Schritt:%s %s (%s)\n",
+	"  %s❌ Error: failed to upload backup -%s %v\n":                                                                          "  %s❌ Fehler: Sicherung konnte nicht hochgeladen werden -%s %v\n",
+	"  %sThe target is append-only; backups on it are never replaced%s\n":                                                    "  %sDas Ziel ist append-only; Sicherungen darauf werden nie ersetzt%s\n",
+	"  %sUploading file:%s %s\n":                                                                                             "  %sDatei wird hochgeladen:%s %s\n",
+	"  %s✅ Success:%s backup uploaded successfully\n":                                                                        "  %s✅ Erfolg:%s Sicherung erfolgreich hochgeladen\n",
+	"  %s⚠️  Warning: Failed to upload manifest -%s %v\n":                                                                    "  %s⚠️  Warnung: Manifest konnte nicht hochgeladen werden -%s %v\n",
+	"  %s⚠️  Warning: Failed to upload parity files -%s %v\n":                                                                "  %s⚠️  Warnung: Paritätsdateien konnten nicht hochgeladen werden -%s %v\n",
+	"  %s🔒 Object lock:%s %s until %s\n":                                                                                     "  %s🔒 Object Lock:%s %s bis %s\n",
+	"  %s🔄 Rotation:%s Append-only target; expire old backups with a bucket lifecycle rule\n":                                "  %s🔄 Rotation:%s Append-only-Ziel; alte Sicherungen per Bucket-Lifecycle-Regel ablaufen lassen\n",
+	"  %s🔄 Rotation:%s Append-only target; expire old backups on the server side\n":                                          "  %s🔄 Rotation:%s Append-only-Ziel; alte Sicherungen auf dem Server ablaufen lassen\n",
+	"  Deleted old backup: %s\n":                                                                                             "  Alte Sicherung gelöscht: %s\n",
+	"  Warning: Failed to delete associated file %s: %v\n":                                                                   "  Warnung: Zugehörige Datei %s konnte nicht gelöscht werden: %v\n",
+	"  Deleted associated file: %s\n":                                                                                        "  Zugehörige Datei gelöscht: %s\n",
+	"  %s🔄 Rotation:%s Not applied to remote targets\n":                                                                      "  %s🔄 Rotation:%s Bei entfernten Zielen nicht angewendet\n",
+	"  %s⚠️  Warning: Failed to upload config file -%s %v\n":                                                                 "  %s⚠️  Warnung: Konfigurationsdatei konnte nicht hochgeladen werden -%s %v\n",
+	"  %s📄 Config:%s Uploaded config file with usage info to %s\n":                                                           "  %s📄 Konfiguration:%s Konfigurationsdatei mit Nutzungshinweisen nach %s hochgeladen\n",
+	"\n%s%s❌ Backup timed out after %s%s\n":                                                                                  "\n%s%s❌ Zeitlimit der Sicherung nach %s überschritten%s\n",
+	"%s⚠️  Warning: Failed to record the timeout in %s:%s %v\n":                                                              "%s⚠️  Warnung: Zeitüberschreitung konnte nicht in %s vermerkt werden:%s %v\n",
+	"  %s❌ Error: failed to write metadata snapshot -%s %v\n":                                                                "  %s❌ Fehler: Metadaten-Snapshot konnte nicht geschrieben werden -%s %v\n",
+	"  %s✅ Success:%s file contents unchanged since %s\n":                                                                    "  %s✅ Erfolg:%s Dateiinhalte seit %s unverändert\n",
+	"  %s📋 Metadata snapshot:%s %s\n":                                                                                        "  %s📋 Metadaten-Snapshot:%s %s\n",
+	"\n%s%s📊 Incompressible files:%s\n":                                                                                      "\n%s%s📊 Nicht komprimierbare Dateien:%s\n",
+	"  %s%s%s: %d file(s), %s, %s compressing, %.1f%% saved\n":                                                               "  %s%s%s: %d Datei(en), %s, %s Komprimierung, %.1f%% gespart\n",
+	"%sSuggestion: store these without compression by adding to .backup.yaml:%s\n":                                           "%sVorschlag: diese ohne Komprimierung speichern, mit folgendem Eintrag in .backup.yaml:%s\n",
+	"\n%s%s⚠️  Warnings (%d):%s\n":                                                                                           "\n%s%s⚠️  Warnungen (%d):%s\n",
+	"  %s%d path(s) %s:%s\n":                                                                                                 "  %s%d Pfad(e) %s:%s\n",
+	"    - ... and %d more (listed in the manifest)\n":                                                                       "    - ... und %d weitere (im Manifest aufgeführt)\n",
+	"%sUse options.onError: fail in .backup.yaml to fail the backup instead of skipping files%s\n":                           "%sMit options.onError: fail in .backup.yaml schlägt die Sicherung fehl, statt Dateien zu überspringen%s\n",
+	"could not be read and were skipped":                                                                                     "konnten nicht gelesen werden und wurden übersprungen",
+	"vanished during the backup and were skipped":                                                                            "verschwanden während der Sicherung und wurden übersprungen",
+	"are symlink loops": "sind Symlink-Schleifen",
+	"are sockets, pipes or devices and were skipped":                   "sind Sockets, Pipes oder Geräte und wurden übersprungen",
+	"exceed the recommended size for tar archives":                     "überschreiten die empfohlene Größe für tar-Archive",
+	"look like secrets and were stored unencrypted in a remote target": "sehen nach Geheimnissen aus und wurden unverschlüsselt in einem entfernten Ziel gespeichert",
+
+	// run-all
+	"%s%s\n======================================\n   📦  Running All Tracked Backups   \n======================================%s\n\n": "%s%s\n======================================\n   📦  Alle registrierten Sicherungen   \n======================================%s\n\n",
+	"%sHint:%s Create ~/.config/go-backup/registry.yaml to track backup locations.\n":                                                  "%sHinweis:%s ~/.config/go-backup/registry.yaml anlegen, um Sicherungsorte zu registrieren.\n",
+	"%sSee docs/global-registry.md for more information.%s\n":                                                                          "%sMehr dazu in docs/global-registry.md.%s\n",
+	"%s%s⚠️  No backup locations found in global registry.%s\n":                                                                        "%s%s⚠️  Keine Sicherungsorte im globalen Register gefunden.%s\n",
+	"%sRun backups in directories with .backup.yaml to register them.%s\n":                                                             "%sSicherungen in Verzeichnissen mit .backup.yaml ausführen, um sie zu registrieren.%s\n",
+	"%sFound %d backup location(s) in registry:%s\n\n":                                                                                 "%s%d Sicherungsort(e) im Register gefunden:%s\n\n",
+	"%s%s⚠️  Stopping: a location with priority %d failed, so lower priorities are not run.%s\n":                                       "%s%s⚠️  Abbruch: ein Ort mit Priorität %d ist fehlgeschlagen, niedrigere Prioritäten werden nicht ausgeführt.%s\n",
+	"%s── Priority %d ──%s\n":                                                                                                          "%s── Priorität %d ──%s\n",
+	"  %s⏸️  Skipped:%s disabled in registry\n\n":                                                                                      "  %s⏸️  Übersprungen:%s im Register deaktiviert\n\n",
+	"  %s%s❌ Error:%s %v\n": "  %s%s❌ Fehler:%s %v\n",
+	"\n%s%s⚠️  Stopping due to error. Use --continue to skip errors.%s\n":        "\n%s%s⚠️  Abbruch wegen eines Fehlers. Mit --continue werden Fehler übersprungen.%s\n",
+	"  %s⏭️  Skipped:%s not due yet (%s schedule, last run %s)\n\n":              "  %s⏭️  Übersprungen:%s noch nicht fällig (Zeitplan %s, letzter Lauf %s)\n\n",
+	"  %s⏸️  Skipped:%s %s\n\n":                                                  "  %s⏸️  Übersprungen:%s %s\n\n",
+	"  %s⏭️  Skipped:%s in blackout window %s\n\n":                               "  %s⏭️  Übersprungen:%s im Sperrfenster %s\n\n",
+	"  %s🔌 Deferred:%s %s\n":                                                     "  %s🔌 Verschoben:%s %s\n",
+	"%s⏳ Waiting %s before starting (jitter up to %s)%s\n\n":                     "%s⏳ Warte %s vor dem Start (zufällige Verzögerung bis %s)%s\n\n",
+	"  %s%s❌ Error:%s Directory does not exist\n":                                "  %s%s❌ Fehler:%s Verzeichnis existiert nicht\n",
+	"  %s%s❌ Error:%s %s not found in directory\n":                               "  %s%s❌ Fehler:%s %s nicht im Verzeichnis gefunden\n",
+	"  %s⚠️  Warning: Failed to create log file, showing output instead:%s %v\n": "  %s⚠️  Warnung: Logdatei konnte nicht angelegt werden, Ausgabe wird stattdessen angezeigt:%s %v\n",
+	"  %s⚠️  Warning: Failed to remove old logs:%s %v\n":                         "  %s⚠️  Warnung: Alte Logs konnten nicht entfernt werden:%s %v\n",
+	"  %s%s❌ Error:%s Backup failed: %v (%s)\n":                                  "  %s%s❌ Fehler:%s Sicherung fehlgeschlagen: %v (%s)\n",
+	"  %sLog:%s %s\n":                                   "  %sLog:%s %s\n",
+	"  %s✅ Success%s (%s)\n":                            "  %s✅ Erfolg%s (%s)\n",
+	"  %s❌ Restore rehearsal failed:%s %v\n":            "  %s❌ Wiederherstellungsprobe fehlgeschlagen:%s %v\n",
+	"  %s🧪 Restore rehearsal passed%s\n":                "  %s🧪 Wiederherstellungsprobe bestanden%s\n",
+	"             Summary\n":                            "          Zusammenfassung\n",
+	"%s✅ Successful:%s %d\n":                            "%s✅ Erfolgreich:%s %d\n",
+	"%s❌ Failed:%s %d\n":                                "%s❌ Fehlgeschlagen:%s %d\n",
+	"%s⚠️  Missing:%s %d\n":                             "%s⚠️  Fehlend:%s %d\n",
+	"%s⏭️  Skipped:%s %d\n":                             "%s⏭️  Übersprungen:%s %d\n",
+	"%s🔌 Deferred:%s %d\n":                              "%s🔌 Verschoben:%s %d\n",
+	"%s🧪 Failed rehearsals:%s %d\n":                     "%s🧪 Fehlgeschlagene Proben:%s %d\n",
+	"%s📊 Total:%s %d\n":                                 "%s📊 Gesamt:%s %d\n",
+	"%s📣 Summary sent to %s%s\n":                        "%s📣 Zusammenfassung an %s gesendet%s\n",
+	"%s⚠️  Warning: Failed to send the summary:%s %v\n": "%s⚠️  Warnung: Zusammenfassung konnte nicht gesendet werden:%s %v\n",
+}
@@ -0,0 +1,82 @@
+// Package i18n translates the messages go-backup prints. Messages are looked up by their
+// English format string, so a message without a translation is printed in English.
+package i18n
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// DefaultLanguage is the language of the messages in the code
+const DefaultLanguage = "en"
+
+// catalogs holds the translations of each language besides English, keyed by the English message
+var catalogs = map[string]map[string]string{
+	"de": german,
+}
+
+// language is the language messages are translated to (see SetLanguage)
+var language = DefaultLanguage
+
+// Languages returns the supported language codes, English first
+func Languages() []string {
+	languages := []string{DefaultLanguage}
+	for lang := range catalogs {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages[1:])
+	return languages
+}
+
+// Catalog returns the translations of a language keyed by the English message, or nil for
+// English and unsupported languages
+func Catalog(lang string) map[string]string {
+	return catalogs[lang]
+}
+
+// Detect returns the language to use: the configured one if set, or else the locale of the
+// environment from LC_ALL, LC_MESSAGES or LANG, the first one set deciding as for other
+// programs. Unsupported languages and the C locale give English.
+func Detect(configured string) string {
+	locale := configured
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale != "" {
+			break
+		}
+		locale = os.Getenv(name)
+	}
+	return Normalize(locale)
+}
+
+// Normalize returns the supported language of a locale like de_DE.UTF-8, de-AT or de, or
+// DefaultLanguage if the locale's language is not supported
+func Normalize(locale string) string {
+	lang := strings.ToLower(locale)
+	if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+		lang = lang[:i]
+	}
+	if _, ok := catalogs[lang]; ok {
+		return lang
+	}
+	return DefaultLanguage
+}
+
+// SetLanguage sets the language of the messages returned by T. Unsupported languages give English.
+func SetLanguage(lang string) {
+	language = Normalize(lang)
+}
+
+// Language returns the language of the messages returned by T
+func Language() string {
+	return language
+}
+
+// T returns the translation of an English message, or the message itself if it has none.
+// Format strings keep their verbs in the same order, so T can wrap the format of Printf.
+func T(message string) string {
+	if translated, ok := catalogs[language][message]; ok {
+		return translated
+	}
+	return message
+}
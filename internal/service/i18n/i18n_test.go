@@ -0,0 +1,87 @@
+package i18n_test
+
+import (
+	"regexp"
+
+	. "github.com/kennycyb/go-backup/internal/service/i18n"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// formatVerbs matches the verbs of a format string, %% included
+var formatVerbs = regexp.MustCompile(`%[-+# 0]*[0-9*]*(\.[0-9*]*)?[a-zA-Z%]`)
+
+var _ = Describe("Messages", func() {
+	AfterEach(func() {
+		SetLanguage(DefaultLanguage)
+	})
+
+	Describe("Normalize", func() {
+		It("should take the language of a locale", func() {
+			Expect(Normalize("de_DE.UTF-8")).To(Equal("de"))
+			Expect(Normalize("de-AT")).To(Equal("de"))
+			Expect(Normalize("DE")).To(Equal("de"))
+			Expect(Normalize("de_CH@euro")).To(Equal("de"))
+		})
+
+		It("should fall back to English", func() {
+			Expect(Normalize("")).To(Equal("en"))
+			Expect(Normalize("C")).To(Equal("en"))
+			Expect(Normalize("POSIX")).To(Equal("en"))
+			Expect(Normalize("fr_FR.UTF-8")).To(Equal("en"))
+		})
+	})
+
+	Describe("Detect", func() {
+		BeforeEach(func() {
+			GinkgoT().Setenv("LC_ALL", "")
+			GinkgoT().Setenv("LC_MESSAGES", "")
+			GinkgoT().Setenv("LANG", "de_DE.UTF-8")
+		})
+
+		It("should prefer the configured language", func() {
+			Expect(Detect("en")).To(Equal("en"))
+		})
+
+		It("should use the first locale variable set", func() {
+			Expect(Detect("")).To(Equal("de"))
+
+			GinkgoT().Setenv("LC_MESSAGES", "C")
+			Expect(Detect("")).To(Equal("en"))
+
+			GinkgoT().Setenv("LC_ALL", "de_AT.UTF-8")
+			Expect(Detect("")).To(Equal("de"))
+		})
+	})
+
+	Describe("T", func() {
+		It("should translate to the language set", func() {
+			SetLanguage("de")
+			Expect(Language()).To(Equal("de"))
+			Expect(T("Backup aborted.")).To(Equal("Sicherung abgebrochen."))
+		})
+
+		It("should return messages without a translation as they are", func() {
+			SetLanguage("de")
+			Expect(T("not a catalog message")).To(Equal("not a catalog message"))
+
+			SetLanguage(DefaultLanguage)
+			Expect(T("Backup aborted.")).To(Equal("Backup aborted."))
+		})
+	})
+
+	Describe("Catalogs", func() {
+		It("should list English first", func() {
+			Expect(Languages()).To(Equal([]string{"en", "de"}))
+		})
+
+		It("should keep the format verbs of each message in order", func() {
+			for _, lang := range Languages()[1:] {
+				for message, translated := range Catalog(lang) {
+					Expect(formatVerbs.FindAllString(translated, -1)).To(Equal(formatVerbs.FindAllString(message, -1)),
+						"%s translation of %q", lang, message)
+				}
+			}
+		})
+	})
+})
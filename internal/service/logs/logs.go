@@ -0,0 +1,158 @@
+// Package logs stores the captured output of backup runs, one file per run and location
+package logs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+)
+
+// DefaultKeepLogs is how many log files are kept per location
+const DefaultKeepLogs = 30
+
+// logTimeFormat names log files by the time the run started
+const logTimeFormat = "20060102-150405"
+
+// LogFile is a captured run log
+type LogFile struct {
+	Path string
+	Time time.Time // When the run started
+}
+
+// LogDir returns the directory holding all run logs: logs in the state directory,
+// $XDG_STATE_HOME/go-backup/logs or ~/.local/state/go-backup/logs
+func LogDir() (string, error) {
+	stateDir, err := configService.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "logs"), nil
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// LocationDirName returns the log subdirectory name for a backup location.
+// It starts with the directory name for readability and ends with a short hash of the
+// full path, so projects with the same name in different places never share logs.
+func LocationDirName(location string) string {
+	name := unsafeNameChars.ReplaceAllString(filepath.Base(location), "_")
+	sum := sha256.Sum256([]byte(location))
+	return name + "-" + hex.EncodeToString(sum[:])[:8]
+}
+
+// locationLogDir returns the directory holding the logs of one location
+func locationLogDir(location string) (string, error) {
+	logDir, err := LogDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(logDir, LocationDirName(location)), nil
+}
+
+// CreateLog creates the log file for a run of the location started at the given time.
+// The caller must close the returned file.
+func CreateLog(location string, started time.Time) (*os.File, error) {
+	dir, err := locationLogDir(location)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	base := started.Format(logTimeFormat)
+	path := filepath.Join(dir, base+".log")
+	for seq := 2; ; seq++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			return file, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create log file: %w", err)
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s_%d.log", base, seq))
+	}
+}
+
+// ListLogs returns the logs of a location, newest first.
+// Returns an empty list if the location has no logs.
+func ListLogs(location string) ([]LogFile, error) {
+	dir, err := locationLogDir(location)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []LogFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log directory: %w", err)
+	}
+
+	logs := []LogFile{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".log") {
+			continue
+		}
+		stamp := strings.SplitN(strings.TrimSuffix(name, ".log"), "_", 2)[0]
+		started, err := time.ParseInLocation(logTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		logs = append(logs, LogFile{Path: filepath.Join(dir, name), Time: started})
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		if logs[i].Time.Equal(logs[j].Time) {
+			return logs[i].Path > logs[j].Path
+		}
+		return logs[i].Time.After(logs[j].Time)
+	})
+	return logs, nil
+}
+
+// PruneLogs deletes all but the newest keep logs of a location
+func PruneLogs(location string, keep int) error {
+	logs, err := ListLogs(location)
+	if err != nil {
+		return err
+	}
+	if len(logs) <= keep {
+		return nil
+	}
+
+	for _, log := range logs[keep:] {
+		if err := os.Remove(log.Path); err != nil {
+			return fmt.Errorf("failed to remove old log %s: %w", log.Path, err)
+		}
+	}
+	return nil
+}
+
+// TailLines returns the last n lines of a log file, or all lines if n is 0 or less
+func TailLines(path string, n int) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log file: %w", err)
+	}
+
+	content := strings.TrimRight(string(data), "\n")
+	if content == "" {
+		return []string{}, nil
+	}
+	lines := strings.Split(content, "\n")
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
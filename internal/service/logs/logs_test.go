@@ -0,0 +1,129 @@
+package logs_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/kennycyb/go-backup/internal/service/logs"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Logs", func() {
+	var stateDir string
+
+	BeforeEach(func() {
+		var err error
+		stateDir, err = os.MkdirTemp("", "logs-test")
+		Expect(err).NotTo(HaveOccurred())
+		GinkgoT().Setenv("XDG_STATE_HOME", stateDir)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(stateDir)
+	})
+
+	Describe("LogDir", func() {
+		It("should use XDG_STATE_HOME", func() {
+			dir, err := LogDir()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dir).To(Equal(filepath.Join(stateDir, "go-backup", "logs")))
+		})
+
+		It("should default to ~/.local/state", func() {
+			GinkgoT().Setenv("XDG_STATE_HOME", "")
+			GinkgoT().Setenv("HOME", stateDir)
+			dir, err := LogDir()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dir).To(Equal(filepath.Join(stateDir, ".local", "state", "go-backup", "logs")))
+		})
+	})
+
+	Describe("LocationDirName", func() {
+		It("should keep projects with the same name apart", func() {
+			first := LocationDirName("/home/me/work/project")
+			second := LocationDirName("/home/me/personal/project")
+			Expect(first).To(HavePrefix("project-"))
+			Expect(second).To(HavePrefix("project-"))
+			Expect(first).NotTo(Equal(second))
+		})
+
+		It("should replace characters that are awkward in file names", func() {
+			Expect(LocationDirName("/home/me/my project")).To(HavePrefix("my_project-"))
+		})
+	})
+
+	Describe("CreateLog and ListLogs", func() {
+		It("should list logs newest first", func() {
+			location := "/home/me/project"
+			started := time.Date(2025, 5, 20, 12, 0, 0, 0, time.Local)
+			for i := 0; i < 3; i++ {
+				file, err := CreateLog(location, started.Add(time.Duration(i)*time.Hour))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(file.Close()).To(Succeed())
+			}
+
+			logs, err := ListLogs(location)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logs).To(HaveLen(3))
+			Expect(logs[0].Time).To(Equal(started.Add(2 * time.Hour)))
+			Expect(logs[2].Time).To(Equal(started))
+		})
+
+		It("should not overwrite a log from a run started in the same second", func() {
+			location := "/home/me/project"
+			started := time.Date(2025, 5, 20, 12, 0, 0, 0, time.Local)
+			first, err := CreateLog(location, started)
+			Expect(err).NotTo(HaveOccurred())
+			first.Close()
+			second, err := CreateLog(location, started)
+			Expect(err).NotTo(HaveOccurred())
+			second.Close()
+
+			Expect(second.Name()).NotTo(Equal(first.Name()))
+			Expect(strings.HasSuffix(second.Name(), "_2.log")).To(BeTrue())
+
+			logs, err := ListLogs(location)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logs).To(HaveLen(2))
+			Expect(logs[0].Path).To(Equal(second.Name()))
+		})
+
+		It("should return an empty list for a location without logs", func() {
+			logs, err := ListLogs("/home/me/never-run")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logs).To(BeEmpty())
+		})
+	})
+
+	Describe("PruneLogs", func() {
+		It("should keep only the newest logs", func() {
+			location := "/home/me/project"
+			started := time.Date(2025, 5, 20, 12, 0, 0, 0, time.Local)
+			for i := 0; i < 5; i++ {
+				file, err := CreateLog(location, started.Add(time.Duration(i)*time.Minute))
+				Expect(err).NotTo(HaveOccurred())
+				file.Close()
+			}
+
+			Expect(PruneLogs(location, 2)).To(Succeed())
+
+			logs, err := ListLogs(location)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logs).To(HaveLen(2))
+			Expect(logs[1].Time).To(Equal(started.Add(3 * time.Minute)))
+		})
+	})
+
+	Describe("TailLines", func() {
+		It("should return the last lines of a log", func() {
+			path := filepath.Join(stateDir, "run.log")
+			Expect(os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0644)).To(Succeed())
+
+			Expect(TailLines(path, 2)).To(Equal([]string{"two", "three"}))
+			Expect(TailLines(path, 0)).To(Equal([]string{"one", "two", "three"}))
+		})
+	})
+})
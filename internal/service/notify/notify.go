@@ -0,0 +1,199 @@
+// Package notify sends the summary of a run-all to a webhook, as one message for all locations
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/kennycyb/go-backup/internal/service/compress"
+)
+
+// DefaultTimeout is how long sending the summary may take, so a dead endpoint does not hold up a scheduled run
+const DefaultTimeout = 10 * time.Second
+
+// Outcomes of a location in the summary
+const (
+	StatusSuccess  = "success"
+	StatusFailed   = "failed"
+	StatusMissing  = "missing"  // The directory or its config file does not exist
+	StatusSkipped  = "skipped"  // Disabled, or its schedule is not due yet
+	StatusDeferred = "deferred" // Put off while on battery or a metered connection
+)
+
+// LocationResult is how the backup of one location went
+type LocationResult struct {
+	Location        string
+	Status          string
+	RunID           string        // ID of the location's run, as in its log; empty for locations that did not run
+	Reason          string        // Why it failed or was skipped
+	Duration        time.Duration // Zero for locations that did not run
+	Size            int64         // Size of the backup written, if known
+	RehearsalFailed bool          // The restore rehearsal after the backup failed
+}
+
+// Summary is the outcome of a whole run-all
+type Summary struct {
+	Host       string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Locations  []LocationResult
+}
+
+// Count returns how many locations had the status
+func (s *Summary) Count(status string) int {
+	count := 0
+	for _, location := range s.Locations {
+		if location.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// FailedRehearsals returns how many restore rehearsals failed
+func (s *Summary) FailedRehearsals() int {
+	count := 0
+	for _, location := range s.Locations {
+		if location.RehearsalFailed {
+			count++
+		}
+	}
+	return count
+}
+
+// Failed reports whether any location failed, was missing or failed its rehearsal
+func (s *Summary) Failed() bool {
+	return s.Count(StatusFailed) > 0 || s.Count(StatusMissing) > 0 || s.FailedRehearsals() > 0
+}
+
+// Text describes the summary for chat messages: a headline followed by one line per location
+func (s *Summary) Text() string {
+	var text strings.Builder
+	icon := "✅"
+	if s.Failed() {
+		icon = "❌"
+	}
+	fmt.Fprintf(&text, "%s go-backup run-all on %s: %d succeeded, %d failed, %d missing, %d skipped",
+		icon, s.Host, s.Count(StatusSuccess), s.Count(StatusFailed), s.Count(StatusMissing), s.Count(StatusSkipped))
+	// Deferred locations only show up on laptops that defer backups, so others keep the shorter headline
+	if deferred := s.Count(StatusDeferred); deferred > 0 {
+		fmt.Fprintf(&text, ", %d deferred", deferred)
+	}
+	fmt.Fprintf(&text, " (%s)", s.FinishedAt.Sub(s.StartedAt).Round(time.Second))
+	for _, location := range s.Locations {
+		fmt.Fprintf(&text, "\n• %s: %s", location.Location, location.Status)
+		var details []string
+		if location.Duration > 0 {
+			details = append(details, location.Duration.Round(time.Second).String())
+		}
+		if location.Size > 0 {
+			details = append(details, compress.FormatFileSize(location.Size))
+		}
+		if location.Reason != "" {
+			details = append(details, location.Reason)
+		}
+		if location.RehearsalFailed {
+			details = append(details, "restore rehearsal failed")
+		}
+		// Failures name their run, so the log and backups of it can be found
+		if location.RunID != "" && (location.Status == StatusFailed || location.RehearsalFailed) {
+			details = append(details, "run "+location.RunID)
+		}
+		if len(details) > 0 {
+			fmt.Fprintf(&text, " (%s)", strings.Join(details, ", "))
+		}
+	}
+	return text.String()
+}
+
+// payload is the JSON body sent to the webhook. The text field is what chat services such as
+// Slack, Mattermost and Discord-compatible endpoints show.
+type payload struct {
+	Text             string            `json:"text"`
+	Host             string            `json:"host"`
+	StartedAt        time.Time         `json:"startedAt"`
+	FinishedAt       time.Time         `json:"finishedAt"`
+	Succeeded        int               `json:"succeeded"`
+	Failed           int               `json:"failed"`
+	Missing          int               `json:"missing"`
+	Skipped          int               `json:"skipped"`
+	Deferred         int               `json:"deferred"`
+	FailedRehearsals int               `json:"failedRehearsals"`
+	Locations        []locationPayload `json:"locations"`
+}
+
+// locationPayload is one location in the payload
+type locationPayload struct {
+	Location        string  `json:"location"`
+	Status          string  `json:"status"`
+	RunID           string  `json:"runId,omitempty"`
+	Reason          string  `json:"reason,omitempty"`
+	Seconds         float64 `json:"seconds,omitempty"`
+	Size            int64   `json:"size,omitempty"`
+	RehearsalFailed bool    `json:"rehearsalFailed,omitempty"`
+}
+
+// Send posts the summary as JSON to the webhook URL. Any 2xx response counts as delivered.
+func Send(url string, summary *Summary, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	body := payload{
+		Text:             summary.Text(),
+		Host:             summary.Host,
+		StartedAt:        summary.StartedAt,
+		FinishedAt:       summary.FinishedAt,
+		Succeeded:        summary.Count(StatusSuccess),
+		Failed:           summary.Count(StatusFailed),
+		Missing:          summary.Count(StatusMissing),
+		Skipped:          summary.Count(StatusSkipped),
+		Deferred:         summary.Count(StatusDeferred),
+		FailedRehearsals: summary.FailedRehearsals(),
+		Locations:        make([]locationPayload, 0, len(summary.Locations)),
+	}
+	for _, location := range summary.Locations {
+		body.Locations = append(body.Locations, locationPayload{
+			Location:        location.Location,
+			Status:          location.Status,
+			RunID:           location.RunID,
+			Reason:          location.Reason,
+			Seconds:         location.Duration.Seconds(),
+			Size:            location.Size,
+			RehearsalFailed: location.RehearsalFailed,
+		})
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error encoding summary: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// Leave out the URL, whose path often holds the webhook's secret token
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("error sending summary: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("error sending summary: webhook answered %s", resp.Status)
+	}
+	return nil
+}
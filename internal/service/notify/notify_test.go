@@ -0,0 +1,84 @@
+package notify_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/kennycyb/go-backup/internal/service/notify"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Notify", func() {
+	started := time.Date(2025, 5, 20, 2, 0, 0, 0, time.UTC)
+	summary := &Summary{
+		Host:       "nas",
+		StartedAt:  started,
+		FinishedAt: started.Add(95 * time.Second),
+		Locations: []LocationResult{
+			{Location: "/srv/db", Status: StatusSuccess, RunID: "5d0c2b1e-8f4a-4c3e-9b7d-2a6e1f0c9d84", Duration: 30 * time.Second, Size: 2048},
+			{Location: "/srv/app", Status: StatusFailed, RunID: "b3e9a7c2-1d5f-4e8b-a6c0-7f2d9e4b1a35", Reason: "exit status 1", Duration: 65 * time.Second},
+			{Location: "/srv/media", Status: StatusSkipped, Reason: "not due yet"},
+		},
+	}
+
+	Describe("Text", func() {
+		It("should describe every location in one message", func() {
+			Expect(summary.Text()).To(Equal("❌ go-backup run-all on nas: 1 succeeded, 1 failed, 0 missing, 1 skipped (1m35s)\n" +
+				"• /srv/db: success (30s, 2.00 KB)\n" +
+				"• /srv/app: failed (1m5s, exit status 1, run b3e9a7c2-1d5f-4e8b-a6c0-7f2d9e4b1a35)\n" +
+				"• /srv/media: skipped (not due yet)"))
+		})
+
+		It("should count deferred locations", func() {
+			deferred := &Summary{
+				Host:       "laptop",
+				StartedAt:  started,
+				FinishedAt: started.Add(time.Second),
+				Locations:  []LocationResult{{Location: "/home/me/photos", Status: StatusDeferred, Reason: "on a metered connection"}},
+			}
+			Expect(deferred.Failed()).To(BeFalse())
+			Expect(deferred.Text()).To(Equal("✅ go-backup run-all on laptop: 0 succeeded, 0 failed, 0 missing, 0 skipped, 1 deferred (1s)\n" +
+				"• /home/me/photos: deferred (on a metered connection)"))
+		})
+	})
+
+	Describe("Send", func() {
+		It("should post the summary as JSON", func() {
+			var received map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Method).To(Equal(http.MethodPost))
+				Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+				Expect(json.NewDecoder(r.Body).Decode(&received)).To(Succeed())
+			}))
+			defer server.Close()
+
+			Expect(Send(server.URL, summary, time.Second)).To(Succeed())
+			Expect(received).To(HaveKeyWithValue("text", summary.Text()))
+			Expect(received).To(HaveKeyWithValue("succeeded", BeNumerically("==", 1)))
+			Expect(received).To(HaveKeyWithValue("failed", BeNumerically("==", 1)))
+			Expect(received["locations"]).To(HaveLen(3))
+			Expect(received["locations"].([]any)[0]).To(HaveKeyWithValue("size", BeNumerically("==", 2048)))
+			Expect(received["locations"].([]any)[1]).To(HaveKeyWithValue("runId", "b3e9a7c2-1d5f-4e8b-a6c0-7f2d9e4b1a35"))
+			Expect(received["locations"].([]any)[2]).NotTo(HaveKey("runId"))
+		})
+
+		It("should fail when the webhook does not accept it", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			}))
+			defer server.Close()
+
+			err := Send(server.URL, summary, time.Second)
+			Expect(err).To(MatchError(ContainSubstring("404")))
+		})
+
+		It("should not reveal the webhook's URL in errors", func() {
+			err := Send("http://127.0.0.1:1/hooks/secret-token", summary, time.Second)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).NotTo(ContainSubstring("secret-token"))
+		})
+	})
+})
@@ -0,0 +1,137 @@
+// Package plain turns the decorated output of go-backup into plain text for screen readers and
+// grep: colors, emoji and box drawing are removed, leaving simple "Label: value" lines.
+package plain
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ansiEscape matches the color and style sequences of a terminal
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*[A-Za-z]")
+
+// symbols replaces the decorations that stand for a word or punctuation
+var symbols = strings.NewReplacer(
+	" → ", " to ",
+	"→ ", "",
+	"── ", "",
+	" ──", "",
+	"│ ", "",
+	"│", "",
+	"•", "-",
+	"…", "...",
+)
+
+// Line returns a line of output without colors, emoji and box drawing. Emoji are removed with
+// the spaces that follow them, so "❌ Error: x" becomes "Error: x".
+func Line(line string) string {
+	line = ansiEscape.ReplaceAllString(line, "")
+	line = symbols.Replace(line)
+
+	var out strings.Builder
+	skipSpace := false
+	for _, r := range line {
+		switch {
+		case isEmoji(r):
+			skipSpace = true
+			continue
+		case skipSpace && r == ' ':
+			continue
+		}
+		skipSpace = false
+		out.WriteRune(r)
+	}
+	return strings.TrimRightFunc(out.String(), unicode.IsSpace)
+}
+
+// isEmoji reports whether r is an emoji, a pictographic symbol or a modifier of one
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // Emoji, pictographs and their modifiers
+	case r >= 0x2600 && r <= 0x27BF: // Miscellaneous symbols and dingbats, e.g. ⚠ ✅ ❌ ✓
+	case r >= 0x2300 && r <= 0x23FF: // Technical symbols, e.g. ⏭ ⏸
+	case r == 0x2139: // ℹ
+	case r == 0xFE0F || r == 0x200D: // Emoji presentation selector and joiner
+	default:
+		return false
+	}
+	return true
+}
+
+// isRule reports whether a line is only a horizontal rule, e.g. the border of a banner
+func isRule(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" && strings.Trim(trimmed, "=─-") == ""
+}
+
+// Writer writes the plain form of the output written to it (see Line). Banner borders are
+// dropped and banner titles lose their indentation. Incomplete lines, e.g. prompts, are written
+// right away, so questions are shown before the answer is read.
+type Writer struct {
+	w         io.Writer
+	pending   []byte // An incomplete escape sequence at the end of the last write
+	midLine   bool   // The last write ended within a line
+	afterRule bool   // The last line was a rule, so this one may be a banner title
+}
+
+// NewWriter returns a Writer that writes plain output to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes the plain form of p, reporting all of p as written unless w fails
+func (pw *Writer) Write(p []byte) (int, error) {
+	data := append(pw.pending, p...)
+	pw.pending = nil
+
+	var out bytes.Buffer
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			// Hold back an escape sequence that the next write completes
+			if esc := bytes.LastIndexByte(data, 0x1b); esc >= 0 && !ansiEscape.Match(data[esc:]) {
+				pw.pending = append([]byte(nil), data[esc:]...)
+				data = data[:esc]
+			}
+			if len(data) > 0 {
+				out.WriteString(pw.line(string(data), false))
+				pw.midLine = true
+			}
+			break
+		}
+		out.WriteString(pw.line(string(data[:end]), true))
+		pw.midLine = false
+		data = data[end+1:]
+	}
+
+	if _, err := pw.w.Write(out.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// line returns the plain form of a line, or of its start for an incomplete line
+func (pw *Writer) line(text string, complete bool) string {
+	stripped := ansiEscape.ReplaceAllString(text, "")
+	if complete && !pw.midLine && isRule(stripped) {
+		pw.afterRule = true
+		return ""
+	}
+
+	text = Line(text)
+	if pw.afterRule && !pw.midLine {
+		text = strings.TrimSpace(text)
+		pw.afterRule = false
+	}
+	if complete {
+		return text + "\n"
+	}
+	// The rest of the line follows, so keep its trailing space, e.g. after a prompt
+	if trailing := len(stripped) - len(strings.TrimRight(stripped, " ")); trailing > 0 && text != "" {
+		text += strings.Repeat(" ", trailing)
+	}
+	return text
+}
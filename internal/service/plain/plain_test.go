@@ -0,0 +1,78 @@
+package plain_test
+
+import (
+	"bytes"
+	"fmt"
+
+	. "github.com/kennycyb/go-backup/internal/service/plain"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	red   = "\033[31m"
+	bold  = "\033[1m"
+	dim   = "\033[2m"
+	reset = "\033[0m"
+)
+
+var _ = Describe("Plain output", func() {
+	Describe("Line", func() {
+		It("should remove colors", func() {
+			Expect(Line(dim + "Source:" + reset + " /home/me")).To(Equal("Source: /home/me"))
+		})
+
+		It("should remove emoji with the spaces after them", func() {
+			Expect(Line(red + bold + "❌ Error:" + reset + " no targets")).To(Equal("Error: no targets"))
+			Expect(Line("  ⚠️  Warning: disk almost full")).To(Equal("  Warning: disk almost full"))
+			Expect(Line("  🔄 Rotation: Keeping latest 7 backups")).To(Equal("  Rotation: Keeping latest 7 backups"))
+			Expect(Line("⏭️  Skipped: 2")).To(Equal("Skipped: 2"))
+		})
+
+		It("should replace box drawing and symbols with text", func() {
+			Expect(Line("→ Destination: /mnt/backup")).To(Equal("Destination: /mnt/backup"))
+			Expect(Line("Moved the global registry: a → b")).To(Equal("Moved the global registry: a to b"))
+			Expect(Line("── Priority 10 ──")).To(Equal("Priority 10"))
+			Expect(Line("  │ tar: file changed")).To(Equal("  tar: file changed"))
+			Expect(Line("  • Method: gpg")).To(Equal("  - Method: gpg"))
+			Expect(Line("a-very-long…name")).To(Equal("a-very-long...name"))
+		})
+
+		It("should keep other text", func() {
+			Expect(Line("Größe: 1,5 GB — 日本語")).To(Equal("Größe: 1,5 GB — 日本語"))
+		})
+	})
+
+	Describe("Writer", func() {
+		var out *bytes.Buffer
+		var w *Writer
+
+		BeforeEach(func() {
+			out = &bytes.Buffer{}
+			w = NewWriter(out)
+		})
+
+		It("should drop banner borders and the indentation of the title", func() {
+			fmt.Fprintf(w, "%s%s\n==============================\n   📦  Starting Backup Job    \n==============================%s\n", bold, red, reset)
+			fmt.Fprintf(w, "%sSource:%s %s\n", dim, reset, "/src")
+			Expect(out.String()).To(Equal("\nStarting Backup Job\nSource: /src\n"))
+		})
+
+		It("should write prompts right away", func() {
+			fmt.Fprintf(w, "%sContinue with backup anyway? [y/N]:%s ", red, reset)
+			Expect(out.String()).To(Equal("Continue with backup anyway? [y/N]: "))
+		})
+
+		It("should join escape sequences split between writes", func() {
+			w.Write([]byte("Done\033["))
+			w.Write([]byte("0m\n"))
+			Expect(out.String()).To(Equal("Done\n"))
+		})
+
+		It("should report what was written", func() {
+			n, err := w.Write([]byte("⚠️  x\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(len("⚠️  x\n")))
+		})
+	})
+})
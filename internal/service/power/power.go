@@ -0,0 +1,82 @@
+// Package power tells whether the machine runs on battery or a metered connection,
+// so laptops can defer backups until they are plugged in and on an unmetered network
+package power
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// State is the power source and network connection of the machine
+type State struct {
+	OnBattery    bool // Running on battery; false on AC power, or when there is no battery
+	Battery      int  // Charge of the battery in percent, -1 if unknown
+	Metered      bool // The network connection is metered, e.g. a phone hotspot
+	MeteredKnown bool // Whether the platform can tell metered connections apart
+}
+
+// Policy is when backups are deferred
+type Policy struct {
+	RequireAC     bool // Defer backups on battery
+	MinBattery    int  // With RequireAC, back up on battery anyway from this charge in percent; 0 never does
+	SkipOnMetered bool // Defer backups on a metered connection
+}
+
+// Enabled reports whether the policy defers any backups
+func (p Policy) Enabled() bool {
+	return p.RequireAC || p.SkipOnMetered
+}
+
+// DeferReason returns why a backup is deferred in the state, or "" if it can go ahead
+func (p Policy) DeferReason(state State) string {
+	if p.RequireAC && state.OnBattery {
+		switch {
+		case state.Battery < 0:
+			return "on battery power"
+		case p.MinBattery == 0:
+			return fmt.Sprintf("on battery power (%d%%)", state.Battery)
+		case state.Battery < p.MinBattery:
+			return fmt.Sprintf("on battery power at %d%%, below %d%%", state.Battery, p.MinBattery)
+		}
+	}
+	if p.SkipOnMetered && state.Metered {
+		return "on a metered connection"
+	}
+	return ""
+}
+
+// Current returns the state of the machine. Parts that cannot be read count as AC power and
+// an unmetered connection, so a backup is never deferred by mistake.
+func Current() State {
+	return readState()
+}
+
+var pmsetPercent = regexp.MustCompile(`(\d+)%`)
+
+// ParsePmset reads the power source and charge from the output of macOS 'pmset -g batt'
+func ParsePmset(output string) State {
+	state := State{Battery: -1}
+	state.OnBattery = strings.Contains(output, "'Battery Power'")
+	if match := pmsetPercent.FindStringSubmatch(output); match != nil {
+		state.Battery, _ = strconv.Atoi(match[1])
+	}
+	return state
+}
+
+// ParseNetworkManagerMetered reads NetworkManager's Metered property as printed by busctl,
+// e.g. "u 4". Connections marked metered (1) or guessed to be (3) count as metered.
+func ParseNetworkManagerMetered(output string) (metered, ok bool) {
+	fields := strings.Fields(output)
+	if len(fields) != 2 || fields[0] != "u" {
+		return false, false
+	}
+	switch fields[1] {
+	case "1", "3":
+		return true, true
+	case "0":
+		return false, false // NetworkManager does not know either
+	}
+	return false, true
+}
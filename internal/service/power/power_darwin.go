@@ -0,0 +1,15 @@
+//go:build darwin
+
+package power
+
+import "os/exec"
+
+// readState asks pmset for the power source. macOS has no command telling whether the
+// connection is metered, so it never counts as metered.
+func readState() State {
+	output, err := exec.Command("pmset", "-g", "batt").Output()
+	if err != nil {
+		return State{Battery: -1}
+	}
+	return ParsePmset(string(output))
+}
@@ -0,0 +1,68 @@
+//go:build linux
+
+package power
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// powerSupplyDir is where the kernel lists AC adapters and batteries
+const powerSupplyDir = "/sys/class/power_supply"
+
+// readState reads the power supplies from sysfs and asks NetworkManager about the connection
+func readState() State {
+	state := ReadPowerSupplies(powerSupplyDir)
+	output, err := exec.Command("busctl", "get-property", "org.freedesktop.NetworkManager",
+		"/org/freedesktop/NetworkManager", "org.freedesktop.NetworkManager", "Metered").Output()
+	if err == nil {
+		state.Metered, state.MeteredKnown = ParseNetworkManagerMetered(string(output))
+	}
+	return state
+}
+
+// ReadPowerSupplies reads the state of the power supplies listed in a sysfs directory. The
+// machine is on battery when a battery discharges and no AC adapter is online; batteries of
+// devices such as wireless mice are ignored.
+func ReadPowerSupplies(dir string) State {
+	state := State{Battery: -1}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return state
+	}
+	read := func(supply, name string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, supply, name))
+		return strings.TrimSpace(string(data))
+	}
+
+	acOnline, discharging := false, false
+	charge, batteries := 0, 0
+	for _, entry := range entries {
+		supply := entry.Name()
+		switch read(supply, "type") {
+		case "Mains", "USB":
+			if read(supply, "online") == "1" {
+				acOnline = true
+			}
+		case "Battery":
+			if read(supply, "scope") == "Device" {
+				continue
+			}
+			if read(supply, "status") == "Discharging" {
+				discharging = true
+			}
+			if capacity, err := strconv.Atoi(read(supply, "capacity")); err == nil {
+				charge += capacity
+				batteries++
+			}
+		}
+	}
+	state.OnBattery = discharging && !acOnline
+	if batteries > 0 {
+		state.Battery = charge / batteries
+	}
+	return state
+}
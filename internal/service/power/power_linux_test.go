@@ -0,0 +1,58 @@
+package power_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kennycyb/go-backup/internal/service/power"
+)
+
+var _ = Describe("ReadPowerSupplies", func() {
+	var dir string
+
+	supply := func(name string, files map[string]string) {
+		Expect(os.MkdirAll(filepath.Join(dir, name), 0755)).To(Succeed())
+		for file, value := range files {
+			Expect(os.WriteFile(filepath.Join(dir, name, file), []byte(value+"\n"), 0644)).To(Succeed())
+		}
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	It("should report a discharging battery without AC power", func() {
+		supply("AC", map[string]string{"type": "Mains", "online": "0"})
+		supply("BAT0", map[string]string{"type": "Battery", "status": "Discharging", "capacity": "42"})
+
+		state := power.ReadPowerSupplies(dir)
+		Expect(state.OnBattery).To(BeTrue())
+		Expect(state.Battery).To(Equal(42))
+	})
+
+	It("should report AC power when the adapter is online", func() {
+		supply("AC", map[string]string{"type": "Mains", "online": "1"})
+		supply("BAT0", map[string]string{"type": "Battery", "status": "Charging", "capacity": "42"})
+
+		Expect(power.ReadPowerSupplies(dir).OnBattery).To(BeFalse())
+	})
+
+	It("should average several batteries and ignore those of devices", func() {
+		supply("BAT0", map[string]string{"type": "Battery", "status": "Discharging", "capacity": "40"})
+		supply("BAT1", map[string]string{"type": "Battery", "status": "Discharging", "capacity": "60"})
+		supply("hidpp_battery_0", map[string]string{"type": "Battery", "scope": "Device", "status": "Discharging", "capacity": "5"})
+
+		state := power.ReadPowerSupplies(dir)
+		Expect(state.OnBattery).To(BeTrue())
+		Expect(state.Battery).To(Equal(50))
+	})
+
+	It("should report AC power for a machine without power supplies", func() {
+		state := power.ReadPowerSupplies(filepath.Join(dir, "missing"))
+		Expect(state.OnBattery).To(BeFalse())
+		Expect(state.Battery).To(Equal(-1))
+	})
+})
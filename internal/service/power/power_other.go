@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package power
+
+// readState reports AC power and an unmetered connection on platforms that cannot be queried
+func readState() State {
+	return State{Battery: -1}
+}
@@ -0,0 +1,56 @@
+package power_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kennycyb/go-backup/internal/service/power"
+)
+
+var _ = Describe("Power", func() {
+	DescribeTable("DeferReason",
+		func(policy power.Policy, state power.State, expected string) {
+			Expect(policy.DeferReason(state)).To(Equal(expected))
+		},
+		Entry("On AC power", power.Policy{RequireAC: true}, power.State{Battery: 40}, ""),
+		Entry("On battery", power.Policy{RequireAC: true}, power.State{OnBattery: true, Battery: 80}, "on battery power (80%)"),
+		Entry("On battery with an unknown charge", power.Policy{RequireAC: true, MinBattery: 50}, power.State{OnBattery: true, Battery: -1}, "on battery power"),
+		Entry("On battery below the threshold", power.Policy{RequireAC: true, MinBattery: 50}, power.State{OnBattery: true, Battery: 35}, "on battery power at 35%, below 50%"),
+		Entry("On battery at the threshold", power.Policy{RequireAC: true, MinBattery: 50}, power.State{OnBattery: true, Battery: 50}, ""),
+		Entry("On battery without requireAC", power.Policy{SkipOnMetered: true}, power.State{OnBattery: true, Battery: 5}, ""),
+		Entry("On a metered connection", power.Policy{SkipOnMetered: true}, power.State{Metered: true, MeteredKnown: true}, "on a metered connection"),
+		Entry("On a metered connection without skipOnMetered", power.Policy{RequireAC: true}, power.State{Metered: true, MeteredKnown: true}, ""),
+	)
+
+	Describe("ParsePmset", func() {
+		It("should read the power source and charge", func() {
+			state := power.ParsePmset("Now drawing from 'Battery Power'\n -InternalBattery-0 (id=4653155)\t85%; discharging; 4:12 remaining present: true\n")
+			Expect(state.OnBattery).To(BeTrue())
+			Expect(state.Battery).To(Equal(85))
+
+			state = power.ParsePmset("Now drawing from 'AC Power'\n -InternalBattery-0 (id=4653155)\t100%; charged; 0:00 remaining present: true\n")
+			Expect(state.OnBattery).To(BeFalse())
+			Expect(state.Battery).To(Equal(100))
+		})
+
+		It("should leave the charge unknown without a battery", func() {
+			state := power.ParsePmset("Now drawing from 'AC Power'\n")
+			Expect(state.OnBattery).To(BeFalse())
+			Expect(state.Battery).To(Equal(-1))
+		})
+	})
+
+	DescribeTable("ParseNetworkManagerMetered",
+		func(output string, metered, ok bool) {
+			gotMetered, gotOK := power.ParseNetworkManagerMetered(output)
+			Expect(gotMetered).To(Equal(metered))
+			Expect(gotOK).To(Equal(ok))
+		},
+		Entry("Metered", "u 1\n", true, true),
+		Entry("Guessed metered", "u 3\n", true, true),
+		Entry("Not metered", "u 2\n", false, true),
+		Entry("Guessed not metered", "u 4\n", false, true),
+		Entry("Unknown", "u 0\n", false, false),
+		Entry("Unexpected output", "", false, false),
+	)
+})
@@ -0,0 +1,31 @@
+// Package priority lowers the CPU and IO priority of the backup process,
+// so background backups don't slow down interactive work
+package priority
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// NiceLevel is the niceness a lowered process runs at
+const NiceLevel = 10
+
+// Lower lowers the CPU priority of the process to NiceLevel and, where the platform
+// supports it, its IO priority to the lowest best-effort level. Tools started afterwards
+// (gpg, git) inherit the lower priority. A process that already runs at a lower priority
+// is left as it is.
+func Lower() error {
+	if err := lower(); err != nil {
+		return fmt.Errorf("failed to lower process priority: %w", err)
+	}
+	return nil
+}
+
+// LimitCPU caps the number of CPUs used to run Go code and returns the previous limit.
+// A limit of 0 or less leaves the setting unchanged.
+func LimitCPU(limit int) int {
+	if limit <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return runtime.GOMAXPROCS(limit)
+}
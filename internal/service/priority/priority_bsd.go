@@ -0,0 +1,18 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package priority
+
+import "syscall"
+
+// lower sets the niceness of the process; these platforms have no IO priority
+// that can be set without cgo
+func lower() error {
+	current, err := syscall.Getpriority(syscall.PRIO_PROCESS, 0)
+	if err != nil {
+		return err
+	}
+	if current >= NiceLevel {
+		return nil
+	}
+	return syscall.Setpriority(syscall.PRIO_PROCESS, 0, NiceLevel)
+}
@@ -0,0 +1,53 @@
+//go:build linux
+
+package priority
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// ioprio_set(2) arguments: best-effort class at its lowest level
+const (
+	ioprioWhoProcess = 1
+	ioprioClassBE    = 2
+	ioprioClassShift = 13
+	ioprioLowest     = 7
+)
+
+// lower applies the priority to every thread of the process. Linux keeps nice values
+// and IO priorities per thread, and new threads inherit them from their creator.
+func lower() error {
+	tasks, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+
+		// The raw syscall returns 20 - nice, so higher values mean a higher priority
+		current, err := syscall.Getpriority(syscall.PRIO_PROCESS, tid)
+		if err == syscall.ESRCH {
+			continue // The thread has exited
+		}
+		if err != nil {
+			return err
+		}
+		if 20-current < NiceLevel {
+			if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, NiceLevel); err != nil && err != syscall.ESRCH {
+				return err
+			}
+		}
+
+		ioprio := ioprioClassBE<<ioprioClassShift | ioprioLowest
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(ioprio)); errno != 0 && errno != syscall.ESRCH {
+			return errno
+		}
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package priority
+
+// lower does nothing on platforms without a supported way to change priorities
+func lower() error {
+	return nil
+}
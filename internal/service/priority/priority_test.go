@@ -0,0 +1,35 @@
+package priority_test
+
+import (
+	"runtime"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/kennycyb/go-backup/internal/service/priority"
+)
+
+var _ = Describe("Priority", func() {
+	Describe("LimitCPU", func() {
+		It("should cap GOMAXPROCS and return the previous limit", func() {
+			original := runtime.GOMAXPROCS(0)
+			DeferCleanup(runtime.GOMAXPROCS, original)
+
+			Expect(priority.LimitCPU(1)).To(Equal(original))
+			Expect(runtime.GOMAXPROCS(0)).To(Equal(1))
+		})
+
+		It("should leave the setting unchanged for a limit of 0", func() {
+			original := runtime.GOMAXPROCS(0)
+			Expect(priority.LimitCPU(0)).To(Equal(original))
+			Expect(runtime.GOMAXPROCS(0)).To(Equal(original))
+		})
+	})
+
+	Describe("Lower", func() {
+		It("should succeed and can be repeated", func() {
+			Expect(priority.Lower()).To(Succeed())
+			Expect(priority.Lower()).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,205 @@
+// Package reconcile brings the backup history of a target back in line with the backups it holds
+// after they were deleted, moved or copied by hand. Targets may be local directories or remote
+// locations, which are listed through the storage service.
+package reconcile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
+)
+
+// Options controls how Target reconciles a history
+type Options struct {
+	Prefix string               // Name prefix of the source's backups, e.g. "project-"
+	Source string               // Absolute path of the source the untracked backups must be made from
+	Naming backupService.Naming // Naming of the config, for backups without a readable sidecar
+	Force  bool                 // Drop the history even when none of its recorded backups is found
+	DryRun bool                 // Report the changes without making them to the config
+}
+
+// Addition is a record added for a backup missing from the history
+type Addition struct {
+	Record      configService.BackupRecord
+	FromSidecar bool // False if the record was rebuilt from the timestamp in the backup's name
+}
+
+// Foreign is an untracked backup made on another host or from another directory
+type Foreign struct {
+	Name     string
+	MadeFrom string // Source of the backup, followed by " on <host>" when its host is known
+}
+
+// Result describes how Target changed the history of a target
+type Result struct {
+	Removed []configService.BackupRecord // Records of backups no longer on the target
+	// KeptMissing is the number of recorded backups kept although none of them was found, e.g.
+	// because a drive is not mounted; Force removes them instead
+	KeptMissing int
+	Added       []Addition
+	Unreadable  []string  // Untracked backups without a readable sidecar or a timestamp in their name
+	Foreign     []Foreign // Untracked backups of another host or source, left out
+	TooOld      []string  // Untracked backups older than the backups the target keeps (maxBackups)
+}
+
+// Target reconciles the history of a target with the files it holds: records of backups that are
+// gone are dropped, and untracked backups of the source named with opts.Prefix are added. Their
+// records are rebuilt from the sidecar config or manifest kept next to them, downloaded from remote
+// targets, or else from the timestamp in their name. The config is only changed without opts.DryRun.
+// Returns an error if the target cannot be listed.
+func Target(config *configService.BackupConfig, target configService.ResolvedTarget, opts Options) (*Result, error) {
+	dest := target.GetDestination()
+	dir := dest
+	if target.IsFileTarget() {
+		dir = filepath.Dir(dest)
+		if storageService.IsRemote(dest) {
+			remote, err := storageService.ParseRemote(dest)
+			if err != nil {
+				return nil, err
+			}
+			dir = remote.Dir().String()
+		}
+	}
+	files, err := listEntries(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(files))
+	for _, file := range files {
+		names[file.Name] = true
+	}
+
+	result := &Result{}
+	stale := configService.StaleBackupRecords(target.Backups, names)
+	if len(stale) > 0 && len(stale) == len(target.Backups) && !opts.Force {
+		result.KeptMissing = len(stale)
+		stale = nil
+	}
+	gone := make(map[string]bool, len(stale))
+	for _, record := range stale {
+		gone[record.Filename] = true
+	}
+	result.Removed = stale
+	if !opts.DryRun {
+		configService.RemoveBackupRecords(config, dest, gone)
+	}
+	if target.IsFileTarget() {
+		return result, nil
+	}
+
+	tracked := make(map[string]bool)
+	for _, record := range target.Backups {
+		if gone[record.Filename] {
+			continue
+		}
+		tracked[record.Filename] = true
+		if record.SnapshotOf != "" {
+			// A metadata snapshot keeps the archive holding its contents
+			tracked[record.SnapshotOf] = true
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "go-backup-repair-state-")
+	if err != nil {
+		return result, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	host := backupService.CurrentHost()
+	for _, file := range files {
+		if !backupService.IsArchiveName(file.Name) || !strings.HasPrefix(file.Name, opts.Prefix) || tracked[file.Name] {
+			continue
+		}
+		record, fromSidecar, ok := untrackedRecord(dir, file, names, opts, tmpDir)
+		if !ok {
+			result.Unreadable = append(result.Unreadable, file.Name)
+			continue
+		}
+		if owner := (backupService.Owner{Host: record.Host, Source: record.Source}); !owner.BelongsTo(host, opts.Source) {
+			madeFrom := owner.Source
+			if owner.Host != "" {
+				madeFrom += " on " + owner.Host
+			}
+			result.Foreign = append(result.Foreign, Foreign{Name: file.Name, MadeFrom: madeFrom})
+			continue
+		}
+
+		var kept bool
+		if opts.DryRun {
+			kept = configService.KeepsBackupRecord(config, dest, record)
+		} else {
+			kept = configService.InsertBackupRecord(config, dest, record)
+		}
+		if !kept {
+			result.TooOld = append(result.TooOld, file.Name)
+			continue
+		}
+		result.Added = append(result.Added, Addition{Record: record, FromSidecar: fromSidecar})
+	}
+	return result, nil
+}
+
+// untrackedRecord builds the history record of a backup missing from the history from its sidecar
+// config or manifest, downloading them from remote targets, or else from the timestamp in its name.
+// fromSidecar tells which; ok is false when neither works.
+func untrackedRecord(dir string, file storageService.RemoteFile, names map[string]bool, opts Options,
+	tmpDir string) (record configService.BackupRecord, fromSidecar, ok bool) {
+	sidecarDir := dir
+	if storageService.IsRemote(dir) {
+		sidecarDir = tmpDir
+		if remote, err := storageService.ParseRemote(dir); err == nil {
+			baseName := backupService.BackupBaseName(file.Name)
+			for _, name := range []string{baseName + ".backup.yaml", baseName + backupService.ManifestSuffix} {
+				if names[name] {
+					storageService.Download(remote.Join(name), filepath.Join(tmpDir, name))
+				}
+			}
+		}
+	}
+
+	record, fromSidecar = configService.RecordFromSidecars(sidecarDir, file.Name)
+	if !fromSidecar {
+		_, createdAt, parsed := opts.Naming.ParseName(file.Name)
+		if !parsed {
+			_, createdAt, parsed = backupService.DefaultNaming().ParseName(file.Name)
+		}
+		if !parsed {
+			return record, false, false
+		}
+		record = configService.BackupRecord{Filename: file.Name, Source: opts.Source, CreatedAt: createdAt}
+	}
+	if file.Size > 0 {
+		record.Size = file.Size
+	}
+	return record, fromSidecar, true
+}
+
+// listEntries returns the files in a local or remote directory with their sizes
+func listEntries(dir string) ([]storageService.RemoteFile, error) {
+	if storageService.IsRemote(dir) {
+		remote, err := storageService.ParseRemote(dir)
+		if err != nil {
+			return nil, err
+		}
+		return storageService.List(remote)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []storageService.RemoteFile
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		files = append(files, storageService.RemoteFile{Name: entry.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return files, nil
+}
@@ -0,0 +1,130 @@
+package reconcile_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	. "github.com/kennycyb/go-backup/internal/service/reconcile"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Target", func() {
+	var (
+		backupDir string
+		config    *configService.BackupConfig
+		opts      Options
+	)
+
+	BeforeEach(func() {
+		backupDir = GinkgoT().TempDir()
+		config = &configService.BackupConfig{Targets: []configService.BackupTarget{{Path: backupDir}}}
+		opts = Options{Prefix: "project-", Source: "/home/me/project", Naming: backupService.DefaultNaming()}
+	})
+
+	// write creates a file of the given name in the target
+	write := func(name string) {
+		Expect(os.WriteFile(filepath.Join(backupDir, name), []byte("archive"), 0644)).To(Succeed())
+	}
+
+	// target returns the config's target as commands see it
+	target := func() configService.ResolvedTarget {
+		targets, err := configService.ResolveTargets(config, configService.TargetFlags{})
+		Expect(err).NotTo(HaveOccurred())
+		return targets[0]
+	}
+
+	It("should drop records of backups that are gone and add untracked backups of the source", func() {
+		write("project-20250520-123045.tar.gz")
+		write("project-20250521-080000.tar.gz")
+		write("other-20250521-080000.tar.gz")
+		config.Targets[0].Backups = []configService.BackupRecord{
+			{Filename: "project-20250521-080000.tar.gz", CreatedAt: time.Date(2025, 5, 21, 8, 0, 0, 0, time.Local)},
+			{Filename: "project-20250519-080000.tar.gz", CreatedAt: time.Date(2025, 5, 19, 8, 0, 0, 0, time.Local)},
+		}
+
+		result, err := Target(config, target(), opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Removed).To(HaveLen(1))
+		Expect(result.Removed[0].Filename).To(Equal("project-20250519-080000.tar.gz"))
+		Expect(result.Added).To(HaveLen(1))
+		Expect(result.Added[0].FromSidecar).To(BeFalse())
+		Expect(result.Added[0].Record.CreatedAt).To(Equal(time.Date(2025, 5, 20, 12, 30, 45, 0, time.Local)))
+
+		var names []string
+		for _, record := range config.Targets[0].Backups {
+			names = append(names, record.Filename)
+		}
+		Expect(names).To(Equal([]string{"project-20250521-080000.tar.gz", "project-20250520-123045.tar.gz"}))
+	})
+
+	It("should keep the history when none of its backups is found unless forced", func() {
+		config.Targets[0].Backups = []configService.BackupRecord{{Filename: "project-20250520-123045.tar.gz"}}
+
+		result, err := Target(config, target(), opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.KeptMissing).To(Equal(1))
+		Expect(result.Removed).To(BeEmpty())
+		Expect(config.Targets[0].Backups).To(HaveLen(1))
+
+		opts.Force = true
+		result, err = Target(config, target(), opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Removed).To(HaveLen(1))
+		Expect(config.Targets[0].Backups).To(BeEmpty())
+	})
+
+	It("should leave out backups of another source and those without a timestamp", func() {
+		write("project-20250520-123045.tar.gz")
+		write("project-latest.tar.gz")
+		manifest := backupService.NewManifest("project-20250520-123045.tar.gz", "/home/me/elsewhere/project", nil)
+		manifest.CreatedAt = time.Date(2025, 5, 20, 12, 30, 45, 0, time.UTC)
+		Expect(backupService.WriteManifest(filepath.Join(backupDir, "project-20250520-123045"+backupService.ManifestSuffix), manifest)).To(Succeed())
+
+		result, err := Target(config, target(), opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Added).To(BeEmpty())
+		Expect(result.Unreadable).To(Equal([]string{"project-latest.tar.gz"}))
+		Expect(result.Foreign).To(HaveLen(1))
+		Expect(result.Foreign[0].Name).To(Equal("project-20250520-123045.tar.gz"))
+		Expect(result.Foreign[0].MadeFrom).To(HavePrefix("/home/me/elsewhere/project"))
+	})
+
+	It("should leave out backups older than those the target keeps", func() {
+		config.Targets[0].MaxBackups = 1
+		config.Targets[0].Backups = []configService.BackupRecord{{Filename: "project-20250521-080000.tar.gz",
+			CreatedAt: time.Date(2025, 5, 21, 8, 0, 0, 0, time.Local)}}
+		write("project-20250521-080000.tar.gz")
+		write("project-20250520-123045.tar.gz")
+
+		result, err := Target(config, target(), opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.TooOld).To(Equal([]string{"project-20250520-123045.tar.gz"}))
+		Expect(config.Targets[0].Backups).To(HaveLen(1))
+	})
+
+	It("should not change the config in a dry run", func() {
+		write("project-20250520-123045.tar.gz")
+		config.Targets[0].Backups = []configService.BackupRecord{
+			{Filename: "project-20250520-123045.tar.gz"},
+			{Filename: "project-20250519-080000.tar.gz"},
+		}
+		write("project-20250521-080000.tar.gz")
+		opts.DryRun = true
+
+		result, err := Target(config, target(), opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Removed).To(HaveLen(1))
+		Expect(result.Added).To(HaveLen(1))
+		Expect(config.Targets[0].Backups).To(HaveLen(2))
+	})
+
+	It("should fail for a target that cannot be listed", func() {
+		config.Targets[0].Path = filepath.Join(backupDir, "unmounted")
+		_, err := Target(config, target(), opts)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,288 @@
+// Package replicate catches directory targets up with the backups that went to a fallback target
+// while they were unreachable, e.g. a NAS that was offline. Targets may be local directories or
+// remote locations; files are read from and written to either through the storage service.
+package replicate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
+)
+
+// Replica is a backup that a fallback target holds and another target is missing
+type Replica struct {
+	From   configService.ResolvedTarget
+	Record configService.BackupRecord
+}
+
+// Plan lists the backups a target that is not a fallback is missing
+type Plan struct {
+	Target  configService.ResolvedTarget
+	Missing []Replica // Oldest first
+	Skipped []string  // Fallbacks left out since their backups are encrypted or chunked differently
+}
+
+// CopyResult describes what Copy stored besides the backup itself
+type CopyResult struct {
+	Companions []string // Manifests, config copies and checksum files copied with the backup
+	Warnings   []string // Problems that did not fail the copy, e.g. a companion that could not be copied
+}
+
+// Plans returns a plan for each directory target that is not a fallback, in the order of targets.
+// Returns nil if there is no directory target with fallback: true or none without, since then
+// there is nothing to replicate.
+func Plans(config *configService.BackupConfig, targets []configService.ResolvedTarget) []Plan {
+	var fallbacks, primaries []configService.ResolvedTarget
+	for _, target := range targets {
+		switch {
+		case target.IsFileTarget():
+		case target.Fallback:
+			fallbacks = append(fallbacks, target)
+		default:
+			primaries = append(primaries, target)
+		}
+	}
+	if len(fallbacks) == 0 || len(primaries) == 0 {
+		return nil
+	}
+
+	plans := make([]Plan, 0, len(primaries))
+	for _, primary := range primaries {
+		plans = append(plans, missingReplicas(config, primary, fallbacks))
+	}
+	return plans
+}
+
+// missingReplicas returns the backups of the fallback targets that are missing from the target's
+// history and that it would keep, oldest first. Backups encrypted or chunked differently than the
+// target's own are left out, since they are copied as they are.
+func missingReplicas(config *configService.BackupConfig, target configService.ResolvedTarget, fallbacks []configService.ResolvedTarget) Plan {
+	plan := Plan{Target: target}
+	have := make(map[string]bool)
+	for _, record := range target.Backups {
+		have[record.Filename] = true
+	}
+
+	for _, fallback := range fallbacks {
+		if !sameBackupFormat(config, target, fallback) {
+			plan.Skipped = append(plan.Skipped, fallback.GetDestination())
+			continue
+		}
+		for i := len(fallback.Backups) - 1; i >= 0; i-- {
+			record := fallback.Backups[i]
+			if have[record.Filename] || record.SnapshotOf != "" || !configService.KeepsBackupRecord(config, target.GetDestination(), record) {
+				continue
+			}
+			have[record.Filename] = true
+			plan.Missing = append(plan.Missing, Replica{From: fallback, Record: record})
+		}
+	}
+	return plan
+}
+
+// sameBackupFormat reports whether both targets store backups with the same archive format, encryption and chunks
+func sameBackupFormat(config *configService.BackupConfig, a, b configService.ResolvedTarget) bool {
+	receiver := func(target configService.ResolvedTarget) string {
+		if encryption := target.EffectiveEncryption(config.Encryption); encryption != nil {
+			return encryption.Receiver
+		}
+		return ""
+	}
+	format := func(target configService.ResolvedTarget) string {
+		format, _ := target.ArchiveFormat()
+		if format == "" {
+			format, _ = config.Options.ArchiveFormat()
+		}
+		return compressionService.FormatExtension(format)
+	}
+	chunksA, errA := a.ChunkSize()
+	chunksB, errB := b.ChunkSize()
+	return receiver(a) == receiver(b) && format(a) == format(b) && errA == nil && errB == nil && chunksA == chunksB
+}
+
+// ListFiles returns the names of the files in a local or remote directory target
+func ListFiles(dest string) (map[string]bool, error) {
+	names := make(map[string]bool)
+	if storageService.IsRemote(dest) {
+		remote, err := storageService.ParseRemote(dest)
+		if err != nil {
+			return nil, err
+		}
+		files, err := storageService.List(remote)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			names[file.Name] = true
+		}
+		return names, nil
+	}
+
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		names[entry.Name()] = true
+	}
+	return names, nil
+}
+
+// Copy copies a backup with its manifest and config copy from the fallback target to the target
+// and adds them to the target's SHA256SUMS. existing holds the names of the files on the target,
+// as returned by ListFiles, and is updated with the files copied. The copies are checked against
+// the fallback's SHA256SUMS where it has entries for them. Files the target already has are not
+// replaced. opts applies to uploads to a remote target.
+// Returns an error if the backup itself could not be copied.
+func Copy(config *configService.BackupConfig, replica Replica, target configService.ResolvedTarget, existing map[string]bool, opts storageService.UploadOptions) (*CopyResult, error) {
+	tmpDir, err := os.MkdirTemp("", "go-backup-replicate-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fromDest := replica.From.GetDestination()
+	available, err := ListFiles(fromDest)
+	if err != nil {
+		return nil, fmt.Errorf("fallback %s not readable: %w", fromDest, err)
+	}
+	name := replica.Record.Filename
+	if !available[name] {
+		return nil, fmt.Errorf("%s is no longer on %s", name, fromDest)
+	}
+
+	// fetch returns a local path of a file on the fallback target, downloading remote ones
+	fetch := func(fileName string) (string, error) {
+		if !storageService.IsRemote(fromDest) {
+			return filepath.Join(fromDest, fileName), nil
+		}
+		remote, err := storageService.ParseRemote(fromDest)
+		if err != nil {
+			return "", err
+		}
+		localPath := filepath.Join(tmpDir, fileName)
+		return localPath, storageService.Download(remote.Join(fileName), localPath)
+	}
+	// store copies a local file to the target, reading a local copy back to check it
+	store := func(localPath, fileName string) error {
+		dest := target.GetDestination()
+		if !storageService.IsRemote(dest) {
+			_, err := backupService.CopyFileVerified(localPath, filepath.Join(dest, fileName))
+			return err
+		}
+		remote, err := storageService.ParseRemote(dest)
+		if err != nil {
+			return err
+		}
+		return storageService.Upload(localPath, remote.Join(fileName), opts)
+	}
+
+	archivePath, err := fetch(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	sum, err := backupService.FileSHA256(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	var fallbackSums map[string]string
+	if available[backupService.ChecksumsFileName] {
+		if sumsPath, err := fetch(backupService.ChecksumsFileName); err == nil {
+			fallbackSums, _ = backupService.ReadChecksums(filepath.Dir(sumsPath))
+		}
+	}
+	if fallbackSums[name] != "" && fallbackSums[name] != sum {
+		return nil, fmt.Errorf("%s on %s does not match its checksum; run 'go-backup repair' or verify the fallback", name, fromDest)
+	}
+
+	result := &CopyResult{}
+	if !existing[name] {
+		if err := store(archivePath, name); err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %w", name, err)
+		}
+		existing[name] = true
+		immutable := config.Rotation != nil && config.Rotation.Immutable
+		if immutable && !storageService.IsRemote(target.GetDestination()) {
+			if _, err := backupService.MakeImmutable(filepath.Join(target.GetDestination(), name)); err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("failed to protect backup - %v", err))
+			}
+		}
+	}
+
+	// Manifests and configs are checked against the fallback's SHA256SUMS as well and listed with the backup
+	sums := map[string]string{name: sum}
+	baseName := backupService.BackupBaseName(name)
+	for _, companion := range []string{
+		baseName + backupService.ManifestSuffix,
+		baseName + backupService.ManifestSuffix + ".gpg",
+		baseName + ".backup.yaml",
+		baseName + ".backup.yaml.gpg",
+		name + backupService.ChecksumSuffix,
+	} {
+		if !available[companion] || existing[companion] {
+			continue
+		}
+		localPath, err := fetch(companion)
+		var companionSum string
+		if err == nil {
+			companionSum, err = backupService.FileSHA256(localPath)
+		}
+		if err == nil && fallbackSums[companion] != "" && fallbackSums[companion] != companionSum {
+			err = fmt.Errorf("it does not match its checksum on %s", fromDest)
+		}
+		if err == nil {
+			err = store(localPath, companion)
+		}
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("failed to copy %s - %v", companion, err))
+			continue
+		}
+		existing[companion] = true
+		result.Companions = append(result.Companions, companion)
+		if companion != name+backupService.ChecksumSuffix {
+			sums[companion] = companionSum
+		}
+	}
+
+	if err := addChecksums(target, existing, sums, tmpDir, opts); err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("failed to update %s - %v", backupService.ChecksumsFileName, err))
+	}
+	return result, nil
+}
+
+// addChecksums adds checksums by file name, of a backup and its companions, to the SHA256SUMS
+// of a local or remote directory target
+func addChecksums(target configService.ResolvedTarget, existing map[string]bool, sums map[string]string, tmpDir string, opts storageService.UploadOptions) error {
+	dest := target.GetDestination()
+	if !storageService.IsRemote(dest) {
+		return backupService.AddChecksums(dest, sums)
+	}
+
+	remote, err := storageService.ParseRemote(dest)
+	if err != nil {
+		return err
+	}
+	sumsDir := filepath.Join(tmpDir, "sums")
+	if err := os.MkdirAll(sumsDir, 0755); err != nil {
+		return err
+	}
+	sumsRemote := remote.Join(backupService.ChecksumsFileName)
+	if existing[backupService.ChecksumsFileName] {
+		if err := storageService.Download(sumsRemote, filepath.Join(sumsDir, backupService.ChecksumsFileName)); err != nil {
+			return err
+		}
+	}
+	if err := backupService.AddChecksums(sumsDir, sums); err != nil {
+		return err
+	}
+	if err := storageService.Upload(filepath.Join(sumsDir, backupService.ChecksumsFileName), sumsRemote, opts); err != nil {
+		return err
+	}
+	existing[backupService.ChecksumsFileName] = true
+	return nil
+}
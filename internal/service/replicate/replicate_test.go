@@ -0,0 +1,214 @@
+package replicate_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	. "github.com/kennycyb/go-backup/internal/service/replicate"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeUploader implements the exec protocol on top of the directory in $FAKE_STORE
+const fakeUploader = `#!/bin/sh
+req=$(cat)
+field() { printf '%s' "$req" | sed -n "s/.*\"$1\":\"\([^\"]*\)\".*/\1/p"; }
+op=$(field operation); path=$(field path); file=$(field file)
+mkdir -p "$FAKE_STORE"
+case "$op" in
+  upload) mkdir -p "$(dirname "$FAKE_STORE/$path")"; cp "$file" "$FAKE_STORE/$path" ;;
+  download) cp "$FAKE_STORE/$path" "$file" ;;
+  list)
+    sep=""; printf '{"files":['
+    for f in "$FAKE_STORE/$path"/*; do
+      [ -f "$f" ] || continue
+      printf '%s{"name":"%s","size":%s}' "$sep" "$(basename "$f")" "$(wc -c < "$f" | tr -d ' ')"; sep=","
+    done
+    printf ']}' ;;
+  *) exit 3 ;;
+esac
+`
+
+// record returns a backup record of the given day in May 2025
+func record(day int) configService.BackupRecord {
+	createdAt := time.Date(2025, 5, day, 12, 30, 45, 0, time.UTC)
+	return configService.BackupRecord{Filename: "project-" + createdAt.Format("20060102-150405") + ".tar.gz", CreatedAt: createdAt}
+}
+
+// resolve returns the targets of the config as commands see them
+func resolve(config *configService.BackupConfig) []configService.ResolvedTarget {
+	targets, err := configService.ResolveTargets(config, configService.TargetFlags{})
+	Expect(err).NotTo(HaveOccurred())
+	return targets
+}
+
+var _ = Describe("Plans", func() {
+	It("should return nothing without both a fallback and a directory target that is not one", func() {
+		config := &configService.BackupConfig{Targets: []configService.BackupTarget{
+			{Path: "/mnt/nas/backups"},
+			{File: "/mnt/usb/latest.tar.gz"},
+		}}
+		Expect(Plans(config, resolve(config))).To(BeNil())
+
+		config.Targets[0].Fallback = true
+		Expect(Plans(config, resolve(config))).To(BeNil())
+	})
+
+	It("should list the backups each target is missing, oldest first", func() {
+		snapshot := record(4)
+		snapshot.SnapshotOf = record(3).Filename
+		config := &configService.BackupConfig{Targets: []configService.BackupTarget{
+			{Path: "/mnt/nas/backups", Backups: []configService.BackupRecord{record(1)}},
+			{Path: "/var/backups", Fallback: true, Backups: []configService.BackupRecord{snapshot, record(3), record(2), record(1)}},
+			{Path: "/mnt/offsite"},
+		}}
+
+		plans := Plans(config, resolve(config))
+		Expect(plans).To(HaveLen(2))
+		Expect(plans[0].Target.GetDestination()).To(Equal("/mnt/nas/backups"))
+		Expect(plans[1].Target.GetDestination()).To(Equal("/mnt/offsite"))
+
+		var names []string
+		for _, replica := range plans[0].Missing {
+			Expect(replica.From.GetDestination()).To(Equal("/var/backups"))
+			names = append(names, replica.Record.Filename)
+		}
+		Expect(names).To(Equal([]string{record(2).Filename, record(3).Filename}))
+		Expect(plans[1].Missing).To(HaveLen(3))
+	})
+
+	It("should leave out backups older than those the target keeps", func() {
+		config := &configService.BackupConfig{Targets: []configService.BackupTarget{
+			{Path: "/mnt/nas/backups", MaxBackups: 2, Backups: []configService.BackupRecord{record(5), record(4)}},
+			{Path: "/var/backups", Fallback: true, Backups: []configService.BackupRecord{record(6), record(3)}},
+		}}
+
+		plans := Plans(config, resolve(config))
+		Expect(plans).To(HaveLen(1))
+		Expect(plans[0].Missing).To(HaveLen(1))
+		Expect(plans[0].Missing[0].Record.Filename).To(Equal(record(6).Filename))
+	})
+
+	It("should skip fallbacks whose backups are encrypted differently", func() {
+		config := &configService.BackupConfig{Targets: []configService.BackupTarget{
+			{Path: "/mnt/nas/backups", Encryption: &configService.EncryptionConfig{Method: "gpg", Receiver: "nas@example.com"}},
+			{Path: "/var/backups", Fallback: true, Backups: []configService.BackupRecord{record(1)}},
+		}}
+
+		plans := Plans(config, resolve(config))
+		Expect(plans).To(HaveLen(1))
+		Expect(plans[0].Missing).To(BeEmpty())
+		Expect(plans[0].Skipped).To(Equal([]string{"/var/backups"}))
+	})
+})
+
+var _ = Describe("Copy", func() {
+	var (
+		tmpDir   string
+		fallback string
+		primary  string
+		config   *configService.BackupConfig
+		replica  Replica
+		name     string
+	)
+
+	BeforeEach(func() {
+		tmpDir = GinkgoT().TempDir()
+		fallback = filepath.Join(tmpDir, "fallback")
+		primary = filepath.Join(tmpDir, "nas")
+		Expect(os.MkdirAll(fallback, 0755)).To(Succeed())
+		Expect(os.MkdirAll(primary, 0755)).To(Succeed())
+
+		name = record(1).Filename
+		manifest := backupService.BackupBaseName(name) + backupService.ManifestSuffix
+		Expect(os.WriteFile(filepath.Join(fallback, name), []byte("archive"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(fallback, manifest), []byte(`{"files":1}`), 0644)).To(Succeed())
+
+		config = &configService.BackupConfig{Targets: []configService.BackupTarget{
+			{Path: primary},
+			{Path: fallback, Fallback: true, Backups: []configService.BackupRecord{record(1)}},
+		}}
+		replica = Plans(config, resolve(config))[0].Missing[0]
+	})
+
+	target := func() configService.ResolvedTarget {
+		return resolve(config)[0]
+	}
+
+	It("should copy a backup with its manifest and add both to the target's SHA256SUMS", func() {
+		existing, err := ListFiles(primary)
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := Copy(config, replica, target(), existing, storageService.UploadOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Warnings).To(BeEmpty())
+		manifest := backupService.BackupBaseName(name) + backupService.ManifestSuffix
+		Expect(result.Companions).To(Equal([]string{manifest}))
+		Expect(os.ReadFile(filepath.Join(primary, name))).To(Equal([]byte("archive")))
+
+		sums, err := backupService.ReadChecksums(primary)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sums).To(HaveKey(name))
+		Expect(sums).To(HaveKey(manifest))
+		Expect(existing).To(HaveKey(name))
+	})
+
+	It("should keep a file the target already has", func() {
+		Expect(os.WriteFile(filepath.Join(primary, name), []byte("already there"), 0644)).To(Succeed())
+		existing, err := ListFiles(primary)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = Copy(config, replica, target(), existing, storageService.UploadOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.ReadFile(filepath.Join(primary, name))).To(Equal([]byte("already there")))
+	})
+
+	It("should refuse a backup that does not match the fallback's SHA256SUMS", func() {
+		Expect(backupService.AddChecksums(fallback, map[string]string{name: "0000"})).To(Succeed())
+
+		_, err := Copy(config, replica, target(), map[string]bool{}, storageService.UploadOptions{})
+		Expect(err).To(MatchError(ContainSubstring("does not match its checksum")))
+		Expect(filepath.Join(primary, name)).NotTo(BeAnExistingFile())
+	})
+
+	It("should fail when the backup is no longer on the fallback", func() {
+		Expect(os.Remove(filepath.Join(fallback, name))).To(Succeed())
+
+		_, err := Copy(config, replica, target(), map[string]bool{}, storageService.UploadOptions{})
+		Expect(err).To(MatchError(ContainSubstring("no longer on")))
+	})
+
+	It("should upload to a remote target and update its SHA256SUMS there", func() {
+		store := filepath.Join(tmpDir, "store")
+		binDir := filepath.Join(tmpDir, "bin")
+		Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binDir, "fake-uploader"), []byte(fakeUploader), 0755)).To(Succeed())
+		GinkgoT().Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+		GinkgoT().Setenv("FAKE_STORE", store)
+		Expect(os.MkdirAll(filepath.Join(store, "backups"), 0755)).To(Succeed())
+		config.Targets[0].Path = "exec:fake-uploader:backups"
+
+		existing, err := ListFiles(target().GetDestination())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(existing).To(BeEmpty())
+
+		result, err := Copy(config, replica, target(), existing, storageService.UploadOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Warnings).To(BeEmpty())
+		Expect(os.ReadFile(filepath.Join(store, "backups", name))).To(Equal([]byte("archive")))
+		sums, err := backupService.ReadChecksums(filepath.Join(store, "backups"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sums).To(HaveKey(name))
+	})
+})
+
+var _ = Describe("ListFiles", func() {
+	It("should fail for a target that is not reachable", func() {
+		_, err := ListFiles(filepath.Join(GinkgoT().TempDir(), "unmounted"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,185 @@
+// Package secrets finds files that look like credentials, such as .env files, SSH keys
+// and AWS credentials, before they are backed up unencrypted to cloud storage
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kennycyb/go-backup/internal/service/compress"
+)
+
+// MaxContentSize is the size up to which file contents are searched; larger files are only
+// checked by name
+const MaxContentSize = 256 * 1024
+
+// MinEntropy is the Shannon entropy in bits per character from which a value assigned to a
+// secret-like name counts as a secret rather than a placeholder such as "changeme"
+const MinEntropy = 3.5
+
+// Finding is a file that looks like it holds a secret
+type Finding struct {
+	Path   string // Relative to the source, with forward slashes
+	Reason string // What the file looks like, e.g. "SSH private key"
+}
+
+// keyFileNames are the default names of SSH private keys
+var keyFileNames = map[string]bool{"id_rsa": true, "id_dsa": true, "id_ecdsa": true, "id_ed25519": true}
+
+// credentialFileNames are files that hold passwords for other tools
+var credentialFileNames = map[string]bool{".netrc": true, ".pgpass": true, ".git-credentials": true}
+
+// keyStoreExtensions are files that bundle private keys with their certificates
+var keyStoreExtensions = map[string]bool{".p12": true, ".pfx": true, ".jks": true, ".keystore": true}
+
+// templateSuffixes mark .env files that hold no real values, e.g. .env.example
+var templateSuffixes = []string{".example", ".sample", ".template", ".dist"}
+
+// contentRules are tokens recognisable by their format alone
+var contentRules = []struct {
+	pattern *regexp.Regexp
+	reason  string
+}{
+	{regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY( BLOCK)?-----`), "private key"},
+	{regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`), "AWS access key"},
+	{regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`), "GitHub token"},
+	{regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`), "Slack token"},
+	{regexp.MustCompile(`\bsk_live_[0-9A-Za-z]{24,}`), "Stripe secret key"},
+}
+
+// assignmentPattern matches a value assigned to a secret-like name, e.g. API_KEY=... or "password": "..."
+var assignmentPattern = regexp.MustCompile(`(?i)([a-z0-9_.-]*(?:secret|passw(?:or)?d|token|api[_-]?key|access[_-]?key|private[_-]?key)[a-z0-9_.-]*)["']?\s*[:=]\s*["']?([A-Za-z0-9+/=_.~-]{16,})`)
+
+// CheckName returns why a file looks like a secret by its path alone
+func CheckName(relPath string) (string, bool) {
+	relPath = filepath.ToSlash(relPath)
+	name := path.Base(relPath)
+	switch {
+	case name == ".env" || strings.HasPrefix(name, ".env."):
+		for _, suffix := range templateSuffixes {
+			if strings.HasSuffix(name, suffix) {
+				return "", false
+			}
+		}
+		return "environment file", true
+	case keyFileNames[name]:
+		return "SSH private key", true
+	case name == "credentials" && path.Base(path.Dir(relPath)) == ".aws":
+		return "AWS credentials file", true
+	case credentialFileNames[name]:
+		return "credentials file", true
+	case keyStoreExtensions[strings.ToLower(path.Ext(name))]:
+		return "key store", true
+	}
+	return "", false
+}
+
+// CheckContent returns why file contents look like they hold a secret. Binary contents are
+// not searched.
+func CheckContent(data []byte) (string, bool) {
+	head := data
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return "", false
+	}
+	for _, rule := range contentRules {
+		if rule.pattern.Match(data) {
+			return rule.reason, true
+		}
+	}
+	for _, match := range assignmentPattern.FindAllSubmatch(data, -1) {
+		if Entropy(string(match[2])) >= MinEntropy {
+			return fmt.Sprintf("random-looking value for %s", match[1]), true
+		}
+	}
+	return "", false
+}
+
+// Entropy returns the Shannon entropy of s in bits per character
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// Scan walks sourceDir and returns the files that look like secrets, leaving out excluded
+// paths. Files are checked by name, and those up to MaxContentSize also by contents.
+func Scan(sourceDir string, excludes []string) ([]Finding, error) {
+	matcher := compress.NewExcludeMatcher(excludes)
+	var findings []Finding
+
+	err := filepath.Walk(sourceDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Unreadable paths are reported by the archive walk; only an unreadable source is fatal here
+			if filePath == sourceDir {
+				return err
+			}
+			return nil
+		}
+		if filePath == sourceDir {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(sourceDir, filePath)
+		if err != nil {
+			return fmt.Errorf("error getting relative path: %w", err)
+		}
+		if info.IsDir() {
+			if matcher.Excluded(relPath) && matcher.CanSkipDir(relPath) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || matcher.Excluded(relPath) {
+			return nil
+		}
+
+		if finding, ok := checkFile(filePath, relPath, info.Size()); ok {
+			findings = append(findings, finding)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning %s for secrets: %w", sourceDir, err)
+	}
+	return findings, nil
+}
+
+// checkFile checks one file by name and, if it is small enough, by contents
+func checkFile(filePath, relPath string, size int64) (Finding, bool) {
+	relPath = filepath.ToSlash(relPath)
+	if reason, ok := CheckName(relPath); ok {
+		return Finding{Path: relPath, Reason: reason}, true
+	}
+	if size > MaxContentSize {
+		return Finding{}, false
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return Finding{}, false
+	}
+	if reason, ok := CheckContent(data); ok {
+		return Finding{Path: relPath, Reason: reason}, true
+	}
+	return Finding{}, false
+}
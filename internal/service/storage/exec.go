@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ExecProtocolVersion is the version of the exec protocol sent with every request
+const ExecProtocolVersion = 1
+
+// Operations of the exec protocol
+const (
+	ExecUpload   = "upload"   // Store File at Path, replacing an existing file
+	ExecDownload = "download" // Write the file at Path to File
+	ExecList     = "list"     // Return the files in the directory Path
+)
+
+// ExecRequest is the JSON request an exec target's command reads from its standard input.
+// The command is run once per request.
+type ExecRequest struct {
+	Version   int    `json:"version"`
+	Operation string `json:"operation"`
+	Path      string `json:"path"`           // Path on the destination: a file, or the directory for list
+	File      string `json:"file,omitempty"` // Local file to upload, or where to write a download
+	Size      int64  `json:"size,omitempty"` // Size of the file to upload
+}
+
+// ExecResponse is the JSON response an exec target's command writes to its standard output.
+// Empty output means success for uploads and downloads.
+type ExecResponse struct {
+	Error string     `json:"error,omitempty"` // Set when the request failed
+	Files []ExecFile `json:"files,omitempty"` // Files found by list
+}
+
+// ExecFile is a file in the response to a list request
+type ExecFile struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"` // RFC 3339; may be left out
+}
+
+// runExec sends a request to the command of an exec location and reads its response.
+// The command may carry arguments, e.g. "corp-blob --bucket backups".
+// Returns an error if the command fails, exits non-zero or reports an error.
+func runExec(ctx context.Context, r *Remote, req ExecRequest) (*ExecResponse, error) {
+	command := strings.Fields(r.Host)
+	if len(command) == 0 {
+		return nil, fmt.Errorf("exec location %q has no command", r.String())
+	}
+	req.Version = ExecProtocolVersion
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := runToolContext(ctx, command[0], input, command[1:]...)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ExecResponse{}
+	if len(strings.TrimSpace(string(output))) > 0 {
+		if err := json.Unmarshal(output, response); err != nil {
+			return nil, fmt.Errorf("%s %s: invalid response: %w", command[0], req.Operation, err)
+		}
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("%s %s failed: %s", command[0], req.Operation, response.Error)
+	}
+	return response, nil
+}
+
+// listExec lists an exec location's directory
+func listExec(ctx context.Context, r *Remote) ([]RemoteFile, error) {
+	response, err := runExec(ctx, r, ExecRequest{Operation: ExecList, Path: r.Path})
+	if err != nil {
+		return nil, err
+	}
+	files := make([]RemoteFile, 0, len(response.Files))
+	for _, file := range response.Files {
+		files = append(files, RemoteFile{Name: file.Name, Size: file.Size, ModTime: file.ModTime})
+	}
+	return files, nil
+}
+
+// uploadExec stores a local file at an exec location
+func uploadExec(ctx context.Context, localPath string, size int64, r *Remote) error {
+	if r.Path == "" || strings.HasSuffix(r.Path, "/") {
+		return fmt.Errorf("invalid exec upload location %q: missing file name", r.String())
+	}
+	_, err := runExec(ctx, r, ExecRequest{Operation: ExecUpload, Path: r.Path, File: localPath, Size: size})
+	return err
+}
+
+// downloadExec has the command of an exec location write a file to localPath
+func downloadExec(ctx context.Context, r *Remote, localPath string) error {
+	if _, err := runExec(ctx, r, ExecRequest{Operation: ExecDownload, Path: r.Path, File: localPath}); err != nil {
+		return err
+	}
+	if _, err := os.Stat(localPath); err != nil {
+		return fmt.Errorf("%s download did not write %s", strings.Fields(r.Host)[0], localPath)
+	}
+	return nil
+}
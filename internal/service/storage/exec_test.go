@@ -0,0 +1,93 @@
+package storage_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/kennycyb/go-backup/internal/service/storage"
+)
+
+// fakeUploader implements the exec protocol on top of the directory in $FAKE_STORE.
+// Its arguments are recorded in $FAKE_STORE.args; a path containing "denied" is refused.
+const fakeUploader = `#!/bin/sh
+echo "$@" > "$FAKE_STORE.args"
+req=$(cat)
+field() { printf '%s' "$req" | sed -n "s/.*\"$1\":\"\([^\"]*\)\".*/\1/p"; }
+op=$(field operation); path=$(field path); file=$(field file)
+case "$path" in *denied*) echo '{"error":"access denied"}'; exit 0 ;; esac
+mkdir -p "$FAKE_STORE"
+case "$op" in
+  upload) mkdir -p "$(dirname "$FAKE_STORE/$path")"; cp "$file" "$FAKE_STORE/$path" ;;
+  download) cp "$FAKE_STORE/$path" "$file" ;;
+  list)
+    sep=""; printf '{"files":['
+    for f in "$FAKE_STORE/$path"/*; do
+      [ -f "$f" ] || continue
+      printf '%s{"name":"%s","size":%s}' "$sep" "$(basename "$f")" "$(wc -c < "$f" | tr -d ' ')"; sep=","
+    done
+    printf ']}' ;;
+  *) exit 3 ;;
+esac
+`
+
+var _ = Describe("Exec targets", func() {
+	var (
+		tmpDir string
+		store  string
+		local  string
+	)
+
+	BeforeEach(func() {
+		tmpDir = GinkgoT().TempDir()
+		store = filepath.Join(tmpDir, "store")
+		binDir := filepath.Join(tmpDir, "bin")
+		Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binDir, "fake-uploader"), []byte(fakeUploader), 0755)).To(Succeed())
+		GinkgoT().Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+		GinkgoT().Setenv("FAKE_STORE", store)
+
+		local = filepath.Join(tmpDir, "backup.tar.gz")
+		Expect(os.WriteFile(local, []byte("archive"), 0644)).To(Succeed())
+	})
+
+	It("should upload, list and download through the command", func() {
+		remote, err := ParseRemote("exec:fake-uploader --bucket team:backups/backup.tar.gz")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Upload(local, remote, UploadOptions{})).To(Succeed())
+		Expect(filepath.Join(store, "backups/backup.tar.gz")).To(BeAnExistingFile())
+		Expect(os.ReadFile(store + ".args")).To(Equal([]byte("--bucket team\n")))
+
+		files, err := List(remote.Dir())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		Expect(files[0].Name).To(Equal("backup.tar.gz"))
+		Expect(files[0].Size).To(Equal(int64(7)))
+
+		downloaded := filepath.Join(tmpDir, "downloaded.tar.gz")
+		Expect(Download(remote, downloaded)).To(Succeed())
+		Expect(os.ReadFile(downloaded)).To(Equal([]byte("archive")))
+	})
+
+	It("should report errors returned by the command", func() {
+		remote, err := ParseRemote("exec:fake-uploader:denied/backup.tar.gz")
+		Expect(err).NotTo(HaveOccurred())
+		err = Upload(local, remote, UploadOptions{})
+		Expect(err).To(MatchError(ContainSubstring("access denied")))
+	})
+
+	It("should fail when the command exits with an error", func() {
+		remote, err := ParseRemote("exec:fake-uploader:backups/missing.tar.gz")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Download(remote, filepath.Join(tmpDir, "missing.tar.gz"))).NotTo(Succeed())
+		Expect(filepath.Join(tmpDir, "missing.tar.gz")).NotTo(BeAnExistingFile())
+	})
+
+	It("should refuse uploads without a file name", func() {
+		remote, err := ParseRemote("exec:fake-uploader:backups/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Upload(local, remote, UploadOptions{})).NotTo(Succeed())
+	})
+})
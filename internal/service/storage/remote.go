@@ -0,0 +1,566 @@
+// Package storage provides access to backups on remote targets.
+// Remote locations are handled by the standard command line tools for each scheme, so their
+// configuration and credentials apply unchanged: aws for s3://, sftp for sftp:// and rclone for rclone:.
+// exec: locations are handled by a user's own command speaking the JSON exec protocol.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported remote location schemes
+const (
+	SchemeS3     = "s3"
+	SchemeSFTP   = "sftp"
+	SchemeRclone = "rclone"
+	SchemeExec   = "exec"
+)
+
+// Remote is a parsed remote location such as s3://bucket/backups/ or rclone:gdrive:backups
+type Remote struct {
+	Scheme string
+	User   string // SFTP only
+	Host   string // S3 bucket, SFTP host, rclone remote name or exec command
+	Port   string // SFTP only
+	Key    string // SFTP only: the private key to log in with; empty uses ssh's configuration
+	Path   string // Path within the host, without a leading slash for S3 and rclone
+	// Region and Endpoint are S3 only: the bucket's region and the URL of an S3-compatible
+	// service such as MinIO; empty uses the aws CLI's configuration
+	Region   string
+	Endpoint string
+}
+
+// Query parameters of s3:// and sftp:// locations
+const (
+	s3RegionParam   = "region"
+	s3EndpointParam = "endpoint"
+	sftpKeyParam    = "key"
+)
+
+// RemoteFile is a file found when listing a remote location
+type RemoteFile struct {
+	Name    string
+	Size    int64
+	ModTime time.Time // Zero if the tool does not report it
+}
+
+// IsRemote reports whether the location refers to a remote target rather than a local path
+func IsRemote(location string) bool {
+	return strings.HasPrefix(location, SchemeS3+"://") ||
+		strings.HasPrefix(location, SchemeSFTP+"://") ||
+		strings.HasPrefix(location, SchemeRclone+":") ||
+		strings.HasPrefix(location, SchemeExec+":")
+}
+
+// ParseRemote parses a remote location.
+// Supported forms are s3://bucket/path[?region=...&endpoint=...], sftp://[user@]host[:port]/path[?key=...],
+// rclone:remote:path and exec:command:path.
+// Returns an error if the location is not a supported remote location.
+func ParseRemote(location string) (*Remote, error) {
+	if rest, ok := strings.CutPrefix(location, SchemeRclone+":"); ok && !strings.HasPrefix(rest, "//") {
+		name, remotePath, found := strings.Cut(rest, ":")
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid rclone location %q (expected rclone:remote:path)", location)
+		}
+		return &Remote{Scheme: SchemeRclone, Host: name, Path: remotePath}, nil
+	}
+	if rest, ok := strings.CutPrefix(location, SchemeExec+":"); ok {
+		command, remotePath, found := strings.Cut(rest, ":")
+		if !found || strings.TrimSpace(command) == "" {
+			return nil, fmt.Errorf("invalid exec location %q (expected exec:command:path)", location)
+		}
+		return &Remote{Scheme: SchemeExec, Host: command, Path: remotePath}, nil
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote location %q: %w", location, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid remote location %q: missing host", location)
+	}
+
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote location %q: %w", location, err)
+	}
+	switch u.Scheme {
+	case SchemeS3:
+		remote := &Remote{Scheme: SchemeS3, Host: u.Host, Path: strings.TrimPrefix(u.Path, "/")}
+		for key := range query {
+			if key != s3RegionParam && key != s3EndpointParam {
+				return nil, fmt.Errorf("invalid remote location %q: unknown parameter %q (expected %s or %s)", location, key, s3RegionParam, s3EndpointParam)
+			}
+		}
+		remote.Region, remote.Endpoint = query.Get(s3RegionParam), query.Get(s3EndpointParam)
+		return remote, nil
+	case SchemeSFTP:
+		for key := range query {
+			if key != sftpKeyParam {
+				return nil, fmt.Errorf("invalid remote location %q: unknown parameter %q (expected %s)", location, key, sftpKeyParam)
+			}
+		}
+		remote := &Remote{Scheme: SchemeSFTP, Host: u.Hostname(), Port: u.Port(), Key: query.Get(sftpKeyParam), Path: u.Path}
+		if u.User != nil {
+			remote.User = u.User.Username()
+		}
+		if remote.Path == "" {
+			remote.Path = "/"
+		}
+		return remote, nil
+	}
+	return nil, fmt.Errorf("unsupported remote location %q (expected s3://, sftp://, rclone: or exec:)", location)
+}
+
+// String returns the location in the form accepted by ParseRemote
+func (r *Remote) String() string {
+	switch r.Scheme {
+	case SchemeRclone, SchemeExec:
+		return fmt.Sprintf("%s:%s:%s", r.Scheme, r.Host, r.Path)
+	case SchemeSFTP:
+		if r.Key != "" {
+			return "sftp://" + r.sshHost() + r.Path + "?" + url.Values{sftpKeyParam: {r.Key}}.Encode()
+		}
+		return "sftp://" + r.sshHost() + r.Path
+	case SchemeS3:
+		query := url.Values{}
+		if r.Region != "" {
+			query.Set(s3RegionParam, r.Region)
+		}
+		if r.Endpoint != "" {
+			query.Set(s3EndpointParam, r.Endpoint)
+		}
+		if len(query) > 0 {
+			return r.s3URL() + "?" + query.Encode()
+		}
+	}
+	return fmt.Sprintf("%s://%s/%s", r.Scheme, r.Host, r.Path)
+}
+
+// s3URL returns the s3://bucket/key form the aws CLI accepts, without region and endpoint
+func (r *Remote) s3URL() string {
+	return fmt.Sprintf("s3://%s/%s", r.Host, r.Path)
+}
+
+// awsArgs appends the aws CLI options selecting the S3 location's region and endpoint
+func (r *Remote) awsArgs(args ...string) []string {
+	if r.Region != "" {
+		args = append(args, "--region", r.Region)
+	}
+	if r.Endpoint != "" {
+		args = append(args, "--endpoint-url", r.Endpoint)
+	}
+	return args
+}
+
+// Base returns the last element of the remote path
+func (r *Remote) Base() string {
+	return path.Base(strings.TrimSuffix(r.Path, "/"))
+}
+
+// Dir returns the remote location of the directory containing this one
+func (r *Remote) Dir() *Remote {
+	dir := *r
+	dir.Path = path.Dir(strings.TrimSuffix(r.Path, "/"))
+	if dir.Path == "." {
+		dir.Path = ""
+	}
+	if dir.Path != "" && !strings.HasSuffix(dir.Path, "/") {
+		dir.Path += "/"
+	}
+	return &dir
+}
+
+// Join returns the remote location of a file inside this directory
+func (r *Remote) Join(name string) *Remote {
+	joined := *r
+	if joined.Path == "" || strings.HasSuffix(joined.Path, "/") {
+		joined.Path += name
+	} else {
+		joined.Path += "/" + name
+	}
+	return &joined
+}
+
+// sshHost returns [user@]host[:port] for SFTP locations
+func (r *Remote) sshHost() string {
+	host := r.Host
+	if r.User != "" {
+		host = r.User + "@" + host
+	}
+	if r.Port != "" {
+		host += ":" + r.Port
+	}
+	return host
+}
+
+// sftpArgs returns the sftp connection arguments for the remote host
+func (r *Remote) sftpArgs() []string {
+	args := []string{"-q"}
+	if r.Port != "" {
+		args = append(args, "-P", r.Port)
+	}
+	if r.Key != "" {
+		args = append(args, "-i", r.Key)
+	}
+	host := r.Host
+	if r.User != "" {
+		host = r.User + "@" + host
+	}
+	return append(args, host)
+}
+
+// List returns the files in the remote directory.
+// Returns an error if the tool for the scheme is missing or fails.
+func List(r *Remote) ([]RemoteFile, error) {
+	return ListContext(context.Background(), r)
+}
+
+// ListContext is List that stops when the context is done
+func ListContext(ctx context.Context, r *Remote) ([]RemoteFile, error) {
+	switch r.Scheme {
+	case SchemeS3:
+		prefix := r.Path
+		if prefix != "" && !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		output, err := runToolContext(ctx, "aws", nil, r.awsArgs("s3", "ls", fmt.Sprintf("s3://%s/%s", r.Host, prefix))...)
+		if err != nil {
+			return nil, err
+		}
+		return parseS3Listing(string(output)), nil
+	case SchemeSFTP:
+		batch := fmt.Sprintf("ls -ln %s\n", quoteSFTPPath(r.Path))
+		output, err := runToolContext(ctx, "sftp", []byte(batch), append([]string{"-b", "-"}, r.sftpArgs()...)...)
+		if err != nil {
+			return nil, err
+		}
+		return parseSFTPListing(string(output)), nil
+	case SchemeRclone:
+		output, err := runToolContext(ctx, "rclone", nil, "lsjson", "--files-only", r.Host+":"+r.Path)
+		if err != nil {
+			return nil, err
+		}
+		return parseRcloneListing(output)
+	case SchemeExec:
+		return listExec(ctx, r)
+	}
+	return nil, fmt.Errorf("unsupported remote scheme %q", r.Scheme)
+}
+
+// Download copies the remote file to localPath.
+// The file is written to a temporary name first, so an interrupted download never leaves a partial file at localPath.
+// Returns an error if the tool for the scheme is missing or fails.
+func Download(r *Remote, localPath string) error {
+	partPath := localPath + ".part"
+	defer os.Remove(partPath)
+
+	var err error
+	switch r.Scheme {
+	case SchemeS3:
+		_, err = runTool("aws", nil, r.awsArgs("s3", "cp", "--only-show-errors", r.s3URL(), partPath)...)
+	case SchemeSFTP:
+		batch := fmt.Sprintf("get %s %s\n", quoteSFTPPath(r.Path), quoteSFTPPath(partPath))
+		_, err = runTool("sftp", []byte(batch), append([]string{"-b", "-"}, r.sftpArgs()...)...)
+	case SchemeRclone:
+		_, err = runTool("rclone", nil, "copyto", r.Host+":"+r.Path, partPath)
+	case SchemeExec:
+		err = downloadExec(context.Background(), r, partPath)
+	default:
+		return fmt.Errorf("unsupported remote scheme %q", r.Scheme)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(partPath, localPath); err != nil {
+		return fmt.Errorf("error saving downloaded file: %w", err)
+	}
+	return nil
+}
+
+// ErrRangeUnsupported is returned by DownloadRange for schemes that can only download whole files
+var ErrRangeUnsupported = errors.New("reading part of a file is not supported for this remote location")
+
+// DownloadRange copies length bytes of the remote file, starting at offset, to localPath.
+// Only s3:// and rclone: locations can read part of a file; others return ErrRangeUnsupported.
+// Returns an error if the tool for the scheme is missing or fails.
+func DownloadRange(r *Remote, offset, length int64, localPath string) error {
+	partPath := localPath + ".part"
+	defer os.Remove(partPath)
+
+	var err error
+	switch r.Scheme {
+	case SchemeS3:
+		byteRange := fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+		_, err = runTool("aws", nil, r.awsArgs("s3api", "get-object", "--bucket", r.Host, "--key", r.Path, "--range", byteRange, partPath)...)
+	case SchemeRclone:
+		err = runToolToFile(partPath, "rclone", "cat", "--offset", strconv.FormatInt(offset, 10),
+			"--count", strconv.FormatInt(length, 10), r.Host+":"+r.Path)
+	default:
+		return ErrRangeUnsupported
+	}
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(partPath)
+	if err != nil {
+		return fmt.Errorf("error reading downloaded part: %w", err)
+	}
+	if info.Size() != length {
+		return fmt.Errorf("downloaded %d of %d bytes from %s", info.Size(), length, r)
+	}
+	if err := os.Rename(partPath, localPath); err != nil {
+		return fmt.Errorf("error saving downloaded part: %w", err)
+	}
+	return nil
+}
+
+// ErrDeleteUnsupported is returned by Delete for schemes that cannot remove files
+var ErrDeleteUnsupported = errors.New("deleting files is not supported for this remote location")
+
+// Delete removes the remote file.
+// exec: locations return ErrDeleteUnsupported, since the exec protocol has no delete request.
+// Returns an error if the tool for the scheme is missing or fails.
+func Delete(r *Remote) error {
+	var err error
+	switch r.Scheme {
+	case SchemeS3:
+		_, err = runTool("aws", nil, r.awsArgs("s3", "rm", "--only-show-errors", r.s3URL())...)
+	case SchemeSFTP:
+		batch := fmt.Sprintf("rm %s\n", quoteSFTPPath(r.Path))
+		_, err = runTool("sftp", []byte(batch), append([]string{"-b", "-"}, r.sftpArgs()...)...)
+	case SchemeRclone:
+		_, err = runTool("rclone", nil, "deletefile", r.Host+":"+r.Path)
+	case SchemeExec:
+		return ErrDeleteUnsupported
+	default:
+		return fmt.Errorf("unsupported remote scheme %q", r.Scheme)
+	}
+	return err
+}
+
+// CacheDir returns the directory holding downloaded remote files
+func CacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "go-backup", "remote"), nil
+}
+
+// CachePath returns where the remote file is kept in the local cache.
+// Files from different remote directories never share a cache path.
+func CachePath(r *Remote) (string, error) {
+	cacheDir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(r.Dir().String()))
+	return filepath.Join(cacheDir, hex.EncodeToString(sum[:])[:16], r.Base()), nil
+}
+
+// Fetch returns a local copy of the remote file, downloading it into the cache unless it is already there.
+// Backups are never modified once written, so a cached copy is reused unless refresh is set.
+// The second return value reports whether the file was served from the cache.
+func Fetch(r *Remote, refresh bool) (string, bool, error) {
+	localPath, err := CachePath(r)
+	if err != nil {
+		return "", false, err
+	}
+
+	if !refresh {
+		if _, err := os.Stat(localPath); err == nil {
+			return localPath, true, nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0700); err != nil {
+		return "", false, fmt.Errorf("error creating cache directory: %w", err)
+	}
+	if err := Download(r, localPath); err != nil {
+		return "", false, err
+	}
+	return localPath, false, nil
+}
+
+// toolWaitDelay is how long a stopped tool's output is still read, in case a child
+// process such as ssh keeps it open
+const toolWaitDelay = 5 * time.Second
+
+// runTool runs an external command with optional stdin and returns its standard output.
+// Returns an error including the tool's error output if it fails.
+func runTool(name string, stdin []byte, args ...string) ([]byte, error) {
+	return runToolContext(context.Background(), name, stdin, args...)
+}
+
+// runToolContext is runTool for a command that is killed when the context is done
+func runToolContext(ctx context.Context, name string, stdin []byte, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, fmt.Errorf("%s is required for this remote location but was not found in PATH", name)
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = toolWaitDelay
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("%s stopped: %w", name, ctx.Err())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w, details: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// runToolToFile runs an external command and writes its standard output to a new file at path,
+// for output too large to hold in memory
+func runToolToFile(path, name string, args ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%s is required for this remote location but was not found in PATH", name)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", path, err)
+	}
+	defer file.Close()
+
+	cmd := exec.Command(name, args...)
+	cmd.WaitDelay = toolWaitDelay
+	cmd.Stdout = file
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w, details: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return file.Close()
+}
+
+// quoteSFTPPath quotes a path for an sftp batch command
+func quoteSFTPPath(p string) string {
+	return `"` + strings.ReplaceAll(p, `"`, `\"`) + `"`
+}
+
+// parseS3Listing parses `aws s3 ls` output, e.g. "2025-05-20 12:30:45    1048576 name".
+// Common prefixes ("PRE dir/") are skipped.
+func parseS3Listing(output string) []RemoteFile {
+	var files []RemoteFile
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] == "PRE" {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		modTime, _ := time.ParseInLocation("2006-01-02 15:04:05", fields[0]+" "+fields[1], time.Local)
+
+		// Names may contain spaces, so take everything after the size column
+		files = append(files, RemoteFile{Name: afterFields(line, 3), Size: size, ModTime: modTime})
+	}
+	return files
+}
+
+// parseSFTPListing parses `ls -ln` output from sftp, e.g.
+// "-rw-r--r--    1 1000     1000      1048576 May 20 12:30 /backups/name".
+// Directories and echoed batch commands are skipped.
+func parseSFTPListing(output string) []RemoteFile {
+	var files []RemoteFile
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 9 || !strings.HasPrefix(fields[0], "-") {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		// The date is "May 20 12:30" for recent files and "May 20 2024" for older ones
+		stamp := strings.Join(fields[5:8], " ")
+		modTime, err := time.ParseInLocation("Jan 2 2006", stamp, time.Local)
+		if err != nil {
+			if t, err := time.ParseInLocation("Jan 2 15:04", stamp, time.Local); err == nil {
+				now := time.Now()
+				modTime = t.AddDate(now.Year(), 0, 0)
+				if modTime.After(now) {
+					modTime = modTime.AddDate(-1, 0, 0)
+				}
+			}
+		}
+
+		files = append(files, RemoteFile{Name: path.Base(afterFields(line, 8)), Size: size, ModTime: modTime})
+	}
+	return files
+}
+
+// afterFields returns the rest of the line after the first n whitespace separated fields
+func afterFields(line string, n int) string {
+	rest := strings.TrimLeft(line, " \t")
+	for i := 0; i < n; i++ {
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			return ""
+		}
+		rest = strings.TrimLeft(rest[end:], " \t")
+	}
+	return strings.TrimRight(rest, "\r")
+}
+
+// parseRcloneListing parses `rclone lsjson` output
+func parseRcloneListing(output []byte) ([]RemoteFile, error) {
+	var entries []struct {
+		Name    string
+		Size    int64
+		ModTime time.Time
+		IsDir   bool
+	}
+	if err := json.Unmarshal(output, &entries); err != nil {
+		return nil, fmt.Errorf("error parsing rclone listing: %w", err)
+	}
+
+	var files []RemoteFile
+	for _, entry := range entries {
+		if entry.IsDir {
+			continue
+		}
+		files = append(files, RemoteFile{Name: entry.Name, Size: entry.Size, ModTime: entry.ModTime})
+	}
+	return files, nil
+}
+
+// TestHelperParseS3Listing exposes the parseS3Listing function for testing
+func TestHelperParseS3Listing(output string) []RemoteFile {
+	return parseS3Listing(output)
+}
+
+// TestHelperParseSFTPListing exposes the parseSFTPListing function for testing
+func TestHelperParseSFTPListing(output string) []RemoteFile {
+	return parseSFTPListing(output)
+}
+
+// TestHelperParseRcloneListing exposes the parseRcloneListing function for testing
+func TestHelperParseRcloneListing(output []byte) ([]RemoteFile, error) {
+	return parseRcloneListing(output)
+}
@@ -0,0 +1,301 @@
+package storage_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/kennycyb/go-backup/internal/service/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Remote", func() {
+	Describe("IsRemote", func() {
+		It("should recognise remote locations", func() {
+			Expect(IsRemote("s3://bucket/backups/")).To(BeTrue())
+			Expect(IsRemote("sftp://user@host/backups")).To(BeTrue())
+			Expect(IsRemote("rclone:gdrive:backups")).To(BeTrue())
+			Expect(IsRemote("exec:corp-blob:backups")).To(BeTrue())
+		})
+
+		It("should treat other paths as local", func() {
+			Expect(IsRemote("/mnt/backups")).To(BeFalse())
+			Expect(IsRemote("backups/s3://x")).To(BeFalse())
+			Expect(IsRemote("C:\\backups")).To(BeFalse())
+		})
+	})
+
+	Describe("ParseRemote", func() {
+		It("should parse S3 locations", func() {
+			remote, err := ParseRemote("s3://bucket/backups/project-20250520-123045.tar.gz")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.Scheme).To(Equal(SchemeS3))
+			Expect(remote.Host).To(Equal("bucket"))
+			Expect(remote.Path).To(Equal("backups/project-20250520-123045.tar.gz"))
+			Expect(remote.Base()).To(Equal("project-20250520-123045.tar.gz"))
+			Expect(remote.Dir().String()).To(Equal("s3://bucket/backups/"))
+		})
+
+		It("should parse the region and endpoint of S3 locations", func() {
+			remote, err := ParseRemote("s3://bucket/backups/?endpoint=http%3A%2F%2Fminio.local%3A9000&region=eu-central-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.Host).To(Equal("bucket"))
+			Expect(remote.Path).To(Equal("backups/"))
+			Expect(remote.Region).To(Equal("eu-central-1"))
+			Expect(remote.Endpoint).To(Equal("http://minio.local:9000"))
+
+			file := remote.Join("a.tar.gz")
+			Expect(file.Base()).To(Equal("a.tar.gz"))
+			Expect(file.String()).To(Equal("s3://bucket/backups/a.tar.gz?endpoint=http%3A%2F%2Fminio.local%3A9000&region=eu-central-1"))
+			Expect(ParseRemote(file.String())).To(Equal(file))
+
+			_, err = ParseRemote("s3://bucket/backups/?profile=work")
+			Expect(err).To(MatchError(ContainSubstring(`unknown parameter "profile"`)))
+		})
+
+		It("should parse SFTP locations with user and port", func() {
+			remote, err := ParseRemote("sftp://alice@nas.local:2222/srv/backups")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.Scheme).To(Equal(SchemeSFTP))
+			Expect(remote.User).To(Equal("alice"))
+			Expect(remote.Host).To(Equal("nas.local"))
+			Expect(remote.Port).To(Equal("2222"))
+			Expect(remote.Path).To(Equal("/srv/backups"))
+			Expect(remote.Join("a.tar.gz").String()).To(Equal("sftp://alice@nas.local:2222/srv/backups/a.tar.gz"))
+		})
+
+		It("should parse the key of SFTP locations", func() {
+			remote, err := ParseRemote("sftp://alice@nas.local/srv/backups/?key=~%2F.ssh%2Fnas")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.Key).To(Equal("~/.ssh/nas"))
+			Expect(remote.Path).To(Equal("/srv/backups/"))
+			file := remote.Join("a.tar.gz")
+			Expect(file.String()).To(Equal("sftp://alice@nas.local/srv/backups/a.tar.gz?key=~%2F.ssh%2Fnas"))
+			Expect(ParseRemote(file.String())).To(Equal(file))
+
+			_, err = ParseRemote("sftp://nas.local/srv/backups/?region=eu")
+			Expect(err).To(MatchError(ContainSubstring(`unknown parameter "region"`)))
+		})
+
+		It("should parse rclone locations", func() {
+			remote, err := ParseRemote("rclone:gdrive:backups/project")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.Scheme).To(Equal(SchemeRclone))
+			Expect(remote.Host).To(Equal("gdrive"))
+			Expect(remote.Path).To(Equal("backups/project"))
+			Expect(remote.String()).To(Equal("rclone:gdrive:backups/project"))
+		})
+
+		It("should parse exec locations with command arguments", func() {
+			remote, err := ParseRemote("exec:corp-blob --bucket team:backups/project")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.Scheme).To(Equal(SchemeExec))
+			Expect(remote.Host).To(Equal("corp-blob --bucket team"))
+			Expect(remote.Path).To(Equal("backups/project"))
+			Expect(remote.Join("a.tar.gz").String()).To(Equal("exec:corp-blob --bucket team:backups/project/a.tar.gz"))
+		})
+
+		It("should reject invalid locations", func() {
+			_, err := ParseRemote("exec::backups")
+			Expect(err).To(HaveOccurred())
+			_, err = ParseRemote("rclone:missing-path")
+			Expect(err).To(HaveOccurred())
+			_, err = ParseRemote("s3:///no-bucket")
+			Expect(err).To(HaveOccurred())
+			_, err = ParseRemote("ftp://host/backups")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("listing parsers", func() {
+		It("should parse aws s3 ls output", func() {
+			output := "                           PRE old/\n" +
+				"2025-05-20 12:30:45    1048576 project-20250520-123045.tar.gz\n" +
+				"2025-05-21 08:00:00         20 name with spaces.tar.gz\n"
+			files := TestHelperParseS3Listing(output)
+			Expect(files).To(HaveLen(2))
+			Expect(files[0].Name).To(Equal("project-20250520-123045.tar.gz"))
+			Expect(files[0].Size).To(Equal(int64(1048576)))
+			Expect(files[0].ModTime.Year()).To(Equal(2025))
+			Expect(files[1].Name).To(Equal("name with spaces.tar.gz"))
+			Expect(files[1].Size).To(Equal(int64(20)))
+		})
+
+		It("should parse sftp ls -ln output", func() {
+			output := "drwxr-xr-x    2 1000     1000         4096 May 20 12:30 /srv/backups/old\n" +
+				"-rw-r--r--    1 1000     1000      1048576 May 20  2024 /srv/backups/project-20240520-123045.tar.gz\n"
+			files := TestHelperParseSFTPListing(output)
+			Expect(files).To(HaveLen(1))
+			Expect(files[0].Name).To(Equal("project-20240520-123045.tar.gz"))
+			Expect(files[0].Size).To(Equal(int64(1048576)))
+			Expect(files[0].ModTime.Year()).To(Equal(2024))
+		})
+
+		It("should parse rclone lsjson output", func() {
+			output := `[{"Path":"a.tar.gz","Name":"a.tar.gz","Size":42,"ModTime":"2025-05-20T12:30:45Z","IsDir":false},
+				{"Path":"old","Name":"old","Size":-1,"ModTime":"2025-05-20T12:30:45Z","IsDir":true}]`
+			files, err := TestHelperParseRcloneListing([]byte(output))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+			Expect(files[0].Name).To(Equal("a.tar.gz"))
+			Expect(files[0].ModTime).To(Equal(time.Date(2025, 5, 20, 12, 30, 45, 0, time.UTC)))
+		})
+	})
+
+	Describe("Fetch", func() {
+		var (
+			tmpDir    string
+			remoteDir string
+		)
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = os.MkdirTemp("", "storage-test")
+			Expect(err).NotTo(HaveOccurred())
+			remoteDir = filepath.Join(tmpDir, "remote")
+			Expect(os.MkdirAll(remoteDir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(remoteDir, "backup.tar.gz"), []byte("v1"), 0644)).To(Succeed())
+
+			// A fake rclone that serves "fake:<path>" from the local file system
+			binDir := filepath.Join(tmpDir, "bin")
+			Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+			script := "#!/bin/sh\n[ \"$1\" = copyto ] || exit 1\ncp \"${2#fake:}\" \"$3\"\n"
+			Expect(os.WriteFile(filepath.Join(binDir, "rclone"), []byte(script), 0755)).To(Succeed())
+
+			GinkgoT().Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+			GinkgoT().Setenv("XDG_CACHE_HOME", filepath.Join(tmpDir, "cache"))
+			GinkgoT().Setenv("HOME", tmpDir)
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(tmpDir)
+		})
+
+		It("should download into the cache and reuse the cached copy", func() {
+			remote, err := ParseRemote("rclone:fake:" + filepath.Join(remoteDir, "backup.tar.gz"))
+			Expect(err).NotTo(HaveOccurred())
+
+			localPath, cached, err := Fetch(remote, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached).To(BeFalse())
+			Expect(os.ReadFile(localPath)).To(Equal([]byte("v1")))
+
+			// The cached copy is used even if the remote changes
+			Expect(os.WriteFile(filepath.Join(remoteDir, "backup.tar.gz"), []byte("v2"), 0644)).To(Succeed())
+			localPath, cached, err = Fetch(remote, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached).To(BeTrue())
+			Expect(os.ReadFile(localPath)).To(Equal([]byte("v1")))
+
+			// Refresh downloads again
+			localPath, cached, err = Fetch(remote, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached).To(BeFalse())
+			Expect(os.ReadFile(localPath)).To(Equal([]byte("v2")))
+		})
+
+		It("should not leave a file in the cache when the download fails", func() {
+			remote, err := ParseRemote("rclone:fake:" + filepath.Join(remoteDir, "missing.tar.gz"))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _, err = Fetch(remote, false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("rclone failed"))
+
+			localPath, err := CachePath(remote)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(localPath).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Describe("Delete", func() {
+		var (
+			tmpDir  string
+			logPath string
+		)
+
+		BeforeEach(func() {
+			tmpDir = GinkgoT().TempDir()
+			logPath = filepath.Join(tmpDir, "aws.log")
+
+			// A fake aws that records its arguments
+			binDir := filepath.Join(tmpDir, "bin")
+			Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+			script := "#!/bin/sh\necho \"$@\" >> \"$AWS_LOG\"\n"
+			Expect(os.WriteFile(filepath.Join(binDir, "aws"), []byte(script), 0755)).To(Succeed())
+			GinkgoT().Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+			GinkgoT().Setenv("AWS_LOG", logPath)
+		})
+
+		It("should remove S3 objects through the location's region and endpoint", func() {
+			remote, err := ParseRemote("s3://bucket/backups/a.tar.gz?region=eu-central-1&endpoint=http://minio.local:9000")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(Delete(remote)).To(Succeed())
+			Expect(os.ReadFile(logPath)).To(Equal([]byte("s3 rm --only-show-errors s3://bucket/backups/a.tar.gz --region eu-central-1 --endpoint-url http://minio.local:9000\n")))
+		})
+
+		It("should remove SFTP files with the location's port and key", func() {
+			// A fake sftp that records its arguments and batch commands
+			script := "#!/bin/sh\necho \"$@\" >> \"$AWS_LOG\"\ncat >> \"$AWS_LOG\"\n"
+			Expect(os.WriteFile(filepath.Join(tmpDir, "bin", "sftp"), []byte(script), 0755)).To(Succeed())
+
+			remote, err := ParseRemote("sftp://backup@nas:2222/backups/a.tar.gz?key=/keys/nas")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(Delete(remote)).To(Succeed())
+			Expect(os.ReadFile(logPath)).To(Equal([]byte("-b - -q -P 2222 -i /keys/nas backup@nas\nrm \"/backups/a.tar.gz\"\n")))
+		})
+
+		It("should refuse exec locations", func() {
+			remote, err := ParseRemote("exec:corp-blob:backups/a.tar.gz")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(Delete(remote)).To(MatchError(ErrDeleteUnsupported))
+		})
+	})
+
+	Describe("DownloadRange", func() {
+		var tmpDir string
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = os.MkdirTemp("", "storage-range-test")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(tmpDir, "backup.tar.gz"), []byte("0123456789"), 0644)).To(Succeed())
+
+			// A fake rclone whose cat serves "fake:<path>" from the local file system
+			binDir := filepath.Join(tmpDir, "bin")
+			Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+			script := "#!/bin/sh\n[ \"$1\" = cat ] || exit 1\ntail -c +$(($3 + 1)) \"${6#fake:}\" | head -c \"$5\"\n"
+			Expect(os.WriteFile(filepath.Join(binDir, "rclone"), []byte(script), 0755)).To(Succeed())
+			GinkgoT().Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(tmpDir)
+		})
+
+		It("should download part of a file", func() {
+			remote, err := ParseRemote("rclone:fake:" + filepath.Join(tmpDir, "backup.tar.gz"))
+			Expect(err).NotTo(HaveOccurred())
+
+			localPath := filepath.Join(tmpDir, "part")
+			Expect(DownloadRange(remote, 3, 4, localPath)).To(Succeed())
+			Expect(os.ReadFile(localPath)).To(Equal([]byte("3456")))
+		})
+
+		It("should fail when the file ends before the range", func() {
+			remote, err := ParseRemote("rclone:fake:" + filepath.Join(tmpDir, "backup.tar.gz"))
+			Expect(err).NotTo(HaveOccurred())
+
+			localPath := filepath.Join(tmpDir, "part")
+			Expect(DownloadRange(remote, 8, 4, localPath)).To(MatchError(ContainSubstring("downloaded 2 of 4 bytes")))
+			Expect(localPath).NotTo(BeAnExistingFile())
+		})
+
+		It("should report schemes that cannot read part of a file", func() {
+			remote, err := ParseRemote("sftp://backup@nas/backups/backup.tar.gz")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(DownloadRange(remote, 0, 4, filepath.Join(tmpDir, "part"))).To(MatchError(ErrRangeUnsupported))
+		})
+	})
+})
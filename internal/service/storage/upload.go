@@ -0,0 +1,310 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Default upload settings, chosen for multi-GB archives on high-latency links
+const (
+	DefaultPartSize    = 64 << 20
+	DefaultConcurrency = 4
+)
+
+// S3 multipart upload limits
+const (
+	minS3PartSize = 5 << 20
+	maxS3Parts    = 10000
+)
+
+// sftpRequestsPerStream is the sftp default for outstanding requests (-R)
+const sftpRequestsPerStream = 64
+
+// UploadOptions controls how large files are split into parts and how many are sent at once
+type UploadOptions struct {
+	PartSize    int64       // Bytes per part; 0 uses DefaultPartSize
+	Concurrency int         // Parts in flight at once; 0 uses DefaultConcurrency
+	ObjectLock  *ObjectLock // S3 only: protect the uploaded object from deletion until the retention date
+}
+
+// S3 object lock modes. Governance retention can be lifted by users with a special permission;
+// compliance retention cannot be shortened or removed by anyone, including the root account.
+const (
+	ObjectLockGovernance = "GOVERNANCE"
+	ObjectLockCompliance = "COMPLIANCE"
+)
+
+// ObjectLock is the S3 object lock retention applied to an upload.
+// The bucket must have been created with object lock enabled.
+type ObjectLock struct {
+	Mode        string    // ObjectLockGovernance or ObjectLockCompliance
+	RetainUntil time.Time // The object cannot be deleted or overwritten before this time
+}
+
+// args returns the s3api arguments that request the retention
+func (l *ObjectLock) args() []string {
+	return []string{"--object-lock-mode", l.Mode, "--object-lock-retain-until-date", l.RetainUntil.UTC().Format(time.RFC3339)}
+}
+
+// withDefaults returns the options with unset values replaced by the defaults
+func (o UploadOptions) withDefaults() UploadOptions {
+	if o.PartSize <= 0 {
+		o.PartSize = DefaultPartSize
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultConcurrency
+	}
+	return o
+}
+
+// Upload copies the local file to the remote location, replacing any existing file.
+// Files larger than one part are sent in parallel parts: S3 uses a multipart upload,
+// rclone its multi-thread streams, and sftp keeps more requests in flight on its connection.
+// Returns an error if the tool for the scheme is missing or fails.
+func Upload(localPath string, r *Remote, opts UploadOptions) error {
+	return UploadContext(context.Background(), localPath, r, opts)
+}
+
+// UploadContext is Upload that stops when the context is done, e.g. when a run times out.
+// An interrupted S3 multipart upload is aborted; the error then wraps the context's error.
+func UploadContext(ctx context.Context, localPath string, r *Remote, opts UploadOptions) error {
+	opts = opts.withDefaults()
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", localPath, err)
+	}
+
+	if opts.ObjectLock != nil && r.Scheme != SchemeS3 {
+		return fmt.Errorf("object lock is only supported for S3 locations, not %q", r.String())
+	}
+
+	switch r.Scheme {
+	case SchemeS3:
+		if r.Path == "" || r.Path[len(r.Path)-1] == '/' {
+			return fmt.Errorf("invalid S3 upload location %q: missing object key", r.String())
+		}
+		if info.Size() <= opts.PartSize {
+			if opts.ObjectLock != nil {
+				return putS3Object(ctx, localPath, r, opts.ObjectLock)
+			}
+			_, err = runToolContext(ctx, "aws", nil, r.awsArgs("s3", "cp", "--only-show-errors", localPath, r.s3URL())...)
+			return err
+		}
+		return uploadS3Multipart(ctx, localPath, info.Size(), r, opts)
+	case SchemeSFTP:
+		// Upload under a temporary name, so an interrupted upload never leaves a partial file.
+		// SFTP rename does not replace files, hence the removal (the "-" ignores a missing file).
+		// The directory is created for a project's first backup, if its parent exists.
+		partPath := r.Path + ".part"
+		batch := fmt.Sprintf("-mkdir %s\nput %s %s\n-rm %s\nrename %s %s\n", quoteSFTPPath(path.Dir(r.Path)),
+			quoteSFTPPath(localPath), quoteSFTPPath(partPath), quoteSFTPPath(r.Path), quoteSFTPPath(partPath), quoteSFTPPath(r.Path))
+		args := []string{"-b", "-", "-R", strconv.Itoa(opts.Concurrency * sftpRequestsPerStream)}
+		_, err = runToolContext(ctx, "sftp", []byte(batch), append(args, r.sftpArgs()...)...)
+		return err
+	case SchemeRclone:
+		_, err = runToolContext(ctx, "rclone", nil, "copyto",
+			"--multi-thread-streams", strconv.Itoa(opts.Concurrency),
+			"--multi-thread-chunk-size", fmt.Sprintf("%dB", opts.PartSize),
+			localPath, r.Host+":"+r.Path)
+		return err
+	case SchemeExec:
+		return uploadExec(ctx, localPath, info.Size(), r)
+	}
+	return fmt.Errorf("unsupported remote scheme %q", r.Scheme)
+}
+
+// putS3Object uploads the file in one request with object lock retention.
+// aws s3 cp cannot set retention, and S3 requires a Content-MD5 header for locked uploads.
+func putS3Object(ctx context.Context, localPath string, r *Remote, lock *ObjectLock) error {
+	md5sum, err := fileMD5(localPath)
+	if err != nil {
+		return err
+	}
+	args := append([]string{"s3api", "put-object", "--bucket", r.Host, "--key", r.Path, "--body", localPath,
+		"--content-md5", md5sum, "--output", "json"}, lock.args()...)
+	_, err = runToolContext(ctx, "aws", nil, r.awsArgs(args...)...)
+	return err
+}
+
+// fileMD5 returns the base64-encoded MD5 digest of the file, as sent in a Content-MD5 header
+func fileMD5(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("error hashing %s: %w", path, err)
+	}
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+// s3Part is a completed part of a multipart upload
+type s3Part struct {
+	ETag       string `json:"ETag"`
+	PartNumber int    `json:"PartNumber"`
+}
+
+// s3PartSize returns the part size for a multipart upload of size bytes,
+// raised as needed to stay within the S3 part size and part count limits
+func s3PartSize(size, partSize int64) int64 {
+	if partSize < minS3PartSize {
+		partSize = minS3PartSize
+	}
+	for (size+partSize-1)/partSize > maxS3Parts {
+		partSize *= 2
+	}
+	return partSize
+}
+
+// uploadS3Multipart uploads the file in parts with up to opts.Concurrency parts in flight.
+// The upload is aborted if any part fails or the context is done, so no incomplete upload is left to be billed.
+func uploadS3Multipart(ctx context.Context, localPath string, size int64, r *Remote, opts UploadOptions) error {
+	partSize := s3PartSize(size, opts.PartSize)
+	partCount := int((size + partSize - 1) / partSize)
+
+	createArgs := []string{"s3api", "create-multipart-upload", "--bucket", r.Host, "--key", r.Path, "--output", "json"}
+	if opts.ObjectLock != nil {
+		createArgs = append(createArgs, opts.ObjectLock.args()...)
+	}
+	output, err := runToolContext(ctx, "aws", nil, r.awsArgs(createArgs...)...)
+	if err != nil {
+		return err
+	}
+	var created struct {
+		UploadID string `json:"UploadId"`
+	}
+	if err := json.Unmarshal(output, &created); err != nil || created.UploadID == "" {
+		return fmt.Errorf("unexpected create-multipart-upload response: %s", output)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "go-backup-upload-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	parts := make([]s3Part, partCount)
+	numbers := make(chan int)
+	errs := make(chan error, partCount)
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency && i < partCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for number := range numbers {
+				offset := int64(number-1) * partSize
+				etag, err := uploadS3Part(ctx, localPath, tmpDir, r, created.UploadID, number, offset, min(partSize, size-offset), opts.ObjectLock != nil)
+				if err != nil {
+					errs <- fmt.Errorf("part %d of %d: %w", number, partCount, err)
+					continue
+				}
+				parts[number-1] = s3Part{ETag: etag, PartNumber: number}
+			}
+		}()
+	}
+
+	failed := false
+	for number := 1; number <= partCount && !failed; number++ {
+		select {
+		case err = <-errs:
+			failed = true
+		case <-ctx.Done():
+			err = fmt.Errorf("upload stopped: %w", ctx.Err())
+			failed = true
+		case numbers <- number:
+		}
+	}
+	close(numbers)
+	wg.Wait()
+	if !failed && len(errs) > 0 {
+		err = <-errs
+		failed = true
+	}
+	if failed {
+		runTool("aws", nil, r.awsArgs("s3api", "abort-multipart-upload", "--bucket", r.Host, "--key", r.Path, "--upload-id", created.UploadID)...)
+		return err
+	}
+
+	partsPath := filepath.Join(tmpDir, "parts.json")
+	partsJSON, err := json.Marshal(struct {
+		Parts []s3Part `json:"Parts"`
+	}{parts})
+	if err != nil {
+		return fmt.Errorf("failed to encode upload parts: %w", err)
+	}
+	if err := os.WriteFile(partsPath, partsJSON, 0600); err != nil {
+		return fmt.Errorf("failed to write upload parts: %w", err)
+	}
+	_, err = runToolContext(ctx, "aws", nil, r.awsArgs("s3api", "complete-multipart-upload", "--bucket", r.Host, "--key", r.Path,
+		"--upload-id", created.UploadID, "--multipart-upload", "file://"+partsPath)...)
+	if err != nil {
+		runTool("aws", nil, r.awsArgs("s3api", "abort-multipart-upload", "--bucket", r.Host, "--key", r.Path, "--upload-id", created.UploadID)...)
+	}
+	return err
+}
+
+// uploadS3Part uploads one part of the file and returns its ETag.
+// aws only reads part bodies from files, so the part is copied to a temporary file first.
+// Parts of locked uploads carry a Content-MD5 header, which S3 requires for them.
+func uploadS3Part(ctx context.Context, localPath, tmpDir string, r *Remote, uploadID string, number int, offset, length int64, withMD5 bool) (string, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	partPath := filepath.Join(tmpDir, fmt.Sprintf("part-%05d", number))
+	defer os.Remove(partPath)
+	part, err := os.Create(partPath)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, io.NewSectionReader(file, offset, length)); err != nil {
+		part.Close()
+		return "", err
+	}
+	if err := part.Close(); err != nil {
+		return "", err
+	}
+
+	args := []string{"s3api", "upload-part", "--bucket", r.Host, "--key", r.Path,
+		"--upload-id", uploadID, "--part-number", strconv.Itoa(number), "--body", partPath, "--output", "json"}
+	if withMD5 {
+		md5sum, err := fileMD5(partPath)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, "--content-md5", md5sum)
+	}
+	output, err := runToolContext(ctx, "aws", nil, r.awsArgs(args...)...)
+	if err != nil {
+		return "", err
+	}
+	var uploaded struct {
+		ETag string `json:"ETag"`
+	}
+	if err := json.Unmarshal(output, &uploaded); err != nil || uploaded.ETag == "" {
+		return "", fmt.Errorf("unexpected upload-part response: %s", output)
+	}
+	return uploaded.ETag, nil
+}
+
+// TestHelperS3PartSize exposes the s3PartSize function for testing
+func TestHelperS3PartSize(size, partSize int64) int64 {
+	return s3PartSize(size, partSize)
+}
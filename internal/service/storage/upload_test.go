@@ -0,0 +1,193 @@
+package storage_test
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/kennycyb/go-backup/internal/service/storage"
+)
+
+// fakeAWS implements the aws commands used for uploads on top of the directory in $FAKE_S3.
+// Setting $FAIL_PART makes that part number fail, and $SLOW_PART makes it hang.
+// Object lock arguments are recorded in $FAKE_S3/lock, and Content-MD5 headers in $FAKE_S3/md5.
+const fakeAWS = `#!/bin/sh
+cmd="$1 $2"; shift 2; mkdir -p "$FAKE_S3"
+while [ $# -gt 0 ]; do
+  case "$1" in
+    --bucket) bucket=$2; shift 2 ;;
+    --key) key=$2; shift 2 ;;
+    --upload-id) id=$2; shift 2 ;;
+    --part-number) n=$2; shift 2 ;;
+    --body) body=$2; shift 2 ;;
+    --multipart-upload) parts=${2#file://}; shift 2 ;;
+    --object-lock-mode) mode=$2; shift 2 ;;
+    --object-lock-retain-until-date) until=$2; shift 2 ;;
+    --content-md5) echo "$2" >> "$FAKE_S3/md5"; shift 2 ;;
+    --output) shift 2 ;;
+    *) src=$dst; dst=$1; shift ;;
+  esac
+done
+case "$cmd" in
+  "s3 cp") out="$FAKE_S3/${dst#s3://}"; mkdir -p "$(dirname "$out")"; cp "$src" "$out" ;;
+  "s3api put-object") out="$FAKE_S3/$bucket/$key"; mkdir -p "$(dirname "$out")"; cp "$body" "$out"; echo "$mode $until" > "$FAKE_S3/lock"; echo '{}' ;;
+  "s3api create-multipart-upload") mkdir -p "$FAKE_S3/.uploads/u1"; [ -n "$mode" ] && echo "$mode $until" > "$FAKE_S3/lock"; echo '{"UploadId":"u1"}' ;;
+  "s3api upload-part") [ "$n" = "$FAIL_PART" ] && exit 1; [ "$n" = "$SLOW_PART" ] && exec sleep 30; cp "$body" "$FAKE_S3/.uploads/$id/$n"; echo "{\"ETag\":\"e$n\"}" ;;
+  "s3api complete-multipart-upload")
+    out="$FAKE_S3/$bucket/$key"; mkdir -p "$(dirname "$out")"; : > "$out"
+    for n in $(grep -o '"PartNumber":[0-9]*' "$parts" | cut -d: -f2); do cat "$FAKE_S3/.uploads/$id/$n" >> "$out"; done ;;
+  "s3api abort-multipart-upload") rm -rf "$FAKE_S3/.uploads/$id"; touch "$FAKE_S3/aborted" ;;
+  *) exit 1 ;;
+esac
+`
+
+var _ = Describe("Upload", func() {
+	var (
+		tmpDir  string
+		s3Dir   string
+		binDir  string
+		payload []byte
+		local   string
+	)
+
+	BeforeEach(func() {
+		tmpDir = GinkgoT().TempDir()
+		s3Dir = filepath.Join(tmpDir, "s3")
+		binDir = filepath.Join(tmpDir, "bin")
+		Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binDir, "aws"), []byte(fakeAWS), 0755)).To(Succeed())
+		GinkgoT().Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+		GinkgoT().Setenv("FAKE_S3", s3Dir)
+		GinkgoT().Setenv("FAIL_PART", "")
+		GinkgoT().Setenv("SLOW_PART", "")
+
+		// Large enough for three parts of the minimum S3 part size
+		payload = make([]byte, 12<<20)
+		_, err := rand.Read(payload)
+		Expect(err).NotTo(HaveOccurred())
+		local = filepath.Join(tmpDir, "backup.tar.gz")
+		Expect(os.WriteFile(local, payload, 0644)).To(Succeed())
+	})
+
+	It("should upload small files in one request", func() {
+		remote, err := ParseRemote("s3://bucket/backups/backup.tar.gz")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(Upload(local, remote, UploadOptions{PartSize: 16 << 20})).To(Succeed())
+		Expect(os.ReadFile(filepath.Join(s3Dir, "bucket/backups/backup.tar.gz"))).To(Equal(payload))
+		Expect(filepath.Join(s3Dir, ".uploads")).NotTo(BeADirectory())
+	})
+
+	It("should upload large files to S3 in parallel parts", func() {
+		remote, err := ParseRemote("s3://bucket/backups/backup.tar.gz")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(Upload(local, remote, UploadOptions{PartSize: 5 << 20, Concurrency: 3})).To(Succeed())
+		Expect(os.ReadFile(filepath.Join(s3Dir, "bucket/backups/backup.tar.gz"))).To(Equal(payload))
+
+		parts, err := os.ReadDir(filepath.Join(s3Dir, ".uploads/u1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parts).To(HaveLen(3))
+	})
+
+	It("should abort the multipart upload when a part fails", func() {
+		GinkgoT().Setenv("FAIL_PART", "2")
+		remote, err := ParseRemote("s3://bucket/backups/backup.tar.gz")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = Upload(local, remote, UploadOptions{PartSize: 5 << 20, Concurrency: 2})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("part 2 of 3"))
+		Expect(filepath.Join(s3Dir, "aborted")).To(BeAnExistingFile())
+		Expect(filepath.Join(s3Dir, "bucket/backups/backup.tar.gz")).NotTo(BeAnExistingFile())
+	})
+
+	It("should stop and abort the multipart upload when the context is done", func() {
+		GinkgoT().Setenv("SLOW_PART", "1")
+		remote, err := ParseRemote("s3://bucket/backups/backup.tar.gz")
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err = UploadContext(ctx, local, remote, UploadOptions{PartSize: 5 << 20, Concurrency: 1})
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(time.Since(start)).To(BeNumerically("<", 10*time.Second))
+		Expect(filepath.Join(s3Dir, "aborted")).To(BeAnExistingFile())
+	})
+
+	It("should upload small files with object lock retention", func() {
+		remote, err := ParseRemote("s3://bucket/backups/backup.tar.gz")
+		Expect(err).NotTo(HaveOccurred())
+		lock := &ObjectLock{Mode: ObjectLockCompliance, RetainUntil: time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+		Expect(Upload(local, remote, UploadOptions{PartSize: 16 << 20, ObjectLock: lock})).To(Succeed())
+		Expect(os.ReadFile(filepath.Join(s3Dir, "bucket/backups/backup.tar.gz"))).To(Equal(payload))
+		Expect(os.ReadFile(filepath.Join(s3Dir, "lock"))).To(Equal([]byte("COMPLIANCE 2030-01-02T03:04:05Z\n")))
+
+		sum := md5.Sum(payload)
+		Expect(os.ReadFile(filepath.Join(s3Dir, "md5"))).To(Equal([]byte(base64.StdEncoding.EncodeToString(sum[:]) + "\n")))
+	})
+
+	It("should lock multipart uploads and send a checksum with every part", func() {
+		remote, err := ParseRemote("s3://bucket/backups/backup.tar.gz")
+		Expect(err).NotTo(HaveOccurred())
+		lock := &ObjectLock{Mode: ObjectLockGovernance, RetainUntil: time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+		Expect(Upload(local, remote, UploadOptions{PartSize: 5 << 20, Concurrency: 3, ObjectLock: lock})).To(Succeed())
+		Expect(os.ReadFile(filepath.Join(s3Dir, "bucket/backups/backup.tar.gz"))).To(Equal(payload))
+		Expect(os.ReadFile(filepath.Join(s3Dir, "lock"))).To(Equal([]byte("GOVERNANCE 2030-01-02T03:04:05Z\n")))
+
+		sums, err := os.ReadFile(filepath.Join(s3Dir, "md5"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.Fields(string(sums))).To(HaveLen(3))
+	})
+
+	It("should refuse object lock for other locations than S3", func() {
+		remote, err := ParseRemote("rclone:gdrive:backups/backup.tar.gz")
+		Expect(err).NotTo(HaveOccurred())
+		lock := &ObjectLock{Mode: ObjectLockGovernance, RetainUntil: time.Now().Add(time.Hour)}
+
+		err = Upload(local, remote, UploadOptions{ObjectLock: lock})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("only supported for S3"))
+	})
+
+	It("should reject S3 locations without an object key", func() {
+		remote, err := ParseRemote("s3://bucket/backups/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Upload(local, remote, UploadOptions{})).NotTo(Succeed())
+	})
+
+	It("should pass the stream settings to rclone", func() {
+		script := "#!/bin/sh\necho \"$@\" > \"$FAKE_S3.args\"\n"
+		Expect(os.WriteFile(filepath.Join(binDir, "rclone"), []byte(script), 0755)).To(Succeed())
+		remote, err := ParseRemote("rclone:gdrive:backups/backup.tar.gz")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(Upload(local, remote, UploadOptions{PartSize: 8 << 20, Concurrency: 6})).To(Succeed())
+		args, err := os.ReadFile(s3Dir + ".args")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.TrimSpace(string(args))).To(Equal("copyto --multi-thread-streams 6 --multi-thread-chunk-size 8388608B " + local + " gdrive:backups/backup.tar.gz"))
+	})
+
+	Describe("S3 part size", func() {
+		It("should respect the S3 minimum part size", func() {
+			Expect(TestHelperS3PartSize(100<<20, 1<<20)).To(Equal(int64(5 << 20)))
+		})
+
+		It("should grow parts to stay within the part count limit", func() {
+			size := int64(10000) * (5 << 20) * 3
+			partSize := TestHelperS3PartSize(size, 5<<20)
+			Expect((size + partSize - 1) / partSize).To(BeNumerically("<=", 10000))
+		})
+	})
+})
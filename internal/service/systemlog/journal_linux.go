@@ -0,0 +1,31 @@
+//go:build linux
+
+package systemlog
+
+import "net"
+
+// journalSocket is where journald receives entries in its native protocol
+const journalSocket = "/run/systemd/journal/socket"
+
+// journal sends entries to journald, which keeps their fields searchable with journalctl
+type journal struct {
+	conn *net.UnixConn
+}
+
+// openJournal connects to the journald socket; it fails on hosts without systemd
+func openJournal(socketPath string) (sink, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &journal{conn: conn}, nil
+}
+
+func (j *journal) write(priority Priority, message string, fields []Field) error {
+	_, err := j.conn.Write(journalPayload(priority, message, fields))
+	return err
+}
+
+func (j *journal) close() error {
+	return j.conn.Close()
+}
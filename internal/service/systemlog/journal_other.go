@@ -0,0 +1,13 @@
+//go:build !linux
+
+package systemlog
+
+import "errors"
+
+// journalSocket is unused where there is no journald
+const journalSocket = ""
+
+// openJournal fails, since journald only runs on Linux
+func openJournal(socketPath string) (sink, error) {
+	return nil, errors.New("journald is not available on this platform")
+}
@@ -0,0 +1,10 @@
+//go:build windows || plan9
+
+package systemlog
+
+import "errors"
+
+// openSyslog fails, since there is no syslog daemon on this platform
+func openSyslog() (sink, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,36 @@
+//go:build !windows && !plan9
+
+package systemlog
+
+import "log/syslog"
+
+// syslogSink sends entries to the local syslog daemon
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+// openSyslog connects to the local syslog daemon with the user facility
+func openSyslog() (sink, error) {
+	w, err := syslog.New(syslog.LOG_USER|syslog.LOG_INFO, Identifier)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{w: w}, nil
+}
+
+func (s *syslogSink) write(priority Priority, message string, fields []Field) error {
+	line := syslogMessage(message, fields)
+	switch priority {
+	case PriorityErr:
+		return s.w.Err(line)
+	case PriorityWarning:
+		return s.w.Warning(line)
+	case PriorityNotice:
+		return s.w.Notice(line)
+	}
+	return s.w.Info(line)
+}
+
+func (s *syslogSink) close() error {
+	return s.w.Close()
+}
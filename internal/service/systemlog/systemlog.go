@@ -0,0 +1,123 @@
+// Package systemlog writes backup results to the system log, so administrators see them
+// alongside other system logs: journald on systemd hosts, syslog elsewhere.
+package systemlog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Identifier is the program name entries are logged under
+const Identifier = "go-backup"
+
+// Priority is the syslog severity of an entry
+type Priority int
+
+// Priorities used for backup results, as defined by syslog
+const (
+	PriorityErr     Priority = 3 // A backup failed
+	PriorityWarning Priority = 4 // A backup succeeded with warnings, e.g. unreadable files
+	PriorityNotice  Priority = 5 // A backup was skipped on purpose
+	PriorityInfo    Priority = 6 // A backup succeeded
+)
+
+// Field is a structured detail of an entry, such as the target of a backup.
+// Keys are upper case, as journald requires, e.g. "TARGET".
+type Field struct {
+	Key   string
+	Value string
+}
+
+// sink is a system log destination
+type sink interface {
+	write(priority Priority, message string, fields []Field) error
+	close() error
+}
+
+// Logger writes entries to the system log. A nil Logger discards them, so callers that
+// only log when asked to need no checks.
+type Logger struct {
+	sink sink
+}
+
+// Open connects to journald when it is running, and to the syslog daemon otherwise
+func Open() (*Logger, error) {
+	if s, err := openJournal(journalSocket); err == nil {
+		return &Logger{sink: s}, nil
+	}
+	s, err := openSyslog()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the system log: %w", err)
+	}
+	return &Logger{sink: s}, nil
+}
+
+// Log writes an entry with the given priority and fields
+func (l *Logger) Log(priority Priority, message string, fields ...Field) error {
+	if l == nil {
+		return nil
+	}
+	return l.sink.write(priority, message, fields)
+}
+
+// Close disconnects from the system log
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.sink.close()
+}
+
+// syslogMessage appends the fields to the message as key=value pairs, since syslog has no
+// structured data of its own. Values with spaces or quotes are quoted.
+func syslogMessage(message string, fields []Field) string {
+	var b strings.Builder
+	b.WriteString(message)
+	for _, field := range fields {
+		value := field.Value
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&b, " %s=%s", strings.ToLower(field.Key), value)
+	}
+	return b.String()
+}
+
+// journalPayload encodes an entry in the journald native protocol: one KEY=value line per field.
+// Newlines in values are replaced by spaces, which keeps to the simple form of the protocol.
+func journalPayload(priority Priority, message string, fields []Field) []byte {
+	var b strings.Builder
+	line := func(key, value string) {
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(strings.ReplaceAll(value, "\n", " "))
+		b.WriteByte('\n')
+	}
+	line("MESSAGE", message)
+	line("PRIORITY", strconv.Itoa(int(priority)))
+	line("SYSLOG_IDENTIFIER", Identifier)
+	for _, field := range fields {
+		line(field.Key, field.Value)
+	}
+	return []byte(b.String())
+}
+
+// TestHelperSyslogMessage exposes the syslogMessage function for testing
+func TestHelperSyslogMessage(message string, fields []Field) string {
+	return syslogMessage(message, fields)
+}
+
+// TestHelperJournalPayload exposes the journalPayload function for testing
+func TestHelperJournalPayload(priority Priority, message string, fields []Field) []byte {
+	return journalPayload(priority, message, fields)
+}
+
+// TestHelperOpenJournal opens a Logger on a journald socket at the given path, for testing
+func TestHelperOpenJournal(socketPath string) (*Logger, error) {
+	s, err := openJournal(socketPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Logger{sink: s}, nil
+}
@@ -0,0 +1,13 @@
+package systemlog_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSystemlog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Systemlog Suite")
+}
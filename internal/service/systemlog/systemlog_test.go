@@ -0,0 +1,56 @@
+package systemlog_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	. "github.com/kennycyb/go-backup/internal/service/systemlog"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Systemlog", func() {
+	fields := []Field{{Key: "TARGET", Value: "/backups"}, {Key: "ERROR", Value: "disk full"}}
+
+	It("should append the fields to syslog messages", func() {
+		Expect(TestHelperSyslogMessage("backup failed", fields)).To(Equal(`backup failed target=/backups error="disk full"`))
+		Expect(TestHelperSyslogMessage("backup failed", []Field{{Key: "ERROR", Value: ""}})).To(Equal(`backup failed error=""`))
+	})
+
+	It("should encode journald entries one field per line", func() {
+		payload := TestHelperJournalPayload(PriorityErr, "backup failed", append(fields, Field{Key: "DETAILS", Value: "a\nb"}))
+		Expect(string(payload)).To(Equal("MESSAGE=backup failed\nPRIORITY=3\nSYSLOG_IDENTIFIER=go-backup\nTARGET=/backups\nERROR=disk full\nDETAILS=a b\n"))
+	})
+
+	It("should send entries to the journald socket", func() {
+		if runtime.GOOS != "linux" {
+			Skip("journald is only used on Linux")
+		}
+		dir, err := os.MkdirTemp("", "journal")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		socketPath := filepath.Join(dir, "socket")
+		server, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+		Expect(err).NotTo(HaveOccurred())
+		defer server.Close()
+
+		logger, err := TestHelperOpenJournal(socketPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(logger.Log(PriorityInfo, "backup completed", fields[0])).To(Succeed())
+		Expect(logger.Close()).To(Succeed())
+
+		buf := make([]byte, 4096)
+		n, err := server.Read(buf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(buf[:n])).To(ContainSubstring("PRIORITY=6\n"))
+		Expect(string(buf[:n])).To(ContainSubstring("TARGET=/backups\n"))
+	})
+
+	It("should discard entries on a nil logger", func() {
+		var logger *Logger
+		Expect(logger.Log(PriorityErr, "ignored")).To(Succeed())
+		Expect(logger.Close()).To(Succeed())
+	})
+})
@@ -0,0 +1,199 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReleaseURL is the GitHub API endpoint describing the latest go-backup release
+const ReleaseURL = "https://api.github.com/repos/kennycyb/go-backup/releases/latest"
+
+// Default check settings; a slow or offline network only delays one command per interval
+const (
+	DefaultInterval = 24 * time.Hour
+	DefaultTimeout  = 2 * time.Second
+)
+
+// CheckOptions controls where and how often the latest release is looked up
+type CheckOptions struct {
+	URL       string        // Release endpoint; empty uses ReleaseURL
+	StatePath string        // File remembering the last check; empty uses the user cache directory
+	Interval  time.Duration // Minimum time between requests; 0 uses DefaultInterval
+	Timeout   time.Duration // Request timeout; 0 uses DefaultTimeout
+}
+
+// withDefaults returns the options with unset values replaced by the defaults
+func (o CheckOptions) withDefaults() (CheckOptions, error) {
+	if o.URL == "" {
+		o.URL = ReleaseURL
+	}
+	if o.StatePath == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return o, fmt.Errorf("error finding cache directory: %w", err)
+		}
+		o.StatePath = filepath.Join(cacheDir, "go-backup", "update-check.json")
+	}
+	if o.Interval <= 0 {
+		o.Interval = DefaultInterval
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+	return o, nil
+}
+
+// state is the outcome of the last check, so the release endpoint is asked at most once per interval
+type state struct {
+	CheckedAt time.Time `json:"checkedAt"`
+	Latest    string    `json:"latest,omitempty"` // Latest release seen, kept when a later check fails
+}
+
+// Check returns the latest release if it is newer than the current version, or "" otherwise.
+// The endpoint is only asked when the last check is older than the interval; in between the
+// remembered release is used. Nothing but a plain GET is sent, so the check carries no
+// information about the installation. A failed request is remembered like a successful one,
+// so offline machines do not retry on every command.
+// Returns "" without checking for development builds whose version is not a release number.
+func Check(current string, now time.Time, opts CheckOptions) (string, error) {
+	if _, ok := parseVersion(current); !ok {
+		return "", nil
+	}
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return "", err
+	}
+
+	last := readState(opts.StatePath)
+	if now.Sub(last.CheckedAt) >= opts.Interval || now.Before(last.CheckedAt) {
+		latest, fetchErr := fetchLatest(opts.URL, opts.Timeout)
+		if fetchErr == nil {
+			last.Latest = latest
+		}
+		last.CheckedAt = now
+		if err := writeState(opts.StatePath, last); err != nil {
+			return "", err
+		}
+		if fetchErr != nil {
+			return "", fetchErr
+		}
+	}
+
+	if IsNewer(last.Latest, current) {
+		return last.Latest, nil
+	}
+	return "", nil
+}
+
+// fetchLatest asks the release endpoint for the tag of the latest release
+func fetchLatest(url string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating release request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error checking for a newer release: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error checking for a newer release: %s", resp.Status)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", fmt.Errorf("error parsing release: %w", err)
+	}
+	if _, ok := parseVersion(release.TagName); !ok {
+		return "", fmt.Errorf("error parsing release: unexpected tag %q", release.TagName)
+	}
+	return release.TagName, nil
+}
+
+// readState reads the last check; a missing or damaged file counts as never checked
+func readState(path string) state {
+	var s state
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return state{}
+	}
+	return s
+}
+
+// writeState remembers the last check
+func writeState(path string, s state) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("error encoding update check: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating cache directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error saving update check: %w", err)
+	}
+	return nil
+}
+
+// IsNewer reports whether version a is a later release than version b.
+// Versions are dotted numbers with an optional "v" prefix, such as "v1.4.2";
+// pre-release suffixes ("-rc1") are ignored. Unparsable versions are never newer.
+func IsNewer(a, b string) bool {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	if !okA || !okB {
+		return false
+	}
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var x, y int
+		if i < len(va) {
+			x = va[i]
+		}
+		if i < len(vb) {
+			y = vb[i]
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	return false
+}
+
+// parseVersion splits a version like "v1.4.2-rc1" into its numbers
+func parseVersion(version string) ([]int, bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	if version == "" {
+		return nil, false
+	}
+
+	var numbers []int
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, true
+}
@@ -0,0 +1,108 @@
+package update_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/kennycyb/go-backup/internal/service/update"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Update", func() {
+	Describe("IsNewer", func() {
+		It("should compare dotted versions numerically", func() {
+			Expect(IsNewer("v1.10.0", "1.9.3")).To(BeTrue())
+			Expect(IsNewer("1.2", "1.1.9")).To(BeTrue())
+			Expect(IsNewer("1.2.0", "1.2")).To(BeFalse())
+			Expect(IsNewer("v1.0.0", "v1.0.1")).To(BeFalse())
+		})
+
+		It("should ignore pre-release suffixes and unparsable versions", func() {
+			Expect(IsNewer("v1.1.0-rc1", "1.0.0")).To(BeTrue())
+			Expect(IsNewer("v1.0.0-rc1", "1.0.0")).To(BeFalse())
+			Expect(IsNewer("latest", "1.0.0")).To(BeFalse())
+			Expect(IsNewer("2.0.0", "dev")).To(BeFalse())
+		})
+	})
+
+	Describe("Check", func() {
+		var (
+			tmpDir   string
+			server   *httptest.Server
+			requests int
+			tag      string
+			opts     CheckOptions
+		)
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = os.MkdirTemp("", "update-test")
+			Expect(err).NotTo(HaveOccurred())
+
+			requests = 0
+			tag = "v1.2.0"
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.Write([]byte(`{"tag_name": "` + tag + `"}`))
+			}))
+			opts = CheckOptions{URL: server.URL, StatePath: filepath.Join(tmpDir, "state.json"), Interval: time.Hour}
+		})
+
+		AfterEach(func() {
+			server.Close()
+			os.RemoveAll(tmpDir)
+		})
+
+		It("should report a newer release", func() {
+			latest, err := Check("1.1.0", time.Now(), opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(latest).To(Equal("v1.2.0"))
+
+			latest, err = Check("1.2.0", time.Now(), opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(latest).To(BeEmpty())
+		})
+
+		It("should ask the endpoint at most once per interval", func() {
+			now := time.Now()
+			_, err := Check("1.1.0", now, opts)
+			Expect(err).NotTo(HaveOccurred())
+
+			tag = "v1.3.0"
+			latest, err := Check("1.1.0", now.Add(30*time.Minute), opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(latest).To(Equal("v1.2.0"))
+			Expect(requests).To(Equal(1))
+
+			latest, err = Check("1.1.0", now.Add(2*time.Hour), opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(latest).To(Equal("v1.3.0"))
+			Expect(requests).To(Equal(2))
+		})
+
+		It("should remember failed checks and keep the last known release", func() {
+			now := time.Now()
+			_, err := Check("1.1.0", now, opts)
+			Expect(err).NotTo(HaveOccurred())
+
+			server.Close()
+			_, err = Check("1.1.0", now.Add(2*time.Hour), opts)
+			Expect(err).To(HaveOccurred())
+
+			latest, err := Check("1.1.0", now.Add(150*time.Minute), opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(latest).To(Equal("v1.2.0"))
+		})
+
+		It("should not check development builds", func() {
+			latest, err := Check("dev", time.Now(), opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(latest).To(BeEmpty())
+			Expect(requests).To(BeZero())
+		})
+	})
+})
@@ -0,0 +1,152 @@
+// Package verify checks backups on local targets for corruption without restoring them: each
+// archive is compared with its stored checksums and read to its end, and the manifest and config
+// copy next to it are checked against SHA256SUMS.
+package verify
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	storageService "github.com/kennycyb/go-backup/internal/service/storage"
+)
+
+// Checksum is the comparison of a file with one of its stored checksums
+type Checksum struct {
+	From string // Where the checksum is stored: SHA256SUMS or the .sha256 file next to the backup
+	Err  error  // Nil if the file matches
+}
+
+// Sidecar is the comparison of a manifest or config copy with its checksum in SHA256SUMS
+type Sidecar struct {
+	Name string
+	Err  error // Nil if the file matches
+}
+
+// Result describes what Backup found
+type Result struct {
+	Path string
+	// SumsErr is why SHA256SUMS could not be read; the backup is only checked against the
+	// .sha256 file then, and does not count as damaged for it
+	SumsErr error
+	// ChecksumFileErr is why the .sha256 file next to the backup could not be read although it exists
+	ChecksumFileErr error
+	Checksums       []Checksum // Empty when no checksum is stored for the backup
+	Encrypted       bool       // The archive structure is not checked, as reading it needs the private key
+	Archive         *compressionService.ArchiveCheck
+	ArchiveErr      error // Why the archive could not be read to its end
+	Sidecars        []Sidecar
+}
+
+// Intact reports whether nothing damaged was found
+func (r *Result) Intact() bool {
+	if r.ChecksumFileErr != nil || r.ArchiveErr != nil {
+		return false
+	}
+	for _, checksum := range r.Checksums {
+		if checksum.Err != nil {
+			return false
+		}
+	}
+	for _, sidecar := range r.Sidecars {
+		if sidecar.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Skipped is a target Backups left out
+type Skipped struct {
+	Dest   string
+	Remote bool  // Backups on remote targets must be fetched to be verified
+	Err    error // Why the directory of a local target could not be read
+}
+
+// Backups returns the paths of the backups named with prefix on the local targets, and the
+// targets that were left out. A file target's backup is included when it exists.
+func Backups(targets []configService.ResolvedTarget, prefix string) ([]string, []Skipped) {
+	var files []string
+	var skipped []Skipped
+	for _, target := range targets {
+		dest := target.GetDestination()
+		switch {
+		case storageService.IsRemote(dest):
+			skipped = append(skipped, Skipped{Dest: dest, Remote: true})
+		case target.IsFileTarget():
+			if _, err := os.Stat(dest); err == nil {
+				files = append(files, dest)
+			}
+		default:
+			found, err := InDirectory(dest, prefix)
+			if err != nil {
+				skipped = append(skipped, Skipped{Dest: dest, Err: err})
+				continue
+			}
+			files = append(files, found...)
+		}
+	}
+	return files, skipped
+}
+
+// InDirectory returns the paths of the archives in a directory whose name starts with prefix
+func InDirectory(dir, prefix string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && backupService.IsArchiveName(entry.Name()) && strings.HasPrefix(entry.Name(), prefix) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Backup checks a backup against its stored checksums and reads its archive through, along with
+// the checksums of its manifest and config copy
+func Backup(path string) *Result {
+	name := filepath.Base(path)
+	result := &Result{Path: path}
+
+	sums, err := backupService.ReadChecksums(filepath.Dir(path))
+	result.SumsErr = err
+	// The checksum is stored in SHA256SUMS and in the .sha256 file next to the backup
+	stored := map[string]string{backupService.ChecksumsFileName: sums[name]}
+	if sum, err := backupService.ReadChecksumFile(path); err == nil {
+		stored[name+backupService.ChecksumSuffix] = sum
+	} else if !os.IsNotExist(err) {
+		result.ChecksumFileErr = err
+	}
+	for _, from := range []string{backupService.ChecksumsFileName, name + backupService.ChecksumSuffix} {
+		if sum := stored[from]; sum != "" {
+			result.Checksums = append(result.Checksums, Checksum{From: from, Err: backupService.VerifyChecksum(path, sum)})
+		}
+	}
+
+	if strings.HasSuffix(name, ".gpg") {
+		result.Encrypted = true
+	} else {
+		result.Archive, result.ArchiveErr = compressionService.VerifyArchive(path)
+	}
+
+	baseName := backupService.BackupBaseName(name)
+	for _, sidecar := range []string{
+		baseName + backupService.ManifestSuffix,
+		baseName + backupService.ManifestSuffix + ".gpg",
+		baseName + ".backup.yaml",
+		baseName + ".backup.yaml.gpg",
+	} {
+		if sum := sums[sidecar]; sum != "" {
+			err := backupService.VerifyChecksum(filepath.Join(filepath.Dir(path), sidecar), sum)
+			result.Sidecars = append(result.Sidecars, Sidecar{Name: sidecar, Err: err})
+		}
+	}
+	return result
+}
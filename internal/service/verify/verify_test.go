@@ -0,0 +1,109 @@
+package verify_test
+
+import (
+	"os"
+	"path/filepath"
+
+	backupService "github.com/kennycyb/go-backup/internal/service/backup"
+	compressionService "github.com/kennycyb/go-backup/internal/service/compress"
+	configService "github.com/kennycyb/go-backup/internal/service/config"
+	. "github.com/kennycyb/go-backup/internal/service/verify"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Backup", func() {
+	var backupDir, archive string
+
+	BeforeEach(func() {
+		tmpDir := GinkgoT().TempDir()
+		sourceDir := filepath.Join(tmpDir, "project")
+		backupDir = filepath.Join(tmpDir, "backups")
+		Expect(os.MkdirAll(sourceDir, 0755)).To(Succeed())
+		Expect(os.MkdirAll(backupDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sourceDir, "main.go"), []byte("package main"), 0644)).To(Succeed())
+
+		archive = filepath.Join(backupDir, "project-20250520-123045.tar.gz")
+		Expect(compressionService.CreateTarGzArchive(sourceDir, archive, nil)).To(Succeed())
+		sum, err := backupService.FileSHA256(archive)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backupService.AddChecksum(backupDir, filepath.Base(archive), sum)).To(Succeed())
+		Expect(backupService.WriteChecksumFile(archive, sum)).To(Succeed())
+	})
+
+	It("should find an intact backup intact", func() {
+		result := Backup(archive)
+		Expect(result.Intact()).To(BeTrue())
+		Expect(result.Checksums).To(Equal([]Checksum{
+			{From: backupService.ChecksumsFileName},
+			{From: "project-20250520-123045.tar.gz" + backupService.ChecksumSuffix},
+		}))
+		Expect(result.Archive.Entries).To(BeNumerically(">", 0))
+	})
+
+	It("should report a backup that no longer matches its checksums", func() {
+		Expect(os.WriteFile(archive, []byte("truncated"), 0644)).To(Succeed())
+
+		result := Backup(archive)
+		Expect(result.Intact()).To(BeFalse())
+		Expect(result.Checksums).To(HaveLen(2))
+		Expect(result.Checksums[0].Err).To(HaveOccurred())
+		Expect(result.ArchiveErr).To(HaveOccurred())
+	})
+
+	It("should report a sidecar that does not match SHA256SUMS", func() {
+		manifest := filepath.Join(backupDir, "project-20250520-123045"+backupService.ManifestSuffix)
+		Expect(os.WriteFile(manifest, []byte(`{"files":1}`), 0644)).To(Succeed())
+		Expect(backupService.AddChecksum(backupDir, filepath.Base(manifest), "0000")).To(Succeed())
+
+		result := Backup(archive)
+		Expect(result.Intact()).To(BeFalse())
+		Expect(result.Sidecars).To(HaveLen(1))
+		Expect(result.Sidecars[0].Name).To(Equal(filepath.Base(manifest)))
+	})
+
+	It("should only compare an encrypted backup with its checksum", func() {
+		encrypted := archive + ".gpg"
+		Expect(os.WriteFile(encrypted, []byte("encrypted"), 0644)).To(Succeed())
+
+		result := Backup(encrypted)
+		Expect(result.Intact()).To(BeTrue())
+		Expect(result.Encrypted).To(BeTrue())
+		Expect(result.Checksums).To(BeEmpty())
+		Expect(result.Archive).To(BeNil())
+	})
+})
+
+var _ = Describe("Backups", func() {
+	It("should find the source's backups on local targets and skip the others", func() {
+		tmpDir := GinkgoT().TempDir()
+		nas := filepath.Join(tmpDir, "nas")
+		Expect(os.MkdirAll(nas, 0755)).To(Succeed())
+		for _, name := range []string{"project-20250521-080000.tar.gz", "project-20250520-123045.tar.gz.gpg", "other-20250520-123045.tar.gz", "project-20250520-123045.manifest.json"} {
+			Expect(os.WriteFile(filepath.Join(nas, name), []byte("archive"), 0644)).To(Succeed())
+		}
+		latest := filepath.Join(tmpDir, "latest.tar.gz")
+		Expect(os.WriteFile(latest, []byte("archive"), 0644)).To(Succeed())
+
+		config := &configService.BackupConfig{Targets: []configService.BackupTarget{
+			{Path: nas},
+			{File: latest},
+			{File: filepath.Join(tmpDir, "missing.tar.gz")},
+			{Path: filepath.Join(tmpDir, "unmounted")},
+			{Path: "s3://bucket/backups"},
+		}}
+		targets, err := configService.ResolveTargets(config, configService.TargetFlags{})
+		Expect(err).NotTo(HaveOccurred())
+
+		files, skipped := Backups(targets, "project-")
+		Expect(files).To(Equal([]string{
+			filepath.Join(nas, "project-20250520-123045.tar.gz.gpg"),
+			filepath.Join(nas, "project-20250521-080000.tar.gz"),
+			latest,
+		}))
+		Expect(skipped).To(HaveLen(2))
+		Expect(skipped[0].Dest).To(Equal(filepath.Join(tmpDir, "unmounted")))
+		Expect(skipped[0].Err).To(HaveOccurred())
+		Expect(skipped[1]).To(Equal(Skipped{Dest: "s3://bucket/backups", Remote: true}))
+	})
+})